@@ -58,9 +58,11 @@ func main() {
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
 
-	err = sg.Close()
+	err = sg.Shutdown()
 
 	if err != nil {
-		sg.Logger.Error().Err(err).Msg("Exception whilst closing sandwich")
+		sg.Logger.Error().Err(err).Msg("Exception whilst shutting down sandwich")
+
+		os.Exit(1)
 	}
 }
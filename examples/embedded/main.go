@@ -0,0 +1,79 @@
+// Command embedded runs sandwich inside another program and handles
+// MESSAGE_CREATE events in-process instead of through a message queue.
+//
+//	SANDWICH_TOKEN=... go run ./examples/embedded
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/hooks"
+	"github.com/rs/zerolog"
+)
+
+func main() {
+	logger := zerolog.ConsoleWriter{
+		Out:        os.Stdout,
+		TimeFormat: time.Stamp,
+	}
+
+	log := zerolog.New(logger).With().Timestamp().Logger()
+
+	configuration := &hooks.Configuration{}
+	configuration.Logging.Level = "info"
+	configuration.Logging.ConsoleLoggingEnabled = true
+	configuration.Producer.Type = "none"
+	configuration.GRPC.Network = "tcp"
+	configuration.GRPC.Host = "127.0.0.1:10000"
+	configuration.HTTP.Host = "127.0.0.1:5469"
+
+	manager := &hooks.ManagerConfiguration{
+		Identifier:  "embedded",
+		DisplayName: "Embedded",
+		Token:       os.Getenv("SANDWICH_TOKEN"),
+	}
+	manager.Messaging.ClientName = "embedded"
+	manager.Sharding.AutoSharded = true
+
+	configuration.Managers = []*hooks.ManagerConfiguration{manager}
+
+	sg, err := hooks.New(logger, configuration)
+	if err != nil {
+		log.Panic().Err(err).Msg("Cannot create sandwich")
+	}
+
+	remove := hooks.ListenFunc(sg, func(ctx context.Context, packet *hooks.Payload) error {
+		log.Info().
+			Str("manager", packet.Metadata.Identifier).
+			Str("data", string(packet.ReceivedPayload.Data)).
+			Msg("Received message")
+
+		return nil
+	}, "MESSAGE_CREATE")
+	defer remove()
+
+	if err = sg.Open(); err != nil {
+		log.Panic().Err(err).Msg("Cannot open sandwich")
+	}
+
+	// The manager does not auto start so it is started here instead.
+	ready, err := hooks.StartManager(sg, manager.Identifier)
+	if err != nil {
+		log.Panic().Err(err).Msg("Cannot start manager")
+	}
+
+	<-ready
+	log.Info().Msg("Manager is ready")
+
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	<-sc
+
+	if err = sg.Close(); err != nil {
+		log.Error().Err(err).Msg("Exception whilst closing sandwich")
+	}
+}
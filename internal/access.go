@@ -0,0 +1,222 @@
+package gateway
+
+import (
+	"path"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"github.com/gorilla/sessions"
+)
+
+// Messages returned when a request is denied. Each says which mechanism
+// denied the request so a configuration can be debugged.
+const (
+	deniedNotElevated    = forbiddenMessage
+	deniedPublicReadOnly = forbiddenMessage + ". Public mode only allows read-only endpoints"
+	deniedPublicMethod   = forbiddenMessage +
+		". Public mode only allows the RPC methods listed in http.public_allowed_methods"
+	deniedElevatedMethod = forbiddenMessage + ". This RPC method can never be allowed in public mode"
+	deniedPermission     = forbiddenMessage + ". Missing permission: "
+	deniedRestricted     = forbiddenMessage +
+		". Users with permissions can only use read-only endpoints and the RPC methods they are allowed"
+)
+
+// RPC methods which always require an elevated user, even if they are listed
+// in HTTP.PublicAllowedMethods.
+var elevatedMethods = map[string]bool{
+	"manager:capture":       true,
+	"manager:capture:fetch": true,
+	"manager:shard:send":    true,
+}
+
+// Username of the user used for requests made through public mode without
+// being logged in.
+const publicUsername = "Public"
+
+// AuthorizeSession checks if a session can access an endpoint. Elevated users
+// can access every endpoint. Users in Permissions and, when HTTP.Public is
+// enabled, anyone can access read-only endpoints. If the request is denied,
+// the reason is returned.
+func (sg *Sandwich) AuthorizeSession(session *sessions.Session, readOnly bool) (user *structs.DiscordUser, denied string) {
+	auth, user := sg.AuthenticateSession(session)
+	if auth {
+		return user, ""
+	}
+
+	if sg.hasPermissions(user) {
+		if !readOnly {
+			return user, deniedRestricted
+		}
+
+		return user, ""
+	}
+
+	sg.ConfigurationMu.RLock()
+	public := sg.Configuration.HTTP.Public
+	sg.ConfigurationMu.RUnlock()
+
+	if !public {
+		return user, deniedNotElevated
+	}
+
+	if !readOnly {
+		return user, deniedPublicReadOnly
+	}
+
+	return publicUser(user), ""
+}
+
+// AuthorizeRPC checks if a session can call an RPC method. Elevated users can
+// call every method. Users in Permissions are authorized here and have their
+// patterns checked by missingPermission. When HTTP.Public is enabled, anyone
+// can call the methods in HTTP.PublicAllowedMethods. If the request is denied,
+// the reason is returned.
+func (sg *Sandwich) AuthorizeRPC(session *sessions.Session, method string) (user *structs.DiscordUser, denied string) {
+	auth, user := sg.AuthenticateSession(session)
+	if auth || sg.hasPermissions(user) {
+		return user, ""
+	}
+
+	sg.ConfigurationMu.RLock()
+	defer sg.ConfigurationMu.RUnlock()
+
+	if !sg.Configuration.HTTP.Public {
+		return user, deniedNotElevated
+	}
+
+	if elevatedMethods[method] {
+		return user, deniedElevatedMethod
+	}
+
+	for _, allowed := range sg.Configuration.HTTP.PublicAllowedMethods {
+		if allowed == method {
+			return publicUser(user), ""
+		}
+	}
+
+	return user, deniedPublicMethod
+}
+
+// missingPermission returns the method if the user has permissions
+// configured and none of them match it. Methods are matched with path.Match so
+// "manager:*" allows every method starting with "manager:". Users without
+// permissions configured have already been authorized by AuthorizeRPC.
+func (sg *Sandwich) missingPermission(user *structs.DiscordUser, method string) (missing string) {
+	if user == nil {
+		return ""
+	}
+
+	sg.ConfigurationMu.RLock()
+	defer sg.ConfigurationMu.RUnlock()
+
+	userID := user.ID.String()
+
+	for _, elevatedUserID := range sg.Configuration.ElevatedUsers {
+		if elevatedUserID == userID {
+			return ""
+		}
+	}
+
+	permissions, ok := sg.Configuration.Permissions[userID]
+	if !ok {
+		return ""
+	}
+
+	for _, permission := range permissions {
+		if matched, _ := path.Match(permission, method); matched {
+			return ""
+		}
+	}
+
+	return method
+}
+
+// hasPermissions returns if the user has RPC permissions configured. These
+// users are not elevated so they cannot change the daemon outside of the RPC
+// methods they are allowed.
+func (sg *Sandwich) hasPermissions(user *structs.DiscordUser) bool {
+	if user == nil {
+		return false
+	}
+
+	sg.ConfigurationMu.RLock()
+	defer sg.ConfigurationMu.RUnlock()
+
+	_, ok := sg.Configuration.Permissions[user.ID.String()]
+
+	return ok
+}
+
+// publicUser returns the user a public request is made as. RPC handlers
+// expect a user so one is made for requests that are not logged in.
+func publicUser(user *structs.DiscordUser) *structs.DiscordUser {
+	if user != nil {
+		return user
+	}
+
+	return &structs.DiscordUser{
+		Username: publicUsername,
+	}
+}
+
+// redactConfiguration returns a copy of the configuration with the session
+// secret, API tokens, OAuth client secret and redis password replaced with
+// redactedToken so they are never sent to the dashboard.
+func redactConfiguration(configuration *SandwichConfiguration) *SandwichConfiguration {
+	redacted := *configuration
+
+	redacted.HTTP.SessionSecret = redactSecret(configuration.HTTP.SessionSecret)
+	redacted.Caching.Redis.Password = redactSecret(configuration.Caching.Redis.Password)
+
+	redacted.HTTP.APITokens = make([]APIToken, len(configuration.HTTP.APITokens))
+	for i, token := range configuration.HTTP.APITokens {
+		redacted.HTTP.APITokens[i] = APIToken{Name: token.Name, Token: redactSecret(token.Token)}
+	}
+
+	if configuration.OAuth != nil {
+		oauth := *configuration.OAuth
+		oauth.ClientSecret = redactSecret(oauth.ClientSecret)
+		redacted.OAuth = &oauth
+	}
+
+	return &redacted
+}
+
+// restoreSecrets replaces secrets which are still redactedToken with the
+// values in current. The dashboard sends back the configuration it was given
+// so secrets that were not changed would otherwise be overwritten.
+func restoreSecrets(configuration *SandwichConfiguration, current *SandwichConfiguration) {
+	if configuration.HTTP.SessionSecret == redactedToken {
+		configuration.HTTP.SessionSecret = current.HTTP.SessionSecret
+	}
+
+	if configuration.Caching.Redis.Password == redactedToken {
+		configuration.Caching.Redis.Password = current.Caching.Redis.Password
+	}
+
+	tokens := make(map[string]string, len(current.HTTP.APITokens))
+	for _, token := range current.HTTP.APITokens {
+		tokens[token.Name] = token.Token
+	}
+
+	for i, token := range configuration.HTTP.APITokens {
+		if token.Token == redactedToken {
+			configuration.HTTP.APITokens[i].Token = tokens[token.Name]
+		}
+	}
+
+	if configuration.OAuth != nil && configuration.OAuth.ClientSecret == redactedToken {
+		if current.OAuth != nil {
+			configuration.OAuth.ClientSecret = current.OAuth.ClientSecret
+		} else {
+			configuration.OAuth.ClientSecret = ""
+		}
+	}
+}
+
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return redactedToken
+}
@@ -0,0 +1,274 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"github.com/gorilla/sessions"
+	"github.com/rs/zerolog"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testElevatedID   = 1
+	testPermissionID = 2
+	testAnonymousID  = 3
+)
+
+func newAccessSandwich(public bool) *Sandwich {
+	configuration := &SandwichConfiguration{
+		ElevatedUsers: []string{snowflake.ID(testElevatedID).String()},
+		Permissions: map[string][]string{
+			snowflake.ID(testPermissionID).String(): {"manager:shard:*"},
+		},
+	}
+
+	configuration.HTTP.Public = public
+	configuration.HTTP.PublicAllowedMethods = []string{"manager:shard:status", "manager:capture"}
+	configuration.HTTP.APITokens = []APIToken{{Name: "ci", Token: "secret-token"}}
+
+	return &Sandwich{
+		Logger:        zerolog.Nop(),
+		Configuration: configuration,
+	}
+}
+
+func userSession(t *testing.T, userID int64) *sessions.Session {
+	t.Helper()
+
+	session := sessions.NewSession(nil, sessionName)
+
+	body, err := json.Marshal(structs.DiscordUser{ID: snowflake.ID(userID)})
+	if err != nil {
+		t.Fatalf("failed to marshal user: %v", err)
+	}
+
+	session.Values["user"] = body
+
+	return session
+}
+
+func accessSessions(t *testing.T) map[string]*sessions.Session {
+	t.Helper()
+
+	token := sessions.NewSession(nil, sessionName)
+	token.Values[apiTokenSessionKey] = "ci"
+
+	return map[string]*sessions.Session{
+		"elevated":   userSession(t, testElevatedID),
+		"permission": userSession(t, testPermissionID),
+		"token":      token,
+		"user":       userSession(t, testAnonymousID),
+		"anonymous":  sessions.NewSession(nil, sessionName),
+	}
+}
+
+func TestAuthorizeSession(t *testing.T) {
+	tests := []struct {
+		public   bool
+		session  string
+		readOnly bool
+		denied   string
+	}{
+		{false, "elevated", true, ""},
+		{false, "elevated", false, ""},
+		{false, "permission", true, ""},
+		{false, "permission", false, deniedRestricted},
+		{false, "token", true, ""},
+		{false, "token", false, ""},
+		{false, "user", true, deniedNotElevated},
+		{false, "user", false, deniedNotElevated},
+		{false, "anonymous", true, deniedNotElevated},
+		{false, "anonymous", false, deniedNotElevated},
+		{true, "elevated", true, ""},
+		{true, "elevated", false, ""},
+		{true, "permission", true, ""},
+		{true, "permission", false, deniedRestricted},
+		{true, "token", true, ""},
+		{true, "token", false, ""},
+		{true, "user", true, ""},
+		{true, "user", false, deniedPublicReadOnly},
+		{true, "anonymous", true, ""},
+		{true, "anonymous", false, deniedPublicReadOnly},
+	}
+
+	for _, test := range tests {
+		sg := newAccessSandwich(test.public)
+		session := accessSessions(t)[test.session]
+
+		user, denied := sg.AuthorizeSession(session, test.readOnly)
+		if denied != test.denied {
+			t.Errorf("public=%v session=%s readOnly=%v: denied %q, want %q",
+				test.public, test.session, test.readOnly, denied, test.denied)
+		}
+
+		if denied == "" && user == nil {
+			t.Errorf("public=%v session=%s readOnly=%v: authorized without a user",
+				test.public, test.session, test.readOnly)
+		}
+	}
+}
+
+// authorizeRPC checks a method the same way executeRequest does.
+func authorizeRPC(sg *Sandwich, session *sessions.Session, method string) (denied string) {
+	user, denied := sg.AuthorizeRPC(session, method)
+	if denied != "" {
+		return denied
+	}
+
+	if missing := sg.missingPermission(user, method); missing != "" {
+		return deniedPermission + missing
+	}
+
+	return ""
+}
+
+func TestAuthorizeRPC(t *testing.T) {
+	const (
+		allowed  = "manager:shard:status"
+		mutating = "daemon:update"
+		elevated = "manager:capture"
+	)
+
+	tests := []struct {
+		public  bool
+		session string
+		method  string
+		denied  string
+	}{
+		{false, "elevated", allowed, ""},
+		{false, "elevated", mutating, ""},
+		{false, "elevated", elevated, ""},
+		{false, "permission", allowed, ""},
+		{false, "permission", mutating, deniedPermission + mutating},
+		{false, "permission", elevated, deniedPermission + elevated},
+		{false, "token", allowed, ""},
+		{false, "token", mutating, ""},
+		{false, "token", elevated, ""},
+		{false, "user", allowed, deniedNotElevated},
+		{false, "user", mutating, deniedNotElevated},
+		{false, "user", elevated, deniedNotElevated},
+		{false, "anonymous", allowed, deniedNotElevated},
+		{false, "anonymous", mutating, deniedNotElevated},
+		{false, "anonymous", elevated, deniedNotElevated},
+		{true, "elevated", allowed, ""},
+		{true, "elevated", mutating, ""},
+		{true, "elevated", elevated, ""},
+		{true, "permission", allowed, ""},
+		{true, "permission", mutating, deniedPermission + mutating},
+		{true, "permission", elevated, deniedPermission + elevated},
+		{true, "token", allowed, ""},
+		{true, "token", mutating, ""},
+		{true, "token", elevated, ""},
+		{true, "user", allowed, ""},
+		{true, "user", mutating, deniedPublicMethod},
+		{true, "user", elevated, deniedElevatedMethod},
+		{true, "anonymous", allowed, ""},
+		{true, "anonymous", mutating, deniedPublicMethod},
+		{true, "anonymous", elevated, deniedElevatedMethod},
+	}
+
+	for _, test := range tests {
+		sg := newAccessSandwich(test.public)
+		session := accessSessions(t)[test.session]
+
+		if denied := authorizeRPC(sg, session, test.method); denied != test.denied {
+			t.Errorf("public=%v session=%s method=%s: denied %q, want %q",
+				test.public, test.session, test.method, denied, test.denied)
+		}
+	}
+}
+
+func TestAuthorizeSessionConcurrentUpdate(t *testing.T) {
+	sg := newAccessSandwich(true)
+	session := accessSessions(t)["anonymous"]
+
+	done := make(chan void)
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 1000; i++ {
+			sg.ConfigurationMu.Lock()
+			sg.Configuration.HTTP.Public = i%2 == 0
+			sg.ConfigurationMu.Unlock()
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		sg.AuthorizeSession(session, true)
+	}
+
+	<-done
+}
+
+func TestAuthenticateSessionPermissionsNotElevated(t *testing.T) {
+	sg := newAccessSandwich(false)
+
+	if auth, _ := sg.AuthenticateSession(userSession(t, testPermissionID)); auth {
+		t.Error("user with permissions was elevated")
+	}
+
+	if auth, _ := sg.AuthenticateSession(userSession(t, testElevatedID)); !auth {
+		t.Error("elevated user was not elevated")
+	}
+}
+
+func TestAuthorizeSessionPermissionsReadOnly(t *testing.T) {
+	sg := newAccessSandwich(false)
+	session := userSession(t, testPermissionID)
+
+	if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+		t.Errorf("read-only endpoint denied: %s", denied)
+	}
+
+	if _, denied := sg.AuthorizeSession(session, false); denied != deniedRestricted {
+		t.Errorf("mutating endpoint got %q, want %q", denied, deniedRestricted)
+	}
+}
+
+func TestRedactConfiguration(t *testing.T) {
+	sg := newAccessSandwich(false)
+	sg.Configuration.HTTP.SessionSecret = "session-secret"
+	sg.Configuration.Caching.Redis.Password = "redis-password"
+	sg.Configuration.OAuth = &oauth2.Config{ClientID: "id", ClientSecret: "client-secret"}
+
+	redacted := redactConfiguration(sg.Configuration)
+
+	if redacted.HTTP.SessionSecret != redactedToken {
+		t.Errorf("session secret was %q", redacted.HTTP.SessionSecret)
+	}
+
+	if redacted.Caching.Redis.Password != redactedToken {
+		t.Errorf("redis password was %q", redacted.Caching.Redis.Password)
+	}
+
+	if redacted.HTTP.APITokens[0].Token != redactedToken || redacted.HTTP.APITokens[0].Name != "ci" {
+		t.Errorf("api token was %+v", redacted.HTTP.APITokens[0])
+	}
+
+	if redacted.OAuth.ClientSecret != redactedToken || redacted.OAuth.ClientID != "id" {
+		t.Errorf("oauth was %+v", redacted.OAuth)
+	}
+
+	if sg.Configuration.HTTP.APITokens[0].Token != "secret-token" ||
+		sg.Configuration.OAuth.ClientSecret != "client-secret" ||
+		sg.Configuration.HTTP.SessionSecret != "session-secret" {
+		t.Error("redacting modified the configuration")
+	}
+
+	restored := redactConfiguration(sg.Configuration)
+	restored.HTTP.APITokens = append(restored.HTTP.APITokens, APIToken{Name: "new", Token: "new-token"})
+	restoreSecrets(restored, sg.Configuration)
+
+	if restored.HTTP.SessionSecret != "session-secret" ||
+		restored.Caching.Redis.Password != "redis-password" ||
+		restored.OAuth.ClientSecret != "client-secret" {
+		t.Errorf("secrets were not restored: %+v", restored.HTTP)
+	}
+
+	if restored.HTTP.APITokens[0].Token != "secret-token" || restored.HTTP.APITokens[1].Token != "new-token" {
+		t.Errorf("api tokens were %+v", restored.HTTP.APITokens)
+	}
+}
@@ -0,0 +1,448 @@
+package gateway
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"github.com/vmihailenco/msgpack"
+	"golang.org/x/xerrors"
+)
+
+// Suffix of the messaging channel consumers acknowledge events on.
+const ackSuffix = ":ack"
+
+// Defaults used when the acknowledgement configuration is not set.
+const (
+	defaultAckTimeout         = 30
+	defaultAckAttempts        = 3
+	defaultAckPendingLimit    = 10000
+	defaultAckDeadLetterLimit = 1000
+)
+
+// ackMessage is sent by consumers on <channel_name>:ack once they have
+// processed events which required an acknowledgement.
+type ackMessage struct {
+	EventIDs []int64 `msgpack:"event_ids"`
+}
+
+// pendingAck is a published event which is waiting to be acknowledged. Data
+// is the payload as it was produced so it can be published again as is.
+type pendingAck struct {
+	EventID        int64     `msgpack:"event_id"`
+	Type           string    `msgpack:"type"`
+	Data           []byte    `msgpack:"data"`
+	Compression    string    `msgpack:"compression,omitempty"`
+	Attempts       int       `msgpack:"attempts"`
+	FirstPublished time.Time `msgpack:"first_published"`
+	LastPublished  time.Time `msgpack:"last_published"`
+	DeadLettered   time.Time `msgpack:"dead_lettered"`
+	Reason         string    `msgpack:"reason"`
+
+	timer *time.Timer
+}
+
+func (pa *pendingAck) API() structs.UnackedEvent {
+	return structs.UnackedEvent{
+		EventID:        pa.EventID,
+		Type:           pa.Type,
+		Size:           len(pa.Data),
+		Attempts:       pa.Attempts,
+		FirstPublished: pa.FirstPublished,
+		LastPublished:  pa.LastPublished,
+		DeadLettered:   pa.DeadLettered,
+		Reason:         pa.Reason,
+	}
+}
+
+// ackRequired returns if events of the type must be acknowledged by a
+// consumer. ConfigurationMu must be held.
+func (mg *Manager) ackRequired(eventType string) bool {
+	for _, ackEvent := range mg.Configuration.Messaging.AckEvents {
+		if ackEvent == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trackAck starts waiting for an event to be acknowledged. If too many
+// events are already waiting, the event is dead lettered straight away.
+// ConfigurationMu must be held.
+func (mg *Manager) trackAck(eventID int64, eventType string, compression string, data []byte) {
+	timeout := time.Duration(mg.Configuration.Messaging.AckTimeout) * time.Second
+	limit := mg.Configuration.Messaging.AckPendingLimit
+	deadLetterLimit := mg.Configuration.Messaging.AckDeadLetterLimit
+
+	now := time.Now().UTC()
+
+	pending := &pendingAck{
+		EventID:        eventID,
+		Type:           eventType,
+		Data:           append([]byte(nil), data...),
+		Compression:    compression,
+		Attempts:       1,
+		FirstPublished: now,
+		LastPublished:  now,
+	}
+
+	mg.acksMu.Lock()
+
+	if len(mg.pendingAcks) >= limit {
+		mg.acksMu.Unlock()
+
+		mg.Logger.Warn().
+			Int64("event_id", eventID).
+			Str("type", eventType).
+			Int("limit", limit).
+			Msg("Too many events are waiting for an acknowledgement, dead lettering event")
+
+		mg.deadLetter(pending, "pending acknowledgement limit reached", deadLetterLimit)
+
+		return
+	}
+
+	pending.timer = time.AfterFunc(timeout, func() { mg.ackExpired(eventID) })
+	mg.pendingAcks[eventID] = pending
+	mg.acksMu.Unlock()
+}
+
+// ackExpired publishes an unacknowledged event again or dead letters it once
+// messaging.ack_attempts has been reached.
+func (mg *Manager) ackExpired(eventID int64) {
+	mg.ConfigurationMu.RLock()
+	timeout := time.Duration(mg.Configuration.Messaging.AckTimeout) * time.Second
+	maxAttempts := mg.Configuration.Messaging.AckAttempts
+	deadLetterLimit := mg.Configuration.Messaging.AckDeadLetterLimit
+	mg.ConfigurationMu.RUnlock()
+
+	mg.acksMu.Lock()
+
+	pending, ok := mg.pendingAcks[eventID]
+	if !ok {
+		mg.acksMu.Unlock()
+
+		return
+	}
+
+	if pending.Attempts >= maxAttempts {
+		delete(mg.pendingAcks, eventID)
+		mg.acksMu.Unlock()
+
+		mg.Logger.Warn().
+			Int64("event_id", eventID).
+			Str("type", pending.Type).
+			Int("attempts", pending.Attempts).
+			Msg("Event was not acknowledged, dead lettering event")
+
+		mg.deadLetter(pending, "not acknowledged", deadLetterLimit)
+
+		return
+	}
+
+	pending.Attempts++
+	pending.LastPublished = time.Now().UTC()
+	pending.timer = time.AfterFunc(timeout, func() { mg.ackExpired(eventID) })
+	attempts, data, compression := pending.Attempts, pending.Data, pending.Compression
+	mg.acksMu.Unlock()
+
+	if err := mg.republish(compression, data); err != nil {
+		mg.Logger.Warn().Err(err).Int64("event_id", eventID).Msg("Failed to publish unacknowledged event again")
+
+		return
+	}
+
+	mg.Logger.Debug().
+		Int64("event_id", eventID).
+		Int("attempt", attempts).
+		Msg("Published unacknowledged event again")
+}
+
+// republish sends an already produced payload using the current producer.
+func (mg *Manager) republish(compression string, data []byte) (err error) {
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	producer := mg.Producer()
+	if producer == nil {
+		return xerrors.New("manager has no producer")
+	}
+
+	err = publishMessage(mg.ctx, producer, mg.Configuration.Messaging.ChannelName, mg.payloadMessage(nil, compression), data)
+	mg.recordPublish(len(data), err)
+
+	if err != nil {
+		return xerrors.Errorf("republish: %w", err)
+	}
+
+	return nil
+}
+
+// acknowledge stops waiting for events consumers have processed.
+func (mg *Manager) acknowledge(eventIDs []int64) (acknowledged int) {
+	mg.acksMu.Lock()
+	defer mg.acksMu.Unlock()
+
+	for _, eventID := range eventIDs {
+		pending, ok := mg.pendingAcks[eventID]
+		if !ok {
+			continue
+		}
+
+		pending.timer.Stop()
+		delete(mg.pendingAcks, eventID)
+
+		acknowledged++
+	}
+
+	return acknowledged
+}
+
+// deadLetter adds an event to the dead letters, dropping the oldest once
+// there are more than limit.
+func (mg *Manager) deadLetter(pending *pendingAck, reason string, limit int) {
+	pending.DeadLettered = time.Now().UTC()
+	pending.Reason = reason
+	pending.timer = nil
+
+	mg.acksMu.Lock()
+	mg.deadLetters = append(mg.deadLetters, pending)
+
+	if dropped := len(mg.deadLetters) - limit; dropped > 0 {
+		mg.deadLetters = mg.deadLetters[dropped:]
+
+		mg.Logger.Warn().Int("dropped", dropped).Msg("Dropped oldest dead lettered events")
+	}
+	mg.acksMu.Unlock()
+
+	go mg.persistDeadLetters()
+}
+
+// subscribeAcks listens for acknowledgements on the current producer if
+// messaging.ack_events is set, replacing the previous subscription. Events
+// still waiting are kept so acknowledgements sent through a new producer
+// connection are matched. ConfigurationMu must be held.
+func (mg *Manager) subscribeAcks() {
+	mg.acksMu.Lock()
+	defer mg.acksMu.Unlock()
+
+	if mg.acksCancel != nil {
+		mg.acksCancel()
+		mg.acksCancel = nil
+	}
+
+	producer := mg.Producer()
+	if len(mg.Configuration.Messaging.AckEvents) == 0 || producer == nil {
+		return
+	}
+
+	if !mg.deadLettersLoaded {
+		mg.deadLettersLoaded = true
+
+		go mg.loadDeadLetters()
+	}
+
+	subscriber, ok := producer.(MQSubscriber)
+	if !ok {
+		mg.Logger.Warn().
+			Str("driver", producer.String()).
+			Msg("Producer cannot subscribe so acknowledgements cannot be received")
+
+		return
+	}
+
+	channel := mg.Configuration.Messaging.ChannelName + ackSuffix
+	ctx, cancel := context.WithCancel(mg.ctx)
+
+	err := subscriber.Subscribe(ctx, channel, func(data []byte) {
+		var ack ackMessage
+
+		if err := msgpack.Unmarshal(data, &ack); err != nil {
+			mg.Logger.Warn().Err(err).Msg("Rejected acknowledgement")
+
+			return
+		}
+
+		mg.acknowledge(ack.EventIDs)
+	})
+	if err != nil {
+		cancel()
+		mg.Logger.Error().Err(err).Str("channel", channel).Msg("Failed to subscribe to acknowledgements")
+
+		return
+	}
+
+	mg.acksCancel = cancel
+
+	mg.Logger.Info().Str("channel", channel).Msg("Listening for acknowledgements")
+}
+
+// Unacked returns the events waiting for an acknowledgement and the dead
+// lettered events.
+func (mg *Manager) Unacked() structs.APIUnacked {
+	mg.ConfigurationMu.RLock()
+	result := structs.APIUnacked{
+		Manager:         mg.Configuration.Identifier,
+		PendingLimit:    mg.Configuration.Messaging.AckPendingLimit,
+		DeadLetterLimit: mg.Configuration.Messaging.AckDeadLetterLimit,
+	}
+	mg.ConfigurationMu.RUnlock()
+
+	mg.acksMu.Lock()
+	defer mg.acksMu.Unlock()
+
+	result.Pending = make([]structs.UnackedEvent, 0, len(mg.pendingAcks))
+	for _, pending := range mg.pendingAcks {
+		result.Pending = append(result.Pending, pending.API())
+	}
+
+	sort.Slice(result.Pending, func(i, j int) bool {
+		return result.Pending[i].FirstPublished.Before(result.Pending[j].FirstPublished)
+	})
+
+	result.DeadLetters = make([]structs.UnackedEvent, 0, len(mg.deadLetters))
+	for _, pending := range mg.deadLetters {
+		result.DeadLetters = append(result.DeadLetters, pending.API())
+	}
+
+	return result
+}
+
+// takeDeadLetters removes dead lettered events. If eventIDs is empty, all
+// are removed.
+func (mg *Manager) takeDeadLetters(eventIDs []int64) (taken []*pendingAck) {
+	ids := make(map[int64]bool, len(eventIDs))
+	for _, eventID := range eventIDs {
+		ids[eventID] = true
+	}
+
+	mg.acksMu.Lock()
+	defer mg.acksMu.Unlock()
+
+	kept := mg.deadLetters[:0]
+
+	for _, pending := range mg.deadLetters {
+		if len(ids) == 0 || ids[pending.EventID] {
+			taken = append(taken, pending)
+		} else {
+			kept = append(kept, pending)
+		}
+	}
+
+	mg.deadLetters = kept
+
+	return taken
+}
+
+// RequeueDeadLetters publishes dead lettered events again and waits for them
+// to be acknowledged with a fresh set of attempts. If eventIDs is empty, all
+// are requeued. Events which fail to publish are dead lettered again.
+func (mg *Manager) RequeueDeadLetters(eventIDs []int64) (requeued []int64) {
+	mg.ConfigurationMu.RLock()
+	deadLetterLimit := mg.Configuration.Messaging.AckDeadLetterLimit
+	mg.ConfigurationMu.RUnlock()
+
+	taken := mg.takeDeadLetters(eventIDs)
+
+	for _, pending := range taken {
+		if err := mg.republish(pending.Compression, pending.Data); err != nil {
+			mg.Logger.Warn().Err(err).Int64("event_id", pending.EventID).Msg("Failed to requeue event")
+			mg.deadLetter(pending, err.Error(), deadLetterLimit)
+
+			continue
+		}
+
+		mg.ConfigurationMu.RLock()
+		mg.trackAck(pending.EventID, pending.Type, pending.Compression, pending.Data)
+		mg.ConfigurationMu.RUnlock()
+
+		requeued = append(requeued, pending.EventID)
+	}
+
+	go mg.persistDeadLetters()
+
+	return requeued
+}
+
+// DiscardDeadLetters removes dead lettered events without publishing them.
+// If eventIDs is empty, all are discarded.
+func (mg *Manager) DiscardDeadLetters(eventIDs []int64) (discarded []int64) {
+	for _, pending := range mg.takeDeadLetters(eventIDs) {
+		discarded = append(discarded, pending.EventID)
+	}
+
+	go mg.persistDeadLetters()
+
+	return discarded
+}
+
+// deadLettersKey is the redis key the dead letters of the manager are
+// persisted to.
+func (mg *Manager) deadLettersKey(sr *stateRedis) string {
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	return sr.key("acks:" + mg.Configuration.Identifier + ":dead_letters")
+}
+
+// persistDeadLetters writes the dead letters to redis when the redis state
+// backend is used so they are kept across restarts.
+func (mg *Manager) persistDeadLetters() {
+	sr := mg.Sandwich.State.redis
+	if sr == nil {
+		return
+	}
+
+	mg.deadLettersPersistMu.Lock()
+	defer mg.deadLettersPersistMu.Unlock()
+
+	mg.acksMu.Lock()
+	data, err := msgpack.Marshal(mg.deadLetters)
+	mg.acksMu.Unlock()
+
+	if err != nil {
+		mg.Logger.Warn().Err(err).Msg("Failed to marshal dead letters")
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+
+	if err = sr.client.Set(ctx, mg.deadLettersKey(sr), data, 0).Err(); err != nil {
+		mg.Logger.Warn().Err(err).Msg("Failed to persist dead letters")
+	}
+}
+
+// loadDeadLetters restores the dead letters persisted by a previous run of
+// the daemon.
+func (mg *Manager) loadDeadLetters() {
+	sr := mg.Sandwich.State.redis
+	if sr == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+
+	data, err := sr.client.Get(ctx, mg.deadLettersKey(sr)).Bytes()
+	if err != nil {
+		return
+	}
+
+	var persisted []*pendingAck
+
+	if err = msgpack.Unmarshal(data, &persisted); err != nil {
+		mg.Logger.Warn().Err(err).Msg("Failed to load persisted dead letters")
+
+		return
+	}
+
+	mg.acksMu.Lock()
+	mg.deadLetters = append(persisted, mg.deadLetters...)
+	mg.acksMu.Unlock()
+
+	mg.Logger.Info().Int("dead_letters", len(persisted)).Msg("Loaded persisted dead letters")
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// Seconds an affinity override lasts if events.guild_affinity_ttl is not set.
+const defaultGuildAffinityTTL = 7 * 24 * 60 * 60
+
+// guildAffinityOverride replaces the affinity tag of a guild until it expires.
+type guildAffinityOverride struct {
+	tag     string
+	expires time.Time
+}
+
+// guildAffinityTag returns the affinity tag stamped onto a payload. Only
+// events which belong to a guild are tagged. defaultTag is
+// events.guild_affinity_tag which the caller reads as it already holds
+// ConfigurationMu.
+func (mg *Manager) guildAffinityTag(packet *structs.SandwichPayload, defaultTag string) string {
+	mg.guildAffinityMu.RLock()
+	overrides := len(mg.guildAffinity)
+	mg.guildAffinityMu.RUnlock()
+
+	if defaultTag == "" && overrides == 0 {
+		return ""
+	}
+
+	guildID := payloadGuildID(packet)
+	if guildID == 0 {
+		return ""
+	}
+
+	if overrides > 0 {
+		mg.guildAffinityMu.RLock()
+		override, ok := mg.guildAffinity[guildID]
+		mg.guildAffinityMu.RUnlock()
+
+		if ok && time.Now().Before(override.expires) {
+			return override.tag
+		}
+	}
+
+	return defaultTag
+}
+
+// SetGuildAffinity overrides the affinity tag of guilds for ttl. If ttl is
+// not positive, events.guild_affinity_ttl is used. An empty tag removes the
+// overrides so the guilds use the tag of the manager again. The number of
+// active overrides is returned.
+func (mg *Manager) SetGuildAffinity(guildIDs []snowflake.ID, tag string, ttl time.Duration) (active int) {
+	if ttl <= 0 {
+		mg.ConfigurationMu.RLock()
+		ttl = time.Duration(mg.Configuration.Events.GuildAffinityTTL) * time.Second
+		mg.ConfigurationMu.RUnlock()
+	}
+
+	expires := time.Now().Add(ttl)
+
+	mg.guildAffinityMu.Lock()
+	defer mg.guildAffinityMu.Unlock()
+
+	for _, guildID := range guildIDs {
+		if tag == "" {
+			delete(mg.guildAffinity, guildID)
+		} else {
+			mg.guildAffinity[guildID] = guildAffinityOverride{tag: tag, expires: expires}
+		}
+	}
+
+	return mg.purgeGuildAffinity()
+}
+
+// GuildAffinityOverrides removes expired affinity overrides and returns how
+// many are still active.
+func (mg *Manager) GuildAffinityOverrides() (active int) {
+	mg.guildAffinityMu.Lock()
+	defer mg.guildAffinityMu.Unlock()
+
+	return mg.purgeGuildAffinity()
+}
+
+// purgeGuildAffinity removes expired overrides. guildAffinityMu must be held.
+func (mg *Manager) purgeGuildAffinity() (active int) {
+	now := time.Now()
+
+	for guildID, override := range mg.guildAffinity {
+		if !now.Before(override.expires) {
+			delete(mg.guildAffinity, guildID)
+		}
+	}
+
+	return len(mg.guildAffinity)
+}
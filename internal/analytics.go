@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"github.com/gorilla/sessions"
+)
+
+// Interval between the cached analytics being recomputed.
+const analyticsCacheInterval = 5 * time.Second
+
+// CachedAnalytics returns the most recently computed analytics with their
+// age. If fresh is set or nothing has been cached yet, the analytics are
+// computed and cached first.
+func (sg *Sandwich) CachedAnalytics(fresh bool) (result structs.APIAnalyticsResult) {
+	cached, _ := sg.analytics.Load().(*structs.APIAnalyticsResult)
+
+	if fresh || cached == nil {
+		cached = sg.refreshAnalytics()
+	}
+
+	result = *cached
+	result.Age = time.Now().UTC().Sub(result.GeneratedAt).Milliseconds()
+
+	return result
+}
+
+func (sg *Sandwich) refreshAnalytics() *structs.APIAnalyticsResult {
+	result := sg.FetchAnalytics()
+	sg.analytics.Store(&result)
+
+	return &result
+}
+
+// analyticsCacheRunner keeps the cached analytics up to date so requests do
+// not have to walk the state themselves.
+func (sg *Sandwich) analyticsCacheRunner() {
+	t := time.NewTicker(analyticsCacheInterval)
+	defer t.Stop()
+
+	for {
+		sg.refreshAnalytics()
+		<-t.C
+	}
+}
+
+// freshAnalytics returns if a request asked for fresh analytics and is
+// allowed to. Only elevated users can force analytics to be recomputed.
+func freshAnalytics(sg *Sandwich, session *sessions.Session, r *http.Request) bool {
+	if r.URL.Query().Get("fresh") != "true" {
+		return false
+	}
+
+	auth, _ := sg.AuthenticateSession(session)
+
+	return auth
+}
@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// Session value holding the name of the API token a request was made with.
+	apiTokenSessionKey = "api_token"
+
+	// Prefix of the username of the user requests made with an API token are
+	// made as, followed by the name of the token.
+	apiTokenUsernamePrefix = "token:"
+)
+
+// APIToken is a static token which lets automation call the HTTP API and RPC
+// as an elevated user without logging in through OAuth.
+type APIToken struct {
+	// Name of the token used in logs and the audit log in place of the token.
+	Name  string `json:"name" yaml:"name"`
+	Token string `json:"token" yaml:"token"`
+}
+
+// apiTokenName returns the name of the API token in an Authorization header.
+// Every configured token is compared in constant time so the response time
+// does not leak which token, or how much of it, matched.
+func (sg *Sandwich) apiTokenName(authorization string) (name string, ok bool) {
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return "", false
+	}
+
+	presented := []byte(strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer ")))
+	if len(presented) == 0 {
+		return "", false
+	}
+
+	sg.ConfigurationMu.RLock()
+	defer sg.ConfigurationMu.RUnlock()
+
+	for _, token := range sg.Configuration.HTTP.APITokens {
+		if token.Token == "" {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare(presented, []byte(token.Token)) == 1 && !ok {
+			name, ok = token.Name, true
+		}
+	}
+
+	return name, ok
+}
+
+// requestSession returns the session of a request. Requests with a valid API
+// token get a new session which is never read from or saved to the session
+// store. Other requests use their cookie session.
+func (sg *Sandwich) requestSession(r *http.Request) *sessions.Session {
+	if name, ok := sg.apiTokenName(r.Header.Get("Authorization")); ok {
+		session := sessions.NewSession(sg.Store, sessionName)
+		session.Values[apiTokenSessionKey] = name
+
+		return session
+	}
+
+	session, _ := sg.Store.Get(r, sessionName)
+
+	return session
+}
+
+// apiTokenUser returns the user of a session made with an API token.
+func apiTokenUser(session *sessions.Session) (user *structs.DiscordUser, ok bool) {
+	name, ok := session.Values[apiTokenSessionKey].(string)
+	if !ok {
+		return nil, false
+	}
+
+	return &structs.DiscordUser{
+		Username: apiTokenUsernamePrefix + name,
+	}, true
+}
@@ -0,0 +1,441 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"github.com/rs/zerolog"
+	"golang.org/x/xerrors"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// Default number of entries that can be waiting to be written.
+	defaultAuditQueueSize = 1024
+
+	// Number of recent entries kept in memory for /api/audit.
+	auditRecentEntries = 1000
+
+	// Default and maximum entries returned by /api/audit.
+	auditDefaultLimit = 50
+	auditMaxLimit     = auditRecentEntries
+
+	// Interval between checking for dropped entries. At most one alert is
+	// sent per interval.
+	auditDropCheckInterval = time.Minute
+
+	// Largest line read when loading entries from an existing audit log.
+	auditMaxLineSize = 1024 * 1024
+
+	auditRedacted = "[redacted]"
+)
+
+// RPC methods which send a notification when they succeed. Destructive
+// methods which already send their own notification are not listed.
+var destructiveMethods = map[string]bool{
+	"manager:shardgroup:delete": true,
+	"manager:unacked:discard":   true,
+	"manager:errors:reset":      true,
+	"manager:shard:restart":     true,
+	"manager:shard:send":        true,
+}
+
+// Keys which are redacted from audit parameters.
+var auditSecretKeys = []string{"token", "secret", "password", "client_secret"}
+
+// Matches the token part of a discord webhook url.
+var auditWebhookToken = regexp.MustCompile(`(/api/(?:v\d+/)?webhooks/\d+/)[\w-]+`)
+
+// AuditLogger keeps the most recent mutating actions in memory and, if a
+// filename is provided, asynchronously writes them to an append only file as
+// JSON lines. If the queue is full, entries are dropped and counted instead
+// of blocking the caller.
+type AuditLogger struct {
+	logger zerolog.Logger
+
+	writer io.WriteCloser // Nil when entries are only kept in memory
+	ids    *snowflake.Generator
+
+	queueMu sync.RWMutex
+	queue   chan *structs.AuditEntry
+	closed  bool
+
+	recentMu sync.RWMutex
+	recent   []structs.AuditEntry
+
+	dropped *int64
+	done    chan void
+}
+
+// NewAuditLogger creates an AuditLogger that writes to filename, rotating
+// the file in the same way as the daemon logs. If filename is empty, entries
+// are only kept in memory and are lost on restart.
+func NewAuditLogger(logger zerolog.Logger, filename string, maxSize int, maxBackups int,
+	maxAge int, queueSize int) (al *AuditLogger, err error) {
+	if queueSize < 1 {
+		queueSize = defaultAuditQueueSize
+	}
+
+	ids, err := snowflake.NewGenerator(0, 0)
+	if err != nil {
+		return nil, xerrors.Errorf("new audit logger: %w", err)
+	}
+
+	al = &AuditLogger{
+		logger: logger.With().Str("service", "audit").Logger(),
+
+		ids: ids,
+
+		queueMu: sync.RWMutex{},
+		queue:   make(chan *structs.AuditEntry, queueSize),
+
+		recentMu: sync.RWMutex{},
+		recent:   make([]structs.AuditEntry, 0, auditRecentEntries),
+
+		dropped: new(int64),
+		done:    make(chan void),
+	}
+
+	if filename != "" {
+		if err = os.MkdirAll(path.Dir(filename), 0o744); err != nil {
+			return nil, xerrors.Errorf("new audit logger: %w", err)
+		}
+
+		al.writer = &lumberjack.Logger{
+			Filename:   filename,
+			MaxSize:    maxSize,
+			MaxBackups: maxBackups,
+			MaxAge:     maxAge,
+		}
+
+		al.loadRecent(filename)
+	}
+
+	go al.run()
+
+	return al, nil
+}
+
+// Record queues an entry to be written. This never blocks and does
+// nothing if the AuditLogger is nil.
+func (al *AuditLogger) Record(entry structs.AuditEntry) {
+	if al == nil {
+		return
+	}
+
+	entry.ID = al.ids.Generate()
+	entry.Time = time.Now().UTC()
+
+	al.queueMu.RLock()
+	defer al.queueMu.RUnlock()
+
+	if al.closed {
+		return
+	}
+
+	select {
+	case al.queue <- &entry:
+	default:
+		atomic.AddInt64(al.dropped, 1)
+	}
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (al *AuditLogger) Dropped() int64 {
+	if al == nil {
+		return 0
+	}
+
+	return atomic.LoadInt64(al.dropped)
+}
+
+// Entries returns up to limit of the most recent entries, newest first, with
+// an ID lower than before. A before of 0 returns the newest entries.
+func (al *AuditLogger) Entries(limit int, before snowflake.ID) (entries []structs.AuditEntry) {
+	entries = make([]structs.AuditEntry, 0)
+
+	if al == nil {
+		return entries
+	}
+
+	al.recentMu.RLock()
+	defer al.recentMu.RUnlock()
+
+	for i := len(al.recent) - 1; i >= 0 && len(entries) < limit; i-- {
+		if before == 0 || al.recent[i].ID < before {
+			entries = append(entries, al.recent[i])
+		}
+	}
+
+	return entries
+}
+
+// Close writes any queued entries and closes the file.
+func (al *AuditLogger) Close() (err error) {
+	if al == nil {
+		return nil
+	}
+
+	al.queueMu.Lock()
+	if !al.closed {
+		al.closed = true
+		close(al.queue)
+	}
+	al.queueMu.Unlock()
+
+	<-al.done
+
+	if al.writer == nil {
+		return nil
+	}
+
+	return al.writer.Close()
+}
+
+func (al *AuditLogger) run() {
+	defer close(al.done)
+
+	for entry := range al.queue {
+		if al.writer == nil {
+			al.remember(*entry)
+
+			continue
+		}
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			al.logger.Error().Err(err).Str("action", entry.Action).Msg("Failed to marshal audit entry")
+
+			continue
+		}
+
+		_, err = al.writer.Write(append(data, '\n'))
+		if err != nil {
+			al.logger.Error().Err(err).Str("action", entry.Action).Msg("Failed to write audit entry")
+		}
+
+		al.remember(*entry)
+	}
+}
+
+func (al *AuditLogger) remember(entry structs.AuditEntry) {
+	al.recentMu.Lock()
+	defer al.recentMu.Unlock()
+
+	if len(al.recent) >= auditRecentEntries {
+		al.recent = append(al.recent[:0], al.recent[1:]...)
+	}
+
+	al.recent = append(al.recent, entry)
+}
+
+// loadRecent reads the most recent entries from an existing audit log so
+// they are still available from /api/audit after a restart.
+func (al *AuditLogger) loadRecent(filename string) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			al.logger.Warn().Err(err).Msg("Failed to open audit log")
+		}
+
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), auditMaxLineSize)
+
+	for scanner.Scan() {
+		entry := structs.AuditEntry{}
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+
+		al.remember(entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		al.logger.Warn().Err(err).Msg("Failed to read audit log")
+	}
+}
+
+// auditResponseWriter records the status of a RPC response for the audit
+// log.
+type auditResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// auditManager returns the manager in the parameters of a RPC request.
+func auditManager(parameters interface{}) string {
+	if values, ok := parameters.(map[string]interface{}); ok {
+		if manager, ok := values["manager"].(string); ok {
+			return manager
+		}
+	}
+
+	return ""
+}
+
+// auditUser returns the identifying fields of a user for an audit entry.
+func auditUser(user *structs.DiscordUser) *structs.DiscordUser {
+	if user == nil {
+		return nil
+	}
+
+	return &structs.DiscordUser{
+		ID:            user.ID,
+		Username:      user.Username,
+		Discriminator: user.Discriminator,
+	}
+}
+
+// auditParameters decodes RPC data and redacts any secrets in it.
+func (sg *Sandwich) auditParameters(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var parameters interface{}
+
+	if err := json.Unmarshal([]byte(sg.Redact(string(data))), &parameters); err != nil {
+		return nil
+	}
+
+	return redactSecrets(parameters)
+}
+
+func redactSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if isSecretKey(key) {
+				v[key] = auditRedacted
+			} else {
+				v[key] = redactSecrets(child)
+			}
+		}
+
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactSecrets(child)
+		}
+
+		return v
+	case string:
+		return auditWebhookToken.ReplaceAllString(v, "${1}"+auditRedacted)
+	default:
+		return v
+	}
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, secret := range auditSecretKeys {
+		if key == secret || strings.HasSuffix(key, "_"+secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// configurationDiff summarises which sections of the configuration changed.
+func configurationDiff(oldConfiguration *SandwichConfiguration, newConfiguration *SandwichConfiguration) string {
+	if oldConfiguration == nil {
+		return "created configuration"
+	}
+
+	changed := make([]string, 0)
+
+	oldValue := reflect.ValueOf(oldConfiguration).Elem()
+	newValue := reflect.ValueOf(newConfiguration).Elem()
+
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := oldValue.Type().Field(i)
+		if field.Name == "Managers" {
+			continue
+		}
+
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, strings.Split(field.Tag.Get("yaml"), ",")[0])
+		}
+	}
+
+	oldManagers := make(map[string]*ManagerConfiguration)
+	for _, manager := range oldConfiguration.Managers {
+		oldManagers[manager.Identifier] = manager
+	}
+
+	for _, manager := range newConfiguration.Managers {
+		oldManager, ok := oldManagers[manager.Identifier]
+
+		switch {
+		case !ok:
+			changed = append(changed, "managers."+manager.Identifier+" (added)")
+		case !reflect.DeepEqual(oldManager, manager):
+			changed = append(changed, "managers."+manager.Identifier)
+		}
+
+		delete(oldManagers, manager.Identifier)
+	}
+
+	for identifier := range oldManagers {
+		changed = append(changed, "managers."+identifier+" (removed)")
+	}
+
+	if len(changed) == 0 {
+		return "no changes"
+	}
+
+	sort.Strings(changed)
+
+	return "changed " + strings.Join(changed, ", ")
+}
+
+// auditRunner alerts when audit entries have been dropped.
+func (sg *Sandwich) auditRunner() {
+	t := time.NewTicker(auditDropCheckInterval)
+	defer t.Stop()
+
+	var alerted int64
+
+	for range t.C {
+		dropped := sg.Audit.Dropped()
+		if dropped <= alerted {
+			continue
+		}
+
+		sg.Logger.Error().Int64("dropped", dropped-alerted).Msg("Audit entries were dropped as the queue is full")
+
+		go sg.Notify(context.Background(),
+			NewNotification("Audit entries were dropped",
+				fmt.Sprintf("%d audit entries were dropped as the audit queue is full", dropped-alerted)).
+				WithSeverity(SeverityDanger))
+
+		alerted = dropped
+	}
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// Blacklists which can be modified through the manager:blacklist RPCs.
+const (
+	BlacklistEvent   = "event"
+	BlacklistProduce = "produce"
+)
+
+// ErrUnknownBlacklist is returned when a blacklist that does not exist is used.
+var ErrUnknownBlacklist = xerrors.New("unknown blacklist. Expected event or produce")
+
+// ErrBlacklistVersion is returned when a blacklist has changed since the
+// version that was provided.
+var ErrBlacklistVersion = xerrors.New("blacklist has been modified since the version provided")
+
+// BlacklistVersion returns a version for a blacklist which changes whenever
+// its entries do. This can be used to detect concurrent modifications.
+func BlacklistVersion(entries []string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strings.Join(entries, "\n")))
+
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// Blacklist returns the entries of a blacklist along with its version.
+func (mg *Manager) Blacklist(list string) (entries []string, version string, err error) {
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	list = strings.ToLower(list)
+
+	current, err := mg.blacklistEntries(list)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries = append(make([]string, 0, len(*current)), *current...)
+
+	return entries, BlacklistVersion(entries), nil
+}
+
+// ModifyBlacklist adds or removes events from a blacklist and recompiles it.
+// If version is not empty, the blacklist is only modified if it has not
+// changed since. ConfigurationMu must be held for writing.
+func (mg *Manager) ModifyBlacklist(list string, add []string, remove []string,
+	version string) (entries []string, newVersion string, err error) {
+	list = strings.ToLower(list)
+
+	current, err := mg.blacklistEntries(list)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if version != "" && version != BlacklistVersion(*current) {
+		return nil, "", ErrBlacklistVersion
+	}
+
+	removed := make(map[string]void)
+	for _, name := range NormalizeEventNames(remove) {
+		removed[name] = void{}
+	}
+
+	entries = make([]string, 0, len(*current)+len(add))
+	seen := make(map[string]void)
+
+	for _, name := range append(append(make([]string, 0, len(*current)+len(add)), *current...),
+		NormalizeEventNames(add)...) {
+		if _, ok := removed[name]; ok {
+			continue
+		}
+
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		seen[name] = void{}
+		entries = append(entries, name)
+	}
+
+	*current = entries
+
+	switch list {
+	case BlacklistEvent:
+		mg.EventBlacklistMu.Lock()
+		mg.EventBlacklist = mg.compileEventMatcher("event_blacklist", entries)
+		mg.EventBlacklistMu.Unlock()
+	case BlacklistProduce:
+		mg.ProduceBlacklistMu.Lock()
+		mg.ProduceBlacklist = mg.compileEventMatcher("produce_blacklist", entries)
+		mg.ProduceBlacklistMu.Unlock()
+	}
+
+	mg.logIntentWarnings(mg.Configuration)
+
+	return entries, BlacklistVersion(entries), nil
+}
+
+// blacklistEntries returns a pointer to the configured entries of a blacklist.
+func (mg *Manager) blacklistEntries(list string) (entries *[]string, err error) {
+	switch list {
+	case BlacklistEvent:
+		return &mg.Configuration.Events.EventBlacklist, nil
+	case BlacklistProduce:
+		return &mg.Configuration.Events.ProduceBlacklist, nil
+	default:
+		return nil, ErrUnknownBlacklist
+	}
+}
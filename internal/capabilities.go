@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// ProducerWarnings checks the producer settings against the capabilities of
+// the selected driver and returns a warning for each setting which will not
+// behave as configured.
+func (sc *SandwichConfiguration) ProducerWarnings() (warnings []structs.ConfigurationWarning) {
+	capabilities, ok := mqclients.MQCapabilities[sc.Producer.Type]
+	if !ok {
+		return []structs.ConfigurationWarning{
+			{
+				Setting: "producer.type",
+				Message: fmt.Sprintf("%q is not a known producer. Expected one of %v",
+					sc.Producer.Type, mqclients.MQClients),
+			},
+		}
+	}
+
+	async, _ := mqclients.GetEntry(sc.Producer.Configuration, "Async").(string)
+	if enabled, _ := strconv.ParseBool(async); enabled && !capabilities.SupportsFlush {
+		warnings = append(warnings, structs.ConfigurationWarning{
+			Setting: "producer.configuration.async",
+			Message: fmt.Sprintf("the %s producer cannot flush so asynchronous publishes "+
+				"are not waited for before it is closed", sc.Producer.Type),
+		})
+	}
+
+	return warnings
+}
+
+// logProducerWarnings logs any producer settings which are not supported by
+// the selected driver.
+func (sg *Sandwich) logProducerWarnings(sc *SandwichConfiguration) {
+	for _, warning := range sc.ProducerWarnings() {
+		sg.Logger.Warn().Str("setting", warning.Setting).Msg(warning.Message)
+	}
+}
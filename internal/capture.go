@@ -0,0 +1,226 @@
+package gateway
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Default and maximum duration of a capture.
+	defaultCaptureDuration = 5 * time.Minute
+	maxCaptureDuration     = 30 * time.Minute
+
+	// Default and maximum events kept by a capture.
+	defaultCaptureEvents = 1000
+	maxCaptureEvents     = 10000
+)
+
+// ErrCaptureRunning is returned when starting a capture on a manager which
+// already has one running.
+var ErrCaptureRunning = xerrors.New("a capture is already running on this manager")
+
+// ErrNoCapture is returned when fetching a capture that has not been started.
+var ErrNoCapture = xerrors.New("no capture has been started on this manager")
+
+// EventCapture buffers a copy of the payloads a manager produces which match
+// its filters. Once the capture has expired or is full, no more payloads are
+// added but the buffer is kept until it is fetched.
+type EventCapture struct {
+	GuildID   snowflake.ID
+	Events    map[string]void
+	Start     time.Time
+	End       time.Time
+	MaxEvents int
+	User      *structs.DiscordUser
+
+	payloadsMu sync.Mutex
+	payloads   []jsoniter.RawMessage
+	captured   int
+	dropped    int
+}
+
+// Active returns if the capture is still accepting payloads.
+func (ec *EventCapture) Active(now time.Time) bool {
+	return ec != nil && now.Before(ec.End)
+}
+
+// matches returns if a payload should be captured.
+func (ec *EventCapture) matches(packet *structs.SandwichPayload) bool {
+	if len(ec.Events) > 0 {
+		if _, ok := ec.Events[packet.Type]; !ok {
+			return false
+		}
+	}
+
+	if ec.GuildID != 0 {
+		return payloadGuildID(packet) == ec.GuildID
+	}
+
+	return true
+}
+
+// add buffers a payload if there is space.
+func (ec *EventCapture) add(packet *structs.SandwichPayload) (err error) {
+	ec.payloadsMu.Lock()
+	defer ec.payloadsMu.Unlock()
+
+	if len(ec.payloads) >= ec.MaxEvents {
+		ec.dropped++
+
+		return nil
+	}
+
+	payload, err := json.Marshal(packet)
+	if err != nil {
+		return xerrors.Errorf("capture marshal: %w", err)
+	}
+
+	ec.payloads = append(ec.payloads, payload)
+	ec.captured++
+
+	return nil
+}
+
+// API returns the description of the capture shown in the API.
+func (ec *EventCapture) API(manager string) structs.EventCapture {
+	ec.payloadsMu.Lock()
+	defer ec.payloadsMu.Unlock()
+
+	events := make([]string, 0, len(ec.Events))
+	for event := range ec.Events {
+		events = append(events, event)
+	}
+
+	return structs.EventCapture{
+		Manager:   manager,
+		GuildID:   ec.GuildID,
+		Events:    events,
+		Start:     ec.Start,
+		End:       ec.End,
+		MaxEvents: ec.MaxEvents,
+		Buffered:  len(ec.payloads),
+		Captured:  ec.captured,
+		Dropped:   ec.dropped,
+		User:      auditUser(ec.User),
+	}
+}
+
+// StartCapture starts capturing the payloads produced by the manager. Only
+// payloads for guildID and of the events provided are captured, if set.
+// Duration and maxEvents are clamped to their maximums.
+func (mg *Manager) StartCapture(user *structs.DiscordUser, guildID snowflake.ID, events []string,
+	duration time.Duration, maxEvents int) (capture *EventCapture, err error) {
+	if duration <= 0 {
+		duration = defaultCaptureDuration
+	} else if duration > maxCaptureDuration {
+		duration = maxCaptureDuration
+	}
+
+	if maxEvents <= 0 {
+		maxEvents = defaultCaptureEvents
+	} else if maxEvents > maxCaptureEvents {
+		maxEvents = maxCaptureEvents
+	}
+
+	now := time.Now().UTC()
+
+	capture = &EventCapture{
+		GuildID:   guildID,
+		Events:    make(map[string]void),
+		Start:     now,
+		End:       now.Add(duration),
+		MaxEvents: maxEvents,
+		User:      user,
+
+		payloadsMu: sync.Mutex{},
+		payloads:   make([]jsoniter.RawMessage, 0),
+	}
+
+	for _, event := range NormalizeEventNames(events) {
+		capture.Events[event] = void{}
+	}
+
+	mg.CaptureMu.Lock()
+	defer mg.CaptureMu.Unlock()
+
+	// A capture which has finished can be replaced even if it has not been
+	// fetched, so a forgotten capture does not block new ones.
+	if mg.Capture.Active(now) {
+		return nil, ErrCaptureRunning
+	}
+
+	mg.Capture = capture
+
+	return capture, nil
+}
+
+// FetchCapture returns the buffered payloads of the current capture and
+// clears the buffer. The capture is removed once it has finished.
+func (mg *Manager) FetchCapture() (capture *EventCapture, payloads []jsoniter.RawMessage, err error) {
+	mg.CaptureMu.Lock()
+	defer mg.CaptureMu.Unlock()
+
+	capture = mg.Capture
+	if capture == nil {
+		return nil, nil, ErrNoCapture
+	}
+
+	capture.payloadsMu.Lock()
+	payloads = capture.payloads
+	capture.payloads = make([]jsoniter.RawMessage, 0)
+	capture.payloadsMu.Unlock()
+
+	if !capture.Active(time.Now().UTC()) {
+		mg.Capture = nil
+	}
+
+	return capture, payloads, nil
+}
+
+// capturePayload adds a produced payload to the current capture if it matches.
+func (mg *Manager) capturePayload(packet *structs.SandwichPayload) {
+	mg.CaptureMu.RLock()
+	capture := mg.Capture
+	mg.CaptureMu.RUnlock()
+
+	if !capture.Active(time.Now().UTC()) || !capture.matches(packet) {
+		return
+	}
+
+	if err := capture.add(packet); err != nil {
+		mg.Logger.Warn().Err(err).Str("type", packet.Type).Msg("Failed to capture payload")
+	}
+}
+
+// payloadGuildID returns the guild a payload belongs to, or 0 if it does not
+// belong to one.
+func payloadGuildID(packet *structs.SandwichPayload) snowflake.ID {
+	return eventGuildID(packet.Type, packet.ReceivedPayload.Data)
+}
+
+// eventGuildID returns the guild the data of an event belongs to, or 0 if it
+// does not belong to one.
+func eventGuildID(eventType string, raw []byte) snowflake.ID {
+	if len(raw) == 0 {
+		return 0
+	}
+
+	key := "guild_id"
+	if strings.HasPrefix(eventType, "GUILD_") && json.Get(raw, key).ValueType() == jsoniter.InvalidValue {
+		// GUILD_CREATE, GUILD_UPDATE and GUILD_DELETE are the guild itself.
+		key = "id"
+	}
+
+	id, err := snowflake.ParseString(json.Get(raw, key).ToString())
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// channelUser is a manager and the channel it publishes to.
+type channelUser struct {
+	identifier  string
+	channel     string
+	allowShared bool
+}
+
+// channelWarnings returns a warning for each manager which publishes to the
+// same channel as a manager with a different identifier, as consumers would
+// receive both event streams mixed together. Managers with
+// messaging.allow_shared_channel set are not warned about.
+func channelWarnings(users []channelUser) (warnings []structs.ConfigurationWarning) {
+	channels := make(map[string][]string)
+
+	for _, user := range users {
+		channels[user.channel] = append(channels[user.channel], user.identifier)
+	}
+
+	for _, user := range users {
+		if user.allowShared {
+			continue
+		}
+
+		others := make([]string, 0)
+
+		for _, identifier := range channels[user.channel] {
+			if identifier != user.identifier {
+				others = append(others, identifier)
+			}
+		}
+
+		if len(others) == 0 {
+			continue
+		}
+
+		sort.Strings(others)
+
+		warnings = append(warnings, structs.ConfigurationWarning{
+			Manager: user.identifier,
+			Setting: "messaging.channel_name",
+			Message: fmt.Sprintf("channel %q is also published to by %s. Set messaging.allow_shared_channel "+
+				"if this is intentional", user.channel, strings.Join(others, ", ")),
+		})
+	}
+
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Manager < warnings[j].Manager })
+
+	return warnings
+}
+
+// managerChannelWarnings returns the channel warnings of the running
+// managers. If override is not nil, it is used in place of the manager with
+// the same identifier or added if there is none.
+func (sg *Sandwich) managerChannelWarnings(override *ManagerConfiguration) []structs.ConfigurationWarning {
+	users := make([]channelUser, 0)
+
+	sg.ManagersMu.RLock()
+	for identifier, mg := range sg.Managers {
+		if override != nil && override.Identifier == identifier {
+			continue
+		}
+
+		mg.ConfigurationMu.RLock()
+		users = append(users, channelUser{
+			identifier:  identifier,
+			channel:     strings.TrimSpace(mg.Configuration.Messaging.ChannelName),
+			allowShared: mg.Configuration.Messaging.AllowSharedChannel,
+		})
+		mg.ConfigurationMu.RUnlock()
+	}
+	sg.ManagersMu.RUnlock()
+
+	if override != nil {
+		users = append(users, channelUser{
+			identifier:  override.Identifier,
+			channel:     strings.TrimSpace(override.Messaging.ChannelName),
+			allowShared: override.Messaging.AllowSharedChannel,
+		})
+	}
+
+	return channelWarnings(users)
+}
+
+// notifyChannelCollisions logs any managers sharing a channel and sends a
+// webhook the first time each one is found.
+func (sg *Sandwich) notifyChannelCollisions() {
+	for _, warning := range sg.managerChannelWarnings(nil) {
+		key := warning.Manager + "\x00" + warning.Message
+
+		sg.channelWarningsMu.Lock()
+		_, notified := sg.channelWarnings[key]
+		sg.channelWarnings[key] = void{}
+		sg.channelWarningsMu.Unlock()
+
+		sg.Logger.Warn().Str("manager", warning.Manager).Msg(warning.Message)
+
+		if notified {
+			continue
+		}
+
+		go sg.Notify(context.Background(),
+			NewNotification("Managers are publishing to the same channel", warning.Message).
+				WithSeverity(SeverityWarning).
+				WithFooter("Manager "+warning.Manager))
+	}
+}
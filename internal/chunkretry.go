@@ -0,0 +1,277 @@
+package gateway
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Attempts made to chunk a guild before giving up if
+	// caching.chunk_retry_attempts is not set.
+	defaultChunkRetryAttempts = 5
+
+	// Delay before the first retry of a failed chunk. Each following retry
+	// waits twice as long up to maxChunkRetryDelay.
+	chunkRetryDelay    = 30 * time.Second
+	maxChunkRetryDelay = 30 * time.Minute
+)
+
+// chunkFailure tracks the failed attempts to chunk a guild.
+type chunkFailure struct {
+	shard       *Shard
+	attempts    int
+	lastError   string
+	lastAttempt time.Time
+	nextAttempt time.Time // Zero once retries are exhausted
+	timer       *time.Timer
+}
+
+// chunkRetryBackoff returns how long to wait before retrying after a number
+// of failed attempts.
+func chunkRetryBackoff(attempts int) time.Duration {
+	delay := chunkRetryDelay
+
+	for i := 1; i < attempts && delay < maxChunkRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxChunkRetryDelay {
+		delay = maxChunkRetryDelay
+	}
+
+	return delay
+}
+
+// recordChunkFailure records a failed attempt to chunk a guild and schedules
+// a retry unless caching.chunk_retry_attempts has been reached.
+func (mg *Manager) recordChunkFailure(sh *Shard, guildID snowflake.ID, err error) {
+	mg.ConfigurationMu.RLock()
+	maxAttempts := mg.Configuration.Caching.ChunkRetryAttempts
+	mg.ConfigurationMu.RUnlock()
+
+	now := time.Now().UTC()
+
+	mg.chunkFailuresMu.Lock()
+	defer mg.chunkFailuresMu.Unlock()
+
+	failure, ok := mg.chunkFailures[guildID]
+	if !ok {
+		failure = &chunkFailure{}
+		mg.chunkFailures[guildID] = failure
+	}
+
+	failure.shard = sh
+	failure.attempts++
+	failure.lastError = err.Error()
+	failure.lastAttempt = now
+
+	if failure.attempts >= maxAttempts {
+		failure.nextAttempt = time.Time{}
+
+		sh.Logger.Warn().
+			Int64("guild_id", guildID.Int64()).
+			Int("attempts", failure.attempts).
+			Msg("Giving up chunking guild")
+
+		return
+	}
+
+	delay := chunkRetryBackoff(failure.attempts)
+	failure.nextAttempt = now.Add(delay)
+	failure.timer = time.AfterFunc(delay, func() { sh.retryChunk(guildID) })
+
+	sh.Logger.Debug().
+		Int64("guild_id", guildID.Int64()).
+		Int("attempts", failure.attempts).
+		Dur("delay", delay).
+		Msg("Scheduled chunk retry")
+}
+
+// clearChunkFailure removes the failure record of a guild once it has been
+// chunked.
+func (mg *Manager) clearChunkFailure(guildID snowflake.ID) {
+	mg.chunkFailuresMu.Lock()
+	failure, ok := mg.chunkFailures[guildID]
+	delete(mg.chunkFailures, guildID)
+	mg.chunkFailuresMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if failure.timer != nil {
+		failure.timer.Stop()
+	}
+
+	mg.Logger.Info().
+		Int64("guild_id", guildID.Int64()).
+		Int("attempts", failure.attempts).
+		Msg("Chunked guild after previous failures")
+}
+
+// forgetChunkFailure drops the failure record of a guild the bot is no
+// longer in and stops its retry.
+func (mg *Manager) forgetChunkFailure(guildID snowflake.ID) {
+	mg.chunkFailuresMu.Lock()
+	failure, ok := mg.chunkFailures[guildID]
+	delete(mg.chunkFailures, guildID)
+	mg.chunkFailuresMu.Unlock()
+
+	if ok && failure.timer != nil {
+		failure.timer.Stop()
+	}
+}
+
+// retryChunk chunks a guild again using the chunk limiter of the shardgroup.
+// If the shardgroup has closed, the failure is dropped as the guild will be
+// chunked by the new shardgroup.
+func (sh *Shard) retryChunk(guildID snowflake.ID) {
+	if sh.ShardGroup.closed() {
+		sh.Manager.chunkFailuresMu.Lock()
+		delete(sh.Manager.chunkFailures, guildID)
+		sh.Manager.chunkFailuresMu.Unlock()
+
+		return
+	}
+
+	ticket := sh.ShardGroup.ChunkLimiter.Wait()
+	defer sh.ShardGroup.ChunkLimiter.FreeTicket(ticket)
+
+	if err := sh.ChunkGuild(guildID, true); err != nil {
+		sh.Logger.Debug().Err(err).Int64("guild_id", guildID.Int64()).Msg("Chunk retry failed")
+	}
+}
+
+// ChunkFailures returns the guilds which failed to chunk. Unless pending is
+// set, only guilds which have exhausted their retries are returned.
+func (mg *Manager) ChunkFailures(pending bool) (failures []structs.ChunkFailure) {
+	mg.ConfigurationMu.RLock()
+	identifier := mg.Configuration.Identifier
+	mg.ConfigurationMu.RUnlock()
+
+	mg.chunkFailuresMu.Lock()
+	defer mg.chunkFailuresMu.Unlock()
+
+	failures = make([]structs.ChunkFailure, 0)
+
+	for guildID, failure := range mg.chunkFailures {
+		if failure.shard.ShardGroup.closed() {
+			delete(mg.chunkFailures, guildID)
+
+			continue
+		}
+
+		exhausted := failure.nextAttempt.IsZero()
+		if !exhausted && !pending {
+			continue
+		}
+
+		failures = append(failures, structs.ChunkFailure{
+			Manager:     identifier,
+			ShardGroup:  failure.shard.ShardGroup.ID,
+			ShardID:     failure.shard.ShardID,
+			GuildID:     guildID,
+			Attempts:    failure.attempts,
+			LastError:   failure.lastError,
+			LastAttempt: failure.lastAttempt,
+			NextAttempt: failure.nextAttempt,
+			Exhausted:   exhausted,
+		})
+	}
+
+	sort.Slice(failures, func(i, j int) bool { return failures[i].GuildID < failures[j].GuildID })
+
+	return failures
+}
+
+// RequeueChunkFailures resets the attempts of guilds which have exhausted
+// their retries and chunks them again. If guildIDs is empty, every exhausted
+// guild is requeued. Returns the guilds which were requeued.
+func (mg *Manager) RequeueChunkFailures(guildIDs []snowflake.ID) (requeued []snowflake.ID) {
+	mg.chunkFailuresMu.Lock()
+	defer mg.chunkFailuresMu.Unlock()
+
+	if len(guildIDs) == 0 {
+		for guildID := range mg.chunkFailures {
+			guildIDs = append(guildIDs, guildID)
+		}
+	}
+
+	requeued = make([]snowflake.ID, 0)
+
+	for _, guildID := range guildIDs {
+		failure, ok := mg.chunkFailures[guildID]
+		if !ok || !failure.nextAttempt.IsZero() {
+			continue
+		}
+
+		sh := failure.shard
+		guildID := guildID
+
+		failure.attempts = 0
+		failure.nextAttempt = time.Now().UTC()
+		failure.timer = time.AfterFunc(0, func() { sh.retryChunk(guildID) })
+
+		requeued = append(requeued, guildID)
+	}
+
+	sort.Slice(requeued, func(i, j int) bool { return requeued[i] < requeued[j] })
+
+	return requeued
+}
+
+// RequestGuildChunks chunks a guild through the shard of the newest
+// shardgroup it belongs to. The shard must be ready and the guild must not be
+// unavailable.
+func (mg *Manager) RequestGuildChunks(guildID snowflake.ID,
+	wait bool) (result structs.RPCManagerGuildChunkResponse, err error) {
+	shardGroup := mg.latestShardGroup()
+	if shardGroup == nil {
+		return result, ErrNoShardGroup
+	}
+
+	shardID := int((guildID.Int64() >> 22) % int64(shardGroup.ShardCount))
+
+	shardGroup.ShardsMu.RLock()
+	sh, ok := shardGroup.Shards[shardID]
+	shardGroup.ShardsMu.RUnlock()
+
+	if !ok {
+		return result, ErrInvalidShard
+	}
+
+	sh.StatusMu.RLock()
+	status := sh.Status
+	sh.StatusMu.RUnlock()
+
+	if status != structs.ShardReady {
+		return result, xerrors.Errorf("shard %d is %s: %w", shardID, status.String(), ErrShardNotReady)
+	}
+
+	sh.UnavailableMu.RLock()
+	unavailable := sh.Unavailable[guildID]
+	sh.UnavailableMu.RUnlock()
+
+	if unavailable {
+		return result, ErrGuildUnavailable
+	}
+
+	start := time.Now().UTC()
+
+	chunks, err := sh.requestGuildChunks(guildID, wait)
+	if err != nil {
+		return result, xerrors.Errorf("request guild chunks: %w", err)
+	}
+
+	return structs.RPCManagerGuildChunkResponse{
+		ShardGroup: shardGroup.ID,
+		ShardID:    shardID,
+		Waited:     wait,
+		Chunks:     chunks,
+		Duration:   time.Now().UTC().Sub(start).Milliseconds(),
+	}, nil
+}
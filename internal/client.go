@@ -39,6 +39,9 @@ type Client struct {
 	// Will use RestTunnel if not empty
 	restTunnelURL string
 	reverse       bool
+
+	// Requests made by the client. Nil if the client is not owned by a manager.
+	stats *restStats
 }
 
 // NewClient makes a new client.
@@ -69,14 +72,27 @@ func (c *Client) Fetch(ctx context.Context, method string, url string,
 		req.Header.Set(k, v)
 	}
 
+	// HandleRequest replaces the URL when using RestTunnel.
+	route := restRoute(req.Method, req.URL.Path)
+	start := time.Now()
+
 	res, err := c.HandleRequest(req, false)
 	if err != nil {
+		if res != nil {
+			c.stats.record(route, res.StatusCode, time.Since(start))
+		} else {
+			c.stats.record(route, 0, time.Since(start))
+		}
+
 		return
 	}
 
 	defer res.Body.Close()
 
 	_body, err = ioutil.ReadAll(res.Body)
+
+	c.stats.record(route, res.StatusCode, time.Since(start))
+
 	if err != nil {
 		return nil, res.StatusCode, xerrors.Errorf("failed to read request body: %w", err)
 	}
@@ -125,11 +141,17 @@ func (c *Client) HandleRequest(req *http.Request, retry bool) (res *http.Respons
 	}
 
 	if c.restTunnelURL == "" {
+		start := time.Now()
+
 		if res, err = c.HTTP.Do(req); err != nil {
 			return res, fmt.Errorf("failed to do HTTP request: %w", err)
 		}
 
 		if res.StatusCode == http.StatusTooManyRequests {
+			// The request is retried so the rate limit would otherwise not
+			// be counted.
+			c.stats.record(restRoute(req.Method, req.URL.Path), res.StatusCode, time.Since(start))
+
 			resp := structs.TooManyRequests{}
 			err = json.NewDecoder(res.Body).Decode(&resp)
 
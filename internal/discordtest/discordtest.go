@@ -0,0 +1,298 @@
+// Package discordtest runs a fake Discord that managers can be started
+// against without a network connection. Its URL is used as a RestTunnel in
+// reverse mode so REST requests such as /gateway/bot are answered by it and
+// shards are pointed at its gateway:
+//
+//	server := discordtest.NewServer(t, &discord.Guild{ID: 1, Name: "guild"})
+//
+//	configuration.RestTunnel.Enabled = true
+//	configuration.RestTunnel.URL = server.URL
+//
+// The gateway answers IDENTIFY with READY followed by a GUILD_CREATE for each
+// guild, RESUME with RESUMED and heartbeats with an ACK.
+package discordtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	jsoniter "github.com/json-iterator/go"
+	"nhooyr.io/websocket"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	// Interval sent in HELLO. Tests finish long before a heartbeat is due.
+	heartbeatInterval = 45000
+
+	// Sessions the gateway allows to be started.
+	sessionStartLimit = 1000
+
+	// How long a write to a shard can take.
+	writeTimeout = 5 * time.Second
+)
+
+// Server is a fake Discord REST API and gateway.
+type Server struct {
+	// URL to use as the RestTunnel URL.
+	URL string
+
+	server *httptest.Server
+	guilds []*discord.Guild
+
+	connsMu sync.Mutex
+	conns   map[*conn]struct{}
+
+	connections *int64
+	identifies  *int64
+	resumes     *int64
+}
+
+// conn is a shard connected to the gateway.
+type conn struct {
+	ws *websocket.Conn
+
+	// Held whilst writing so sequences are sent in order.
+	writeMu sync.Mutex
+	seq     int64
+}
+
+// NewServer starts a Server which sends a GUILD_CREATE for each guild when a
+// shard identifies. It is closed when the test finishes.
+func NewServer(t testing.TB, guilds ...*discord.Guild) *Server {
+	t.Helper()
+
+	s := &Server{
+		guilds:      guilds,
+		conns:       make(map[*conn]struct{}),
+		connections: new(int64),
+		identifies:  new(int64),
+		resumes:     new(int64),
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// GatewayURL returns the URL /gateway/bot points shards at.
+func (s *Server) GatewayURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + "/gateway"
+}
+
+// Connections returns how many times a shard has connected to the gateway.
+func (s *Server) Connections() int64 {
+	return atomic.LoadInt64(s.connections)
+}
+
+// Identifies returns how many IDENTIFY payloads the gateway has received.
+func (s *Server) Identifies() int64 {
+	return atomic.LoadInt64(s.identifies)
+}
+
+// Resumes returns how many RESUME payloads the gateway has received.
+func (s *Server) Resumes() int64 {
+	return atomic.LoadInt64(s.resumes)
+}
+
+// Dispatch sends an event to every connected shard and returns how many it
+// was sent to.
+func (s *Server) Dispatch(eventType string, data interface{}) (sent int, err error) {
+	s.connsMu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.connsMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	for _, c := range conns {
+		if err = c.dispatch(ctx, eventType, data); err != nil {
+			return sent, fmt.Errorf("dispatch %s: %w", eventType, err)
+		}
+
+		sent++
+	}
+
+	return sent, nil
+}
+
+// Close disconnects every shard and stops the server.
+func (s *Server) Close() {
+	s.connsMu.Lock()
+	for c := range s.conns {
+		_ = c.ws.Close(websocket.StatusGoingAway, "")
+	}
+	s.connsMu.Unlock()
+
+	s.server.Close()
+}
+
+func (s *Server) handle(rw http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.EqualFold(r.Header.Get("Upgrade"), "websocket"):
+		s.serveGateway(rw, r)
+	case r.URL.Path == "/resttunnel":
+		alive := map[string]interface{}{
+			"success": true,
+			"data":    map[string]interface{}{"name": "discordtest", "version": "0", "reverse": true},
+		}
+
+		writeJSON(rw, http.StatusOK, alive)
+	case strings.HasSuffix(r.URL.Path, "/gateway/bot"):
+		gateway := discord.GatewayBot{URL: s.GatewayURL(), Shards: 1}
+		gateway.SessionStartLimit.Total = sessionStartLimit
+		gateway.SessionStartLimit.Remaining = sessionStartLimit
+		gateway.SessionStartLimit.MaxConcurrency = 1
+
+		writeJSON(rw, http.StatusOK, gateway)
+	default:
+		writeJSON(rw, http.StatusNotFound, map[string]interface{}{"message": "404: Not Found", "code": 0})
+	}
+}
+
+func writeJSON(rw http.ResponseWriter, status int, value interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+
+	_ = json.NewEncoder(rw).Encode(value)
+}
+
+func (s *Server) serveGateway(rw http.ResponseWriter, r *http.Request) {
+	ws, err := websocket.Accept(rw, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &conn{ws: ws}
+
+	atomic.AddInt64(s.connections, 1)
+
+	s.connsMu.Lock()
+	s.conns[c] = struct{}{}
+	s.connsMu.Unlock()
+
+	defer func() {
+		s.connsMu.Lock()
+		delete(s.conns, c)
+		s.connsMu.Unlock()
+
+		_ = ws.Close(websocket.StatusNormalClosure, "")
+	}()
+
+	ctx := r.Context()
+
+	if c.send(ctx, discord.GatewayOpHello, map[string]int{"heartbeat_interval": heartbeatInterval}) != nil {
+		return
+	}
+
+	for {
+		_, data, err := ws.Read(ctx)
+		if err != nil {
+			return
+		}
+
+		payload := discord.ReceivedPayload{}
+		if json.Unmarshal(data, &payload) != nil {
+			continue
+		}
+
+		if s.reply(ctx, c, payload) != nil {
+			return
+		}
+	}
+}
+
+// reply responds to a payload sent by a shard.
+func (s *Server) reply(ctx context.Context, c *conn, payload discord.ReceivedPayload) error {
+	switch payload.Op {
+	case discord.GatewayOpIdentify:
+		session := atomic.AddInt64(s.identifies, 1)
+
+		ready := discord.Ready{
+			Version:          9,
+			User:             &discord.User{ID: 1, Username: "discordtest", Bot: true},
+			Guilds:           make([]*discord.Guild, 0, len(s.guilds)),
+			SessionID:        fmt.Sprintf("session-%d", session),
+			ResumeGatewayURL: s.GatewayURL(),
+		}
+
+		for _, guild := range s.guilds {
+			ready.Guilds = append(ready.Guilds, &discord.Guild{ID: guild.ID, Unavailable: true})
+		}
+
+		if err := c.dispatch(ctx, "READY", ready); err != nil {
+			return err
+		}
+
+		for _, guild := range s.guilds {
+			if err := c.dispatch(ctx, "GUILD_CREATE", guild); err != nil {
+				return err
+			}
+		}
+	case discord.GatewayOpResume:
+		atomic.AddInt64(s.resumes, 1)
+
+		resume := discord.Resume{}
+		if err := json.Unmarshal(payload.Data, &resume); err != nil {
+			return err
+		}
+
+		c.writeMu.Lock()
+		c.seq = resume.Sequence
+		c.writeMu.Unlock()
+
+		return c.dispatch(ctx, "RESUMED", nil)
+	case discord.GatewayOpHeartbeat:
+		return c.send(ctx, discord.GatewayOpHeartbeatACK, nil)
+	}
+
+	return nil
+}
+
+// send writes a payload which is not a dispatch.
+func (c *conn) send(ctx context.Context, op discord.GatewayOp, data interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.write(ctx, map[string]interface{}{"op": op, "d": data})
+}
+
+// dispatch writes an event with the next sequence.
+func (c *conn) dispatch(ctx context.Context, eventType string, data interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.seq++
+
+	return c.write(ctx, map[string]interface{}{
+		"op": discord.GatewayOpDispatch,
+		"t":  eventType,
+		"s":  c.seq,
+		"d":  data,
+	})
+}
+
+func (c *conn) write(ctx context.Context, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return c.ws.Write(ctx, websocket.MessageText, data)
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"github.com/vmihailenco/msgpack"
+	"golang.org/x/xerrors"
+)
+
+// Encodings consumer payloads can be serialized with. Set with
+// messaging.encoding.
+const (
+	encodingMsgpack = "msgpack"
+	encodingJSON    = "json"
+)
+
+// Compressions payloads can be published with. Set with
+// messaging.compression.
+const (
+	compressionBrotli = "brotli"
+	compressionNone   = "none"
+)
+
+// Headers sent with each payload by producers which support headers so
+// consumers can tell how to read it.
+const (
+	encodingHeader    = "Sandwich-Encoding"
+	compressionHeader = "Sandwich-Compression"
+)
+
+// payloadEncoding returns the encoding to use for a messaging.encoding value.
+// Configurations updated over RPC are not normalized so anything other than
+// json falls back to msgpack.
+func payloadEncoding(encoding string) string {
+	if encoding == encodingJSON {
+		return encodingJSON
+	}
+
+	return encodingMsgpack
+}
+
+// validPayloadEncoding returns if a messaging.encoding value is supported.
+func validPayloadEncoding(encoding string) bool {
+	return encoding == "" || encoding == encodingMsgpack || encoding == encodingJSON
+}
+
+// marshalPayload serializes a payload for consumers with an encoding.
+func marshalPayload(encoding string, packet *structs.SandwichPayload) (data []byte, err error) {
+	switch payloadEncoding(encoding) {
+	case encodingJSON:
+		data, err = json.Marshal(packet)
+	default:
+		data, err = msgpack.Marshal(packet)
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("marshal %s payload: %w", payloadEncoding(encoding), err)
+	}
+
+	return data, nil
+}
+
+// validPayloadCompression returns if a messaging.compression value is
+// supported.
+func validPayloadCompression(compression string) bool {
+	return compression == "" || compression == compressionBrotli || compression == compressionNone
+}
+
+// payloadCompression returns how a dispatch of a size is compressed.
+// Dispatches are compressed with brotli unless messaging.compression is none
+// or they are smaller than messaging.compression_threshold. ConfigurationMu
+// of the manager must be held.
+func (mg *Manager) payloadCompression(size int) string {
+	if mg.Configuration.Messaging.Compression == compressionNone ||
+		size < mg.Configuration.Messaging.CompressionThreshold {
+		return compressionNone
+	}
+
+	return compressionBrotli
+}
+
+// payloadMessage returns the properties a payload of the manager is published
+// with. ConfigurationMu of the manager must be held.
+func (mg *Manager) payloadMessage(key []byte, compression string) mqclients.Message {
+	return mqclients.Message{
+		Key: key,
+		Headers: map[string]string{
+			encodingHeader:    payloadEncoding(mg.Configuration.Messaging.Encoding),
+			compressionHeader: compression,
+		},
+	}
+}
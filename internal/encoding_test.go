@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/vmihailenco/msgpack"
+)
+
+// consumerPayload is how a consumer reads a SandwichPayload. Data and the
+// before extra are set to new values of the type expected before decoding.
+type consumerPayload struct {
+	Op       discord.GatewayOp        `json:"op" msgpack:"op"`
+	Sequence int64                    `json:"s,omitempty" msgpack:"s,omitempty"`
+	Type     string                   `json:"t,omitempty" msgpack:"t,omitempty"`
+	Data     interface{}              `json:"d,omitempty" msgpack:"d,omitempty"`
+	Extra    consumerExtra            `json:"e,omitempty" msgpack:"e,omitempty"`
+	Metadata structs.SandwichMetadata `json:"__sandwich" msgpack:"__sandwich"`
+	Trace    map[string]int           `json:"__trace,omitempty" msgpack:"__trace,omitempty"`
+}
+
+type consumerExtra struct {
+	Before interface{} `json:"before,omitempty" msgpack:"before,omitempty"`
+}
+
+// decodePayload decodes data of an encoding the way a consumer expecting
+// the types in want would.
+func decodePayload(t *testing.T, encoding string, data []byte, want *structs.SandwichPayload) (payload consumerPayload) {
+	t.Helper()
+
+	payload.Data = reflect.New(reflect.TypeOf(want.Data).Elem()).Interface()
+
+	if before, ok := want.Extra["before"]; ok {
+		payload.Extra.Before = reflect.New(reflect.TypeOf(before).Elem()).Interface()
+	}
+
+	var err error
+
+	switch encoding {
+	case encodingJSON:
+		err = json.Unmarshal(data, &payload)
+	default:
+		err = msgpack.Unmarshal(data, &payload)
+	}
+
+	if err != nil {
+		t.Fatalf("failed to decode %s payload: %v", encoding, err)
+	}
+
+	return payload
+}
+
+func TestPayloadEncodingsEquivalent(t *testing.T) {
+	user := &discord.User{ID: 104, Username: "user", Discriminator: "0001", Bot: true}
+
+	packets := []*structs.SandwichPayload{
+		{
+			ReceivedPayload: discord.ReceivedPayload{Op: discord.GatewayOpDispatch, Type: "CHANNEL_UPDATE", Sequence: 12},
+			Data: &discord.Channel{
+				ID:      testChannelID,
+				GuildID: testGuildID,
+				Name:    "after",
+				Topic:   "ünïcode topic ✨",
+				NSFW:    true,
+				PermissionOverwrites: []discord.ChannelOverwrite{
+					{ID: "102", Type: "role", Allow: 1024, Deny: 2048},
+				},
+			},
+			Extra: map[string]interface{}{
+				"before": &discord.Channel{ID: testChannelID, GuildID: testGuildID, Name: "before"},
+			},
+			Metadata: structs.SandwichMetadata{
+				Version:    VERSION,
+				Identifier: "test",
+				GuildID:    int64(testGuildID),
+				Shard:      [3]int{0, 1, 2},
+				EventID:    1 << 40,
+				Affinity:   "eu",
+				Ack:        true,
+			},
+			Trace: map[string]int{"read": 1, "publish": 3},
+		},
+		{
+			ReceivedPayload: discord.ReceivedPayload{Op: discord.GatewayOpDispatch, Type: "GUILD_MEMBER_ADD"},
+			Data: &discord.GuildMember{
+				User:     user,
+				Nick:     "nick",
+				Roles:    []snowflake.ID{testRoleID, 1 << 62},
+				JoinedAt: "2021-01-01T00:00:00Z",
+				Mute:     true,
+			},
+			Metadata: structs.SandwichMetadata{Version: VERSION, Identifier: "test", Unhandled: true},
+		},
+	}
+
+	for _, packet := range packets {
+		decoded := make(map[string]consumerPayload)
+
+		for _, encoding := range []string{encodingMsgpack, encodingJSON} {
+			data, err := marshalPayload(encoding, packet)
+			if err != nil {
+				t.Fatalf("%s: failed to marshal %s: %v", packet.Type, encoding, err)
+			}
+
+			decoded[encoding] = decodePayload(t, encoding, data, packet)
+		}
+
+		if !reflect.DeepEqual(decoded[encodingMsgpack], decoded[encodingJSON]) {
+			t.Errorf("%s: msgpack decoded to %+v, json to %+v",
+				packet.Type, decoded[encodingMsgpack], decoded[encodingJSON])
+		}
+
+		got := decoded[encodingJSON]
+		if !reflect.DeepEqual(got.Data, packet.Data) || got.Metadata != packet.Metadata ||
+			got.Op != packet.Op || got.Type != packet.Type || got.Sequence != packet.Sequence {
+			t.Errorf("%s: payload decoded to %+v", packet.Type, got)
+		}
+
+		if before, ok := packet.Extra["before"]; ok && !reflect.DeepEqual(got.Extra.Before, before) {
+			t.Errorf("%s: before decoded to %+v", packet.Type, got.Extra.Before)
+		}
+	}
+}
+
+func TestPayloadEncodingFallback(t *testing.T) {
+	packet := &structs.SandwichPayload{
+		ReceivedPayload: discord.ReceivedPayload{Op: discord.GatewayOpDispatch, Type: "READY"},
+	}
+
+	msgpackData, err := marshalPayload(encodingMsgpack, packet)
+	if err != nil {
+		t.Fatalf("failed to marshal msgpack: %v", err)
+	}
+
+	for _, encoding := range []string{"", "unknown"} {
+		data, err := marshalPayload(encoding, packet)
+		if err != nil {
+			t.Fatalf("failed to marshal %q: %v", encoding, err)
+		}
+
+		if !reflect.DeepEqual(data, msgpackData) {
+			t.Errorf("encoding %q was not msgpack", encoding)
+		}
+	}
+}
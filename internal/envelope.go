@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"fmt"
+	"io"
+
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/xerrors"
+)
+
+// Kinds of failure decoding the data of an event.
+const (
+	decodeErrorSyntax = "syntax" // The data is not valid JSON
+	decodeErrorType   = "type"   // A field does not have the type expected
+)
+
+// ErrEnvelopeNoOp is returned when a gateway payload does not have an op.
+var ErrEnvelopeNoOp = xerrors.New("payload has no op")
+
+// eventDecodeError is returned when the data of an event cannot be decoded
+// into the structure its handler expects.
+type eventDecodeError struct {
+	kind string
+	err  error
+}
+
+func (e *eventDecodeError) Error() string {
+	return fmt.Sprintf("decode %s: %s", e.kind, e.err)
+}
+
+func (e *eventDecodeError) Unwrap() error {
+	return e.err
+}
+
+// parseEnvelope reads the op, sequence and type of a gateway payload and
+// keeps the data as raw bytes. Fields with an unexpected type are left empty
+// and unknown fields are skipped, so only a payload which is not valid JSON
+// or has no op fails. The data is decoded by the handler of the event.
+func parseEnvelope(buf []byte, msg *discord.ReceivedPayload) (err error) {
+	iter := json.BorrowIterator(buf)
+	defer json.ReturnIterator(iter)
+
+	hasOp := false
+
+	for field := iter.ReadObject(); field != ""; field = iter.ReadObject() {
+		next := iter.WhatIsNext()
+
+		switch {
+		case field == "op" && next == jsoniter.NumberValue:
+			msg.Op = discord.GatewayOp(iter.ReadUint8())
+			hasOp = true
+		case field == "s" && next == jsoniter.NumberValue:
+			msg.Sequence = iter.ReadInt64()
+		case field == "t" && next == jsoniter.StringValue:
+			msg.Type = iter.ReadString()
+		case field == "d" && next != jsoniter.NilValue:
+			msg.Data = iter.SkipAndReturnBytes()
+		default:
+			iter.Skip()
+		}
+	}
+
+	if iter.Error != nil && iter.Error != io.EOF {
+		return xerrors.Errorf("parse envelope: %w", iter.Error)
+	}
+
+	if !hasOp {
+		return ErrEnvelopeNoOp
+	}
+
+	return nil
+}
+
+// decodeEvent decodes the data of an event into out. Failures are returned
+// as an eventDecodeError so they are counted by their kind.
+func decodeEvent(msg discord.ReceivedPayload, out interface{}) (err error) {
+	err = json.Unmarshal(msg.Data, out)
+	if err == nil {
+		return nil
+	}
+
+	kind := decodeErrorType
+	if !json.Valid(msg.Data) {
+		kind = decodeErrorSyntax
+	}
+
+	return &eventDecodeError{kind: kind, err: err}
+}
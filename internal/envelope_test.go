@@ -0,0 +1,202 @@
+package gateway
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+// Payloads captured from the gateway with the IDs replaced. Guild 100 is the
+// guild newDiscordManager is started with.
+const (
+	capturedMessageCreate = `{"id":"300","channel_id":"200","guild_id":"100",` +
+		`"author":{"id":"400","username":"user","discriminator":"0001","avatar":null},` +
+		`"member":{"roles":[],"joined_at":"2021-01-01T00:00:00.000000+00:00","deaf":false,"mute":false},` +
+		`"content":"hello","timestamp":"2021-01-01T00:00:00.000000+00:00","edited_timestamp":null,` +
+		`"tts":false,"mention_everyone":false,"mentions":[],"mention_roles":[],"attachments":[],` +
+		`"embeds":[],"pinned":false,"type":0,"flags":0,"nonce":"1"}`
+	capturedGuildUpdate = `{"id":"100","name":"renamed","icon":null,"owner_id":"400",` +
+		`"verification_level":0,"default_message_notifications":0,"explicit_content_filter":0,` +
+		`"roles":[],"emojis":[],"features":[],"mfa_level":0,"system_channel_flags":0,` +
+		`"premium_tier":0,"preferred_locale":"en-US","nsfw_level":0}`
+	capturedGuildMemberAdd = `{"guild_id":"100","user":{"id":"401","username":"joined",` +
+		`"discriminator":"0002","avatar":null},"roles":["100"],` +
+		`"joined_at":"2021-01-01T00:00:00.000000+00:00","deaf":false,"mute":false,"pending":false}`
+	capturedChannelUpdate = `{"id":"200","type":0,"guild_id":"100","name":"general","position":1,` +
+		`"permission_overwrites":[],"nsfw":false,"parent_id":null,"topic":null,` +
+		`"last_message_id":"300","rate_limit_per_user":0}`
+)
+
+// injectField adds a field to a captured payload, replacing it if it exists.
+func injectField(t *testing.T, payload string, field string, value interface{}) []byte {
+	t.Helper()
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(payload), &fields); err != nil {
+		t.Fatalf("captured payload is not valid json: %v", err)
+	}
+
+	fields[field] = value
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+
+	return data
+}
+
+func TestParseEnvelopeInjectedFields(t *testing.T) {
+	data := `"d":` + capturedMessageCreate
+
+	tests := []struct {
+		name    string
+		frame   string
+		err     error
+		op      discord.GatewayOp
+		seq     int64
+		typ     string
+		hasData bool
+	}{
+		{"captured", `{"t":"MESSAGE_CREATE","s":5,"op":0,` + data + `}`, nil, 0, 5, "MESSAGE_CREATE", true},
+		{"unknown field", `{"t":"MESSAGE_CREATE","s":5,"op":0,"shard":[0,1],"x":{"y":[1,{"z":null}]},` + data + `}`,
+			nil, 0, 5, "MESSAGE_CREATE", true},
+		{"string sequence", `{"t":"MESSAGE_CREATE","s":"5","op":0,` + data + `}`, nil, 0, 0, "MESSAGE_CREATE", true},
+		{"numeric type", `{"t":5,"s":5,"op":0,` + data + `}`, nil, 0, 5, "", true},
+		{"null data", `{"t":null,"s":null,"op":11,"d":null}`, nil, discord.GatewayOpHeartbeatACK, 0, "", false},
+		{"string op", `{"t":"MESSAGE_CREATE","s":5,"op":"0",` + data + `}`, ErrEnvelopeNoOp, 0, 5, "MESSAGE_CREATE", true},
+		{"no op", `{"t":"MESSAGE_CREATE","s":5,` + data + `}`, ErrEnvelopeNoOp, 0, 5, "MESSAGE_CREATE", true},
+	}
+
+	for _, test := range tests {
+		msg := discord.ReceivedPayload{}
+
+		err := parseEnvelope([]byte(test.frame), &msg)
+		if !xerrors.Is(err, test.err) {
+			t.Errorf("%s: returned %v, want %v", test.name, err, test.err)
+		}
+
+		if msg.Op != test.op || msg.Sequence != test.seq || msg.Type != test.typ {
+			t.Errorf("%s: parsed op %d, s %d, t %q", test.name, msg.Op, msg.Sequence, msg.Type)
+		}
+
+		if hasData := len(msg.Data) > 0; hasData != test.hasData {
+			t.Errorf("%s: parsed data %s", test.name, msg.Data)
+		} else if hasData && string(msg.Data) != capturedMessageCreate {
+			t.Errorf("%s: data was changed to %s", test.name, msg.Data)
+		}
+	}
+
+	if err := parseEnvelope([]byte(`{"op":0,"d":{`), &discord.ReceivedPayload{}); err == nil ||
+		xerrors.Is(err, ErrEnvelopeNoOp) {
+		t.Errorf("truncated payload returned %v", err)
+	}
+}
+
+// producedEvent is a payload seen by an event hook.
+type producedEvent struct {
+	eventType string
+	raw       bool // Published with the data discord sent as state failed to decode it
+}
+
+func TestDispatchInjectedFields(t *testing.T) {
+	mg, fake := newDiscordManager(t, &discord.Guild{ID: testGuildID, Name: "guild"})
+	sh := onlyShard(t, mg)
+
+	produced := make(chan producedEvent, 16)
+
+	remove := mg.Sandwich.AddEventHook(func(ctx context.Context, packet *structs.SandwichPayload) error {
+		// Status updates are produced too whilst the shard settles.
+		if packet.Type == "SHARD_STATUS" {
+			return nil
+		}
+
+		_, raw := packet.Data.(stdjson.RawMessage)
+		produced <- producedEvent{eventType: packet.Type, raw: raw}
+
+		return nil
+	})
+	defer remove()
+
+	conn, generation := sh.ws.Get()
+
+	tests := []struct {
+		name      string
+		eventType string
+		data      []byte
+		decodeErr bool
+	}{
+		{"message", "MESSAGE_CREATE", []byte(capturedMessageCreate), false},
+		{"message unknown field", "MESSAGE_CREATE",
+			injectField(t, capturedMessageCreate, "poll", map[string]interface{}{"question": "?"}), false},
+		{"message wrong content", "MESSAGE_CREATE",
+			injectField(t, capturedMessageCreate, "content", []string{"hello"}), true},
+		{"message wrong author", "MESSAGE_CREATE", injectField(t, capturedMessageCreate, "author", "400"), true},
+		{"guild unknown field", "GUILD_UPDATE",
+			injectField(t, capturedGuildUpdate, "incidents_data", map[string]interface{}{"raid_detected_at": nil}), false},
+		{"guild wrong name", "GUILD_UPDATE", injectField(t, capturedGuildUpdate, "name", 5), true},
+		{"guild wrong roles", "GUILD_UPDATE", injectField(t, capturedGuildUpdate, "roles", map[string]int{"a": 1}), true},
+		{"member unknown field", "GUILD_MEMBER_ADD", injectField(t, capturedGuildMemberAdd, "flags", 0), false},
+		{"member wrong roles", "GUILD_MEMBER_ADD", injectField(t, capturedGuildMemberAdd, "roles", "100"), true},
+		{"channel unknown field", "CHANNEL_UPDATE", injectField(t, capturedChannelUpdate, "icon_emoji", nil), false},
+		{"channel wrong position", "CHANNEL_UPDATE", injectField(t, capturedChannelUpdate, "position", "1"), true},
+	}
+
+	for _, test := range tests {
+		before := decodeErrors(mg, test.eventType)
+
+		if _, err := fake.Dispatch(test.eventType, stdjson.RawMessage(test.data)); err != nil {
+			t.Fatalf("%s: failed to dispatch: %v", test.name, err)
+		}
+
+		select {
+		case event := <-produced:
+			if event.eventType != test.eventType || event.raw != test.decodeErr {
+				t.Errorf("%s: produced %+v", test.name, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: event was not produced", test.name)
+		}
+
+		if counted := decodeErrors(mg, test.eventType) - before; (counted > 0) != test.decodeErr {
+			t.Errorf("%s: counted %d decode errors", test.name, counted)
+		}
+	}
+
+	// The state was updated from the payload with an unknown field.
+	guild, ok := mg.Sandwich.State.GetGuild(&StateCtx{Sg: mg.Sandwich, Mg: mg, Sh: sh}, testGuildID, false)
+	if !ok || guild.Name != "renamed" {
+		t.Errorf("guild in state is %+v", guild)
+	}
+
+	if current, currentGeneration := sh.ws.Get(); current != conn || currentGeneration != generation {
+		t.Errorf("shard connection changed from generation %d to %d", generation, currentGeneration)
+	}
+
+	sh.StatusMu.RLock()
+	status := sh.Status
+	sh.StatusMu.RUnlock()
+
+	if status != structs.ShardReady {
+		t.Errorf("shard is %s", status.String())
+	}
+}
+
+// decodeErrors returns how many times the data of an event could not be
+// decoded by its state handler.
+func decodeErrors(mg *Manager, eventType string) (count int64) {
+	for _, counter := range mg.EventErrors() {
+		if counter.Type == eventType && counter.Stage == eventStageState {
+			for _, kindCount := range counter.DecodeErrors {
+				count += kindCount
+			}
+		}
+	}
+
+	return count
+}
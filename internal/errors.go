@@ -9,6 +9,26 @@ var ErrSessionLimitExhausted = errors.New("the session limit has been reached")
 // ErrInvalidToken is returned when an invalid token is used.
 var ErrInvalidToken = errors.New("token passed is not valid")
 
+// ErrInvalidTransition is returned when a shard status change is not allowed
+// by the shard state machine.
+var ErrInvalidTransition = errors.New("invalid shard status transition")
+
+// ErrShutdownTimeout is returned by Shutdown when dispatches were still being
+// handled once shutdown_timeout had passed.
+var ErrShutdownTimeout = errors.New("timed out draining dispatches")
+
+// ErrProducerUnavailable is returned when publishing whilst the manager has
+// no producer client.
+var ErrProducerUnavailable = errors.New("producer is not connected")
+
+// ErrDangerousOp is returned when sending a raw payload with an op which
+// would change the state of the shard without allow_dangerous.
+var ErrDangerousOp = errors.New("op changes the state of the shard and requires allow_dangerous")
+
+// ErrDuplicateWorkerID is returned when creating a manager with the same
+// messaging.worker_id as another manager.
+var ErrDuplicateWorkerID = errors.New("worker id is used by another manager")
+
 // ErrReconnect is used to distinguish if the shard simply wants to reconnect.
 var ErrReconnect = errors.New("reconnect is required")
 
@@ -17,4 +37,6 @@ var (
 	ErrInvalidShardGroup = errors.New("invalid shard group id specified")
 	ErrInvalidShard      = errors.New("invalid shard id specified")
 	ErrChunkTimeout      = errors.New("timed out on initial member chunks")
+	ErrShardNotReady     = errors.New("shard is not ready")
+	ErrGuildUnavailable  = errors.New("guild is unavailable")
 )
@@ -0,0 +1,657 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"net/url"
+	"strconv"
+
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/xerrors"
+)
+
+// Gateway encodings a manager can use.
+const (
+	EncodingJSON = "json"
+	EncodingETF  = "etf"
+)
+
+// External term format tags used by the gateway.
+const (
+	etfVersion       = 131
+	etfNewFloat      = 70
+	etfSmallInteger  = 97
+	etfInteger       = 98
+	etfFloat         = 99
+	etfAtom          = 100
+	etfSmallTuple    = 104
+	etfLargeTuple    = 105
+	etfNil           = 106
+	etfString        = 107
+	etfList          = 108
+	etfBinary        = 109
+	etfSmallBig      = 110
+	etfLargeBig      = 111
+	etfSmallAtom     = 115
+	etfMap           = 116
+	etfAtomUTF8      = 118
+	etfSmallAtomUTF8 = 119
+)
+
+// Integers larger than this lose precision as JSON numbers so they are
+// written as strings. Snowflakes are always this large and are strings in
+// the JSON the gateway sends, so handlers decode them the same either way.
+const etfMaxSafeInteger = 1<<53 - 1
+
+var (
+	// ErrETFVersion is returned when a payload does not start with the
+	// external term format version.
+	ErrETFVersion = xerrors.New("etf: missing version byte")
+
+	// ErrETFTruncated is returned when a payload ends part way through a term.
+	ErrETFTruncated = xerrors.New("etf: unexpected end of term")
+)
+
+// gatewayEncoding returns the encoding shards of the manager connect with.
+func (mg *Manager) gatewayEncoding() string {
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	return mg.Configuration.Bot.Encoding
+}
+
+// gatewayDialURL adds the encoding and transport compression parameters to
+// a gateway URL.
+func (mg *Manager) gatewayDialURL(gatewayURL string) (string, error) {
+	u, err := url.Parse(gatewayURL)
+	if err != nil {
+		return gatewayURL, xerrors.Errorf("gateway dial url: %w", err)
+	}
+
+	query := u.Query()
+
+	if encoding := mg.gatewayEncoding(); encoding != "" {
+		query.Set("encoding", encoding)
+	}
+
+	if mg.transportCompression() {
+		query.Set("compress", "zlib-stream")
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// etfDecoder transcodes external term format into JSON.
+type etfDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *etfDecoder) take(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, ErrETFTruncated
+	}
+
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+
+	return b, nil
+}
+
+func (d *etfDecoder) uint8() (int, error) {
+	b, err := d.take(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(b[0]), nil
+}
+
+func (d *etfDecoder) uint16() (int, error) {
+	b, err := d.take(2)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(binary.BigEndian.Uint16(b)), nil
+}
+
+func (d *etfDecoder) uint32() (int, error) {
+	b, err := d.take(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(binary.BigEndian.Uint32(b)), nil
+}
+
+// atom reads the name of an atom with the tag already read.
+func (d *etfDecoder) atom(tag int) (string, error) {
+	var (
+		n   int
+		err error
+	)
+
+	if tag == etfSmallAtom || tag == etfSmallAtomUTF8 {
+		n, err = d.uint8()
+	} else {
+		n, err = d.uint16()
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	b, err := d.take(n)
+
+	return string(b), err
+}
+
+// bigInt reads a big integer with the tag already read. Digits are little
+// endian.
+func (d *etfDecoder) bigInt(tag int) (*big.Int, error) {
+	var (
+		n   int
+		err error
+	)
+
+	if tag == etfSmallBig {
+		n, err = d.uint8()
+	} else {
+		n, err = d.uint32()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	sign, err := d.uint8()
+	if err != nil {
+		return nil, err
+	}
+
+	digits, err := d.take(n)
+	if err != nil {
+		return nil, err
+	}
+
+	reversed := make([]byte, n)
+	for i, digit := range digits {
+		reversed[n-1-i] = digit
+	}
+
+	value := new(big.Int).SetBytes(reversed)
+	if sign != 0 {
+		value.Neg(value)
+	}
+
+	return value, nil
+}
+
+// key writes a term used as a map key as a JSON object key.
+func (d *etfDecoder) key(stream *jsoniter.Stream) error {
+	tag, err := d.uint8()
+	if err != nil {
+		return err
+	}
+
+	switch tag {
+	case etfAtom, etfSmallAtom, etfAtomUTF8, etfSmallAtomUTF8:
+		name, err := d.atom(tag)
+		if err != nil {
+			return err
+		}
+
+		stream.WriteObjectField(name)
+	case etfBinary:
+		n, err := d.uint32()
+		if err != nil {
+			return err
+		}
+
+		b, err := d.take(n)
+		if err != nil {
+			return err
+		}
+
+		stream.WriteObjectField(string(b))
+	default:
+		// Keys of any other type are written as their JSON value.
+		d.pos--
+
+		var key bytes.Buffer
+
+		keyStream := json.BorrowStream(&key)
+		err = d.term(keyStream)
+		_ = keyStream.Flush()
+		json.ReturnStream(keyStream)
+
+		if err != nil {
+			return err
+		}
+
+		stream.WriteObjectField(key.String())
+	}
+
+	return nil
+}
+
+// term writes the next term as JSON.
+func (d *etfDecoder) term(stream *jsoniter.Stream) (err error) {
+	tag, err := d.uint8()
+	if err != nil {
+		return err
+	}
+
+	switch tag {
+	case etfSmallInteger:
+		n, err := d.uint8()
+		if err != nil {
+			return err
+		}
+
+		stream.WriteInt(n)
+	case etfInteger:
+		b, err := d.take(4)
+		if err != nil {
+			return err
+		}
+
+		stream.WriteInt32(int32(binary.BigEndian.Uint32(b)))
+	case etfNewFloat:
+		b, err := d.take(8)
+		if err != nil {
+			return err
+		}
+
+		stream.WriteFloat64(math.Float64frombits(binary.BigEndian.Uint64(b)))
+	case etfFloat:
+		b, err := d.take(31)
+		if err != nil {
+			return err
+		}
+
+		f, err := strconv.ParseFloat(string(bytes.TrimRight(b, "\x00")), 64)
+		if err != nil {
+			return xerrors.Errorf("etf float: %w", err)
+		}
+
+		stream.WriteFloat64(f)
+	case etfSmallBig, etfLargeBig:
+		value, err := d.bigInt(tag)
+		if err != nil {
+			return err
+		}
+
+		if value.IsInt64() && value.Int64() <= etfMaxSafeInteger && value.Int64() >= -etfMaxSafeInteger {
+			stream.WriteInt64(value.Int64())
+		} else {
+			stream.WriteString(value.String())
+		}
+	case etfAtom, etfSmallAtom, etfAtomUTF8, etfSmallAtomUTF8:
+		name, err := d.atom(tag)
+		if err != nil {
+			return err
+		}
+
+		switch name {
+		case "nil", "null":
+			stream.WriteNil()
+		case "true":
+			stream.WriteTrue()
+		case "false":
+			stream.WriteFalse()
+		default:
+			stream.WriteString(name)
+		}
+	case etfBinary:
+		n, err := d.uint32()
+		if err != nil {
+			return err
+		}
+
+		b, err := d.take(n)
+		if err != nil {
+			return err
+		}
+
+		stream.WriteString(string(b))
+	case etfString:
+		// Lists of small integers are sent as strings of bytes.
+		n, err := d.uint16()
+		if err != nil {
+			return err
+		}
+
+		b, err := d.take(n)
+		if err != nil {
+			return err
+		}
+
+		stream.WriteArrayStart()
+
+		for i, c := range b {
+			if i > 0 {
+				stream.WriteMore()
+			}
+
+			stream.WriteInt(int(c))
+		}
+
+		stream.WriteArrayEnd()
+	case etfNil:
+		stream.WriteEmptyArray()
+	case etfList, etfSmallTuple, etfLargeTuple:
+		var n int
+
+		switch tag {
+		case etfSmallTuple:
+			n, err = d.uint8()
+		default:
+			n, err = d.uint32()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		stream.WriteArrayStart()
+
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				stream.WriteMore()
+			}
+
+			if err = d.term(stream); err != nil {
+				return err
+			}
+		}
+
+		stream.WriteArrayEnd()
+
+		// Proper lists end with an empty list which is not an element.
+		if tag == etfList {
+			if tail, err := d.uint8(); err != nil || tail != etfNil {
+				return xerrors.New("etf: improper lists are not supported")
+			}
+		}
+	case etfMap:
+		n, err := d.uint32()
+		if err != nil {
+			return err
+		}
+
+		stream.WriteObjectStart()
+
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				stream.WriteMore()
+			}
+
+			if err = d.key(stream); err != nil {
+				return err
+			}
+
+			if err = d.term(stream); err != nil {
+				return err
+			}
+		}
+
+		stream.WriteObjectEnd()
+	default:
+		return xerrors.Errorf("etf: unsupported tag %d", tag)
+	}
+
+	return nil
+}
+
+// skipKey reads a map key and returns it if it is an atom or binary.
+func (d *etfDecoder) skipKey() (name string, err error) {
+	start := d.pos
+
+	tag, err := d.uint8()
+	if err != nil {
+		return "", err
+	}
+
+	switch tag {
+	case etfAtom, etfSmallAtom, etfAtomUTF8, etfSmallAtomUTF8:
+		return d.atom(tag)
+	case etfBinary:
+		n, err := d.uint32()
+		if err != nil {
+			return "", err
+		}
+
+		b, err := d.take(n)
+
+		return string(b), err
+	}
+
+	d.pos = start
+
+	var discard bytes.Buffer
+
+	stream := json.BorrowStream(&discard)
+	err = d.term(stream)
+	json.ReturnStream(stream)
+
+	return "", err
+}
+
+// parseETFEnvelope fills msg from a gateway payload in external term format
+// the same way parseEnvelope does for JSON. The data is transcoded to JSON so
+// the handlers of events do not depend on the encoding.
+func parseETFEnvelope(buf []byte, msg *discord.ReceivedPayload) (err error) {
+	if len(buf) == 0 || buf[0] != etfVersion {
+		return ErrETFVersion
+	}
+
+	d := &etfDecoder{buf: buf, pos: 1}
+
+	tag, err := d.uint8()
+	if err != nil {
+		return xerrors.Errorf("parse etf envelope: %w", err)
+	}
+
+	if tag != etfMap {
+		return xerrors.Errorf("parse etf envelope: payload is not a map but tag %d", tag)
+	}
+
+	n, err := d.uint32()
+	if err != nil {
+		return xerrors.Errorf("parse etf envelope: %w", err)
+	}
+
+	hasOp := false
+
+	for i := 0; i < n; i++ {
+		field, err := d.skipKey()
+		if err != nil {
+			return xerrors.Errorf("parse etf envelope: %w", err)
+		}
+
+		var value bytes.Buffer
+
+		stream := json.BorrowStream(&value)
+		err = d.term(stream)
+		_ = stream.Flush()
+		json.ReturnStream(stream)
+
+		if err != nil {
+			return xerrors.Errorf("parse etf envelope %s: %w", field, err)
+		}
+
+		raw := value.Bytes()
+
+		switch field {
+		case "op":
+			op, err := strconv.Atoi(string(raw))
+			if err == nil {
+				msg.Op = discord.GatewayOp(op)
+				hasOp = true
+			}
+		case "s":
+			msg.Sequence, _ = strconv.ParseInt(string(raw), 10, 64)
+		case "t":
+			msg.Type, _ = strconv.Unquote(string(raw))
+		case "d":
+			if string(raw) != "null" {
+				msg.Data = raw
+			}
+		}
+	}
+
+	if !hasOp {
+		return ErrEnvelopeNoOp
+	}
+
+	return nil
+}
+
+// jsonToETF encodes JSON as external term format. Strings are sent as
+// binaries, objects as maps with binary keys and null as the nil atom.
+func jsonToETF(data []byte) (result []byte, err error) {
+	iter := json.BorrowIterator(data)
+	defer json.ReturnIterator(iter)
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.WriteByte(etfVersion)
+
+	writeETFValue(out, iter)
+
+	if iter.Error != nil {
+		return nil, xerrors.Errorf("json to etf: %w", iter.Error)
+	}
+
+	return out.Bytes(), nil
+}
+
+func writeETFAtom(out *bytes.Buffer, name string) {
+	out.WriteByte(etfSmallAtomUTF8)
+	out.WriteByte(byte(len(name)))
+	out.WriteString(name)
+}
+
+func writeETFBinary(out *bytes.Buffer, value string) {
+	var n [4]byte
+
+	binary.BigEndian.PutUint32(n[:], uint32(len(value)))
+
+	out.WriteByte(etfBinary)
+	out.Write(n[:])
+	out.WriteString(value)
+}
+
+func writeETFNumber(out *bytes.Buffer, number string) {
+	if i, err := strconv.ParseInt(number, 10, 64); err == nil {
+		switch {
+		case i >= 0 && i <= math.MaxUint8:
+			out.WriteByte(etfSmallInteger)
+			out.WriteByte(byte(i))
+		case i >= math.MinInt32 && i <= math.MaxInt32:
+			var b [4]byte
+
+			binary.BigEndian.PutUint32(b[:], uint32(int32(i)))
+
+			out.WriteByte(etfInteger)
+			out.Write(b[:])
+		default:
+			sign := byte(0)
+
+			u := uint64(i)
+			if i < 0 {
+				sign = 1
+				u = uint64(-i)
+			}
+
+			digits := make([]byte, 0, 8)
+			for ; u > 0; u >>= 8 {
+				digits = append(digits, byte(u))
+			}
+
+			out.WriteByte(etfSmallBig)
+			out.WriteByte(byte(len(digits)))
+			out.WriteByte(sign)
+			out.Write(digits)
+		}
+
+		return
+	}
+
+	f, _ := strconv.ParseFloat(number, 64)
+
+	var b [8]byte
+
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(f))
+
+	out.WriteByte(etfNewFloat)
+	out.Write(b[:])
+}
+
+func writeETFValue(out *bytes.Buffer, iter *jsoniter.Iterator) {
+	switch iter.WhatIsNext() {
+	case jsoniter.StringValue:
+		writeETFBinary(out, iter.ReadString())
+	case jsoniter.NumberValue:
+		writeETFNumber(out, string(iter.ReadNumber()))
+	case jsoniter.BoolValue:
+		if iter.ReadBool() {
+			writeETFAtom(out, "true")
+		} else {
+			writeETFAtom(out, "false")
+		}
+	case jsoniter.ArrayValue:
+		var elements bytes.Buffer
+
+		n := 0
+
+		for iter.ReadArray() {
+			writeETFValue(&elements, iter)
+			n++
+		}
+
+		if n == 0 {
+			out.WriteByte(etfNil)
+
+			return
+		}
+
+		var b [4]byte
+
+		binary.BigEndian.PutUint32(b[:], uint32(n))
+
+		out.WriteByte(etfList)
+		out.Write(b[:])
+		out.Write(elements.Bytes())
+		out.WriteByte(etfNil)
+	case jsoniter.ObjectValue:
+		var entries bytes.Buffer
+
+		n := 0
+
+		for field := iter.ReadObject(); field != ""; field = iter.ReadObject() {
+			writeETFBinary(&entries, field)
+			writeETFValue(&entries, iter)
+			n++
+		}
+
+		var b [4]byte
+
+		binary.BigEndian.PutUint32(b[:], uint32(n))
+
+		out.WriteByte(etfMap)
+		out.Write(b[:])
+		out.Write(entries.Bytes())
+	case jsoniter.NilValue:
+		iter.Skip()
+		writeETFAtom(out, "nil")
+	default:
+		iter.ReportError("json to etf", "unexpected value")
+	}
+}
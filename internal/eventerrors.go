@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+// Stages an event can fail at.
+const (
+	eventStageState   = "state"
+	eventStagePublish = "publish"
+)
+
+const (
+	// Period the error rate of an event type is measured over.
+	eventErrorWindow = 5 * time.Minute
+
+	// Events of a type needed within a window before its error rate can
+	// alert, so a single failure of a rare event does not.
+	eventErrorMinEvents = 20
+
+	// Percentage of events of a type failing within a window that alerts.
+	eventErrorAlertRate = 50
+)
+
+type eventErrorKey struct {
+	eventType string
+	stage     string
+}
+
+// eventErrorCounter counts the events of a type which reached a stage and
+// how many failed. The window counts are reset every eventErrorWindow.
+type eventErrorCounter struct {
+	mu sync.Mutex
+
+	events      int64
+	errors      int64
+	lastError   string
+	lastErrorAt time.Time
+
+	decodeErrors map[string]int64 // Errors decoding the data by their kind
+
+	windowStart  time.Time
+	windowEvents int64
+	windowErrors int64
+	alerted      bool // If the current window has already alerted
+}
+
+// recordEventOutcome counts an event of a type reaching a stage and if it
+// failed. A webhook is sent once per window when the error rate of the type
+// passes eventErrorAlertRate. Events without a state handler are not failures.
+func (sh *Shard) recordEventOutcome(eventType string, stage string, err error) {
+	if xerrors.Is(err, NoHandler) {
+		err = nil
+	}
+
+	mg := sh.Manager
+	key := eventErrorKey{eventType: eventType, stage: stage}
+
+	mg.eventErrorsMu.RLock()
+	counter, ok := mg.eventErrors[key]
+	mg.eventErrorsMu.RUnlock()
+
+	if !ok {
+		mg.eventErrorsMu.Lock()
+		if counter, ok = mg.eventErrors[key]; !ok {
+			counter = &eventErrorCounter{}
+			mg.eventErrors[key] = counter
+		}
+		mg.eventErrorsMu.Unlock()
+	}
+
+	now := time.Now().UTC()
+
+	counter.mu.Lock()
+
+	if now.Sub(counter.windowStart) > eventErrorWindow {
+		counter.windowStart = now
+		counter.windowEvents = 0
+		counter.windowErrors = 0
+		counter.alerted = false
+	}
+
+	counter.events++
+	counter.windowEvents++
+
+	if err != nil {
+		counter.errors++
+		counter.windowErrors++
+		counter.lastError = err.Error()
+		counter.lastErrorAt = now
+
+		var decodeError *eventDecodeError
+
+		if xerrors.As(err, &decodeError) {
+			if counter.decodeErrors == nil {
+				counter.decodeErrors = make(map[string]int64)
+			}
+
+			counter.decodeErrors[decodeError.kind]++
+		}
+	}
+
+	alert := err != nil && !counter.alerted &&
+		counter.windowEvents >= eventErrorMinEvents &&
+		counter.windowErrors*100 > counter.windowEvents*eventErrorAlertRate
+
+	if alert {
+		counter.alerted = true
+	}
+
+	windowEvents, windowErrors := counter.windowEvents, counter.windowErrors
+	counter.mu.Unlock()
+
+	if !alert {
+		return
+	}
+
+	sh.Logger.Warn().
+		Str("type", eventType).
+		Str("stage", stage).
+		Int64("events", windowEvents).
+		Int64("errors", windowErrors).
+		Err(err).
+		Msg("Event type is failing")
+
+	go sh.PublishWebhook(
+		fmt.Sprintf("`%s` events are failing at the %s stage", eventType, stage),
+		fmt.Sprintf("%d of %d events failed in the last %s.\nLast error: `%s`",
+			windowErrors, windowEvents, eventErrorWindow, err.Error()),
+		discord.EmbedWarning, false)
+}
+
+// EventErrors returns the counters of event types which have failed at any
+// stage, most errors first.
+func (mg *Manager) EventErrors() (result []structs.EventErrorCount) {
+	mg.eventErrorsMu.RLock()
+	defer mg.eventErrorsMu.RUnlock()
+
+	result = make([]structs.EventErrorCount, 0)
+
+	for key, counter := range mg.eventErrors {
+		counter.mu.Lock()
+		if counter.errors > 0 {
+			var decodeErrors map[string]int64
+
+			if len(counter.decodeErrors) > 0 {
+				decodeErrors = make(map[string]int64, len(counter.decodeErrors))
+
+				for kind, count := range counter.decodeErrors {
+					decodeErrors[kind] = count
+				}
+			}
+
+			result = append(result, structs.EventErrorCount{
+				Type:         key.eventType,
+				Stage:        key.stage,
+				Events:       counter.events,
+				Errors:       counter.errors,
+				LastError:    counter.lastError,
+				LastErrorAt:  counter.lastErrorAt,
+				WindowStart:  counter.windowStart,
+				WindowEvents: counter.windowEvents,
+				WindowErrors: counter.windowErrors,
+				DecodeErrors: decodeErrors,
+			})
+		}
+		counter.mu.Unlock()
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Errors != result[j].Errors {
+			return result[i].Errors > result[j].Errors
+		}
+
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+
+		return result[i].Stage < result[j].Stage
+	})
+
+	return result
+}
+
+// ResetEventErrors clears the counters of event types. If eventTypes is
+// empty, every counter is cleared.
+func (mg *Manager) ResetEventErrors(eventTypes []string) (reset int) {
+	eventTypes = NormalizeEventNames(eventTypes)
+
+	mg.eventErrorsMu.Lock()
+	defer mg.eventErrorsMu.Unlock()
+
+	if len(eventTypes) == 0 {
+		reset = len(mg.eventErrors)
+		mg.eventErrors = make(map[eventErrorKey]*eventErrorCounter)
+
+		return reset
+	}
+
+	for _, eventType := range eventTypes {
+		for _, stage := range []string{eventStageState, eventStagePublish} {
+			key := eventErrorKey{eventType: eventType, stage: stage}
+
+			if _, ok := mg.eventErrors[key]; ok {
+				delete(mg.eventErrors, key)
+				reset++
+			}
+		}
+	}
+
+	return reset
+}
@@ -0,0 +1,240 @@
+package gateway
+
+import (
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// KnownEvents is the list of dispatch event types the gateway is
+// known to send. It is used to validate configured event names.
+var KnownEvents = []string{
+	"READY",
+	"RESUMED",
+	"APPLICATION_COMMAND_CREATE",
+	"APPLICATION_COMMAND_UPDATE",
+	"APPLICATION_COMMAND_DELETE",
+	"CHANNEL_CREATE",
+	"CHANNEL_UPDATE",
+	"CHANNEL_DELETE",
+	"CHANNEL_PINS_UPDATE",
+	"THREAD_CREATE",
+	"THREAD_UPDATE",
+	"THREAD_DELETE",
+	"THREAD_LIST_SYNC",
+	"THREAD_MEMBER_UPDATE",
+	"THREAD_MEMBERS_UPDATE",
+	"GUILD_CREATE",
+	"GUILD_UPDATE",
+	"GUILD_DELETE",
+	"GUILD_BAN_ADD",
+	"GUILD_BAN_REMOVE",
+	"GUILD_EMOJIS_UPDATE",
+	"GUILD_STICKERS_UPDATE",
+	"GUILD_INTEGRATIONS_UPDATE",
+	"GUILD_MEMBER_ADD",
+	"GUILD_MEMBER_REMOVE",
+	"GUILD_MEMBER_UPDATE",
+	"GUILD_MEMBERS_CHUNK",
+	"GUILD_ROLE_CREATE",
+	"GUILD_ROLE_UPDATE",
+	"GUILD_ROLE_DELETE",
+	"INTEGRATION_CREATE",
+	"INTEGRATION_UPDATE",
+	"INTEGRATION_DELETE",
+	"INTERACTION_CREATE",
+	"INVITE_CREATE",
+	"INVITE_DELETE",
+	"MESSAGE_CREATE",
+	"MESSAGE_UPDATE",
+	"MESSAGE_DELETE",
+	"MESSAGE_DELETE_BULK",
+	"MESSAGE_REACTION_ADD",
+	"MESSAGE_REACTION_REMOVE",
+	"MESSAGE_REACTION_REMOVE_ALL",
+	"MESSAGE_REACTION_REMOVE_EMOJI",
+	"PRESENCE_UPDATE",
+	"STAGE_INSTANCE_CREATE",
+	"STAGE_INSTANCE_UPDATE",
+	"STAGE_INSTANCE_DELETE",
+	"TYPING_START",
+	"USER_UPDATE",
+	"VOICE_STATE_UPDATE",
+	"VOICE_SERVER_UPDATE",
+	"WEBHOOKS_UPDATE",
+}
+
+// NormalizeEventName converts an event name such as messageCreate or
+// message-create into the upper snake case used by the gateway.
+func NormalizeEventName(name string) string {
+	var b strings.Builder
+
+	name = strings.TrimSpace(name)
+	runes := []rune(name)
+
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ' || r == '.':
+			b.WriteRune('_')
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])):
+			b.WriteRune('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+
+	return b.String()
+}
+
+// EventMatcher matches event types against a list of names and globs
+// that has been compiled ahead of time.
+type EventMatcher struct {
+	exact    map[string]void
+	patterns []string
+}
+
+// NewEventMatcher normalizes and compiles a list of event names. Entries
+// may contain globs such as *_UPDATE. Entries that are not known events are
+// returned as warnings but are still matched, whilst invalid patterns are
+// returned as warnings and skipped.
+func NewEventMatcher(entries []string) (em *EventMatcher, warnings []string) {
+	em = &EventMatcher{
+		exact:    make(map[string]void),
+		patterns: make([]string, 0),
+	}
+
+	for _, entry := range entries {
+		name := NormalizeEventName(entry)
+		if name == "" {
+			continue
+		}
+
+		if problem := eventNameProblem(entry); problem != "" {
+			warnings = append(warnings, problem)
+		}
+
+		if !strings.ContainsAny(name, "*?[") {
+			em.exact[name] = void{}
+		} else if _, err := path.Match(name, ""); err == nil {
+			em.patterns = append(em.patterns, name)
+		}
+	}
+
+	return em, warnings
+}
+
+// Match returns true if the event type is matched by the EventMatcher.
+func (em *EventMatcher) Match(eventType string) bool {
+	if em == nil {
+		return false
+	}
+
+	if _, ok := em.exact[eventType]; ok {
+		return true
+	}
+
+	for _, pattern := range em.patterns {
+		if ok, _ := path.Match(pattern, eventType); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NormalizeEventNames converts all entries to upper snake case.
+func NormalizeEventNames(entries []string) (normalized []string) {
+	normalized = make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if name := NormalizeEventName(entry); name != "" {
+			normalized = append(normalized, name)
+		}
+	}
+
+	return normalized
+}
+
+// eventNameProblem returns why an event name or pattern will not match as
+// expected. An empty string is returned if it matches a known event.
+func eventNameProblem(entry string) (problem string) {
+	name := NormalizeEventName(entry)
+
+	if !strings.ContainsAny(name, "*?[") {
+		if !isKnownEvent(name) {
+			return "unknown event type " + entry
+		}
+
+		return ""
+	}
+
+	if _, err := path.Match(name, ""); err != nil {
+		return "invalid event pattern " + entry
+	}
+
+	if !matchesKnownEvent(name) {
+		return "event pattern " + entry + " does not match any known event type"
+	}
+
+	return ""
+}
+
+// EventNameWarnings returns a warning for every configured event name which
+// is unknown or is a pattern that is invalid or matches no known event.
+func (mc *ManagerConfiguration) EventNameWarnings() (warnings []structs.ConfigurationWarning) {
+	settings := []struct {
+		name    string
+		entries []string
+	}{
+		{"events.event_blacklist", mc.Events.EventBlacklist},
+		{"events.produce_blacklist", mc.Events.ProduceBlacklist},
+		{"events.include_before", mc.Events.IncludeBefore},
+		{"caching.lazy_member_events", mc.Caching.LazyMemberEvents},
+		{"messaging.ack_events", mc.Messaging.AckEvents},
+	}
+
+	for _, setting := range settings {
+		for _, entry := range setting.entries {
+			if strings.TrimSpace(entry) == "" {
+				continue
+			}
+
+			problem := eventNameProblem(entry)
+			if problem == "" {
+				continue
+			}
+
+			warnings = append(warnings, structs.ConfigurationWarning{
+				Manager: mc.Identifier,
+				Setting: setting.name,
+				Event:   entry,
+				Message: setting.name + " has " + problem,
+			})
+		}
+	}
+
+	return warnings
+}
+
+func isKnownEvent(name string) bool {
+	for _, event := range KnownEvents {
+		if event == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesKnownEvent(pattern string) bool {
+	for _, event := range KnownEvents {
+		if ok, _ := path.Match(pattern, event); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeEventName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"MESSAGE_CREATE", "MESSAGE_CREATE"},
+		{"messageCreate", "MESSAGE_CREATE"},
+		{"MessageCreate", "MESSAGE_CREATE"},
+		{"message_create", "MESSAGE_CREATE"},
+		{"message-create", "MESSAGE_CREATE"},
+		{"message create", "MESSAGE_CREATE"},
+		{"message.create", "MESSAGE_CREATE"},
+		{"  guildMemberAdd ", "GUILD_MEMBER_ADD"},
+		{"messageReactionRemoveAll", "MESSAGE_REACTION_REMOVE_ALL"},
+		{"*_update", "*_UPDATE"},
+		{"guild*", "GUILD*"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := NormalizeEventName(test.name); got != test.want {
+			t.Errorf("NormalizeEventName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEventMatcherMatch(t *testing.T) {
+	tests := []struct {
+		entries   []string
+		eventType string
+		want      bool
+	}{
+		{[]string{"MESSAGE_CREATE"}, "MESSAGE_CREATE", true},
+		{[]string{"messageCreate"}, "MESSAGE_CREATE", true},
+		{[]string{"messageCreate"}, "MESSAGE_UPDATE", false},
+		{[]string{"*_UPDATE"}, "GUILD_UPDATE", true},
+		{[]string{"*_update"}, "MESSAGE_UPDATE", true},
+		{[]string{"*_UPDATE"}, "GUILD_CREATE", false},
+		{[]string{"GUILD_MEMBER_*"}, "GUILD_MEMBER_ADD", true},
+		{[]string{"GUILD_MEMBER_*"}, "GUILD_CREATE", false},
+		{[]string{"MESSAGE_REACTION_?DD"}, "MESSAGE_REACTION_ADD", true},
+		{[]string{"GUILD_BAN_[AR]*"}, "GUILD_BAN_REMOVE", true},
+		{[]string{"UNKNOWN_EVENT"}, "UNKNOWN_EVENT", true},
+		{[]string{"GUILD_[CREATE"}, "GUILD_CREATE", false},
+		{[]string{"", "  "}, "", false},
+		{nil, "MESSAGE_CREATE", false},
+	}
+
+	for _, test := range tests {
+		matcher, _ := NewEventMatcher(test.entries)
+
+		if got := matcher.Match(test.eventType); got != test.want {
+			t.Errorf("NewEventMatcher(%q).Match(%q) = %v, want %v", test.entries, test.eventType, got, test.want)
+		}
+	}
+
+	var matcher *EventMatcher
+	if matcher.Match("MESSAGE_CREATE") {
+		t.Error("nil matcher matched an event")
+	}
+}
+
+func TestNewEventMatcherWarnings(t *testing.T) {
+	tests := []struct {
+		entries  []string
+		warnings []string
+	}{
+		{[]string{"MESSAGE_CREATE", "messageCreate", "*_UPDATE"}, nil},
+		{[]string{"MESSAGE_CRATE"}, []string{"unknown event type MESSAGE_CRATE"}},
+		{[]string{"messageCrate"}, []string{"unknown event type messageCrate"}},
+		{[]string{"GUILD_[CREATE"}, []string{"invalid event pattern GUILD_[CREATE"}},
+		{[]string{"FOO_*"}, []string{"event pattern FOO_* does not match any known event type"}},
+		{
+			[]string{"MESSAGE_CREATE", "FOO", "BAR_*"},
+			[]string{"unknown event type FOO", "event pattern BAR_* does not match any known event type"},
+		},
+	}
+
+	for _, test := range tests {
+		_, warnings := NewEventMatcher(test.entries)
+
+		if !reflect.DeepEqual(warnings, test.warnings) {
+			t.Errorf("NewEventMatcher(%q) warnings = %q, want %q", test.entries, warnings, test.warnings)
+		}
+	}
+}
+
+func TestEventNameWarnings(t *testing.T) {
+	mc := &ManagerConfiguration{Identifier: "test"}
+	mc.Events.EventBlacklist = []string{"typingStart", "TYPNG_START"}
+	mc.Events.ProduceBlacklist = []string{"*_UPDATE", "NOTHING_*"}
+	mc.Events.IncludeBefore = []string{"GUILD_[UPDATE"}
+	mc.Caching.LazyMemberEvents = []string{"MESSAGE_CREATE", ""}
+	mc.Messaging.AckEvents = []string{"messageDelete", "messageDestroy"}
+
+	warnings := mc.EventNameWarnings()
+
+	want := []struct {
+		setting string
+		event   string
+	}{
+		{"events.event_blacklist", "TYPNG_START"},
+		{"events.produce_blacklist", "NOTHING_*"},
+		{"events.include_before", "GUILD_[UPDATE"},
+		{"messaging.ack_events", "messageDestroy"},
+	}
+
+	if len(warnings) != len(want) {
+		t.Fatalf("got %d warnings, want %d: %+v", len(warnings), len(want), warnings)
+	}
+
+	for i, warning := range warnings {
+		if warning.Manager != "test" || warning.Setting != want[i].setting || warning.Event != want[i].event {
+			t.Errorf("warning %d = %+v, want %s %s", i, warning, want[i].setting, want[i].event)
+		}
+
+		if warning.Message == "" {
+			t.Errorf("warning %d has no message", i)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/vmihailenco/msgpack"
+	"golang.org/x/xerrors"
+)
+
+// Suffix of the messaging channel consumers send gateway commands on.
+const gatewayCommandsSuffix = ":gateway"
+
+var (
+	// ErrGatewayCommandOp is returned for gateway commands with an op
+	// consumers are not allowed to send.
+	ErrGatewayCommandOp = xerrors.New("op cannot be sent as a gateway command")
+
+	// ErrGatewayCommandData is returned for gateway commands without data.
+	ErrGatewayCommandData = xerrors.New("gateway command has no data")
+)
+
+// gatewayCommand is a command a consumer sends to have a payload sent to the
+// gateway. Data is decoded separately once the op is known.
+//
+// Commands with a guild, such as voice state updates and member requests, are
+// sent on the shard of the guild. ShardID is only used by status updates.
+type gatewayCommand struct {
+	ShardID int               `msgpack:"shard_id"`
+	Op      discord.GatewayOp `msgpack:"op"`
+}
+
+// subscribeGatewayCommands listens for gateway commands on the current
+// producer if messaging.gateway_commands is enabled, replacing the previous
+// subscription. ConfigurationMu must be held.
+func (mg *Manager) subscribeGatewayCommands() {
+	mg.gatewayCommandsMu.Lock()
+	defer mg.gatewayCommandsMu.Unlock()
+
+	if mg.gatewayCommandsCancel != nil {
+		mg.gatewayCommandsCancel()
+		mg.gatewayCommandsCancel = nil
+	}
+
+	producer := mg.Producer()
+	if !mg.Configuration.Messaging.GatewayCommands || producer == nil {
+		return
+	}
+
+	subscriber, ok := producer.(MQSubscriber)
+	if !ok {
+		mg.Logger.Warn().
+			Str("driver", producer.String()).
+			Msg("Producer cannot subscribe so gateway commands are disabled")
+
+		return
+	}
+
+	channel := mg.Configuration.Messaging.ChannelName + gatewayCommandsSuffix
+	ctx, cancel := context.WithCancel(mg.ctx)
+
+	err := subscriber.Subscribe(ctx, channel, func(data []byte) {
+		go mg.handleGatewayCommand(data)
+	})
+	if err != nil {
+		cancel()
+		mg.Logger.Error().Err(err).Str("channel", channel).Msg("Failed to subscribe to gateway commands")
+
+		return
+	}
+
+	mg.gatewayCommandsCancel = cancel
+
+	mg.Logger.Info().Str("channel", channel).Msg("Listening for gateway commands")
+}
+
+// handleGatewayCommand sends a gateway command received from a consumer.
+func (mg *Manager) handleGatewayCommand(data []byte) {
+	command, payload, guildID, err := decodeGatewayCommand(data)
+	if err != nil {
+		mg.Logger.Warn().Err(err).Msg("Rejected gateway command")
+
+		return
+	}
+
+	shardGroup := mg.latestShardGroup()
+	if shardGroup == nil {
+		mg.Logger.Warn().Int("op", int(command.Op)).Msg("Dropped gateway command as there is no shardgroup")
+
+		return
+	}
+
+	shardID := command.ShardID
+	if guildID != 0 {
+		shardID = int((guildID.Int64() >> 22) % int64(shardGroup.ShardCount))
+	}
+
+	shardGroup.ShardsMu.RLock()
+	sh, ok := shardGroup.Shards[shardID]
+	shardGroup.ShardsMu.RUnlock()
+
+	if !ok {
+		mg.Logger.Warn().
+			Int("op", int(command.Op)).
+			Int("shard", shardID).
+			Msg("Dropped gateway command for a shard which is not running")
+
+		return
+	}
+
+	if err = sh.SendEvent(command.Op, payload); err != nil {
+		sh.Logger.Warn().Err(err).Int("op", int(command.Op)).Msg("Failed to send gateway command")
+
+		return
+	}
+
+	sh.Logger.Debug().Int("op", int(command.Op)).Msg("Sent gateway command")
+}
+
+// decodeGatewayCommand decodes a gateway command and its data. Only status
+// updates, voice state updates and member requests are accepted. The guild
+// the data is for is returned if it has one.
+func decodeGatewayCommand(data []byte) (command gatewayCommand, payload interface{}, guildID snowflake.ID, err error) {
+	if err = msgpack.Unmarshal(data, &command); err != nil {
+		return command, nil, guildID, xerrors.Errorf("decode gateway command: %w", err)
+	}
+
+	switch command.Op {
+	case discord.GatewayOpStatusUpdate:
+		var body struct {
+			Data *discord.UpdateStatus `msgpack:"data"`
+		}
+
+		err = msgpack.Unmarshal(data, &body)
+		if err == nil && body.Data != nil {
+			if !validPresenceStatus(body.Data.Status) {
+				return command, nil, guildID, ErrInvalidPresenceStatus
+			}
+
+			payload = body.Data
+		}
+	case discord.GatewayOpVoiceStateUpdate:
+		var body struct {
+			Data *discord.UpdateVoiceState `msgpack:"data"`
+		}
+
+		err = msgpack.Unmarshal(data, &body)
+		if err == nil && body.Data != nil {
+			payload, guildID = body.Data, body.Data.GuildID
+		}
+	case discord.GatewayOpRequestGuildMembers:
+		var body struct {
+			Data *discord.RequestGuildMembers `msgpack:"data"`
+		}
+
+		err = msgpack.Unmarshal(data, &body)
+		if err == nil && body.Data != nil {
+			payload, guildID = body.Data, body.Data.GuildID
+		}
+	default:
+		return command, nil, guildID, xerrors.Errorf("op %d: %w", command.Op, ErrGatewayCommandOp)
+	}
+
+	if err != nil {
+		return command, nil, guildID, xerrors.Errorf("decode gateway command data: %w", err)
+	}
+
+	if payload == nil {
+		return command, nil, guildID, ErrGatewayCommandData
+	}
+
+	return command, payload, guildID, nil
+}
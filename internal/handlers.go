@@ -9,19 +9,26 @@ import (
 	"net/url"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/accumulator"
 	methodrouter "github.com/TheRockettek/Sandwich-Daemon/pkg/methodrouter"
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
 	"github.com/TheRockettek/Sandwich-Daemon/structs"
 	"github.com/fasthttp/websocket"
+	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
 	"github.com/hashicorp/go-uuid"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog"
 	"github.com/savsgio/gotils"
 	"github.com/valyala/fasthttp"
 	"github.com/valyala/fasthttp/fasthttpadaptor"
+	"github.com/vmihailenco/msgpack"
 )
 
 const (
@@ -34,16 +41,6 @@ const (
 	discordUsersMe = "https://discord.com/api/users/@me"
 )
 
-var upgrader = websocket.FastHTTPUpgrader{
-	EnableCompression: true,
-	CheckOrigin: func(ctx *fasthttp.RequestCtx) bool {
-		origins := []string{"http://127.0.0.1:8080", "http://127.0.0.1:5469", "https://sandwich.welcomer.gg"}
-		origin := gotils.B2S(ctx.Request.Header.Peek("Origin"))
-
-		return gotils.StringSliceInclude(origins, origin)
-	},
-}
-
 func passFastHTTPResponse(ctx *fasthttp.RequestCtx, data interface{}, success bool, status int) {
 	var resp []byte
 
@@ -95,10 +92,18 @@ func passResponse(rw http.ResponseWriter, data interface{}, success bool, status
 			Data:    data,
 		})
 	} else {
-		resp, err = json.Marshal(structs.BaseResponse{
-			Success: false,
-			Error:   data.(string),
-		})
+		response := structs.BaseResponse{Success: false}
+
+		// Failures are usually a message. Results which partly failed, such
+		// as a rebuild with errors, are sent as the data instead.
+		if message, ok := data.(string); ok {
+			response.Error = message
+		} else {
+			response.Error = http.StatusText(status)
+			response.Data = data
+		}
+
+		resp, err = json.Marshal(response)
 	}
 
 	if err != nil {
@@ -128,6 +133,14 @@ func (sg *Sandwich) HandleRequest(ctx *fasthttp.RequestCtx) {
 	var processingMS int64
 
 	start := time.Now()
+
+	if sg.ShuttingDown() {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+
+		return
+	}
+
+	sg.stripBasePath(ctx)
 	path := gotils.B2S(ctx.Path())
 
 	defer func() {
@@ -144,11 +157,15 @@ func (sg *Sandwich) HandleRequest(ctx *fasthttp.RequestCtx) {
 			log = sg.Logger.Info()
 		}
 
-		// Suppress /api/poll messages
-		if path == "/api/poll" && statusCode == 200 {
+		// Suppress /api/poll and probe messages
+		if (path == "/api/poll" || path == "/healthz" || path == "/readyz") && statusCode == 200 {
 			return
 		}
 
+		if token, ok := sg.apiTokenName(gotils.B2S(ctx.Request.Header.Peek("Authorization"))); ok {
+			log = log.Str("token", token)
+		}
+
 		log.Msgf("%s %s %s %d %d %dms",
 			ctx.RemoteAddr(),
 			ctx.Request.Header.Method(),
@@ -167,6 +184,18 @@ func (sg *Sandwich) HandleRequest(ctx *fasthttp.RequestCtx) {
 	case "/api/console":
 		APIConsole(sg, ctx)
 
+		return
+	case "/healthz":
+		APIHealthz(sg, ctx)
+
+		return
+	case "/readyz":
+		APIReadyz(sg, ctx)
+
+		return
+	}
+
+	if sg.setCORSHeaders(ctx) {
 		return
 	}
 
@@ -175,6 +204,11 @@ func (sg *Sandwich) HandleRequest(ctx *fasthttp.RequestCtx) {
 		if ctx.Response.StatusCode() != http.StatusNotFound {
 			ctx.SetContentType("application/json;charset=utf8")
 		}
+		// API responses are kept as they are so clients get their JSON
+		// errors instead of the dashboard.
+		if strings.HasPrefix(path, "/api/") {
+			return
+		}
 		// If there is no URL in router then try serving from the dist
 		// folder.
 		if ctx.Response.StatusCode() == http.StatusNotFound && path != "/" {
@@ -184,7 +218,7 @@ func (sg *Sandwich) HandleRequest(ctx *fasthttp.RequestCtx) {
 		// If there is no URL in router or in dist then send index.html
 		if ctx.Response.StatusCode() == http.StatusNotFound {
 			ctx.Response.Reset()
-			ctx.SendFile(webRootPath + "/index.html")
+			sg.serveIndex(ctx)
 		}
 	}, fasthttp.CompressBrotliDefaultCompression, fasthttp.CompressDefaultCompression)(ctx)
 
@@ -192,12 +226,16 @@ func (sg *Sandwich) HandleRequest(ctx *fasthttp.RequestCtx) {
 	ctx.Response.Header.Set("X-Elapsed", strconv.FormatInt(processingMS, 10))
 }
 
-// AuthenticateSession verifies the session is valid. We simply store the user object
-// in the session. There are 100% better ways to do this but for our case this is
-// good enough. If HTTP.Public is enabled, it will not require authentication.
-// Please only use this if its on a private IP but regardless, you shouldn't have
-// this enabled.
+// AuthenticateSession verifies the session is valid and the user is elevated. We
+// simply store the user object in the session. There are 100% better ways to do
+// this but for our case this is good enough. Sessions made with an API token
+// are always elevated. Users with permissions are not elevated, they and
+// HTTP.Public are handled by AuthorizeSession and AuthorizeRPC.
 func (sg *Sandwich) AuthenticateSession(session *sessions.Session) (auth bool, user *structs.DiscordUser) {
+	if user, ok := apiTokenUser(session); ok {
+		return true, user
+	}
+
 	userBody, ok := session.Values["user"].([]byte)
 	if !ok {
 		return false, user
@@ -210,9 +248,8 @@ func (sg *Sandwich) AuthenticateSession(session *sessions.Session) (auth bool, u
 		return false, user
 	}
 
-	if sg.Configuration.HTTP.Public {
-		return true, user
-	}
+	sg.ConfigurationMu.RLock()
+	defer sg.ConfigurationMu.RUnlock()
 
 	for _, userID := range sg.Configuration.ElevatedUsers {
 		if userID == user.ID.String() {
@@ -224,7 +261,12 @@ func (sg *Sandwich) AuthenticateSession(session *sessions.Session) (auth bool, u
 }
 
 // SaveSession should be used as a defer when handling requests.
+// Sessions made with an API token are not saved.
 func (sg *Sandwich) SaveSession(s *sessions.Session, r *http.Request, rw http.ResponseWriter) {
+	if _, ok := apiTokenUser(s); ok {
+		return
+	}
+
 	if err := s.Save(r, rw); err != nil {
 		sg.Logger.Error().Err(err).Msg("Failed to save session")
 	}
@@ -238,7 +280,7 @@ func LogoutHandler(sg *Sandwich) http.HandlerFunc {
 
 		session.Values = make(map[interface{}]interface{})
 
-		http.Redirect(rw, r, "/", http.StatusTemporaryRedirect)
+		sg.redirect(rw, r, "/")
 	}
 }
 
@@ -261,7 +303,7 @@ func LoginHandler(sg *Sandwich) http.HandlerFunc {
 		// OAuth page.
 		session.Values["oauth_csrf"] = csrfString
 
-		url := sg.Configuration.OAuth.AuthCodeURL(csrfString)
+		url := sg.oauthConfiguration(r).AuthCodeURL(csrfString)
 		http.Redirect(rw, r, url, http.StatusTemporaryRedirect)
 	}
 }
@@ -283,14 +325,14 @@ func OAuthCallbackHandler(sg *Sandwich) http.HandlerFunc {
 
 		if !ok {
 			// http.Error(rw, "Missing CSRF state", http.StatusInternalServerError)
-			http.Redirect(rw, r, "/login", http.StatusTemporaryRedirect)
+			sg.redirect(rw, r, "/login")
 
 			return
 		}
 
 		if _csrfString != csrfString {
 			// http.Error(rw, "Mismatched CSRF states", http.StatusUnauthorized)
-			http.Redirect(rw, r, "/login", http.StatusTemporaryRedirect)
+			sg.redirect(rw, r, "/login")
 
 			return
 		}
@@ -301,20 +343,22 @@ func OAuthCallbackHandler(sg *Sandwich) http.HandlerFunc {
 		// Create an OAuth exchange with the code we were given.
 		code := urlQuery.Get("code")
 
-		token, err := sg.Configuration.OAuth.Exchange(ctx, code)
+		oauthConfiguration := sg.oauthConfiguration(r)
+
+		token, err := oauthConfiguration.Exchange(ctx, code)
 		if err != nil {
 			// http.Error(rw, "Failed to exchange code: "+err.Error(), http.StatusInternalServerError)
-			http.Redirect(rw, r, "/login", http.StatusTemporaryRedirect)
+			sg.redirect(rw, r, "/login")
 
 			return
 		}
 
 		// Create a client with our exchanged token and retrieve a user.
-		client := sg.Configuration.OAuth.Client(ctx, token)
+		client := oauthConfiguration.Client(ctx, token)
 
 		resp, err := client.Get(discordUsersMe) //nolint:noctx
 		if err != nil {
-			http.Redirect(rw, r, "/login", http.StatusTemporaryRedirect)
+			sg.redirect(rw, r, "/login")
 
 			return
 		}
@@ -324,7 +368,7 @@ func OAuthCallbackHandler(sg *Sandwich) http.HandlerFunc {
 
 		if err != nil {
 			// http.Error(rw, err.Error(), http.StatusInternalServerError)
-			http.Redirect(rw, r, "/login", http.StatusTemporaryRedirect)
+			sg.redirect(rw, r, "/login")
 
 			return
 		}
@@ -333,7 +377,7 @@ func OAuthCallbackHandler(sg *Sandwich) http.HandlerFunc {
 
 		if err = json.Unmarshal(body, &discordUserResponse); err != nil {
 			// http.Error(rw, err.Error(), http.StatusInternalServerError)
-			http.Redirect(rw, r, "/login", http.StatusTemporaryRedirect)
+			sg.redirect(rw, r, "/login")
 
 			return
 		}
@@ -341,7 +385,7 @@ func OAuthCallbackHandler(sg *Sandwich) http.HandlerFunc {
 		session.Values["user"] = body
 
 		// Once the user has logged in, send them back to the home page.
-		http.Redirect(rw, r, "/", http.StatusTemporaryRedirect)
+		sg.redirect(rw, r, "/")
 	}
 }
 
@@ -349,7 +393,7 @@ func OAuthCallbackHandler(sg *Sandwich) http.HandlerFunc {
 // object and if they are elevated for the dashboard.
 func APIMeHandler(sg *Sandwich) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
-		session, _ := sg.Store.Get(r, sessionName)
+		session := sg.requestSession(r)
 		defer sg.SaveSession(session, r, rw)
 
 		// Authenticate the user
@@ -367,43 +411,66 @@ func APIMeHandler(sg *Sandwich) http.HandlerFunc {
 func APIStatusHandler(sg *Sandwich) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		now := time.Now().UTC()
+		managers := sg.managersSnapshot()
 
 		_result := structs.APIStatusResult{
-			Managers: make([]structs.APIStatusManager, 0, len(sg.Managers)),
+			Managers: make([]structs.APIStatusManager, 0, len(managers)),
 			Uptime:   now.Sub(sg.Start).Round(time.Millisecond).Milliseconds(),
+			Incident: sg.Incidents().Current,
 		}
 
-		for _, manager := range sg.Managers {
+		for _, manager := range managers {
+			shardGroups := manager.shardGroupsSnapshot()
+
 			_manager := structs.APIStatusManager{
-				DisplayName: manager.Configuration.DisplayName,
-				Guilds:      0,
-				ShardGroups: make([]structs.APIStatusShardGroup, 0, len(manager.ShardGroups)),
+				DisplayName:       manager.displayName(),
+				Guilds:            0,
+				ProducedMessages:  manager.ProducedMessages(),
+				ProducedBytes:     manager.ProducedBytes(),
+				LastPublish:       manager.LastPublish(),
+				ProducerStatus:    manager.ProducerStatus(),
+				Maintenance:       manager.APIMaintenance(),
+				LazyMembers:       manager.APILazyMembers(),
+				ReadLimitExceeded: manager.ReadLimitExceeded(),
+				REST:              manager.restStats.API(),
+				Resumes:           manager.Resumes(),
+				ReplayedEvents:    manager.ReplayedEvents(),
+				InvalidSessions:   manager.InvalidSessions(),
+				ShardGroups:       make([]structs.APIStatusShardGroup, 0, len(shardGroups)),
 			}
 
-			for _, shardgroup := range manager.ShardGroups {
+			for _, shardgroup := range shardGroups {
 				_manager.Guilds += int64(shardgroup.GetGuildCount())
 
+				shards := shardgroup.shardsSnapshot()
+
 				shardgroup.StatusMu.RLock()
 				_shardgroup := structs.APIStatusShardGroup{
 					ID:     shardgroup.ID,
 					Status: shardgroup.Status,
-					Shards: make([]structs.APIStatusShard, 0, len(shardgroup.Shards)),
+					Shards: make([]structs.APIStatusShard, 0, len(shards)),
 				}
 				shardgroup.StatusMu.RUnlock()
 
-				shardgroup.ShardsMu.RLock()
-				for _, shard := range shardgroup.Shards {
+				for _, shard := range shards {
+					latencyEWMA, jitter := shard.SmoothedLatency()
+					recovery := shard.guildRecovery.API()
+
 					shard.StatusMu.RLock()
 					_shard := structs.APIStatusShard{
-						Status:  shard.Status,
-						Latency: shard.Latency(),
-						Uptime:  now.Sub(shard.Start).Round(time.Millisecond).Milliseconds(),
+						Status:         shard.Status,
+						StatusSince:    shard.StatusSince,
+						Latency:        shard.Latency(),
+						LatencyEWMA:    latencyEWMA,
+						Jitter:         jitter,
+						Uptime:         now.Sub(shard.Start).Round(time.Millisecond).Milliseconds(),
+						SinceLastEvent: int64(shard.SinceLastDispatch().Seconds()),
+						Recovery:       recovery,
 					}
 					shard.StatusMu.RUnlock()
 
 					_shardgroup.Shards = append(_shardgroup.Shards, _shard)
 				}
-				shardgroup.ShardsMu.RUnlock()
 
 				_manager.ShardGroups = append(_manager.ShardGroups, _shardgroup)
 			}
@@ -417,39 +484,54 @@ func APIStatusHandler(sg *Sandwich) http.HandlerFunc {
 
 // ConstructAnalytics returns a LineChart struct based off of manager analytics.
 func (sg *Sandwich) ConstructAnalytics() structs.LineChart {
-	datasets := make([]structs.Dataset, 0, len(sg.Managers))
+	return sg.constructChart(func(mg *Manager) *accumulator.Accumulator { return mg.Analytics })
+}
+
+// ConstructRESTAnalytics returns a LineChart of the REST requests made by
+// each manager.
+func (sg *Sandwich) ConstructRESTAnalytics() structs.LineChart {
+	return sg.constructChart(func(mg *Manager) *accumulator.Accumulator { return mg.RESTRequests })
+}
+
+// constructChart returns a LineChart with a dataset for each manager from
+// the accumulator series returns. series is called with AnalyticsMu held.
+func (sg *Sandwich) constructChart(series func(mg *Manager) *accumulator.Accumulator) structs.LineChart {
+	managers := sg.managersSnapshot()
+	datasets := make([]structs.Dataset, 0, len(managers))
 
 	// Create and sort x axis keys.
-	mankeys := make([]string, 0, len(sg.Managers))
-	for key := range sg.Managers {
+	mankeys := make([]string, 0, len(managers))
+	for key := range managers {
 		mankeys = append(mankeys, key)
 	}
 
 	sort.Strings(mankeys)
 
 	for i, ident := range mankeys {
-		mg := sg.Managers[ident]
+		mg := managers[ident]
 
 		mg.AnalyticsMu.RLock()
-		if mg.Analytics == nil {
+		acc := series(mg)
+
+		if acc == nil {
 			mg.AnalyticsMu.RUnlock()
 
 			continue
 		}
 
-		mg.Analytics.RLock()
-		data := make([]interface{}, 0, len(mg.Analytics.Samples))
+		acc.RLock()
+		data := make([]interface{}, 0, len(acc.Samples))
 
-		for _, sample := range mg.Analytics.Samples {
+		for _, sample := range acc.Samples {
 			data = append(data, structs.DataStamp{Time: sample.StoredAt, Value: sample.Value})
 		}
-		mg.Analytics.RUnlock()
+		acc.RUnlock()
 		mg.AnalyticsMu.RUnlock()
 
 		colour := structs.LineChartColours[i%len(structs.LineChartColours)]
 
 		datasets = append(datasets, structs.Dataset{
-			Label:            mg.Configuration.DisplayName,
+			Label:            mg.displayName(),
 			BackgroundColour: colour[0],
 			BorderColour:     colour[1],
 			Data:             data,
@@ -461,56 +543,90 @@ func (sg *Sandwich) ConstructAnalytics() structs.LineChart {
 	}
 }
 
-// APIAnalyticsHandler handles the /api/analytics request which
-// requires elevation.
+// APIAnalyticsHandler handles the /api/analytics request. Elevated users can
+// pass fresh=true to recompute the analytics instead of using the cache.
 func APIAnalyticsHandler(sg *Sandwich) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
-		session, _ := sg.Store.Get(r, sessionName)
-		if auth, _ := sg.AuthenticateSession(session); !auth {
-			passResponse(rw, forbiddenMessage, false, http.StatusForbidden)
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
 
 			return
 		}
 
-		passResponse(rw, sg.FetchAnalytics(), true, http.StatusOK)
+		passResponse(rw, sg.CachedAnalytics(freshAnalytics(sg, session, r)), true, http.StatusOK)
 	}
 }
 
-// FetchAnalytics returns the data for the /api/analytics endpoint.
+// FetchAnalytics computes the data for the /api/analytics endpoint. The
+// global state and the managers are counted in parallel. Use CachedAnalytics
+// instead unless an up to date result is required.
 func (sg *Sandwich) FetchAnalytics() (result structs.APIAnalyticsResult) {
-	managers := make([]structs.ManagerInformation, 0, len(sg.Managers))
-	guildCount := int64(0)
+	var channelCount, userCount, emojiCount, roleCount, presenceCount, memberCount int64
 
-	sg.State.ChannelsMu.RLock()
-	channelCount := int64(len(sg.State.Channels))
-	sg.State.ChannelsMu.RUnlock()
+	wg := sync.WaitGroup{}
+	wg.Add(6)
 
-	sg.State.UsersMu.RLock()
-	userCount := int64(len(sg.State.Users))
-	sg.State.UsersMu.RUnlock()
+	go func() {
+		defer wg.Done()
 
-	sg.State.EmojisMu.RLock()
-	emojiCount := int64(len(sg.State.Emojis))
-	sg.State.EmojisMu.RUnlock()
+		sg.State.ChannelsMu.RLock()
+		channelCount = int64(len(sg.State.Channels))
+		sg.State.ChannelsMu.RUnlock()
+	}()
 
-	memberCount := int64(0)
+	go func() {
+		defer wg.Done()
 
-	sg.State.GuildMembersMu.RLock()
-	for _, gm := range sg.State.GuildMembers {
-		gm.MembersMu.RLock()
-		memberCount += int64(len(gm.Members))
-		gm.MembersMu.RUnlock()
-	}
-	sg.State.GuildMembersMu.RUnlock()
+		sg.State.UsersMu.RLock()
+		userCount = int64(len(sg.State.Users))
+		sg.State.UsersMu.RUnlock()
+	}()
 
-	for _, manager := range sg.Managers {
-		manager.ConfigurationMu.RLock()
+	go func() {
+		defer wg.Done()
+
+		sg.State.EmojisMu.RLock()
+		emojiCount = int64(len(sg.State.Emojis))
+		sg.State.EmojisMu.RUnlock()
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		roleCount = int64(sg.State.RoleCount())
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		presenceCount = int64(sg.State.PresenceCount())
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		sg.State.GuildMembersMu.RLock()
+		for _, gm := range sg.State.GuildMembers {
+			gm.MembersMu.RLock()
+			memberCount += int64(len(gm.Members))
+			gm.MembersMu.RUnlock()
+		}
+		sg.State.GuildMembersMu.RUnlock()
+	}()
+
+	guildCount := int64(0)
 
+	snapshot := sg.managersSnapshot()
+	managers := make([]structs.ManagerInformation, 0, len(snapshot))
+
+	shardMaps := make(map[string][]structs.ShardMapEntry, len(snapshot))
+
+	for identifier, manager := range snapshot {
 		managerGuilds := int64(0)
 		statuses := make(map[int32]structs.ShardGroupStatus)
 
-		manager.ShardGroupsMu.RLock()
-		for i, sg := range manager.ShardGroups {
+		for i, sg := range manager.shardGroupsSnapshot() {
 			sg.StatusMu.RLock()
 			statuses[i] = sg.Status
 			sg.StatusMu.RUnlock()
@@ -519,35 +635,56 @@ func (sg *Sandwich) FetchAnalytics() (result structs.APIAnalyticsResult) {
 			managerGuilds += int64(len(sg.Guilds))
 			sg.GuildsMu.RUnlock()
 		}
-		manager.ShardGroupsMu.RUnlock()
 
+		manager.ConfigurationMu.RLock()
 		_manager := structs.ManagerInformation{
 			Name:      manager.Configuration.DisplayName,
 			Guilds:    managerGuilds,
 			Status:    statuses,
 			AutoStart: manager.Configuration.AutoStart,
+			REST:      manager.restStats.API(),
+			SLOs:      manager.SLOs(),
+			Dispatch:  manager.DispatchQueue(),
+			Retry:     manager.publishRetry.API(),
+			State:     manager.stateQueries.API(),
+			Mutuals:   manager.mutuals.API(),
 		}
 		manager.ConfigurationMu.RUnlock()
 
 		guildCount += managerGuilds
 
 		managers = append(managers, _manager)
+		shardMaps[identifier] = manager.shardMap()
 	}
 
-	now := time.Now()
+	graph := sg.ConstructAnalytics()
+	restGraph := sg.ConstructRESTAnalytics()
+
+	wg.Wait()
+
+	now := time.Now().UTC()
 
 	result = structs.APIAnalyticsResult{
-		Graph:  sg.ConstructAnalytics(),
-		Guilds: guildCount,
+		Graph:     graph,
+		RESTGraph: restGraph,
+		Guilds:    guildCount,
 
-		Channels: channelCount,
-		Users:    userCount,
-		Members:  memberCount,
-		Emojis:   emojiCount,
+		Channels:  channelCount,
+		Users:     userCount,
+		Members:   memberCount,
+		Emojis:    emojiCount,
+		Roles:     roleCount,
+		Presences: presenceCount,
 
 		Uptime:   DurationTimestamp(now.Sub(sg.Start)),
 		Events:   atomic.LoadInt64(sg.TotalEvents),
 		Managers: managers,
+
+		MemberEvictions: sg.State.MemberEvictions(),
+
+		ShardMaps: shardMaps,
+
+		GeneratedAt: now,
 	}
 
 	return result
@@ -557,9 +694,9 @@ func (sg *Sandwich) FetchAnalytics() (result structs.APIAnalyticsResult) {
 // and is likely to be used as it supports compression.
 func APIPollHandler(sg *Sandwich) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
-		session, _ := sg.Store.Get(r, sessionName)
-		if auth, _ := sg.AuthenticateSession(session); !auth {
-			passResponse(rw, forbiddenMessage, false, http.StatusForbidden)
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
 
 			return
 		}
@@ -569,7 +706,7 @@ func APIPollHandler(sg *Sandwich) http.HandlerFunc {
 		passResponse(rw, structs.APISubscribeResult{
 			Managers:          sg.FetchManagerResponse(),
 			RestTunnel:        resttunnel,
-			Analytics:         sg.FetchAnalytics(),
+			Analytics:         sg.CachedAnalytics(freshAnalytics(sg, session, r)),
 			Start:             sg.Start,
 			RestTunnelEnabled: sg.RestTunnelEnabled.IsSet(),
 			Waiting:           atomic.LoadInt64(sg.PoolWaiting),
@@ -580,9 +717,9 @@ func APIPollHandler(sg *Sandwich) http.HandlerFunc {
 // APIConsole is a websocket that relays the stdout to clients.
 func APIConsole(sg *Sandwich, ctx *fasthttp.RequestCtx) {
 	fasthttpadaptor.NewFastHTTPHandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-		session, _ := sg.Store.Get(r, sessionName)
-		if auth, _ := sg.AuthenticateSession(session); !auth {
-			passResponse(rw, forbiddenMessage, false, http.StatusForbidden)
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
 
 			return
 		}
@@ -593,7 +730,7 @@ func APIConsole(sg *Sandwich, ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	err := upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+	err := sg.upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
 		conn.EnableWriteCompression(true)
 		if err := conn.SetCompressionLevel(flate.BestCompression); err != nil {
 			sg.Logger.Error().Err(err).Msg("Failed to set compression level")
@@ -621,9 +758,9 @@ func APIConsole(sg *Sandwich, ctx *fasthttp.RequestCtx) {
 // /api/resttunnel and /api/configuration endpoint.
 func APISubscribe(sg *Sandwich, ctx *fasthttp.RequestCtx) {
 	fasthttpadaptor.NewFastHTTPHandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-		session, _ := sg.Store.Get(r, sessionName)
-		if auth, _ := sg.AuthenticateSession(session); !auth {
-			passResponse(rw, forbiddenMessage, false, http.StatusForbidden)
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
 
 			return
 		}
@@ -634,7 +771,7 @@ func APISubscribe(sg *Sandwich, ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	err := upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+	err := sg.upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
 		conn.EnableWriteCompression(true)
 		if err := conn.SetCompressionLevel(flate.BestCompression); err != nil {
 			sg.Logger.Error().Err(err).Msg("Failed to set compression level")
@@ -644,7 +781,7 @@ func APISubscribe(sg *Sandwich, ctx *fasthttp.RequestCtx) {
 		for {
 			result := structs.APISubscribeResult{}
 			result.Managers = sg.FetchManagerResponse()
-			result.Analytics = sg.FetchAnalytics()
+			result.Analytics = sg.CachedAnalytics(false)
 
 			resttunnel, _, _, _, _ := sg.FetchRestTunnelResponse() //nolint:bodyclose
 			if len(resttunnel) > 0 {
@@ -671,26 +808,130 @@ func APISubscribe(sg *Sandwich, ctx *fasthttp.RequestCtx) {
 	}
 }
 
-// APIManagersHandler handles the /api/managers endpoint.
+// Fields of a manager which can be selected in the /api/managers endpoint.
+// status is an alias of shard_groups.
+var managerResponseFields = []string{"configuration", "gateway", "error", "shard_groups"}
+
+// APIManagersHandler handles the /api/managers endpoint. Managers are returned
+// as a list sorted by identifier and can be filtered with the manager query
+// parameter. The fields query parameter selects which fields are included.
+// Passing format=map returns the previous map response.
 func APIManagersHandler(sg *Sandwich) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
-		session, _ := sg.Store.Get(r, sessionName)
-		if auth, _ := sg.AuthenticateSession(session); !auth {
-			passResponse(rw, forbiddenMessage, false, http.StatusForbidden)
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		query := r.URL.Query()
+
+		// TODO: Remove the map format once the frontend uses the list response.
+		if query.Get("format") == "map" {
+			passResponse(rw, sg.FetchManagerResponse(), true, http.StatusOK)
 
 			return
 		}
 
-		passResponse(rw, sg.FetchManagerResponse(), true, http.StatusOK)
+		fields := make(map[string]bool)
+
+		if value := query.Get("fields"); value != "" {
+			for _, field := range strings.Split(value, ",") {
+				field = strings.TrimSpace(field)
+				if field == "status" {
+					field = "shard_groups"
+				}
+
+				fields[field] = true
+			}
+		} else {
+			for _, field := range managerResponseFields {
+				fields[field] = true
+			}
+		}
+
+		passResponse(rw, sg.FetchManagerList(query.Get("manager"), fields), true, http.StatusOK)
+	}
+}
+
+// FetchManagerList returns the data for the /api/managers endpoint. If
+// identifier is not empty, only that manager is returned.
+func (sg *Sandwich) FetchManagerList(identifier string,
+	fields map[string]bool) (managers []structs.APIManagersResponseManager) {
+	managers = make([]structs.APIManagersResponseManager, 0)
+
+	for managerID, manager := range sg.managersSnapshot() {
+		if identifier != "" && managerID != identifier {
+			continue
+		}
+
+		mg := structs.APIManagersResponseManager{
+			Identifier: managerID,
+		}
+
+		if fields["configuration"] {
+			manager.ConfigurationMu.RLock()
+			mg.Configuration = manager.Configuration
+			manager.ConfigurationMu.RUnlock()
+		}
+
+		if fields["gateway"] {
+			manager.GatewayMu.RLock()
+			mg.Gateway = manager.Gateway
+			manager.GatewayMu.RUnlock()
+		}
+
+		if fields["error"] {
+			manager.ErrorMu.RLock()
+			mg.Error = manager.Error
+			manager.ErrorMu.RUnlock()
+		}
+
+		if fields["shard_groups"] {
+			mg.ShardGroups = make([]structs.APIManagersResponseShardGroup, 0)
+
+			for _, shardgroup := range manager.shardGroupsSnapshot() {
+				shg := shardgroup.apiResponse()
+
+				shards := make([]structs.APIConfigurationResponseShard, 0, len(shg.Shards))
+				for _, shard := range shg.Shards {
+					shards = append(shards, shard.(structs.APIConfigurationResponseShard))
+				}
+
+				sort.Slice(shards, func(i, j int) bool { return shards[i].ShardID < shards[j].ShardID })
+
+				mg.ShardGroups = append(mg.ShardGroups, structs.APIManagersResponseShardGroup{
+					Status:     shg.Status,
+					Error:      shg.Error,
+					Start:      shg.Start,
+					WaitingFor: shg.WaitingFor,
+					ID:         shg.ID,
+					ShardCount: shg.ShardCount,
+					ShardIDs:   shg.ShardIDs,
+					Shards:     shards,
+
+					CloseSummary: shg.CloseSummary,
+				})
+			}
+
+			sort.Slice(mg.ShardGroups, func(i, j int) bool { return mg.ShardGroups[i].ID < mg.ShardGroups[j].ID })
+		}
+
+		managers = append(managers, mg)
 	}
+
+	sort.Slice(managers, func(i, j int) bool { return managers[i].Identifier < managers[j].Identifier })
+
+	return managers
 }
 
-// FetchManagerResponse returns the data for the /api/manager endpoint.
+// FetchManagerResponse returns the managers as a map. This is used by
+// /api/ws, /api/poll and /api/managers?format=map.
 func (sg *Sandwich) FetchManagerResponse() (managers map[string]structs.APIConfigurationResponseManager) {
 	managers = make(map[string]structs.APIConfigurationResponseManager)
 
-	sg.ManagersMu.RLock()
-	for managerID, manager := range sg.Managers {
+	for managerID, manager := range sg.managersSnapshot() {
 		mg := structs.APIConfigurationResponseManager{}
 
 		manager.ConfigurationMu.RLock()
@@ -705,71 +946,90 @@ func (sg *Sandwich) FetchManagerResponse() (managers map[string]structs.APIConfi
 		mg.Error = manager.Error
 		manager.ErrorMu.RUnlock()
 
-		mg.ShardGroups = make(map[int32]structs.APIConfigurationResponseShardGroup)
+		mg.GuildAffinityOverrides = manager.GuildAffinityOverrides()
 
-		manager.ShardGroupsMu.RLock()
-		for shardgroupID, shardgroup := range manager.ShardGroups {
-			shg := structs.APIConfigurationResponseShardGroup{
-				Start:      shardgroup.Start,
-				ID:         shardgroup.ID,
-				ShardCount: shardgroup.ShardCount,
-				ShardIDs:   shardgroup.ShardIDs,
-				WaitingFor: atomic.LoadInt32(shardgroup.WaitingFor),
-			}
+		mg.ShardGroups = make(map[int32]structs.APIConfigurationResponseShardGroup)
 
-			shardgroup.StatusMu.RLock()
-			shg.Status = shardgroup.Status
-			shardgroup.StatusMu.RUnlock()
-
-			shardgroup.ErrorMu.RLock()
-			shg.Error = shardgroup.Error
-			shardgroup.ErrorMu.RUnlock()
-
-			shg.Shards = make(map[int]interface{})
-
-			shardgroup.ShardsMu.RLock()
-			for shardID, shard := range shardgroup.Shards {
-				shard.RLock()
-				shd := structs.APIConfigurationResponseShard{
-					ShardID:              shard.ShardID,
-					User:                 shard.User,
-					HeartbeatInterval:    shard.HeartbeatInterval,
-					MaxHeartbeatFailures: shard.MaxHeartbeatFailures,
-					Start:                shard.Start,
-					Retries:              atomic.LoadInt32(shard.Retries),
-				}
-				shard.RUnlock()
+		for shardgroupID, shardgroup := range manager.shardGroupsSnapshot() {
+			mg.ShardGroups[shardgroupID] = shardgroup.apiResponse()
+		}
 
-				shard.StatusMu.RLock()
-				shd.Status = shard.Status
-				shard.StatusMu.RUnlock()
+		managers[managerID] = mg
+	}
 
-				shard.LastHeartbeatMu.RLock()
-				shd.LastHeartbeatAck = shard.LastHeartbeatAck
-				shd.LastHeartbeatSent = shard.LastHeartbeatSent
-				shard.LastHeartbeatMu.RUnlock()
+	return managers
+}
 
-				shg.Shards[shardID] = shd
-			}
-			shardgroup.ShardsMu.RUnlock()
+// apiResponse returns the shardgroup as shown in the manager responses.
+func (sg *ShardGroup) apiResponse() (shg structs.APIConfigurationResponseShardGroup) {
+	shg = structs.APIConfigurationResponseShardGroup{
+		Start:      sg.Start,
+		ID:         sg.ID,
+		ShardCount: sg.ShardCount,
+		ShardIDs:   sg.ShardIDs,
+		WaitingFor: atomic.LoadInt32(sg.WaitingFor),
+
+		StartupQueue:   sg.startup.API(),
+		ActiveProducer: sg.IsActiveProducer(),
+	}
 
-			mg.ShardGroups[shardgroupID] = shg
+	sg.StatusMu.RLock()
+	shg.Status = sg.Status
+	sg.StatusMu.RUnlock()
+
+	sg.ErrorMu.RLock()
+	shg.Error = sg.Error
+	sg.ErrorMu.RUnlock()
+
+	sg.CloseSummaryMu.RLock()
+	shg.CloseSummary = sg.CloseSummary
+	sg.CloseSummaryMu.RUnlock()
+
+	shg.Shards = make(map[int]interface{})
+
+	now := time.Now().UTC()
+
+	for shardID, shard := range sg.shardsSnapshot() {
+		shard.RLock()
+		shd := structs.APIConfigurationResponseShard{
+			ShardID:              shard.ShardID,
+			User:                 shard.User,
+			HeartbeatInterval:    shard.HeartbeatInterval,
+			MaxHeartbeatFailures: shard.MaxHeartbeatFailures,
+			SinceLastEvent:       int64(shard.SinceLastDispatch().Seconds()),
+			Opcodes:              shard.opcodes.API(now),
+			Session:              shard.Session(),
+			ReadLimitExceeded:    shard.ReadLimitExceeded(),
+			Start:                shard.Start,
+			Retries:              atomic.LoadInt32(shard.Retries),
+			Watchdog:             shard.Watchdog(),
 		}
-		manager.ShardGroupsMu.RUnlock()
+		shard.RUnlock()
 
-		managers[managerID] = mg
+		shard.StatusMu.RLock()
+		shd.Status = shard.Status
+		shd.StatusSince = shard.StatusSince
+		shard.StatusMu.RUnlock()
+
+		shard.LastHeartbeatMu.RLock()
+		shd.LastHeartbeatAck = shard.LastHeartbeatAck
+		shd.LastHeartbeatSent = shard.LastHeartbeatSent
+		shd.LatencyEWMA = shard.latencyEWMA
+		shd.Jitter = shard.jitter
+		shard.LastHeartbeatMu.RUnlock()
+
+		shg.Shards[shardID] = shd
 	}
-	sg.ManagersMu.RUnlock()
 
-	return managers
+	return shg
 }
 
 // APIConfigurationHandler handles the /api/configuration endpoint.
 func APIConfigurationHandler(sg *Sandwich) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
-		session, _ := sg.Store.Get(r, sessionName)
-		if auth, _ := sg.AuthenticateSession(session); !auth {
-			passResponse(rw, forbiddenMessage, false, http.StatusForbidden)
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
 
 			return
 		}
@@ -784,114 +1044,1142 @@ func (sg *Sandwich) FetchConfigurationResponse() (pl structs.APIConfigurationRes
 		Start:             sg.Start,
 		RestTunnelEnabled: sg.RestTunnelEnabled.IsSet(),
 		MQDrivers:         mqclients.MQClients,
+		MQCapabilities:    mqclients.MQCapabilities,
 		Version:           VERSION,
+		Warnings:          sg.ValidateConfiguration(),
 	}
 
 	sg.ConfigurationMu.RLock()
-	pl.Configuration = sg.Configuration
+	pl.Configuration = redactConfiguration(sg.Configuration)
 	sg.ConfigurationMu.RUnlock()
 
+	pl.Captures = make([]structs.EventCapture, 0)
+
+	for identifier, manager := range sg.managersSnapshot() {
+		manager.CaptureMu.RLock()
+		if manager.Capture != nil {
+			pl.Captures = append(pl.Captures, manager.Capture.API(identifier))
+		}
+		manager.CaptureMu.RUnlock()
+	}
+
+	sort.Slice(pl.Captures, func(i, j int) bool {
+		return pl.Captures[i].Manager < pl.Captures[j].Manager
+	})
+
 	return
 }
 
-// APIRestTunnelHandler handles the /api/resttunnel endpoint.
-func APIRestTunnelHandler(sg *Sandwich) http.HandlerFunc {
+// APIAuditHandler handles the /api/audit endpoint. The limit and before
+// query parameters can be used to page through recent entries.
+func APIAuditHandler(sg *Sandwich) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
-		session, _ := sg.Store.Get(r, sessionName)
-		if auth, _ := sg.AuthenticateSession(session); !auth {
-			passResponse(rw, forbiddenMessage, false, http.StatusForbidden)
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
 
 			return
 		}
 
-		body, resp, err, ok, status := sg.FetchRestTunnelResponse() //nolint:bodyclose
-		if err != "" || !ok {
-			passResponse(rw, err, ok, status)
+		query := r.URL.Query()
+
+		limit := auditDefaultLimit
+
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				passResponse(rw, "Invalid limit provided", false, http.StatusBadRequest)
+
+				return
+			}
+
+			if parsed > auditMaxLimit {
+				parsed = auditMaxLimit
+			}
+
+			limit = parsed
+		}
+
+		var before snowflake.ID
+
+		if value := query.Get("before"); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				passResponse(rw, "Invalid before provided", false, http.StatusBadRequest)
+
+				return
+			}
+
+			before = snowflake.ID(parsed)
+		}
+
+		passResponse(rw, sg.Audit.Entries(limit, before), true, http.StatusOK)
+	}
+}
+
+// APIGuildSyncHandler handles the /api/state/guilds/{id}/sync endpoint. The
+// members query parameter sets how many members are included and after
+// continues from a previous response. If the Accept header asks for msgpack,
+// the response is encoded with msgpack instead of json.
+func APIGuildSyncHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
 
 			return
 		}
 
-		// We want to write directly as its a proxied request.
-		rw.WriteHeader(resp.StatusCode)
+		guildID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			passResponse(rw, "Invalid guild provided", false, http.StatusBadRequest)
 
-		if _, err := rw.Write(body); err != nil {
-			passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+
+		var memberLimit int
+
+		if value := query.Get("members"); value != "" {
+			memberLimit, err = strconv.Atoi(value)
+			if err != nil || memberLimit < 0 {
+				passResponse(rw, "Invalid members provided", false, http.StatusBadRequest)
+
+				return
+			}
+
+			if memberLimit > maxGuildSyncMembers {
+				memberLimit = maxGuildSyncMembers
+			}
+		}
+
+		var after int64
+
+		if value := query.Get("after"); value != "" {
+			after, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				passResponse(rw, "Invalid after provided", false, http.StatusBadRequest)
+
+				return
+			}
+
+			if memberLimit == 0 {
+				memberLimit = defaultGuildSyncMembers
+			}
+		}
+
+		document, err := sg.State.GuildSync(&StateCtx{Sg: sg}, snowflake.ID(guildID), memberLimit, snowflake.ID(after))
+		if err != nil {
+			passResponse(rw, err.Error(), false, http.StatusNotFound)
+
+			return
+		}
+
+		document.Manager, document.ShardGroup, document.ShardID, _ = sg.GuildOwner(snowflake.ID(guildID))
+
+		if strings.Contains(r.Header.Get("Accept"), "msgpack") {
+			passMsgpackResponse(rw, document, http.StatusOK)
+
+			return
 		}
+
+		passResponse(rw, document, true, http.StatusOK)
 	}
 }
 
-// FetchRestTunnelResponse returns the raw body for the /api/resttunnel request.
-func (sg *Sandwich) FetchRestTunnelResponse() (body []byte, resp *http.Response, err string, ok bool, status int) {
-	if sg.RestTunnelEnabled.IsNotSet() {
-		err = "RestTunnel is not enabled"
-		status = http.StatusOK
-		ok = true
+// passStateResponse responds with a cached object. If the fields query
+// parameter is set, only the comma separated top level fields are returned.
+func passStateResponse(rw http.ResponseWriter, r *http.Request, value interface{}) {
+	fields := r.URL.Query().Get("fields")
+	if fields == "" {
+		passResponse(rw, value, true, http.StatusOK)
 
 		return
 	}
 
-	_url, _err := url.Parse(sg.Configuration.RestTunnel.URL)
-
-	if _err != nil {
-		err = _err.Error()
-		status = http.StatusInternalServerError
+	data, err := json.Marshal(value)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
 
 		return
 	}
 
-	resp, _err = http.Get(_url.Scheme + "://" + _url.Host + "/resttunnel/analytics") //nolint:noctx
+	all := make(map[string]jsoniter.RawMessage)
 
-	if _err != nil {
-		err = _err.Error()
-		status = http.StatusInternalServerError
+	err = json.Unmarshal(data, &all)
+	if err != nil {
+		passResponse(rw, "Fields cannot be filtered on this object", false, http.StatusBadRequest)
 
 		return
 	}
 
-	body, _err = ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
+	filtered := make(map[string]jsoniter.RawMessage)
 
-	if _err != nil {
-		err = _err.Error()
-		status = http.StatusInternalServerError
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if raw, ok := all[field]; ok {
+			filtered[field] = raw
+		}
+	}
 
-		return
+	passResponse(rw, filtered, true, http.StatusOK)
+}
+
+// stateID parses a snowflake from the variables of a request.
+func stateID(r *http.Request, name string) (id snowflake.ID, ok bool) {
+	parsed, err := strconv.ParseInt(mux.Vars(r)[name], 10, 64)
+	if err != nil {
+		return 0, false
 	}
 
-	return body, resp, err, true, http.StatusOK
+	return snowflake.ID(parsed), true
 }
 
-// APIRPCHandler handles the /api/rpc endpoint.
-func APIRPCHandler(sg *Sandwich) http.HandlerFunc {
+// APIStateGuildHandler handles the /api/state/guilds/{id} endpoint. The
+// guild is returned with its roles, channels, threads and emojis.
+func APIStateGuildHandler(sg *Sandwich) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
-		session, _ := sg.Store.Get(r, sessionName)
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
 
-		auth, user := sg.AuthenticateSession(session)
-		if !auth {
-			passResponse(rw, forbiddenMessage, false, http.StatusForbidden)
+			return
+		}
+
+		guildID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid guild provided", false, http.StatusBadRequest)
 
 			return
 		}
 
-		body, err := ioutil.ReadAll(r.Body)
+		guild, err := sg.stateGuild(guildID)
 		if err != nil {
-			passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+			passResponse(rw, err.Error(), false, http.StatusNotFound)
 
 			return
 		}
 
-		RPCMessage := structs.RPCRequest{}
-		err = json.Unmarshal(body, &RPCMessage)
+		passStateResponse(rw, r, guild)
+	}
+}
 
-		if err != nil {
-			passResponse(rw, "Invalid payload sent", false, http.StatusBadRequest)
+// APIStateMembersHandler handles the /api/state/guilds/{id}/members endpoint.
+// Members are returned in order of their ID. limit sets how many are
+// returned and after continues from the next of a previous response. When
+// using the redis backend, only members held in memory are listed.
+func APIStateMembersHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		guildID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid guild provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		query := r.URL.Query()
+
+		limit := defaultGuildSyncMembers
+
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				passResponse(rw, "Invalid limit provided", false, http.StatusBadRequest)
+
+				return
+			}
+
+			if parsed > maxGuildSyncMembers {
+				parsed = maxGuildSyncMembers
+			}
+
+			limit = parsed
+		}
+
+		var after int64
+
+		if value := query.Get("after"); value != "" {
+			var err error
+
+			after, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				passResponse(rw, "Invalid after provided", false, http.StatusBadRequest)
+
+				return
+			}
+		}
+
+		result, ok := sg.stateMembers(guildID, limit, snowflake.ID(after))
+		if !ok {
+			passResponse(rw, ErrGuildNotInState.Error(), false, http.StatusNotFound)
+
+			return
+		}
+
+		passResponse(rw, result, true, http.StatusOK)
+	}
+}
+
+// APIStateMemberHandler handles the /api/state/guilds/{id}/members/{member}
+// endpoint.
+func APIStateMemberHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		guildID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid guild provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		memberID, ok := stateID(r, "member")
+		if !ok {
+			passResponse(rw, "Invalid member provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		member, ok := sg.stateMember(guildID, memberID)
+		if !ok {
+			passResponse(rw, "Member is not in state", false, http.StatusNotFound)
+
+			return
+		}
+
+		passStateResponse(rw, r, member)
+	}
+}
+
+// APIStateRolesHandler handles the /api/state/guilds/{id}/roles endpoint.
+func APIStateRolesHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		guildID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid guild provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		roles, err := sg.stateRoles(guildID)
+		if err != nil {
+			passResponse(rw, err.Error(), false, http.StatusNotFound)
+
+			return
+		}
+
+		passResponse(rw, roles, true, http.StatusOK)
+	}
+}
+
+// APIStateRoleHandler handles the /api/state/guilds/{id}/roles/{role}
+// endpoint.
+func APIStateRoleHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		guildID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid guild provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		roleID, ok := stateID(r, "role")
+		if !ok {
+			passResponse(rw, "Invalid role provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		role, ok := sg.stateRole(guildID, roleID)
+		if !ok {
+			passResponse(rw, "Role is not in state", false, http.StatusNotFound)
+
+			return
+		}
+
+		passStateResponse(rw, r, role)
+	}
+}
+
+// APIStateVoiceStatesHandler handles the /api/state/guilds/{id}/voice_states
+// endpoint. Only users in a voice channel have a voice state.
+func APIStateVoiceStatesHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		guildID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid guild provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		voiceStates, ok := sg.stateVoiceStates(guildID)
+		if !ok {
+			passResponse(rw, ErrGuildNotInState.Error(), false, http.StatusNotFound)
+
+			return
+		}
+
+		passResponse(rw, voiceStates, true, http.StatusOK)
+	}
+}
+
+// APIStateVoiceStateHandler handles the
+// /api/state/guilds/{id}/voice_states/{user} endpoint.
+func APIStateVoiceStateHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		guildID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid guild provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		userID, ok := stateID(r, "user")
+		if !ok {
+			passResponse(rw, "Invalid user provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		voiceState, ok := sg.State.GetVoiceState(guildID, userID)
+		if !ok {
+			passResponse(rw, "User is not in a voice channel", false, http.StatusNotFound)
+
+			return
+		}
+
+		passStateResponse(rw, r, voiceState)
+	}
+}
+
+// APIStateChannelHandler handles the /api/state/channels/{id} endpoint.
+func APIStateChannelHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		channelID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid channel provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		channel, ok := sg.stateChannel(channelID)
+		if !ok {
+			passResponse(rw, "Channel is not in state", false, http.StatusNotFound)
+
+			return
+		}
+
+		passStateResponse(rw, r, channel)
+	}
+}
+
+// APIStateUserHandler handles the /api/state/users/{id} endpoint.
+func APIStateUserHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		userID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid user provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		user, ok := sg.stateUser(userID)
+		if !ok {
+			passResponse(rw, "User is not in state", false, http.StatusNotFound)
+
+			return
+		}
+
+		passStateResponse(rw, r, user)
+	}
+}
+
+// APIStateMutualGuildsHandler handles the /api/state/users/{id}/mutual_guilds
+// endpoint. manager is required and must have caching.store_mutuals enabled.
+// Set expand to true to include the name and icon of each guild.
+func APIStateMutualGuildsHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		userID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid user provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		query := r.URL.Query()
+
+		sg.ManagersMu.RLock()
+		manager, ok := sg.Managers[query.Get("manager")]
+		sg.ManagersMu.RUnlock()
+
+		if !ok {
+			passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		var limit int
+
+		if value := query.Get("limit"); value != "" {
+			var err error
+
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 1 {
+				passResponse(rw, "Invalid limit provided", false, http.StatusBadRequest)
+
+				return
+			}
+		}
+
+		var after int64
+
+		if value := query.Get("after"); value != "" {
+			var err error
+
+			after, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				passResponse(rw, "Invalid after provided", false, http.StatusBadRequest)
+
+				return
+			}
+		}
+
+		result, err := manager.MutualGuilds(userID, limit, snowflake.ID(after), query.Get("expand") == "true")
+		if err != nil {
+			passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+			return
+		}
+
+		passResponse(rw, result, true, http.StatusOK)
+	}
+}
+
+// APIStatePresenceHandler handles the /api/state/presences/{id} endpoint.
+// Presences are only cached when caching.cache_presences is enabled.
+func APIStatePresenceHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, false); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		userID, ok := stateID(r, "id")
+		if !ok {
+			passResponse(rw, "Invalid user provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		presence, ok := sg.statePresence(userID)
+		if !ok {
+			passResponse(rw, "Presence is not in state", false, http.StatusNotFound)
+
+			return
+		}
+
+		passStateResponse(rw, r, presence)
+	}
+}
+
+// APIShardMapHandler handles the /api/shardmap endpoint. The shards of a
+// manager are returned from the cached analytics with how many shards have
+// each status so the dashboard can poll it frequently.
+func APIShardMapHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		identifier := r.URL.Query().Get("manager")
+		analytics := sg.CachedAnalytics(false)
+
+		shards, ok := analytics.ShardMaps[identifier]
+		if !ok {
+			passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		result := structs.APIShardMapResult{
+			Manager:     identifier,
+			GeneratedAt: analytics.GeneratedAt,
+			Age:         analytics.Age,
+			Totals:      make(map[structs.ShardStatus]int),
+			Shards:      shards,
+		}
+
+		for _, shard := range shards {
+			result.Totals[shard.Status]++
+		}
+
+		passResponse(rw, result, true, http.StatusOK)
+	}
+}
+
+// APIErrorsHandler handles the /api/errors endpoint which lists the event
+// types of a manager which have failed whilst updating the state or being
+// published.
+func APIErrorsHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		sg.ManagersMu.RLock()
+		manager, ok := sg.Managers[r.URL.Query().Get("manager")]
+		sg.ManagersMu.RUnlock()
+
+		if !ok {
+			passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		passResponse(rw, manager.EventErrors(), true, http.StatusOK)
+	}
+}
+
+// APIRESTRoutesHandler handles the /api/rest/routes endpoint. The routes of
+// the manager with the most errors and requests are listed. limit defaults
+// to defaultRESTRouteLimit.
+func APIRESTRoutesHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		query := r.URL.Query()
+
+		limit := defaultRESTRouteLimit
+
+		if rawLimit := query.Get("limit"); rawLimit != "" {
+			parsed, err := strconv.Atoi(rawLimit)
+			if err != nil || parsed < 1 {
+				passResponse(rw, "Invalid limit provided", false, http.StatusBadRequest)
+
+				return
+			}
+
+			limit = parsed
+		}
+
+		sg.ManagersMu.RLock()
+		manager, ok := sg.Managers[query.Get("manager")]
+		sg.ManagersMu.RUnlock()
+
+		if !ok {
+			passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		passResponse(rw, manager.restStats.Routes(limit), true, http.StatusOK)
+	}
+}
+
+// APIChunkFailuresHandler handles the /api/state/chunk_failures endpoint. Only
+// guilds which have exhausted their retries are listed unless all is true.
+// The manager query parameter limits the results to a single manager.
+func APIChunkFailuresHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		query := r.URL.Query()
+		pending := query.Get("all") == "true"
+		identifier := query.Get("manager")
+
+		failures := make([]structs.ChunkFailure, 0)
+
+		sg.ManagersMu.RLock()
+		managers := make([]*Manager, 0, len(sg.Managers))
+
+		for key, manager := range sg.Managers {
+			if identifier == "" || key == identifier {
+				managers = append(managers, manager)
+			}
+		}
+		sg.ManagersMu.RUnlock()
+
+		if identifier != "" && len(managers) == 0 {
+			passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		for _, manager := range managers {
+			failures = append(failures, manager.ChunkFailures(pending)...)
+		}
+
+		passResponse(rw, failures, true, http.StatusOK)
+	}
+}
+
+// APITopGuildsHandler handles the /api/state/top_guilds endpoint which lists
+// the guilds of a manager that received the most events. window is a
+// duration such as 1h and defaults to defaultTopGuildsWindow.
+func APITopGuildsHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		query := r.URL.Query()
+
+		window := defaultTopGuildsWindow
+
+		if rawWindow := query.Get("window"); rawWindow != "" {
+			parsed, err := time.ParseDuration(rawWindow)
+			if err != nil || parsed <= 0 || parsed > topGuildsBuckets*topGuildsBucket {
+				passResponse(rw, fmt.Sprintf("Invalid window provided. It must be a duration up to %s",
+					topGuildsBuckets*topGuildsBucket), false, http.StatusBadRequest)
+
+				return
+			}
+
+			window = parsed
+		}
+
+		identifier := query.Get("manager")
+
+		sg.ManagersMu.RLock()
+		manager, ok := sg.Managers[identifier]
+		sg.ManagersMu.RUnlock()
+
+		if !ok {
+			passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		result := manager.TopGuilds(window)
+		result.Manager = identifier
+
+		passResponse(rw, result, true, http.StatusOK)
+	}
+}
+
+// APIIncidentsHandler handles the /api/incidents endpoint which returns the
+// gateway incident in progress and the ones which have ended.
+func APIIncidentsHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		passResponse(rw, sg.Incidents(), true, http.StatusOK)
+	}
+}
+
+// APIRebalanceReportHandler handles the /api/managers/{id}/rebalance_report
+// endpoint which returns the last rebalance report of a manager.
+func APIRebalanceReportHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		sg.ManagersMu.RLock()
+		manager, ok := sg.Managers[mux.Vars(r)["id"]]
+		sg.ManagersMu.RUnlock()
+
+		if !ok {
+			passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		report := manager.RebalanceReport()
+		if report == nil {
+			passResponse(rw, "No rebalance report has been generated yet", false, http.StatusNotFound)
+
+			return
+		}
+
+		passResponse(rw, report, true, http.StatusOK)
+	}
+}
+
+// APIJobsHandler handles the /api/jobs endpoint. It returns every job which
+// has not been dismissed or removed, oldest first.
+func APIJobsHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		jobs := make([]structs.Job, 0)
+		for _, job := range sg.Jobs.List() {
+			jobs = append(jobs, job.API())
+		}
+
+		passResponse(rw, jobs, true, http.StatusOK)
+	}
+}
+
+// APIJobHandler handles the /api/jobs/{id} endpoint. It returns the state
+// and progress of a single job.
+func APIJobHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		jobID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			passResponse(rw, "Invalid job provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		job, ok := sg.Jobs.Get(snowflake.ID(jobID))
+		if !ok {
+			passResponse(rw, ErrJobNotFound.Error(), false, http.StatusNotFound)
+
+			return
+		}
+
+		passResponse(rw, job.API(), true, http.StatusOK)
+	}
+}
+
+// APIUnhandledHandler handles the /api/unhandled endpoint. It returns the
+// dispatch types a manager has received which the daemon has no handler for.
+func APIUnhandledHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		sg.ManagersMu.RLock()
+		manager, ok := sg.Managers[r.URL.Query().Get("manager")]
+		sg.ManagersMu.RUnlock()
+
+		if !ok {
+			passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		passResponse(rw, manager.UnhandledEvents(), true, http.StatusOK)
+	}
+}
+
+// APIUnackedHandler handles the /api/unacked endpoint. It returns the events
+// of a manager waiting for a consumer to acknowledge them and the ones which
+// were dead lettered.
+func APIUnackedHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		sg.ManagersMu.RLock()
+		manager, ok := sg.Managers[r.URL.Query().Get("manager")]
+		sg.ManagersMu.RUnlock()
+
+		if !ok {
+			passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		passResponse(rw, manager.Unacked(), true, http.StatusOK)
+	}
+}
+
+// APIShardLogsHandler handles the /api/debug/shard_logs endpoint. It returns
+// the logging escalation of the shard query parameter and the lines kept
+// whilst it was escalated. The shardgroup query parameter defaults to the
+// newest shardgroup.
+func APIShardLogsHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		query := r.URL.Query()
+
+		sg.ManagersMu.RLock()
+		manager, ok := sg.Managers[query.Get("manager")]
+		sg.ManagersMu.RUnlock()
+
+		if !ok {
+			passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		var shardGroupID *int32
+
+		if rawShardGroup := query.Get("shardgroup"); rawShardGroup != "" {
+			parsed, err := strconv.ParseInt(rawShardGroup, 10, 32)
+			if err != nil {
+				passResponse(rw, "Invalid shardgroup provided", false, http.StatusBadRequest)
+
+				return
+			}
+
+			id := int32(parsed)
+			shardGroupID = &id
+		}
+
+		shardID, err := strconv.Atoi(query.Get("shard"))
+		if err != nil {
+			passResponse(rw, "Invalid shard provided", false, http.StatusBadRequest)
+
+			return
+		}
+
+		shard, err := manager.shard(shardGroupID, shardID)
+		if err != nil {
+			passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+			return
+		}
+
+		passResponse(rw, shard.Logger.Status(), true, http.StatusOK)
+	}
+}
+
+// passMsgpackResponse writes a successful response encoded with msgpack.
+func passMsgpackResponse(rw http.ResponseWriter, data interface{}, status int) {
+	resp, err := msgpack.Marshal(structs.BaseResponse{
+		Success: true,
+		Data:    data,
+	})
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/msgpack")
+	rw.WriteHeader(status)
+
+	if _, err = rw.Write(resp); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// APIRestTunnelHandler handles the /api/resttunnel endpoint.
+func APIRestTunnelHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		body, resp, err, ok, status := sg.FetchRestTunnelResponse() //nolint:bodyclose
+		if err != "" || !ok {
+			passResponse(rw, err, ok, status)
+
+			return
+		}
+
+		// We want to write directly as its a proxied request.
+		rw.WriteHeader(resp.StatusCode)
+
+		if _, err := rw.Write(body); err != nil {
+			passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+		}
+	}
+}
+
+// FetchRestTunnelResponse returns the raw body for the /api/resttunnel request.
+func (sg *Sandwich) FetchRestTunnelResponse() (body []byte, resp *http.Response, err string, ok bool, status int) {
+	if sg.RestTunnelEnabled.IsNotSet() {
+		err = "RestTunnel is not enabled"
+		status = http.StatusOK
+		ok = true
+
+		return
+	}
+
+	_url, _err := url.Parse(sg.Configuration.RestTunnel.URL)
+
+	if _err != nil {
+		err = _err.Error()
+		status = http.StatusInternalServerError
+
+		return
+	}
+
+	resp, _err = http.Get(_url.Scheme + "://" + _url.Host + "/resttunnel/analytics") //nolint:noctx
+
+	if _err != nil {
+		err = _err.Error()
+		status = http.StatusInternalServerError
+
+		return
+	}
+
+	body, _err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if _err != nil {
+		err = _err.Error()
+		status = http.StatusInternalServerError
+
+		return
+	}
+
+	return body, resp, err, true, http.StatusOK
+}
+
+// APIRPCMethodsHandler handles the /api/rpc/methods endpoint. It lists the RPC
+// methods in the same way as daemon:methods.
+func APIRPCMethodsHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+		if _, denied := sg.AuthorizeSession(session, true); denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
+
+			return
+		}
+
+		passResponse(rw, rpcMethods(), true, http.StatusOK)
+	}
+}
+
+// APIRPCHandler handles the /api/rpc endpoint.
+func APIRPCHandler(sg *Sandwich) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		session := sg.requestSession(r)
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+			return
+		}
+
+		RPCMessage := structs.RPCRequest{}
+		err = json.Unmarshal(body, &RPCMessage)
+
+		if err != nil {
+			passResponse(rw, "Invalid payload sent", false, http.StatusBadRequest)
+
+			return
+		}
+
+		user, denied := sg.AuthorizeRPC(session, RPCMessage.Method)
+		if denied != "" {
+			passResponse(rw, denied, false, http.StatusForbidden)
 
 			return
 		}
 
 		ok := executeRequest(sg, user, RPCMessage, rw)
 		if !ok {
-			passResponse(rw, fmt.Sprintf("Unknown method: %s", RPCMessage.Method), false, http.StatusBadRequest)
+			passResponse(rw, fmt.Sprintf(
+				"Unknown method: %s. Methods are listed by daemon:methods and GET /api/rpc/methods",
+				RPCMessage.Method,
+			), false, http.StatusBadRequest)
 
 			return
 		}
@@ -911,11 +2199,37 @@ func createEndpoints(sg *Sandwich) (router *methodrouter.MethodRouter) {
 
 	router.HandleFunc("/api/analytics", APIAnalyticsHandler(sg), "GET")
 	router.HandleFunc("/api/managers", APIManagersHandler(sg), "GET")
+	router.HandleFunc("/api/managers/{id}/rebalance_report", APIRebalanceReportHandler(sg), "GET")
 	router.HandleFunc("/api/configuration", APIConfigurationHandler(sg), "GET")
 	router.HandleFunc("/api/resttunnel", APIRestTunnelHandler(sg), "GET")
+	router.HandleFunc("/api/audit", APIAuditHandler(sg), "GET")
+	router.HandleFunc("/api/jobs", APIJobsHandler(sg), "GET")
+	router.HandleFunc("/api/jobs/{id}", APIJobHandler(sg), "GET")
+	router.HandleFunc("/api/state/guilds/{id}", APIStateGuildHandler(sg), "GET")
+	router.HandleFunc("/api/state/guilds/{id}/sync", APIGuildSyncHandler(sg), "GET")
+	router.HandleFunc("/api/state/guilds/{id}/members", APIStateMembersHandler(sg), "GET")
+	router.HandleFunc("/api/state/guilds/{id}/members/{member}", APIStateMemberHandler(sg), "GET")
+	router.HandleFunc("/api/state/guilds/{id}/roles", APIStateRolesHandler(sg), "GET")
+	router.HandleFunc("/api/state/guilds/{id}/roles/{role}", APIStateRoleHandler(sg), "GET")
+	router.HandleFunc("/api/state/guilds/{id}/voice_states", APIStateVoiceStatesHandler(sg), "GET")
+	router.HandleFunc("/api/state/guilds/{id}/voice_states/{user}", APIStateVoiceStateHandler(sg), "GET")
+	router.HandleFunc("/api/state/channels/{id}", APIStateChannelHandler(sg), "GET")
+	router.HandleFunc("/api/state/users/{id}", APIStateUserHandler(sg), "GET")
+	router.HandleFunc("/api/state/users/{id}/mutual_guilds", APIStateMutualGuildsHandler(sg), "GET")
+	router.HandleFunc("/api/state/presences/{id}", APIStatePresenceHandler(sg), "GET")
+	router.HandleFunc("/api/state/chunk_failures", APIChunkFailuresHandler(sg), "GET")
+	router.HandleFunc("/api/state/top_guilds", APITopGuildsHandler(sg), "GET")
+	router.HandleFunc("/api/shardmap", APIShardMapHandler(sg), "GET")
+	router.HandleFunc("/api/errors", APIErrorsHandler(sg), "GET")
+	router.HandleFunc("/api/incidents", APIIncidentsHandler(sg), "GET")
+	router.HandleFunc("/api/debug/shard_logs", APIShardLogsHandler(sg), "GET")
+	router.HandleFunc("/api/unacked", APIUnackedHandler(sg), "GET")
+	router.HandleFunc("/api/unhandled", APIUnhandledHandler(sg), "GET")
+	router.HandleFunc("/api/rest/routes", APIRESTRoutesHandler(sg), "GET")
 
 	router.HandleFunc("/api/poll", APIPollHandler(sg), "GET")
 	router.HandleFunc("/api/rpc", APIRPCHandler(sg), "POST")
+	router.HandleFunc("/api/rpc/methods", APIRPCMethodsHandler(sg), "GET")
 
 	return
 }
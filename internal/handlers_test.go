@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// managersOrder is the order of managers, shardgroups and shards in a
+// response from /api/managers.
+type managersOrder []struct {
+	Identifier  string `json:"identifier"`
+	ShardGroups []struct {
+		ID     int32 `json:"id"`
+		Shards []struct {
+			ShardID int `json:"shard_id"`
+		} `json:"shards"`
+	} `json:"shard_groups"`
+}
+
+func TestAPIManagersOrder(t *testing.T) {
+	const (
+		managers    = 8
+		shardGroups = 6
+		shards      = 12
+		calls       = 20
+	)
+
+	seed := time.Now().UnixNano()
+	t.Logf("inserting with seed %d", seed)
+
+	random := rand.New(rand.NewSource(seed))
+
+	sg, err := newSandwich(ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to create sandwich: %v", err)
+	}
+
+	sg.Configuration.HTTP.APITokens = []APIToken{{Name: "ci", Token: "secret-token"}}
+
+	for _, i := range random.Perm(managers) {
+		configuration := &ManagerConfiguration{Identifier: fmt.Sprintf("manager%d", i), Token: testToken}
+		configuration.Messaging.ClientName = "sandwich"
+
+		mg, err := sg.NewManager(configuration)
+		if err != nil {
+			t.Fatalf("failed to create manager: %v", err)
+		}
+
+		// IDs are spread out so they are not ordered the same as strings.
+		for _, groupID := range random.Perm(shardGroups) {
+			group := mg.NewShardGroup(int32(groupID * 5))
+
+			for _, shardID := range random.Perm(shards) {
+				group.Shards[shardID*3] = group.NewShard(shardID * 3)
+			}
+
+			mg.ShardGroups[group.ID] = group
+		}
+
+		sg.Managers[configuration.Identifier] = mg
+	}
+
+	handler := APIManagersHandler(sg)
+
+	var first managersOrder
+
+	for call := 0; call < calls; call++ {
+		r := httptest.NewRequest(http.MethodGet, "/api/managers", nil)
+		r.Header.Set("Authorization", "Bearer secret-token")
+
+		rw := httptest.NewRecorder()
+		handler(rw, r)
+
+		order := managersOrder{}
+		response := structs.BaseResponse{Data: &order}
+
+		if err := json.Unmarshal(rw.Body.Bytes(), &response); err != nil || rw.Code != http.StatusOK {
+			t.Fatalf("managers returned %d: %s", rw.Code, rw.Body.String())
+		}
+
+		if call > 0 {
+			if !reflect.DeepEqual(order, first) {
+				t.Fatalf("call %d returned a different order to the first", call)
+			}
+
+			continue
+		}
+
+		first = order
+
+		if len(order) != managers {
+			t.Fatalf("returned %d managers, want %d", len(order), managers)
+		}
+
+		if !sort.SliceIsSorted(order, func(i, j int) bool { return order[i].Identifier < order[j].Identifier }) {
+			t.Errorf("managers are not sorted by identifier: %+v", order)
+		}
+
+		for _, mg := range order {
+			groups := mg.ShardGroups
+			if len(groups) != shardGroups {
+				t.Fatalf("%s returned %d shardgroups, want %d", mg.Identifier, len(groups), shardGroups)
+			}
+
+			if !sort.SliceIsSorted(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID }) {
+				t.Errorf("shardgroups of %s are not sorted by ID: %+v", mg.Identifier, groups)
+			}
+
+			for _, group := range groups {
+				groupShards := group.Shards
+				if len(groupShards) != shards {
+					t.Fatalf("shardgroup %d returned %d shards, want %d", group.ID, len(groupShards), shards)
+				}
+
+				if !sort.SliceIsSorted(groupShards, func(i, j int) bool {
+					return groupShards[i].ShardID < groupShards[j].ShardID
+				}) {
+					t.Errorf("shards of shardgroup %d are not sorted by ID: %+v", group.ID, groupShards)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"github.com/valyala/fasthttp"
+)
+
+// managerReady returns if the active producer shardgroup of the manager has
+// every shard ready or reconnecting and its producer has not failed.
+func (mg *Manager) managerReady() bool {
+	if mg.Producer() == nil || mg.ProducerStatus() == structs.ProducerError {
+		return false
+	}
+
+	for _, shardGroup := range mg.shardGroupsSnapshot() {
+		if !shardGroup.IsActiveProducer() {
+			continue
+		}
+
+		shards := shardGroup.shardsSnapshot()
+		if len(shards) == 0 {
+			return false
+		}
+
+		for _, sh := range shards {
+			sh.StatusMu.RLock()
+			status := sh.Status
+			sh.StatusMu.RUnlock()
+
+			if status != structs.ShardReady && status != structs.ShardReconnecting {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// Readiness returns if the daemon is ready and if each manager counted
+// towards readiness is. http.readiness_managers decides which managers are
+// counted, all managers are when it is empty. The daemon is ready once at
+// least one of them is.
+func (sg *Sandwich) Readiness() (ready bool, managers map[string]bool) {
+	sg.ConfigurationMu.RLock()
+	identifiers := sg.Configuration.HTTP.ReadinessManagers
+	sg.ConfigurationMu.RUnlock()
+
+	snapshot := sg.managersSnapshot()
+	managers = make(map[string]bool)
+
+	if len(identifiers) == 0 {
+		for identifier := range snapshot {
+			identifiers = append(identifiers, identifier)
+		}
+	}
+
+	for _, identifier := range identifiers {
+		mg, ok := snapshot[identifier]
+		managers[identifier] = ok && mg.managerReady()
+
+		if managers[identifier] {
+			ready = true
+		}
+	}
+
+	return ready, managers
+}
+
+// writeHealth writes a probe response without the router so probes stay
+// cheap.
+func writeHealth(ctx *fasthttp.RequestCtx, result structs.APIHealthResult, healthy bool) {
+	status := http.StatusOK
+	if !healthy {
+		status = http.StatusServiceUnavailable
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		status = http.StatusInternalServerError
+	}
+
+	ctx.SetStatusCode(status)
+	ctx.SetContentType("application/json;charset=utf8")
+	ctx.SetBody(body)
+}
+
+// APIHealthz handles /healthz which succeeds for as long as the process is
+// serving HTTP.
+func APIHealthz(sg *Sandwich, ctx *fasthttp.RequestCtx) {
+	writeHealth(ctx, structs.APIHealthResult{Status: "ok"}, true)
+}
+
+// APIReadyz handles /readyz which succeeds once a manager counted towards
+// readiness is ready.
+func APIReadyz(sg *Sandwich, ctx *fasthttp.RequestCtx) {
+	ready, managers := sg.Readiness()
+
+	result := structs.APIHealthResult{
+		Status:   "ok",
+		Managers: managers,
+	}
+
+	if !ready {
+		result.Status = "unavailable"
+	}
+
+	writeHealth(ctx, result, ready)
+}
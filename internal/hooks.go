@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"golang.org/x/xerrors"
+)
+
+// EventHook receives a payload produced by a manager in-process. Hooks run
+// in the produce path before the payload is published so they should return
+// quickly. The payload is reused once the hook returns and must be copied if
+// it is kept. A returned error fails the publish like a producer error.
+type EventHook func(ctx context.Context, packet *structs.SandwichPayload) error
+
+type eventHookEntry struct {
+	id   int64
+	hook EventHook
+}
+
+// AddEventHook registers a hook which receives every payload produced by
+// any manager, including those not sent to the producer when the producer
+// type is none. The returned function removes the hook.
+func (sg *Sandwich) AddEventHook(hook EventHook) (remove func()) {
+	sg.hooksMu.Lock()
+	sg.hooksIter++
+	id := sg.hooksIter
+	sg.hooks = append(sg.hooks, eventHookEntry{id: id, hook: hook})
+	sg.hooksMu.Unlock()
+
+	return func() {
+		sg.hooksMu.Lock()
+		defer sg.hooksMu.Unlock()
+
+		for i, entry := range sg.hooks {
+			if entry.id == id {
+				sg.hooks = append(sg.hooks[:i:i], sg.hooks[i+1:]...)
+
+				return
+			}
+		}
+	}
+}
+
+// runEventHooks passes a payload to each hook. Every hook is run even if
+// an earlier one fails and the first error is returned.
+func (sg *Sandwich) runEventHooks(ctx context.Context, packet *structs.SandwichPayload) (err error) {
+	sg.hooksMu.RLock()
+	hooks := sg.hooks
+	sg.hooksMu.RUnlock()
+
+	for _, entry := range hooks {
+		if hookErr := entry.hook(ctx, packet); hookErr != nil && err == nil {
+			err = xerrors.Errorf("event hook: %w", hookErr)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+// Fields of the identify payload which bot.identify_extra cannot set.
+var identifyCoreFields = map[string]void{
+	"token":               {},
+	"intents":             {},
+	"shard":               {},
+	"properties":          {},
+	"compress":            {},
+	"large_threshold":     {},
+	"presence":            {},
+	"guild_subscriptions": {},
+}
+
+// IdentifyProperties overrides the connection properties sent when a shard
+// identifies. Empty fields use the defaults.
+type IdentifyProperties struct {
+	OS      string `json:"os" yaml:"os"`
+	Browser string `json:"browser" yaml:"browser"`
+	Device  string `json:"device" yaml:"device"`
+}
+
+// validateIdentifyExtra returns an error if any extra identify field would
+// replace a field the daemon sets itself.
+func validateIdentifyExtra(extra map[string]interface{}) error {
+	conflicts := make([]string, 0)
+
+	for key := range extra {
+		if _, ok := identifyCoreFields[strings.ToLower(strings.TrimSpace(key))]; ok {
+			conflicts = append(conflicts, key)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+
+		return xerrors.Errorf("Manager identify_extra cannot set %s", strings.Join(conflicts, ", "))
+	}
+
+	return nil
+}
+
+// identifyPayload builds the data of the identify payload with the extra
+// fields of bot.identify_extra merged in. ConfigurationMu must be held.
+func (sh *Shard) identifyPayload() (payload map[string]interface{}, err error) {
+	bot := &sh.Manager.Configuration.Bot
+
+	properties := &discord.IdentifyProperties{
+		OS:      runtime.GOOS,
+		Browser: "Sandwich " + VERSION,
+		Device:  "Sandwich " + VERSION,
+	}
+
+	if bot.IdentifyProperties.OS != "" {
+		properties.OS = bot.IdentifyProperties.OS
+	}
+
+	if bot.IdentifyProperties.Browser != "" {
+		properties.Browser = bot.IdentifyProperties.Browser
+	}
+
+	if bot.IdentifyProperties.Device != "" {
+		properties.Device = bot.IdentifyProperties.Device
+	}
+
+	identify, err := json.Marshal(discord.Identify{
+		Token:              sh.Manager.Configuration.Token,
+		Properties:         properties,
+		Compress:           bot.Compression && !bot.TransportCompression,
+		LargeThreshold:     bot.LargeThreshold,
+		Shard:              [2]int{sh.ShardID, sh.ShardGroup.ShardCount},
+		Presence:           sh.presence(bot.DefaultPresence),
+		GuildSubscriptions: bot.GuildSubscriptions,
+		Intents:            bot.Intents,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("identify payload marshal: %w", err)
+	}
+
+	payload = make(map[string]interface{})
+
+	if err = json.Unmarshal(identify, &payload); err != nil {
+		return nil, xerrors.Errorf("identify payload unmarshal: %w", err)
+	}
+
+	// Conflicts are rejected when the configuration is loaded but are
+	// skipped here too so the core fields can never be replaced.
+	for key, value := range bot.IdentifyExtra {
+		if _, ok := identifyCoreFields[strings.ToLower(strings.TrimSpace(key))]; !ok {
+			payload[key] = value
+		}
+	}
+
+	return payload, nil
+}
+
+// recordIdentify stores the identify payload which was sent, without the
+// token, in the session of the shard.
+func (sh *Shard) recordIdentify(payload map[string]interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		sh.Logger.Debug().Err(err).Msg("Failed to record identify payload")
+
+		return
+	}
+
+	sh.sessionMu.Lock()
+	sh.session.Identify = []byte(sh.Manager.Redact(string(data)))
+	sh.session.IdentifyAt = time.Now().UTC()
+	sh.sessionMu.Unlock()
+}
@@ -0,0 +1,311 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+const (
+	// Interval between checking if shards are disconnecting together.
+	incidentCheckInterval = 5 * time.Second
+
+	// Incidents kept for /api/incidents once they have ended.
+	maxIncidentHistory = 20
+
+	// Defaults used when the incident configuration is not set.
+	defaultIncidentThreshold      = 25
+	defaultIncidentWindow         = 60
+	defaultIncidentRecovery       = 90
+	defaultIncidentMinShards      = 10
+	defaultIncidentBackoff        = 4
+	defaultIncidentUpdateInterval = 300
+)
+
+// incidentSettings is the incident configuration with defaults applied.
+type incidentSettings struct {
+	enabled        bool
+	threshold      float64
+	window         time.Duration
+	recovery       float64
+	minShards      int
+	backoff        float64
+	updateInterval time.Duration
+}
+
+// incidentSettings returns the incident configuration of the daemon.
+func (sg *Sandwich) incidentSettings() (settings incidentSettings) {
+	sg.ConfigurationMu.RLock()
+	configuration := sg.Configuration.Incident
+	sg.ConfigurationMu.RUnlock()
+
+	settings = incidentSettings{
+		enabled:        configuration.Threshold >= 0,
+		threshold:      configuration.Threshold,
+		window:         time.Duration(configuration.Window) * time.Second,
+		recovery:       configuration.Recovery,
+		minShards:      configuration.MinShards,
+		backoff:        configuration.BackoffMultiplier,
+		updateInterval: time.Duration(configuration.UpdateInterval) * time.Second,
+	}
+
+	if settings.threshold == 0 {
+		settings.threshold = defaultIncidentThreshold
+	}
+
+	if settings.window <= 0 {
+		settings.window = defaultIncidentWindow * time.Second
+	}
+
+	if settings.recovery <= 0 || settings.recovery > 100 {
+		settings.recovery = defaultIncidentRecovery
+	}
+
+	if settings.minShards < 1 {
+		settings.minShards = defaultIncidentMinShards
+	}
+
+	if settings.backoff < 1 {
+		settings.backoff = defaultIncidentBackoff
+	}
+
+	if settings.updateInterval <= 0 {
+		settings.updateInterval = defaultIncidentUpdateInterval * time.Second
+	}
+
+	return settings
+}
+
+// recordDisconnect counts a ready shard losing its connection.
+func (sg *Sandwich) recordDisconnect(now time.Time) {
+	sg.disconnectsMu.Lock()
+	sg.disconnects = append(sg.disconnects, now)
+	sg.disconnectsMu.Unlock()
+
+	sg.incidentMu.Lock()
+	if sg.incident != nil {
+		sg.incident.Disconnects++
+	}
+	sg.incidentMu.Unlock()
+}
+
+// recentDisconnects returns how many shards have disconnected within the
+// window and forgets older disconnects.
+func (sg *Sandwich) recentDisconnects(now time.Time, window time.Duration) int {
+	sg.disconnectsMu.Lock()
+	defer sg.disconnectsMu.Unlock()
+
+	cutoff := now.Add(-window)
+
+	i := 0
+	for i < len(sg.disconnects) && sg.disconnects[i].Before(cutoff) {
+		i++
+	}
+
+	sg.disconnects = sg.disconnects[i:]
+
+	return len(sg.disconnects)
+}
+
+// InIncident returns if the daemon believes discord is having a gateway
+// incident.
+func (sg *Sandwich) InIncident() bool {
+	sg.incidentMu.RLock()
+	defer sg.incidentMu.RUnlock()
+
+	return sg.incident != nil
+}
+
+// incidentCleared returns a channel which is closed whilst there is no
+// incident.
+func (sg *Sandwich) incidentCleared() <-chan void {
+	sg.incidentMu.RLock()
+	defer sg.incidentMu.RUnlock()
+
+	return sg.incidentClear
+}
+
+// incidentBackoff returns how long a shard should wait before trying to
+// reconnect during an incident. The wait is stretched by the backoff
+// multiplier and spread randomly so shards do not reconnect together. 0 is
+// returned when there is no incident.
+func (sg *Sandwich) incidentBackoff(wait time.Duration) time.Duration {
+	if !sg.InIncident() {
+		return 0
+	}
+
+	stretched := int64(float64(wait) * sg.incidentSettings().backoff)
+	if stretched <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(stretched))
+}
+
+// shardHealth counts the shards which have been opened and how many of
+// those are ready.
+func (sg *Sandwich) shardHealth() (total int, ready int) {
+	for _, mg := range sg.managersSnapshot() {
+		for _, shardGroup := range mg.shardGroupsSnapshot() {
+			for _, sh := range shardGroup.shardsSnapshot() {
+				sh.StatusMu.RLock()
+				status := sh.Status
+				sh.StatusMu.RUnlock()
+
+				switch status {
+				case structs.ShardIdle, structs.ShardClosed:
+					continue
+				case structs.ShardReady:
+					ready++
+				case structs.ShardWaiting,
+					structs.ShardConnecting,
+					structs.ShardConnected,
+					structs.ShardReconnecting:
+				}
+
+				total++
+			}
+		}
+	}
+
+	return total, ready
+}
+
+// evaluateIncident starts an incident when enough shards have disconnected
+// within the window and ends it once enough shards are ready again.
+func (sg *Sandwich) evaluateIncident(now time.Time) {
+	settings := sg.incidentSettings()
+	total, ready := sg.shardHealth()
+	recent := sg.recentDisconnects(now, settings.window)
+
+	recovery := float64(100)
+	if total > 0 {
+		recovery = float64(ready) * 100 / float64(total)
+	}
+
+	surge := total >= settings.minShards && float64(recent)*100/float64(total) >= settings.threshold
+
+	sg.incidentMu.Lock()
+
+	var (
+		title  string
+		colour int
+		report structs.Incident
+	)
+
+	switch current := sg.incident; {
+	case current == nil:
+		if !settings.enabled || !surge {
+			sg.incidentMu.Unlock()
+
+			return
+		}
+
+		sg.incident = &structs.Incident{
+			StartedAt:   now,
+			Shards:      total,
+			Disconnects: int64(recent),
+			PeakDown:    total - ready,
+			Recovery:    recovery,
+		}
+		sg.incidentClear = make(chan void)
+		sg.incidentUpdated = now
+
+		title, colour, report = "Gateway incident detected", discord.EmbedDanger, *sg.incident
+
+		sg.Logger.Warn().
+			Int("shards", total).
+			Int("disconnects", recent).
+			Msg("Shards are disconnecting together. Entering incident mode")
+	case recovery >= settings.recovery && !surge:
+		current.Shards = total
+		current.Recovery = recovery
+		current.Duration = now.Sub(current.StartedAt).Milliseconds()
+		current.EndedAt = &now
+
+		sg.incidentHistory = append(sg.incidentHistory, *current)
+		if len(sg.incidentHistory) > maxIncidentHistory {
+			sg.incidentHistory = sg.incidentHistory[len(sg.incidentHistory)-maxIncidentHistory:]
+		}
+
+		sg.incident = nil
+		close(sg.incidentClear)
+
+		title, colour, report = "Gateway incident has ended", discord.EmbedSandwich, *current
+
+		sg.Logger.Info().
+			Float64("recovery", recovery).
+			Int64("duration", current.Duration).
+			Msg("Shards have recovered. Leaving incident mode")
+	default:
+		current.Shards = total
+		current.Recovery = recovery
+		current.Duration = now.Sub(current.StartedAt).Milliseconds()
+
+		if down := total - ready; down > current.PeakDown {
+			current.PeakDown = down
+		}
+
+		if now.Sub(sg.incidentUpdated) >= settings.updateInterval {
+			sg.incidentUpdated = now
+
+			title, colour, report = "Gateway incident is ongoing", discord.EmbedWarning, *current
+		}
+	}
+
+	sg.incidentMu.Unlock()
+
+	if title != "" {
+		go sg.publishIncidentWebhook(title, colour, report)
+	}
+}
+
+// publishIncidentWebhook sends the summary of an incident. It replaces the
+// webhooks of each shard which are suppressed during the incident.
+func (sg *Sandwich) publishIncidentWebhook(title string, colour int, incident structs.Incident) {
+	description := fmt.Sprintf("%d of %d shards were disconnected at the worst point and %d disconnects "+
+		"have been seen. %.1f%% of shards are ready.",
+		incident.PeakDown, incident.Shards, incident.Disconnects, incident.Recovery)
+
+	if incident.Duration > 0 {
+		description += fmt.Sprintf("\nThe incident has lasted %s.",
+			(time.Duration(incident.Duration) * time.Millisecond).Round(time.Second))
+	}
+
+	sg.Notify(context.Background(), NewNotification(title, description).WithColour(colour))
+}
+
+// Incidents returns the current incident and the incidents which have ended.
+func (sg *Sandwich) Incidents() (result structs.APIIncidentsResult) {
+	sg.incidentMu.RLock()
+	defer sg.incidentMu.RUnlock()
+
+	if sg.incident != nil {
+		current := *sg.incident
+		result.Current = &current
+	}
+
+	result.History = make([]structs.Incident, len(sg.incidentHistory))
+	copy(result.History, sg.incidentHistory)
+
+	return result
+}
+
+// incidentRunner checks for gateway incidents until the daemon shuts down.
+func (sg *Sandwich) incidentRunner() {
+	t := time.NewTicker(incidentCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-sg.ctx.Done():
+			return
+		case now := <-t.C:
+			sg.evaluateIncident(now.UTC())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestIncidentRunnerStops(t *testing.T) {
+	sg, err := newSandwich(ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to create sandwich: %v", err)
+	}
+
+	done := make(chan void)
+
+	go func() {
+		sg.incidentRunner()
+		close(done)
+	}()
+
+	sg.cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("incidentRunner did not stop once the daemon was cancelled")
+	}
+}
+
+func TestRecordDisconnect(t *testing.T) {
+	sg, err := newSandwich(ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to create sandwich: %v", err)
+	}
+
+	now := time.Now().UTC()
+
+	sg.recordDisconnect(now.Add(-2 * time.Minute))
+	sg.recordDisconnect(now)
+	sg.recordDisconnect(now)
+
+	if got := sg.recentDisconnects(now, time.Minute); got != 2 {
+		t.Errorf("recentDisconnects = %d, want 2", got)
+	}
+}
@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+// IntentWarnings cross-checks the intents against every event type referenced
+// in the configuration and returns a warning for each one that will not be
+// received. No warnings are returned when intents are not set, as every event
+// is sent without them.
+func (mc *ManagerConfiguration) IntentWarnings() (warnings []structs.ConfigurationWarning) {
+	intents := uint(mc.Bot.Intents)
+	if intents == 0 {
+		return nil
+	}
+
+	settings := []struct {
+		name    string
+		entries []string
+	}{
+		{"events.event_blacklist", mc.Events.EventBlacklist},
+		{"events.produce_blacklist", mc.Events.ProduceBlacklist},
+		{"caching.lazy_member_events", mc.Caching.LazyMemberEvents},
+	}
+
+	for _, setting := range settings {
+		for _, entry := range setting.entries {
+			required, ok := missingIntents(NormalizeEventName(entry), intents)
+			if !ok {
+				continue
+			}
+
+			names := discord.IntentsToNames(required)
+
+			warnings = append(warnings, structs.ConfigurationWarning{
+				Manager: mc.Identifier,
+				Setting: setting.name,
+				Event:   entry,
+				Intents: names,
+				Message: fmt.Sprintf("%s references %s which will not be received without the %s intent",
+					setting.name, entry, strings.Join(names, " or ")),
+			})
+		}
+	}
+
+	return warnings
+}
+
+// missingIntents returns the intents needed for an event type or pattern if
+// none of the events it matches are received with the intents provided.
+func missingIntents(name string, intents uint) (required uint, missing bool) {
+	if !strings.ContainsAny(name, "*?[") {
+		if discord.EventReceived(name, intents) {
+			return 0, false
+		}
+
+		return discord.EventIntents[name], true
+	}
+
+	for _, event := range KnownEvents {
+		if ok, _ := path.Match(name, event); !ok {
+			continue
+		}
+
+		if discord.EventReceived(event, intents) {
+			return 0, false
+		}
+
+		required |= discord.EventIntents[event]
+	}
+
+	return required, required != 0
+}
+
+// ValidateConfiguration returns the warnings of the producer, the channels
+// managers publish to and the intents and event names of every manager
+// configuration.
+func (sg *Sandwich) ValidateConfiguration() (warnings []structs.ConfigurationWarning) {
+	warnings = make([]structs.ConfigurationWarning, 0)
+
+	sg.ConfigurationMu.RLock()
+	warnings = append(warnings, sg.Configuration.ProducerWarnings()...)
+	sg.ConfigurationMu.RUnlock()
+
+	warnings = append(warnings, sg.managerChannelWarnings(nil)...)
+
+	sg.ManagersMu.RLock()
+	defer sg.ManagersMu.RUnlock()
+
+	for _, mg := range sg.Managers {
+		mg.ConfigurationMu.RLock()
+		warnings = append(warnings, mg.Configuration.IntentWarnings()...)
+		warnings = append(warnings, mg.Configuration.EventNameWarnings()...)
+		mg.ConfigurationMu.RUnlock()
+	}
+
+	return warnings
+}
+
+// logIntentWarnings logs any events in the configuration which will not be
+// received with the configured intents.
+func (mg *Manager) logIntentWarnings(mc *ManagerConfiguration) {
+	for _, warning := range mc.IntentWarnings() {
+		mg.Logger.Warn().
+			Str("setting", warning.Setting).
+			Str("event", warning.Event).
+			Strs("intents", warning.Intents).
+			Msg("Configured event will not be received with the current intents")
+	}
+}
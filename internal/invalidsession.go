@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+const (
+	// Discord requires waiting between 1 and 5 seconds before identifying
+	// after an invalid session which cannot be resumed.
+	invalidSessionMinWait = time.Second
+	invalidSessionMaxWait = 5 * time.Second
+
+	// Defaults used when the invalid session configuration is not set.
+	defaultInvalidSessionWindow         = 300
+	defaultInvalidSessionShardThreshold = 3
+	defaultInvalidSessionThreshold      = 10
+)
+
+// invalidSessionCounter counts invalid sessions over a rolling window.
+type invalidSessionCounter struct {
+	mu      sync.Mutex
+	times   []time.Time
+	alerted time.Time // When the last alert was sent
+}
+
+func newInvalidSessionCounter() *invalidSessionCounter {
+	return &invalidSessionCounter{
+		mu: sync.Mutex{},
+	}
+}
+
+// trim forgets invalid sessions older than the window. mu must be held.
+func (c *invalidSessionCounter) trim(now time.Time, window time.Duration) {
+	cutoff := now.Add(-window)
+
+	i := 0
+	for i < len(c.times) && c.times[i].Before(cutoff) {
+		i++
+	}
+
+	c.times = c.times[i:]
+}
+
+// record counts an invalid session and returns how many happened within the
+// window. alert is true when the threshold is reached and no alert has been
+// sent within the window.
+func (c *invalidSessionCounter) record(now time.Time, window time.Duration, threshold int) (count int, alert bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.trim(now, window)
+	c.times = append(c.times, now)
+	count = len(c.times)
+
+	if count >= threshold && now.Sub(c.alerted) >= window {
+		c.alerted = now
+		alert = true
+	}
+
+	return count, alert
+}
+
+// count returns the invalid sessions within the window.
+func (c *invalidSessionCounter) count(now time.Time, window time.Duration) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.trim(now, window)
+
+	return len(c.times)
+}
+
+// invalidSessionWindow returns bot.invalid_session_window as a duration.
+func (mg *Manager) invalidSessionWindow() time.Duration {
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	return time.Duration(mg.Configuration.Bot.InvalidSessionWindow) * time.Second
+}
+
+// InvalidSessions returns the invalid sessions the shards of the manager have
+// received within bot.invalid_session_window.
+func (mg *Manager) InvalidSessions() int {
+	return mg.invalidSessions.count(time.Now().UTC(), mg.invalidSessionWindow())
+}
+
+// invalidSessionWait returns a random wait between 1 and 5 seconds.
+func invalidSessionWait() time.Duration {
+	return invalidSessionMinWait + time.Duration(rand.Int63n(int64(invalidSessionMaxWait-invalidSessionMinWait)))
+}
+
+// recordInvalidSession counts an invalid session for the shard and its
+// manager and alerts when either passes its threshold.
+func (sh *Shard) recordInvalidSession(resumable bool) {
+	now := time.Now().UTC()
+
+	sh.Manager.ConfigurationMu.RLock()
+	window := time.Duration(sh.Manager.Configuration.Bot.InvalidSessionWindow) * time.Second
+	shardThreshold := sh.Manager.Configuration.Bot.InvalidSessionShardThreshold
+	managerThreshold := sh.Manager.Configuration.Bot.InvalidSessionThreshold
+	sh.Manager.ConfigurationMu.RUnlock()
+
+	shardCount, shardAlert := sh.invalidSessions.record(now, window, shardThreshold)
+	managerCount, managerAlert := sh.Manager.invalidSessions.record(now, window, managerThreshold)
+
+	if shardAlert {
+		sh.Logger.Warn().
+			Int("invalid_sessions", shardCount).
+			Dur("window", window).
+			Msg("Shard is receiving invalid sessions frequently")
+
+		go sh.PublishWebhook("Shard is receiving invalid sessions frequently",
+			fmt.Sprintf("%d invalid sessions in the last %s. The last one was %s.\n%s",
+				shardCount, window, resumableDescription(resumable), sh.Manager.invalidSessionHints()),
+			discord.EmbedWarning, false)
+	}
+
+	if managerAlert {
+		sh.Manager.Logger.Warn().
+			Int("invalid_sessions", managerCount).
+			Dur("window", window).
+			Msg("Manager is receiving invalid sessions frequently. Check for duplicate instances or an exhausted session limit")
+
+		go sh.Manager.publishInvalidSessionWebhook(managerCount, window)
+	}
+}
+
+func resumableDescription(resumable bool) string {
+	if resumable {
+		return "resumable"
+	}
+
+	return "not resumable"
+}
+
+// invalidSessionHints lists the usual causes of frequent invalid sessions.
+// The session limit is called out when it is close to running out.
+func (mg *Manager) invalidSessionHints() string {
+	hints := []string{
+		"Another instance may be running with the same token and shards",
+		"Intents may have changed or include privileged intents which are not enabled",
+	}
+
+	mg.GatewayMu.RLock()
+	remaining := mg.Gateway.SessionStartLimit.Remaining
+	total := mg.Gateway.SessionStartLimit.Total
+	mg.GatewayMu.RUnlock()
+
+	limit := "The session start limit may be exhausted by repeated identifies"
+	if total > 0 && remaining*10 < total {
+		limit = fmt.Sprintf("Only %d of %d session starts remain, repeated identifies are exhausting the limit",
+			remaining, total)
+		hints = append([]string{limit}, hints...)
+	} else {
+		hints = append(hints, limit)
+	}
+
+	return "Likely causes:\n- " + strings.Join(hints, "\n- ")
+}
+
+// publishInvalidSessionWebhook alerts that the shards of the manager as a
+// whole are receiving invalid sessions frequently.
+func (mg *Manager) publishInvalidSessionWebhook(count int, window time.Duration) {
+	mg.Sandwich.Notify(context.Background(),
+		NewNotification("Manager is receiving invalid sessions frequently",
+			fmt.Sprintf("%d invalid sessions in the last %s.\n%s", count, window, mg.invalidSessionHints())).
+			ForManager(mg).
+			WithSeverity(SeverityDanger))
+}
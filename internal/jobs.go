@@ -0,0 +1,377 @@
+package gateway
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"golang.org/x/xerrors"
+)
+
+// Types of job.
+const (
+	JobShardGroupCreate = "shardgroup:create"
+	JobShardGroupRoll   = "shardgroup:roll"
+)
+
+// Seconds a finished job is kept before it is removed if it has not been
+// dismissed and job_retention is not set.
+const defaultJobRetention = 24 * 60 * 60
+
+// ErrJobNotFound is returned when a job does not exist or has been removed.
+var ErrJobNotFound = xerrors.New("no job exists with this id")
+
+// ErrJobRunning is returned when dismissing a job that has started but not
+// yet finished.
+var ErrJobRunning = xerrors.New("job is running and cannot be dismissed until it has finished")
+
+// Job is a long running action started through RPC. Jobs are only kept in
+// memory and are removed when dismissed or once job_retention has passed
+// since they finished.
+type Job struct {
+	ID                  snowflake.ID
+	Type                string
+	Manager             string
+	User                *structs.DiscordUser
+	ShardIDs            []int
+	Created             time.Time
+	StartAt             time.Time
+	EstimatedCompletion time.Time
+
+	statusMu   sync.RWMutex
+	status     structs.JobStatus
+	err        string
+	started    time.Time
+	finished   time.Time
+	shardGroup *ShardGroup
+
+	// Closed when a scheduled job is dismissed.
+	cancel chan void
+}
+
+// JobStore keeps the jobs started on the daemon.
+type JobStore struct {
+	ids *snowflake.Generator
+
+	jobsMu sync.RWMutex
+	jobs   map[snowflake.ID]*Job
+}
+
+// NewJobStore creates an empty JobStore.
+func NewJobStore() (js *JobStore, err error) {
+	ids, err := snowflake.NewGenerator(0, 0)
+	if err != nil {
+		return nil, xerrors.Errorf("new job store: %w", err)
+	}
+
+	return &JobStore{
+		ids: ids,
+
+		jobsMu: sync.RWMutex{},
+		jobs:   make(map[snowflake.ID]*Job),
+	}, nil
+}
+
+// estimateShardGroupStart returns how long starting shardIDs is expected to
+// take. Shards identify in buckets of maxConcurrency every identifyRatelimit.
+func estimateShardGroupStart(shardIDs []int, maxConcurrency int) time.Duration {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	buckets := math.Ceil(float64(len(shardIDs)) / float64(maxConcurrency))
+
+	return time.Duration(buckets) * identifyRatelimit
+}
+
+// Create adds a new job. If startAt is zero or in the past, the job can start
+// immediately.
+func (js *JobStore) Create(jobType string, manager string, user *structs.DiscordUser, shardIDs []int,
+	startAt time.Time, duration time.Duration) (job *Job) {
+	now := time.Now().UTC()
+
+	if startAt.Before(now) {
+		startAt = now
+	}
+
+	job = &Job{
+		ID:                  js.ids.Generate(),
+		Type:                jobType,
+		Manager:             manager,
+		User:                user,
+		ShardIDs:            shardIDs,
+		Created:             now,
+		StartAt:             startAt.UTC(),
+		EstimatedCompletion: startAt.UTC().Add(duration),
+
+		statusMu: sync.RWMutex{},
+		status:   structs.JobScheduled,
+
+		cancel: make(chan void),
+	}
+
+	js.jobsMu.Lock()
+	js.jobs[job.ID] = job
+	js.jobsMu.Unlock()
+
+	return job
+}
+
+// Get returns a job by its id.
+func (js *JobStore) Get(id snowflake.ID) (job *Job, ok bool) {
+	js.jobsMu.RLock()
+	job, ok = js.jobs[id]
+	js.jobsMu.RUnlock()
+
+	return
+}
+
+// List returns every job, oldest first.
+func (js *JobStore) List() (jobs []*Job) {
+	js.jobsMu.RLock()
+	jobs = make([]*Job, 0, len(js.jobs))
+
+	for _, job := range js.jobs {
+		jobs = append(jobs, job)
+	}
+	js.jobsMu.RUnlock()
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+
+	return jobs
+}
+
+// Dismiss removes a job. Scheduled jobs are cancelled before they start
+// however jobs which are running cannot be dismissed.
+func (js *JobStore) Dismiss(id snowflake.ID) (err error) {
+	js.jobsMu.Lock()
+	defer js.jobsMu.Unlock()
+
+	job, ok := js.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	job.statusMu.Lock()
+	defer job.statusMu.Unlock()
+
+	switch job.status {
+	case structs.JobRunning:
+		return ErrJobRunning
+	case structs.JobScheduled:
+		job.status = structs.JobCancelled
+		job.finished = time.Now().UTC()
+		close(job.cancel)
+	}
+
+	delete(js.jobs, id)
+
+	return nil
+}
+
+// Prune removes jobs which finished longer than retention ago.
+func (js *JobStore) Prune(now time.Time, retention time.Duration) {
+	js.jobsMu.Lock()
+	defer js.jobsMu.Unlock()
+
+	for id, job := range js.jobs {
+		job.statusMu.RLock()
+		finished := job.finished
+		job.statusMu.RUnlock()
+
+		if !finished.IsZero() && now.Sub(finished) > retention {
+			delete(js.jobs, id)
+		}
+	}
+}
+
+// waitForStart blocks until the job should start and marks it as running.
+// False is returned if the job was dismissed whilst waiting.
+func (job *Job) waitForStart() bool {
+	t := time.NewTimer(time.Until(job.StartAt))
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-job.cancel:
+		return false
+	}
+
+	job.statusMu.Lock()
+	defer job.statusMu.Unlock()
+
+	if job.status != structs.JobScheduled {
+		return false
+	}
+
+	job.status = structs.JobRunning
+	job.started = time.Now().UTC()
+
+	return true
+}
+
+// attach sets the shardgroup the job reports the progress of.
+func (job *Job) attach(sg *ShardGroup) {
+	job.statusMu.Lock()
+	job.shardGroup = sg
+	job.statusMu.Unlock()
+}
+
+// finish marks the job as completed or failed if err is not nil.
+func (job *Job) finish(err error) {
+	job.statusMu.Lock()
+	defer job.statusMu.Unlock()
+
+	if err != nil {
+		job.status = structs.JobFailed
+		job.err = err.Error()
+	} else {
+		job.status = structs.JobCompleted
+	}
+
+	job.finished = time.Now().UTC()
+}
+
+// API returns the job with the progress of each of its shards.
+func (job *Job) API() (result structs.Job) {
+	job.statusMu.RLock()
+	result = structs.Job{
+		ID:                  job.ID,
+		Type:                job.Type,
+		Manager:             job.Manager,
+		Status:              job.status,
+		Error:               job.err,
+		User:                job.User,
+		Created:             job.Created,
+		StartAt:             job.StartAt,
+		Started:             job.started,
+		Finished:            job.finished,
+		EstimatedCompletion: job.EstimatedCompletion,
+		ShardIDs:            job.ShardIDs,
+		Shards:              make(map[int]structs.JobShard),
+		Total:               len(job.ShardIDs),
+	}
+	sg := job.shardGroup
+	job.statusMu.RUnlock()
+
+	if sg == nil {
+		return result
+	}
+
+	shardGroupID := sg.ID
+	result.ShardGroup = &shardGroupID
+
+	sg.ShardsMu.RLock()
+	for shardID, shard := range sg.Shards {
+		shard.StatusMu.RLock()
+		result.Shards[shardID] = structs.JobShard{
+			Status:      shard.Status,
+			StatusSince: shard.StatusSince,
+		}
+		shard.StatusMu.RUnlock()
+
+		if result.Shards[shardID].Status == structs.ShardReady {
+			result.Ready++
+		}
+	}
+	sg.ShardsMu.RUnlock()
+
+	return result
+}
+
+// runShardGroupCreate starts a shardgroup for a job once its start time has
+// passed and waits for it to either be ready or to close.
+func (mg *Manager) runShardGroupCreate(job *Job, shardCount int) {
+	if !job.waitForStart() {
+		mg.Logger.Info().Int64("job", job.ID.Int64()).Msg("Scheduled shardgroup creation was cancelled")
+
+		return
+	}
+
+	_, err := mg.openJobShardGroup(job, shardCount)
+	job.finish(err)
+}
+
+// runShardGroupRoll replaces a shardgroup for a job. A new shardgroup with
+// the shards of the job is started and, once every shard is ready, the
+// shardgroup being replaced is closed and removed from the manager. If the
+// new shardgroup fails, the old one is left running.
+func (mg *Manager) runShardGroupRoll(job *Job, shardCount int, replacing *ShardGroup) {
+	if !job.waitForStart() {
+		mg.Logger.Info().Int64("job", job.ID.Int64()).Msg("Scheduled shardgroup roll was cancelled")
+
+		return
+	}
+
+	sg, err := mg.openJobShardGroup(job, shardCount)
+	if err != nil {
+		job.finish(xerrors.Errorf("roll shardgroup %d: %w", replacing.ID, err))
+
+		return
+	}
+
+	// Open closes every older shardgroup before signalling it is ready.
+	mg.ShardGroupsMu.Lock()
+	if mg.ShardGroups[replacing.ID] == replacing {
+		delete(mg.ShardGroups, replacing.ID)
+	}
+	mg.ShardGroupsMu.Unlock()
+
+	mg.Logger.Info().
+		Int32("shardgroup", sg.ID).
+		Int32("replaced", replacing.ID).
+		Msg("Rolled shardgroup")
+
+	job.finish(nil)
+}
+
+// openJobShardGroup starts a shardgroup with the shards of a job and waits
+// for it to either be ready or to close.
+func (mg *Manager) openJobShardGroup(job *Job, shardCount int) (sg *ShardGroup, err error) {
+	sg = mg.newScaledShardGroup()
+	job.attach(sg)
+
+	ready, err := sg.Open(job.ShardIDs, shardCount)
+	if err != nil {
+		return sg, err
+	}
+
+	select {
+	case <-ready:
+	case <-sg.close:
+		select {
+		case <-ready:
+		default:
+			return sg, xerrors.New("shardgroup was closed before it was ready")
+		}
+	}
+
+	return sg, nil
+}
+
+// jobRetention returns job_retention as a duration.
+func (sg *Sandwich) jobRetention() time.Duration {
+	sg.ConfigurationMu.RLock()
+	seconds := sg.Configuration.JobRetention
+	sg.ConfigurationMu.RUnlock()
+
+	if seconds < 1 {
+		seconds = defaultJobRetention
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// jobRunner removes expired jobs.
+func (sg *Sandwich) jobRunner() {
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+
+	for {
+		now := (<-t.C).UTC()
+		sg.Jobs.Prune(now, sg.jobRetention())
+	}
+}
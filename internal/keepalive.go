@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// Event type of keepalive payloads.
+const keepaliveEvent = "SANDWICH_KEEPALIVE"
+
+// Time between checking if keepalives have been enabled.
+const keepaliveIdleInterval = 5 * time.Second
+
+// keepaliveRunner publishes a keepalive every messaging.keepalive_interval
+// seconds until the manager is closed. The interval is read again after each
+// keepalive so changes apply without restarting the manager.
+func (mg *Manager) keepaliveRunner() {
+	if !mg.keepaliveActive.SetToIf(false, true) {
+		return
+	}
+	defer mg.keepaliveActive.UnSet()
+
+	for {
+		mg.ConfigurationMu.RLock()
+		interval := time.Duration(mg.Configuration.Messaging.KeepaliveInterval) * time.Second
+		mg.ConfigurationMu.RUnlock()
+
+		wait := interval
+		if interval <= 0 {
+			wait = keepaliveIdleInterval
+		}
+
+		t := time.NewTimer(wait)
+
+		select {
+		case <-mg.ctx.Done():
+			t.Stop()
+
+			return
+		case <-t.C:
+		}
+
+		if interval > 0 {
+			mg.publishKeepalive()
+		}
+	}
+}
+
+// publishKeepalive sends a keepalive to consumers. Unless
+// messaging.keepalive_analytics is set, it is not counted in analytics.
+func (mg *Manager) publishKeepalive() {
+	mg.ConfigurationMu.RLock()
+	identifier := mg.Configuration.Identifier
+	track := mg.Configuration.Messaging.KeepaliveAnalytics
+	mg.ConfigurationMu.RUnlock()
+
+	err := mg.publishEvent(keepaliveEvent, structs.MessagingKeepalive{
+		Identifier: identifier,
+		Sequence:   mg.PublishedCount(),
+		Time:       time.Now().UTC().UnixNano() / int64(time.Millisecond),
+	}, track)
+	if err != nil {
+		mg.Logger.Warn().Err(err).Msg("Failed to publish keepalive")
+	}
+}
+
+// PublishedCount returns the number of payloads the manager has
+// successfully published.
+func (mg *Manager) PublishedCount() int64 {
+	return atomic.LoadInt64(mg.published)
+}
@@ -0,0 +1,256 @@
+package gateway
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+const (
+	// Prefix of the nonce used for lazy member requests so their chunks
+	// can be told apart from full guild chunks.
+	lazyMemberNoncePrefix = "lazy:"
+
+	// Time lookups for the same guild are grouped before being requested.
+	lazyMemberBatchWindow = 20 * time.Millisecond
+
+	// Discord allows up to 100 user IDs per request.
+	lazyMemberBatchLimit = 100
+
+	// Time a request is kept around waiting for its chunks.
+	lazyMemberRequestTimeout = 10 * time.Second
+
+	// Default milliseconds an event will be held for whilst fetching its member.
+	defaultLazyMemberBudget = 150
+)
+
+// lazyMemberBatch is a group of member lookups for a single guild.
+type lazyMemberBatch struct {
+	nonce   string
+	guildID snowflake.ID
+	userIDs []snowflake.ID
+	waiters map[snowflake.ID][]chan *discord.GuildMember
+}
+
+// lazyMemberFetcher requests specific members of a guild when they are
+// missing from the cache and groups lookups made within a short window.
+type lazyMemberFetcher struct {
+	sh *Shard
+
+	mu       sync.Mutex
+	pending  map[snowflake.ID]*lazyMemberBatch // Batches waiting to be sent by guild
+	inflight map[string]*lazyMemberBatch       // Batches that have been sent by nonce
+
+	nonce *int64
+}
+
+func newLazyMemberFetcher(sh *Shard) *lazyMemberFetcher {
+	return &lazyMemberFetcher{
+		sh: sh,
+
+		mu:       sync.Mutex{},
+		pending:  make(map[snowflake.ID]*lazyMemberBatch),
+		inflight: make(map[string]*lazyMemberBatch),
+
+		nonce: new(int64),
+	}
+}
+
+// Fetch requests a member and waits for it up until the budget.
+func (lf *lazyMemberFetcher) Fetch(guildID snowflake.ID, userID snowflake.ID,
+	budget time.Duration) (member *discord.GuildMember, ok bool) {
+	ch := make(chan *discord.GuildMember, 1)
+
+	lf.mu.Lock()
+	batch, ok := lf.pending[guildID]
+
+	if !ok {
+		batch = &lazyMemberBatch{
+			nonce:   lazyMemberNoncePrefix + strconv.FormatInt(atomic.AddInt64(lf.nonce, 1), 36),
+			guildID: guildID,
+			userIDs: make([]snowflake.ID, 0, 1),
+			waiters: make(map[snowflake.ID][]chan *discord.GuildMember),
+		}
+
+		lf.pending[guildID] = batch
+
+		time.AfterFunc(lazyMemberBatchWindow, func() { lf.send(batch) })
+	}
+
+	if _, ok := batch.waiters[userID]; !ok {
+		batch.userIDs = append(batch.userIDs, userID)
+	}
+
+	batch.waiters[userID] = append(batch.waiters[userID], ch)
+	full := len(batch.userIDs) >= lazyMemberBatchLimit
+	lf.mu.Unlock()
+
+	if full {
+		go lf.send(batch)
+	}
+
+	t := time.NewTimer(budget)
+	defer t.Stop()
+
+	select {
+	case member = <-ch:
+		return member, member != nil
+	case <-t.C:
+		return nil, false
+	}
+}
+
+// send requests the members of a batch if it has not already been sent.
+func (lf *lazyMemberFetcher) send(batch *lazyMemberBatch) {
+	lf.mu.Lock()
+	if lf.pending[batch.guildID] != batch {
+		lf.mu.Unlock()
+
+		return
+	}
+
+	delete(lf.pending, batch.guildID)
+	lf.inflight[batch.nonce] = batch
+	lf.mu.Unlock()
+
+	err := lf.sh.SendEvent(discord.GatewayOpRequestGuildMembers, discord.RequestGuildMembers{
+		GuildID: batch.guildID,
+		Nonce:   batch.nonce,
+		UserIDs: batch.userIDs,
+	})
+	if err != nil {
+		lf.sh.Logger.Warn().Err(err).
+			Int64("guild_id", batch.guildID.Int64()).
+			Msg("Failed to request lazy members")
+
+		lf.finish(batch.nonce)
+
+		return
+	}
+
+	time.AfterFunc(lazyMemberRequestTimeout, func() { lf.finish(batch.nonce) })
+}
+
+// Resolve passes members from a chunk to anything waiting on them. It
+// returns false if the chunk was not for a lazy member request.
+func (lf *lazyMemberFetcher) Resolve(chunk *discord.GuildMembersChunk) bool {
+	if !strings.HasPrefix(chunk.Nonce, lazyMemberNoncePrefix) {
+		return false
+	}
+
+	lf.mu.Lock()
+	batch, ok := lf.inflight[chunk.Nonce]
+
+	if ok {
+		for _, member := range chunk.Members {
+			if member.User == nil {
+				continue
+			}
+
+			for _, ch := range batch.waiters[member.User.ID] {
+				ch <- member
+			}
+
+			delete(batch.waiters, member.User.ID)
+		}
+	}
+	lf.mu.Unlock()
+
+	if ok && chunk.ChunkIndex >= chunk.ChunkCount-1 {
+		lf.finish(chunk.Nonce)
+	}
+
+	return true
+}
+
+// finish removes a sent batch and releases anything still waiting.
+func (lf *lazyMemberFetcher) finish(nonce string) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+
+	batch, ok := lf.inflight[nonce]
+	if !ok {
+		return
+	}
+
+	delete(lf.inflight, nonce)
+
+	for _, waiters := range batch.waiters {
+		for _, ch := range waiters {
+			ch <- nil
+		}
+	}
+}
+
+// APILazyMembers returns the lazy member counters for /api/status. This
+// returns nil if no events have lazy member fetching enabled.
+func (mg *Manager) APILazyMembers() *structs.APIStatusLazyMembers {
+	mg.ConfigurationMu.RLock()
+	enabled := len(mg.Configuration.Caching.LazyMemberEvents) > 0
+	mg.ConfigurationMu.RUnlock()
+
+	if !enabled {
+		return nil
+	}
+
+	return &structs.APIStatusLazyMembers{
+		Hits:     atomic.LoadInt64(mg.lazyMemberHits),
+		Misses:   atomic.LoadInt64(mg.lazyMemberMisses),
+		Timeouts: atomic.LoadInt64(mg.lazyMemberTimeouts),
+	}
+}
+
+// lazyMember returns the member for a user in a guild for event types that
+// have lazy member fetching enabled. If the member is not cached, it will be
+// requested and the event held for at most the configured budget.
+func (sh *Shard) lazyMember(ctx *StateCtx, eventType string, guildID snowflake.ID,
+	user *discord.User) (member *discord.GuildMember, ok bool) {
+	if guildID == 0 || user == nil {
+		return nil, false
+	}
+
+	sh.Manager.ConfigurationMu.RLock()
+	enabled := false
+
+	for _, lazyEvent := range sh.Manager.Configuration.Caching.LazyMemberEvents {
+		if lazyEvent == eventType {
+			enabled = true
+
+			break
+		}
+	}
+
+	budget := time.Duration(sh.Manager.Configuration.Caching.LazyMemberBudget) * time.Millisecond
+	sh.Manager.ConfigurationMu.RUnlock()
+
+	if !enabled {
+		return nil, false
+	}
+
+	guild, ok := ctx.Sg.State.GetGuild(ctx, guildID, false)
+	if !ok {
+		return nil, false
+	}
+
+	member, ok = ctx.Sg.State.GetMember(ctx, guild, user.ID)
+	if ok {
+		atomic.AddInt64(sh.Manager.lazyMemberHits, 1)
+
+		return member, true
+	}
+
+	atomic.AddInt64(sh.Manager.lazyMemberMisses, 1)
+
+	member, ok = sh.lazyMembers.Fetch(guildID, user.ID, budget)
+	if !ok {
+		atomic.AddInt64(sh.Manager.lazyMemberTimeouts, 1)
+	}
+
+	return member, ok
+}
@@ -0,0 +1,304 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Interval between the leave policy being evaluated.
+	leavePolicyInterval = 24 * time.Hour
+
+	// Leaves allowed per day if leave_policy.max_leaves_per_day is not set.
+	defaultMaxLeavesPerDay = 10
+
+	// Time allowed for a single leave request.
+	leaveTimeout = 30 * time.Second
+)
+
+// Reasons a guild is left.
+const (
+	leaveReasonListed   = "listed"
+	leaveReasonInactive = "inactive"
+)
+
+// ErrLeavePolicyDisabled is returned when guilds would be left whilst the
+// leave policy is not enabled.
+var ErrLeavePolicyDisabled = xerrors.New("leave policy is not enabled")
+
+// LeavePolicy decides which guilds a manager leaves automatically. Listed
+// guilds are always left. If MaxMembers and InactiveDays are set, guilds with
+// fewer members that have not sent a message for InactiveDays are also left.
+type LeavePolicy struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	DryRun  bool `json:"dry_run" yaml:"dry_run"` // Only report guilds which would be left
+
+	GuildIDs []snowflake.ID `json:"guild_ids" yaml:"guild_ids"`
+
+	MaxMembers   int `json:"max_members" yaml:"max_members"`
+	InactiveDays int `json:"inactive_days" yaml:"inactive_days"`
+
+	MaxLeavesPerDay int `json:"max_leaves_per_day" yaml:"max_leaves_per_day"`
+}
+
+// markGuildActive records a message being sent in a guild.
+func (mg *Manager) markGuildActive(guildID snowflake.ID, now time.Time) {
+	if guildID == 0 {
+		return
+	}
+
+	mg.guildActivityMu.Lock()
+	mg.guildActivity[guildID] = now
+	mg.guildActivityMu.Unlock()
+}
+
+// lastGuildActivity returns when a message was last sent in a guild. Activity
+// is only tracked whilst the manager is running so guilds without messages
+// are treated as active when the manager was created.
+func (mg *Manager) lastGuildActivity(guildID snowflake.ID) time.Time {
+	mg.guildActivityMu.RLock()
+	last, ok := mg.guildActivity[guildID]
+	mg.guildActivityMu.RUnlock()
+
+	if !ok {
+		return mg.activitySince
+	}
+
+	return last
+}
+
+// leaveCandidates returns the guilds the policy would leave.
+func (mg *Manager) leaveCandidates(policy LeavePolicy, now time.Time) (candidates []structs.LeaveCandidate) {
+	listed := make(map[snowflake.ID]bool, len(policy.GuildIDs))
+	for _, guildID := range policy.GuildIDs {
+		listed[guildID] = true
+	}
+
+	inactiveAfter := time.Duration(policy.InactiveDays) * 24 * time.Hour
+	checkActivity := policy.MaxMembers > 0 && policy.InactiveDays > 0
+
+	seen := make(map[snowflake.ID]bool)
+	candidates = make([]structs.LeaveCandidate, 0)
+
+	mg.ShardGroupsMu.RLock()
+	for _, sg := range mg.ShardGroups {
+		sg.GuildsMu.RLock()
+		for guildID, guild := range sg.Guilds {
+			if seen[guildID] || guild == nil || guild.Guild == nil {
+				continue
+			}
+
+			seen[guildID] = true
+
+			candidate := structs.LeaveCandidate{
+				GuildID:      guildID,
+				Name:         guild.Name,
+				Members:      guild.MemberCount,
+				LastActivity: mg.lastGuildActivity(guildID),
+			}
+
+			switch {
+			case listed[guildID]:
+				candidate.Reason = leaveReasonListed
+			case checkActivity && !guild.Unavailable && guild.MemberCount > 0 &&
+				guild.MemberCount < policy.MaxMembers && now.Sub(candidate.LastActivity) >= inactiveAfter:
+				candidate.Reason = leaveReasonInactive
+			default:
+				continue
+			}
+
+			candidates = append(candidates, candidate)
+		}
+		sg.GuildsMu.RUnlock()
+	}
+	mg.ShardGroupsMu.RUnlock()
+
+	// Listed guilds first then the least active.
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Reason != candidates[j].Reason {
+			return candidates[i].Reason == leaveReasonListed
+		}
+
+		return candidates[i].LastActivity.Before(candidates[j].LastActivity)
+	})
+
+	return candidates
+}
+
+// leavesRemaining returns how many guilds can still be left today.
+// leavesMu must be held.
+func (mg *Manager) leavesRemaining(policy LeavePolicy, now time.Time) int {
+	recent := mg.leaves[:0]
+
+	for _, left := range mg.leaves {
+		if now.Sub(left) < 24*time.Hour {
+			recent = append(recent, left)
+		}
+	}
+
+	mg.leaves = recent
+
+	return policy.MaxLeavesPerDay - len(mg.leaves)
+}
+
+// EvaluateLeavePolicy finds the guilds the leave policy matches and leaves
+// them. If dryRun is set or the policy is in dry run mode, guilds are only
+// reported. At most leave_policy.max_leaves_per_day guilds are left in any
+// 24 hours. user is nil when evaluated automatically.
+func (mg *Manager) EvaluateLeavePolicy(user *structs.DiscordUser, dryRun bool) (report structs.LeavePolicyReport, err error) {
+	mg.ConfigurationMu.RLock()
+	policy := mg.Configuration.LeavePolicy
+	identifier := mg.Configuration.Identifier
+	mg.ConfigurationMu.RUnlock()
+
+	dryRun = dryRun || policy.DryRun
+
+	if !policy.Enabled && !dryRun {
+		return report, ErrLeavePolicyDisabled
+	}
+
+	mg.leavesMu.Lock()
+	defer mg.leavesMu.Unlock()
+
+	now := time.Now().UTC()
+
+	report = structs.LeavePolicyReport{
+		Manager:     identifier,
+		DryRun:      dryRun,
+		EvaluatedAt: now,
+		Candidates:  mg.leaveCandidates(policy, now),
+		Left:        make([]snowflake.ID, 0),
+		Errors:      make([]string, 0),
+	}
+
+	if dryRun {
+		mg.Logger.Info().Int("candidates", len(report.Candidates)).Msg("Evaluated leave policy in dry run mode")
+
+		if len(report.Candidates) > 0 {
+			mg.publishLeaveWebhook(user, "Leave policy dry run",
+				fmt.Sprintf("%d guilds would be left", len(report.Candidates)), discord.EmbedSandwich)
+		}
+
+		return report, nil
+	}
+
+	for _, candidate := range report.Candidates {
+		if mg.leavesRemaining(policy, now) <= 0 {
+			report.Capped = true
+
+			mg.Logger.Warn().Int("max_leaves_per_day", policy.MaxLeavesPerDay).Msg("Reached leave policy daily cap")
+
+			break
+		}
+
+		err := mg.leaveGuild(candidate.GuildID)
+
+		mg.Sandwich.Audit.Record(structs.AuditEntry{
+			Action:     "guild:leave",
+			User:       auditUser(user),
+			Manager:    identifier,
+			Parameters: candidate,
+			Success:    err == nil,
+			Summary:    leaveSummary(candidate, err),
+		})
+
+		if err != nil {
+			mg.Logger.Error().Err(err).Int64("guild_id", candidate.GuildID.Int64()).Msg("Failed to leave guild")
+			report.Errors = append(report.Errors, fmt.Sprintf("%d: %v", candidate.GuildID, err))
+
+			continue
+		}
+
+		mg.leaves = append(mg.leaves, now)
+		report.Left = append(report.Left, candidate.GuildID)
+
+		mg.Logger.Info().
+			Int64("guild_id", candidate.GuildID.Int64()).
+			Str("reason", candidate.Reason).
+			Int("members", candidate.Members).
+			Msg("Left guild")
+
+		mg.publishLeaveWebhook(user, "Left guild", leaveSummary(candidate, nil), discord.EmbedWarning)
+	}
+
+	return report, nil
+}
+
+// leaveGuild leaves a guild through the REST client.
+func (mg *Manager) leaveGuild(guildID snowflake.ID) (err error) {
+	ctx, cancel := context.WithTimeout(mg.ctx, leaveTimeout)
+	defer cancel()
+
+	body, status, err := mg.Client.Fetch(ctx, "DELETE", "/users/@me/guilds/"+guildID.String(), nil, nil)
+	if err != nil {
+		return xerrors.Errorf("leave guild: %w", err)
+	}
+
+	if status != http.StatusNoContent && status != http.StatusOK {
+		return xerrors.Errorf("leave guild: received status %d: %s", status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+func leaveSummary(candidate structs.LeaveCandidate, err error) string {
+	summary := fmt.Sprintf("%s (%d) with %d members. Reason: %s", candidate.Name, candidate.GuildID,
+		candidate.Members, candidate.Reason)
+
+	if candidate.Reason == leaveReasonInactive {
+		summary += fmt.Sprintf(". Last message %s", candidate.LastActivity.Format(time.RFC3339))
+	}
+
+	if err != nil {
+		summary += ". " + err.Error()
+	}
+
+	return summary
+}
+
+// publishLeaveWebhook sends a webhook about the leave policy.
+func (mg *Manager) publishLeaveWebhook(user *structs.DiscordUser, title string, description string, color int) {
+	go mg.Sandwich.Notify(context.Background(),
+		NewNotification(title, description).ForManager(mg).ByUser(user).WithColour(color))
+}
+
+// leavePolicyRunner evaluates the leave policy daily until the manager is
+// closed. Nothing is done whilst the policy is disabled.
+func (mg *Manager) leavePolicyRunner() {
+	if !mg.leavePolicyActive.SetToIf(false, true) {
+		return
+	}
+	defer mg.leavePolicyActive.UnSet()
+
+	t := time.NewTicker(leavePolicyInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-mg.ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		mg.ConfigurationMu.RLock()
+		enabled := mg.Configuration.LeavePolicy.Enabled
+		mg.ConfigurationMu.RUnlock()
+
+		if !enabled {
+			continue
+		}
+
+		if _, err := mg.EvaluateLeavePolicy(nil, false); err != nil {
+			mg.Logger.Error().Err(err).Msg("Failed to evaluate leave policy")
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+const (
+	// Interval between checking if managers have entered or left maintenance.
+	maintenanceCheckInterval = time.Second * 5
+
+	// Longest maintenance window that can be started through RPC.
+	maxMaintenanceDuration = time.Hour * 24
+)
+
+// MaintenanceWindow is a period of time where reconnect and heartbeat
+// notifications are only logged instead of being sent to webhooks.
+type MaintenanceWindow struct {
+	Start  time.Time `json:"start" yaml:"start"`
+	End    time.Time `json:"end" yaml:"end"`
+	Reason string    `json:"reason" yaml:"reason"`
+}
+
+// Active returns if the window covers the time provided.
+func (mw *MaintenanceWindow) Active(now time.Time) bool {
+	return mw != nil && !now.Before(mw.Start) && now.Before(mw.End)
+}
+
+// ActiveMaintenance returns the maintenance window the manager is currently
+// in. Windows started through RPC take priority over configured windows.
+func (mg *Manager) ActiveMaintenance(now time.Time) *MaintenanceWindow {
+	mg.MaintenanceMu.RLock()
+	maintenance := mg.Maintenance
+	mg.MaintenanceMu.RUnlock()
+
+	if maintenance.Active(now) {
+		return maintenance
+	}
+
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	for i := range mg.Configuration.MaintenanceWindows {
+		if window := &mg.Configuration.MaintenanceWindows[i]; window.Active(now) {
+			return window
+		}
+	}
+
+	return nil
+}
+
+// InMaintenance returns if the manager is currently in a maintenance window.
+func (mg *Manager) InMaintenance() bool {
+	return mg.ActiveMaintenance(time.Now().UTC()) != nil
+}
+
+// SetMaintenance starts a maintenance window for the duration provided. A
+// duration of 0 will end any maintenance started this way.
+func (mg *Manager) SetMaintenance(duration time.Duration, reason string) {
+	now := time.Now().UTC()
+
+	mg.MaintenanceMu.Lock()
+	if duration > 0 {
+		mg.Maintenance = &MaintenanceWindow{
+			Start:  now,
+			End:    now.Add(duration),
+			Reason: reason,
+		}
+	} else {
+		mg.Maintenance = nil
+	}
+	mg.MaintenanceMu.Unlock()
+
+	mg.checkMaintenance(now)
+}
+
+// checkMaintenance sends a webhook when the manager enters or leaves maintenance.
+func (mg *Manager) checkMaintenance(now time.Time) {
+	window := mg.ActiveMaintenance(now)
+
+	var notification *Notification
+
+	switch {
+	case window != nil && mg.inMaintenance.SetToIf(false, true):
+		mg.Logger.Info().Time("end", window.End).Str("reason", window.Reason).Msg("Manager has entered maintenance")
+
+		mg.Sandwich.Audit.Record(structs.AuditEntry{
+			Action:  "maintenance:start",
+			Manager: mg.Configuration.Identifier,
+			Success: true,
+			Summary: fmt.Sprintf("Until %s. %s", window.End.Format(time.RFC3339), window.Reason),
+		})
+
+		notification = NewNotification("Manager has entered maintenance",
+			fmt.Sprintf("Reconnect notifications are suppressed until %s\n%s",
+				window.End.Format(time.RFC1123), window.Reason)).
+			WithSeverity(SeverityWarning)
+	case window == nil && mg.inMaintenance.SetToIf(true, false):
+		mg.Logger.Info().Msg("Manager has left maintenance")
+
+		mg.Sandwich.Audit.Record(structs.AuditEntry{
+			Action:  "maintenance:end",
+			Manager: mg.Configuration.Identifier,
+			Success: true,
+		})
+
+		notification = NewNotification("Manager has left maintenance", "")
+	default:
+		return
+	}
+
+	go mg.Sandwich.Notify(context.Background(), notification.ForManager(mg).At(now))
+}
+
+// APIMaintenance returns the active maintenance window for /api/status.
+func (mg *Manager) APIMaintenance() *structs.APIStatusMaintenance {
+	window := mg.ActiveMaintenance(time.Now().UTC())
+	if window == nil {
+		return nil
+	}
+
+	return &structs.APIStatusMaintenance{
+		Start:  window.Start,
+		End:    window.End,
+		Reason: window.Reason,
+	}
+}
+
+func (sg *Sandwich) maintenanceRunner() {
+	t := time.NewTicker(maintenanceCheckInterval)
+
+	for {
+		now := (<-t.C).UTC()
+
+		sg.ManagersMu.RLock()
+		for _, mg := range sg.Managers {
+			mg.checkMaintenance(now)
+		}
+		sg.ManagersMu.RUnlock()
+	}
+}
@@ -3,23 +3,26 @@ package gateway
 import (
 	"context"
 	"math"
-	"math/rand"
-	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/TheRockettek/Sandwich-Daemon/pkg/accumulator"
 	bucketstore "github.com/TheRockettek/Sandwich-Daemon/pkg/bucketstore"
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
 	"github.com/TheRockettek/Sandwich-Daemon/structs"
 	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
 	"github.com/rs/zerolog"
-	"github.com/vmihailenco/msgpack"
+	"github.com/tevino/abool"
 	"golang.org/x/xerrors"
 )
 
 const (
 	maxClientNumber = 9999
+
+	// Time to wait for outstanding publishes when closing a producer.
+	producerCloseTimeout = 10 * time.Second
 )
 
 // ManagerConfiguration represents the configuration for the manager.
@@ -39,21 +42,123 @@ type ManagerConfiguration struct {
 		Compression          bool                  `json:"compression" yaml:"compression"`
 		GuildSubscriptions   bool                  `json:"guild_subscriptions" yaml:"guild_subscriptions"`
 		Retries              int32                 `json:"retries" yaml:"retries"`
+		MaxReconnectWait     int                   `json:"max_reconnect_wait" yaml:"max_reconnect_wait"` // Seconds
 		Intents              int                   `json:"intents" yaml:"intents"`
 		LargeThreshold       int                   `json:"large_threshold" yaml:"large_threshold"`
 		MaxHeartbeatFailures int                   `json:"max_heartbeat_failures" yaml:"max_heartbeat_failures"`
+
+		// Compress the whole connection with zlib-stream instead of each
+		// payload. Compression is ignored when this is enabled.
+		TransportCompression bool `json:"transport_compression" yaml:"transport_compression"`
+
+		// Encoding of the gateway connection, json or etf.
+		Encoding string `json:"encoding" yaml:"encoding"`
+
+		// Seconds a closing ShardGroup waits for dispatches which are
+		// already being handled.
+		DispatchGracePeriod int `json:"dispatch_grace_period" yaml:"dispatch_grace_period"`
+
+		// Handle dispatches on a fixed number of workers per shard instead of
+		// a goroutine each. Events of a guild always use the same worker so
+		// consumers receive them in order. The worker count and queue depth
+		// apply to shards started after they are changed.
+		OrderedDispatch        bool `json:"ordered_dispatch" yaml:"ordered_dispatch"`
+		OrderedDispatchWorkers int  `json:"ordered_dispatch_workers" yaml:"ordered_dispatch_workers"`
+		OrderedDispatchQueue   int  `json:"ordered_dispatch_queue" yaml:"ordered_dispatch_queue"`
+
+		// Largest gateway payload in bytes. Larger payloads are skipped when
+		// resuming where possible.
+		WebsocketReadLimit int64 `json:"websocket_read_limit" yaml:"websocket_read_limit"`
+
+		// Sequences a shard can have missed when it resumes before a warning
+		// is logged and a webhook is sent. 0 disables.
+		ResumeGapWarning int64 `json:"resume_gap_warning" yaml:"resume_gap_warning"`
+
+		// Seconds a shard can go without dispatch events whilst other shards in its
+		// ShardGroup are still receiving them before it is treated as stalled. 0 disables.
+		EventStallThreshold int  `json:"event_stall_threshold" yaml:"event_stall_threshold"`
+		ReidentifyOnStall   bool `json:"reidentify_on_stall" yaml:"reidentify_on_stall"`
+
+		// Fields added to the identify payload for gateway experiments. Fields
+		// the daemon sets itself such as token, intents and shard are rejected.
+		IdentifyExtra      map[string]interface{} `json:"identify_extra" yaml:"identify_extra"`
+		IdentifyProperties IdentifyProperties     `json:"identify_properties" yaml:"identify_properties"`
+
+		// Invalid sessions a shard or the whole manager can receive within
+		// InvalidSessionWindow seconds before a warning and webhook are sent.
+		InvalidSessionWindow         int `json:"invalid_session_window" yaml:"invalid_session_window"`
+		InvalidSessionShardThreshold int `json:"invalid_session_shard_threshold" yaml:"invalid_session_shard_threshold"`
+		InvalidSessionThreshold      int `json:"invalid_session_threshold" yaml:"invalid_session_threshold"`
+
+		// Seconds an event can be handled for before a possible deadlock is
+		// logged.
+		DispatchTimeout int `json:"dispatch_timeout" yaml:"dispatch_timeout"`
+
+		// Seconds to wait for the first member chunk of a guild before chunking
+		// is aborted, then for each following chunk before chunking is treated
+		// as complete. Guilds are kept marked as chunked for
+		// ChunkStatePersistTimeout seconds afterwards.
+		InitialMemberChunkTimeout int `json:"initial_member_chunk_timeout" yaml:"initial_member_chunk_timeout"`
+		MemberChunkTimeout        int `json:"member_chunk_timeout" yaml:"member_chunk_timeout"`
+		ChunkStatePersistTimeout  int `json:"chunk_state_persist_timeout" yaml:"chunk_state_persist_timeout"`
+
+		// Ingest the GUILD_CREATE burst after READY or RESUMED on a few
+		// workers per shard, largest guild first, and chunk guilds once it
+		// has cleared. Recovery lasts GuildRecoveryWindow seconds and for as
+		// long as guilds keep arriving within that many seconds.
+		GuildRecovery        bool `json:"guild_recovery" yaml:"guild_recovery"`
+		GuildRecoveryWindow  int  `json:"guild_recovery_window" yaml:"guild_recovery_window"`
+		GuildRecoveryWorkers int  `json:"guild_recovery_workers" yaml:"guild_recovery_workers"`
 	} `json:"bot" yaml:"bot"`
 
 	Caching struct {
 		CacheUsers     bool `json:"cache_users" yaml:"cache_users"`
 		CacheMembers   bool `json:"cache_members" yaml:"cache_members"`
 		RequestMembers bool `json:"request_members" yaml:"request_members"`
-		StoreMutuals   bool `json:"store_mutuals" yaml:"store_mutuals"`
+
+		// Keep the IDs of the guilds each user is in for mutual guild
+		// lookups. This is not limited by the member limits and grows with
+		// every member seen. Its size is included in the analytics.
+		StoreMutuals bool `json:"store_mutuals" yaml:"store_mutuals"`
+
+		// Keep the status and activity names of users from PRESENCE_UPDATE.
+		// Limited by the member limits under caching of the daemon.
+		CachePresences bool `json:"cache_presences" yaml:"cache_presences"`
+
+		// Event types which will request the member of the author if they are not
+		// cached. The event is held for at most LazyMemberBudget milliseconds.
+		LazyMemberEvents []string `json:"lazy_member_events" yaml:"lazy_member_events"`
+		LazyMemberBudget int      `json:"lazy_member_budget" yaml:"lazy_member_budget"`
+
+		// Attempts made to chunk a guild before it is left for an operator to
+		// requeue. Retries back off exponentially.
+		ChunkRetryAttempts int `json:"chunk_retry_attempts" yaml:"chunk_retry_attempts"`
 	} `json:"caching" yaml:"caching"`
 
 	Events struct {
 		EventBlacklist   []string `json:"event_blacklist" yaml:"event_blacklist"`     // Events completely ignored
 		ProduceBlacklist []string `json:"produce_blacklist" yaml:"produce_blacklist"` // Events not sent to consumers
+
+		// Send guild, channel and role events to consumers before other events
+		// until a ShardGroup is ready.
+		StartupPriority bool `json:"startup_priority" yaml:"startup_priority"`
+
+		// Tag stamped into the metadata of guild events so consumers receiving
+		// a guild from two bots whilst it is migrated can prefer one. Guilds can
+		// be given a different tag through RPC for GuildAffinityTTL seconds.
+		GuildAffinityTag string `json:"guild_affinity_tag" yaml:"guild_affinity_tag"`
+		GuildAffinityTTL int    `json:"guild_affinity_ttl" yaml:"guild_affinity_ttl"`
+
+		// Latency objectives between events being received and published.
+		SLOs []EventSLO `json:"slos" yaml:"slos"`
+
+		// Publish dispatches the daemon has no handler for with the data
+		// discord sent instead of dropping them.
+		ForwardUnhandled bool `json:"forward_unhandled" yaml:"forward_unhandled"`
+
+		// Update events which include the cached object before the update as
+		// before in their extra. VOICE_STATE_UPDATE always includes it.
+		IncludeBefore []string `json:"include_before" yaml:"include_before"`
 	} `json:"events" yaml:"events"`
 
 	// Messaging specific configuration
@@ -65,13 +170,69 @@ type ManagerConfiguration struct {
 		// UseRandomSuffix will append numbers to the end of the client name in order to
 		// reduce likelihood of clashing cluster IDs.
 		UseRandomSuffix bool `json:"use_random_suffix" yaml:"use_random_suffix" msgpack:"use_random_suffix"`
+		// AllowSharedChannel acknowledges that other managers publish to the same
+		// channel so it is not reported as a misconfiguration.
+		AllowSharedChannel bool `json:"allow_shared_channel" yaml:"allow_shared_channel" msgpack:"allow_shared_channel"`
+		// KeepaliveInterval is the number of seconds between SANDWICH_KEEPALIVE
+		// payloads so consumers can tell a dead subscription from a quiet bot.
+		// 0 disables keepalives.
+		KeepaliveInterval int `json:"keepalive_interval" yaml:"keepalive_interval" msgpack:"keepalive_interval"`
+		// KeepaliveAnalytics counts keepalives in the produced analytics.
+		KeepaliveAnalytics bool `json:"keepalive_analytics" yaml:"keepalive_analytics" msgpack:"keepalive_analytics"`
+		// GatewayCommands listens on <channel_name>:gateway for commands
+		// consumers want sent to the gateway. Requires a producer which
+		// can subscribe.
+		GatewayCommands bool `json:"gateway_commands" yaml:"gateway_commands" msgpack:"gateway_commands"`
+		// StateQueries answers msgpack state queries on <channel_name>:state
+		// with objects from the cache. At most StateQueryRate queries are
+		// answered each second. Requires a producer which can reply to
+		// requests.
+		StateQueries   bool `json:"state_queries" yaml:"state_queries" msgpack:"state_queries"`
+		StateQueryRate int  `json:"state_query_rate" yaml:"state_query_rate" msgpack:"state_query_rate"`
+		// AckEvents are event types consumers must acknowledge on
+		// <channel_name>:ack. Unacknowledged events are published again
+		// every AckTimeout seconds up to AckAttempts times and then dead
+		// lettered. Requires a producer which can subscribe.
+		AckEvents          []string `json:"ack_events" yaml:"ack_events" msgpack:"ack_events"`
+		AckTimeout         int      `json:"ack_timeout" yaml:"ack_timeout" msgpack:"ack_timeout"`
+		AckAttempts        int      `json:"ack_attempts" yaml:"ack_attempts" msgpack:"ack_attempts"`
+		AckPendingLimit    int      `json:"ack_pending_limit" yaml:"ack_pending_limit" msgpack:"ack_pending_limit"`
+		AckDeadLetterLimit int      `json:"ack_dead_letter_limit" yaml:"ack_dead_letter_limit" msgpack:"ack_dead_letter_limit"`
+		// Encoding of payloads sent to consumers, msgpack or json. Producers
+		// which support headers send it in the Sandwich-Encoding header.
+		Encoding string `json:"encoding" yaml:"encoding" msgpack:"encoding"`
+		// Compression of dispatches sent to consumers, brotli or none.
+		// Dispatches smaller than CompressionThreshold bytes are not
+		// compressed. Producers which support headers send it in the
+		// Sandwich-Compression header.
+		Compression          string `json:"compression" yaml:"compression" msgpack:"compression"`
+		CompressionThreshold int    `json:"compression_threshold" yaml:"compression_threshold" msgpack:"compression_threshold"`
+		// WorkerID is the worker of the event IDs of dispatches, between 1
+		// and 1023. Managers must use different worker IDs so their event
+		// IDs do not collide. When 0, the lowest worker ID no other manager
+		// uses is assigned.
+		WorkerID int64 `json:"worker_id" yaml:"worker_id" msgpack:"worker_id"`
 	} `json:"messaging" yaml:"messaging"`
 
 	// Sharding specific configuration
 	Sharding struct {
 		AutoSharded bool `json:"auto_sharded" yaml:"auto_sharded" msgpack:"auto_sharded"`
 		ShardCount  int  `json:"shard_count" yaml:"shard_count" msgpack:"shard_count"`
+
+		// Coefficient of variation of guilds, members or events over the
+		// shards above which the rebalance report recommends re-sharding.
+		RebalanceThreshold float64 `json:"rebalance_threshold" yaml:"rebalance_threshold" msgpack:"rebalance_threshold"`
+		// UseRebalanceReport lets auto sharding start with the shard count
+		// the last rebalance report suggests when it is higher than the
+		// gateway recommends.
+		UseRebalanceReport bool `json:"use_rebalance_report" yaml:"use_rebalance_report" msgpack:"use_rebalance_report"`
 	} `json:"sharding" msgpack:"sharding"`
+
+	// Scheduled periods where reconnect notifications are only logged
+	MaintenanceWindows []MaintenanceWindow `json:"maintenance_windows" yaml:"maintenance_windows"`
+
+	// Guilds which are left automatically. Disabled by default.
+	LeavePolicy LeavePolicy `json:"leave_policy" yaml:"leave_policy"`
 }
 
 // Manager represents a bot instance.
@@ -84,6 +245,118 @@ type Manager struct {
 
 	AnalyticsMu sync.RWMutex             `json:"-"`
 	Analytics   *accumulator.Accumulator `json:"-"`
+	Produced    *accumulator.Accumulator `json:"-"`
+
+	// REST requests made by Client per analytics interval.
+	RESTRequests *accumulator.Accumulator `json:"-"`
+	restStats    *restStats
+
+	producedBytes    *int64
+	published        *int64 // Payloads successfully published
+	lastPublish      *int64
+	lastPublishError *int64
+
+	// Payloads waiting to be published again after the producer failed.
+	publishRetry *publishRetry
+
+	eventIDs *snowflake.Generator
+
+	lazyMemberHits     *int64
+	lazyMemberMisses   *int64
+	lazyMemberTimeouts *int64
+
+	readLimitExceeded *int64 // Payloads received over Bot.WebsocketReadLimit
+
+	resumes        *int64 // Sessions resumed by shards
+	replayedEvents *int64 // Dispatches replayed whilst resuming
+
+	dispatchQueued    *int64 // Dispatches waiting for an ordered dispatch worker
+	dispatchSaturated *int64 // Times an ordered dispatch queue was full
+
+	// Dispatch types received without a state handler.
+	unhandledMu sync.Mutex
+	unhandled   map[string]*unhandledEvent
+
+	// Events and errors by event type and the stage they failed at.
+	eventErrorsMu sync.RWMutex
+	eventErrors   map[eventErrorKey]*eventErrorCounter
+
+	chunkFailuresMu sync.Mutex
+	chunkFailures   map[snowflake.ID]*chunkFailure
+
+	MaintenanceMu sync.RWMutex       `json:"-"`
+	Maintenance   *MaintenanceWindow `json:"-"` // Maintenance started through RPC
+	inMaintenance *abool.AtomicBool
+
+	keepaliveActive *abool.AtomicBool
+
+	// Invalid sessions received by any shard within
+	// bot.invalid_session_window.
+	invalidSessions *invalidSessionCounter
+
+	// Cancels the subscription to gateway commands of the current producer.
+	gatewayCommandsMu     sync.Mutex
+	gatewayCommandsCancel context.CancelFunc
+
+	// Cancels the subscription to state queries of the current producer.
+	stateQueriesMu     sync.Mutex
+	stateQueriesCancel context.CancelFunc
+	stateQueries       *stateQueryLimiter
+
+	// Events waiting to be acknowledged by consumers and the ones which
+	// never were. Kept across producer restarts.
+	acksMu               sync.Mutex
+	acksCancel           context.CancelFunc
+	pendingAcks          map[int64]*pendingAck
+	deadLetters          []*pendingAck
+	deadLettersLoaded    bool
+	deadLettersPersistMu sync.Mutex
+
+	// Last MESSAGE_CREATE of each guild since activitySince for the leave
+	// policy.
+	guildActivityMu sync.RWMutex
+	guildActivity   map[snowflake.ID]time.Time
+	activitySince   time.Time
+
+	// Held whilst the leave policy is evaluated. leaves are the times guilds
+	// were left in the last day.
+	leavesMu          sync.Mutex
+	leaves            []time.Time
+	leavePolicyActive *abool.AtomicBool
+
+	// Affinity tags of guilds set through RPC which replace
+	// events.guild_affinity_tag until they expire.
+	guildAffinityMu sync.RWMutex
+	guildAffinity   map[snowflake.ID]guildAffinityOverride
+
+	// Guilds which receive the most events for /api/state/top_guilds.
+	topGuilds *topGuilds
+
+	// Guilds of each user seen when caching.store_mutuals is enabled.
+	mutuals *mutualIndex
+
+	// Latency trackers of event types with an objective, kept in line with
+	// events.slos by sloRunner.
+	sloMu     sync.RWMutex
+	slos      map[string]*sloTracker
+	sloActive *abool.AtomicBool
+
+	// Last rebalance report generated daily or through RPC.
+	rebalanceReportMu sync.RWMutex
+	rebalanceReport   *structs.RebalanceReport
+	rebalanceActive   *abool.AtomicBool
+
+	// Presence set through RPC for every shard which is used instead of
+	// bot.presence when identifying.
+	presenceMu       sync.RWMutex
+	presenceOverride *discord.UpdateStatus
+
+	CaptureMu sync.RWMutex  `json:"-"`
+	Capture   *EventCapture `json:"-"` // Capture started through RPC
+
+	// Held whilst the manager is restarted or parts of it are rebuilt so
+	// these cannot interleave.
+	OperationMu sync.Mutex `json:"-"`
 
 	Sandwich *Sandwich      `json:"-"`
 	Logger   zerolog.Logger `json:"-"`
@@ -92,7 +365,15 @@ type Manager struct {
 	Configuration   *ManagerConfiguration    `json:"configuration"`
 	Buckets         *bucketstore.BucketStore `json:"-"`
 
-	ProducerClient MQClient `json:"-"` // Used to send messages to consumers
+	// Copy of the token and its hash which are updated whenever the token
+	// changes. This can be used without holding ConfigurationMu.
+	tokenMu   sync.RWMutex
+	token     string
+	tokenHash string
+
+	// Used to send messages to consumers. Read it with Producer.
+	producerMu sync.RWMutex
+	producer   MQClient
 
 	Client *Client `json:"-"`
 
@@ -107,17 +388,78 @@ type Manager struct {
 	// a shard group of 160 and 176 active at the same time. Once the 176 shardgroup
 	// has finished ready, the other shard group will stop. 176 will not relay messages
 	// until it has removed the old shardgroup to reduce likelihood of duplicate messages.
-	// These messages will just be completely ignored as if it was in the EventBlacklist
+	// These messages still update the state but are not produced, as if they were in
+	// the ProduceBlacklist.
 	ShardGroups       map[int32]*ShardGroup `json:"shard_groups"`
 	ShardGroupsMu     sync.RWMutex          `json:"-"`
 	ShardGroupIter    *int32                `json:"-"`
 	ShardGroupCounter sync.WaitGroup        `json:"-"`
 
-	EventBlacklistMu sync.RWMutex `json:"-"`
-	EventBlacklist   []string     `json:"-"`
+	EventBlacklistMu sync.RWMutex  `json:"-"`
+	EventBlacklist   *EventMatcher `json:"-"`
 
-	ProduceBlacklistMu sync.RWMutex `json:"-"`
-	ProduceBlacklist   []string     `json:"-"`
+	ProduceBlacklistMu sync.RWMutex  `json:"-"`
+	ProduceBlacklist   *EventMatcher `json:"-"`
+
+	IncludeBeforeMu sync.RWMutex  `json:"-"`
+	IncludeBefore   *EventMatcher `json:"-"`
+}
+
+// eventWorkerID returns the worker ID of the event IDs of a manager. A
+// manager without messaging.worker_id is assigned the lowest worker ID no
+// other manager uses and it is kept in its configuration so it does not
+// change. ErrDuplicateWorkerID is returned if a manager earlier in the
+// configuration already uses the worker ID. ConfigurationMu is read locked
+// as managers created through RPC are added to the configuration whilst it is
+// saved. Like NormalizeConfiguration, this relies on Open only read locking it
+// whilst starting managers.
+func (sg *Sandwich) eventWorkerID(configuration *ManagerConfiguration) (workerID int64, err error) {
+	sg.ConfigurationMu.RLock()
+	defer sg.ConfigurationMu.RUnlock()
+
+	sg.workerIDsMu.Lock()
+	defer sg.workerIDsMu.Unlock()
+
+	workerID = configuration.Messaging.WorkerID
+	if workerID < 0 || workerID > snowflake.MaxWorkerID {
+		return 0, xerrors.Errorf("worker id %d must be between 1 and %d", workerID, snowflake.MaxWorkerID)
+	}
+
+	used := make(map[int64]string)
+
+	for _, managerConfiguration := range sg.Configuration.Managers {
+		if managerConfiguration == configuration || managerConfiguration.Identifier == configuration.Identifier {
+			// Only managers before this one count as duplicates so the
+			// first manager with a worker ID keeps it.
+			if workerID != 0 {
+				break
+			}
+
+			continue
+		}
+
+		if id := managerConfiguration.Messaging.WorkerID; id != 0 {
+			used[id] = managerConfiguration.Identifier
+		}
+	}
+
+	if workerID != 0 {
+		if identifier, ok := used[workerID]; ok {
+			return 0, xerrors.Errorf("worker id %d of %s: %w", workerID, identifier, ErrDuplicateWorkerID)
+		}
+
+		return workerID, nil
+	}
+
+	for workerID = 1; workerID <= snowflake.MaxWorkerID; workerID++ {
+		if _, ok := used[workerID]; !ok {
+			configuration.Messaging.WorkerID = workerID
+
+			return workerID, nil
+		}
+	}
+
+	return 0, xerrors.Errorf("assign worker id: %w", ErrDuplicateWorkerID)
 }
 
 // NewManager creates a new manager.
@@ -132,6 +474,75 @@ func (sg *Sandwich) NewManager(configuration *ManagerConfiguration) (mg *Manager
 		ErrorMu: sync.RWMutex{},
 		Error:   "",
 
+		producedBytes:    new(int64),
+		published:        new(int64),
+		lastPublish:      new(int64),
+		lastPublishError: new(int64),
+
+		MaintenanceMu: sync.RWMutex{},
+		inMaintenance: abool.New(),
+
+		keepaliveActive: abool.New(),
+
+		invalidSessions: newInvalidSessionCounter(),
+
+		gatewayCommandsMu: sync.Mutex{},
+
+		stateQueriesMu: sync.Mutex{},
+		stateQueries:   newStateQueryLimiter(),
+
+		acksMu:               sync.Mutex{},
+		pendingAcks:          make(map[int64]*pendingAck),
+		deadLettersPersistMu: sync.Mutex{},
+
+		guildActivityMu: sync.RWMutex{},
+		guildActivity:   make(map[snowflake.ID]time.Time),
+		activitySince:   time.Now().UTC(),
+
+		leavesMu:          sync.Mutex{},
+		leavePolicyActive: abool.New(),
+
+		guildAffinityMu: sync.RWMutex{},
+		guildAffinity:   make(map[snowflake.ID]guildAffinityOverride),
+
+		topGuilds: newTopGuilds(),
+
+		mutuals: newMutualIndex(),
+
+		sloMu:     sync.RWMutex{},
+		slos:      make(map[string]*sloTracker),
+		sloActive: abool.New(),
+
+		rebalanceReportMu: sync.RWMutex{},
+		rebalanceActive:   abool.New(),
+
+		presenceMu: sync.RWMutex{},
+
+		CaptureMu: sync.RWMutex{},
+
+		OperationMu: sync.Mutex{},
+
+		lazyMemberHits:     new(int64),
+		lazyMemberMisses:   new(int64),
+		lazyMemberTimeouts: new(int64),
+
+		readLimitExceeded: new(int64),
+
+		resumes:        new(int64),
+		replayedEvents: new(int64),
+
+		dispatchQueued:    new(int64),
+		dispatchSaturated: new(int64),
+
+		unhandledMu: sync.Mutex{},
+		unhandled:   make(map[string]*unhandledEvent),
+
+		eventErrorsMu: sync.RWMutex{},
+		eventErrors:   make(map[eventErrorKey]*eventErrorCounter),
+
+		chunkFailuresMu: sync.Mutex{},
+		chunkFailures:   make(map[snowflake.ID]*chunkFailure),
+
 		ConfigurationMu: sync.RWMutex{},
 		Configuration:   configuration,
 		Buckets:         bucketstore.NewBucketStore(),
@@ -148,10 +559,13 @@ func (sg *Sandwich) NewManager(configuration *ManagerConfiguration) (mg *Manager
 		ShardGroupCounter: sync.WaitGroup{},
 
 		EventBlacklistMu: sync.RWMutex{},
-		EventBlacklist:   make([]string, 0),
+		EventBlacklist:   &EventMatcher{},
 
 		ProduceBlacklistMu: sync.RWMutex{},
-		ProduceBlacklist:   make([]string, 0),
+		ProduceBlacklist:   &EventMatcher{},
+
+		IncludeBeforeMu: sync.RWMutex{},
+		IncludeBefore:   &EventMatcher{},
 	}
 
 	if sg.RestTunnelEnabled.IsSet() {
@@ -160,6 +574,20 @@ func (sg *Sandwich) NewManager(configuration *ManagerConfiguration) (mg *Manager
 		mg.Client = NewClient(configuration.Token, "", false, true)
 	}
 
+	mg.restStats = newRESTStats()
+	mg.publishRetry = newPublishRetry(mg)
+	mg.Client.stats = mg.restStats
+
+	workerID, err := sg.eventWorkerID(configuration)
+	if err != nil {
+		return nil, xerrors.Errorf("new manager event id generator: %w", err)
+	}
+
+	mg.eventIDs, err = snowflake.NewGenerator(sg.Configuration.Producer.EventIDEpoch, workerID)
+	if err != nil {
+		return nil, xerrors.Errorf("new manager event id generator: %w", err)
+	}
+
 	err = mg.NormalizeConfiguration()
 	if err != nil {
 		mg.ErrorMu.Lock()
@@ -186,6 +614,7 @@ func (mg *Manager) NormalizeConfiguration() (err error) {
 	}
 
 	mg.Configuration.Token = strings.TrimSpace(mg.Configuration.Token)
+	mg.SetToken(mg.Configuration.Token)
 
 	if mg.Configuration.Bot.MaxHeartbeatFailures < 1 {
 		mg.Configuration.Bot.MaxHeartbeatFailures = 1
@@ -195,10 +624,156 @@ func (mg *Manager) NormalizeConfiguration() (err error) {
 		mg.Configuration.Bot.Retries = 1
 	}
 
+	if mg.Configuration.Bot.MaxReconnectWait < 1 {
+		mg.Configuration.Bot.MaxReconnectWait = defaultMaxReconnectWait
+	}
+
+	if mg.Configuration.Bot.WebsocketReadLimit < 1 {
+		mg.Configuration.Bot.WebsocketReadLimit = websocketReadLimit
+	}
+
+	mg.Configuration.Bot.Encoding = strings.ToLower(strings.TrimSpace(mg.Configuration.Bot.Encoding))
+
+	switch mg.Configuration.Bot.Encoding {
+	case "":
+		mg.Configuration.Bot.Encoding = EncodingJSON
+	case EncodingJSON:
+	case EncodingETF:
+		// Every ETF payload is a binary message so those compressed with
+		// payload compression cannot be told apart from the rest.
+		if mg.Configuration.Bot.Compression && !mg.Configuration.Bot.TransportCompression {
+			return xerrors.New("Manager etf encoding requires compression to be disabled or transport_compression")
+		}
+	default:
+		return xerrors.Errorf("Manager has unknown encoding %s. Use json or etf", mg.Configuration.Bot.Encoding)
+	}
+
+	if mg.Configuration.Bot.DispatchGracePeriod < 1 {
+		mg.Configuration.Bot.DispatchGracePeriod = defaultDispatchGracePeriod
+	}
+
+	if mg.Configuration.Bot.OrderedDispatchWorkers < 1 {
+		mg.Configuration.Bot.OrderedDispatchWorkers = defaultOrderedDispatchWorkers
+	}
+
+	if mg.Configuration.Bot.OrderedDispatchQueue < 1 {
+		mg.Configuration.Bot.OrderedDispatchQueue = defaultOrderedDispatchQueue
+	}
+
+	if mg.Configuration.Bot.InvalidSessionWindow < 1 {
+		mg.Configuration.Bot.InvalidSessionWindow = defaultInvalidSessionWindow
+	}
+
+	if mg.Configuration.Bot.InvalidSessionShardThreshold < 1 {
+		mg.Configuration.Bot.InvalidSessionShardThreshold = defaultInvalidSessionShardThreshold
+	}
+
+	if mg.Configuration.Bot.InvalidSessionThreshold < 1 {
+		mg.Configuration.Bot.InvalidSessionThreshold = defaultInvalidSessionThreshold
+	}
+
+	if mg.Configuration.Bot.DispatchTimeout < 1 {
+		mg.Configuration.Bot.DispatchTimeout = defaultDispatchTimeout
+	}
+
+	if mg.Configuration.Bot.InitialMemberChunkTimeout < 1 {
+		mg.Configuration.Bot.InitialMemberChunkTimeout = defaultInitialMemberChunkTimeout
+	}
+
+	if mg.Configuration.Bot.MemberChunkTimeout < 1 {
+		mg.Configuration.Bot.MemberChunkTimeout = defaultMemberChunkTimeout
+	}
+
+	if mg.Configuration.Bot.ChunkStatePersistTimeout < 1 {
+		mg.Configuration.Bot.ChunkStatePersistTimeout = defaultChunkStatePersistTimeout
+	}
+
+	if mg.Configuration.Bot.GuildRecoveryWindow < 1 {
+		mg.Configuration.Bot.GuildRecoveryWindow = defaultGuildRecoveryWindow
+	}
+
+	if mg.Configuration.Bot.GuildRecoveryWorkers < 1 {
+		mg.Configuration.Bot.GuildRecoveryWorkers = defaultGuildRecoveryWorkers
+	}
+
+	if mg.Configuration.Bot.InitialMemberChunkTimeout < mg.Configuration.Bot.MemberChunkTimeout {
+		return xerrors.Errorf("Manager initial_member_chunk_timeout (%d) must not be less than member_chunk_timeout (%d)",
+			mg.Configuration.Bot.InitialMemberChunkTimeout, mg.Configuration.Bot.MemberChunkTimeout)
+	}
+
+	if err = validateIdentifyExtra(mg.Configuration.Bot.IdentifyExtra); err != nil {
+		return err
+	}
+
 	if mg.Configuration.Messaging.ClientName == "" {
 		return xerrors.New("Manager missing client name. Try sandwich")
 	}
 
+	if !validPayloadEncoding(mg.Configuration.Messaging.Encoding) {
+		return xerrors.Errorf("Manager messaging encoding %q is not supported. Try msgpack or json",
+			mg.Configuration.Messaging.Encoding)
+	}
+
+	if !validPayloadCompression(mg.Configuration.Messaging.Compression) {
+		return xerrors.Errorf("Manager messaging compression %q is not supported. Try brotli or none",
+			mg.Configuration.Messaging.Compression)
+	}
+
+	mg.Configuration.Events.EventBlacklist = NormalizeEventNames(mg.Configuration.Events.EventBlacklist)
+	mg.Configuration.Events.ProduceBlacklist = NormalizeEventNames(mg.Configuration.Events.ProduceBlacklist)
+	mg.Configuration.Events.IncludeBefore = NormalizeEventNames(mg.Configuration.Events.IncludeBefore)
+	mg.Configuration.Caching.LazyMemberEvents = NormalizeEventNames(mg.Configuration.Caching.LazyMemberEvents)
+	mg.Configuration.Messaging.AckEvents = NormalizeEventNames(mg.Configuration.Messaging.AckEvents)
+
+	if mg.Configuration.Messaging.AckTimeout < 1 {
+		mg.Configuration.Messaging.AckTimeout = defaultAckTimeout
+	}
+
+	if mg.Configuration.Messaging.AckAttempts < 1 {
+		mg.Configuration.Messaging.AckAttempts = defaultAckAttempts
+	}
+
+	if mg.Configuration.Messaging.AckPendingLimit < 1 {
+		mg.Configuration.Messaging.AckPendingLimit = defaultAckPendingLimit
+	}
+
+	if mg.Configuration.Messaging.AckDeadLetterLimit < 1 {
+		mg.Configuration.Messaging.AckDeadLetterLimit = defaultAckDeadLetterLimit
+	}
+
+	if mg.Configuration.Caching.LazyMemberBudget < 1 {
+		mg.Configuration.Caching.LazyMemberBudget = defaultLazyMemberBudget
+	}
+
+	if mg.Configuration.LeavePolicy.MaxLeavesPerDay < 1 {
+		mg.Configuration.LeavePolicy.MaxLeavesPerDay = defaultMaxLeavesPerDay
+	}
+
+	if mg.Configuration.Caching.ChunkRetryAttempts < 1 {
+		mg.Configuration.Caching.ChunkRetryAttempts = defaultChunkRetryAttempts
+	}
+
+	mg.Configuration.Events.GuildAffinityTag = strings.TrimSpace(mg.Configuration.Events.GuildAffinityTag)
+
+	if mg.Configuration.Events.GuildAffinityTTL < 1 {
+		mg.Configuration.Events.GuildAffinityTTL = defaultGuildAffinityTTL
+	}
+
+	for i := range mg.Configuration.Events.SLOs {
+		objective := &mg.Configuration.Events.SLOs[i]
+		objective.Event = strings.ToUpper(strings.TrimSpace(objective.Event))
+
+		if objective.Event == "" || objective.Latency < 1 || objective.Target <= 0 || objective.Target > 100 {
+			return xerrors.Errorf("Manager has invalid slo for %s. Latency must be positive and target between 0 and 100", objective.Event)
+		}
+
+		if objective.Window < 1 {
+			objective.Window = defaultSLOWindow
+		}
+	}
+
+	mg.logIntentWarnings(mg.Configuration)
+
 	// if mg.Configuration.Messaging.ChannelName == "" {
 	// 	mg.Configuration.Messaging.ChannelName = mg.Sandwich.Configuration.NATS.Channel
 	// 	mg.Logger.Info().Msg("Using global messaging channel")
@@ -207,6 +782,34 @@ func (mg *Manager) NormalizeConfiguration() (err error) {
 	return err
 }
 
+// SetToken updates the token used by the REST client and the cached token
+// hash. This does not modify the configuration.
+func (mg *Manager) SetToken(token string) {
+	mg.tokenMu.Lock()
+	mg.token = token
+	mg.tokenHash = TokenHash(token)
+	mg.tokenMu.Unlock()
+
+	mg.Client.Token = token
+}
+
+// TokenHash returns the hash of the token the manager is using.
+func (mg *Manager) TokenHash() (hash string) {
+	mg.tokenMu.RLock()
+	hash = mg.tokenHash
+	mg.tokenMu.RUnlock()
+
+	return hash
+}
+
+// Redact removes the token of the manager from a string.
+func (mg *Manager) Redact(s string) string {
+	mg.tokenMu.RLock()
+	defer mg.tokenMu.RUnlock()
+
+	return RedactTokens(s, mg.token)
+}
+
 // Open starts up the manager, initializes the config and will create a shardgroup.
 func (mg *Manager) Open() (err error) {
 	mg.Logger.Info().Msg("Starting up manager")
@@ -226,23 +829,26 @@ func (mg *Manager) Open() (err error) {
 		Samples,
 		Interval,
 	)
+	mg.Produced = accumulator.NewAccumulator(
+		mg.ctx,
+		producedSamples,
+		time.Second,
+	)
+	mg.RESTRequests = accumulator.NewAccumulator(
+		mg.ctx,
+		Samples,
+		Interval,
+	)
 	mg.AnalyticsMu.Unlock()
 
-	var clientName string
-	if mg.Configuration.Messaging.UseRandomSuffix {
-		clientName = mg.Configuration.Messaging.ClientName + "-" + strconv.Itoa(rand.Intn(maxClientNumber)) //nolint:gosec
-	} else {
-		clientName = mg.Configuration.Messaging.ClientName
-	}
+	clientName := mg.producerClientName()
 
 	producerClient, err := NewMQClient(mg.Sandwich.Configuration.Producer.Type)
 	if err != nil {
 		return xerrors.Errorf("manager open producer create: %w", err)
 	}
 
-	mg.ProducerClient = producerClient
-
-	err = mg.ProducerClient.Connect(
+	err = producerClient.Connect(
 		mg.ctx,
 		clientName,
 		mg.Sandwich.Configuration.Producer.Configuration,
@@ -251,19 +857,46 @@ func (mg *Manager) Open() (err error) {
 		return xerrors.Errorf("manager open producer connect: %w", err)
 	}
 
+	mg.swapProducer(producerClient)
+
 	mg.EventBlacklistMu.Lock()
-	mg.EventBlacklist = mg.Configuration.Events.EventBlacklist
+	mg.EventBlacklist = mg.compileEventMatcher("event_blacklist", mg.Configuration.Events.EventBlacklist)
 	mg.EventBlacklistMu.Unlock()
 
 	mg.ProduceBlacklistMu.Lock()
-	mg.ProduceBlacklist = mg.Configuration.Events.ProduceBlacklist
+	mg.ProduceBlacklist = mg.compileEventMatcher("produce_blacklist", mg.Configuration.Events.ProduceBlacklist)
 	mg.ProduceBlacklistMu.Unlock()
 
+	mg.IncludeBeforeMu.Lock()
+	mg.IncludeBefore = mg.compileEventMatcher("include_before", mg.Configuration.Events.IncludeBefore)
+	mg.IncludeBeforeMu.Unlock()
+
+	mg.subscribeGatewayCommands()
+	mg.subscribeStateQueries()
+	mg.subscribeAcks()
+
+	go mg.keepaliveRunner()
+	go mg.leavePolicyRunner()
+	go mg.sloRunner()
+	go mg.rebalanceRunner()
+
 	mg.Gateway, err = mg.GetGateway()
 
 	return err
 }
 
+// compileEventMatcher creates an EventMatcher and logs any entries that
+// are not valid or do not match a known event.
+func (mg *Manager) compileEventMatcher(name string, entries []string) *EventMatcher {
+	matcher, warnings := NewEventMatcher(entries)
+
+	for _, warning := range warnings {
+		mg.Logger.Warn().Str("list", name).Msg(warning)
+	}
+
+	return matcher
+}
+
 // GatherShardCount returns the expected shardcount using the gateway object stored.
 func (mg *Manager) GatherShardCount() (shardCount int) {
 	mg.Sandwich.ConfigurationMu.RLock()
@@ -275,6 +908,13 @@ func (mg *Manager) GatherShardCount() (shardCount int) {
 
 	if mg.Configuration.Sharding.AutoSharded {
 		shardCount = mg.Gateway.Shards
+
+		if suggested, ok := mg.rebalanceShardCount(); ok && mg.Configuration.Sharding.UseRebalanceReport &&
+			suggested > shardCount {
+			mg.Logger.Info().Int("shard_count", suggested).Msg("Using shard count suggested by rebalance report")
+
+			shardCount = suggested
+		}
 	} else {
 		shardCount = mg.Configuration.Sharding.ShardCount
 	}
@@ -287,11 +927,7 @@ func (mg *Manager) GatherShardCount() (shardCount int) {
 
 // Scale creates a new ShardGroup and removes old ones once it has finished.
 func (mg *Manager) Scale(shardIDs []int, shardCount int, start bool) (ready chan bool, err error) {
-	iter := atomic.AddInt32(mg.ShardGroupIter, 1) - 1
-	sg := mg.NewShardGroup(iter)
-	mg.ShardGroupsMu.Lock()
-	mg.ShardGroups[iter] = sg
-	mg.ShardGroupsMu.Unlock()
+	sg := mg.newScaledShardGroup()
 
 	if start {
 		ready, err = sg.Open(shardIDs, shardCount)
@@ -300,8 +936,51 @@ func (mg *Manager) Scale(shardIDs []int, shardCount int, start bool) (ready chan
 	return
 }
 
+// StartShards fetches the gateway and starts a ShardGroup with the shard
+// count of the manager. ready is signalled once every shard is ready.
+func (mg *Manager) StartShards() (ready chan bool, err error) {
+	gw, err := mg.GetGateway()
+	if err != nil {
+		return nil, err
+	}
+
+	mg.GatewayMu.Lock()
+	mg.Gateway = gw
+	mg.GatewayMu.Unlock()
+
+	mg.Logger.Info().
+		Int("sessions", gw.SessionStartLimit.Remaining).
+		Msg("Retrieved gateway information")
+
+	shardCount := mg.GatherShardCount()
+	if shardCount >= gw.SessionStartLimit.Remaining {
+		return nil, xerrors.Errorf("not enough sessions to start %d shard(s). %d remain: %w",
+			shardCount, gw.SessionStartLimit.Remaining, ErrSessionLimitExhausted)
+	}
+
+	return mg.Scale(mg.GenerateShardIDs(shardCount), shardCount, true)
+}
+
+// newScaledShardGroup creates a ShardGroup with the next id and adds it to
+// the manager without opening it.
+func (mg *Manager) newScaledShardGroup() (sg *ShardGroup) {
+	iter := atomic.AddInt32(mg.ShardGroupIter, 1) - 1
+	sg = mg.NewShardGroup(iter)
+	mg.ShardGroupsMu.Lock()
+	mg.ShardGroups[iter] = sg
+	mg.ShardGroupsMu.Unlock()
+
+	return sg
+}
+
 // PublishEvent sends an event to consumers.
 func (mg *Manager) PublishEvent(eventType string, eventData interface{}) (err error) {
+	return mg.publishEvent(eventType, eventData, true)
+}
+
+// publishEvent sends an event to consumers. If track is false, the event is
+// not counted in the produced analytics and is not captured.
+func (mg *Manager) publishEvent(eventType string, eventData interface{}, track bool) (err error) {
 	packet := mg.pp.Get().(*structs.SandwichPayload)
 	defer mg.pp.Put(packet)
 
@@ -315,6 +994,7 @@ func (mg *Manager) PublishEvent(eventType string, eventData interface{}) (err er
 	packet.Metadata = structs.SandwichMetadata{
 		Version:    VERSION,
 		Identifier: mg.Configuration.Identifier,
+		EventID:    mg.eventIDs.Generate().Int64(),
 	}
 
 	// Clear extra values
@@ -322,25 +1002,44 @@ func (mg *Manager) PublishEvent(eventType string, eventData interface{}) (err er
 	packet.Extra = nil
 	packet.Trace = nil
 
-	data, err := msgpack.Marshal(packet)
+	data, err := marshalPayload(mg.Configuration.Messaging.Encoding, packet)
 	if err != nil {
 		return xerrors.Errorf("publishEvent marshal: %w", err)
 	}
 
-	if mg.ProducerClient != nil {
-		err = mg.ProducerClient.Publish(
+	if track {
+		mg.capturePayload(packet)
+	}
+
+	hookErr := mg.Sandwich.runEventHooks(mg.ctx, packet)
+
+	if mg.Producer() != nil {
+		var queued bool
+
+		queued, err = mg.publishWithRetry(
 			mg.ctx,
 			mg.Configuration.Messaging.ChannelName,
+			mg.payloadMessage(nil, compressionNone),
 			data,
+			track,
 		)
-		if err != nil {
+
+		switch {
+		case queued && err == nil:
+		case track:
+			mg.recordPublish(len(data), err)
+		default:
+			mg.recordPublishOutcome(err)
+		}
+
+		if err != nil && !queued {
 			return xerrors.Errorf("publishEvent publish: %w", err)
 		}
 	} else {
 		return xerrors.New("publishEvent publish: No active stanClient")
 	}
 
-	return nil
+	return hookErr
 }
 
 // GenerateShardIDs returns a slice of shard ids the bot will use and accounts for clusters.
@@ -362,12 +1061,53 @@ func (mg *Manager) Close() {
 	}
 	mg.ShardGroupsMu.RUnlock()
 
+	if producer := mg.swapProducer(nil); producer != nil {
+		mg.closeProducer(producer)
+	}
+
 	// cancel is not defined when a manager does not autostart
 	if mg.cancel != nil {
 		mg.cancel()
 	}
 }
 
+// Producer returns the producer used to send messages to consumers. It is nil
+// whilst the manager has no producer.
+func (mg *Manager) Producer() MQClient {
+	mg.producerMu.RLock()
+	defer mg.producerMu.RUnlock()
+
+	return mg.producer
+}
+
+// swapProducer replaces the producer and returns the previous one so it can
+// be closed.
+func (mg *Manager) swapProducer(producer MQClient) (previous MQClient) {
+	mg.producerMu.Lock()
+	previous, mg.producer = mg.producer, producer
+	mg.producerMu.Unlock()
+
+	return previous
+}
+
+// closeProducer flushes and closes a producer, waiting up to producerCloseTimeout
+// for outstanding publishes.
+func (mg *Manager) closeProducer(producerClient MQClient) {
+	mg.closeProducerWithin(producerClient, producerCloseTimeout)
+}
+
+// closeProducerWithin flushes and closes a producer, waiting up to timeout
+// for outstanding publishes.
+func (mg *Manager) closeProducerWithin(producerClient MQClient, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := producerClient.Close(ctx)
+	if err != nil {
+		mg.Logger.Warn().Err(err).Str("driver", producerClient.String()).Msg("Failed to close producer cleanly")
+	}
+}
+
 // GetGateway returns response from /gateway/bot.
 func (mg *Manager) GetGateway() (resp discord.GatewayBot, err error) {
 	_, err = mg.Client.FetchJSON(mg.ctx, "GET", "/gateway/bot", nil, nil, &resp)
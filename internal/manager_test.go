@@ -0,0 +1,303 @@
+package gateway
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/discordtest"
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/tevino/abool"
+	"golang.org/x/xerrors"
+)
+
+// How long the shards of a manager started against discordtest have to be
+// ready. READY is only processed after timeoutDuration.
+const discordReadyTimeout = timeoutDuration + 10*time.Second
+
+// newDiscordManager opens a manager with the none producer whose only shard
+// is ready and connected to a fake discord with guilds. The manager is closed
+// when the test finishes.
+func newDiscordManager(t *testing.T, guilds ...*discord.Guild) (*Manager, *discordtest.Server) {
+	t.Helper()
+
+	fake := discordtest.NewServer(t, guilds...)
+
+	sg, err := newSandwich(ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to create sandwich: %v", err)
+	}
+
+	sg.Configuration.Producer.Type = "none"
+	sg.Configuration.RestTunnel.Enabled = true
+	sg.Configuration.RestTunnel.URL = fake.URL
+	sg.RestTunnelEnabled.Set()
+	sg.RestTunnelReverse.Set()
+
+	configuration := &ManagerConfiguration{Identifier: "test", Token: testToken}
+	configuration.Messaging.ClientName = "sandwich"
+	configuration.Sharding.ShardCount = 1
+
+	mg, err := sg.NewManager(configuration)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	sg.ManagersMu.Lock()
+	sg.Managers[configuration.Identifier] = mg
+	sg.ManagersMu.Unlock()
+
+	if err = mg.Open(); err != nil {
+		t.Fatalf("failed to open manager: %v", err)
+	}
+
+	t.Cleanup(mg.Close)
+
+	ready, err := mg.StartShards()
+	if err != nil {
+		t.Fatalf("failed to start shards: %v", err)
+	}
+
+	select {
+	case <-ready:
+	case <-time.After(discordReadyTimeout):
+		t.Fatal("shards did not become ready")
+	}
+
+	return mg, fake
+}
+
+// onlyShard returns the shard of a manager made by newDiscordManager.
+func onlyShard(t *testing.T, mg *Manager) *Shard {
+	t.Helper()
+
+	group := mg.latestShardGroup()
+	if group == nil {
+		t.Fatal("manager has no shardgroup")
+	}
+
+	group.ShardsMu.RLock()
+	defer group.ShardsMu.RUnlock()
+
+	sh, ok := group.Shards[0]
+	if !ok {
+		t.Fatal("shardgroup has no shard 0")
+	}
+
+	return sh
+}
+
+func workerConfiguration(identifier string, workerID int64) *ManagerConfiguration {
+	configuration := &ManagerConfiguration{Identifier: identifier}
+	configuration.Messaging.WorkerID = workerID
+
+	return configuration
+}
+
+func TestEventWorkerID(t *testing.T) {
+	first := workerConfiguration("first", 0)
+	explicit := workerConfiguration("explicit", 1)
+	second := workerConfiguration("second", 0)
+	duplicate := workerConfiguration("duplicate", 1)
+
+	sg := &Sandwich{Configuration: &SandwichConfiguration{
+		Managers: []*ManagerConfiguration{first, explicit, second, duplicate},
+	}}
+
+	tests := []struct {
+		configuration *ManagerConfiguration
+		workerID      int64
+		err           error
+	}{
+		{first, 2, nil},
+		{explicit, 1, nil},
+		{second, 3, nil},
+		{duplicate, 0, ErrDuplicateWorkerID},
+		// Assigned worker IDs are kept.
+		{first, 2, nil},
+	}
+
+	for _, test := range tests {
+		workerID, err := sg.eventWorkerID(test.configuration)
+		if workerID != test.workerID || !xerrors.Is(err, test.err) {
+			t.Errorf("%s: got %d, %v, want %d, %v",
+				test.configuration.Identifier, workerID, err, test.workerID, test.err)
+		}
+	}
+
+	if _, err := sg.eventWorkerID(workerConfiguration("invalid", 1024)); err == nil {
+		t.Error("worker id 1024 was allowed")
+	}
+}
+
+func TestProducerSwapConcurrent(t *testing.T) {
+	mg := &Manager{
+		lastPublish:      new(int64),
+		lastPublishError: new(int64),
+	}
+	mg.swapProducer(&mqclients.NoneMQClient{})
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 1000; j++ {
+				if producer := mg.Producer(); producer != nil {
+					_ = producer.String()
+				}
+
+				_ = mg.ProducerStatus()
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 1000; j++ {
+				mg.swapProducer(&mqclients.NoneMQClient{})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if previous := mg.swapProducer(nil); previous == nil {
+		t.Error("producer was lost whilst swapping")
+	}
+
+	if mg.Producer() != nil {
+		t.Error("producer was not cleared")
+	}
+}
+
+func TestPublishWithoutProducer(t *testing.T) {
+	sh := newTestShard(t)
+
+	if producer := sh.Manager.swapProducer(nil); producer != nil {
+		t.Fatalf("test manager had producer %v", producer)
+	}
+
+	// Shard status updates are published even before the manager opens.
+	err := sh.SetStatus(structs.ShardWaiting)
+	if !xerrors.Is(err, ErrProducerUnavailable) {
+		t.Errorf("publishing without a producer returned %v", err)
+	}
+}
+
+// acceptingProducer counts the payloads the none driver accepts and those it
+// accepts after it has finished closing, which would be lost.
+type acceptingProducer struct {
+	*mqclients.NoneMQClient
+
+	accepted *int64
+	lost     *int64
+	closed   *abool.AtomicBool
+}
+
+func (p *acceptingProducer) Publish(ctx context.Context, channelName string, data []byte) (err error) {
+	// Widen the window between a publisher taking the producer and using
+	// it, so some publishes reach it after the manager has closed it.
+	time.Sleep(100 * time.Microsecond)
+
+	closed := p.closed.IsSet()
+
+	err = p.NoneMQClient.Publish(ctx, channelName, data)
+	if err == nil {
+		atomic.AddInt64(p.accepted, 1)
+
+		if closed {
+			atomic.AddInt64(p.lost, 1)
+		}
+	}
+
+	return err
+}
+
+func (p *acceptingProducer) Close(ctx context.Context) (err error) {
+	err = p.NoneMQClient.Close(ctx)
+	p.closed.Set()
+
+	return err
+}
+
+func TestManagerCloseUnderLoad(t *testing.T) {
+	sh := newTestShard(t)
+
+	producer := &acceptingProducer{
+		NoneMQClient: &mqclients.NoneMQClient{},
+		accepted:     new(int64),
+		lost:         new(int64),
+		closed:       abool.New(),
+	}
+	sh.Manager.swapProducer(producer)
+
+	succeeded := new(int64)
+	failed := new(int64)
+	stop := make(chan void)
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// Metadata is written to the payload as it is published.
+			packet := &structs.SandwichPayload{
+				ReceivedPayload: discord.ReceivedPayload{Op: discord.GatewayOpDispatch, Type: "MESSAGE_CREATE"},
+				Data:            map[string]string{"content": "load"},
+			}
+
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				if err := sh.PublishEvent(packet); err != nil {
+					atomic.AddInt64(failed, 1)
+				} else {
+					atomic.AddInt64(succeeded, 1)
+				}
+			}
+		}()
+	}
+
+	for atomic.LoadInt64(producer.accepted) < 1000 {
+		time.Sleep(time.Millisecond)
+	}
+
+	sh.Manager.Close()
+
+	for atomic.LoadInt64(failed) < 1000 {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(stop)
+	wg.Wait()
+
+	if lost := atomic.LoadInt64(producer.lost); lost > 0 {
+		t.Errorf("%d publishes were accepted after the producer closed", lost)
+	}
+
+	// Every publish reported as successful reached the producer and every
+	// other one was reported as failed.
+	if succeeded, accepted := atomic.LoadInt64(succeeded), atomic.LoadInt64(producer.accepted); succeeded != accepted {
+		t.Errorf("%d publishes succeeded but the producer accepted %d", succeeded, accepted)
+	}
+
+	if !producer.closed.IsSet() {
+		t.Error("producer was not closed")
+	}
+}
@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+// Prefix of the nonce sent with requests made by chunkGuild so their chunks
+// can be told apart from lazy member requests and those made by consumers.
+const memberChunkNoncePrefix = "chunk:"
+
+// memberChunkRequest tracks the GUILD_MEMBERS_CHUNK of a single request for
+// all members of a guild.
+type memberChunkRequest struct {
+	guildID snowflake.ID
+
+	mu       sync.Mutex
+	count    int          // chunk_count of the first chunk, 0 until it is received
+	received map[int]void // chunk_index of each chunk received
+
+	chunks   chan void // Signalled when a new chunk is received
+	complete chan void // Closed once every chunk has been received
+}
+
+func newMemberChunkRequest(guildID snowflake.ID) *memberChunkRequest {
+	return &memberChunkRequest{
+		guildID: guildID,
+
+		mu:       sync.Mutex{},
+		received: make(map[int]void),
+
+		chunks:   make(chan void, 1),
+		complete: make(chan void),
+	}
+}
+
+// receive marks a chunk as received. Chunks which have already been
+// received are ignored.
+func (mr *memberChunkRequest) receive(chunk *discord.GuildMembersChunk) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	if _, ok := mr.received[chunk.ChunkIndex]; ok {
+		return
+	}
+
+	if mr.count == 0 {
+		mr.count = chunk.ChunkCount
+	}
+
+	mr.received[chunk.ChunkIndex] = void{}
+
+	select {
+	case mr.chunks <- void{}:
+	default:
+	}
+
+	if mr.count > 0 && len(mr.received) == mr.count {
+		close(mr.complete)
+	}
+}
+
+// progress returns how many chunks have been received and how many are
+// expected.
+func (mr *memberChunkRequest) progress() (received int, expected int) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	return len(mr.received), mr.count
+}
+
+// addMemberChunkRequest registers a request and returns the nonce to send
+// it with.
+func (sg *ShardGroup) addMemberChunkRequest(request *memberChunkRequest) (nonce string) {
+	nonce = memberChunkNoncePrefix + strconv.FormatInt(atomic.AddInt64(sg.memberChunkNonce, 1), 36)
+
+	sg.MemberChunkRequestsMu.Lock()
+	sg.MemberChunkRequests[nonce] = request
+	sg.MemberChunkRequestsMu.Unlock()
+
+	return nonce
+}
+
+func (sg *ShardGroup) removeMemberChunkRequest(nonce string) {
+	sg.MemberChunkRequestsMu.Lock()
+	delete(sg.MemberChunkRequests, nonce)
+	sg.MemberChunkRequestsMu.Unlock()
+}
+
+// receiveMemberChunk passes a chunk to the request it answers. False is
+// returned if the chunk was not requested by chunkGuild or the request has
+// already finished.
+func (sg *ShardGroup) receiveMemberChunk(chunk *discord.GuildMembersChunk) bool {
+	if !strings.HasPrefix(chunk.Nonce, memberChunkNoncePrefix) {
+		return false
+	}
+
+	sg.MemberChunkRequestsMu.RLock()
+	request, ok := sg.MemberChunkRequests[chunk.Nonce]
+	sg.MemberChunkRequestsMu.RUnlock()
+
+	if !ok || request.guildID != chunk.GuildID {
+		return false
+	}
+
+	request.receive(chunk)
+
+	return true
+}
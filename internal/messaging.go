@@ -3,12 +3,14 @@ package gateway
 import (
 	"bytes"
 	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
 	"github.com/TheRockettek/Sandwich-Daemon/structs"
 	"github.com/andybalholm/brotli"
 	"github.com/savsgio/gotils"
-	"github.com/vmihailenco/msgpack"
 	"golang.org/x/xerrors"
 )
 
@@ -19,18 +21,71 @@ type MQClient interface {
 
 	Connect(ctx context.Context, clientName string, args map[string]interface{}) (err error)
 	Publish(ctx context.Context, channel string, data []byte) (err error)
+	// Flush waits for any outstanding publishes to be acknowledged or for the
+	// context to be done. Drivers that cannot flush return immediately.
+	Flush(ctx context.Context) (err error)
+	// Close flushes and closes the connection. The client must not be
+	// used after it has been closed.
+	Close(ctx context.Context) (err error)
 	// Function to receive a channel with messages
-	// Function to close
+}
+
+// MQSubscriber is implemented by MQClients which can also receive messages,
+// such as gateway commands from consumers.
+type MQSubscriber interface {
+	// Subscribe calls handler with each message on the channel until the
+	// context is done.
+	Subscribe(ctx context.Context, channel string, handler func(data []byte)) (err error)
+}
+
+// MQResponder is implemented by MQClients which can reply to requests from
+// consumers, such as NATS request/reply.
+type MQResponder interface {
+	// Respond calls handler with each request on the channel and replies
+	// with what it returns until the context is done.
+	Respond(ctx context.Context, channel string, handler func(data []byte) []byte) (err error)
+}
+
+// MQMessagePublisher is implemented by MQClients which can publish a message
+// with a key or headers, such as the partition key of kafka. Messages with the
+// same key keep their order.
+type MQMessagePublisher interface {
+	PublishMessage(ctx context.Context, channel string, message mqclients.Message, data []byte) (err error)
+}
+
+// publishMessage publishes data with the properties of a message if the
+// client supports them. Clients which do not publish just the data.
+func publishMessage(ctx context.Context, client MQClient, channel string, message mqclients.Message,
+	data []byte) (err error) {
+	if publisher, ok := client.(MQMessagePublisher); ok {
+		return publisher.PublishMessage(ctx, channel, message, data)
+	}
+
+	return client.Publish(ctx, channel, data)
+}
+
+// guildKey returns the message key of a guild which is empty for events
+// without a guild.
+func guildKey(guildID int64) []byte {
+	if guildID == 0 {
+		return nil
+	}
+
+	return strconv.AppendInt(nil, guildID, 10)
 }
 
 func NewMQClient(mqType string) (MQClient, error) {
 	switch mqType {
 	case "stan":
 		return &mqclients.StanMQClient{}, nil
+	case "jetstream":
+		return &mqclients.JetStreamMQClient{}, nil
 	case "kafka":
 		return &mqclients.KafkaMQClient{}, nil
 	case "redis":
 		return &mqclients.RedisMQClient{}, nil
+	case "none":
+		return &mqclients.NoneMQClient{}, nil
 	default:
 		return nil, xerrors.New("No MQ client named " + mqType)
 	}
@@ -44,20 +99,29 @@ func (sh *Shard) PublishEvent(packet *structs.SandwichPayload) (err error) {
 	packet.Metadata = structs.SandwichMetadata{
 		Version:    VERSION,
 		Identifier: sh.Manager.Configuration.Identifier,
+		GuildID:    int64(payloadGuildID(packet)),
 		Shard: [3]int{
 			int(sh.ShardGroup.ID),
 			sh.ShardID,
 			sh.ShardGroup.ShardCount,
 		},
+		EventID:   sh.Manager.eventIDs.Generate().Int64(),
+		Affinity:  sh.Manager.guildAffinityTag(packet, sh.Manager.Configuration.Events.GuildAffinityTag),
+		Ack:       sh.Manager.ackRequired(packet.Type),
+		Unhandled: packet.Metadata.Unhandled,
 	}
 
-	payload, err := msgpack.Marshal(packet)
+	payload, err := marshalPayload(sh.Manager.Configuration.Messaging.Encoding, packet)
 	if err != nil {
 		return xerrors.Errorf("failed to marshal payload: %w", err)
 	}
 
 	sh.Logger.Trace().Str("event", gotils.B2S(payload)).Msgf("Processed %s event", packet.Type)
 
+	sh.Manager.capturePayload(packet)
+
+	hookErr := sh.Manager.Sandwich.runEventHooks(sh.currentContext(), packet)
+
 	// Compression testing of large payloads. In the future this *may* be
 	// added however in its current state it is uncertain. With using a 1mb
 	// msgpack payload, compression can be brought down to 48kb using brotli
@@ -101,47 +165,165 @@ func (sh *Shard) PublishEvent(packet *structs.SandwichPayload) (err error) {
 	// Payloads larger than 1MB will default to using Level 6 brotli compression.
 	// For consistency sake, we also compress smaller payloads on the lowest level
 	// which should not affect performance too much as they are still fairly fast.
+	// Payloads under messaging.compression_threshold, or every payload when
+	// messaging.compression is none, are published uncompressed.
 
 	// a := time.Now()
 
-	compressedPayload := sh.cp.Get().(*bytes.Buffer)
+	compression := sh.Manager.payloadCompression(len(payload))
+	data := payload
 
-	if len(payload) > minPayloadCompressionSize {
-		dc := sh.DefaultCompressor.Get().(*brotli.Writer)
-		dc.Reset(compressedPayload)
+	if compression == compressionBrotli {
+		compressedPayload := sh.cp.Get().(*bytes.Buffer)
 
-		_, err = dc.Write(payload)
-		if err != nil {
-			sh.Logger.Warn().Err(err).Msg("Failed to write payload to brotli compressor")
-		}
+		defer func() {
+			compressedPayload.Reset()
+			sh.cp.Put(compressedPayload)
+		}()
+
+		if len(payload) > minPayloadCompressionSize {
+			dc := sh.DefaultCompressor.Get().(*brotli.Writer)
+			dc.Reset(compressedPayload)
+
+			_, err = dc.Write(payload)
+			if err != nil {
+				sh.Logger.Warn().Err(err).Msg("Failed to write payload to brotli compressor")
+			}
+
+			dc.Flush()
+			sh.DefaultCompressor.Put(dc)
+		} else {
+			fc := sh.FastCompressor.Get().(*brotli.Writer)
+			fc.Reset(compressedPayload)
 
-		dc.Flush()
-		sh.DefaultCompressor.Put(dc)
-	} else {
-		fc := sh.FastCompressor.Get().(*brotli.Writer)
-		fc.Reset(compressedPayload)
+			_, err = fc.Write(payload)
+			if err != nil {
+				sh.Logger.Warn().Err(err).Msg("Failed to write payload to brotli compressor")
+			}
 
-		_, err = fc.Write(payload)
-		if err != nil {
-			sh.Logger.Warn().Err(err).Msg("Failed to write payload to brotli compressor")
+			fc.Flush()
+			sh.FastCompressor.Put(fc)
 		}
 
-		fc.Flush()
-		sh.FastCompressor.Put(fc)
+		data = compressedPayload.Bytes()
+	}
+
+	message := sh.Manager.payloadMessage(guildKey(packet.Metadata.GuildID), compression)
+
+	// Acknowledgements are waited for from when the event is produced or
+	// buffered until startup finishes.
+	if packet.Metadata.Ack {
+		sh.Manager.trackAck(packet.Metadata.EventID, packet.Type, compression, data)
+	}
+
+	if sh.ShardGroup.startup.Enqueue(sh, packet.Type, message, data) {
+		return hookErr
+	}
+
+	if err = sh.publish(message, data); err != nil {
+		return err
 	}
 
-	err = sh.Manager.ProducerClient.Publish(
-		sh.ctx,
+	return hookErr
+}
+
+// publish sends a payload to the producer. If it fails and the retry buffer
+// is enabled, it is buffered and no error is returned. ConfigurationMu of the
+// manager must be held.
+func (sh *Shard) publish(message mqclients.Message, data []byte) (err error) {
+	queued, err := sh.Manager.publishWithRetry(
+		sh.currentContext(),
 		sh.Manager.Configuration.Messaging.ChannelName,
-		compressedPayload.Bytes(),
+		message,
+		data,
+		true,
 	)
 
-	compressedPayload.Reset()
-	sh.cp.Put(compressedPayload)
+	// Buffered payloads are counted once they are published.
+	if err != nil || !queued {
+		sh.Manager.recordPublish(len(data), err)
+	}
 
-	if err != nil {
+	if err != nil && !queued {
 		return xerrors.Errorf("publishEvent publish: %w", err)
 	}
 
 	return nil
 }
+
+// recordPublish tracks the outcome of a publish to the producer.
+func (mg *Manager) recordPublish(size int, err error) {
+	if !mg.recordPublishOutcome(err) {
+		return
+	}
+
+	atomic.AddInt64(mg.published, 1)
+	atomic.AddInt64(mg.producedBytes, int64(size))
+
+	mg.AnalyticsMu.RLock()
+	if mg.Produced != nil {
+		mg.Produced.Increment()
+	}
+	mg.AnalyticsMu.RUnlock()
+}
+
+// recordPublishOutcome updates when the producer last published or failed
+// to, which the producer status is based on. Returns if the publish succeeded.
+func (mg *Manager) recordPublishOutcome(err error) (ok bool) {
+	now := time.Now().UnixNano()
+
+	if err != nil {
+		atomic.StoreInt64(mg.lastPublishError, now)
+
+		return false
+	}
+
+	atomic.StoreInt64(mg.lastPublish, now)
+
+	return true
+}
+
+// ProducedMessages returns the number of messages produced in the last minute.
+func (mg *Manager) ProducedMessages() int64 {
+	mg.AnalyticsMu.RLock()
+	defer mg.AnalyticsMu.RUnlock()
+
+	if mg.Produced == nil {
+		return 0
+	}
+
+	return mg.Produced.GetLastSamples(producedSamples).Sum()
+}
+
+// ProducedBytes returns the total number of bytes successfully produced.
+func (mg *Manager) ProducedBytes() int64 {
+	return atomic.LoadInt64(mg.producedBytes)
+}
+
+// LastPublish returns when the producer last successfully published a message.
+func (mg *Manager) LastPublish() time.Time {
+	if last := atomic.LoadInt64(mg.lastPublish); last > 0 {
+		return time.Unix(0, last).UTC()
+	}
+
+	return time.Time{}
+}
+
+// ProducerStatus returns the status of the producer based on its last publish.
+func (mg *Manager) ProducerStatus() structs.ProducerStatus {
+	if mg.Producer() == nil {
+		return structs.ProducerIdle
+	}
+
+	lastPublish := atomic.LoadInt64(mg.lastPublish)
+	lastPublishError := atomic.LoadInt64(mg.lastPublishError)
+
+	switch {
+	case lastPublishError > lastPublish:
+		return structs.ProducerError
+	case lastPublish > 0:
+		return structs.ProducerConnected
+	default:
+		return structs.ProducerIdle
+	}
+}
@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+// benchmarkGuild returns a GUILD_CREATE dispatch with members members.
+func benchmarkGuild(members int) *structs.SandwichPayload {
+	guild := &discord.Guild{ID: testGuildID, Name: "benchmark"}
+
+	for i := 0; i < members; i++ {
+		id := snowflake.ID(1<<40 + i)
+
+		guild.Members = append(guild.Members, &discord.GuildMember{
+			User:     &discord.User{ID: id, Username: "user" + strconv.Itoa(i), Discriminator: "0001"},
+			Roles:    []snowflake.ID{testRoleID},
+			JoinedAt: "2021-01-01T00:00:00Z",
+		})
+	}
+
+	return &structs.SandwichPayload{
+		ReceivedPayload: discord.ReceivedPayload{Op: discord.GatewayOpDispatch, Type: "GUILD_CREATE"},
+		Data:            guild,
+	}
+}
+
+// benchmarkPublish publishes packet to the none producer with
+// messaging.compression set to compression.
+func benchmarkPublish(b *testing.B, compression string, packet *structs.SandwichPayload) {
+	sh := newTestShard(b)
+
+	sh.Manager.Configuration.Messaging.Compression = compression
+	sh.Manager.swapProducer(&mqclients.NoneMQClient{})
+
+	data, err := marshalPayload(encodingMsgpack, packet)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := sh.PublishEvent(packet); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPublishSmall(b *testing.B) {
+	benchmarkPublish(b, compressionNone, benchmarkGuild(1))
+}
+
+func BenchmarkPublishSmallBrotli(b *testing.B) {
+	benchmarkPublish(b, compressionBrotli, benchmarkGuild(1))
+}
+
+func BenchmarkPublishLarge(b *testing.B) {
+	benchmarkPublish(b, compressionNone, benchmarkGuild(10000))
+}
+
+func BenchmarkPublishLargeBrotli(b *testing.B) {
+	benchmarkPublish(b, compressionBrotli, benchmarkGuild(10000))
+}
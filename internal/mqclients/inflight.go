@@ -0,0 +1,77 @@
+package mqclients
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when publishing to an mqclient which is closing or
+// has been closed.
+var ErrClosed = errors.New("mqclient is closed")
+
+// inflight counts publishes which have not finished. Unlike a
+// sync.WaitGroup, publishes may start whilst something is waiting for them
+// and a wait can be given up on without leaving anything blocked. Once
+// stopped, no more publishes can start.
+type inflight struct {
+	countMu sync.Mutex
+	count   int64
+	idle    chan struct{} // Closed when count drops to 0
+	stopped bool
+}
+
+// start adds a publish. False is returned if the mqclient is closing.
+func (in *inflight) start() bool {
+	in.countMu.Lock()
+	defer in.countMu.Unlock()
+
+	if in.stopped {
+		return false
+	}
+
+	if in.count == 0 {
+		in.idle = make(chan struct{})
+	}
+
+	in.count++
+
+	return true
+}
+
+// finish removes a publish added by start.
+func (in *inflight) finish() {
+	in.countMu.Lock()
+	defer in.countMu.Unlock()
+
+	in.count--
+
+	if in.count == 0 {
+		close(in.idle)
+	}
+}
+
+// stop prevents any more publishes from starting.
+func (in *inflight) stop() {
+	in.countMu.Lock()
+	in.stopped = true
+	in.countMu.Unlock()
+}
+
+// wait returns once no publishes are in flight or the context is done.
+func (in *inflight) wait(ctx context.Context) (err error) {
+	in.countMu.Lock()
+	count, idle := in.count, in.idle
+	in.countMu.Unlock()
+
+	if count == 0 {
+		return nil
+	}
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,102 @@
+package mqclients
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInflightStartWhilstWaiting(t *testing.T) {
+	in := &inflight{}
+
+	if !in.start() {
+		t.Fatal("start was rejected before stop")
+	}
+
+	waited := make(chan error)
+
+	go func() { waited <- in.wait(context.Background()) }()
+
+	// Publishes keep starting and finishing whilst the wait is in progress,
+	// which a sync.WaitGroup does not allow.
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 1000; j++ {
+				if in.start() {
+					in.finish()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	select {
+	case err := <-waited:
+		t.Fatalf("wait returned %v whilst a publish was in flight", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	in.finish()
+
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Errorf("wait returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait did not return once no publishes were in flight")
+	}
+}
+
+func TestInflightWaitContext(t *testing.T) {
+	in := &inflight{}
+	in.start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := in.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait returned %v, expected the context error", err)
+	}
+
+	// Giving up on the wait leaves the count intact.
+	in.finish()
+
+	if err := in.wait(context.Background()); err != nil {
+		t.Errorf("wait returned %v once idle", err)
+	}
+}
+
+func TestInflightStop(t *testing.T) {
+	in := &inflight{}
+	in.start()
+	in.stop()
+
+	if in.start() {
+		t.Error("start was allowed after stop")
+	}
+
+	done := make(chan error)
+
+	go func() { done <- in.wait(context.Background()) }()
+
+	in.finish()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("wait returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publishes started before stop were not drained")
+	}
+}
@@ -0,0 +1,286 @@
+package mqclients
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	Register("jetstream", Capabilities{
+		SupportsFlush:     true,
+		SupportsSubscribe: true,
+		SupportsRequests:  true,
+		SupportsHeaders:   true,
+		MaxMessageSize:    1024 * 1024, // Default max_payload of nats
+	})
+}
+
+// Publishes which can be waiting for an acknowledgement before PublishAsync
+// blocks.
+const jetStreamMaxPending = 4096
+
+// JetStreamMQClient publishes to NATS JetStream which replaces NATS
+// Streaming. Publishes are asynchronous and failed acknowledgements are
+// returned by the next Publish or Flush.
+type JetStreamMQClient struct {
+	NatsClient      *nats.Conn            `json:"-"`
+	JetStreamClient nats.JetStreamContext `json:"-"`
+
+	// Subject every message is published to. The channel is used if empty.
+	subject string
+
+	asyncErrMu sync.Mutex
+	asyncErr   error
+
+	channel string
+	cluster string
+}
+
+func (jetStreamMQ *JetStreamMQClient) String() string {
+	return "jetstream"
+}
+
+func (jetStreamMQ *JetStreamMQClient) Channel() string {
+	return jetStreamMQ.channel
+}
+
+func (jetStreamMQ *JetStreamMQClient) Cluster() string {
+	return jetStreamMQ.cluster
+}
+
+// Connect connects to NATS and creates the stream if it is missing and
+// CreateStream is not false. The stream is named Stream, which defaults to
+// the cluster, and captures Subjects, which defaults to Subject or Channel.
+func (jetStreamMQ *JetStreamMQClient) Connect(ctx context.Context, clientName string, args map[string]interface{}) (err error) {
+	var ok bool
+
+	var address string
+
+	if address, ok = GetEntry(args, "Address").(string); !ok {
+		return xerrors.New("jetStreamMQ connect: string type assertion failed for Address")
+	}
+
+	var channel string
+
+	if channel, ok = GetEntry(args, "Channel").(string); !ok {
+		return xerrors.New("jetStreamMQ connect: string type assertion failed for Channel")
+	}
+
+	cluster, _ := GetEntry(args, "Cluster").(string)
+	subject, _ := GetEntry(args, "Subject").(string)
+
+	jetStreamMQ.channel = channel
+	jetStreamMQ.cluster = cluster
+	jetStreamMQ.subject = subject
+
+	jetStreamMQ.NatsClient, err = nats.Connect(address, nats.Name(clientName))
+	if err != nil {
+		return xerrors.Errorf("jetStreamMQ connect nats: %w", err)
+	}
+
+	jetStreamMQ.JetStreamClient, err = jetStreamMQ.NatsClient.JetStream(
+		nats.PublishAsyncMaxPending(jetStreamMaxPending),
+		nats.PublishAsyncErrHandler(func(_ nats.JetStream, msg *nats.Msg, err error) {
+			jetStreamMQ.setAsyncErr(xerrors.Errorf("jetStreamMQ publish to %s: %w", msg.Subject, err))
+		}),
+	)
+	if err != nil {
+		jetStreamMQ.NatsClient.Close()
+
+		return xerrors.Errorf("jetStreamMQ connect jetstream: %w", err)
+	}
+
+	createStream := true
+
+	if createStreamStr, ok := GetEntry(args, "CreateStream").(string); ok {
+		if createStream, err = strconv.ParseBool(createStreamStr); err != nil {
+			createStream = true
+		}
+	}
+
+	if createStream {
+		if err = jetStreamMQ.ensureStream(ctx, args); err != nil {
+			jetStreamMQ.NatsClient.Close()
+
+			return xerrors.Errorf("jetStreamMQ connect: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ensureStream creates the stream if it does not exist.
+func (jetStreamMQ *JetStreamMQClient) ensureStream(ctx context.Context, args map[string]interface{}) (err error) {
+	stream, _ := GetEntry(args, "Stream").(string)
+	if stream == "" {
+		stream = jetStreamMQ.cluster
+	}
+
+	if stream == "" {
+		return xerrors.New("ensure stream: Stream or Cluster must be set to create the stream")
+	}
+
+	if _, err = jetStreamMQ.JetStreamClient.StreamInfo(stream, nats.Context(ctx)); err == nil {
+		return nil
+	}
+
+	var subjects []string
+
+	subjectsStr, _ := GetEntry(args, "Subjects").(string)
+	for _, subject := range strings.Split(subjectsStr, ",") {
+		if subject = strings.TrimSpace(subject); subject != "" {
+			subjects = append(subjects, subject)
+		}
+	}
+
+	if len(subjects) == 0 {
+		if jetStreamMQ.subject != "" {
+			subjects = []string{jetStreamMQ.subject}
+		} else {
+			subjects = []string{jetStreamMQ.channel}
+		}
+	}
+
+	config := &nats.StreamConfig{
+		Name:     stream,
+		Subjects: subjects,
+		Storage:  nats.FileStorage,
+	}
+
+	if storage, _ := GetEntry(args, "Storage").(string); strings.EqualFold(storage, "memory") {
+		config.Storage = nats.MemoryStorage
+	}
+
+	if replicasStr, ok := GetEntry(args, "Replicas").(string); ok {
+		config.Replicas, _ = strconv.Atoi(replicasStr)
+	}
+
+	if _, err = jetStreamMQ.JetStreamClient.AddStream(config, nats.Context(ctx)); err != nil {
+		return xerrors.Errorf("ensure stream %s: %w", stream, err)
+	}
+
+	return nil
+}
+
+func (jetStreamMQ *JetStreamMQClient) setAsyncErr(err error) {
+	jetStreamMQ.asyncErrMu.Lock()
+	jetStreamMQ.asyncErr = err
+	jetStreamMQ.asyncErrMu.Unlock()
+}
+
+// takeAsyncErr returns the last failed acknowledgement and clears it.
+func (jetStreamMQ *JetStreamMQClient) takeAsyncErr() (err error) {
+	jetStreamMQ.asyncErrMu.Lock()
+	err = jetStreamMQ.asyncErr
+	jetStreamMQ.asyncErr = nil
+	jetStreamMQ.asyncErrMu.Unlock()
+
+	return err
+}
+
+// Publish publishes asynchronously. If a previous publish was not
+// acknowledged, its error is returned so it reaches PublishEvent.
+func (jetStreamMQ *JetStreamMQClient) Publish(ctx context.Context, channelName string, data []byte) (err error) {
+	return jetStreamMQ.PublishMessage(ctx, channelName, Message{}, data)
+}
+
+// PublishMessage publishes asynchronously with headers. Keys are not used as
+// a stream keeps the order of every message.
+func (jetStreamMQ *JetStreamMQClient) PublishMessage(ctx context.Context, channelName string, message Message, data []byte) (err error) {
+	msg := &nats.Msg{
+		Subject: jetStreamMQ.subject,
+		Data:    data,
+	}
+
+	if msg.Subject == "" {
+		msg.Subject = channelName
+	}
+
+	if len(message.Headers) > 0 {
+		msg.Header = make(nats.Header, len(message.Headers))
+
+		for key, value := range message.Headers {
+			msg.Header.Set(key, value)
+		}
+	}
+
+	_, err = jetStreamMQ.JetStreamClient.PublishMsgAsync(msg)
+	if err != nil {
+		return xerrors.Errorf("jetStreamMQ publish: %w", err)
+	}
+
+	return jetStreamMQ.takeAsyncErr()
+}
+
+// Subscribe calls handler with each message published to the channel after
+// subscribing until the context is done. Consumers send commands over core
+// NATS so they are not kept by the stream.
+func (jetStreamMQ *JetStreamMQClient) Subscribe(ctx context.Context, channelName string, handler func(data []byte)) (err error) {
+	subscription, err := jetStreamMQ.NatsClient.Subscribe(channelName, func(message *nats.Msg) {
+		handler(message.Data)
+	})
+	if err != nil {
+		return xerrors.Errorf("jetStreamMQ subscribe: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = subscription.Unsubscribe()
+	}()
+
+	return nil
+}
+
+// Respond replies to requests sent to the channel over core NATS with what
+// handler returns until the context is done. Requests without a reply
+// subject are ignored.
+func (jetStreamMQ *JetStreamMQClient) Respond(ctx context.Context, channelName string, handler func(data []byte) []byte) (err error) {
+	subscription, err := jetStreamMQ.NatsClient.Subscribe(channelName, func(message *nats.Msg) {
+		if message.Reply == "" {
+			return
+		}
+
+		_ = message.Respond(handler(message.Data))
+	})
+	if err != nil {
+		return xerrors.Errorf("jetStreamMQ respond: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = subscription.Unsubscribe()
+	}()
+
+	return nil
+}
+
+func (jetStreamMQ *JetStreamMQClient) Flush(ctx context.Context) (err error) {
+	if jetStreamMQ.JetStreamClient == nil {
+		return nil
+	}
+
+	select {
+	case <-jetStreamMQ.JetStreamClient.PublishAsyncComplete():
+	case <-ctx.Done():
+		return xerrors.Errorf("jetStreamMQ flush: %w", ctx.Err())
+	}
+
+	return jetStreamMQ.takeAsyncErr()
+}
+
+func (jetStreamMQ *JetStreamMQClient) Close(ctx context.Context) (err error) {
+	err = jetStreamMQ.Flush(ctx)
+
+	if jetStreamMQ.NatsClient != nil {
+		jetStreamMQ.NatsClient.Close()
+	}
+
+	return err
+}
@@ -0,0 +1,32 @@
+package mqclients_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients/mqclienttest"
+)
+
+// TestJetStream runs against the NATS server at
+// SANDWICH_TEST_JETSTREAM_ADDRESS, such as nats://127.0.0.1:4222, which
+// must have JetStream enabled. A memory stream is created for the test
+// channel if it does not exist.
+func TestJetStream(t *testing.T) {
+	address := os.Getenv("SANDWICH_TEST_JETSTREAM_ADDRESS")
+	if address == "" {
+		t.Skip("SANDWICH_TEST_JETSTREAM_ADDRESS is not set")
+	}
+
+	mqclienttest.Run(t, mqclienttest.Config{
+		Name: "jetstream",
+		New:  func() mqclienttest.Client { return &mqclients.JetStreamMQClient{} },
+		Args: map[string]interface{}{
+			"Address":      address,
+			"Channel":      "sandwich-mqclienttest",
+			"Stream":       "sandwich-mqclienttest",
+			"CreateStream": "true",
+			"Storage":      "memory",
+		},
+	})
+}
@@ -2,14 +2,25 @@ package mqclients
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
 	"strconv"
+	"strings"
 
 	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
 	"golang.org/x/xerrors"
 )
 
 func init() {
-	MQClients = append(MQClients, "kafka")
+	Register("kafka", Capabilities{
+		SupportsBatch:   true,
+		SupportsHeaders: true,
+		MaxMessageSize:  1000012, // Default message.max.bytes of kafka
+	})
 }
 
 type KafkaMQClient struct {
@@ -36,6 +47,106 @@ func parseKafkaBalancer(balancer string) kafka.Balancer {
 	}
 }
 
+func parseKafkaAcks(acks string) (kafka.RequiredAcks, error) {
+	switch strings.ToLower(acks) {
+	case "none", "0":
+		return kafka.RequireNone, nil
+	case "one", "1", "leader":
+		return kafka.RequireOne, nil
+	case "", "all", "-1":
+		return kafka.RequireAll, nil
+	default:
+		return kafka.RequireAll, xerrors.Errorf("unknown acks %q. Expected none, one or all", acks)
+	}
+}
+
+// parseKafkaBrokers returns the brokers from a comma separated string or a
+// list.
+func parseKafkaBrokers(value interface{}) (brokers []string) {
+	switch value := value.(type) {
+	case string:
+		for _, broker := range strings.Split(value, ",") {
+			if broker = strings.TrimSpace(broker); broker != "" {
+				brokers = append(brokers, broker)
+			}
+		}
+	case []interface{}:
+		for _, broker := range value {
+			if broker, ok := broker.(string); ok && broker != "" {
+				brokers = append(brokers, broker)
+			}
+		}
+	case []string:
+		brokers = append(brokers, value...)
+	}
+
+	return brokers
+}
+
+func parseKafkaSASL(args map[string]interface{}) (mechanism sasl.Mechanism, err error) {
+	saslMechanism, _ := GetEntry(args, "SASLMechanism").(string)
+	username, _ := GetEntry(args, "SASLUsername").(string)
+	password, _ := GetEntry(args, "SASLPassword").(string)
+
+	switch strings.ToLower(saslMechanism) {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "scram-sha-256":
+		mechanism, err = scram.Mechanism(scram.SHA256, username, password)
+	case "scram-sha-512":
+		mechanism, err = scram.Mechanism(scram.SHA512, username, password)
+	default:
+		return nil, xerrors.Errorf("unknown SASL mechanism %q. Expected plain, scram-sha-256 or scram-sha-512", saslMechanism)
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("sasl: %w", err)
+	}
+
+	return mechanism, nil
+}
+
+func parseKafkaTLS(args map[string]interface{}) (config *tls.Config, err error) {
+	enabled := false
+
+	if tlsStr, ok := GetEntry(args, "TLS").(string); ok {
+		enabled, _ = strconv.ParseBool(tlsStr)
+	}
+
+	if !enabled {
+		return nil, nil
+	}
+
+	config = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if serverName, ok := GetEntry(args, "TLSServerName").(string); ok {
+		config.ServerName = serverName
+	}
+
+	if skipVerifyStr, ok := GetEntry(args, "TLSSkipVerify").(string); ok {
+		// Opt in for brokers with self signed certificates.
+		config.InsecureSkipVerify, _ = strconv.ParseBool(skipVerifyStr) //nolint:gosec
+	}
+
+	if caFile, ok := GetEntry(args, "TLSCAFile").(string); ok && caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, xerrors.Errorf("read ca file: %w", err)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, xerrors.Errorf("ca file %s contains no certificates", caFile)
+		}
+	}
+
+	return config, nil
+}
+
 func (kafkaMQ *KafkaMQClient) String() string {
 	return "kafka"
 }
@@ -48,27 +159,29 @@ func (kafkaMQ *KafkaMQClient) Cluster() string {
 	return kafkaMQ.cluster
 }
 
+// Connect creates the kafka writer. Brokers is a comma separated list of
+// brokers, Address is used if it is not set. If Topic is set every message
+// is written to it, otherwise the channel it is published to is used. The
+// hash balancer is used by default so messages of the same guild go to the
+// same partition and keep their order.
 func (kafkaMQ *KafkaMQClient) Connect(ctx context.Context, clientName string, args map[string]interface{}) (err error) {
-	var ok bool
-
-	var address string
-
-	if address, ok = GetEntry(args, "Address").(string); !ok {
-		return xerrors.New("kafkaMQ connect: string type assertion failed for Address")
+	brokers := parseKafkaBrokers(GetEntry(args, "Brokers"))
+	if len(brokers) == 0 {
+		brokers = parseKafkaBrokers(GetEntry(args, "Address"))
 	}
 
-	var topic string
-
-	if topic, ok = GetEntry(args, "Topic").(string); !ok {
-		return xerrors.New("kafkaMQ connect: string type assertion failed for Topic")
+	if len(brokers) == 0 {
+		return xerrors.New("kafkaMQ connect: no Brokers or Address were provided")
 	}
 
-	var balancer kafka.Balancer
+	topic, _ := GetEntry(args, "Topic").(string)
 
-	if balancerStr, ok := GetEntry(args, "Balancer").(string); ok {
-		balancer = parseKafkaBalancer(balancerStr)
-	} else {
-		return xerrors.New("kafkaMQ connect: string type assertion failed for Balancer")
+	balancer := kafka.Balancer(&kafka.Hash{})
+
+	if balancerStr, ok := GetEntry(args, "Balancer").(string); ok && balancerStr != "" {
+		if balancer = parseKafkaBalancer(balancerStr); balancer == nil {
+			return xerrors.Errorf("kafkaMQ connect: unknown balancer %q", balancerStr)
+		}
 	}
 
 	var async bool
@@ -79,22 +192,91 @@ func (kafkaMQ *KafkaMQClient) Connect(ctx context.Context, clientName string, ar
 		async = false
 	}
 
+	acksStr, _ := GetEntry(args, "Acks").(string)
+
+	acks, err := parseKafkaAcks(acksStr)
+	if err != nil {
+		return xerrors.Errorf("kafkaMQ connect: %w", err)
+	}
+
+	mechanism, err := parseKafkaSASL(args)
+	if err != nil {
+		return xerrors.Errorf("kafkaMQ connect: %w", err)
+	}
+
+	tlsConfig, err := parseKafkaTLS(args)
+	if err != nil {
+		return xerrors.Errorf("kafkaMQ connect tls: %w", err)
+	}
+
+	kafkaMQ.channel = topic
+
 	kafkaMQ.KafkaClient = &kafka.Writer{
-		Addr:     kafka.TCP(address),
-		Topic:    topic,
-		Balancer: balancer,
-		Async:    async,
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     balancer,
+		RequiredAcks: acks,
+		Async:        async,
+		Transport: &kafka.Transport{
+			ClientID: clientName,
+			SASL:     mechanism,
+			TLS:      tlsConfig,
+		},
 	}
 
 	return nil
 }
 
 func (kafkaMQ *KafkaMQClient) Publish(ctx context.Context, channelName string, data []byte) (err error) {
-	return kafkaMQ.KafkaClient.WriteMessages(
-		ctx,
-		kafka.Message{
-			Topic: channelName,
-			Value: data,
-		},
-	)
+	return kafkaMQ.PublishMessage(ctx, channelName, Message{}, data)
+}
+
+// PublishMessage publishes a message with a partition key and headers.
+// Messages with the same key are written to the same partition when the
+// balancer uses keys.
+func (kafkaMQ *KafkaMQClient) PublishMessage(ctx context.Context, channelName string, message Message, data []byte) (err error) {
+	kafkaMessage := kafka.Message{
+		Key:   message.Key,
+		Value: data,
+	}
+
+	for key, value := range message.Headers {
+		kafkaMessage.Headers = append(kafkaMessage.Headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	// The writer rejects messages with a topic when it has one itself.
+	if kafkaMQ.KafkaClient.Topic == "" {
+		kafkaMessage.Topic = channelName
+	}
+
+	return kafkaMQ.KafkaClient.WriteMessages(ctx, kafkaMessage)
+}
+
+// Flush returns immediately. Synchronous writes are already acknowledged
+// and the writer only flushes async batches when it is closed.
+func (kafkaMQ *KafkaMQClient) Flush(ctx context.Context) (err error) {
+	return nil
+}
+
+func (kafkaMQ *KafkaMQClient) Close(ctx context.Context) (err error) {
+	if kafkaMQ.KafkaClient == nil {
+		return nil
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- kafkaMQ.KafkaClient.Close()
+	}()
+
+	select {
+	case err = <-errCh:
+		if err != nil {
+			return xerrors.Errorf("kafkaMQ close: %w", err)
+		}
+
+		return nil
+	case <-ctx.Done():
+		return xerrors.Errorf("kafkaMQ close: %w", ctx.Err())
+	}
 }
@@ -0,0 +1,28 @@
+package mqclients_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients/mqclienttest"
+)
+
+// TestKafka publishes to the brokers in SANDWICH_TEST_KAFKA_BROKERS, a
+// comma separated list such as 127.0.0.1:9092. The broker must allow topics
+// to be created automatically.
+func TestKafka(t *testing.T) {
+	brokers := os.Getenv("SANDWICH_TEST_KAFKA_BROKERS")
+	if brokers == "" {
+		t.Skip("SANDWICH_TEST_KAFKA_BROKERS is not set")
+	}
+
+	mqclienttest.Run(t, mqclienttest.Config{
+		Name:    "kafka",
+		New:     func() mqclienttest.Client { return &mqclients.KafkaMQClient{} },
+		Channel: "sandwich-mqclienttest",
+		Args: map[string]interface{}{
+			"Brokers": brokers,
+		},
+	})
+}
@@ -0,0 +1,170 @@
+package mqclients_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients/mqclienttest"
+)
+
+// Largest payload the memory broker accepts.
+const memoryMaxMessageSize = 64 * 1024
+
+var (
+	errMemoryClientName   = errors.New("memory: client name is already connected")
+	errMemoryNotConnected = errors.New("memory: not connected")
+	errMemoryInterrupted  = errors.New("memory: connection was interrupted")
+	errMemoryTooLarge     = errors.New("memory: payload is too large")
+)
+
+func init() {
+	mqclients.Register("memory", mqclients.Capabilities{
+		SupportsFlush:  true,
+		MaxMessageSize: memoryMaxMessageSize,
+	})
+}
+
+// memoryBroker keeps published payloads in memory. Like STAN, each client
+// name may only be connected once at a time.
+type memoryBroker struct {
+	mu         sync.Mutex
+	clients    map[string]void
+	channels   map[string][][]byte
+	generation int64 // Increased when connections are interrupted
+}
+
+type void struct{}
+
+func newMemoryBroker() *memoryBroker {
+	return &memoryBroker{
+		clients:  make(map[string]void),
+		channels: make(map[string][][]byte),
+	}
+}
+
+func (mb *memoryBroker) interrupt() {
+	mb.mu.Lock()
+	mb.generation++
+	mb.mu.Unlock()
+}
+
+func (mb *memoryBroker) published(channel string) [][]byte {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	return append([][]byte(nil), mb.channels[channel]...)
+}
+
+// memoryMQClient is a driver for memoryBroker. After an interruption the
+// next publish fails and the client reconnects for the ones after it.
+type memoryMQClient struct {
+	broker *memoryBroker
+
+	mu         sync.Mutex
+	name       string
+	generation int64
+	connected  bool
+
+	channel string
+}
+
+func (memoryMQ *memoryMQClient) String() string {
+	return "memory"
+}
+
+func (memoryMQ *memoryMQClient) Channel() string {
+	return memoryMQ.channel
+}
+
+func (memoryMQ *memoryMQClient) Cluster() string {
+	return ""
+}
+
+func (memoryMQ *memoryMQClient) Connect(ctx context.Context, clientName string, args map[string]interface{}) error {
+	memoryMQ.channel, _ = mqclients.GetEntry(args, "Channel").(string)
+
+	memoryMQ.broker.mu.Lock()
+	defer memoryMQ.broker.mu.Unlock()
+
+	if _, ok := memoryMQ.broker.clients[clientName]; ok {
+		return errMemoryClientName
+	}
+
+	memoryMQ.broker.clients[clientName] = void{}
+
+	memoryMQ.mu.Lock()
+	memoryMQ.name = clientName
+	memoryMQ.generation = memoryMQ.broker.generation
+	memoryMQ.connected = true
+	memoryMQ.mu.Unlock()
+
+	return nil
+}
+
+func (memoryMQ *memoryMQClient) Publish(ctx context.Context, channelName string, data []byte) error {
+	if len(data) > memoryMaxMessageSize {
+		return errMemoryTooLarge
+	}
+
+	memoryMQ.broker.mu.Lock()
+	defer memoryMQ.broker.mu.Unlock()
+
+	memoryMQ.mu.Lock()
+	defer memoryMQ.mu.Unlock()
+
+	if !memoryMQ.connected {
+		return errMemoryNotConnected
+	}
+
+	if memoryMQ.generation != memoryMQ.broker.generation {
+		memoryMQ.generation = memoryMQ.broker.generation
+
+		return errMemoryInterrupted
+	}
+
+	memoryMQ.broker.channels[channelName] = append(memoryMQ.broker.channels[channelName],
+		append([]byte(nil), data...))
+
+	return nil
+}
+
+// Flush returns immediately as publishes are stored before they return.
+func (memoryMQ *memoryMQClient) Flush(ctx context.Context) error {
+	return nil
+}
+
+func (memoryMQ *memoryMQClient) Close(ctx context.Context) error {
+	memoryMQ.broker.mu.Lock()
+	defer memoryMQ.broker.mu.Unlock()
+
+	memoryMQ.mu.Lock()
+	defer memoryMQ.mu.Unlock()
+
+	if memoryMQ.connected {
+		delete(memoryMQ.broker.clients, memoryMQ.name)
+		memoryMQ.connected = false
+	}
+
+	return nil
+}
+
+// TestMemory runs the harness against an in-memory broker so every check,
+// including deliveries and reconnecting, runs without an external broker.
+func TestMemory(t *testing.T) {
+	broker := newMemoryBroker()
+
+	mqclienttest.Run(t, mqclienttest.Config{
+		Name: "memory",
+		New:  func() mqclienttest.Client { return &memoryMQClient{broker: broker} },
+		Args: map[string]interface{}{
+			"Channel": "sandwich-mqclienttest",
+		},
+		Interrupt: func(t *testing.T, client mqclienttest.Client) {
+			broker.interrupt()
+		},
+		Published: broker.published,
+	})
+}
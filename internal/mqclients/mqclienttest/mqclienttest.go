@@ -0,0 +1,402 @@
+// Package mqclienttest checks that a producer driver behaves the way the
+// daemon expects. Drivers run it from their own tests against a broker:
+//
+//	func TestStan(t *testing.T) {
+//		mqclienttest.Run(t, mqclienttest.Config{
+//			Name: "stan",
+//			New:  func() mqclienttest.Client { return &mqclients.StanMQClient{} },
+//			Args: map[string]interface{}{"Address": "nats://127.0.0.1:4222", ...},
+//		})
+//	}
+package mqclienttest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+)
+
+const (
+	// How long an operation can take before the driver is considered to be
+	// ignoring its context.
+	operationTimeout = 10 * time.Second
+
+	// Publishes made at once by the concurrency check.
+	concurrentPublishers = 16
+	publishesPerWorker   = 64
+
+	// Payloads over this are only sent when Config.LargePayloads is set as
+	// they are slow to send to a real broker.
+	largePayloadSize = 16 * 1024 * 1024
+
+	// Bytes left under MaxMessageSize for the headers the broker adds.
+	payloadHeadroom = 1024
+)
+
+// Client is the interface a producer driver implements. It matches
+// gateway.MQClient which cannot be imported here.
+type Client interface {
+	String() string
+	Channel() string
+	Cluster() string
+
+	Connect(ctx context.Context, clientName string, args map[string]interface{}) (err error)
+	Publish(ctx context.Context, channel string, data []byte) (err error)
+	Flush(ctx context.Context) (err error)
+	Close(ctx context.Context) (err error)
+}
+
+// Config describes the driver being checked.
+type Config struct {
+	// Name the driver is registered under. Its capabilities are used to
+	// decide which checks apply.
+	Name string
+
+	// New returns a client which has not been connected.
+	New func() Client
+
+	// Arguments passed to Connect. This is the producer configuration.
+	ClientName string
+	Args       map[string]interface{}
+
+	// Channel published to. If empty, the channel of the client is used.
+	Channel string
+
+	// Interrupt breaks the connection of a client, such as by restarting an
+	// in-memory broker. Drivers which cannot be interrupted leave this nil
+	// and the reconnection check is skipped.
+	Interrupt func(t *testing.T, client Client)
+
+	// Published returns the payloads the broker received on a channel so
+	// deliveries can be checked. If nil, only publish errors are checked.
+	Published func(channel string) [][]byte
+
+	// Send payloads over largePayloadSize when checking MaxMessageSize.
+	LargePayloads bool
+}
+
+// Run checks the driver described by config. Each check runs as a subtest
+// with its own client.
+func Run(t *testing.T, config Config) {
+	t.Helper()
+
+	capabilities, ok := mqclients.MQCapabilities[config.Name]
+	if !ok {
+		t.Fatalf("%q is not a registered mqclient. Expected one of %v", config.Name, mqclients.MQClients)
+	}
+
+	if config.ClientName == "" {
+		config.ClientName = "mqclienttest"
+	}
+
+	h := &harness{config: config, capabilities: capabilities}
+
+	t.Run("Lifecycle", h.testLifecycle)
+	t.Run("ConcurrentPublish", h.testConcurrentPublish)
+	t.Run("MaxMessageSize", h.testMaxMessageSize)
+	t.Run("Cancellation", h.testCancellation)
+	t.Run("Reconnect", h.testReconnect)
+}
+
+type harness struct {
+	config       Config
+	capabilities mqclients.Capabilities
+}
+
+// connect creates and connects a client which is closed when the test
+// finishes.
+func (h *harness) connect(t *testing.T) (client Client, channel string) {
+	t.Helper()
+
+	client = h.config.New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	if err := client.Connect(ctx, h.config.ClientName+"-"+strconv.FormatInt(time.Now().UnixNano(), 36), h.config.Args); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+		defer cancel()
+
+		_ = client.Close(ctx)
+	})
+
+	channel = h.config.Channel
+	if channel == "" {
+		channel = client.Channel()
+	}
+
+	if client.String() != h.config.Name {
+		t.Errorf("String() = %q, expected %q", client.String(), h.config.Name)
+	}
+
+	return client, channel
+}
+
+// within fails the test if fn does not return before operationTimeout.
+func within(t *testing.T, name string, fn func() error) (err error) {
+	t.Helper()
+
+	done := make(chan error, 1)
+
+	go func() { done <- fn() }()
+
+	select {
+	case err = <-done:
+		return err
+	case <-time.After(operationTimeout):
+		t.Fatalf("%s did not return within %s", name, operationTimeout)
+
+		return nil
+	}
+}
+
+// expectDelivered checks the broker received every payload if the config
+// can report deliveries.
+func (h *harness) expectDelivered(t *testing.T, channel string, payloads [][]byte) {
+	t.Helper()
+
+	if h.config.Published == nil {
+		return
+	}
+
+	received := h.config.Published(channel)
+
+	for _, payload := range payloads {
+		found := false
+
+		for _, r := range received {
+			if bytes.Equal(r, payload) {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("payload of %d bytes was published but not received", len(payload))
+		}
+	}
+}
+
+// testLifecycle connects, publishes, flushes and closes a client, then
+// checks the closed client rejects publishes.
+func (h *harness) testLifecycle(t *testing.T) {
+	client := h.config.New()
+	ctx := context.Background()
+
+	err := within(t, "Connect", func() error {
+		return client.Connect(ctx, h.config.ClientName, h.config.Args)
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	channel := h.config.Channel
+	if channel == "" {
+		channel = client.Channel()
+	}
+
+	payloads := make([][]byte, 0, 8)
+
+	for i := 0; i < 8; i++ {
+		payload := []byte(fmt.Sprintf(`{"op":0,"t":"MQCLIENTTEST","d":%d}`, i))
+
+		if err := within(t, "Publish", func() error { return client.Publish(ctx, channel, payload) }); err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+
+		payloads = append(payloads, payload)
+	}
+
+	if err := within(t, "Flush", func() error { return client.Flush(ctx) }); err != nil {
+		t.Errorf("flush: %v", err)
+	}
+
+	// Flushing again with nothing outstanding must also return.
+	if err := within(t, "Flush", func() error { return client.Flush(ctx) }); err != nil {
+		t.Errorf("second flush: %v", err)
+	}
+
+	if err := within(t, "Close", func() error { return client.Close(ctx) }); err != nil {
+		t.Errorf("close: %v", err)
+	}
+
+	h.expectDelivered(t, channel, payloads)
+
+	// A closed client cannot deliver anything so publishing must fail
+	// rather than drop the payload.
+	if err := within(t, "Publish after Close", func() error {
+		return client.Publish(ctx, channel, []byte(`{}`))
+	}); err == nil {
+		t.Error("publish after close returned no error")
+	}
+
+	// Closing must release the client name so the producer can be
+	// restarted with the same name.
+	again := h.config.New()
+
+	err = within(t, "Connect", func() error {
+		return again.Connect(ctx, h.config.ClientName, h.config.Args)
+	})
+	if err != nil {
+		t.Fatalf("connect with the same client name after close: %v", err)
+	}
+
+	_ = within(t, "Close", func() error { return again.Close(ctx) })
+}
+
+// testConcurrentPublish publishes from many goroutines at once as shards do.
+func (h *harness) testConcurrentPublish(t *testing.T) {
+	client, channel := h.connect(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, concurrentPublishers*publishesPerWorker)
+	payloads := make([][]byte, concurrentPublishers*publishesPerWorker)
+
+	for w := 0; w < concurrentPublishers; w++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < publishesPerWorker; i++ {
+				payload := []byte(fmt.Sprintf(`{"worker":%d,"i":%d}`, w, i))
+				payloads[w*publishesPerWorker+i] = payload
+
+				if err := client.Publish(ctx, channel, payload); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+
+	_ = within(t, "concurrent Publish", func() error {
+		wg.Wait()
+
+		return nil
+	})
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent publish: %v", err)
+	}
+
+	if err := within(t, "Flush", func() error { return client.Flush(ctx) }); err != nil {
+		t.Errorf("flush: %v", err)
+	}
+
+	h.expectDelivered(t, channel, payloads)
+}
+
+// testMaxMessageSize publishes a payload just under MaxMessageSize, which
+// must succeed, and one over it, which must fail rather than be dropped.
+func (h *harness) testMaxMessageSize(t *testing.T) {
+	limit := h.capabilities.MaxMessageSize
+	if limit == 0 {
+		t.Skip("driver has no message size limit")
+	}
+
+	if limit > largePayloadSize && !h.config.LargePayloads {
+		t.Skipf("MaxMessageSize of %d bytes is only checked with LargePayloads", limit)
+	}
+
+	client, channel := h.connect(t)
+	ctx := context.Background()
+
+	under := bytes.Repeat([]byte("a"), limit-payloadHeadroom)
+
+	if err := within(t, "Publish", func() error { return client.Publish(ctx, channel, under) }); err != nil {
+		t.Errorf("publish of %d bytes under MaxMessageSize of %d: %v", len(under), limit, err)
+	}
+
+	over := bytes.Repeat([]byte("a"), limit+1)
+
+	err := within(t, "Publish", func() error {
+		if err := client.Publish(ctx, channel, over); err != nil {
+			return err
+		}
+
+		return client.Flush(ctx)
+	})
+	if err == nil && h.config.Published != nil {
+		for _, r := range h.config.Published(channel) {
+			if len(r) == len(over) {
+				return
+			}
+		}
+
+		t.Errorf("publish of %d bytes over MaxMessageSize of %d returned no error but was not received", len(over), limit)
+	}
+}
+
+// testCancellation checks operations return once their context is done
+// instead of blocking the shard publishing.
+func (h *harness) testCancellation(t *testing.T) {
+	client, channel := h.connect(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Drivers may still publish with a cancelled context but must return.
+	_ = within(t, "Publish with a cancelled context", func() error {
+		return client.Publish(ctx, channel, []byte(`{}`))
+	})
+
+	_ = within(t, "Flush with a cancelled context", func() error {
+		return client.Flush(ctx)
+	})
+
+	// The client must still be usable afterwards.
+	if err := within(t, "Publish", func() error {
+		return client.Publish(context.Background(), channel, []byte(`{}`))
+	}); err != nil {
+		t.Errorf("publish after cancelled operations: %v", err)
+	}
+}
+
+// testReconnect interrupts the connection and checks publishes recover.
+func (h *harness) testReconnect(t *testing.T) {
+	if h.config.Interrupt == nil {
+		t.Skip("no Interrupt provided")
+	}
+
+	client, channel := h.connect(t)
+	ctx := context.Background()
+
+	if err := within(t, "Publish", func() error { return client.Publish(ctx, channel, []byte(`{}`)) }); err != nil {
+		t.Fatalf("publish before interrupt: %v", err)
+	}
+
+	h.config.Interrupt(t, client)
+
+	// Publishes during the interruption may fail but must not block.
+	_ = within(t, "Publish during interrupt", func() error { return client.Publish(ctx, channel, []byte(`{}`)) })
+
+	deadline := time.Now().Add(operationTimeout)
+
+	var err error
+
+	for time.Now().Before(deadline) {
+		payload := []byte(`{"reconnected":true}`)
+
+		if err = within(t, "Publish", func() error { return client.Publish(ctx, channel, payload) }); err == nil {
+			return
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	t.Errorf("publish did not recover within %s of the connection being interrupted: %v", operationTimeout, err)
+}
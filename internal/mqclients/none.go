@@ -0,0 +1,74 @@
+package mqclients
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	Register("none", Capabilities{
+		SupportsFlush: true,
+	})
+}
+
+// NoneMQClient discards every payload. It is used when sandwich is embedded
+// and payloads are only received by event hooks.
+type NoneMQClient struct {
+	channel string
+	cluster string
+
+	// Publishes are tracked so none are accepted once closed, the same as
+	// drivers which send them somewhere.
+	pending inflight
+}
+
+func (noneMQ *NoneMQClient) String() string {
+	return "none"
+}
+
+func (noneMQ *NoneMQClient) Channel() string {
+	return noneMQ.channel
+}
+
+func (noneMQ *NoneMQClient) Cluster() string {
+	return noneMQ.cluster
+}
+
+func (noneMQ *NoneMQClient) Connect(ctx context.Context, clientName string, args map[string]interface{}) (err error) {
+	if channel, ok := GetEntry(args, "Channel").(string); ok {
+		noneMQ.channel = channel
+	}
+
+	if cluster, ok := GetEntry(args, "Cluster").(string); ok {
+		noneMQ.cluster = cluster
+	}
+
+	return nil
+}
+
+func (noneMQ *NoneMQClient) Publish(ctx context.Context, channelName string, data []byte) (err error) {
+	if !noneMQ.pending.start() {
+		return xerrors.Errorf("noneMQ publish: %w", ErrClosed)
+	}
+
+	noneMQ.pending.finish()
+
+	return nil
+}
+
+func (noneMQ *NoneMQClient) Flush(ctx context.Context) (err error) {
+	err = noneMQ.pending.wait(ctx)
+	if err != nil {
+		return xerrors.Errorf("noneMQ flush: %w", err)
+	}
+
+	return nil
+}
+
+// Close stops accepting publishes and waits for the ones in flight.
+func (noneMQ *NoneMQClient) Close(ctx context.Context) (err error) {
+	noneMQ.pending.stop()
+
+	return noneMQ.Flush(ctx)
+}
@@ -0,0 +1,19 @@
+package mqclients_test
+
+import (
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients/mqclienttest"
+)
+
+// TestNone needs no broker so it always runs.
+func TestNone(t *testing.T) {
+	mqclienttest.Run(t, mqclienttest.Config{
+		Name: "none",
+		New:  func() mqclienttest.Client { return &mqclients.NoneMQClient{} },
+		Args: map[string]interface{}{
+			"Channel": "sandwich-mqclienttest",
+		},
+	})
+}
@@ -3,13 +3,19 @@ package mqclients
 import (
 	"context"
 	"strconv"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/vmihailenco/msgpack"
 	"golang.org/x/xerrors"
 )
 
 func init() {
-	MQClients = append(MQClients, "redis")
+	Register("redis", Capabilities{
+		SupportsSubscribe: true,
+		SupportsRequests:  true,
+		MaxMessageSize:    512 * 1024 * 1024, // Largest string value in redis
+	})
 }
 
 type RedisMQClient struct {
@@ -76,3 +82,102 @@ func (redisMQ *RedisMQClient) Publish(ctx context.Context, channelName string, d
 		data,
 	).Err()
 }
+
+// Subscribe calls handler with each message published to the channel until
+// the context is done.
+func (redisMQ *RedisMQClient) Subscribe(ctx context.Context, channelName string, handler func(data []byte)) (err error) {
+	pubsub := redisMQ.redisClient.Subscribe(ctx, channelName)
+
+	if _, err = pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+
+		return xerrors.Errorf("redisMQ subscribe: %w", err)
+	}
+
+	messages := pubsub.Channel()
+
+	go func() {
+		defer pubsub.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				handler([]byte(message.Payload))
+			}
+		}
+	}()
+
+	return nil
+}
+
+// RedisRequest is a request pushed onto the list of a channel. The reply is
+// pushed onto the Reply list which expires after redisReplyExpiry.
+type RedisRequest struct {
+	Reply string `msgpack:"reply"`
+	Data  []byte `msgpack:"data"`
+}
+
+const (
+	// How long a reply list is kept if the requester never reads it.
+	redisReplyExpiry = time.Minute
+
+	// How long BLPOP blocks before the context is checked again.
+	redisRequestPoll = time.Second
+)
+
+// Respond pops requests from the list named after the channel and pushes
+// what handler returns onto the reply list of each request until the context
+// is done.
+func (redisMQ *RedisMQClient) Respond(ctx context.Context, channelName string, handler func(data []byte) []byte) (err error) {
+	go func() {
+		for ctx.Err() == nil {
+			result, err := redisMQ.redisClient.BLPop(ctx, redisRequestPoll, channelName).Result()
+			if err != nil {
+				if !xerrors.Is(err, redis.Nil) && ctx.Err() == nil {
+					time.Sleep(redisRequestPoll)
+				}
+
+				continue
+			}
+
+			// The first value is the name of the list.
+			request := RedisRequest{}
+			if err = msgpack.Unmarshal([]byte(result[1]), &request); err != nil || request.Reply == "" {
+				continue
+			}
+
+			reply := handler(request.Data)
+
+			pipe := redisMQ.redisClient.TxPipeline()
+			pipe.RPush(ctx, request.Reply, reply)
+			pipe.Expire(ctx, request.Reply, redisReplyExpiry)
+			_, _ = pipe.Exec(ctx)
+		}
+	}()
+
+	return nil
+}
+
+// Flush returns immediately as redis publishes are synchronous.
+func (redisMQ *RedisMQClient) Flush(ctx context.Context) (err error) {
+	return nil
+}
+
+func (redisMQ *RedisMQClient) Close(ctx context.Context) (err error) {
+	if redisMQ.redisClient == nil {
+		return nil
+	}
+
+	err = redisMQ.redisClient.Close()
+	if err != nil {
+		return xerrors.Errorf("redisMQ close: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package mqclients_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients/mqclienttest"
+)
+
+// TestRedis publishes to the redis server at SANDWICH_TEST_REDIS_ADDRESS,
+// such as 127.0.0.1:6379, using SANDWICH_TEST_REDIS_PASSWORD if it needs
+// one.
+func TestRedis(t *testing.T) {
+	address := os.Getenv("SANDWICH_TEST_REDIS_ADDRESS")
+	if address == "" {
+		t.Skip("SANDWICH_TEST_REDIS_ADDRESS is not set")
+	}
+
+	mqclienttest.Run(t, mqclienttest.Config{
+		Name:    "redis",
+		New:     func() mqclienttest.Client { return &mqclients.RedisMQClient{} },
+		Channel: "sandwich-mqclienttest",
+		Args: map[string]interface{}{
+			"Address":  address,
+			"Password": os.Getenv("SANDWICH_TEST_REDIS_PASSWORD"),
+			"DB":       "0",
+		},
+	})
+}
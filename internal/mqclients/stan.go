@@ -10,7 +10,12 @@ import (
 )
 
 func init() {
-	MQClients = append(MQClients, "stan")
+	Register("stan", Capabilities{
+		SupportsFlush:     true,
+		SupportsSubscribe: true,
+		SupportsRequests:  true,
+		MaxMessageSize:    1024 * 1024, // Default max_payload of nats
+	})
 }
 
 type StanMQClient struct {
@@ -19,6 +24,9 @@ type StanMQClient struct {
 
 	async bool
 
+	// Publishes which have not been acknowledged.
+	pending inflight
+
 	channel string
 	cluster string
 }
@@ -101,18 +109,115 @@ func (stanMQ *StanMQClient) Connect(ctx context.Context, clientName string, args
 }
 
 func (stanMQ *StanMQClient) Publish(ctx context.Context, channelName string, data []byte) (err error) {
+	if !stanMQ.pending.start() {
+		return xerrors.Errorf("stanMQ publish: %w", ErrClosed)
+	}
+
 	if stanMQ.async {
 		_, err = stanMQ.StanClient.PublishAsync(
 			channelName,
 			data,
-			nil,
+			func(_ string, _ error) {
+				stanMQ.pending.finish()
+			},
 		)
+		if err != nil {
+			stanMQ.pending.finish()
+		}
 
 		return
 	}
 
+	defer stanMQ.pending.finish()
+
 	return stanMQ.StanClient.Publish(
 		channelName,
 		data,
 	)
 }
+
+// Subscribe calls handler with each message published to the channel after
+// subscribing until the context is done.
+func (stanMQ *StanMQClient) Subscribe(ctx context.Context, channelName string, handler func(data []byte)) (err error) {
+	subscription, err := stanMQ.StanClient.Subscribe(channelName, func(message *stan.Msg) {
+		handler(message.Data)
+	})
+	if err != nil {
+		return xerrors.Errorf("stanMQ subscribe: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = subscription.Close()
+	}()
+
+	return nil
+}
+
+// Respond replies to requests sent to the channel over core NATS with what
+// handler returns until the context is done. Requests without a reply
+// subject are ignored.
+func (stanMQ *StanMQClient) Respond(ctx context.Context, channelName string, handler func(data []byte) []byte) (err error) {
+	subscription, err := stanMQ.StanClient.NatsConn().Subscribe(channelName, func(message *nats.Msg) {
+		if message.Reply == "" {
+			return
+		}
+
+		_ = message.Respond(handler(message.Data))
+	})
+	if err != nil {
+		return xerrors.Errorf("stanMQ respond: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = subscription.Unsubscribe()
+	}()
+
+	return nil
+}
+
+func (stanMQ *StanMQClient) Flush(ctx context.Context) (err error) {
+	err = stanMQ.pending.wait(ctx)
+	if err != nil {
+		return xerrors.Errorf("stanMQ flush: %w", err)
+	}
+
+	if stanMQ.NatsClient != nil {
+		err = stanMQ.NatsClient.FlushWithContext(ctx)
+		if err != nil {
+			return xerrors.Errorf("stanMQ flush nats: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close stops accepting publishes and waits for the ones in flight to be
+// acknowledged before closing the connection.
+func (stanMQ *StanMQClient) Close(ctx context.Context) (err error) {
+	stanMQ.pending.stop()
+
+	flushErr := stanMQ.Flush(ctx)
+
+	// Closing the stan connection is required to release the client ID
+	// otherwise reconnecting with the same name will be rejected.
+	if stanMQ.StanClient != nil {
+		err = stanMQ.StanClient.Close()
+		if err != nil {
+			err = xerrors.Errorf("stanMQ close: %w", err)
+		}
+	}
+
+	if stanMQ.NatsClient != nil {
+		stanMQ.NatsClient.Close()
+	}
+
+	if flushErr != nil {
+		return flushErr
+	}
+
+	return err
+}
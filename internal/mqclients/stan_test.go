@@ -0,0 +1,35 @@
+package mqclients_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients/mqclienttest"
+)
+
+// TestStan runs against the NATS streaming server at
+// SANDWICH_TEST_STAN_ADDRESS, such as nats://127.0.0.1:4222. The cluster
+// defaults to test-cluster, the cluster ID nats-streaming-server starts
+// with.
+func TestStan(t *testing.T) {
+	address := os.Getenv("SANDWICH_TEST_STAN_ADDRESS")
+	if address == "" {
+		t.Skip("SANDWICH_TEST_STAN_ADDRESS is not set")
+	}
+
+	cluster := os.Getenv("SANDWICH_TEST_STAN_CLUSTER")
+	if cluster == "" {
+		cluster = "test-cluster"
+	}
+
+	mqclienttest.Run(t, mqclienttest.Config{
+		Name: "stan",
+		New:  func() mqclienttest.Client { return &mqclients.StanMQClient{} },
+		Args: map[string]interface{}{
+			"Address": address,
+			"Cluster": cluster,
+			"Channel": "sandwich-mqclienttest",
+		},
+	})
+}
@@ -5,6 +5,27 @@ import "strings"
 // MQClients lists all current mqclients we have available.
 var MQClients = []string{}
 
+// MQCapabilities contains the capabilities of each mqclient by name.
+var MQCapabilities = map[string]Capabilities{}
+
+// Capabilities describes which producer features an mqclient supports.
+type Capabilities struct {
+	SupportsFlush     bool `json:"supports_flush"`     // Flush waits for outstanding publishes
+	SupportsBatch     bool `json:"supports_batch"`     // Messages are sent in batches
+	SupportsLagProbe  bool `json:"supports_lag_probe"` // Consumer lag can be queried
+	SupportsDedup     bool `json:"supports_dedup"`     // Messages can carry an ID the broker deduplicates on
+	SupportsSubscribe bool `json:"supports_subscribe"` // Messages can be received from consumers
+	SupportsHeaders   bool `json:"supports_headers"`   // Messages can carry headers
+	SupportsRequests  bool `json:"supports_requests"`  // Requests from consumers can be replied to
+	MaxMessageSize    int  `json:"max_message_size"`   // Bytes. 0 if there is no limit
+}
+
+// Register adds an mqclient and its capabilities to the available mqclients.
+func Register(name string, capabilities Capabilities) {
+	MQClients = append(MQClients, name)
+	MQCapabilities[name] = capabilities
+}
+
 // Returns first match from a map and handles keys as non case sensitive.
 func GetEntry(m map[string]interface{}, key string) interface{} {
 	key = strings.ToLower(key)
@@ -16,3 +37,10 @@ func GetEntry(m map[string]interface{}, key string) interface{} {
 
 	return nil
 }
+
+// Message holds the properties of a published message which only some
+// mqclients support. Others publish just the data.
+type Message struct {
+	Key     []byte            // Messages with the same key keep their order
+	Headers map[string]string // Sent with the message if SupportsHeaders
+}
@@ -0,0 +1,222 @@
+package gateway
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Mutual guilds returned at once when no limit is given.
+	defaultMutualGuilds = 100
+
+	// Most mutual guilds returned at once.
+	maxMutualGuilds = 1000
+)
+
+// ErrMutualsDisabled is returned when looking up mutual guilds on a manager
+// without caching.store_mutuals.
+var ErrMutualsDisabled = xerrors.New("caching.store_mutuals is not enabled for this manager")
+
+// mutualIndex holds the IDs of the guilds each user seen by a manager is in.
+// Only IDs are kept so it is not limited by the member limits of the daemon.
+// There is no limit on its size, it holds an entry for every member seen in
+// every guild of the manager. Its size is reported in the manager analytics.
+type mutualIndex struct {
+	mu      sync.RWMutex
+	guilds  map[snowflake.ID]map[snowflake.ID]void // Guild IDs by user ID
+	users   map[snowflake.ID]map[snowflake.ID]void // User IDs by guild ID
+	entries int64
+}
+
+func newMutualIndex() *mutualIndex {
+	return &mutualIndex{
+		mu:     sync.RWMutex{},
+		guilds: make(map[snowflake.ID]map[snowflake.ID]void),
+		users:  make(map[snowflake.ID]map[snowflake.ID]void),
+	}
+}
+
+func (mi *mutualIndex) add(guildID snowflake.ID, userIDs []snowflake.ID) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	users, ok := mi.users[guildID]
+	if !ok {
+		users = make(map[snowflake.ID]void)
+		mi.users[guildID] = users
+	}
+
+	for _, userID := range userIDs {
+		guilds, ok := mi.guilds[userID]
+		if !ok {
+			guilds = make(map[snowflake.ID]void)
+			mi.guilds[userID] = guilds
+		}
+
+		if _, ok := guilds[guildID]; !ok {
+			guilds[guildID] = void{}
+			users[userID] = void{}
+			mi.entries++
+		}
+	}
+}
+
+func (mi *mutualIndex) remove(guildID snowflake.ID, userID snowflake.ID) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	guilds, ok := mi.guilds[userID]
+	if !ok {
+		return
+	}
+
+	if _, ok := guilds[guildID]; !ok {
+		return
+	}
+
+	delete(guilds, guildID)
+	mi.entries--
+
+	if len(guilds) == 0 {
+		delete(mi.guilds, userID)
+	}
+
+	if users, ok := mi.users[guildID]; ok {
+		delete(users, userID)
+
+		if len(users) == 0 {
+			delete(mi.users, guildID)
+		}
+	}
+}
+
+// removeGuild removes a guild from the users which are in it.
+func (mi *mutualIndex) removeGuild(guildID snowflake.ID) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	for userID := range mi.users[guildID] {
+		guilds := mi.guilds[userID]
+		delete(guilds, guildID)
+		mi.entries--
+
+		if len(guilds) == 0 {
+			delete(mi.guilds, userID)
+		}
+	}
+
+	delete(mi.users, guildID)
+}
+
+// API returns the size of the index.
+func (mi *mutualIndex) API() structs.MutualIndexStats {
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+
+	return structs.MutualIndexStats{
+		Users:   len(mi.guilds),
+		Guilds:  len(mi.users),
+		Entries: mi.entries,
+	}
+}
+
+func (mi *mutualIndex) reset() {
+	mi.mu.Lock()
+	mi.guilds = make(map[snowflake.ID]map[snowflake.ID]void)
+	mi.users = make(map[snowflake.ID]map[snowflake.ID]void)
+	mi.entries = 0
+	mi.mu.Unlock()
+}
+
+// page returns up to limit guilds of a user in order of their ID after the
+// given guild. next is set if there are more.
+func (mi *mutualIndex) page(userID snowflake.ID, limit int,
+	after snowflake.ID) (guildIDs []snowflake.ID, next snowflake.ID) {
+	mi.mu.RLock()
+	guildIDs = make([]snowflake.ID, 0, len(mi.guilds[userID]))
+	for guildID := range mi.guilds[userID] {
+		if guildID > after {
+			guildIDs = append(guildIDs, guildID)
+		}
+	}
+	mi.mu.RUnlock()
+
+	sort.Slice(guildIDs, func(i, j int) bool { return guildIDs[i] < guildIDs[j] })
+
+	if len(guildIDs) > limit {
+		guildIDs = guildIDs[:limit]
+		next = guildIDs[limit-1]
+	}
+
+	return guildIDs, next
+}
+
+// storesMutuals returns if caching.store_mutuals is enabled.
+func (mg *Manager) storesMutuals() (enabled bool) {
+	mg.ConfigurationMu.RLock()
+	enabled = mg.Configuration.Caching.StoreMutuals
+	mg.ConfigurationMu.RUnlock()
+
+	return enabled
+}
+
+// storeMutuals adds the members of a guild to the mutuals of the manager if
+// caching.store_mutuals is enabled.
+func (ctx *StateCtx) storeMutuals(guildID snowflake.ID, members []*discord.GuildMember) {
+	if ctx.Mg == nil || len(members) == 0 || !ctx.Mg.storesMutuals() {
+		return
+	}
+
+	userIDs := make([]snowflake.ID, 0, len(members))
+
+	for _, member := range members {
+		if member.User != nil {
+			userIDs = append(userIDs, member.User.ID)
+		}
+	}
+
+	ctx.Mg.mutuals.add(guildID, userIDs)
+}
+
+// MutualGuilds returns up to limit guilds a user shares with the manager in
+// order of their ID after the given guild. If expand is set, the name and
+// icon of each guild in state is included.
+func (mg *Manager) MutualGuilds(userID snowflake.ID, limit int, after snowflake.ID,
+	expand bool) (result structs.APIMutualGuilds, err error) {
+	if !mg.storesMutuals() {
+		return result, ErrMutualsDisabled
+	}
+
+	if limit < 1 {
+		limit = defaultMutualGuilds
+	} else if limit > maxMutualGuilds {
+		limit = maxMutualGuilds
+	}
+
+	result.GuildIDs, result.Next = mg.mutuals.page(userID, limit, after)
+
+	if !expand {
+		return result, nil
+	}
+
+	ctx := &StateCtx{Sg: mg.Sandwich, Mg: mg}
+	result.Guilds = make([]*structs.MutualGuild, 0, len(result.GuildIDs))
+
+	for _, guildID := range result.GuildIDs {
+		guild := &structs.MutualGuild{ID: guildID}
+
+		if g, ok := mg.Sandwich.State.GetGuild(ctx, guildID, false); ok {
+			guild.Name = g.Name
+			guild.Icon = g.Icon
+		}
+
+		result.Guilds = append(result.Guilds, guild)
+	}
+
+	return result, nil
+}
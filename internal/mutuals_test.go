@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+func TestMutualIndexRemoveGuild(t *testing.T) {
+	mi := newMutualIndex()
+
+	mi.add(1, []snowflake.ID{10, 11})
+	mi.add(2, []snowflake.ID{10, 12})
+	mi.add(2, []snowflake.ID{10})
+
+	if got, want := mi.API(), (structs.MutualIndexStats{Users: 3, Guilds: 2, Entries: 4}); got != want {
+		t.Errorf("stats were %+v, want %+v", got, want)
+	}
+
+	mi.removeGuild(2)
+
+	if guildIDs, _ := mi.page(10, defaultMutualGuilds, 0); !reflect.DeepEqual(guildIDs, []snowflake.ID{1}) {
+		t.Errorf("user 10 was in %v", guildIDs)
+	}
+
+	if guildIDs, _ := mi.page(12, defaultMutualGuilds, 0); len(guildIDs) != 0 {
+		t.Errorf("user 12 was in %v", guildIDs)
+	}
+
+	if got, want := mi.API(), (structs.MutualIndexStats{Users: 2, Guilds: 1, Entries: 2}); got != want {
+		t.Errorf("stats were %+v, want %+v", got, want)
+	}
+
+	mi.remove(1, 10)
+	mi.remove(1, 10)
+	mi.remove(1, 11)
+
+	if got := mi.API(); got != (structs.MutualIndexStats{}) {
+		t.Errorf("stats were %+v once empty", got)
+	}
+}
+
+func TestMutualIndexPage(t *testing.T) {
+	mi := newMutualIndex()
+
+	for _, guildID := range []snowflake.ID{5, 3, 1, 4, 2} {
+		mi.add(guildID, []snowflake.ID{10})
+	}
+
+	guildIDs, next := mi.page(10, 2, 0)
+	if !reflect.DeepEqual(guildIDs, []snowflake.ID{1, 2}) || next != 2 {
+		t.Errorf("first page was %v next %d", guildIDs, next)
+	}
+
+	guildIDs, next = mi.page(10, 2, 4)
+	if !reflect.DeepEqual(guildIDs, []snowflake.ID{5}) || next != 0 {
+		t.Errorf("last page was %v next %d", guildIDs, next)
+	}
+}
@@ -0,0 +1,248 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+// NotificationSeverity is how serious a notification is. It decides the
+// colour of the embed unless one is set.
+type NotificationSeverity int
+
+const (
+	SeverityInfo NotificationSeverity = iota
+	SeverityWarning
+	SeverityDanger
+)
+
+// Colour returns the embed colour of the severity.
+func (ns NotificationSeverity) Colour() int {
+	switch ns {
+	case SeverityWarning:
+		return discord.EmbedWarning
+	case SeverityDanger:
+		return discord.EmbedDanger
+	default:
+		return discord.EmbedSandwich
+	}
+}
+
+// Notification builds the webhook message of an event. The manager,
+// shardgroup and shard it is about are shown in the footer of embeds or as a
+// prefix of raw messages, and the user decides the name and avatar the
+// message is sent with. Shard notifications use the bot user if no user is
+// set.
+type Notification struct {
+	Title       string
+	Description string
+	URL         string
+	Severity    NotificationSeverity
+	Colour      int  // Used instead of the severity colour if set
+	Raw         bool // Sent as plain content instead of an embed
+	Timestamp   time.Time
+
+	manager    *Manager
+	shardGroup *ShardGroup
+	shard      *Shard
+	extra      string // Appended to the footer
+
+	username  string
+	avatarURL string
+}
+
+// NewNotification creates an informational notification.
+func NewNotification(title string, description string) *Notification {
+	return &Notification{
+		Title:       title,
+		Description: description,
+		Severity:    SeverityInfo,
+	}
+}
+
+// ForManager shows the notification is about a manager.
+func (n *Notification) ForManager(mg *Manager) *Notification {
+	n.manager = mg
+
+	return n
+}
+
+// ForShardGroup shows the notification is about a shardgroup and its manager.
+func (n *Notification) ForShardGroup(sg *ShardGroup) *Notification {
+	n.manager = sg.Manager
+	n.shardGroup = sg
+
+	return n
+}
+
+// ForShard shows the notification is about a shard, its shardgroup and
+// manager.
+func (n *Notification) ForShard(sh *Shard) *Notification {
+	n.manager = sh.Manager
+	n.shardGroup = sh.ShardGroup
+	n.shard = sh
+
+	return n
+}
+
+// WithFooter adds text to the end of the footer.
+func (n *Notification) WithFooter(text string) *Notification {
+	n.extra = text
+
+	return n
+}
+
+// ByUser sends the notification as the user which caused it.
+func (n *Notification) ByUser(user *structs.DiscordUser) *Notification {
+	if user != nil {
+		n.username = user.Username
+		n.avatarURL = avatarURL(user.ID, user.Avatar)
+	}
+
+	return n
+}
+
+// WithSeverity sets the severity of the notification.
+func (n *Notification) WithSeverity(severity NotificationSeverity) *Notification {
+	n.Severity = severity
+
+	return n
+}
+
+// WithColour sets the embed colour, ignoring the severity.
+func (n *Notification) WithColour(colour int) *Notification {
+	n.Colour = colour
+
+	return n
+}
+
+// WithStatusColour sets the embed colour to the colour of a shard status.
+func (n *Notification) WithStatusColour(status structs.ShardStatus) *Notification {
+	n.Colour = status.Colour()
+
+	return n
+}
+
+// WithURL links the title of the embed.
+func (n *Notification) WithURL(url string) *Notification {
+	n.URL = url
+
+	return n
+}
+
+// AsRaw sends the notification as plain content when raw is true.
+func (n *Notification) AsRaw(raw bool) *Notification {
+	n.Raw = raw
+
+	return n
+}
+
+// At sets the time shown on the embed. The current time is used otherwise.
+func (n *Notification) At(timestamp time.Time) *Notification {
+	n.Timestamp = timestamp
+
+	return n
+}
+
+func avatarURL(id snowflake.ID, avatar string) string {
+	return fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png", id.String(), avatar)
+}
+
+// colour returns the embed colour of the notification.
+func (n *Notification) colour() int {
+	if n.Colour != 0 {
+		return n.Colour
+	}
+
+	return n.Severity.Colour()
+}
+
+// footer describes what the notification is about, such as
+// "Manager Welcomer | ShardGroup 1 | Shard 4".
+func (n *Notification) footer() string {
+	parts := make([]string, 0, 3)
+
+	if n.manager != nil {
+		parts = append(parts, "Manager "+n.manager.displayName())
+	}
+
+	if n.shardGroup != nil {
+		parts = append(parts, fmt.Sprintf("ShardGroup %d", n.shardGroup.ID))
+	}
+
+	if n.shard != nil {
+		parts = append(parts, fmt.Sprintf("Shard %d", n.shard.ShardID))
+	}
+
+	if n.extra != "" {
+		parts = append(parts, n.extra)
+	}
+
+	return strings.Join(parts, " | ")
+}
+
+// prefix is the short form of the footer used by raw messages, such as
+// "[**Welcomer - 1/4**]".
+func (n *Notification) prefix() string {
+	if n.manager == nil {
+		return ""
+	}
+
+	switch {
+	case n.shard != nil:
+		return fmt.Sprintf("[**%s - %d/%d**] ", n.manager.displayName(), n.shardGroup.ID, n.shard.ShardID)
+	case n.shardGroup != nil:
+		return fmt.Sprintf("[**%s - %d**] ", n.manager.displayName(), n.shardGroup.ID)
+	default:
+		return fmt.Sprintf("[**%s**] ", n.manager.displayName())
+	}
+}
+
+// Message builds the webhook message of the notification.
+func (n *Notification) Message() (message discord.WebhookMessage) {
+	if n.Raw {
+		message.Content = strings.TrimSpace(n.prefix() + n.Title + " " + n.Description)
+	} else {
+		timestamp := n.Timestamp
+		if timestamp.IsZero() {
+			timestamp = time.Now().UTC()
+		}
+
+		embed := discord.Embed{
+			Title:       n.Title,
+			Description: n.Description,
+			URL:         n.URL,
+			Color:       n.colour(),
+			Timestamp:   WebhookTime(timestamp),
+		}
+
+		if footer := n.footer(); footer != "" {
+			embed.Footer = &discord.EmbedFooter{Text: footer}
+		}
+
+		message.Embeds = []discord.Embed{embed}
+	}
+
+	message.Username, message.AvatarURL = n.username, n.avatarURL
+
+	if message.Username == "" && n.shard != nil {
+		n.shard.RLock()
+		if n.shard.User != nil {
+			message.Username = n.shard.User.Username
+			message.AvatarURL = avatarURL(n.shard.User.ID, n.shard.User.Avatar)
+		}
+		n.shard.RUnlock()
+	}
+
+	return message
+}
+
+// Notify sends a notification to all added webhooks.
+func (sg *Sandwich) Notify(ctx context.Context, n *Notification) {
+	sg.PublishWebhook(ctx, n.Message())
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// checkGolden compares the indented JSON of v to testdata/<name>.json. The
+// file is rewritten instead when run with -update.
+func checkGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("%s: failed to marshal: %v", name, err)
+	}
+
+	got = append(got, '\n')
+	path := filepath.Join("testdata", name+".json")
+
+	if *updateGolden {
+		if err = ioutil.WriteFile(path, got, 0o600); err != nil {
+			t.Fatalf("%s: failed to update: %v", name, err)
+		}
+
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s: failed to read golden file: %v", name, err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s: message changed\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestNotificationMessage(t *testing.T) {
+	sh := newTestShard(t)
+	sh.Manager.Configuration.DisplayName = "Welcomer"
+	sh.ShardGroup.ID = 1
+	sh.ShardID = 4
+	sh.User = &discord.User{ID: 330416853971107840, Username: "Welcomer", Avatar: "bot"}
+
+	user := &structs.DiscordUser{ID: 143090142360371200, Username: "Rock", Avatar: "user"}
+	at := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		notification *Notification
+	}{
+		{
+			"shard_embed",
+			NewNotification("Shard is now **READY**", "").
+				ForShard(sh).WithStatusColour(structs.ShardReady).At(at),
+		},
+		{
+			"shard_raw",
+			NewNotification("Shard is now **READY**", "").
+				ForShard(sh).WithStatusColour(structs.ShardReady).AsRaw(true),
+		},
+		{
+			"shardgroup_embed",
+			NewNotification("ShardGroup closed", "Closed after an error").
+				ForShardGroup(sh.ShardGroup).WithSeverity(SeverityDanger).WithFooter("Error").At(at),
+		},
+		{
+			"shardgroup_raw",
+			NewNotification("ShardGroup closed", "Closed after an error").
+				ForShardGroup(sh.ShardGroup).WithSeverity(SeverityDanger).AsRaw(true),
+		},
+		{
+			"manager_embed",
+			NewNotification("Manager restarted", "Restarted through RPC").
+				ForManager(sh.Manager).ByUser(user).WithSeverity(SeverityWarning).
+				WithURL("https://sandwich.example.com").At(at),
+		},
+		{
+			"manager_raw",
+			NewNotification("Manager restarted", "Restarted through RPC").
+				ForManager(sh.Manager).ByUser(user).AsRaw(true),
+		},
+		{
+			"daemon_embed",
+			NewNotification("Daemon updated", "").ByUser(user).At(at),
+		},
+	}
+
+	for _, test := range tests {
+		checkGolden(t, filepath.Join("notification", test.name), test.notification.Message())
+	}
+}
+
+func TestNotificationDefaultTimestamp(t *testing.T) {
+	before := time.Now().UTC().Truncate(time.Second)
+
+	message := NewNotification("title", "description").Message()
+
+	timestamp, err := time.Parse(time.RFC3339, message.Embeds[0].Timestamp)
+	if err != nil {
+		t.Fatalf("failed to parse timestamp %q: %v", message.Embeds[0].Timestamp, err)
+	}
+
+	if timestamp.Before(before) || timestamp.After(time.Now().UTC()) {
+		t.Errorf("timestamp was %s, want the current time", timestamp)
+	}
+}
@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+// Window that reconnect requests and invalid sessions are counted over.
+const opcodeRateWindow = time.Hour
+
+// opcodeCounters counts the packets a shard has received from the gateway
+// by their opcode.
+type opcodeCounters struct {
+	dispatch       *int64
+	heartbeat      *int64
+	heartbeatACK   *int64
+	reconnect      *int64
+	invalidSession *int64
+	hello          *int64
+	other          *int64
+
+	// Times of reconnect requests and invalid sessions within the last
+	// opcodeRateWindow. These are rare so are kept individually.
+	recentMu              sync.Mutex
+	recentReconnects      []time.Time
+	recentInvalidSessions []time.Time
+}
+
+func newOpcodeCounters() *opcodeCounters {
+	return &opcodeCounters{
+		dispatch:       new(int64),
+		heartbeat:      new(int64),
+		heartbeatACK:   new(int64),
+		reconnect:      new(int64),
+		invalidSession: new(int64),
+		hello:          new(int64),
+		other:          new(int64),
+
+		recentMu:              sync.Mutex{},
+		recentReconnects:      make([]time.Time, 0),
+		recentInvalidSessions: make([]time.Time, 0),
+	}
+}
+
+// Record counts a packet received with the opcode provided.
+func (oc *opcodeCounters) Record(op discord.GatewayOp, now time.Time) {
+	switch op {
+	case discord.GatewayOpDispatch:
+		atomic.AddInt64(oc.dispatch, 1)
+	case discord.GatewayOpHeartbeat:
+		atomic.AddInt64(oc.heartbeat, 1)
+	case discord.GatewayOpHeartbeatACK:
+		atomic.AddInt64(oc.heartbeatACK, 1)
+	case discord.GatewayOpHello:
+		atomic.AddInt64(oc.hello, 1)
+	case discord.GatewayOpReconnect:
+		atomic.AddInt64(oc.reconnect, 1)
+
+		oc.recentMu.Lock()
+		oc.recentReconnects = append(pruneTimes(oc.recentReconnects, now), now)
+		oc.recentMu.Unlock()
+	case discord.GatewayOpInvalidSession:
+		atomic.AddInt64(oc.invalidSession, 1)
+
+		oc.recentMu.Lock()
+		oc.recentInvalidSessions = append(pruneTimes(oc.recentInvalidSessions, now), now)
+		oc.recentMu.Unlock()
+	default:
+		atomic.AddInt64(oc.other, 1)
+	}
+}
+
+// API returns the counters for the shard API response.
+func (oc *opcodeCounters) API(now time.Time) *structs.APIShardOpcodes {
+	oc.recentMu.Lock()
+	oc.recentReconnects = pruneTimes(oc.recentReconnects, now)
+	oc.recentInvalidSessions = pruneTimes(oc.recentInvalidSessions, now)
+	reconnects := len(oc.recentReconnects)
+	invalidSessions := len(oc.recentInvalidSessions)
+	oc.recentMu.Unlock()
+
+	return &structs.APIShardOpcodes{
+		Dispatch:       atomic.LoadInt64(oc.dispatch),
+		Heartbeat:      atomic.LoadInt64(oc.heartbeat),
+		HeartbeatACK:   atomic.LoadInt64(oc.heartbeatACK),
+		Reconnect:      atomic.LoadInt64(oc.reconnect),
+		InvalidSession: atomic.LoadInt64(oc.invalidSession),
+		Hello:          atomic.LoadInt64(oc.hello),
+		Other:          atomic.LoadInt64(oc.other),
+
+		ReconnectsLastHour:      int64(reconnects),
+		InvalidSessionsLastHour: int64(invalidSessions),
+	}
+}
+
+// pruneTimes removes times older than opcodeRateWindow. Times are in order
+// so everything before the first recent time can be dropped.
+func pruneTimes(times []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-opcodeRateWindow)
+
+	for i, t := range times {
+		if t.After(cutoff) {
+			return times[i:]
+		}
+	}
+
+	return times[:0]
+}
@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"sync/atomic"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+// Defaults used when bot.ordered_dispatch is enabled without a worker count
+// or queue depth.
+const (
+	defaultOrderedDispatchWorkers = 8
+	defaultOrderedDispatchQueue   = 128
+)
+
+// orderedDispatcher hands the dispatches of a shard to a fixed set of workers.
+// Dispatches of the same guild always go to the same worker so they are
+// handled in the order they were received.
+type orderedDispatcher struct {
+	queues []chan func()
+}
+
+// newOrderedDispatcher starts the workers of a shard. They run until the
+// shardgroup closes, after which they handle what is left in their queues
+// and stop.
+func (sh *Shard) newOrderedDispatcher(workers int, depth int) *orderedDispatcher {
+	od := &orderedDispatcher{
+		queues: make([]chan func(), workers),
+	}
+
+	for i := range od.queues {
+		queue := make(chan func(), depth)
+		od.queues[i] = queue
+
+		go sh.orderedDispatchWorker(queue)
+	}
+
+	return od
+}
+
+func (sh *Shard) orderedDispatchWorker(queue chan func()) {
+	for {
+		select {
+		case job := <-queue:
+			atomic.AddInt64(sh.Manager.dispatchQueued, -1)
+			job()
+		case <-sh.ShardGroup.close:
+			for {
+				select {
+				case job := <-queue:
+					atomic.AddInt64(sh.Manager.dispatchQueued, -1)
+					job()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// dispatcher returns the ordered dispatcher of the shard, starting it with
+// the current configuration if it is not running yet.
+func (sh *Shard) dispatcher() *orderedDispatcher {
+	sh.orderedDispatchMu.Lock()
+	defer sh.orderedDispatchMu.Unlock()
+
+	if sh.orderedDispatch == nil {
+		sh.Manager.ConfigurationMu.RLock()
+		workers := sh.Manager.Configuration.Bot.OrderedDispatchWorkers
+		depth := sh.Manager.Configuration.Bot.OrderedDispatchQueue
+		sh.Manager.ConfigurationMu.RUnlock()
+
+		sh.orderedDispatch = sh.newOrderedDispatcher(workers, depth)
+	}
+
+	return sh.orderedDispatch
+}
+
+// dispatch runs exec for a dispatch. When bot.ordered_dispatch is enabled it
+// is queued on the worker of the guild the dispatch belongs to, blocking the
+// reader whilst the queue is full. Otherwise it is ran in a new goroutine.
+func (sh *Shard) dispatch(msg discord.ReceivedPayload, exec func()) {
+	sh.Manager.ConfigurationMu.RLock()
+	ordered := sh.Manager.Configuration.Bot.OrderedDispatch
+	sh.Manager.ConfigurationMu.RUnlock()
+
+	if !ordered {
+		go exec()
+
+		return
+	}
+
+	select {
+	case <-sh.ShardGroup.close:
+		// The workers are stopping so nothing queued now would be handled.
+		go exec()
+
+		return
+	default:
+	}
+
+	od := sh.dispatcher()
+	guildID := eventGuildID(msg.Type, msg.Data)
+	queue := od.queues[uint64(guildID)%uint64(len(od.queues))]
+
+	atomic.AddInt64(sh.Manager.dispatchQueued, 1)
+
+	select {
+	case queue <- exec:
+		return
+	default:
+	}
+
+	atomic.AddInt64(sh.Manager.dispatchSaturated, 1)
+	sh.Logger.Debug().
+		Str("type", msg.Type).
+		Int64("guild_id", guildID.Int64()).
+		Msg("Ordered dispatch queue is full, waiting for the worker")
+
+	select {
+	case queue <- exec:
+	case <-sh.ShardGroup.close:
+		atomic.AddInt64(sh.Manager.dispatchQueued, -1)
+
+		go exec()
+	}
+}
+
+// DispatchQueue returns how many dispatches are waiting in the ordered
+// dispatch queues of the manager and how many times a queue was full.
+func (mg *Manager) DispatchQueue() structs.DispatchQueueStats {
+	return structs.DispatchQueueStats{
+		Queued:    atomic.LoadInt64(mg.dispatchQueued),
+		Saturated: atomic.LoadInt64(mg.dispatchSaturated),
+	}
+}
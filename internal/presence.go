@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+// ErrInvalidPresenceStatus is returned when a presence update has a status
+// discord does not accept.
+var ErrInvalidPresenceStatus = xerrors.New("presence status must be online, dnd, idle, invisible or offline")
+
+// validPresenceStatus returns if the status can be sent in a presence update.
+func validPresenceStatus(status string) bool {
+	switch status {
+	case "online", "dnd", "idle", "invisible", "offline":
+		return true
+	default:
+		return false
+	}
+}
+
+// presence returns the presence the shard identifies with. A presence set on
+// the shard is preferred over one set on the manager, which is preferred over
+// bot.presence.
+func (sh *Shard) presence(defaultPresence *discord.UpdateStatus) *discord.UpdateStatus {
+	sh.presenceMu.RLock()
+	presence := sh.presenceOverride
+	sh.presenceMu.RUnlock()
+
+	if presence != nil {
+		return presence
+	}
+
+	sh.Manager.presenceMu.RLock()
+	presence = sh.Manager.presenceOverride
+	sh.Manager.presenceMu.RUnlock()
+
+	if presence != nil {
+		return presence
+	}
+
+	return defaultPresence
+}
+
+// UpdatePresence sends a presence update to shards of the manager and stores
+// it so the shards identify with it when they reconnect. If no shardgroup is
+// provided the newest shardgroup is used and if no shards are provided every
+// shard of the shardgroup is updated. A presence sent to every shard of the
+// newest shardgroup is also used by shardgroups created afterwards.
+func (mg *Manager) UpdatePresence(shardGroupID *int32, shardIDs []int,
+	presence discord.UpdateStatus) (result structs.RPCManagerStatusUpdateResponse, err error) {
+	if !validPresenceStatus(presence.Status) {
+		return result, ErrInvalidPresenceStatus
+	}
+
+	var shardGroup *ShardGroup
+
+	if shardGroupID != nil {
+		mg.ShardGroupsMu.RLock()
+		shardGroup = mg.ShardGroups[*shardGroupID]
+		mg.ShardGroupsMu.RUnlock()
+	} else {
+		shardGroup = mg.latestShardGroup()
+	}
+
+	if shardGroup == nil {
+		return result, ErrNoShardGroup
+	}
+
+	shards := shardGroup.shardsSnapshot()
+
+	if len(shardIDs) == 0 {
+		shardIDs = make([]int, 0, len(shards))
+		for shardID := range shards {
+			shardIDs = append(shardIDs, shardID)
+		}
+
+		if shardGroupID == nil {
+			mg.presenceMu.Lock()
+			mg.presenceOverride = &presence
+			mg.presenceMu.Unlock()
+		}
+	}
+
+	sort.Ints(shardIDs)
+
+	result = structs.RPCManagerStatusUpdateResponse{
+		ShardGroup: shardGroup.ID,
+		Shards:     make([]structs.RPCShardResult, len(shardIDs)),
+	}
+
+	wg := sync.WaitGroup{}
+
+	for i, shardID := range shardIDs {
+		result.Shards[i].ShardID = shardID
+
+		sh, ok := shards[shardID]
+		if !ok {
+			result.Shards[i].Error = "Invalid shard provided"
+
+			continue
+		}
+
+		sh.presenceMu.Lock()
+		sh.presenceOverride = &presence
+		sh.presenceMu.Unlock()
+
+		wg.Add(1)
+
+		go func(shardResult *structs.RPCShardResult, sh *Shard) {
+			defer wg.Done()
+
+			if err := sh.SendEvent(discord.GatewayOpStatusUpdate, presence); err != nil {
+				shardResult.Error = err.Error()
+			} else {
+				shardResult.Success = true
+			}
+		}(&result.Shards[i], sh)
+	}
+
+	wg.Wait()
+
+	for _, shardResult := range result.Shards {
+		if shardResult.Success {
+			result.Updated++
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// startupPayload is a compressed payload waiting in a startupQueue.
+type startupPayload struct {
+	shard   *Shard
+	message mqclients.Message
+	data    []byte
+	queued  time.Time
+}
+
+// startupQueue holds the payloads produced by a ShardGroup whilst it is
+// starting up so structural events can be sent to consumers before the
+// member and presence events of guilds that were received earlier. Once the
+// ShardGroup is ready, the queue is drained and payloads are published in
+// order again.
+type startupQueue struct {
+	sg *ShardGroup
+
+	payloadsMu sync.Mutex
+	active     bool
+	finishing  bool
+	structural []startupPayload
+	other      []startupPayload
+
+	notify chan void
+
+	// Longest time in nanoseconds a non structural payload has waited.
+	maxDelay *int64
+}
+
+// isStructuralEvent returns if an event describes the structure of a guild
+// and should be sent first during startup.
+func isStructuralEvent(eventType string) bool {
+	switch eventType {
+	case "GUILD_CREATE", "GUILD_UPDATE", "GUILD_DELETE":
+		return true
+	}
+
+	return strings.HasPrefix(eventType, "CHANNEL_") || strings.HasPrefix(eventType, "GUILD_ROLE_")
+}
+
+func newStartupQueue(sg *ShardGroup) *startupQueue {
+	return &startupQueue{
+		sg: sg,
+
+		payloadsMu: sync.Mutex{},
+		structural: make([]startupPayload, 0),
+		other:      make([]startupPayload, 0),
+
+		notify: make(chan void, 1),
+
+		maxDelay: new(int64),
+	}
+}
+
+// Start starts prioritising payloads.
+func (sq *startupQueue) Start() {
+	sq.payloadsMu.Lock()
+	sq.active = true
+	sq.payloadsMu.Unlock()
+
+	go sq.run()
+}
+
+// Finish publishes any payloads still queued and then stops prioritising.
+// Payloads continue to be queued until the queue is empty so they are not
+// published ahead of ones that are already waiting.
+func (sq *startupQueue) Finish() {
+	sq.payloadsMu.Lock()
+	if !sq.active || sq.finishing {
+		sq.payloadsMu.Unlock()
+
+		return
+	}
+
+	sq.finishing = true
+	sq.payloadsMu.Unlock()
+
+	sq.wake()
+}
+
+// Enqueue queues a payload if the queue is active. If it is not, false is
+// returned and the payload should be published immediately. The data is
+// copied so it can be reused by the caller.
+func (sq *startupQueue) Enqueue(sh *Shard, eventType string, message mqclients.Message, data []byte) (queued bool) {
+	sq.payloadsMu.Lock()
+	defer sq.payloadsMu.Unlock()
+
+	if !sq.active {
+		return false
+	}
+
+	payload := startupPayload{
+		shard:   sh,
+		message: message,
+		data:    append(make([]byte, 0, len(data)), data...),
+		queued:  time.Now(),
+	}
+
+	if isStructuralEvent(eventType) {
+		sq.structural = append(sq.structural, payload)
+	} else {
+		sq.other = append(sq.other, payload)
+	}
+
+	sq.wake()
+
+	return true
+}
+
+func (sq *startupQueue) wake() {
+	select {
+	case sq.notify <- void{}:
+	default:
+	}
+}
+
+// next returns the next payload to publish. Structural payloads always go
+// first. If the queue is empty and finishing, the queue is deactivated.
+func (sq *startupQueue) next() (payload startupPayload, structural bool, ok bool, finished bool) {
+	sq.payloadsMu.Lock()
+	defer sq.payloadsMu.Unlock()
+
+	switch {
+	case len(sq.structural) > 0:
+		payload, sq.structural = sq.structural[0], sq.structural[1:]
+
+		return payload, true, true, false
+	case len(sq.other) > 0:
+		payload, sq.other = sq.other[0], sq.other[1:]
+
+		return payload, false, true, false
+	case sq.finishing:
+		sq.active = false
+
+		return payload, false, false, true
+	default:
+		return payload, false, false, false
+	}
+}
+
+func (sq *startupQueue) run() {
+	for {
+		payload, structural, ok, finished := sq.next()
+
+		if finished {
+			sq.sg.Logger.Info().
+				Dur("max_delay", time.Duration(atomic.LoadInt64(sq.maxDelay))).
+				Msg("Finished prioritising startup events")
+
+			return
+		}
+
+		if !ok {
+			select {
+			case <-sq.notify:
+			case <-sq.sg.close:
+				sq.Finish()
+			}
+
+			continue
+		}
+
+		if !structural {
+			sq.recordDelay(time.Since(payload.queued))
+		}
+
+		payload.shard.Manager.ConfigurationMu.RLock()
+		err := payload.shard.publish(payload.message, payload.data)
+		payload.shard.Manager.ConfigurationMu.RUnlock()
+
+		if err != nil {
+			payload.shard.Logger.Error().Err(err).Msg("Failed to publish queued startup event")
+		}
+	}
+}
+
+func (sq *startupQueue) recordDelay(delay time.Duration) {
+	for {
+		current := atomic.LoadInt64(sq.maxDelay)
+		if int64(delay) <= current || atomic.CompareAndSwapInt64(sq.maxDelay, current, int64(delay)) {
+			return
+		}
+	}
+}
+
+// API returns the state of the queue for the shardgroup API response.
+func (sq *startupQueue) API() *structs.APIStartupQueue {
+	sq.payloadsMu.Lock()
+	defer sq.payloadsMu.Unlock()
+
+	return &structs.APIStartupQueue{
+		Active:     sq.active,
+		Structural: len(sq.structural),
+		Other:      len(sq.other),
+		MaxDelay:   time.Duration(atomic.LoadInt64(sq.maxDelay)).Milliseconds(),
+	}
+}
@@ -0,0 +1,355 @@
+package gateway
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fasthttp/websocket"
+	"github.com/savsgio/gotils"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/oauth2"
+	"golang.org/x/xerrors"
+)
+
+// Origins allowed when http.allowed_origins is not set.
+var defaultAllowedOrigins = []string{"http://127.0.0.1:8080", "http://127.0.0.1:5469", "https://sandwich.welcomer.gg"}
+
+// parseBaseURL parses HTTP.BaseURL. The path always ends with a slash.
+func parseBaseURL(baseURL string) (base *url.URL, err error) {
+	base, err = url.Parse(strings.TrimSpace(baseURL))
+	if err != nil {
+		return nil, xerrors.Errorf("parse base url: %w", err)
+	}
+
+	if base.Scheme == "" || base.Host == "" {
+		return nil, xerrors.Errorf("parse base url: %q must include the scheme and host", baseURL)
+	}
+
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	return base, nil
+}
+
+// baseURL returns HTTP.BaseURL or nil if it is not set.
+func (sg *Sandwich) baseURL() *url.URL {
+	sg.ConfigurationMu.RLock()
+	baseURL := sg.Configuration.HTTP.BaseURL
+	sg.ConfigurationMu.RUnlock()
+
+	if baseURL == "" {
+		return nil
+	}
+
+	base, err := parseBaseURL(baseURL)
+	if err != nil {
+		return nil
+	}
+
+	return base
+}
+
+// basePath returns the path the dashboard is served under, such as
+// "/sandwich/". This is "/" when HTTP.BaseURL is not set.
+func (sg *Sandwich) basePath() string {
+	if base := sg.baseURL(); base != nil {
+		return base.Path
+	}
+
+	return "/"
+}
+
+// externalURL returns the URL the dashboard is reached through, ending with
+// a slash. HTTP.BaseURL is used if set, otherwise the X-Forwarded-Proto and
+// X-Forwarded-Host headers are used if HTTP.TrustProxyHeaders is enabled. If
+// neither are available, an empty string is returned.
+func (sg *Sandwich) externalURL(r *http.Request) string {
+	if base := sg.baseURL(); base != nil {
+		return base.String()
+	}
+
+	sg.ConfigurationMu.RLock()
+	trustProxyHeaders := sg.Configuration.HTTP.TrustProxyHeaders
+	sg.ConfigurationMu.RUnlock()
+
+	if !trustProxyHeaders {
+		return ""
+	}
+
+	host := firstHeaderValue(r.Header.Get("X-Forwarded-Host"))
+	if host == "" {
+		return ""
+	}
+
+	proto := firstHeaderValue(r.Header.Get("X-Forwarded-Proto"))
+	if proto == "" {
+		proto = "http"
+	}
+
+	return proto + "://" + host + "/"
+}
+
+// firstHeaderValue returns the first value of a comma separated header as
+// proxies append to them.
+func firstHeaderValue(value string) string {
+	if i := strings.IndexByte(value, ','); i >= 0 {
+		value = value[:i]
+	}
+
+	return strings.TrimSpace(value)
+}
+
+// redirect sends a redirect to a path relative to the base path.
+func (sg *Sandwich) redirect(rw http.ResponseWriter, r *http.Request, path string) {
+	http.Redirect(rw, r, sg.basePath()+strings.TrimPrefix(path, "/"), http.StatusTemporaryRedirect)
+}
+
+// oauthConfiguration returns the OAuth configuration with the redirect url
+// derived from the external URL when it is known.
+func (sg *Sandwich) oauthConfiguration(r *http.Request) *oauth2.Config {
+	sg.ConfigurationMu.RLock()
+	config := *sg.Configuration.OAuth
+	sg.ConfigurationMu.RUnlock()
+
+	if external := sg.externalURL(r); external != "" {
+		config.RedirectURL = external + "oauth2/callback"
+	}
+
+	return &config
+}
+
+// configureSessionStore scopes the session cookie to the base URL.
+func (sg *Sandwich) configureSessionStore() {
+	sg.Store.Options.Path = sg.basePath()
+
+	if base := sg.baseURL(); base != nil {
+		// Browsers reject cookies with an IP address as the domain.
+		if net.ParseIP(base.Hostname()) == nil {
+			sg.Store.Options.Domain = base.Hostname()
+		}
+
+		sg.Store.Options.Secure = base.Scheme == "https"
+	}
+}
+
+// stripBasePath removes the base path from a request when the reverse proxy
+// passes the full path through instead of stripping it.
+func (sg *Sandwich) stripBasePath(ctx *fasthttp.RequestCtx) {
+	basePath := sg.basePath()
+	if basePath == "/" {
+		return
+	}
+
+	path := gotils.B2S(ctx.Path())
+
+	var uri string
+
+	switch {
+	case path+"/" == basePath:
+		uri = "/"
+	case strings.HasPrefix(path, basePath):
+		uri = "/" + strings.TrimPrefix(path, basePath)
+	default:
+		return
+	}
+
+	if query := ctx.URI().QueryString(); len(query) > 0 {
+		uri += "?" + string(query)
+	}
+
+	// The request uri is set rather than the path as handlers adapted from
+	// net/http read it instead.
+	ctx.Request.SetRequestURI(uri)
+}
+
+// Wildcard entry of http.allowed_origins which allows every origin.
+const allowAnyOrigin = "*"
+
+// allowedOrigins returns http.allowed_origins. It is read on every request
+// so changes made through daemon:update apply immediately.
+func (sg *Sandwich) allowedOrigins() []string {
+	sg.ConfigurationMu.RLock()
+	origins := sg.Configuration.HTTP.AllowedOrigins
+	sg.ConfigurationMu.RUnlock()
+
+	// Configurations written before allowed_origins existed do not set it.
+	if origins == nil {
+		return defaultAllowedOrigins
+	}
+
+	return origins
+}
+
+// matchOrigin returns if an origin matches an entry of http.allowed_origins.
+// Entries with a scheme must match the scheme and host. Entries without one
+// match the host regardless of the scheme, or just the hostname if they have
+// no port. A leading "*." matches any subdomain. The "*" entry never
+// matches as it only allows requests without credentials, which is handled
+// by setCORSHeaders.
+func matchOrigin(originURL *url.URL, allowed string) bool {
+	allowed = strings.TrimSuffix(strings.TrimSpace(allowed), "/")
+
+	if allowed == allowAnyOrigin {
+		return false
+	}
+
+	if i := strings.Index(allowed, "://"); i >= 0 {
+		if !strings.EqualFold(originURL.Scheme, allowed[:i]) {
+			return false
+		}
+
+		allowed = allowed[i+3:]
+	}
+
+	host := originURL.Host
+	if !strings.Contains(allowed, ":") {
+		host = originURL.Hostname()
+	}
+
+	if strings.HasPrefix(allowed, "*.") {
+		return strings.HasSuffix(strings.ToLower(host), strings.ToLower(allowed[1:]))
+	}
+
+	return strings.EqualFold(host, allowed)
+}
+
+// originAllowed returns if an origin is allowed by http.allowed_origins.
+func (sg *Sandwich) originAllowed(origin string) bool {
+	originURL, err := url.Parse(origin)
+	if err != nil || originURL.Host == "" {
+		return false
+	}
+
+	for _, allowed := range sg.allowedOrigins() {
+		if matchOrigin(originURL, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkOrigin allows websocket connections from http.allowed_origins, the
+// base URL, the request host and, when trusted, the forwarded host. The
+// websockets use the session cookie so "*" does not allow any origin here,
+// otherwise any site could open them as a logged in user. Requests with an
+// API token are allowed from anywhere as browsers cannot send one on a
+// websocket, so they come from clients such as pkg/client which send no
+// origin at all.
+func (sg *Sandwich) checkOrigin(ctx *fasthttp.RequestCtx) bool {
+	if _, ok := sg.apiTokenName(gotils.B2S(ctx.Request.Header.Peek("Authorization"))); ok {
+		return true
+	}
+
+	origin := gotils.B2S(ctx.Request.Header.Peek("Origin"))
+
+	if sg.originAllowed(origin) {
+		return true
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil || originURL.Host == "" {
+		return false
+	}
+
+	if base := sg.baseURL(); base != nil && originURL.Scheme == base.Scheme && originURL.Host == base.Host {
+		return true
+	}
+
+	if originURL.Host == gotils.B2S(ctx.Host()) {
+		return true
+	}
+
+	sg.ConfigurationMu.RLock()
+	trustProxyHeaders := sg.Configuration.HTTP.TrustProxyHeaders
+	sg.ConfigurationMu.RUnlock()
+
+	if trustProxyHeaders {
+		forwardedHost := firstHeaderValue(gotils.B2S(ctx.Request.Header.Peek("X-Forwarded-Host")))
+
+		return forwardedHost != "" && originURL.Host == forwardedHost
+	}
+
+	return false
+}
+
+// setCORSHeaders allows cross-origin requests to the REST endpoints from
+// http.allowed_origins. Origins allowed through the wildcard cannot send
+// credentials so they cannot use the session of a logged in user. True is
+// returned if the request was a preflight which has been answered.
+func (sg *Sandwich) setCORSHeaders(ctx *fasthttp.RequestCtx) (preflight bool) {
+	origin := gotils.B2S(ctx.Request.Header.Peek("Origin"))
+	if origin == "" {
+		return false
+	}
+
+	ctx.Response.Header.Add("Vary", "Origin")
+
+	originURL, err := url.Parse(origin)
+	if err != nil || originURL.Host == "" {
+		return false
+	}
+
+	credentials := false
+	wildcard := false
+
+	for _, allowed := range sg.allowedOrigins() {
+		if strings.TrimSpace(allowed) == allowAnyOrigin {
+			wildcard = true
+		} else if matchOrigin(originURL, allowed) {
+			credentials = true
+
+			break
+		}
+	}
+
+	switch {
+	case credentials:
+		ctx.Response.Header.Set("Access-Control-Allow-Origin", origin)
+		ctx.Response.Header.Set("Access-Control-Allow-Credentials", "true")
+	case wildcard:
+		ctx.Response.Header.Set("Access-Control-Allow-Origin", allowAnyOrigin)
+	default:
+		return false
+	}
+
+	if !ctx.IsOptions() || len(ctx.Request.Header.Peek("Access-Control-Request-Method")) == 0 {
+		return false
+	}
+
+	ctx.Response.Header.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	ctx.Response.Header.Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	ctx.Response.Header.Set("Access-Control-Max-Age", "600")
+	ctx.SetStatusCode(http.StatusNoContent)
+
+	return true
+}
+
+// newUpgrader creates the websocket upgrader used by the dashboard.
+func (sg *Sandwich) newUpgrader() websocket.FastHTTPUpgrader {
+	return websocket.FastHTTPUpgrader{
+		EnableCompression: true,
+		CheckOrigin:       sg.checkOrigin,
+	}
+}
+
+// serveIndex sends index.html with a base element so the dashboard resolves
+// its assets and API calls under the base path.
+func (sg *Sandwich) serveIndex(ctx *fasthttp.RequestCtx) {
+	index, err := ioutil.ReadFile(webRootPath + "/index.html")
+	if err != nil {
+		ctx.Error(err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	base := []byte(`<head><base href="` + sg.basePath() + `">`)
+
+	ctx.SetContentType("text/html; charset=utf-8")
+	ctx.SetBody(bytes.Replace(index, []byte("<head>"), base, 1))
+}
@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func originCtx(host, origin string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetHost(host)
+	ctx.Request.Header.Set("Origin", origin)
+
+	return ctx
+}
+
+func TestCheckOriginWildcard(t *testing.T) {
+	sg := &Sandwich{Configuration: &SandwichConfiguration{}}
+	sg.Configuration.HTTP.AllowedOrigins = []string{"*", "https://dashboard.example.com", "*.example.org"}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://evil.example.net", false},
+		{"https://dashboard.example.com", true},
+		{"http://dashboard.example.com", false},
+		{"https://sub.example.org", true},
+		{"http://127.0.0.1:5469", true},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := sg.checkOrigin(originCtx("127.0.0.1:5469", test.origin)); got != test.want {
+			t.Errorf("checkOrigin(%q) = %v, want %v", test.origin, got, test.want)
+		}
+	}
+}
+
+func TestCheckOriginAPIToken(t *testing.T) {
+	sg := &Sandwich{Configuration: &SandwichConfiguration{}}
+	sg.Configuration.HTTP.APITokens = []APIToken{{Name: "client", Token: "secret"}}
+
+	tests := []struct {
+		authorization string
+		want          bool
+	}{
+		{"Bearer secret", true},
+		{"Bearer wrong", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		ctx := originCtx("127.0.0.1:5469", "")
+		ctx.Request.Header.Set("Authorization", test.authorization)
+
+		if got := sg.checkOrigin(ctx); got != test.want {
+			t.Errorf("checkOrigin with %q = %v, want %v", test.authorization, got, test.want)
+		}
+	}
+}
+
+func TestSetCORSHeadersWildcard(t *testing.T) {
+	sg := &Sandwich{Configuration: &SandwichConfiguration{}}
+	sg.Configuration.HTTP.AllowedOrigins = []string{"*", "https://dashboard.example.com"}
+
+	ctx := originCtx("127.0.0.1:5469", "https://evil.example.net")
+	sg.setCORSHeaders(ctx)
+
+	if got := string(ctx.Response.Header.Peek("Access-Control-Allow-Origin")); got != allowAnyOrigin {
+		t.Errorf("wildcard origin got Access-Control-Allow-Origin %q", got)
+	}
+
+	if got := ctx.Response.Header.Peek("Access-Control-Allow-Credentials"); len(got) != 0 {
+		t.Errorf("wildcard origin was allowed credentials")
+	}
+
+	ctx = originCtx("127.0.0.1:5469", "https://dashboard.example.com")
+	sg.setCORSHeaders(ctx)
+
+	if got := string(ctx.Response.Header.Peek("Access-Control-Allow-Credentials")); got != "true" {
+		t.Errorf("listed origin was not allowed credentials")
+	}
+}
@@ -0,0 +1,294 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+const (
+	// Defaults used when producer.retry_buffer is enabled without a size or
+	// age.
+	defaultPublishRetrySize   = 10000
+	defaultPublishRetryMaxAge = 60
+
+	// Delay before retrying the oldest payload after a failed retry. Each
+	// following failure waits twice as long up to maxPublishRetryDelay.
+	publishRetryDelay    = 100 * time.Millisecond
+	maxPublishRetryDelay = 10 * time.Second
+)
+
+// publishRetryPayload is a serialized payload waiting to be published again.
+type publishRetryPayload struct {
+	channel string
+	message mqclients.Message
+	data    []byte
+	track   bool // Counted as a produced message once published
+	queued  time.Time
+}
+
+// publishRetry buffers payloads which could not be published whilst the
+// broker is unavailable and publishes them again in order. Once a payload is
+// buffered, following payloads are buffered behind it until it drains so
+// consumers do not receive events out of order. When the buffer is full the
+// oldest payload is dropped, as are payloads older than the max age.
+type publishRetry struct {
+	mg *Manager
+
+	mu      sync.Mutex
+	ring    []publishRetryPayload
+	head    int
+	count   int
+	running bool
+
+	dropped *int64
+	retried *int64
+}
+
+func newPublishRetry(mg *Manager) *publishRetry {
+	return &publishRetry{
+		mg: mg,
+
+		mu: sync.Mutex{},
+
+		dropped: new(int64),
+		retried: new(int64),
+	}
+}
+
+// settings returns if the retry buffer is enabled with its size and max age.
+func (pr *publishRetry) settings() (enabled bool, size int, maxAge time.Duration) {
+	pr.mg.Sandwich.ConfigurationMu.RLock()
+	config := pr.mg.Sandwich.Configuration.Producer.RetryBuffer
+	pr.mg.Sandwich.ConfigurationMu.RUnlock()
+
+	// Configurations updated over RPC are not normalized.
+	if config.Size < 1 {
+		config.Size = defaultPublishRetrySize
+	}
+
+	if config.MaxAge < 1 {
+		config.MaxAge = defaultPublishRetryMaxAge
+	}
+
+	return config.Enabled, config.Size, time.Duration(config.MaxAge) * time.Second
+}
+
+// Pending returns if payloads are waiting to be published again.
+func (pr *publishRetry) Pending() bool {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	return pr.count > 0
+}
+
+// Enqueue buffers a copy of a payload. False is returned if the retry buffer
+// is disabled.
+func (pr *publishRetry) Enqueue(channel string, message mqclients.Message, data []byte, track bool) (queued bool) {
+	enabled, size, _ := pr.settings()
+	if !enabled {
+		return false
+	}
+
+	payload := publishRetryPayload{
+		channel: channel,
+		message: mqclients.Message{
+			Key:     append([]byte(nil), message.Key...),
+			Headers: message.Headers,
+		},
+		data:   append(make([]byte, 0, len(data)), data...),
+		track:  track,
+		queued: time.Now(),
+	}
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	pr.resize(size)
+
+	if pr.count == len(pr.ring) {
+		pr.ring[pr.head] = publishRetryPayload{}
+		pr.head = (pr.head + 1) % len(pr.ring)
+		pr.count--
+
+		atomic.AddInt64(pr.dropped, 1)
+	}
+
+	pr.ring[(pr.head+pr.count)%len(pr.ring)] = payload
+	pr.count++
+
+	if !pr.running {
+		pr.running = true
+
+		go pr.run()
+	}
+
+	return true
+}
+
+// resize changes the capacity of the ring, dropping the oldest payloads if
+// they no longer fit. mu must be held.
+func (pr *publishRetry) resize(size int) {
+	if len(pr.ring) == size {
+		return
+	}
+
+	for pr.count > size {
+		pr.head = (pr.head + 1) % len(pr.ring)
+		pr.count--
+
+		atomic.AddInt64(pr.dropped, 1)
+	}
+
+	ring := make([]publishRetryPayload, size)
+	for i := 0; i < pr.count; i++ {
+		ring[i] = pr.ring[(pr.head+i)%len(pr.ring)]
+	}
+
+	pr.ring = ring
+	pr.head = 0
+}
+
+// peek returns the oldest payload, dropping any which are older than maxAge.
+func (pr *publishRetry) peek(maxAge time.Duration) (payload publishRetryPayload, ok bool) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	for pr.count > 0 {
+		payload = pr.ring[pr.head]
+		if time.Since(payload.queued) <= maxAge {
+			return payload, true
+		}
+
+		pr.pop()
+
+		atomic.AddInt64(pr.dropped, 1)
+	}
+
+	pr.running = false
+
+	return payload, false
+}
+
+// Discard drops every buffered payload and returns how many were dropped.
+// They are counted as dropped.
+func (pr *publishRetry) Discard() (dropped int) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	for pr.count > 0 {
+		pr.pop()
+		dropped++
+	}
+
+	atomic.AddInt64(pr.dropped, int64(dropped))
+
+	return dropped
+}
+
+// pop removes the oldest payload. mu must be held.
+func (pr *publishRetry) pop() {
+	pr.ring[pr.head] = publishRetryPayload{}
+	pr.head = (pr.head + 1) % len(pr.ring)
+	pr.count--
+}
+
+// run publishes buffered payloads oldest first until the buffer is empty or
+// the manager closes. After a failure the payload is retried with backoff.
+func (pr *publishRetry) run() {
+	delay := publishRetryDelay
+
+	for {
+		_, _, maxAge := pr.settings()
+
+		payload, ok := pr.peek(maxAge)
+		if !ok {
+			return
+		}
+
+		err := pr.publish(payload)
+		if err != nil {
+			select {
+			case <-pr.mg.ctx.Done():
+				pr.mu.Lock()
+				pr.running = false
+				pr.mu.Unlock()
+
+				return
+			case <-time.After(delay):
+			}
+
+			if delay *= 2; delay > maxPublishRetryDelay {
+				delay = maxPublishRetryDelay
+			}
+
+			continue
+		}
+
+		delay = publishRetryDelay
+
+		pr.mu.Lock()
+		pr.pop()
+		pr.mu.Unlock()
+
+		atomic.AddInt64(pr.retried, 1)
+	}
+}
+
+func (pr *publishRetry) publish(payload publishRetryPayload) (err error) {
+	client := pr.mg.Producer()
+	if client == nil {
+		return ErrProducerUnavailable
+	}
+
+	err = publishMessage(pr.mg.ctx, client, payload.channel, payload.message, payload.data)
+
+	if payload.track {
+		pr.mg.recordPublish(len(payload.data), err)
+	} else {
+		pr.mg.recordPublishOutcome(err)
+	}
+
+	return err
+}
+
+// API returns the state of the retry buffer.
+func (pr *publishRetry) API() structs.PublishRetryStats {
+	pr.mu.Lock()
+	queued := pr.count
+	pr.mu.Unlock()
+
+	return structs.PublishRetryStats{
+		Queued:  queued,
+		Dropped: atomic.LoadInt64(pr.dropped),
+		Retried: atomic.LoadInt64(pr.retried),
+	}
+}
+
+// publishWithRetry publishes a payload. If the retry buffer is enabled, the
+// payload is buffered when the publish fails or earlier payloads are still
+// buffered. queued is true if the payload was buffered. err is the error of
+// the publish if one was attempted or ErrProducerUnavailable if the manager
+// has no producer.
+func (mg *Manager) publishWithRetry(ctx context.Context, channel string, message mqclients.Message, data []byte,
+	track bool) (queued bool, err error) {
+	if mg.publishRetry.Pending() && mg.publishRetry.Enqueue(channel, message, data, track) {
+		return true, nil
+	}
+
+	if client := mg.Producer(); client != nil {
+		err = publishMessage(ctx, client, channel, message, data)
+	} else {
+		err = ErrProducerUnavailable
+	}
+
+	if err != nil && mg.publishRetry.Enqueue(channel, message, data, track) {
+		return true, err
+	}
+
+	return false, err
+}
@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"nhooyr.io/websocket"
+)
+
+const (
+	// Read limit errors in a row without receiving a dispatch before the
+	// session is discarded and the shard identifies again.
+	maxReadLimitStreak = 3
+
+	// Bytes at the start of an oversized payload searched for its event type
+	// and sequence.
+	readLimitPeekLength = 256
+)
+
+var (
+	peekTypeRegex     = regexp.MustCompile(`"t"\s*:\s*"([A-Z_]+)"`)
+	peekSequenceRegex = regexp.MustCompile(`"s"\s*:\s*(\d+)`)
+)
+
+// ReadLimitError is returned when the gateway sends a payload larger than the
+// read limit. The websocket library closes the connection when this happens.
+type ReadLimitError struct {
+	Limit       int64
+	Size        int // Bytes read before the limit was reached
+	MessageType websocket.MessageType
+	EventType   string // Empty if it could not be determined
+	Sequence    int64  // 0 if it could not be determined
+	Err         error
+}
+
+func (e *ReadLimitError) Error() string {
+	return fmt.Sprintf("payload exceeded read limit of %d bytes: %v", e.Limit, e.Err)
+}
+
+func (e *ReadLimitError) Unwrap() error {
+	return e.Err
+}
+
+// isReadLimitError returns if an error from reading the websocket was caused
+// by the read limit. The websocket library does not export an error for this.
+func isReadLimitError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "read limited at")
+}
+
+// newReadLimitError describes a payload that exceeded the read limit from the
+// bytes read before the limit was reached. Compressed payloads cannot be
+// inspected so only their size is known.
+func newReadLimitError(limit int64, mt websocket.MessageType, buf []byte, err error) *ReadLimitError {
+	rle := &ReadLimitError{
+		Limit:       limit,
+		Size:        len(buf),
+		MessageType: mt,
+		Err:         err,
+	}
+
+	if mt != websocket.MessageText {
+		return rle
+	}
+
+	peek := buf
+	if len(peek) > readLimitPeekLength {
+		peek = peek[:readLimitPeekLength]
+	}
+
+	if match := peekTypeRegex.FindSubmatch(peek); match != nil {
+		rle.EventType = string(match[1])
+	}
+
+	if match := peekSequenceRegex.FindSubmatch(peek); match != nil {
+		rle.Sequence, _ = strconv.ParseInt(string(match[1]), 10, 64)
+	}
+
+	return rle
+}
+
+// readLimit returns the websocket read limit of the manager.
+func (mg *Manager) readLimit() int64 {
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	return mg.Configuration.Bot.WebsocketReadLimit
+}
+
+// handleReadLimit records a payload that exceeded the read limit. As the same
+// payload is sent again when resuming, the sequence is moved past it if it is
+// the next dispatch so the resume skips it. True is returned once too many
+// have been received in a row and the shard should identify again instead.
+func (sh *Shard) handleReadLimit(rle *ReadLimitError) (reidentify bool) {
+	atomic.AddInt64(sh.readLimitExceeded, 1)
+	atomic.AddInt64(sh.Manager.readLimitExceeded, 1)
+
+	streak := atomic.AddInt64(sh.readLimitStreak, 1)
+	seq := atomic.LoadInt64(sh.seq)
+
+	skipped := rle.Sequence != 0 && rle.Sequence == seq+1
+	if skipped {
+		atomic.StoreInt64(sh.seq, rle.Sequence)
+	}
+
+	sh.Logger.Warn().
+		Int64("limit", rle.Limit).
+		Int("size", rle.Size).
+		Str("type", rle.EventType).
+		Int64("sequence", rle.Sequence).
+		Int64("streak", streak).
+		Bool("skipped", skipped).
+		Msg("Gateway sent a payload larger than the read limit")
+
+	if streak < maxReadLimitStreak {
+		return false
+	}
+
+	atomic.StoreInt64(sh.readLimitStreak, 0)
+
+	go sh.PublishNoisyWebhook(
+		"Re-identifying after repeatedly exceeding the read limit",
+		fmt.Sprintf("Received %d payloads over %d bytes in a row. Last event type: `%s`",
+			streak, rle.Limit, rle.EventType),
+		16760839, false)
+
+	return true
+}
+
+// ReadLimitExceeded returns how many payloads the shard has received which
+// were larger than the read limit.
+func (sh *Shard) ReadLimitExceeded() int64 {
+	return atomic.LoadInt64(sh.readLimitExceeded)
+}
+
+// ReadLimitExceeded returns how many payloads shards of the manager have
+// received which were larger than the read limit.
+func (mg *Manager) ReadLimitExceeded() int64 {
+	return atomic.LoadInt64(mg.readLimitExceeded)
+}
@@ -0,0 +1,276 @@
+package gateway
+
+import (
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Interval between rebalance reports being generated.
+	rebalanceInterval = 24 * time.Hour
+
+	// Coefficient of variation above which re-sharding is recommended if
+	// sharding.rebalance_threshold is not set.
+	defaultRebalanceThreshold = 0.25
+)
+
+// Measures shards are compared by in a rebalance report.
+const (
+	rebalanceMeasureGuilds  = "guilds"
+	rebalanceMeasureMembers = "members"
+	rebalanceMeasureEvents  = "events"
+)
+
+// ErrNoShardGroup is returned when a manager has no shardgroup with shards
+// to report on.
+var ErrNoShardGroup = xerrors.New("manager has no running shardgroup")
+
+// latestShardGroup returns the newest shardgroup of the manager which has
+// shards. Whilst scaling this is the shardgroup which replaces the others.
+func (mg *Manager) latestShardGroup() (latest *ShardGroup) {
+	for _, sg := range mg.shardGroupsSnapshot() {
+		if sg.ShardCount < 1 {
+			continue
+		}
+
+		sg.ShardsMu.RLock()
+		shards := len(sg.Shards)
+		sg.ShardsMu.RUnlock()
+
+		if shards > 0 && (latest == nil || sg.ID > latest.ID) {
+			latest = sg
+		}
+	}
+
+	return latest
+}
+
+// rebalanceShards measures the load of each shard of the shardgroup. Guilds
+// are counted by the shard they belong to and event rates are the dispatches
+// a shard has received per second since it was created.
+func (sg *ShardGroup) rebalanceShards(now time.Time) (shards []structs.RebalanceShard) {
+	guilds := make(map[int]int64)
+	members := make(map[int]int64)
+
+	sg.GuildsMu.RLock()
+	for guildID, guild := range sg.Guilds {
+		shardID := int((guildID.Int64() >> 22) % int64(sg.ShardCount))
+		guilds[shardID]++
+
+		if guild != nil && guild.Guild != nil {
+			members[shardID] += int64(guild.MemberCount)
+		}
+	}
+	sg.GuildsMu.RUnlock()
+
+	snapshot := sg.shardsSnapshot()
+	shards = make([]structs.RebalanceShard, 0, len(snapshot))
+
+	for shardID, shard := range snapshot {
+		var eventRate float64
+
+		if elapsed := now.Sub(shard.Start).Seconds(); elapsed > 0 {
+			eventRate = float64(atomic.LoadInt64(shard.opcodes.dispatch)) / elapsed
+		}
+
+		shards = append(shards, structs.RebalanceShard{
+			ShardID:   shardID,
+			Guilds:    guilds[shardID],
+			Members:   members[shardID],
+			EventRate: eventRate,
+		})
+	}
+
+	sort.Slice(shards, func(i, j int) bool { return shards[i].ShardID < shards[j].ShardID })
+
+	return shards
+}
+
+// variation returns the coefficient of variation of the values and the
+// ratio of the largest value to the mean.
+func variation(values []float64) (cv float64, peak float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	var sum, max float64
+
+	for _, value := range values {
+		sum += value
+
+		if value > max {
+			max = value
+		}
+	}
+
+	mean := sum / float64(len(values))
+	if mean == 0 {
+		return 0, 0
+	}
+
+	var squares float64
+
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+
+	return math.Sqrt(squares/float64(len(values))) / mean, max / mean
+}
+
+// GenerateRebalanceReport measures how evenly guilds, members and events are
+// spread over the shards of the newest shardgroup and recommends re-sharding
+// when the variation of any of them is above sharding.rebalance_threshold.
+// The report is stored as the last report of the manager.
+func (mg *Manager) GenerateRebalanceReport() (report *structs.RebalanceReport, err error) {
+	sg := mg.latestShardGroup()
+	if sg == nil {
+		return nil, ErrNoShardGroup
+	}
+
+	mg.ConfigurationMu.RLock()
+	identifier := mg.Configuration.Identifier
+	threshold := mg.Configuration.Sharding.RebalanceThreshold
+	mg.ConfigurationMu.RUnlock()
+
+	if threshold <= 0 {
+		threshold = defaultRebalanceThreshold
+	}
+
+	mg.GatewayMu.RLock()
+	recommendedShards := mg.Gateway.Shards
+	maxConcurrency := mg.Gateway.SessionStartLimit.MaxConcurrency
+	mg.GatewayMu.RUnlock()
+
+	now := time.Now().UTC()
+	shards := sg.rebalanceShards(now)
+
+	guilds := make([]float64, len(shards))
+	members := make([]float64, len(shards))
+	events := make([]float64, len(shards))
+
+	for i, shard := range shards {
+		guilds[i] = float64(shard.Guilds)
+		members[i] = float64(shard.Members)
+		events[i] = shard.EventRate
+	}
+
+	report = &structs.RebalanceReport{
+		Manager:             identifier,
+		GeneratedAt:         now,
+		ShardGroup:          sg.ID,
+		ShardCount:          sg.ShardCount,
+		Threshold:           threshold,
+		Variation:           make(map[string]float64),
+		SuggestedShardCount: sg.ShardCount,
+		Shards:              shards,
+	}
+
+	// The heaviest shard of the measure which varies the most decides the
+	// suggestion. Scaling the shard count by how far it is above the mean
+	// brings an average shard down to the load the heaviest one had.
+	var peak float64
+
+	for measure, values := range map[string][]float64{
+		rebalanceMeasureGuilds:  guilds,
+		rebalanceMeasureMembers: members,
+		rebalanceMeasureEvents:  events,
+	} {
+		cv, measurePeak := variation(values)
+		report.Variation[measure] = cv
+
+		if cv > threshold && cv > report.Variation[report.Measure] {
+			report.Measure = measure
+			peak = measurePeak
+		}
+	}
+
+	if report.Measure != "" {
+		suggested := int(math.Ceil(float64(sg.ShardCount) * peak))
+		if suggested < recommendedShards {
+			suggested = recommendedShards
+		}
+
+		if maxConcurrency > 1 {
+			suggested = int(math.Ceil(float64(suggested)/float64(maxConcurrency))) * maxConcurrency
+		}
+
+		if suggested > sg.ShardCount {
+			report.Recommended = true
+			report.SuggestedShardCount = suggested
+		}
+	}
+
+	mg.rebalanceReportMu.Lock()
+	mg.rebalanceReport = report
+	mg.rebalanceReportMu.Unlock()
+
+	mg.Logger.Info().
+		Bool("recommended", report.Recommended).
+		Str("measure", report.Measure).
+		Int("shard_count", report.ShardCount).
+		Int("suggested_shard_count", report.SuggestedShardCount).
+		Msg("Generated rebalance report")
+
+	return report, nil
+}
+
+// RebalanceReport returns the last rebalance report of the manager or nil if
+// none has been generated.
+func (mg *Manager) RebalanceReport() *structs.RebalanceReport {
+	mg.rebalanceReportMu.RLock()
+	defer mg.rebalanceReportMu.RUnlock()
+
+	return mg.rebalanceReport
+}
+
+// rebalanceShardCount returns the shard count suggested by the last
+// rebalance report if it recommends re-sharding. Nothing is suggested
+// during a gateway incident.
+func (mg *Manager) rebalanceShardCount() (shardCount int, ok bool) {
+	// Shard loads are not representative whilst shards are reconnecting.
+	if mg.Sandwich.InIncident() {
+		return 0, false
+	}
+
+	report := mg.RebalanceReport()
+	if report == nil || !report.Recommended {
+		return 0, false
+	}
+
+	return report.SuggestedShardCount, true
+}
+
+// rebalanceRunner generates a rebalance report daily until the manager is
+// closed.
+func (mg *Manager) rebalanceRunner() {
+	if !mg.rebalanceActive.SetToIf(false, true) {
+		return
+	}
+	defer mg.rebalanceActive.UnSet()
+
+	t := time.NewTicker(rebalanceInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-mg.ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		if mg.Sandwich.InIncident() {
+			mg.Logger.Info().Msg("Skipping rebalance report whilst there is a gateway incident")
+
+			continue
+		}
+
+		if _, err := mg.GenerateRebalanceReport(); err != nil && !xerrors.Is(err, ErrNoShardGroup) {
+			mg.Logger.Error().Err(err).Msg("Failed to generate rebalance report")
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	// First wait after a failed reconnect. Each following failure waits
+	// twice as long up to bot.max_reconnect_wait.
+	minReconnectWait = time.Second
+
+	// Seconds a shard waits at most between reconnect attempts if
+	// bot.max_reconnect_wait is not set.
+	defaultMaxReconnectWait = 600
+
+	// Fraction each wait is randomly lengthened or shortened by so shards
+	// which disconnected together do not all reconnect at the same instant.
+	reconnectJitter = 0.2
+)
+
+// nextReconnectWait returns the wait after another failed reconnect, doubling
+// the previous one up to limit.
+func nextReconnectWait(wait time.Duration, limit time.Duration) time.Duration {
+	wait *= 2
+	if wait > limit {
+		wait = limit
+	}
+
+	return wait
+}
+
+// jitterReconnectWait spreads a wait by up to reconnectJitter either side.
+func jitterReconnectWait(wait time.Duration) time.Duration {
+	return time.Duration(float64(wait) * (1 + reconnectJitter*(2*rand.Float64()-1)))
+}
+
+// maxReconnectWait returns bot.max_reconnect_wait as a duration.
+func (mg *Manager) maxReconnectWait() time.Duration {
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	return time.Duration(mg.Configuration.Bot.MaxReconnectWait) * time.Second
+}
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextReconnectWait(t *testing.T) {
+	limit := defaultMaxReconnectWait * time.Second
+
+	want := []time.Duration{
+		2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second,
+		64 * time.Second, 128 * time.Second, 256 * time.Second, 512 * time.Second,
+		limit, limit, limit,
+	}
+
+	wait := minReconnectWait
+
+	for i, expected := range want {
+		wait = nextReconnectWait(wait, limit)
+		if wait != expected {
+			t.Fatalf("wait %d was %s, want %s", i+1, wait, expected)
+		}
+	}
+}
+
+func TestNextReconnectWaitSmallLimit(t *testing.T) {
+	limit := 3 * time.Second
+
+	wait := nextReconnectWait(minReconnectWait, limit)
+	if wait != 2*time.Second {
+		t.Errorf("first wait was %s", wait)
+	}
+
+	for i := 0; i < 5; i++ {
+		if wait = nextReconnectWait(wait, limit); wait != limit {
+			t.Errorf("wait was %s, want the limit of %s", wait, limit)
+		}
+	}
+}
+
+func TestJitterReconnectWait(t *testing.T) {
+	const wait = 10 * time.Second
+
+	low := time.Duration(float64(wait) * (1 - reconnectJitter))
+	high := time.Duration(float64(wait) * (1 + reconnectJitter))
+
+	seen := make(map[time.Duration]bool)
+
+	for i := 0; i < 1000; i++ {
+		jittered := jitterReconnectWait(wait)
+		if jittered < low || jittered > high {
+			t.Fatalf("jittered wait %s is outside %s to %s", jittered, low, high)
+		}
+
+		seen[jittered] = true
+	}
+
+	if len(seen) < 2 {
+		t.Error("waits were not jittered")
+	}
+}
+
+func TestMaxReconnectWait(t *testing.T) {
+	mg := newTestShard(t).Manager
+
+	if limit := mg.maxReconnectWait(); limit != 10*time.Minute {
+		t.Errorf("default limit was %s, want 10m", limit)
+	}
+
+	mg.ConfigurationMu.Lock()
+	mg.Configuration.Bot.MaxReconnectWait = 30
+	mg.ConfigurationMu.Unlock()
+
+	if limit := mg.maxReconnectWait(); limit != 30*time.Second {
+		t.Errorf("limit was %s, want 30s", limit)
+	}
+}
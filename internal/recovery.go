@@ -0,0 +1,282 @@
+package gateway
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+const (
+	// Defaults used when bot.guild_recovery is enabled without a window or
+	// worker count.
+	defaultGuildRecoveryWindow  = 30
+	defaultGuildRecoveryWorkers = 4
+
+	// Interval between checking if a shard has finished recovering.
+	guildRecoveryCheckInterval = time.Second
+)
+
+// guildRecoveryPayload is the part of a GUILD_CREATE used to order it.
+type guildRecoveryPayload struct {
+	ID          snowflake.ID `json:"id"`
+	MemberCount int          `json:"member_count"`
+}
+
+// guildRecoveryItem is a GUILD_CREATE waiting to be ingested.
+type guildRecoveryItem struct {
+	memberCount int
+	order       int64
+	exec        func()
+}
+
+// guildRecoveryHeap is a max heap so the largest guilds are ingested first.
+// Guilds of the same size keep the order they were received in.
+type guildRecoveryHeap []*guildRecoveryItem
+
+func (h guildRecoveryHeap) Len() int { return len(h) }
+
+func (h guildRecoveryHeap) Less(i, j int) bool {
+	if h[i].memberCount != h[j].memberCount {
+		return h[i].memberCount > h[j].memberCount
+	}
+
+	return h[i].order < h[j].order
+}
+
+func (h guildRecoveryHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *guildRecoveryHeap) Push(x interface{}) {
+	*h = append(*h, x.(*guildRecoveryItem))
+}
+
+func (h *guildRecoveryHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return item
+}
+
+// guildRecovery throttles the burst of GUILD_CREATE discord sends after a
+// shard has been disconnected for a while. For bot.guild_recovery_window
+// seconds after READY or RESUMED, and for as long as guilds keep arriving
+// within that many seconds of each other, GUILD_CREATE are ingested by at
+// most bot.guild_recovery_workers workers, largest guild first. As guilds are
+// reordered, a guild can be ingested after later events of it are handled.
+type guildRecovery struct {
+	sh *Shard
+
+	mu       sync.Mutex
+	active   bool
+	started  time.Time
+	until    time.Time
+	window   time.Duration
+	workers  int
+	running  int
+	order    int64
+	ingested int
+	queue    guildRecoveryHeap
+
+	// Closed whilst the shard is not recovering.
+	done chan void
+}
+
+func newGuildRecovery(sh *Shard) *guildRecovery {
+	done := make(chan void)
+	close(done)
+
+	return &guildRecovery{
+		sh: sh,
+
+		mu:    sync.Mutex{},
+		queue: make(guildRecoveryHeap, 0),
+
+		done: done,
+	}
+}
+
+// Start begins recovering after READY or RESUMED. If the shard is already
+// recovering, the window is restarted.
+func (gr *guildRecovery) Start() {
+	gr.sh.Manager.ConfigurationMu.RLock()
+	enabled := gr.sh.Manager.Configuration.Bot.GuildRecovery
+	window := gr.sh.Manager.Configuration.Bot.GuildRecoveryWindow
+	workers := gr.sh.Manager.Configuration.Bot.GuildRecoveryWorkers
+	gr.sh.Manager.ConfigurationMu.RUnlock()
+
+	if !enabled {
+		return
+	}
+
+	// Configurations updated over RPC are not normalized.
+	if window < 1 {
+		window = defaultGuildRecoveryWindow
+	}
+
+	if workers < 1 {
+		workers = defaultGuildRecoveryWorkers
+	}
+
+	now := time.Now().UTC()
+
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	gr.window = time.Duration(window) * time.Second
+	gr.workers = workers
+	gr.until = now.Add(gr.window)
+
+	if gr.active {
+		return
+	}
+
+	gr.active = true
+	gr.started = now
+	gr.ingested = 0
+	gr.done = make(chan void)
+
+	go gr.supervise()
+}
+
+// Enqueue queues a GUILD_CREATE to be ingested by a recovery worker. If the
+// shard is not recovering, false is returned and exec should be ran as usual.
+func (gr *guildRecovery) Enqueue(msg discord.ReceivedPayload, exec func()) (queued bool) {
+	gr.mu.Lock()
+	active := gr.active
+	gr.mu.Unlock()
+
+	if !active {
+		return false
+	}
+
+	// Guilds which cannot be decoded are queued last and fail again when
+	// they are ingested.
+	var payload guildRecoveryPayload
+	_ = gr.sh.decodeContent(msg, &payload)
+
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	if until := time.Now().UTC().Add(gr.window); until.After(gr.until) {
+		gr.until = until
+	}
+
+	gr.order++
+	heap.Push(&gr.queue, &guildRecoveryItem{
+		memberCount: payload.MemberCount,
+		order:       gr.order,
+		exec:        exec,
+	})
+
+	if gr.running < gr.workers {
+		gr.running++
+
+		go gr.work()
+	}
+
+	return true
+}
+
+// work ingests queued guilds until the queue is empty.
+func (gr *guildRecovery) work() {
+	for {
+		gr.mu.Lock()
+		if len(gr.queue) == 0 {
+			gr.running--
+			gr.mu.Unlock()
+
+			return
+		}
+
+		item := heap.Pop(&gr.queue).(*guildRecoveryItem)
+		gr.mu.Unlock()
+
+		item.exec()
+
+		gr.mu.Lock()
+		gr.ingested++
+		gr.mu.Unlock()
+	}
+}
+
+// supervise ends recovery once the window has passed and every queued guild
+// has been ingested, or when the shardgroup closes. Guilds which are still
+// queued when the shardgroup closes are left for the workers.
+func (gr *guildRecovery) supervise() {
+	t := time.NewTicker(guildRecoveryCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-gr.sh.ShardGroup.close:
+			gr.finish()
+
+			return
+		case now := <-t.C:
+			gr.mu.Lock()
+			idle := now.UTC().After(gr.until) && len(gr.queue) == 0 && gr.running == 0
+			gr.mu.Unlock()
+
+			if idle {
+				gr.finish()
+
+				return
+			}
+		}
+	}
+}
+
+func (gr *guildRecovery) finish() {
+	gr.mu.Lock()
+	gr.active = false
+	close(gr.done)
+	duration := time.Since(gr.started)
+	ingested := gr.ingested
+	gr.mu.Unlock()
+
+	gr.sh.Logger.Info().
+		Int("guilds", ingested).
+		Dur("duration", duration).
+		Msg("Finished guild recovery")
+}
+
+// Recovered returns a channel which is closed once the shard is no longer
+// recovering.
+func (gr *guildRecovery) Recovered() <-chan void {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	return gr.done
+}
+
+// API returns the recovery progress of the shard or nil if it is not
+// recovering.
+func (gr *guildRecovery) API() *structs.APIStatusGuildRecovery {
+	gr.mu.Lock()
+	if !gr.active {
+		gr.mu.Unlock()
+
+		return nil
+	}
+
+	status := &structs.APIStatusGuildRecovery{
+		Queued:   len(gr.queue) + gr.running,
+		Ingested: gr.ingested,
+	}
+	gr.mu.Unlock()
+
+	gr.sh.UnavailableMu.RLock()
+	for _, unavailable := range gr.sh.Unavailable {
+		if unavailable {
+			status.Unavailable++
+		}
+	}
+	gr.sh.UnavailableMu.RUnlock()
+
+	return status
+}
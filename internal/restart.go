@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"math/rand"
+	"strconv"
+
+	"golang.org/x/xerrors"
+)
+
+// Parts of a manager which can be rebuilt without restarting it.
+const (
+	rebuiltProducer   = "producer"
+	rebuiltClient     = "client"
+	rebuiltRestTunnel = "resttunnel"
+	rebuiltGateway    = "gateway"
+)
+
+// ErrManagerReplaced is returned when an operation was waiting on a manager
+// which has since been restarted or deleted.
+var ErrManagerReplaced = xerrors.New("manager was replaced whilst waiting for another operation to finish")
+
+// producerClientName returns the client name the producer connects with.
+// ConfigurationMu must be held.
+func (mg *Manager) producerClientName() string {
+	if mg.Configuration.Messaging.UseRandomSuffix {
+		return mg.Configuration.Messaging.ClientName + "-" + strconv.Itoa(rand.Intn(maxClientNumber)) //nolint:gosec
+	}
+
+	return mg.Configuration.Messaging.ClientName
+}
+
+// lockOperation acquires OperationMu and checks the manager is still the one
+// registered under its identifier. The returned function releases the lock.
+func (mg *Manager) lockOperation() (unlock func(), err error) {
+	mg.OperationMu.Lock()
+
+	mg.ConfigurationMu.RLock()
+	identifier := mg.Configuration.Identifier
+	mg.ConfigurationMu.RUnlock()
+
+	mg.Sandwich.ManagersMu.RLock()
+	current := mg.Sandwich.Managers[identifier]
+	mg.Sandwich.ManagersMu.RUnlock()
+
+	if current != mg {
+		mg.OperationMu.Unlock()
+
+		return nil, ErrManagerReplaced
+	}
+
+	return mg.OperationMu.Unlock, nil
+}
+
+// RestartProducer connects a new producer using the current configuration
+// and replaces the current one with it. Shards are not touched. The previous
+// producer is closed once it has been replaced so publishes are not held up
+// whilst it flushes. If the new producer fails to connect, the current one is
+// kept.
+func (mg *Manager) RestartProducer() (rebuilt []string, err error) {
+	unlock, err := mg.lockOperation()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	mg.Sandwich.ConfigurationMu.RLock()
+	producerType := mg.Sandwich.Configuration.Producer.Type
+	producerConfiguration := mg.Sandwich.Configuration.Producer.Configuration
+	mg.Sandwich.ConfigurationMu.RUnlock()
+
+	mg.ConfigurationMu.RLock()
+	clientName := mg.producerClientName()
+	mg.ConfigurationMu.RUnlock()
+
+	producerClient, err := NewMQClient(producerType)
+	if err != nil {
+		return nil, xerrors.Errorf("restart producer create: %w", err)
+	}
+
+	err = producerClient.Connect(mg.ctx, clientName, producerConfiguration)
+	if err != nil {
+		return nil, xerrors.Errorf("restart producer connect: %w", err)
+	}
+
+	mg.ConfigurationMu.Lock()
+	previous := mg.swapProducer(producerClient)
+	mg.subscribeGatewayCommands()
+	mg.subscribeStateQueries()
+	mg.subscribeAcks()
+	mg.ConfigurationMu.Unlock()
+
+	if previous != nil {
+		mg.closeProducer(previous)
+	}
+
+	mg.Logger.Info().Str("driver", producerClient.String()).Msg("Restarted producer")
+
+	return []string{rebuiltProducer}, nil
+}
+
+// ResetClient resolves the RestTunnel settings again, applies them to the
+// REST client and refreshes the gateway response. Shards are not touched.
+// Each step is attempted even if a previous one fails.
+func (mg *Manager) ResetClient() (rebuilt []string, errs []error) {
+	unlock, err := mg.lockOperation()
+	if err != nil {
+		return nil, []error{err}
+	}
+	defer unlock()
+
+	mg.Sandwich.ConfigurationMu.RLock()
+	restTunnelConfigured := mg.Sandwich.Configuration.RestTunnel.Enabled
+	restTunnelURL := mg.Sandwich.Configuration.RestTunnel.URL
+	mg.Sandwich.ConfigurationMu.RUnlock()
+
+	var reverse bool
+
+	if restTunnelConfigured {
+		var enabled bool
+
+		enabled, reverse, err = mg.Sandwich.VerifyRestTunnel(restTunnelURL)
+
+		switch {
+		case err != nil:
+			errs = append(errs, xerrors.Errorf("reset client resttunnel: %w", err))
+		case !enabled:
+			errs = append(errs, xerrors.New("reset client resttunnel: RestTunnel could not be reached"))
+		default:
+			rebuilt = append(rebuilt, rebuiltRestTunnel)
+		}
+
+		if !enabled {
+			restTunnelURL = ""
+		}
+	} else {
+		restTunnelURL = ""
+	}
+
+	mg.tokenMu.RLock()
+	token := mg.token
+	mg.tokenMu.RUnlock()
+
+	mg.Client.mu.Lock()
+	mg.Client.Token = token
+	mg.Client.restTunnelURL = restTunnelURL
+	mg.Client.reverse = reverse
+	mg.Client.mu.Unlock()
+
+	rebuilt = append(rebuilt, rebuiltClient)
+
+	gw, err := mg.GetGateway()
+	if err != nil {
+		errs = append(errs, xerrors.Errorf("reset client gateway: %w", err))
+	} else {
+		mg.GatewayMu.Lock()
+		mg.Gateway = gw
+		mg.GatewayMu.Unlock()
+
+		rebuilt = append(rebuilt, rebuiltGateway)
+	}
+
+	mg.Logger.Info().Strs("rebuilt", rebuilt).Int("errors", len(errs)).Msg("Reset REST client")
+
+	return rebuilt, errs
+}
@@ -0,0 +1,201 @@
+package gateway
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"github.com/rs/zerolog"
+)
+
+// closingProducer blocks in Close until release is closed.
+type closingProducer struct {
+	mqclients.NoneMQClient
+
+	closing chan void
+	release chan void
+}
+
+func (p *closingProducer) Close(ctx context.Context) (err error) {
+	close(p.closing)
+	<-p.release
+
+	return nil
+}
+
+func newRestartManager(t *testing.T, producerType string) *Manager {
+	t.Helper()
+
+	sg, err := newSandwich(ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to create sandwich: %v", err)
+	}
+
+	sg.Configuration.Producer.Type = producerType
+
+	mg := &Manager{
+		Sandwich:      sg,
+		Logger:        zerolog.Nop(),
+		Configuration: &ManagerConfiguration{Identifier: "test"},
+		ctx:           context.Background(),
+	}
+
+	sg.Managers[mg.Configuration.Identifier] = mg
+
+	return mg
+}
+
+func TestRestartProducerClosesPreviousAfterSwap(t *testing.T) {
+	mg := newRestartManager(t, "none")
+
+	previous := &closingProducer{closing: make(chan void), release: make(chan void)}
+	mg.swapProducer(previous)
+
+	done := make(chan error)
+
+	go func() {
+		_, err := mg.RestartProducer()
+		done <- err
+	}()
+
+	select {
+	case <-previous.closing:
+	case <-time.After(time.Second):
+		t.Fatal("previous producer was not closed")
+	}
+
+	// The new producer is in use whilst the previous one is still closing
+	// and the configuration can still be read.
+	if producer := mg.Producer(); producer == nil || producer == MQClient(previous) {
+		t.Errorf("producer was %v whilst the previous one closed", producer)
+	}
+
+	mg.ConfigurationMu.Lock()
+	mg.ConfigurationMu.Unlock()
+
+	close(previous.release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("RestartProducer returned %v", err)
+	}
+}
+
+func TestRestartProducerKeepsPreviousOnError(t *testing.T) {
+	mg := newRestartManager(t, "unknown")
+
+	previous := &mqclients.NoneMQClient{}
+	mg.swapProducer(previous)
+
+	if _, err := mg.RestartProducer(); err == nil {
+		t.Fatal("RestartProducer did not fail with an unknown producer")
+	}
+
+	if mg.Producer() != MQClient(previous) {
+		t.Error("previous producer was replaced after failing to restart")
+	}
+}
+
+// callRPC calls a registered RPC method as the test user and decodes the data
+// of its response into result.
+func callRPC(t *testing.T, mg *Manager, method string, event interface{},
+	result interface{}) (status int) {
+	t.Helper()
+
+	handler, ok := rpcHandlers[method]
+	if !ok {
+		t.Fatalf("%s is not registered", method)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+
+	rw := httptest.NewRecorder()
+	handler.f(mg.Sandwich, &structs.DiscordUser{Username: "test"}, structs.RPCRequest{Method: method, Data: data}, rw)
+
+	response := structs.BaseResponse{Data: result}
+	if err = json.Unmarshal(rw.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response was not valid json: %s", rw.Body.String())
+	}
+
+	return rw.Code
+}
+
+func TestRebuildKeepsShardsConnected(t *testing.T) {
+	mg, fake := newDiscordManager(t)
+	sh := onlyShard(t, mg)
+
+	conn, generation := sh.ws.Get()
+	connections, identifies := fake.Connections(), fake.Identifies()
+
+	rebuilds := []struct {
+		name    string
+		method  string
+		status  int
+		rebuilt []string
+	}{
+		{"producer restart", "manager:producer:restart", http.StatusOK, []string{rebuiltProducer}},
+		{"client reset", "manager:client:reset", http.StatusOK, []string{rebuiltRestTunnel, rebuiltClient, rebuiltGateway}},
+		// A failed rebuild still reports what it did and leaves shards alone.
+		{"failed producer restart", "manager:producer:restart", http.StatusInternalServerError, nil},
+	}
+
+	for _, rebuild := range rebuilds {
+		if rebuild.status != http.StatusOK {
+			mg.Sandwich.ConfigurationMu.Lock()
+			mg.Sandwich.Configuration.Producer.Type = "unknown"
+			mg.Sandwich.ConfigurationMu.Unlock()
+		}
+
+		result := structs.RPCManagerRebuildResponse{}
+
+		status := callRPC(t, mg, rebuild.method, structs.RPCManagerRebuildEvent{Manager: "test"}, &result)
+		if status != rebuild.status {
+			t.Errorf("%s: returned %d %+v, want %d", rebuild.name, status, result, rebuild.status)
+		}
+
+		if len(result.Rebuilt) != len(rebuild.rebuilt) || (rebuild.status != http.StatusOK) != (len(result.Errors) > 0) {
+			t.Errorf("%s: returned %+v, want %v rebuilt", rebuild.name, result, rebuild.rebuilt)
+		}
+
+		if current, currentGeneration := sh.ws.Get(); current != conn || currentGeneration != generation {
+			t.Errorf("%s: shard connection changed from generation %d to %d", rebuild.name, generation, currentGeneration)
+		}
+
+		if fake.Connections() != connections || fake.Identifies() != identifies {
+			t.Errorf("%s: shard reconnected %d times and identified %d times", rebuild.name,
+				fake.Connections()-connections, fake.Identifies()-identifies)
+		}
+
+		sh.StatusMu.RLock()
+		shardStatus := sh.Status
+		sh.StatusMu.RUnlock()
+
+		if shardStatus != structs.ShardReady {
+			t.Errorf("%s: shard is %s", rebuild.name, shardStatus.String())
+		}
+
+		// The connection is still read from.
+		seq := atomic.LoadInt64(sh.seq)
+
+		if _, err := fake.Dispatch("TYPING_START", map[string]interface{}{}); err != nil {
+			t.Fatalf("%s: failed to dispatch: %v", rebuild.name, err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for atomic.LoadInt64(sh.seq) == seq && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if atomic.LoadInt64(sh.seq) == seq {
+			t.Errorf("%s: shard did not read an event dispatched after rebuilding", rebuild.name)
+		}
+	}
+}
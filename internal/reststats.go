@@ -0,0 +1,198 @@
+package gateway
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+const (
+	// Most routes tracked by a manager. Requests to other routes are counted
+	// under restRouteOther.
+	maxRESTRoutes  = 256
+	restRouteOther = "other"
+
+	// Routes listed by /api/rest/routes if no limit is given.
+	defaultRESTRouteLimit = 25
+)
+
+var (
+	restVersionRegex   = regexp.MustCompile(`^/api(/v\d+)?`)
+	restSnowflakeRegex = regexp.MustCompile(`^\d{15,21}$`)
+)
+
+// restRouteStats counts the requests made to a single route.
+type restRouteStats struct {
+	requests    int64
+	errors      int64 // Responses which were not 2xx, including failed requests
+	rateLimited int64
+	latency     int64 // Total nanoseconds
+}
+
+// restStats counts the REST requests made by the client of a manager. They
+// are counted whether or not RestTunnel is used.
+type restStats struct {
+	requests     *int64
+	pending      *int64 // Requests since the analytics were last gathered
+	success      *int64
+	clientErrors *int64
+	rateLimited  *int64
+	serverErrors *int64
+	failed       *int64 // Requests which did not receive a response
+	latency      *int64 // Total nanoseconds
+
+	routesMu sync.Mutex
+	routes   map[string]*restRouteStats
+}
+
+func newRESTStats() *restStats {
+	return &restStats{
+		requests:     new(int64),
+		pending:      new(int64),
+		success:      new(int64),
+		clientErrors: new(int64),
+		rateLimited:  new(int64),
+		serverErrors: new(int64),
+		failed:       new(int64),
+		latency:      new(int64),
+
+		routesMu: sync.Mutex{},
+		routes:   make(map[string]*restRouteStats),
+	}
+}
+
+// restRoute templates a request path so requests to the same endpoint are
+// counted together. The version prefix is removed, snowflakes are replaced
+// with :id and webhook tokens and reaction emojis are hidden.
+func restRoute(method string, path string) string {
+	path = restVersionRegex.ReplaceAllString(path, "")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range segments {
+		switch {
+		case restSnowflakeRegex.MatchString(segment):
+			segments[i] = ":id"
+		case i >= 2 && (segments[i-2] == "webhooks" || segments[i-2] == "interactions"):
+			segments[i] = ":token"
+		case i >= 1 && segments[i-1] == "reactions":
+			segments[i] = ":emoji"
+		}
+	}
+
+	return method + " /" + strings.Join(segments, "/")
+}
+
+// record counts a request to route. A status of 0 means no response was
+// received. The stats may be nil for clients which are not owned by a manager.
+func (rs *restStats) record(route string, status int, latency time.Duration) {
+	if rs == nil {
+		return
+	}
+
+	atomic.AddInt64(rs.requests, 1)
+	atomic.AddInt64(rs.pending, 1)
+	atomic.AddInt64(rs.latency, int64(latency))
+
+	switch {
+	case status == 0:
+		atomic.AddInt64(rs.failed, 1)
+	case status == 429:
+		atomic.AddInt64(rs.rateLimited, 1)
+	case status >= 500:
+		atomic.AddInt64(rs.serverErrors, 1)
+	case status >= 400:
+		atomic.AddInt64(rs.clientErrors, 1)
+	case status >= 200 && status < 300:
+		atomic.AddInt64(rs.success, 1)
+	}
+
+	rs.routesMu.Lock()
+	defer rs.routesMu.Unlock()
+
+	stats, ok := rs.routes[route]
+	if !ok {
+		if len(rs.routes) >= maxRESTRoutes {
+			route = restRouteOther
+		}
+
+		if stats, ok = rs.routes[route]; !ok {
+			stats = &restRouteStats{}
+			rs.routes[route] = stats
+		}
+	}
+
+	stats.requests++
+	stats.latency += int64(latency)
+
+	if status < 200 || status >= 300 {
+		stats.errors++
+	}
+
+	if status == 429 {
+		stats.rateLimited++
+	}
+}
+
+// takePending returns the requests made since it was last called.
+func (rs *restStats) takePending() int64 {
+	return atomic.SwapInt64(rs.pending, 0)
+}
+
+// API returns the counters for the status and analytics endpoints.
+func (rs *restStats) API() structs.APIRESTStats {
+	result := structs.APIRESTStats{
+		Requests:     atomic.LoadInt64(rs.requests),
+		Success:      atomic.LoadInt64(rs.success),
+		ClientErrors: atomic.LoadInt64(rs.clientErrors),
+		RateLimited:  atomic.LoadInt64(rs.rateLimited),
+		ServerErrors: atomic.LoadInt64(rs.serverErrors),
+		Failed:       atomic.LoadInt64(rs.failed),
+	}
+
+	if result.Requests > 0 {
+		result.AverageLatency = time.Duration(atomic.LoadInt64(rs.latency) / result.Requests).Milliseconds()
+	}
+
+	return result
+}
+
+// Routes returns the routes with the most errors followed by the most
+// requests.
+func (rs *restStats) Routes(limit int) (routes []structs.APIRESTRoute) {
+	rs.routesMu.Lock()
+	routes = make([]structs.APIRESTRoute, 0, len(rs.routes))
+
+	for route, stats := range rs.routes {
+		routes = append(routes, structs.APIRESTRoute{
+			Route:          route,
+			Requests:       stats.requests,
+			Errors:         stats.errors,
+			RateLimited:    stats.rateLimited,
+			AverageLatency: time.Duration(stats.latency / stats.requests).Milliseconds(),
+		})
+	}
+	rs.routesMu.Unlock()
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Errors != routes[j].Errors {
+			return routes[i].Errors > routes[j].Errors
+		}
+
+		if routes[i].Requests != routes[j].Requests {
+			return routes[i].Requests > routes[j].Requests
+		}
+
+		return routes[i].Route < routes[j].Route
+	})
+
+	if limit > 0 && len(routes) > limit {
+		routes = routes[:limit]
+	}
+
+	return routes
+}
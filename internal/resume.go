@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+// resumeTracker counts the dispatches discord replays between a RESUME being
+// sent and RESUMED being received.
+type resumeTracker struct {
+	active        bool
+	sentAt        time.Time
+	sequence      int64 // Sequence sent in the RESUME
+	firstSequence int64 // Sequence of the first replayed dispatch
+	replayed      int64
+}
+
+// startResume begins counting replayed dispatches for a RESUME sent with seq.
+func (sh *Shard) startResume(seq int64) {
+	sh.resumeMu.Lock()
+	sh.resume = resumeTracker{
+		active:   true,
+		sentAt:   time.Now().UTC(),
+		sequence: seq,
+	}
+	sh.resumeMu.Unlock()
+}
+
+// cancelResume stops counting replayed dispatches if the RESUME could not
+// be sent.
+func (sh *Shard) cancelResume() {
+	sh.resumeMu.Lock()
+	sh.resume.active = false
+	sh.resumeMu.Unlock()
+}
+
+// recordResumeDispatch counts a dispatch received whilst resuming. This is
+// called by the websocket reader so dispatches after RESUMED are never
+// counted as replayed.
+func (sh *Shard) recordResumeDispatch(msg discord.ReceivedPayload, now time.Time) {
+	sh.resumeMu.Lock()
+
+	if !sh.resume.active {
+		sh.resumeMu.Unlock()
+
+		return
+	}
+
+	if msg.Type != "RESUMED" {
+		sh.resume.replayed++
+
+		if sh.resume.firstSequence == 0 {
+			sh.resume.firstSequence = msg.Sequence
+		}
+
+		sh.resumeMu.Unlock()
+
+		return
+	}
+
+	tracker := sh.resume
+	sh.resume.active = false
+	sh.resumeMu.Unlock()
+
+	resume := &structs.ShardResume{
+		At:            tracker.sentAt,
+		Duration:      now.Sub(tracker.sentAt),
+		Sequence:      tracker.sequence,
+		FirstSequence: tracker.firstSequence,
+		LastSequence:  msg.Sequence,
+		Replayed:      tracker.replayed,
+	}
+
+	// RESUMED carries the latest sequence of the session. Older gateways
+	// omit it so the last replayed dispatch is used instead.
+	if resume.LastSequence == 0 {
+		resume.LastSequence = atomic.LoadInt64(sh.seq)
+	}
+
+	if resume.LastSequence > resume.Sequence {
+		resume.Gap = resume.LastSequence - resume.Sequence
+	}
+
+	sh.sessionMu.Lock()
+	sh.session.Resumes++
+	sh.session.ReplayedEvents += resume.Replayed
+	sh.session.LastResume = resume
+	sh.sessionMu.Unlock()
+
+	atomic.AddInt64(sh.Manager.resumes, 1)
+	atomic.AddInt64(sh.Manager.replayedEvents, resume.Replayed)
+
+	sh.Logger.Debug().
+		Int64("sequence", resume.Sequence).
+		Int64("first_sequence", resume.FirstSequence).
+		Int64("gap", resume.Gap).
+		Int64("replayed", resume.Replayed).
+		Dur("duration", resume.Duration).
+		Msg("Resumed session")
+
+	threshold := sh.Manager.resumeGapWarning()
+	if threshold <= 0 || resume.Gap <= threshold {
+		return
+	}
+
+	sh.Logger.Warn().
+		Int64("gap", resume.Gap).
+		Int64("replayed", resume.Replayed).
+		Int64("threshold", threshold).
+		Msg("Resumed with a large sequence gap")
+
+	go sh.PublishNoisyWebhook(
+		"Resumed with a large sequence gap",
+		fmt.Sprintf("Resumed from sequence %d to %d, %d events behind. %d events were replayed in %s.",
+			resume.Sequence, resume.LastSequence, resume.Gap, resume.Replayed, resume.Duration.Round(time.Millisecond)),
+		discord.EmbedWarning, false)
+}
+
+// resumeGapWarning returns the sequence gap a resume can have before it is
+// warned about. 0 disables the warning.
+func (mg *Manager) resumeGapWarning() int64 {
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	return mg.Configuration.Bot.ResumeGapWarning
+}
+
+// Resumes returns how many sessions shards of the manager have resumed.
+func (mg *Manager) Resumes() int64 {
+	return atomic.LoadInt64(mg.resumes)
+}
+
+// ReplayedEvents returns how many dispatches discord has replayed to shards
+// of the manager when resuming.
+func (mg *Manager) ReplayedEvents() int64 {
+	return atomic.LoadInt64(mg.replayedEvents)
+}
@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"net/http"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,20 +14,81 @@ import (
 	structs "github.com/TheRockettek/Sandwich-Daemon/structs"
 	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
 	"github.com/rs/zerolog"
+	"golang.org/x/xerrors"
 )
 
-var rpcHandlers = make(map[string]func(sg *Sandwich, user *structs.DiscordUser,
-	req structs.RPCRequest, rw http.ResponseWriter) bool)
+// rpcHandler is a registered RPC method. example is the data the method
+// expects, which daemon:methods returns so callers know what to send.
+type rpcHandler struct {
+	description string
+	example     interface{}
+	f           func(sg *Sandwich, user *structs.DiscordUser, req structs.RPCRequest, rw http.ResponseWriter) bool
+}
+
+var rpcHandlers = make(map[string]rpcHandler)
+
+func registerHandler(method string, description string, example interface{},
+	f func(sg *Sandwich, user *structs.DiscordUser, req structs.RPCRequest, rw http.ResponseWriter) bool) {
+	rpcHandlers[method] = rpcHandler{
+		description: description,
+		example:     example,
+		f:           f,
+	}
+}
+
+// rpcMethods returns every registered RPC method sorted by name.
+func rpcMethods() (methods []structs.RPCMethod) {
+	methods = make([]structs.RPCMethod, 0, len(rpcHandlers))
+
+	for method, handler := range rpcHandlers {
+		methods = append(methods, structs.RPCMethod{
+			Method:      method,
+			Description: handler.description,
+			Example:     handler.example,
+		})
+	}
+
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Method < methods[j].Method })
 
-func registerHandler(method string, f func(sg *Sandwich, user *structs.DiscordUser,
-	req structs.RPCRequest, rw http.ResponseWriter) bool) {
-	rpcHandlers[method] = f
+	return methods
 }
 
 func executeRequest(sg *Sandwich, user *structs.DiscordUser,
 	req structs.RPCRequest, rw http.ResponseWriter) (ok bool) {
-	if f, ok := rpcHandlers[req.Method]; ok {
-		f(sg, user, req, rw)
+	if handler, ok := rpcHandlers[req.Method]; ok {
+		var success bool
+
+		recorder := &auditResponseWriter{ResponseWriter: rw, status: http.StatusOK}
+
+		if missing := sg.missingPermission(user, req.Method); missing != "" {
+			passResponse(recorder, deniedPermission+missing, false, http.StatusForbidden)
+		} else {
+			success = handler.f(sg, user, req, recorder)
+		}
+
+		sg.rpcLogEvent(user).
+			Str("method", req.Method).
+			Bool("success", success).
+			Int("status", recorder.status).
+			Msg("Executed RPC request")
+
+		parameters := sg.auditParameters(req.Data)
+
+		sg.Audit.Record(structs.AuditEntry{
+			Action:     req.Method,
+			User:       auditUser(user),
+			Manager:    auditManager(parameters),
+			Parameters: parameters,
+			Success:    success,
+			Status:     recorder.status,
+		})
+
+		if success && destructiveMethods[req.Method] {
+			go sg.Notify(context.Background(),
+				NewNotification("Called "+req.Method, "").
+					ByUser(user).
+					WithSeverity(SeverityWarning))
+		}
 
 		return true
 	}
@@ -34,6 +96,16 @@ func executeRequest(sg *Sandwich, user *structs.DiscordUser,
 	return false
 }
 
+// rpcLogEvent starts a log line for a RPC request with the user which made it.
+func (sg *Sandwich) rpcLogEvent(user *structs.DiscordUser) *zerolog.Event {
+	event := sg.Logger.Info()
+	if user != nil {
+		event = event.Int64("user_id", user.ID.Int64()).Str("user", user.Username)
+	}
+
+	return event
+}
+
 // RPCManagerShardGroupCreate handles the creation of a new shardgroup.
 func RPCManagerShardGroupCreate(sg *Sandwich, user *structs.DiscordUser,
 	req structs.RPCRequest, rw http.ResponseWriter) bool {
@@ -107,33 +179,32 @@ func RPCManagerShardGroupCreate(sg *Sandwich, user *structs.DiscordUser,
 			_shardIDs = append(_shardIDs, strconv.Itoa(shardID))
 		}
 
-		go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-			Username: user.Username,
-			AvatarURL: fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-				user.ID.String(), user.Avatar),
-			Embeds: []discord.Embed{
-				{
-					Title:       "Created new shardgroup",
-					Description: "Shards: " + strings.Join(_shardIDs, ", "),
-					Color:       discord.EmbedSandwich,
-					Timestamp:   WebhookTime(time.Now().UTC()),
-					Footer: &discord.EmbedFooter{
-						Text: fmt.Sprintf("Manager %s | ShardCount %d",
-							manager.Configuration.DisplayName, event.ShardCount),
-					},
-				},
-			},
-		})
-
-		_, err = manager.Scale(event.ShardIDs, event.ShardCount, true)
+		manager.GatewayMu.RLock()
+		duration := estimateShardGroupStart(event.ShardIDs, manager.Gateway.SessionStartLimit.MaxConcurrency)
+		manager.GatewayMu.RUnlock()
 
-		if err != nil {
-			passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+		job := sg.Jobs.Create(JobShardGroupCreate, event.Manager, user, event.ShardIDs, event.StartAt, duration)
 
-			return false
+		description := "Shards: " + strings.Join(_shardIDs, ", ")
+		if job.StartAt.After(job.Created) {
+			description += fmt.Sprintf("\nStarting at %s", job.StartAt.Format(time.RFC3339))
 		}
 
-		passResponse(rw, true, true, http.StatusOK)
+		go sg.Notify(context.Background(),
+			NewNotification("Created new shardgroup", description).
+				ForManager(manager).
+				ByUser(user).
+				WithFooter(fmt.Sprintf("ShardCount %d", event.ShardCount)))
+
+		go manager.runShardGroupCreate(job, event.ShardCount)
+
+		passResponse(rw, structs.RPCManagerShardGroupCreateResponse{
+			JobID:               job.ID,
+			ShardIDs:            job.ShardIDs,
+			ShardCount:          event.ShardCount,
+			StartAt:             job.StartAt,
+			EstimatedCompletion: job.EstimatedCompletion,
+		}, true, http.StatusOK)
 	} else {
 		passResponse(rw, fmt.Sprintf(
 			"Not enough sessions to start %d shard(s). %d remain",
@@ -146,6 +217,71 @@ func RPCManagerShardGroupCreate(sg *Sandwich, user *structs.DiscordUser,
 	return true
 }
 
+// RPCJobStatus returns the progress of a job or every job if no id is given.
+func RPCJobStatus(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCJobStatusEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	if event.ID == 0 {
+		jobs := make([]structs.Job, 0)
+		for _, job := range sg.Jobs.List() {
+			jobs = append(jobs, job.API())
+		}
+
+		passResponse(rw, jobs, true, http.StatusOK)
+
+		return true
+	}
+
+	job, ok := sg.Jobs.Get(event.ID)
+	if !ok {
+		passResponse(rw, ErrJobNotFound.Error(), false, http.StatusNotFound)
+
+		return false
+	}
+
+	passResponse(rw, job.API(), true, http.StatusOK)
+
+	return true
+}
+
+// RPCJobDismiss removes a finished job or cancels a scheduled one.
+func RPCJobDismiss(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCJobDismissEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	err = sg.Jobs.Dismiss(event.ID)
+
+	switch {
+	case xerrors.Is(err, ErrJobNotFound):
+		passResponse(rw, err.Error(), false, http.StatusNotFound)
+
+		return false
+	case err != nil:
+		passResponse(rw, err.Error(), false, http.StatusConflict)
+
+		return false
+	}
+
+	passResponse(rw, true, true, http.StatusOK)
+
+	return true
+}
+
 // RPCManagerShardGroupStop handles stopping a shardgroup.
 func RPCManagerShardGroupStop(sg *Sandwich, user *structs.DiscordUser,
 	req structs.RPCRequest, rw http.ResponseWriter) bool {
@@ -178,22 +314,10 @@ func RPCManagerShardGroupStop(sg *Sandwich, user *structs.DiscordUser,
 		return false
 	}
 
-	go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-		Username: user.Username,
-		AvatarURL: fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-			user.ID.String(), user.Avatar),
-		Embeds: []discord.Embed{
-			{
-				Title:     "Stopped shardgroup",
-				Color:     discord.EmbedSandwich,
-				Timestamp: WebhookTime(time.Now().UTC()),
-				Footer: &discord.EmbedFooter{
-					Text: fmt.Sprintf("Manager %s | ShardGroup %d",
-						manager.Configuration.DisplayName, event.ShardGroup),
-				},
-			},
-		},
-	})
+	go sg.Notify(context.Background(),
+		NewNotification("Stopped shardgroup", "").
+			ForShardGroup(shardgroup).
+			ByUser(user))
 
 	shardgroup.Close()
 	passResponse(rw, true, true, http.StatusOK)
@@ -252,6 +376,116 @@ func RPCManagerShardGroupDelete(sg *Sandwich, user *structs.DiscordUser,
 	return true
 }
 
+// RPCManagerShardGroupRoll replaces a shardgroup with a new one running the
+// same shards. The old shardgroup is closed and deleted once every shard of
+// the new one is ready. Progress is reported through the job returned.
+func RPCManagerShardGroupRoll(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerShardGroupRollEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	var shardgroup *ShardGroup
+
+	if event.ShardGroup != nil {
+		manager.ShardGroupsMu.RLock()
+		shardgroup = manager.ShardGroups[*event.ShardGroup]
+		manager.ShardGroupsMu.RUnlock()
+	} else {
+		shardgroup = manager.latestShardGroup()
+	}
+
+	if shardgroup == nil {
+		passResponse(rw, "Invalid shardgroup provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	shardCount := shardgroup.ShardCount
+	shardIDs := append([]int(nil), shardgroup.ShardIDs...)
+
+	if event.AutoShard {
+		manager.GatewayMu.Lock()
+		gw, err := manager.GetGateway()
+
+		if err != nil {
+			manager.Logger.Warn().Err(err).Msg("Received error retrieving gateway object. Using old response.")
+		} else {
+			manager.Gateway = gw
+		}
+
+		shardCount = manager.Gateway.Shards
+		manager.GatewayMu.Unlock()
+
+		if shardCount < 1 {
+			shardCount = 1
+		}
+
+		shardIDs = manager.GenerateShardIDs(shardCount)
+	}
+
+	if len(shardIDs) == 0 {
+		passResponse(rw, "ShardGroup has no shards to roll", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	manager.GatewayMu.RLock()
+	remaining := manager.Gateway.SessionStartLimit.Remaining
+	duration := estimateShardGroupStart(shardIDs, manager.Gateway.SessionStartLimit.MaxConcurrency)
+	manager.GatewayMu.RUnlock()
+
+	if len(shardIDs) >= remaining {
+		passResponse(rw, fmt.Sprintf(
+			"Not enough sessions to start %d shard(s). %d remain",
+			len(shardIDs), remaining,
+		), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	job := sg.Jobs.Create(JobShardGroupRoll, event.Manager, user, shardIDs, event.StartAt, duration)
+
+	description := fmt.Sprintf("Replacing ShardGroup %d", shardgroup.ID)
+	if job.StartAt.After(job.Created) {
+		description += fmt.Sprintf("\nStarting at %s", job.StartAt.Format(time.RFC3339))
+	}
+
+	go sg.Notify(context.Background(),
+		NewNotification("Rolling shardgroup", description).
+			ForManager(manager).
+			ByUser(user).
+			WithFooter(fmt.Sprintf("ShardCount %d", shardCount)))
+
+	go manager.runShardGroupRoll(job, shardCount, shardgroup)
+
+	passResponse(rw, structs.RPCManagerShardGroupRollResponse{
+		JobID:               job.ID,
+		Replacing:           shardgroup.ID,
+		ShardIDs:            job.ShardIDs,
+		ShardCount:          shardCount,
+		StartAt:             job.StartAt,
+		EstimatedCompletion: job.EstimatedCompletion,
+	}, true, http.StatusOK)
+
+	return true
+}
+
 // RPCManagerUpdate handles updating a managers configuration.
 func RPCManagerUpdate(sg *Sandwich, user *structs.DiscordUser,
 	req structs.RPCRequest, rw http.ResponseWriter) bool {
@@ -310,72 +544,1013 @@ func RPCManagerUpdate(sg *Sandwich, user *structs.DiscordUser,
 			manager.Sandwich.Configuration.Producer.Configuration,
 		)
 		if err == nil {
-			manager.ProducerClient = producerClient
+			if previous := manager.swapProducer(producerClient); previous != nil {
+				go manager.closeProducer(previous)
+			}
+		}
+	}
+
+	event.Events.EventBlacklist = NormalizeEventNames(event.Events.EventBlacklist)
+	event.Events.ProduceBlacklist = NormalizeEventNames(event.Events.ProduceBlacklist)
+	event.Events.IncludeBefore = NormalizeEventNames(event.Events.IncludeBefore)
+
+	manager.logIntentWarnings(&event)
+
+	manager.EventBlacklistMu.Lock()
+	if !reflect.DeepEqual(event.Events.EventBlacklist, manager.Configuration.Events.EventBlacklist) {
+		manager.EventBlacklist = manager.compileEventMatcher("event_blacklist", event.Events.EventBlacklist)
+	}
+	manager.EventBlacklistMu.Unlock()
+
+	manager.ProduceBlacklistMu.Lock()
+	if !reflect.DeepEqual(event.Events.ProduceBlacklist, manager.Configuration.Events.ProduceBlacklist) {
+		manager.ProduceBlacklist = manager.compileEventMatcher("produce_blacklist", event.Events.ProduceBlacklist)
+	}
+	manager.ProduceBlacklistMu.Unlock()
+
+	manager.IncludeBeforeMu.Lock()
+	if !reflect.DeepEqual(event.Events.IncludeBefore, manager.Configuration.Events.IncludeBefore) {
+		manager.IncludeBefore = manager.compileEventMatcher("include_before", event.Events.IncludeBefore)
+	}
+	manager.IncludeBeforeMu.Unlock()
+
+	// The mutuals would miss the members seen whilst disabled.
+	if !event.Caching.StoreMutuals {
+		manager.mutuals.reset()
+	}
+
+	event.Token = strings.TrimSpace(event.Token)
+
+	manager.Configuration = &event
+	manager.SetToken(manager.Configuration.Token)
+	manager.subscribeGatewayCommands()
+	manager.subscribeStateQueries()
+	manager.subscribeAcks()
+
+	// Updates the managers in the sandwich configuration
+	managers := []*ManagerConfiguration{}
+
+	for _, _manager := range sg.Configuration.Managers {
+		if _manager.Identifier == manager.Configuration.Identifier {
+			managers = append(managers, manager.Configuration)
+		} else {
+			managers = append(managers, _manager)
 		}
 	}
 
-	manager.EventBlacklistMu.Lock()
-	if !reflect.DeepEqual(event.Events.EventBlacklist, manager.Configuration.Events.EventBlacklist) {
-		manager.EventBlacklist = manager.Configuration.Events.EventBlacklist
+	sg.Configuration.Managers = managers
+
+	err = sg.SaveConfiguration(sg.Configuration, ConfigurationPath)
+
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	go sg.Notify(context.Background(),
+		NewNotification("Updated manager configuration", "").
+			ForManager(manager).
+			ByUser(user))
+
+	passResponse(rw, true, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerBlacklistGet handles returning a blacklist of a manager.
+func RPCManagerBlacklistGet(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerBlacklistEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	entries, version, err := manager.Blacklist(event.List)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	passResponse(rw, structs.RPCManagerBlacklistResponse{
+		List:    event.List,
+		Events:  entries,
+		Version: version,
+	}, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerBlacklistAdd handles adding events to a blacklist of a manager.
+func RPCManagerBlacklistAdd(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	return rpcManagerBlacklistModify(sg, user, req, rw, true)
+}
+
+// RPCManagerBlacklistRemove handles removing events from a blacklist of a manager.
+func RPCManagerBlacklistRemove(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	return rpcManagerBlacklistModify(sg, user, req, rw, false)
+}
+
+func rpcManagerBlacklistModify(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter, add bool) bool {
+	event := structs.RPCManagerBlacklistEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ConfigurationMu.Lock()
+	defer sg.ConfigurationMu.Unlock()
+
+	manager.ConfigurationMu.Lock()
+	defer manager.ConfigurationMu.Unlock()
+
+	var entries []string
+
+	var version string
+
+	if add {
+		entries, version, err = manager.ModifyBlacklist(event.List, event.Events, nil, event.Version)
+	} else {
+		entries, version, err = manager.ModifyBlacklist(event.List, nil, event.Events, event.Version)
+	}
+
+	switch {
+	case xerrors.Is(err, ErrBlacklistVersion):
+		passResponse(rw, err.Error(), false, http.StatusConflict)
+
+		return false
+	case err != nil:
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	err = sg.SaveConfiguration(sg.Configuration, ConfigurationPath)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	title := "Added to " + event.List + " blacklist"
+	if !add {
+		title = "Removed from " + event.List + " blacklist"
+	}
+
+	go sg.Notify(context.Background(),
+		NewNotification(title, strings.Join(event.Events, ", ")).
+			ForManager(manager).
+			ByUser(user))
+
+	passResponse(rw, structs.RPCManagerBlacklistResponse{
+		List:    event.List,
+		Events:  entries,
+		Version: version,
+	}, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerCapture handles starting a capture of the payloads a manager
+// produces.
+func RPCManagerCapture(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerCaptureEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	capture, err := manager.StartCapture(user, event.GuildID, event.Events,
+		time.Duration(event.Duration)*time.Second, event.MaxEvents)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusConflict)
+
+		return false
+	}
+
+	response := capture.API(event.Manager)
+
+	description := "All guilds"
+	if response.GuildID != 0 {
+		description = "Guild " + response.GuildID.String()
+	}
+
+	if len(response.Events) > 0 {
+		description += ": " + strings.Join(response.Events, ", ")
+	}
+
+	go sg.Notify(context.Background(),
+		NewNotification("Started event capture", description).
+			ByUser(user).
+			WithFooter(fmt.Sprintf("Manager %s until %s", event.Manager, response.End.Format(time.RFC3339))))
+
+	passResponse(rw, response, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerCaptureFetch handles returning and clearing the payloads buffered
+// by the capture of a manager.
+func RPCManagerCaptureFetch(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerCaptureFetchEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	capture, payloads, err := manager.FetchCapture()
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusNotFound)
+
+		return false
+	}
+
+	passResponse(rw, structs.RPCManagerCaptureFetchResponse{
+		Capture:  capture.API(event.Manager),
+		Payloads: payloads,
+	}, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerCreate handles the creation of new managers.
+func RPCManagerCreate(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerCreateEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	_displayName := event.Identifier
+	_identifier := strings.ReplaceAll(event.Identifier, " ", "")
+
+	sg.ManagersMu.RLock()
+	_, ok := sg.Managers[_identifier]
+	sg.ManagersMu.RUnlock()
+
+	if ok {
+		passResponse(rw, "Manager with this name already exists", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	config := &ManagerConfiguration{
+		Persist:     event.Persist,
+		Identifier:  _identifier,
+		DisplayName: _displayName,
+		Token:       event.Token,
+	}
+
+	// Default configuration things
+	config.Messaging.ClientName = event.Client
+	config.Messaging.ChannelName = event.Channel
+	config.Bot.DefaultPresence = &discord.UpdateStatus{}
+
+	config.Messaging.UseRandomSuffix = true
+	config.Bot.Retries = 2
+	config.Bot.Intents = 32511
+	config.Bot.Compression = true
+	config.Bot.LargeThreshold = 250
+	config.Sharding.ShardCount = 1
+	config.Bot.MaxHeartbeatFailures = 5
+
+	sg.ConfigurationMu.Lock()
+	sg.Configuration.Managers = append(sg.Configuration.Managers, config)
+
+	err = sg.SaveConfiguration(sg.Configuration, ConfigurationPath)
+	sg.ConfigurationMu.Unlock()
+
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	manager, err := sg.NewManager(config)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	sg.ManagersMu.Lock()
+	sg.Managers[config.Identifier] = manager
+	sg.ManagersMu.Unlock()
+
+	gw, err := manager.GetGateway()
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	manager.GatewayMu.Lock()
+	manager.Gateway = gw
+	manager.GatewayMu.Unlock()
+
+	err = manager.Open()
+
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	go sg.Notify(context.Background(),
+		NewNotification("Created new manager", "").
+			ForManager(manager).
+			ByUser(user))
+
+	passResponse(rw, true, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerDelete handles deleting managers.
+func RPCManagerDelete(sg *Sandwich, user *structs.DiscordUser, req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerDeleteEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	if event.Confirm != event.Manager {
+		passResponse(rw, "Incorrect confirm value. Must be equal to manager", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	unlock, err := manager.lockOperation()
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusConflict)
+
+		return false
+	}
+	defer unlock()
+
+	sg.rpcLogEvent(user).Str("manager", event.Manager).Msg("Deleting manager")
+
+	manager.Close()
+
+	sg.ManagersMu.Lock()
+	delete(sg.Managers, event.Manager)
+	sg.ManagersMu.Unlock()
+
+	managers := []*ManagerConfiguration{}
+
+	sg.ConfigurationMu.Lock()
+
+	for _, _manager := range sg.Configuration.Managers {
+		if _manager.Identifier != event.Manager {
+			managers = append(managers, _manager)
+		}
+	}
+
+	sg.Configuration.Managers = managers
+
+	err = sg.SaveConfiguration(sg.Configuration, ConfigurationPath)
+	sg.ConfigurationMu.Unlock()
+
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	passResponse(rw, true, true, http.StatusOK)
+
+	go sg.Notify(context.Background(),
+		NewNotification("Deleted manager", "").
+			ForManager(manager).
+			ByUser(user))
+
+	return true
+}
+
+// RPCManagerRestart handles restarting a manager.
+func RPCManagerRestart(sg *Sandwich, user *structs.DiscordUser, req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerRestartEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	if event.Confirm != event.Manager {
+		passResponse(rw, "Incorrect confirm value. Must be equal to manager", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	manager.Close()
+
+	sg.ManagersMu.Lock()
+	delete(sg.Managers, event.Manager)
+	sg.ManagersMu.Unlock()
+
+	manager, err = sg.NewManager(manager.Configuration)
+
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	sg.ManagersMu.Lock()
+	sg.Managers[event.Manager] = manager
+	sg.ManagersMu.Unlock()
+
+	gw, err := manager.GetGateway()
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	manager.GatewayMu.Lock()
+	manager.Gateway = gw
+	manager.GatewayMu.Unlock()
+
+	passResponse(rw, true, true, http.StatusOK)
+
+	go sg.Notify(context.Background(),
+		NewNotification("Created new manager", "").
+			ForManager(manager).
+			ByUser(user))
+
+	return true
+}
+
+// RPCManagerProducerRestart reconnects the producer of a manager without
+// restarting its shardgroups.
+func RPCManagerProducerRestart(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerRebuildEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	result := structs.RPCManagerRebuildResponse{
+		Errors: make([]string, 0),
+	}
+
+	result.Rebuilt, err = manager.RestartProducer()
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	sg.publishRebuildWebhook(user, manager, "Restarted producer", result)
+
+	if err != nil {
+		passResponse(rw, result, false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	passResponse(rw, result, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerClientReset rebuilds the REST client of a manager and refreshes
+// its gateway response without restarting its shardgroups.
+func RPCManagerClientReset(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerRebuildEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	result := structs.RPCManagerRebuildResponse{
+		Errors: make([]string, 0),
+	}
+
+	rebuilt, errs := manager.ResetClient()
+	result.Rebuilt = rebuilt
+
+	for _, err := range errs {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	sg.publishRebuildWebhook(user, manager, "Reset REST client", result)
+
+	if len(errs) > 0 {
+		passResponse(rw, result, false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	passResponse(rw, result, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerChunkRetry requeues guilds which have exhausted their chunk
+// retries.
+func RPCManagerChunkRetry(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerChunkRetryEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	requeued := manager.RequeueChunkFailures(event.GuildIDs)
+
+	manager.Logger.Info().
+		Str("user", user.Username).
+		Int("guilds", len(requeued)).
+		Msg("Requeued failed guild chunks")
+
+	passResponse(rw, structs.RPCManagerChunkRetryResponse{
+		Requeued: requeued,
+	}, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerGuildChunk chunks a guild on demand, such as after a consumer
+// misses it in the cache.
+func RPCManagerGuildChunk(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerGuildChunkEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	result, err := manager.RequestGuildChunks(event.GuildID, event.Wait)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	manager.Logger.Debug().
+		Str("user", user.Username).
+		Int64("guild_id", event.GuildID.Int64()).
+		Int("shard", result.ShardID).
+		Int("chunks", result.Chunks).
+		Msg("Chunked guild on demand")
+
+	passResponse(rw, result, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerMutualGuilds returns the guilds a user shares with a manager.
+func RPCManagerMutualGuilds(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerMutualGuildsEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	result, err := manager.MutualGuilds(event.UserID, event.Limit, event.After, event.Expand)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	passResponse(rw, result, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerVoiceStateUpdate joins, moves or leaves a voice channel in a guild
+// by sending a voice state update on the shard of the guild.
+func RPCManagerVoiceStateUpdate(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerVoiceStateUpdateEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	result, err := manager.UpdateVoiceState(discord.UpdateVoiceState{
+		GuildID:   event.GuildID,
+		ChannelID: event.ChannelID,
+		SelfMute:  event.SelfMute,
+		SelfDeaf:  event.SelfDeaf,
+	})
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	manager.Logger.Debug().
+		Str("user", user.Username).
+		Int64("guild_id", event.GuildID.Int64()).
+		Bool("leave", event.ChannelID == nil).
+		Int("shard", result.ShardID).
+		Msg("Sent voice state update")
+
+	passResponse(rw, result, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerUnackedRequeue publishes dead lettered events again and waits for
+// consumers to acknowledge them.
+func RPCManagerUnackedRequeue(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerUnackedEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	requeued := manager.RequeueDeadLetters(event.EventIDs)
+
+	manager.Logger.Info().
+		Str("user", user.Username).
+		Int("requeued", len(requeued)).
+		Msg("Requeued dead lettered events")
+
+	passResponse(rw, structs.RPCManagerUnackedResponse{EventIDs: requeued}, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerUnackedDiscard removes dead lettered events once they have been
+// dealt with.
+func RPCManagerUnackedDiscard(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerUnackedEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	discarded := manager.DiscardDeadLetters(event.EventIDs)
+
+	manager.Logger.Info().
+		Str("user", user.Username).
+		Int("discarded", len(discarded)).
+		Msg("Discarded dead lettered events")
+
+	passResponse(rw, structs.RPCManagerUnackedResponse{EventIDs: discarded}, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerErrorsReset resets the event error counters of a manager, such
+// as once a fix for a failing event type has been deployed.
+func RPCManagerErrorsReset(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerErrorsResetEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	reset := manager.ResetEventErrors(event.EventTypes)
+
+	manager.Logger.Info().
+		Str("user", user.Username).
+		Strs("events", event.EventTypes).
+		Int("reset", reset).
+		Msg("Reset event error counters")
+
+	passResponse(rw, reset, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerLeavePolicyEvaluate evaluates the leave policy of a manager
+// immediately instead of waiting for the daily evaluation.
+func RPCManagerLeavePolicyEvaluate(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerLeavePolicyEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	report, err := manager.EvaluateLeavePolicy(user, event.DryRun)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	if len(report.Errors) > 0 {
+		passResponse(rw, report, false, http.StatusInternalServerError)
+
+		return false
+	}
+
+	passResponse(rw, report, true, http.StatusOK)
+
+	return true
+}
+
+// RPCManagerRebalanceReport generates a rebalance report for a manager.
+func RPCManagerRebalanceReport(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerRebalanceReportEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
 	}
-	manager.EventBlacklistMu.Unlock()
 
-	manager.ProduceBlacklistMu.Lock()
-	if !reflect.DeepEqual(event.Events.ProduceBlacklist, manager.Configuration.Events.ProduceBlacklist) {
-		manager.ProduceBlacklist = manager.Configuration.Events.ProduceBlacklist
+	report, err := manager.GenerateRebalanceReport()
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
 	}
-	manager.ProduceBlacklistMu.Unlock()
 
-	manager.Configuration = &event
-	manager.Client.Token = manager.Configuration.Token
+	passResponse(rw, report, true, http.StatusOK)
 
-	// Updates the managers in the sandwich configuration
-	managers := []*ManagerConfiguration{}
+	return true
+}
 
-	for _, _manager := range sg.Configuration.Managers {
-		if _manager.Identifier == manager.Configuration.Identifier {
-			managers = append(managers, manager.Configuration)
-		} else {
-			managers = append(managers, _manager)
-		}
+// RPCManagerShardStatusUpdate changes the presence of the shards of a manager.
+func RPCManagerShardStatusUpdate(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerStatusUpdateEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
 	}
 
-	sg.Configuration.Managers = managers
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
 
-	err = sg.SaveConfiguration(sg.Configuration, ConfigurationPath)
+	if !ok {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
 
+	result, err := manager.UpdatePresence(event.ShardGroup, event.Shards, event.Presence)
 	if err != nil {
-		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
 
 		return false
 	}
 
-	go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-		Username: user.Username,
-		AvatarURL: fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-			user.ID.String(), user.Avatar),
-		Embeds: []discord.Embed{
-			{
-				Title:     "Updated manager configuration",
-				Color:     discord.EmbedSandwich,
-				Timestamp: WebhookTime(time.Now().UTC()),
-				Footer: &discord.EmbedFooter{
-					Text: fmt.Sprintf("Manager %s",
-						manager.Configuration.DisplayName),
-				},
-			},
-		},
-	})
+	manager.Logger.Info().
+		Str("user", user.Username).
+		Str("status", event.Presence.Status).
+		Int32("shardgroup", result.ShardGroup).
+		Int("shards", len(result.Shards)).
+		Int("updated", result.Updated).
+		Msg("Updated shard presence")
 
-	passResponse(rw, true, true, http.StatusOK)
+	passResponse(rw, result, true, http.StatusOK)
 
 	return true
 }
 
-// RPCManagerCreate handles the creation of new managers.
-func RPCManagerCreate(sg *Sandwich, user *structs.DiscordUser,
+// RPCManagerShardRestart restarts a single shard without affecting the rest
+// of the manager. The shard resumes its session unless force is set, which
+// discards the session so the shard identifies again. The response is sent
+// once the shard is ready or the timeout has elapsed.
+func RPCManagerShardRestart(sg *Sandwich, user *structs.DiscordUser,
 	req structs.RPCRequest, rw http.ResponseWriter) bool {
-	event := structs.RPCManagerCreateEvent{}
+	event := structs.RPCManagerShardRestartEvent{}
 
 	err := json.Unmarshal(req.Data, &event)
 	if err != nil {
@@ -384,106 +1559,108 @@ func RPCManagerCreate(sg *Sandwich, user *structs.DiscordUser,
 		return false
 	}
 
-	_displayName := event.Identifier
-	_identifier := strings.ReplaceAll(event.Identifier, " ", "")
+	if event.Timeout < 1 {
+		event.Timeout = defaultShardRestartTimeout
+	}
 
 	sg.ManagersMu.RLock()
-	_, ok := sg.Managers[_identifier]
+	manager, ok := sg.Managers[event.Manager]
 	sg.ManagersMu.RUnlock()
 
-	if ok {
-		passResponse(rw, "Manager with this name already exists", false, http.StatusBadRequest)
+	if !ok {
+		passResponse(rw, ErrInvalidManager.Error(), false, http.StatusBadRequest)
 
 		return false
 	}
 
-	config := &ManagerConfiguration{
-		Persist:     event.Persist,
-		Identifier:  _identifier,
-		DisplayName: _displayName,
-		Token:       event.Token,
+	shard, err := manager.shard(event.ShardGroup, event.Shard)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
 	}
 
-	// Default configuration things
-	config.Messaging.ClientName = event.Client
-	config.Messaging.ChannelName = event.Channel
-	config.Bot.DefaultPresence = &discord.UpdateStatus{}
+	shard.Logger.Info().
+		Str("user", user.Username).
+		Bool("force", event.Force).
+		Msg("Restarting shard")
 
-	config.Messaging.UseRandomSuffix = true
-	config.Bot.Retries = 2
-	config.Bot.Intents = 32511
-	config.Bot.Compression = true
-	config.Bot.LargeThreshold = 250
-	config.Sharding.ShardCount = 1
-	config.Bot.MaxHeartbeatFailures = 5
+	result := structs.RPCManagerShardRestartResponse{
+		ShardGroup: shard.ShardGroup.ID,
+		ShardID:    shard.ShardID,
+	}
 
-	sg.ConfigurationMu.Lock()
-	sg.Configuration.Managers = append(sg.Configuration.Managers, config)
+	result.Ready, err = shard.restart(event.Force, time.Duration(event.Timeout)*time.Second)
+	if err != nil {
+		result.Error = err.Error()
+	}
 
-	err = sg.SaveConfiguration(sg.Configuration, ConfigurationPath)
-	sg.ConfigurationMu.Unlock()
+	shard.StatusMu.RLock()
+	result.Status = shard.Status
+	shard.StatusMu.RUnlock()
 
-	if err != nil {
-		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+	passResponse(rw, result, true, http.StatusOK)
 
-		return false
-	}
+	return true
+}
 
-	manager, err := sg.NewManager(config)
+// RPCManagerShardSend sends a raw gateway payload through a shard. This is
+// an escape hatch for ops Sandwich does not support yet.
+func RPCManagerShardSend(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerShardSendEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
 	if err != nil {
-		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
 
 		return false
 	}
 
-	sg.ManagersMu.Lock()
-	sg.Managers[config.Identifier] = manager
-	sg.ManagersMu.Unlock()
+	sg.ManagersMu.RLock()
+	manager, ok := sg.Managers[event.Manager]
+	sg.ManagersMu.RUnlock()
 
-	gw, err := manager.GetGateway()
-	if err != nil {
-		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+	if !ok {
+		passResponse(rw, ErrInvalidManager.Error(), false, http.StatusBadRequest)
 
 		return false
 	}
 
-	manager.GatewayMu.Lock()
-	manager.Gateway = gw
-	manager.GatewayMu.Unlock()
+	shard, err := manager.shard(event.ShardGroup, event.Shard)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
 
-	err = manager.Open()
+		return false
+	}
 
+	sentAt, err := shard.SendRaw(event.Op, event.Data, event.AllowDangerous)
 	if err != nil {
-		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
 
 		return false
 	}
 
-	go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-		Username: user.Username,
-		AvatarURL: fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-			user.ID.String(), user.Avatar),
-		Embeds: []discord.Embed{
-			{
-				Title:     "Created new manager",
-				Color:     discord.EmbedSandwich,
-				Timestamp: WebhookTime(time.Now().UTC()),
-				Footer: &discord.EmbedFooter{
-					Text: fmt.Sprintf("Manager %s",
-						manager.Configuration.DisplayName),
-				},
-			},
-		},
-	})
+	shard.Logger.Info().
+		Str("user", user.Username).
+		Int("op", int(event.Op)).
+		Bool("allow_dangerous", event.AllowDangerous).
+		Msg("Sent raw gateway payload")
 
-	passResponse(rw, true, true, http.StatusOK)
+	passResponse(rw, structs.RPCManagerShardSendResponse{
+		ShardGroup: shard.ShardGroup.ID,
+		ShardID:    shard.ShardID,
+		SentAt:     sentAt,
+	}, true, http.StatusOK)
 
 	return true
 }
 
-// RPCManagerDelete handles deleting managers.
-func RPCManagerDelete(sg *Sandwich, user *structs.DiscordUser, req structs.RPCRequest, rw http.ResponseWriter) bool {
-	event := structs.RPCManagerDeleteEvent{}
+// RPCShardLogLevel escalates the logger of a shard or reverts it to its
+// normal level.
+func RPCShardLogLevel(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCShardLogLevelEvent{}
 
 	err := json.Unmarshal(req.Data, &event)
 	if err != nil {
@@ -492,8 +1669,8 @@ func RPCManagerDelete(sg *Sandwich, user *structs.DiscordUser, req structs.RPCRe
 		return false
 	}
 
-	if event.Confirm != event.Manager {
-		passResponse(rw, "Incorrect confirm value. Must be equal to manager", false, http.StatusBadRequest)
+	if event.Duration < 0 {
+		passResponse(rw, "Duration cannot be negative", false, http.StatusBadRequest)
 
 		return false
 	}
@@ -508,58 +1685,44 @@ func RPCManagerDelete(sg *Sandwich, user *structs.DiscordUser, req structs.RPCRe
 		return false
 	}
 
-	manager.Close()
-
-	sg.ManagersMu.Lock()
-	delete(sg.Managers, event.Manager)
-	sg.ManagersMu.Unlock()
-
-	managers := []*ManagerConfiguration{}
-
-	sg.ConfigurationMu.Lock()
+	shard, err := manager.shard(event.ShardGroup, event.Shard)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
 
-	for _, _manager := range sg.Configuration.Managers {
-		if _manager.Identifier != event.Manager {
-			managers = append(managers, _manager)
-		}
+		return false
 	}
 
-	sg.Configuration.Managers = managers
+	if event.Level == "" {
+		shard.Logger.Reset()
+		passResponse(rw, shard.Logger.Status(), true, http.StatusOK)
 
-	err = sg.SaveConfiguration(sg.Configuration, ConfigurationPath)
-	sg.ConfigurationMu.Unlock()
+		return true
+	}
 
+	level, err := zerolog.ParseLevel(event.Level)
 	if err != nil {
-		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+		passResponse(rw, "Invalid level provided", false, http.StatusBadRequest)
 
 		return false
 	}
 
-	passResponse(rw, true, true, http.StatusOK)
+	duration := time.Duration(event.Duration) * time.Second
+	if duration == 0 {
+		duration = sg.shardLogSettings().duration
+	}
 
-	go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-		Username: user.Username,
-		AvatarURL: fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-			user.ID.String(), user.Avatar),
-		Embeds: []discord.Embed{
-			{
-				Title:     "Deleted manager",
-				Color:     discord.EmbedSandwich,
-				Timestamp: WebhookTime(time.Now().UTC()),
-				Footer: &discord.EmbedFooter{
-					Text: fmt.Sprintf("Manager %s",
-						manager.Configuration.DisplayName),
-				},
-			},
-		},
-	})
+	shard.Logger.Escalate(level, duration, "set by "+user.Username, true)
+
+	passResponse(rw, shard.Logger.Status(), true, http.StatusOK)
 
 	return true
 }
 
-// RPCManagerRestart handles restarting a manager.
-func RPCManagerRestart(sg *Sandwich, user *structs.DiscordUser, req structs.RPCRequest, rw http.ResponseWriter) bool {
-	event := structs.RPCManagerRestartEvent{}
+// RPCManagerAffinitySet overrides the affinity tag of guilds whilst they are
+// migrated between managers.
+func RPCManagerAffinitySet(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCManagerAffinityEvent{}
 
 	err := json.Unmarshal(req.Data, &event)
 	if err != nil {
@@ -568,8 +1731,14 @@ func RPCManagerRestart(sg *Sandwich, user *structs.DiscordUser, req structs.RPCR
 		return false
 	}
 
-	if event.Confirm != event.Manager {
-		passResponse(rw, "Incorrect confirm value. Must be equal to manager", false, http.StatusBadRequest)
+	if len(event.GuildIDs) == 0 {
+		passResponse(rw, "No guilds provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	if event.TTL < 0 {
+		passResponse(rw, "TTL cannot be negative", false, http.StatusBadRequest)
 
 		return false
 	}
@@ -584,55 +1753,41 @@ func RPCManagerRestart(sg *Sandwich, user *structs.DiscordUser, req structs.RPCR
 		return false
 	}
 
-	manager.Close()
-
-	sg.ManagersMu.Lock()
-	delete(sg.Managers, event.Manager)
-	sg.ManagersMu.Unlock()
-
-	manager, err = sg.NewManager(manager.Configuration)
+	event.Tag = strings.TrimSpace(event.Tag)
+	active := manager.SetGuildAffinity(event.GuildIDs, event.Tag, time.Duration(event.TTL)*time.Second)
 
-	if err != nil {
-		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+	manager.Logger.Info().
+		Str("user", user.Username).
+		Str("tag", event.Tag).
+		Int("guilds", len(event.GuildIDs)).
+		Int("active", active).
+		Msg("Updated guild affinity overrides")
 
-		return false
-	}
+	passResponse(rw, structs.RPCManagerAffinityResponse{
+		Updated: len(event.GuildIDs),
+		Active:  active,
+	}, true, http.StatusOK)
 
-	sg.ManagersMu.Lock()
-	sg.Managers[event.Manager] = manager
-	sg.ManagersMu.Unlock()
+	return true
+}
 
-	gw, err := manager.GetGateway()
-	if err != nil {
-		passResponse(rw, err.Error(), false, http.StatusInternalServerError)
+// publishRebuildWebhook sends a webhook describing what was rebuilt on a
+// manager and any errors.
+func (sg *Sandwich) publishRebuildWebhook(user *structs.DiscordUser, manager *Manager,
+	title string, result structs.RPCManagerRebuildResponse) {
+	severity := SeverityInfo
+	description := "Rebuilt: " + strings.Join(result.Rebuilt, ", ")
 
-		return false
+	if len(result.Errors) > 0 {
+		severity = SeverityWarning
+		description += "\nErrors:\n" + strings.Join(result.Errors, "\n")
 	}
 
-	manager.GatewayMu.Lock()
-	manager.Gateway = gw
-	manager.GatewayMu.Unlock()
-
-	passResponse(rw, true, true, http.StatusOK)
-
-	go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-		Username: user.Username,
-		AvatarURL: fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-			user.ID.String(), user.Avatar),
-		Embeds: []discord.Embed{
-			{
-				Title:     "Created new manager",
-				Color:     discord.EmbedSandwich,
-				Timestamp: WebhookTime(time.Now().UTC()),
-				Footer: &discord.EmbedFooter{
-					Text: fmt.Sprintf("Manager %s",
-						manager.Configuration.DisplayName),
-				},
-			},
-		},
-	})
-
-	return true
+	go sg.Notify(context.Background(),
+		NewNotification(title, description).
+			ForManager(manager).
+			ByUser(user).
+			WithSeverity(severity))
 }
 
 // RPCManagerRefreshGateway handles refreshing the gateway.
@@ -723,6 +1878,14 @@ func RPCDaemonUpdate(sg *Sandwich, user *structs.DiscordUser,
 
 	event.Managers = configuration.Managers
 
+	sg.ConfigurationMu.RLock()
+	restoreSecrets(&event, sg.Configuration)
+	sg.ConfigurationMu.RUnlock()
+
+	sg.rpcLogEvent(user).Msg("Updating daemon configuration")
+
+	sg.logProducerWarnings(&event)
+
 	err = sg.SaveConfiguration(&event, ConfigurationPath)
 
 	if err != nil {
@@ -777,23 +1940,16 @@ func RPCDaemonUpdate(sg *Sandwich, user *structs.DiscordUser,
 		sg.Logger.Info().
 			Str("lvl", sg.Configuration.Logging.Level).
 			Msg("Changed logging level")
-		zerolog.SetGlobalLevel(zlLevel)
+		setLogLevel(zlLevel)
 	}
 
+	sg.storeShardLogSettings(nil)
+
 	passResponse(rw, true, true, http.StatusOK)
 
-	go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-		Username: user.Username,
-		AvatarURL: fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-			user.ID.String(), user.Avatar),
-		Embeds: []discord.Embed{
-			{
-				Title:     "Updated daemon configuration",
-				Color:     discord.EmbedSandwich,
-				Timestamp: WebhookTime(time.Now().UTC()),
-			},
-		},
-	})
+	go sg.Notify(context.Background(),
+		NewNotification("Updated daemon configuration", "").
+			ByUser(user))
 
 	return true
 }
@@ -841,18 +1997,9 @@ func RPCDaemonAddWebhook(sg *Sandwich, user *structs.DiscordUser,
 
 	passResponse(rw, true, true, http.StatusOK)
 
-	go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-		Username: user.Username,
-		AvatarURL: fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-			user.ID.String(), user.Avatar),
-		Embeds: []discord.Embed{
-			{
-				Title:     "Added new webhook",
-				Color:     discord.EmbedSandwich,
-				Timestamp: WebhookTime(time.Now().UTC()),
-			},
-		},
-	})
+	go sg.Notify(context.Background(),
+		NewNotification("Added new webhook", "").
+			ByUser(user))
 
 	return true
 }
@@ -915,37 +2062,186 @@ func RPCDaemonRemoveWebhook(sg *Sandwich, user *structs.DiscordUser,
 
 	passResponse(rw, true, true, http.StatusOK)
 
-	go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-		Username: user.Username,
-		AvatarURL: fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-			user.ID.String(), user.Avatar),
-		Embeds: []discord.Embed{
-			{
-				Title:     "Removed webhook",
-				Color:     discord.EmbedSandwich,
-				Timestamp: WebhookTime(time.Now().UTC()),
-			},
-		},
-	})
+	go sg.Notify(context.Background(),
+		NewNotification("Removed webhook", "").
+			ByUser(user))
+
+	return true
+}
+
+// RPCDaemonMaintenance starts or ends maintenance for one or all managers.
+func RPCDaemonMaintenance(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	event := structs.RPCDaemonMaintenanceEvent{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	duration := time.Duration(event.Duration) * time.Second
+	if duration < 0 || duration > maxMaintenanceDuration {
+		passResponse(rw, fmt.Sprintf("Duration must be between 0 and %d seconds",
+			int64(maxMaintenanceDuration.Seconds())), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	managers := make([]*Manager, 0)
+
+	sg.ManagersMu.RLock()
+	if event.Manager == "" {
+		for _, manager := range sg.Managers {
+			managers = append(managers, manager)
+		}
+	} else if manager, ok := sg.Managers[event.Manager]; ok {
+		managers = append(managers, manager)
+	}
+	sg.ManagersMu.RUnlock()
+
+	if len(managers) == 0 {
+		passResponse(rw, "Invalid manager provided", false, http.StatusBadRequest)
+
+		return false
+	}
+
+	for _, manager := range managers {
+		manager.SetMaintenance(duration, event.Reason)
+	}
+
+	passResponse(rw, true, true, http.StatusOK)
+
+	return true
+}
+
+// RPCDaemonMethods lists the RPC methods with what each one does and an
+// example of the data it expects.
+func RPCDaemonMethods(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	passResponse(rw, rpcMethods(), true, http.StatusOK)
+
+	return true
+}
+
+// RPCDaemonValidate handles returning warnings about manager configurations.
+// If a manager configuration is provided, only it will be validated.
+func RPCDaemonValidate(sg *Sandwich, user *structs.DiscordUser,
+	req structs.RPCRequest, rw http.ResponseWriter) bool {
+	if len(req.Data) == 0 || string(req.Data) == "null" {
+		passResponse(rw, sg.ValidateConfiguration(), true, http.StatusOK)
+
+		return true
+	}
+
+	event := ManagerConfiguration{}
+
+	err := json.Unmarshal(req.Data, &event)
+	if err != nil {
+		passResponse(rw, err.Error(), false, http.StatusBadRequest)
+
+		return false
+	}
+
+	warnings := append(make([]structs.ConfigurationWarning, 0), event.IntentWarnings()...)
+	warnings = append(warnings, event.EventNameWarnings()...)
+	warnings = append(warnings, sg.managerChannelWarnings(&event)...)
+
+	passResponse(rw, warnings, true, http.StatusOK)
 
 	return true
 }
 
 func init() {
-	registerHandler("manager:update", RPCManagerUpdate)
-	registerHandler("manager:create", RPCManagerCreate)
-	registerHandler("manager:delete", RPCManagerDelete)
-	registerHandler("manager:restart", RPCManagerRestart)
-	registerHandler("manager:refresh_gateway", RPCManagerRefreshGateway)
-
-	registerHandler("manager:shardgroup:create", RPCManagerShardGroupCreate)
-	registerHandler("manager:shardgroup:stop", RPCManagerShardGroupStop)
-	registerHandler("manager:shardgroup:delete", RPCManagerShardGroupDelete)
-
-	registerHandler("daemon:verify_resttunnel", RPCDaemonVerifyRestTunnel)
-	registerHandler("daemon:update", RPCDaemonUpdate)
-
-	registerHandler("daemon:add_webhook", RPCDaemonAddWebhook)
-	registerHandler("daemon:test_webhook", RPCDaemonTestWebhook)
-	registerHandler("daemon:remove_webhook", RPCDaemonRemoveWebhook)
+	registerHandler("manager:update", "Replaces the configuration of a manager",
+		ManagerConfiguration{}, RPCManagerUpdate)
+	registerHandler("manager:create", "Creates a new manager",
+		structs.RPCManagerCreateEvent{}, RPCManagerCreate)
+	registerHandler("manager:delete", "Closes and removes a manager. confirm must equal manager",
+		structs.RPCManagerDeleteEvent{}, RPCManagerDelete)
+	registerHandler("manager:restart", "Restarts a manager",
+		structs.RPCManagerRestartEvent{}, RPCManagerRestart)
+	registerHandler("manager:refresh_gateway", "Fetches the gateway response of a manager again",
+		structs.RPCManagerRefreshGatewayEvent{}, RPCManagerRefreshGateway)
+	registerHandler("manager:producer:restart", "Reconnects the producer of a manager without touching shards",
+		structs.RPCManagerRebuildEvent{}, RPCManagerProducerRestart)
+	registerHandler("manager:client:reset", "Rebuilds the REST client of a manager without touching shards",
+		structs.RPCManagerRebuildEvent{}, RPCManagerClientReset)
+
+	registerHandler("manager:blacklist:get", "Returns a blacklist of a manager",
+		structs.RPCManagerBlacklistEvent{}, RPCManagerBlacklistGet)
+	registerHandler("manager:blacklist:add", "Adds events to a blacklist of a manager",
+		structs.RPCManagerBlacklistEvent{}, RPCManagerBlacklistAdd)
+	registerHandler("manager:blacklist:remove", "Removes events from a blacklist of a manager",
+		structs.RPCManagerBlacklistEvent{}, RPCManagerBlacklistRemove)
+
+	registerHandler("manager:capture", "Starts capturing the payloads a manager produces",
+		structs.RPCManagerCaptureEvent{}, RPCManagerCapture)
+	registerHandler("manager:capture:fetch", "Returns and clears the payloads buffered by a capture",
+		structs.RPCManagerCaptureFetchEvent{}, RPCManagerCaptureFetch)
+
+	registerHandler("manager:chunk_failures:retry", "Requeues guilds which have exhausted their chunk retries",
+		structs.RPCManagerChunkRetryEvent{}, RPCManagerChunkRetry)
+	registerHandler("manager:guild:chunk", "Requests the members of a guild",
+		structs.RPCManagerGuildChunkEvent{}, RPCManagerGuildChunk)
+	registerHandler("manager:user:mutual_guilds", "Lists the guilds a user shares with a manager",
+		structs.RPCManagerMutualGuildsEvent{}, RPCManagerMutualGuilds)
+	registerHandler("manager:guild:voice_state", "Joins, moves or leaves a voice channel in a guild",
+		structs.RPCManagerVoiceStateUpdateEvent{}, RPCManagerVoiceStateUpdate)
+	registerHandler("manager:unacked:requeue", "Publishes dead lettered events again",
+		structs.RPCManagerUnackedEvent{}, RPCManagerUnackedRequeue)
+	registerHandler("manager:unacked:discard", "Removes dead lettered events",
+		structs.RPCManagerUnackedEvent{}, RPCManagerUnackedDiscard)
+	registerHandler("manager:errors:reset", "Resets the event error counters of a manager",
+		structs.RPCManagerErrorsResetEvent{}, RPCManagerErrorsReset)
+	registerHandler("manager:leave_policy:evaluate", "Evaluates the leave policy of a manager immediately",
+		structs.RPCManagerLeavePolicyEvent{}, RPCManagerLeavePolicyEvaluate)
+	registerHandler("manager:affinity:set", "Overrides the affinity tag of guilds whilst they are migrated",
+		structs.RPCManagerAffinityEvent{}, RPCManagerAffinitySet)
+	registerHandler("manager:rebalance_report", "Generates a report of the load of each shard",
+		structs.RPCManagerRebalanceReportEvent{}, RPCManagerRebalanceReport)
+	registerHandler("manager:shard:status_update", "Updates the presence of shards",
+		structs.RPCManagerStatusUpdateEvent{}, RPCManagerShardStatusUpdate)
+	registerHandler("manager:shard:restart", "Reconnects a single shard, resuming unless force is set",
+		structs.RPCManagerShardRestartEvent{}, RPCManagerShardRestart)
+	registerHandler("manager:shard:send", "Sends a raw gateway payload through a shard",
+		structs.RPCManagerShardSendEvent{}, RPCManagerShardSend)
+
+	registerHandler("manager:shardgroup:create", "Starts a new shardgroup as a job",
+		structs.RPCManagerShardGroupCreateEvent{}, RPCManagerShardGroupCreate)
+	registerHandler("manager:shardgroup:stop", "Closes a shardgroup",
+		structs.RPCManagerShardGroupStopEvent{}, RPCManagerShardGroupStop)
+	registerHandler("manager:shardgroup:delete", "Removes a closed shardgroup",
+		structs.RPCManagerShardGroupDeleteEvent{}, RPCManagerShardGroupDelete)
+	registerHandler("manager:shardgroup:roll", "Replaces a shardgroup with a new one as a job",
+		structs.RPCManagerShardGroupRollEvent{}, RPCManagerShardGroupRoll)
+
+	registerHandler("shard:log_level", "Escalates the log level of a shard or reverts it",
+		structs.RPCShardLogLevelEvent{}, RPCShardLogLevel)
+
+	registerHandler("job:status", "Returns the progress of a job or every job if id is 0",
+		structs.RPCJobStatusEvent{}, RPCJobStatus)
+	registerHandler("daemon:job:status", "Alias of job:status",
+		structs.RPCJobStatusEvent{}, RPCJobStatus)
+	registerHandler("job:dismiss", "Removes a finished job or cancels a scheduled one",
+		structs.RPCJobDismissEvent{}, RPCJobDismiss)
+
+	registerHandler("daemon:methods", "Lists the RPC methods and the data each expects",
+		nil, RPCDaemonMethods)
+	registerHandler("daemon:verify_resttunnel", "Checks if RestTunnel can be reached",
+		nil, RPCDaemonVerifyRestTunnel)
+	registerHandler("daemon:update", "Replaces the daemon configuration. Managers are kept",
+		SandwichConfiguration{}, RPCDaemonUpdate)
+	registerHandler("daemon:maintenance", "Starts or ends maintenance for one or all managers",
+		structs.RPCDaemonMaintenanceEvent{}, RPCDaemonMaintenance)
+	registerHandler("daemon:validate", "Returns warnings about manager configurations",
+		ManagerConfiguration{}, RPCDaemonValidate)
+
+	registerHandler("daemon:add_webhook", "Tests a webhook then adds it. data is the URL",
+		"", RPCDaemonAddWebhook)
+	registerHandler("daemon:test_webhook", "Sends a test message to a webhook URL. data is the URL",
+		"", RPCDaemonTestWebhook)
+	registerHandler("daemon:remove_webhook", "Removes a webhook URL. data is the URL",
+		"", RPCDaemonRemoveWebhook)
 }
@@ -24,7 +24,10 @@ import (
 	gatewayServer "github.com/TheRockettek/Sandwich-Daemon/protobuf"
 	"github.com/TheRockettek/Sandwich-Daemon/structs"
 	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/fasthttp/websocket"
+	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/sessions"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/tevino/abool"
@@ -55,6 +58,10 @@ const (
 	// Samples to hold. 5 seconds and 720 samples is 1 hour.
 	Samples = 720
 
+	// Samples of produced messages to hold. These are taken every second so
+	// 60 samples provides the throughput of the last minute.
+	producedSamples = 60
+
 	// distCacheDuration is the amount of hours to cache dist files.
 	// This is 720 (1 month) by default.
 	distCacheDuration = 720
@@ -84,8 +91,29 @@ type SandwichConfiguration struct {
 
 		MinimalWebhooks bool `json:"minimal_webhooks" yaml:"minimal_webhooks"`
 		// If enabled, webhooks for status changes will use one liners instead of an embed.
+
+		// Lowers the level of a shard's logger for a while when it starts
+		// reconnecting or logs errors quickly.
+		ShardEscalation struct {
+			Enabled        bool   `json:"enabled" yaml:"enabled"`
+			Level          string `json:"level" yaml:"level"`                     // Level escalated shards log at.
+			Duration       int    `json:"duration" yaml:"duration"`               // Seconds before the level reverts.
+			ErrorThreshold int    `json:"error_threshold" yaml:"error_threshold"` // Errors within a minute which escalate.
+			BufferSize     int    `json:"buffer_size" yaml:"buffer_size"`         // Lines kept per shard. 0 disables.
+		} `json:"shard_escalation" yaml:"shard_escalation"`
 	} `json:"logging" yaml:"logging"`
 
+	// Recent RPC calls and automatic actions are always kept in memory.
+	// When enabled, they are also written to Filename.
+	Audit struct {
+		Enabled    bool   `json:"enabled" yaml:"enabled"`
+		Filename   string `json:"filename" yaml:"filename"`       // Path of the audit log.
+		MaxSize    int    `json:"max_size" yaml:"max_size"`       // Size in MB before a new file.
+		MaxBackups int    `json:"max_backups" yaml:"max_backups"` // Number of files to keep.
+		MaxAge     int    `json:"max_age" yaml:"max_age"`         // Number of days to keep a file.
+		QueueSize  int    `json:"queue_size" yaml:"queue_size"`   // Entries waiting to be written before new ones are dropped.
+	} `json:"audit" yaml:"audit"`
+
 	RestTunnel struct {
 		Enabled bool   `json:"enabled" yaml:"enabled"`
 		URL     string `json:"url" yaml:"url"`
@@ -94,8 +122,48 @@ type SandwichConfiguration struct {
 	Producer struct {
 		Type          string                 `json:"type" yaml:"type"`
 		Configuration map[string]interface{} `json:"configuration" yaml:"configuration"`
+
+		// Epoch in milliseconds used when generating event IDs. Defaults to the discord epoch.
+		EventIDEpoch int64 `json:"event_id_epoch" yaml:"event_id_epoch"`
+
+		// Buffers payloads of each manager in memory when publishing fails
+		// and publishes them again in order once the broker is back. The
+		// oldest payloads are dropped once Size payloads are buffered or
+		// they have been buffered for MaxAge seconds.
+		RetryBuffer struct {
+			Enabled bool `json:"enabled" yaml:"enabled"`
+			Size    int  `json:"size" yaml:"size"`
+			MaxAge  int  `json:"max_age" yaml:"max_age"`
+		} `json:"retry_buffer" yaml:"retry_buffer"`
 	} `json:"producer" yaml:"producer"`
 
+	Caching struct {
+		Backend   string `json:"backend" yaml:"backend"`       // memory or redis
+		CacheSize int    `json:"cache_size" yaml:"cache_size"` // Objects kept in memory when using redis
+
+		// Limits on the members held in memory. Members unused for
+		// MemberTTL seconds are removed, as are the least recently used
+		// members of guilds with more than MaxMembersPerGuild and the least
+		// recently used members overall once more than MaxMembers are held.
+		// 0 disables a limit. When using redis, evicted members are still
+		// stored there. Cached presences are limited by MemberTTL and
+		// MaxMembers as well.
+		MemberTTL          int `json:"member_ttl" yaml:"member_ttl"`
+		MaxMembersPerGuild int `json:"max_members_per_guild" yaml:"max_members_per_guild"`
+		MaxMembers         int `json:"max_members" yaml:"max_members"`
+
+		Redis struct {
+			Address  string `json:"address" yaml:"address"`
+			Password string `json:"password" yaml:"password"`
+			DB       int    `json:"db" yaml:"db"`
+			Prefix   string `json:"prefix" yaml:"prefix"`
+
+			// Seconds shard sessions are kept in redis so they can resume
+			// after a restart. Negative disables persisting sessions.
+			SessionTTL int `json:"session_ttl" yaml:"session_ttl"`
+		} `json:"redis" yaml:"redis"`
+	} `json:"caching" yaml:"caching"`
+
 	GRPC struct {
 		Network string `json:"network" yaml:"network"`
 		Host    string `json:"host" yaml:"host"`
@@ -106,12 +174,69 @@ type SandwichConfiguration struct {
 		SessionSecret string `json:"secret" yaml:"secret"`
 		Enabled       bool   `json:"enabled" yaml:"enabled"`
 		Public        bool   `json:"public" yaml:"public"`
+
+		// RPC methods that can be called by anyone when public mode is enabled.
+		// All other methods still require an elevated user.
+		PublicAllowedMethods []string `json:"public_allowed_methods" yaml:"public_allowed_methods"`
+
+		// External URL of the dashboard including any sub-path it is hosted
+		// under behind a reverse proxy, such as https://example.com/sandwich/.
+		BaseURL string `json:"base_url" yaml:"base_url"`
+
+		// Use X-Forwarded-Proto and X-Forwarded-Host to build the external URL
+		// when BaseURL is not set. Only enable this behind a trusted proxy.
+		TrustProxyHeaders bool `json:"trust_proxy_headers" yaml:"trust_proxy_headers"`
+
+		// Identifiers of the managers /readyz checks. Every manager is
+		// checked when empty.
+		ReadinessManagers []string `json:"readiness_managers" yaml:"readiness_managers"`
+
+		// Origins allowed to open the dashboard websockets and to make
+		// cross-origin requests to the API. Entries can be a full origin such
+		// as https://example.com, a host such as example.com:8080 which
+		// matches any scheme, a wildcard subdomain such as *.example.com or
+		// "*" which allows any origin to make requests without credentials.
+		// "*" never allows websockets as they use the session cookie.
+		AllowedOrigins []string `json:"allowed_origins" yaml:"allowed_origins"`
+
+		// Tokens which are elevated when sent as "Authorization: Bearer <token>"
+		// to /api/* and /api/rpc.
+		APITokens []APIToken `json:"api_tokens" yaml:"api_tokens"`
 	} `json:"http" yaml:"http"`
 
+	// When Threshold percent of at least MinShards shards disconnect within
+	// Window seconds, the daemon treats it as a discord incident. Reconnects
+	// are spread out over BackoffMultiplier times longer, member requests
+	// wait and shard webhooks are replaced with a summary sent every
+	// UpdateInterval seconds. The incident ends once Recovery percent of
+	// shards are ready. A negative threshold disables detection.
+	Incident struct {
+		Threshold         float64 `json:"threshold" yaml:"threshold"`
+		Window            int     `json:"window" yaml:"window"`
+		Recovery          float64 `json:"recovery" yaml:"recovery"`
+		MinShards         int     `json:"min_shards" yaml:"min_shards"`
+		BackoffMultiplier float64 `json:"backoff_multiplier" yaml:"backoff_multiplier"`
+		UpdateInterval    int     `json:"update_interval" yaml:"update_interval"`
+	} `json:"incident" yaml:"incident"`
+
+	// Seconds Shutdown waits for dispatches to be handled before producers
+	// are closed.
+	ShutdownTimeout int `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+
+	// Seconds finished RPC jobs are kept before they are removed if they
+	// have not been dismissed.
+	JobRetention int `json:"job_retention" yaml:"job_retention"`
+
 	Webhooks      []string       `json:"webhooks" yaml:"webhooks"`
 	ElevatedUsers []string       `json:"elevated_users" yaml:"elevated_users"`
 	OAuth         *oauth2.Config `json:"oauth" yaml:"oauth"`
 
+	// RPC methods users can call by user id. Users listed here are not
+	// elevated. They can call methods matching one of their patterns, such
+	// as "manager:*", and use read-only endpoints. Users in ElevatedUsers
+	// can call every method.
+	Permissions map[string][]string `json:"permissions" yaml:"permissions"`
+
 	Managers []*ManagerConfiguration `json:"managers" yaml:"managers"`
 }
 
@@ -129,11 +254,46 @@ type Sandwich struct {
 	RestTunnelReverse abool.AtomicBool `json:"-"`
 	RestTunnelEnabled abool.AtomicBool `json:"-"`
 
+	// Set once Shutdown has been called. HTTP requests are refused.
+	shuttingDown abool.AtomicBool
+
+	// Cancelled by Shutdown to stop the background runners.
+	ctx    context.Context
+	cancel func()
+
+	grpcServerMu sync.Mutex
+	grpcServer   *grpc.Server
+
 	ManagersMu sync.RWMutex        `json:"-"`
 	Managers   map[string]*Manager `json:"-"`
 
+	// Serializes assigning messaging.worker_id to new managers.
+	workerIDsMu sync.Mutex
+
 	TotalEvents *int64 `json:"-"`
 
+	// Most recent *structs.APIAnalyticsResult, refreshed by analyticsCacheRunner.
+	analytics atomic.Value
+
+	// Shared channel warnings which have already been sent to webhooks.
+	channelWarningsMu sync.Mutex
+	channelWarnings   map[string]void
+
+	// Times ready shards disconnected within the incident window.
+	disconnectsMu sync.Mutex
+	disconnects   []time.Time
+
+	// Current gateway incident and those which have ended. incidentClear is
+	// closed whilst there is no incident.
+	incidentMu      sync.RWMutex
+	incident        *structs.Incident
+	incidentHistory []structs.Incident
+	incidentClear   chan void
+	incidentUpdated time.Time
+
+	// shardLogSettings of logging.shard_escalation.
+	shardLog atomic.Value
+
 	// Buckets will be shared between all Managers
 	Buckets *bucketstore.BucketStore `json:"-"`
 
@@ -143,16 +303,27 @@ type Sandwich struct {
 	// State
 	State *SandwichState `json:"-"`
 
+	Audit *AuditLogger `json:"-"`
+
+	// Long running actions started through RPC.
+	Jobs *JobStore `json:"-"`
+
 	Router *methodrouter.MethodRouter `json:"-"`
 	Store  *sessions.CookieStore      `json:"-"`
 
 	distHandler fasthttp.RequestHandler
 	fs          *fasthttp.FS
+	upgrader    websocket.FastHTTPUpgrader
 
 	ConsolePump *consolepump.ConsolePump `json:"-"`
 
 	Pool        *limiter.ConcurrencyLimiter `json:"-"`
 	PoolWaiting *int64                      `json:"-"`
+
+	// Listeners which receive produced payloads in-process.
+	hooksMu   sync.RWMutex
+	hooks     []eventHookEntry
+	hooksIter int64
 }
 
 // SandwichState stores the collective state for all ShardGroups
@@ -167,18 +338,86 @@ type SandwichState struct {
 	ChannelsMu sync.RWMutex                      `json:"-"`
 	Channels   map[snowflake.ID]*discord.Channel `json:"-"`
 
-	RolesMu sync.RWMutex                   `json:"-"`
-	Roles   map[snowflake.ID]*discord.Role `json:"-"`
+	// Roles of each guild by guild ID then role ID.
+	RolesMu sync.RWMutex                                    `json:"-"`
+	Roles   map[snowflake.ID]map[snowflake.ID]*discord.Role `json:"-"`
 
 	EmojisMu sync.RWMutex                    `json:"-"`
 	Emojis   map[snowflake.ID]*discord.Emoji `json:"-"`
 
+	// Active threads of each guild by guild ID then thread ID. Threads are
+	// only held in memory.
+	ThreadsMu sync.RWMutex                                       `json:"-"`
+	Threads   map[snowflake.ID]map[snowflake.ID]*discord.Channel `json:"-"`
+
 	UsersMu sync.RWMutex                   `json:"-"`
 	Users   map[snowflake.ID]*discord.User `json:"-"`
+
+	// Voice states of each guild by guild ID then user ID. Voice states are
+	// only held in memory.
+	VoiceStatesMu sync.RWMutex                                          `json:"-"`
+	VoiceStates   map[snowflake.ID]map[snowflake.ID]*discord.VoiceState `json:"-"`
+
+	// Last known presences by user ID when caching.cache_presences is
+	// enabled. Presences are only held in memory.
+	PresencesMu sync.RWMutex                            `json:"-"`
+	Presences   map[snowflake.ID]*discord.StatePresence `json:"-"`
+
+	// When using the redis backend, state is written through to redis and
+	// the lru decides what is kept in memory.
+	redis *stateRedis
+	lru   *lru.Cache
+
+	// Limits on the members held in memory.
+	memberEviction *memberEviction
 }
 
-// NewSandwich creates the application state and initializes it.
+// NewSandwich creates the application state and initializes it with the
+// configuration at ConfigurationPath.
 func NewSandwich(logger io.Writer) (sg *Sandwich, err error) {
+	sg, err = newSandwich(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	sg.Lock()
+	defer sg.Unlock()
+
+	configuration, err := sg.LoadConfiguration(ConfigurationPath)
+	if err != nil {
+		return nil, xerrors.Errorf("new sandwich: %w", err)
+	}
+
+	sg.configure(logger, configuration)
+
+	return sg, nil
+}
+
+// NewSandwichWithConfiguration creates the application state from a
+// configuration instead of reading it from ConfigurationPath. This is used
+// when sandwich is embedded in another program. The configuration is
+// normalized but is still saved to ConfigurationPath if it is changed
+// through RPC.
+func NewSandwichWithConfiguration(logger io.Writer, configuration *SandwichConfiguration) (sg *Sandwich, err error) {
+	sg, err = newSandwich(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	sg.Lock()
+	defer sg.Unlock()
+
+	err = sg.NormalizeConfiguration(configuration)
+	if err != nil {
+		return nil, xerrors.Errorf("new sandwich: %w", err)
+	}
+
+	sg.configure(logger, configuration)
+
+	return sg, nil
+}
+
+func newSandwich(logger io.Writer) (sg *Sandwich, err error) {
 	sg = &Sandwich{
 		Logger:          zerolog.New(logger).With().Timestamp().Logger(),
 		ConfigurationMu: sync.RWMutex{},
@@ -190,16 +429,34 @@ func NewSandwich(logger io.Writer) (sg *Sandwich, err error) {
 		State:           NewSandwichState(),
 		Pool:            limiter.NewConcurrencyLimiter("eventPool", poolConcurrency),
 		PoolWaiting:     new(int64),
+
+		channelWarningsMu: sync.Mutex{},
+		channelWarnings:   make(map[string]void),
+
+		disconnectsMu: sync.Mutex{},
+
+		incidentMu:      sync.RWMutex{},
+		incidentHistory: make([]structs.Incident, 0),
+		incidentClear:   make(chan void),
+
+		hooksMu: sync.RWMutex{},
 	}
 
-	sg.Lock()
-	defer sg.Unlock()
+	sg.ctx, sg.cancel = context.WithCancel(context.Background())
 
-	configuration, err := sg.LoadConfiguration(ConfigurationPath)
+	// There is no incident to begin with.
+	close(sg.incidentClear)
+
+	sg.Jobs, err = NewJobStore()
 	if err != nil {
 		return nil, xerrors.Errorf("new sandwich: %w", err)
 	}
 
+	return sg, nil
+}
+
+// configure stores the configuration and sets up logging from it.
+func (sg *Sandwich) configure(logger io.Writer, configuration *SandwichConfiguration) {
 	sg.ConfigurationMu.Lock()
 	defer sg.ConfigurationMu.Unlock()
 
@@ -212,11 +469,12 @@ func NewSandwich(logger io.Writer) (sg *Sandwich, err error) {
 		sg.Logger.Warn().
 			Str("lvl", sg.Configuration.Logging.Level).
 			Msg("Current zerolog level provided is not valid")
+		setLogLevel(zerolog.GlobalLevel())
 	} else {
 		sg.Logger.Info().
 			Str("lvl", sg.Configuration.Logging.Level).
 			Msg("Changed logging level")
-		zerolog.SetGlobalLevel(zlLevel)
+		setLogLevel(zlLevel)
 	}
 
 	if sg.Configuration.Logging.ConsoleLoggingEnabled {
@@ -253,10 +511,9 @@ func NewSandwich(logger io.Writer) (sg *Sandwich, err error) {
 	}
 
 	mw := io.MultiWriter(writers...)
-	sg.Logger = zerolog.New(mw).With().Timestamp().Logger()
+	sg.Logger = zerolog.New(mw).Sample(levelSampler{}).With().Timestamp().Logger()
+	sg.storeShardLogSettings(mw)
 	sg.Logger.Info().Msg("Logging configured")
-
-	return sg, nil
 }
 
 // LoadConfiguration loads the sandwich configuration.
@@ -349,6 +606,16 @@ func (sg *Sandwich) SaveConfiguration(configuration *SandwichConfiguration, path
 
 	configuration.Managers = storedManagers
 
+	var previous *SandwichConfiguration
+
+	if file, err := ioutil.ReadFile(path); err == nil {
+		previous = &SandwichConfiguration{}
+
+		if err = yaml.Unmarshal(file, previous); err != nil {
+			previous = nil
+		}
+	}
+
 	data, err := yaml.Marshal(configuration)
 	if err != nil {
 		return xerrors.Errorf("save configuration marshal: %w", err)
@@ -356,10 +623,19 @@ func (sg *Sandwich) SaveConfiguration(configuration *SandwichConfiguration, path
 
 	err = ioutil.WriteFile(path, data, 0o600)
 
+	sg.Audit.Record(structs.AuditEntry{
+		Action:  "configuration:save",
+		Success: err == nil,
+		Summary: configurationDiff(previous, configuration),
+	})
+
 	if err != nil {
 		return xerrors.Errorf("save configuration write: %w", err)
 	}
 
+	// This runs in the background as callers often hold the manager locks.
+	go sg.notifyChannelCollisions()
+
 	return nil
 }
 
@@ -389,6 +665,12 @@ func (sg *Sandwich) NormalizeConfiguration(configuration *SandwichConfiguration)
 		return xerrors.Errorf("Configuration missing GRPC host. Try 127.0.0.1:10000")
 	}
 
+	if configuration.HTTP.BaseURL != "" {
+		if _, err := parseBaseURL(configuration.HTTP.BaseURL); err != nil {
+			return xerrors.Errorf("Configuration has invalid HTTP base url: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -417,13 +699,20 @@ func (sg *Sandwich) Open() (err error) {
 		"         **-____-**\n",
 		VERSION, sg.Configuration.HTTP.Host, len(sg.Configuration.Managers), "┬─┬ ノ( ゜-゜ノ)")
 
+	sg.logProducerWarnings(sg.Configuration)
+
 	// Check if HTTP is enabled and if it is, set it up.
 	if sg.Configuration.HTTP.Enabled {
 		if sg.Configuration.HTTP.Public {
 			sg.Logger.Warn().Msg(
-				"Public mode is enabled on the HTTP API. This can allow anyone to" +
-					"get bot credentials if exposed publicly. It is recommended you disable" +
+				"Public mode is enabled on the HTTP API. This can allow anyone to " +
+					"get bot credentials if exposed publicly. It is recommended you disable " +
 					"this and add trusted user ids in sandwich.yaml under \"elevated_users\"")
+
+			if len(sg.Configuration.HTTP.PublicAllowedMethods) > 0 {
+				sg.Logger.Warn().Strs("methods", sg.Configuration.HTTP.PublicAllowedMethods).
+					Msg("Public mode allows anyone to call these RPC methods")
+			}
 		}
 
 		sg.Logger.Info().Msg("Starting up http server")
@@ -438,6 +727,8 @@ func (sg *Sandwich) Open() (err error) {
 		sg.distHandler = sg.fs.NewRequestHandler()
 
 		sg.Store = sessions.NewCookieStore([]byte(sg.Configuration.HTTP.SessionSecret))
+		sg.configureSessionStore()
+		sg.upgrader = sg.newUpgrader()
 
 		sg.Logger.Info().Msg("Creating endpoints")
 		sg.Router = createEndpoints(sg)
@@ -454,6 +745,14 @@ func (sg *Sandwich) Open() (err error) {
 		sg.Logger.Info().Msg("The web interface will not start as HTTP is disabled in the configuration")
 	}
 
+	var opts []grpc.ServerOption
+	grpcServer := grpc.NewServer(opts...)
+	gatewayServer.RegisterGatewayServer(grpcServer, sg.NewGatewayServer())
+
+	sg.grpcServerMu.Lock()
+	sg.grpcServer = grpcServer
+	sg.grpcServerMu.Unlock()
+
 	go func() {
 		lis, err := net.Listen(sg.Configuration.GRPC.Network, sg.Configuration.GRPC.Host)
 		if err != nil {
@@ -462,10 +761,6 @@ func (sg *Sandwich) Open() (err error) {
 			return
 		}
 
-		var opts []grpc.ServerOption
-		grpcServer := grpc.NewServer(opts...)
-		gatewayServer.RegisterGatewayServer(grpcServer, sg.NewGatewayServer())
-
 		sg.Logger.Info().Msgf("Serving gRPC on %s (Press CTRL+C to quit)\n", sg.Configuration.GRPC.Host)
 
 		err = grpcServer.Serve(lis)
@@ -489,24 +784,66 @@ func (sg *Sandwich) Open() (err error) {
 		sg.RestTunnelEnabled.UnSet()
 	}
 
-	go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-		Embeds: []discord.Embed{
-			{
-				Title:       "Starting up Sandwich-Daemon",
-				URL:         "https://github.com/TheRockettek/Sandwich-Daemon",
-				Description: fmt.Sprintf("Version %s", VERSION),
-				Color:       discord.EmbedSandwich,
-				Timestamp:   WebhookTime(time.Now().UTC()),
-			},
-		},
-	})
+	go sg.Notify(context.Background(),
+		NewNotification("Starting up Sandwich-Daemon", fmt.Sprintf("Version %s", VERSION)).
+			WithURL("https://github.com/TheRockettek/Sandwich-Daemon"))
+
+	switch sg.Configuration.Caching.Backend {
+	case "", StateBackendMemory:
+	case StateBackendRedis:
+		sg.Logger.Info().Str("address", sg.Configuration.Caching.Redis.Address).Msg("Using redis state backend")
+
+		err = sg.State.UseRedis(context.Background(), &redis.Options{
+			Addr:     sg.Configuration.Caching.Redis.Address,
+			Password: sg.Configuration.Caching.Redis.Password,
+			DB:       sg.Configuration.Caching.Redis.DB,
+		}, sg.Configuration.Caching.Redis.Prefix, sg.Configuration.Caching.CacheSize)
+		if err != nil {
+			return xerrors.Errorf("sandwich open state: %w", err)
+		}
+	default:
+		return xerrors.Errorf("sandwich open state: unknown backend %s", sg.Configuration.Caching.Backend)
+	}
+
+	sg.State.ConfigureMemberEviction(
+		sg.Configuration.Caching.MemberTTL,
+		sg.Configuration.Caching.MaxMembersPerGuild,
+		sg.Configuration.Caching.MaxMembers,
+	)
+
+	// Recent entries are always kept in memory for /api/audit. They are only
+	// written to a file when the audit log is enabled.
+	auditFilename := ""
+	if sg.Configuration.Audit.Enabled {
+		auditFilename = sg.Configuration.Audit.Filename
+	}
+
+	sg.Audit, err = NewAuditLogger(
+		sg.Logger,
+		auditFilename,
+		sg.Configuration.Audit.MaxSize,
+		sg.Configuration.Audit.MaxBackups,
+		sg.Configuration.Audit.MaxAge,
+		sg.Configuration.Audit.QueueSize,
+	)
+	if err != nil {
+		return xerrors.Errorf("sandwich open audit: %w", err)
+	}
+
+	go sg.auditRunner()
 
 	sg.Logger.Info().Msg("Creating managers")
 
 	sg.startManagers()
+	sg.notifyChannelCollisions()
 
 	go sg.gatherAnalytics()
 	go sg.analyticsRunner()
+	go sg.analyticsCacheRunner()
+	go sg.jobRunner()
+	go sg.memberEvictionRunner()
+	go sg.maintenanceRunner()
+	go sg.incidentRunner()
 
 	return nil
 }
@@ -527,19 +864,10 @@ func (sg *Sandwich) startManagers() {
 				Str("identifier", managerConfiguration.Identifier).
 				Msg("Found conflicting manager identifiers. Ignoring!")
 
-			go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-				Embeds: []discord.Embed{
-					{
-						Title:     "Found conflicting manager identifiers. Ignoring!",
-						Color:     discord.EmbedWarning,
-						Timestamp: WebhookTime(time.Now().UTC()),
-						Footer: &discord.EmbedFooter{
-							Text: fmt.Sprintf("Manager %s",
-								manager.Configuration.DisplayName),
-						},
-					},
-				},
-			})
+			go sg.Notify(context.Background(),
+				NewNotification("Found conflicting manager identifiers. Ignoring!", "").
+					ForManager(manager).
+					WithSeverity(SeverityWarning))
 
 			continue
 		}
@@ -555,61 +883,14 @@ func (sg *Sandwich) startManagers() {
 			sg.Logger.Error().Err(err).Msg("Failed to start up manager")
 		} else if manager.Configuration.AutoStart {
 			go func() {
-				manager.Gateway, err = manager.GetGateway()
-				if err != nil {
-					manager.Logger.Error().Err(err).Msg("Failed to start up manager")
-
-					return
-				}
-
-				manager.GatewayMu.RLock()
-				manager.Logger.Info().
-					Int("sessions", manager.Gateway.SessionStartLimit.Remaining).
-					Msg("Retrieved gateway information")
-
-				shardCount := manager.GatherShardCount()
-				if shardCount >= manager.Gateway.SessionStartLimit.Remaining {
-					manager.Logger.Error().Err(ErrSessionLimitExhausted).Msg("Failed to start up manager")
-					manager.GatewayMu.RUnlock()
-
-					go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-						Embeds: []discord.Embed{
-							{
-								Title: fmt.Sprintf("Failed to start up manager as not enough sessions to start %d shard(s). %d remain",
-									shardCount, manager.Gateway.SessionStartLimit.Remaining),
-								Color:     discord.EmbedDanger,
-								Timestamp: WebhookTime(time.Now().UTC()),
-								Footer: &discord.EmbedFooter{
-									Text: fmt.Sprintf("Manager %s",
-										manager.Configuration.DisplayName),
-								},
-							},
-						},
-					})
-
-					return
-				}
-
-				manager.GatewayMu.RUnlock()
-
-				ready, err := manager.Scale(manager.GenerateShardIDs(shardCount), shardCount, true)
+				ready, err := manager.StartShards()
 				if err != nil {
 					manager.Logger.Error().Err(err).Msg("Failed to start up manager")
 
-					go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-						Embeds: []discord.Embed{
-							{
-								Title:       "Failed to start up manager",
-								Description: err.Error(),
-								Color:       discord.EmbedDanger,
-								Timestamp:   WebhookTime(time.Now().UTC()),
-								Footer: &discord.EmbedFooter{
-									Text: fmt.Sprintf("Manager %s",
-										manager.Configuration.DisplayName),
-								},
-							},
-						},
-					})
+					go sg.Notify(context.Background(),
+						NewNotification("Failed to start up manager", err.Error()).
+							ForManager(manager).
+							WithSeverity(SeverityDanger))
 
 					return
 				}
@@ -654,6 +935,14 @@ func (sg *Sandwich) gatherAnalytics() {
 			if mg.Analytics != nil {
 				mg.Analytics.IncrementBy(managerEvents)
 			}
+
+			if mg.Produced != nil {
+				mg.Produced.RunOnce(time.Now().UTC())
+			}
+
+			if mg.RESTRequests != nil {
+				mg.RESTRequests.IncrementBy(mg.restStats.takePending())
+			}
 			mg.AnalyticsMu.RUnlock()
 
 			events += managerEvents
@@ -730,15 +1019,8 @@ func (sg *Sandwich) VerifyRestTunnel(restTunnelURL string) (enabled bool, revers
 func (sg *Sandwich) Close() (err error) {
 	sg.Logger.Info().Msg("Closing sandwich")
 
-	go sg.PublishWebhook(context.Background(), discord.WebhookMessage{
-		Embeds: []discord.Embed{
-			{
-				Title:     "Shutting down sandwich",
-				Color:     discord.EmbedSandwich,
-				Timestamp: WebhookTime(time.Now().UTC()),
-			},
-		},
-	})
+	go sg.Notify(context.Background(),
+		NewNotification("Shutting down sandwich", ""))
 
 	// Close all managers
 	sg.ManagersMu.RLock()
@@ -747,6 +1029,10 @@ func (sg *Sandwich) Close() (err error) {
 	}
 	sg.ManagersMu.RUnlock()
 
+	if err = sg.Audit.Close(); err != nil {
+		sg.Logger.Error().Err(err).Msg("Failed to close audit log")
+	}
+
 	return
 }
 
@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"sync"
+
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+	"nhooyr.io/websocket"
+)
+
+// Priorities of payloads in the send queue. Lower priorities are sent first
+// and payloads of the same priority are sent in the order they were queued.
+const (
+	sendPriorityHeartbeat = iota
+	sendPrioritySession   // Identify and resume
+	sendPriorityNormal
+	sendPriorities
+)
+
+// ErrSendQueueClosed is returned for payloads which were still queued when
+// the shard was closed.
+var ErrSendQueueClosed = xerrors.New("shard was closed before the payload was sent")
+
+// queuedSend is a payload waiting to be written to the gateway.
+type queuedSend struct {
+	payload    []byte // Marshalled as JSON
+	generation int64  // Connection the payload must be written to
+	done       chan error
+}
+
+// sendQueue orders the payloads a shard sends to the gateway. A single
+// writer goroutine is started when payloads are queued and stops once the
+// queue is empty, so the connection is never written to concurrently.
+type sendQueue struct {
+	mu      sync.Mutex
+	lanes   [sendPriorities][]*queuedSend
+	writing bool
+}
+
+func newSendQueue() *sendQueue {
+	return &sendQueue{
+		mu: sync.Mutex{},
+	}
+}
+
+// sendPriority returns the priority payloads with the op are sent with.
+func sendPriority(op discord.GatewayOp) int {
+	switch op {
+	case discord.GatewayOpHeartbeat:
+		return sendPriorityHeartbeat
+	case discord.GatewayOpIdentify, discord.GatewayOpResume:
+		return sendPrioritySession
+	default:
+		return sendPriorityNormal
+	}
+}
+
+// push queues a payload. start is true when no writer is running and the
+// caller must start one.
+func (sq *sendQueue) push(priority int, item *queuedSend) (start bool) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	sq.lanes[priority] = append(sq.lanes[priority], item)
+
+	if !sq.writing {
+		sq.writing = true
+		start = true
+	}
+
+	return start
+}
+
+// pop returns the next payload to write. When the queue is empty, nil is
+// returned and the writer must stop.
+func (sq *sendQueue) pop() *queuedSend {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	for priority, lane := range sq.lanes {
+		if len(lane) == 0 {
+			continue
+		}
+
+		item := lane[0]
+		lane[0] = nil
+		sq.lanes[priority] = lane[1:]
+
+		return item
+	}
+
+	sq.writing = false
+
+	return nil
+}
+
+// drop fails every queued payload with err and returns how many there were.
+// A payload already being written is not affected.
+func (sq *sendQueue) drop(err error) (dropped int) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	for priority, lane := range sq.lanes {
+		for _, item := range lane {
+			item.done <- err
+		}
+
+		dropped += len(lane)
+		sq.lanes[priority] = nil
+	}
+
+	return dropped
+}
+
+// queueSend queues a payload for the connection of the generation provided
+// and waits until it has been written.
+func (sh *Shard) queueSend(op discord.GatewayOp, payload []byte, generation int64) error {
+	item := &queuedSend{
+		payload:    payload,
+		generation: generation,
+		done:       make(chan error, 1),
+	}
+
+	if sh.sends.push(sendPriority(op), item) {
+		go sh.writeQueued()
+	}
+
+	return <-item.done
+}
+
+// writeQueued writes queued payloads until the queue is empty.
+func (sh *Shard) writeQueued() {
+	for {
+		item := sh.sends.pop()
+		if item == nil {
+			return
+		}
+
+		item.done <- sh.writeQueuedSend(item)
+	}
+}
+
+// writeQueuedSend writes a payload. Payloads are built as JSON so they can be
+// logged and are only converted here when the connection uses ETF.
+func (sh *Shard) writeQueuedSend(item *queuedSend) (err error) {
+	messageType := websocket.MessageText
+	payload := item.payload
+
+	if sh.etf.IsSet() {
+		payload, err = jsonToETF(payload)
+		if err != nil {
+			return xerrors.Errorf("writeQueuedSend etf: %w", err)
+		}
+
+		messageType = websocket.MessageBinary
+	}
+
+	return sh.ws.Write(sh.currentContext(), item.generation, messageType, payload)
+}
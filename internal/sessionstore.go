@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/vmihailenco/msgpack"
+	"golang.org/x/xerrors"
+	"nhooyr.io/websocket"
+)
+
+const (
+	// Seconds a persisted session is kept if caching.redis.session_ttl is
+	// not set. Discord does not let sessions resume for long after the
+	// connection is lost.
+	defaultSessionTTL = 10 * 60
+
+	// Sequences received between writes of a persisted session.
+	sessionPersistInterval = 100
+
+	sessionStoreTimeout = 5 * time.Second
+)
+
+// persistedSession is the session of a shard stored in redis so the shard
+// can resume after the daemon restarts.
+type persistedSession struct {
+	SessionID        string `msgpack:"session_id"`
+	Sequence         int64  `msgpack:"sequence"`
+	ResumeGatewayURL string `msgpack:"resume_gateway_url"`
+}
+
+// sessionStore returns the redis sessions are persisted to and how long
+// they are kept. Sessions are only persisted when the redis state backend
+// is used and caching.redis.session_ttl is not negative.
+func (sg *Sandwich) sessionStore() (sr *stateRedis, ttl time.Duration) {
+	sg.ConfigurationMu.RLock()
+	seconds := sg.Configuration.Caching.Redis.SessionTTL
+	sg.ConfigurationMu.RUnlock()
+
+	if sg.State.redis == nil || seconds < 0 {
+		return nil, 0
+	}
+
+	if seconds == 0 {
+		seconds = defaultSessionTTL
+	}
+
+	return sg.State.redis, time.Duration(seconds) * time.Second
+}
+
+// sessionKey is the redis key of the session of the shard. Sessions belong
+// to a shard count so a session is not resumed once the bot is rescaled.
+func (sh *Shard) sessionKey(sr *stateRedis) string {
+	return sr.key(fmt.Sprintf("session:%s:%d:%d", sh.Manager.TokenHash(), sh.ShardGroup.ShardCount, sh.ShardID))
+}
+
+// persistSession writes the session of the shard to redis. If the shard has
+// no session, the persisted one is removed.
+func (sh *Shard) persistSession() (err error) {
+	sr, ttl := sh.Manager.Sandwich.sessionStore()
+	if sr == nil {
+		return nil
+	}
+
+	sh.RLock()
+	session := persistedSession{
+		SessionID:        sh.sessionID,
+		Sequence:         atomic.LoadInt64(sh.seq),
+		ResumeGatewayURL: sh.resumeGatewayURL,
+	}
+	sh.RUnlock()
+
+	if session.SessionID == "" || session.Sequence == 0 {
+		return sh.forgetSession()
+	}
+
+	data, err := msgpack.Marshal(session)
+	if err != nil {
+		return xerrors.Errorf("persist session marshal: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+
+	err = sr.client.Set(ctx, sh.sessionKey(sr), data, ttl).Err()
+	if err != nil {
+		return xerrors.Errorf("persist session: %w", err)
+	}
+
+	atomic.StoreInt64(sh.persistedSeq, session.Sequence)
+
+	return nil
+}
+
+// forgetSession removes the persisted session of the shard.
+func (sh *Shard) forgetSession() (err error) {
+	sr, _ := sh.Manager.Sandwich.sessionStore()
+	if sr == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+
+	err = sr.client.Del(ctx, sh.sessionKey(sr)).Err()
+	if err != nil {
+		return xerrors.Errorf("forget session: %w", err)
+	}
+
+	atomic.StoreInt64(sh.persistedSeq, 0)
+
+	return nil
+}
+
+// maybePersistSession persists the session in the background once
+// sessionPersistInterval sequences have been received since it was last
+// written, or straight away for a new session. Only one write runs at a time.
+func (sh *Shard) maybePersistSession(seq int64) {
+	if persisted := atomic.LoadInt64(sh.persistedSeq); persisted != 0 && seq-persisted < sessionPersistInterval {
+		return
+	}
+
+	if !sh.persistingSession.SetToIf(false, true) {
+		return
+	}
+
+	go func() {
+		defer sh.persistingSession.UnSet()
+
+		if err := sh.persistSession(); err != nil {
+			sh.Logger.Warn().Err(err).Msg("Failed to persist session")
+		}
+	}()
+}
+
+// loadPersistedSession restores the session persisted by a previous run of
+// the daemon so the shard resumes instead of identifying. It is only
+// attempted the first time the shard connects. If the session has since
+// been invalidated, discord sends INVALID_SESSION and the shard identifies.
+func (sh *Shard) loadPersistedSession() {
+	if !sh.sessionLoaded.SetToIf(false, true) {
+		return
+	}
+
+	sr, _ := sh.Manager.Sandwich.sessionStore()
+	if sr == nil {
+		return
+	}
+
+	sh.RLock()
+	hasSession := sh.sessionID != ""
+	sh.RUnlock()
+
+	if hasSession {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+
+	data, err := sr.client.Get(ctx, sh.sessionKey(sr)).Bytes()
+	if err != nil {
+		if !xerrors.Is(err, redis.Nil) {
+			sh.Logger.Warn().Err(err).Msg("Failed to load persisted session")
+		}
+
+		return
+	}
+
+	var session persistedSession
+
+	if err = msgpack.Unmarshal(data, &session); err != nil || session.SessionID == "" || session.Sequence == 0 {
+		sh.Logger.Warn().Err(err).Msg("Ignoring invalid persisted session")
+
+		return
+	}
+
+	sh.Lock()
+	sh.sessionID = session.SessionID
+	sh.resumeGatewayURL = session.ResumeGatewayURL
+	sh.Unlock()
+
+	atomic.StoreInt64(sh.seq, session.Sequence)
+	atomic.StoreInt64(sh.persistedSeq, session.Sequence)
+
+	sh.Logger.Info().Int64("sequence", session.Sequence).Msg("Loaded persisted session")
+}
+
+// closeSession persists the session of a shard which is closing. Closing
+// with a normal closure ends the session on discord so it is removed instead.
+func (sh *Shard) closeSession(code websocket.StatusCode) {
+	var err error
+
+	if code == websocket.StatusNormalClosure || code == websocket.StatusGoingAway {
+		err = sh.forgetSession()
+	} else {
+		err = sh.persistSession()
+	}
+
+	if err != nil {
+		sh.Logger.Warn().Err(err).Msg("Failed to store session whilst closing")
+	}
+}
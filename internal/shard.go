@@ -5,8 +5,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"runtime"
-	"strings"
+	"io/ioutil"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,7 +16,6 @@ import (
 	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
 	"github.com/TheRockettek/czlib"
 	"github.com/andybalholm/brotli"
-	"github.com/rs/zerolog"
 	"github.com/savsgio/gotils"
 	"github.com/tevino/abool"
 	"golang.org/x/xerrors"
@@ -25,36 +24,29 @@ import (
 
 const (
 	timeoutDuration     = 2 * time.Second
-	dispatchTimeout     = 30 * time.Second
 	waitForReadyTimeout = 10 * time.Second
 	identifyRatelimit   = (5 * time.Second) + (500 * time.Millisecond)
 
-	websocketReadLimit    = 512 << 20
+	websocketReadLimit    = 512 << 20 // Default read limit in bytes
 	reconnectCloseCode    = 4000
-	maxReconnectWait      = 600
 	gatewayConnectTimeout = 5
 
 	messageChannelBuffer      = 64
 	minPayloadCompressionSize = 1000000 // Apply higher level compression to payloads >1 Mb
 
-	// Time necessary to abort chunking if no event received in this timeframe.
-	initialMemberChunkTimeout = 10 * time.Second
-
-	// Time necessary to mark chunking as completed if no more events received in this timeframe.
-	memberChunkTimeout = 1 * time.Second
-
-	// Time between chunks before marked as no longer chunked.
-	chunkStatePersistTimeout = 10 * time.Second
+	// Weight of each heartbeat in the smoothed latency and jitter.
+	latencyEWMAWeight = 0.2
 )
 
 // Shard represents the shard object.
 type Shard struct {
 	sync.RWMutex // used to lock less common variables such as the user
 
-	Status   structs.ShardStatus `json:"status"`
-	StatusMu sync.RWMutex        `json:"-"`
+	Status      structs.ShardStatus `json:"status"`
+	StatusSince time.Time           `json:"state_since"` // When the status last changed
+	StatusMu    sync.RWMutex        `json:"-"`
 
-	Logger zerolog.Logger `json:"-"`
+	Logger *ShardLogger `json:"-"`
 
 	ShardID    int         `json:"shard_id"`
 	ShardGroup *ShardGroup `json:"-"`
@@ -63,6 +55,8 @@ type Shard struct {
 	User *discord.User `json:"user"`
 	// Todo: Add deque that can allow for an event queue (maybe).
 
+	// Replaced by Connect once cancelled, so read it with currentContext
+	// outside of Connect.
 	ctx    context.Context
 	cancel func()
 
@@ -71,7 +65,13 @@ type Shard struct {
 	LastHeartbeatAck  time.Time         `json:"last_heartbeat_ack"`
 	LastHeartbeatSent time.Time         `json:"last_heartbeat_sent"`
 
+	// Smoothed heartbeat latency and its mean absolute deviation in
+	// milliseconds. Guarded by LastHeartbeatMu.
+	latencyEWMA float64
+	jitter      float64
+
 	Heartbeater          *time.Ticker  `json:"-"`
+	heartbeaterChanged   chan void     // Wakes Heartbeat when Heartbeater is replaced
 	HeartbeatInterval    time.Duration `json:"heartbeat_interval"`
 	MaxHeartbeatFailures time.Duration `json:"max_heartbeat_failures"`
 
@@ -84,7 +84,7 @@ type Shard struct {
 	FastCompressor    sync.Pool
 	DefaultCompressor sync.Pool
 
-	wsConn *websocket.Conn
+	ws *wsConnHolder
 
 	mp sync.Pool
 	rp sync.Pool
@@ -94,13 +94,86 @@ type Shard struct {
 	MessageCh chan discord.ReceivedPayload
 	ErrorCh   chan error
 
+	// Dispatch events received since analytics were last gathered.
 	events *int64
 
+	// Lifetime count of packets received by opcode.
+	opcodes *opcodeCounters
+
+	// UnixNano time of the last dispatch event received. Used to detect
+	// shards that still heartbeat but no longer receive events.
+	lastDispatch *int64
+	stalled      *abool.AtomicBool
+
+	// UnixNano time of the last websocket message of any opcode, read by
+	// the watchdog along with the last heartbeat ACK.
+	lastMessage    *int64
+	lastStall      *int64
+	watchdogStalls *int64
+	watchdogActive *abool.AtomicBool
+
+	// Set whilst Open is reading from the shard. reopen is set by
+	// Reconnect once it has connected so a read loop that was still
+	// stopping carries on with the new connection.
+	listening *abool.AtomicBool
+	reopen    *abool.AtomicBool
+
+	lazyMembers *lazyMemberFetcher
+
+	// Payloads received over the read limit and how many in a row without
+	// a dispatch in between.
+	readLimitExceeded *int64
+	readLimitStreak   *int64
+
+	// Dispatches replayed since the last RESUME was sent.
+	resumeMu sync.Mutex
+	resume   resumeTracker
+
 	seq       *int64
 	sessionID string
 
-	// Channel that dictates if the shard has been made ready.
-	ready chan void
+	// Sequence last persisted to redis and if a write is running.
+	// sessionLoaded is set once a persisted session has been looked for.
+	persistedSeq      *int64
+	persistingSession *abool.AtomicBool
+	sessionLoaded     *abool.AtomicBool
+
+	// If the current connection uses the ETF encoding.
+	etf *abool.AtomicBool
+
+	// Payloads waiting to be written to the gateway.
+	sends *sendQueue
+
+	// Gateway URL from READY which resumes connect to instead of the
+	// manager gateway URL.
+	resumeGatewayURL string
+
+	// Trace and session metadata from the last HELLO and READY.
+	sessionMu sync.RWMutex
+	session   structs.ShardSession
+
+	// Closed once the shard has received READY or RESUMED. It is replaced
+	// when the shard connects to start a new session.
+	readyMu sync.Mutex
+	ready   chan void
+
+	// Invalid sessions received within bot.invalid_session_window.
+	invalidSessions *invalidSessionCounter
+
+	// Nanoseconds the next reconnect waits before connecting.
+	reconnectDelay *int64
+
+	// Workers dispatches are handled on when bot.ordered_dispatch is enabled.
+	orderedDispatchMu sync.Mutex
+	orderedDispatch   *orderedDispatcher
+
+	// Throttles GUILD_CREATE ingestion after READY or RESUMED.
+	guildRecovery *guildRecovery
+
+	// Presence set through RPC which is used instead of the manager
+	// presence when identifying.
+	presenceMu       sync.RWMutex
+	presenceOverride *discord.UpdateStatus
 
 	// Channel to pipe errors.
 	errs chan error
@@ -110,19 +183,21 @@ type Shard struct {
 func (sg *ShardGroup) NewShard(shardID int) *Shard {
 	logger := sg.Logger.With().Int("shard", shardID).Logger()
 	sh := &Shard{
-		Status:   structs.ShardIdle,
-		StatusMu: sync.RWMutex{},
+		Status:      structs.ShardIdle,
+		StatusSince: time.Now().UTC(),
+		StatusMu:    sync.RWMutex{},
 
-		Logger: logger,
+		Logger: newShardLogger(sg.Manager.Sandwich, logger),
 
 		ShardID:    shardID,
 		ShardGroup: sg,
 		Manager:    sg.Manager,
 
-		HeartbeatActive:   abool.New(),
-		LastHeartbeatMu:   sync.RWMutex{},
-		LastHeartbeatAck:  time.Now().UTC(),
-		LastHeartbeatSent: time.Now().UTC(),
+		HeartbeatActive:    abool.New(),
+		heartbeaterChanged: make(chan void, 1),
+		LastHeartbeatMu:    sync.RWMutex{},
+		LastHeartbeatAck:   time.Now().UTC(),
+		LastHeartbeatSent:  time.Now().UTC(),
 
 		UnavailableMu: sync.RWMutex{},
 
@@ -137,6 +212,8 @@ func (sg *ShardGroup) NewShard(shardID int) *Shard {
 			New: func() interface{} { return brotli.NewWriterLevel(nil, brotli.DefaultCompression) },
 		},
 
+		ws: newWSConnHolder(),
+
 		// Pool of payloads from discord
 		mp: sync.Pool{
 			New: func() interface{} { return new(discord.ReceivedPayload) },
@@ -157,12 +234,44 @@ func (sg *ShardGroup) NewShard(shardID int) *Shard {
 			New: func() interface{} { return new(bytes.Buffer) },
 		},
 
-		events: new(int64),
+		events:  new(int64),
+		opcodes: newOpcodeCounters(),
+
+		lastDispatch:   new(int64),
+		lastMessage:    new(int64),
+		lastStall:      new(int64),
+		watchdogStalls: new(int64),
+		watchdogActive: abool.New(),
+		stalled:        abool.New(),
+		listening:      abool.New(),
+		reopen:         abool.New(),
+
+		readLimitExceeded: new(int64),
+		readLimitStreak:   new(int64),
+
+		resumeMu: sync.Mutex{},
 
 		seq:       new(int64),
 		sessionID: "",
 
-		ready: make(chan void, 1),
+		persistedSeq:      new(int64),
+		persistingSession: abool.New(),
+		sessionLoaded:     abool.New(),
+
+		etf:   abool.New(),
+		sends: newSendQueue(),
+
+		sessionMu: sync.RWMutex{},
+
+		readyMu: sync.Mutex{},
+		ready:   make(chan void),
+
+		invalidSessions: newInvalidSessionCounter(),
+		reconnectDelay:  new(int64),
+
+		orderedDispatchMu: sync.Mutex{},
+
+		presenceMu: sync.RWMutex{},
 
 		errs: make(chan error),
 	}
@@ -171,15 +280,49 @@ func (sg *ShardGroup) NewShard(shardID int) *Shard {
 		sh.ctx, sh.cancel = context.WithCancel(context.Background())
 	}
 
+	sh.lazyMembers = newLazyMemberFetcher(sh)
+	sh.guildRecovery = newGuildRecovery(sh)
+
 	atomic.StoreInt32(sh.Retries, sg.Manager.Configuration.Bot.Retries)
+	atomic.StoreInt64(sh.lastDispatch, time.Now().UTC().UnixNano())
 
 	return sh
 }
 
-// Open starts up the shard connection.
+// currentContext returns the context of the current connection of the shard.
+func (sh *Shard) currentContext() context.Context {
+	sh.RLock()
+	defer sh.RUnlock()
+
+	return sh.ctx
+}
+
+// Open reads from the shard until it is closed. Only one read loop runs at a
+// time so this does nothing whilst the shard is already being read from.
 func (sh *Shard) Open() {
+	for sh.listening.SetToIf(false, true) {
+		sh.reopen.UnSet()
+		sh.listen()
+		sh.listening.UnSet()
+
+		// A reconnect closes the shard, which stops the loop, then connects
+		// again and opens the shard. If the loop had not stopped by then,
+		// that Open did nothing so this one reads the new connection. The
+		// loop must not carry on before then as Connect is still reading
+		// the HELLO of the new connection.
+		if !sh.reopen.IsSet() {
+			return
+		}
+	}
+}
+
+// listen reads from the shard until the context of its connection is
+// cancelled.
+func (sh *Shard) listen() {
 	sh.Logger.Debug().Msg("Opening Shard")
 
+	ctx := sh.currentContext()
+
 	for {
 		sh.Logger.Debug().Msg("Started listening to shard")
 
@@ -192,7 +335,7 @@ func (sh *Shard) Open() {
 
 		// Check if context is done
 		select {
-		case <-sh.ctx.Done():
+		case <-ctx.Done():
 			sh.Logger.Debug().Msg("Shard context has finished")
 
 			return
@@ -224,6 +367,7 @@ func (sh *Shard) Connect() (err error) {
 	sh.Manager.GatewayMu.RUnlock()
 
 	// If the context has canceled, create new context.
+	sh.Lock()
 	select {
 	case <-sh.ctx.Done():
 		sh.Logger.Trace().Msg("Creating new context")
@@ -231,35 +375,50 @@ func (sh *Shard) Connect() (err error) {
 	default:
 		sh.Logger.Trace().Msg("No need for new context")
 	}
+	ctx := sh.ctx
+	sh.Unlock()
 
 	// Create and wait for the websocket bucket.
 	sh.Logger.Trace().Msg("Creating buckets")
 	sh.Manager.Buckets.CreateBucket(fmt.Sprintf("ws:%d:%d", sh.ShardID, sh.ShardGroup.ShardCount), 120, time.Minute)
 
-	hash, err := QuickHash(sh.Manager.Configuration.Token)
-	if err != nil {
-		sh.Logger.Error().Err(err).Msg("Failed to generate token hash")
-
-		return err
-	}
+	hash := sh.Manager.TokenHash()
 
 	sh.Manager.Sandwich.Buckets.CreateBucket(fmt.Sprintf("gw:%s:%d", hash, concurrencyBucket), 1, identifyRatelimit)
 
-	// When an error occurs and we have to reconnect, we make a ready channel by default
-	// which seems to cause a problem with WaitForReady. To circumvent this, we will
-	// make the ready only when the channel is closed however this may not be necessary
-	// as there is now a loop that fires every 10 seconds meaning it will be up to date regardless.
-
-	if sh.ready == nil {
-		sh.ready = make(chan void, 1)
-	}
+	// Treat connecting as activity so a reconnecting shard is not
+	// immediately considered stalled.
+	atomic.StoreInt64(sh.lastDispatch, time.Now().UTC().UnixNano())
 
 	sh.Manager.GatewayMu.RLock()
 	gatewayURL := sh.Manager.Gateway.URL
 	sh.Manager.GatewayMu.RUnlock()
 
+	sh.loadPersistedSession()
+
+	// Resumes must connect to the gateway the session was created on.
+	sh.RLock()
+	resumeGatewayURL := sh.resumeGatewayURL
+	resuming := sh.sessionID != "" && atomic.LoadInt64(sh.seq) != 0
+	sh.RUnlock()
+
+	if resuming && resumeGatewayURL != "" {
+		gatewayURL = resumeGatewayURL
+	}
+
+	// A resumed session is still ready so only a new session waits for
+	// READY again.
+	if !resuming {
+		sh.resetReady()
+	}
+
+	dialURL, err := sh.Manager.gatewayDialURL(gatewayURL)
+	if err != nil {
+		return err
+	}
+
 	defer func() {
-		if err != nil && sh.wsConn != nil {
+		if conn, _ := sh.ws.Get(); err != nil && conn != nil {
 			if _err := sh.CloseWS(websocket.StatusNormalClosure); _err != nil {
 				sh.Logger.Error().Err(_err).Msg("Failed to close websocket")
 			}
@@ -273,16 +432,24 @@ func (sh *Shard) Connect() (err error) {
 	}
 
 	// If there is no active ws connection, create a new connection to discord.
-	if sh.wsConn == nil {
+	if conn, _ := sh.ws.Get(); conn == nil {
 		var errorCh chan error
 
 		var messageCh chan discord.ReceivedPayload
 
-		errorCh, messageCh, err = sh.FeedWebsocket(sh.ctx, gatewayURL, nil)
+		errorCh, messageCh, err = sh.FeedWebsocket(ctx, dialURL, nil)
 		if err != nil {
 			sh.Logger.Error().Err(err).Msg("Failed to dial")
 
-			go sh.PublishWebhook(fmt.Sprintf("Failed to dial `%s`", gatewayURL), err.Error(), 14431557, false)
+			// Use the manager gateway URL next time in case the resume
+			// gateway is no longer reachable.
+			if gatewayURL == resumeGatewayURL {
+				sh.Lock()
+				sh.resumeGatewayURL = ""
+				sh.Unlock()
+			}
+
+			go sh.PublishNoisyWebhook(fmt.Sprintf("Failed to dial `%s`", gatewayURL), err.Error(), 14431557, false)
 
 			return
 		}
@@ -307,22 +474,24 @@ func (sh *Shard) Connect() (err error) {
 
 	hello := discord.Hello{}
 	err = sh.decodeContent(msg, &hello)
+	sh.recordHello(msg.Data)
 
 	sh.LastHeartbeatMu.Lock()
 	sh.LastHeartbeatAck = time.Now().UTC()
 	sh.LastHeartbeatSent = time.Now().UTC()
+	sh.latencyEWMA, sh.jitter = 0, 0
 	sh.LastHeartbeatMu.Unlock()
 
-	sh.Lock()
-	sh.HeartbeatInterval = hello.HeartbeatInterval * time.Millisecond
-	sh.MaxHeartbeatFailures = sh.HeartbeatInterval * time.Duration(sh.Manager.Configuration.Bot.MaxHeartbeatFailures)
-	sh.Heartbeater = time.NewTicker(sh.HeartbeatInterval)
-	sh.Unlock()
+	sh.setHeartbeater(hello.HeartbeatInterval * time.Millisecond)
 
 	if sh.HeartbeatActive.IsNotSet() {
 		go sh.Heartbeat()
 	}
 
+	if sh.watchdogActive.IsNotSet() {
+		go sh.watchdog()
+	}
+
 	seq := atomic.LoadInt64(sh.seq)
 
 	sh.Logger.Debug().
@@ -341,7 +510,7 @@ func (sh *Shard) Connect() (err error) {
 		if err != nil {
 			sh.Logger.Error().Err(err).Msg("Failed to identify")
 
-			go sh.PublishWebhook("Gateway `IDENTIFY` failed", err.Error(), 14431557, false)
+			go sh.PublishNoisyWebhook("Gateway `IDENTIFY` failed", err.Error(), 14431557, false)
 
 			return
 		}
@@ -350,31 +519,20 @@ func (sh *Shard) Connect() (err error) {
 		if err != nil {
 			sh.Logger.Error().Err(err).Msg("Failed to resume")
 
-			go sh.PublishWebhook("Gateway `RESUME` failed", err.Error(), 14431557, false)
+			go sh.PublishNoisyWebhook("Gateway `RESUME` failed", err.Error(), 14431557, false)
 
 			return
 		}
-
-		// We will assume the bot is ready.
-		if err := sh.SetStatus(structs.ShardReady); err != nil {
-			sh.Logger.Error().Err(err).Msg("Encountered error setting shard status")
-		}
 	}
 
-	sh.Manager.ConfigurationMu.RLock()
-	hash, err = QuickHash(sh.Manager.Configuration.Token)
-
-	if err != nil {
-		sh.Manager.ConfigurationMu.RUnlock()
-		sh.Logger.Error().Err(err).Msg("Failed to generate token hash")
-
-		return
+	// The shard becomes ready once READY or RESUMED has been processed.
+	if err := sh.SetStatus(structs.ShardConnected); err != nil {
+		sh.Logger.Error().Err(err).Msg("Encountered error setting shard status")
 	}
 
 	// Reset the bucket we used for gateway
-	bucket := fmt.Sprintf("gw:%s:%d", hash, sh.ShardID%sh.Manager.Gateway.SessionStartLimit.MaxConcurrency)
+	bucket := fmt.Sprintf("gw:%s:%d", sh.Manager.TokenHash(), sh.ShardID%sh.Manager.Gateway.SessionStartLimit.MaxConcurrency)
 	sh.Manager.Buckets.ResetBucket(bucket)
-	sh.Manager.ConfigurationMu.RUnlock()
 
 	t := time.NewTicker(time.Second * gatewayConnectTimeout)
 
@@ -392,7 +550,7 @@ func (sh *Shard) Connect() (err error) {
 	case err = <-errorch:
 		sh.Logger.Error().Err(err).Msg("Encountered error whilst connecting")
 
-		go sh.PublishWebhook("Encountered error during connection", err.Error(), 14431557, false)
+		go sh.PublishNoisyWebhook("Encountered error during connection", err.Error(), 14431557, false)
 
 		return xerrors.Errorf("encountered error whilst connecting: %w", err)
 	case msg = <-messagech:
@@ -421,12 +579,30 @@ func (sh *Shard) FeedWebsocket(ctx context.Context, u string,
 		return errorCh, messageCh, xerrors.Errorf("failed to connect to websocket: %w", err)
 	}
 
-	conn.SetReadLimit(websocketReadLimit)
-	sh.wsConn = conn
+	readLimit := sh.Manager.readLimit()
+
+	var stream *zlibStream
+	if sh.Manager.transportCompression() {
+		stream = newZlibStream()
+	}
+
+	// The encoding is fixed for the connection so a configuration change
+	// applies once the shard reconnects.
+	etf := sh.Manager.gatewayEncoding() == EncodingETF
+	sh.etf.SetTo(etf)
+
+	conn.SetReadLimit(readLimit)
+	if old, _ := sh.ws.Swap(conn); old != nil {
+		_ = old.Close(websocket.StatusNormalClosure, "")
+	}
 
 	go func() {
+		if stream != nil {
+			defer stream.Close()
+		}
+
 		for {
-			mt, buf, err := conn.Read(ctx)
+			mt, buf, err := readWebsocket(ctx, conn)
 
 			select {
 			case <-ctx.Done():
@@ -434,13 +610,33 @@ func (sh *Shard) FeedWebsocket(ctx context.Context, u string,
 			default:
 			}
 
+			if isReadLimitError(err) {
+				errorCh <- newReadLimitError(readLimit, mt, buf, err)
+
+				return
+			}
+
 			if err != nil {
 				errorCh <- xerrors.Errorf("readMessage read: %w", err)
 
 				return
 			}
 
-			if mt == websocket.MessageBinary {
+			switch {
+			case stream != nil:
+				var complete bool
+
+				buf, complete, err = stream.inflate(buf)
+				if err != nil {
+					errorCh <- xerrors.Errorf("readMessage inflate: %w", err)
+
+					return
+				}
+
+				if !complete {
+					continue
+				}
+			case mt == websocket.MessageBinary && !etf:
 				buf, err = czlib.Decompress(buf)
 				if err != nil {
 					errorCh <- xerrors.Errorf("readMessage decompress: %w", err)
@@ -450,12 +646,22 @@ func (sh *Shard) FeedWebsocket(ctx context.Context, u string,
 			}
 
 			now := time.Now().UTC()
+			sh.recordMessage(now)
+
 			msg := discord.ReceivedPayload{
-				TraceTime: now,
-				Trace:     make(map[string]int),
+				TraceTime:  now,
+				Trace:      make(map[string]int),
+				ReceivedAt: now,
+			}
+
+			// Only the envelope is parsed here so a change to the data of
+			// an event cannot drop it before its handler sees it.
+			if etf {
+				err = parseETFEnvelope(buf, &msg)
+			} else {
+				err = parseEnvelope(buf, &msg)
 			}
 
-			err = json.Unmarshal(buf, &msg)
 			if err != nil {
 				sh.Logger.Error().Err(err).Msg("Failed to unmarshal message")
 
@@ -465,7 +671,15 @@ func (sh *Shard) FeedWebsocket(ctx context.Context, u string,
 			now = time.Now().UTC()
 			msg.AddTrace("unmarshal", now)
 
-			atomic.AddInt64(sh.events, 1)
+			sh.opcodes.Record(msg.Op, now)
+
+			if msg.Op == discord.GatewayOpDispatch {
+				atomic.AddInt64(sh.events, 1)
+				atomic.StoreInt64(sh.lastDispatch, now.UnixNano())
+				atomic.StoreInt64(sh.readLimitStreak, 0)
+
+				sh.recordResumeDispatch(msg, now)
+			}
 
 			messageCh <- msg
 		}
@@ -474,12 +688,27 @@ func (sh *Shard) FeedWebsocket(ctx context.Context, u string,
 	return errorCh, messageCh, nil
 }
 
+// readWebsocket reads a websocket message. Unlike conn.Read, the bytes read
+// before an error are returned so payloads over the read limit can be
+// described.
+func readWebsocket(ctx context.Context, conn *websocket.Conn) (mt websocket.MessageType, buf []byte, err error) {
+	mt, r, err := conn.Reader(ctx)
+	if err != nil {
+		return mt, nil, err
+	}
+
+	buf, err = ioutil.ReadAll(r)
+
+	return mt, buf, err
+}
+
 // OnEvent processes an event.
 func (sh *Shard) OnEvent(msg discord.ReceivedPayload) {
 	var err error
 
 	// This goroutine shows events that are taking too long.
 	fin := make(chan void)
+	dispatchTimeout := sh.Manager.shardTimeouts().dispatch
 
 	go func() {
 		since := time.Now()
@@ -511,7 +740,7 @@ func (sh *Shard) OnEvent(msg discord.ReceivedPayload) {
 		err = sh.SendEvent(discord.GatewayOpHeartbeat, atomic.LoadInt64(sh.seq))
 
 		if err != nil {
-			go sh.PublishWebhook("Failed to send heartbeat to gateway", err.Error(), 16760839, false)
+			go sh.PublishNoisyWebhook("Failed to send heartbeat to gateway", err.Error(), 16760839, false)
 
 			sh.Logger.Error().Err(err).Msg("Failed to send heartbeat in response to gateway, reconnecting...")
 			err = sh.Reconnect(websocket.StatusNormalClosure)
@@ -523,13 +752,23 @@ func (sh *Shard) OnEvent(msg discord.ReceivedPayload) {
 			return
 		}
 	case discord.GatewayOpInvalidSession:
+		// A resumable invalid session keeps the session and sequence so the
+		// reconnect resumes. Otherwise discord requires a random wait of 1 to
+		// 5 seconds before identifying again.
 		resumable := json.Get(msg.Data, "d").ToBool()
 		if !resumable {
+			sh.Lock()
 			sh.sessionID = ""
+			sh.resumeGatewayURL = ""
+			sh.Unlock()
+
 			atomic.StoreInt64(sh.seq, 0)
+			atomic.StoreInt64(sh.reconnectDelay, int64(invalidSessionWait()))
 		}
 
-		go sh.PublishWebhook("Received invalid session from gateway", "", 16760839, false)
+		sh.recordInvalidSession(resumable)
+
+		go sh.PublishNoisyWebhook("Received invalid session from gateway", sh.sessionDescription(), 16760839, false)
 
 		sh.Logger.Warn().Bool("resumable", resumable).Msg("Received invalid session from gateway")
 		err = sh.Reconnect(reconnectCloseCode)
@@ -540,17 +779,15 @@ func (sh *Shard) OnEvent(msg discord.ReceivedPayload) {
 	case discord.GatewayOpHello:
 		hello := discord.Hello{}
 		err = sh.decodeContent(msg, &hello)
+		sh.recordHello(msg.Data)
 
 		sh.LastHeartbeatMu.Lock()
 		sh.LastHeartbeatAck = time.Now().UTC()
 		sh.LastHeartbeatSent = time.Now().UTC()
+		sh.latencyEWMA, sh.jitter = 0, 0
 		sh.LastHeartbeatMu.Unlock()
 
-		sh.Lock()
-		sh.HeartbeatInterval = hello.HeartbeatInterval * time.Millisecond
-		sh.MaxHeartbeatFailures = sh.HeartbeatInterval * time.Duration(sh.Manager.Configuration.Bot.MaxHeartbeatFailures)
-		sh.Heartbeater = time.NewTicker(sh.HeartbeatInterval)
-		sh.Unlock()
+		sh.setHeartbeater(hello.HeartbeatInterval * time.Millisecond)
 
 		sh.Logger.Debug().
 			Dur("interval", sh.HeartbeatInterval).
@@ -568,7 +805,13 @@ func (sh *Shard) OnEvent(msg discord.ReceivedPayload) {
 
 		return
 	case discord.GatewayOpDispatch:
+		if !sh.ShardGroup.startDispatch() {
+			return
+		}
+
 		exec := func() {
+			defer sh.ShardGroup.finishDispatch()
+
 			var ticket int
 
 			atomic.AddInt64(sh.Manager.Sandwich.PoolWaiting, 1)
@@ -593,23 +836,30 @@ func (sh *Shard) OnEvent(msg discord.ReceivedPayload) {
 		// handle messages whilst this is running! This essentially just means we pass
 		// control of the MessageCh to that event for its duration. Currently this is
 		// only the READY event.
-		go exec()
+		if msg.Type != "GUILD_CREATE" || !sh.guildRecovery.Enqueue(msg, exec) {
+			sh.dispatch(msg, exec)
+		}
 	case discord.GatewayOpHeartbeatACK:
 		sh.LastHeartbeatMu.Lock()
 		sh.LastHeartbeatAck = time.Now().UTC()
+		sh.recordLatency(sh.LastHeartbeatAck.Sub(sh.LastHeartbeatSent))
 		sh.Logger.Debug().
 			Int64("RTT", sh.LastHeartbeatAck.Sub(sh.LastHeartbeatSent).Milliseconds()).
+			Float64("ewma", sh.latencyEWMA).
+			Float64("jitter", sh.jitter).
 			Msg("Received heartbeat ACK")
 
 		sh.LastHeartbeatMu.Unlock()
 
 		return
-	case discord.GatewayOpVoiceStateUpdate:
-		// Todo: handle
 	case discord.GatewayOpIdentify,
 		discord.GatewayOpRequestGuildMembers,
 		discord.GatewayOpResume,
-		discord.GatewayOpStatusUpdate:
+		discord.GatewayOpStatusUpdate,
+		discord.GatewayOpVoiceStateUpdate:
+		// Only sent by us. Voice state updates are sent with
+		// Manager.UpdateVoiceState and the resulting VOICE_STATE_UPDATE and
+		// VOICE_SERVER_UPDATE dispatches carry the shard in their metadata.
 	default:
 		sh.Logger.Warn().
 			Int("op", int(msg.Op)).
@@ -620,6 +870,10 @@ func (sh *Shard) OnEvent(msg discord.ReceivedPayload) {
 	}
 
 	atomic.StoreInt64(sh.seq, msg.Sequence)
+
+	if msg.Op == discord.GatewayOpDispatch {
+		sh.maybePersistSession(msg.Sequence)
+	}
 }
 
 // OnDispatch handles a dispatch event.
@@ -655,19 +909,21 @@ func (sh *Shard) OnDispatch(msg discord.ReceivedPayload) (err error) {
 		}
 	}()
 
-	if sh.Manager.ProducerClient == nil {
+	if sh.Manager.Producer() == nil {
 		return xerrors.Errorf("no producer client found")
 	}
 
 	// Ignore events that are in the event blacklist.
 	sh.Manager.EventBlacklistMu.RLock()
-	contains := gotils.StringSliceInclude(sh.Manager.EventBlacklist, msg.Type)
+	contains := sh.Manager.EventBlacklist.Match(msg.Type)
 	sh.Manager.EventBlacklistMu.RUnlock()
 
 	if contains {
 		return
 	}
 
+	sh.Manager.recordGuildEvent(msg, start)
+
 	msg.AddTrace("dispatch", time.Now().UTC())
 
 	results, ok, err := sh.Manager.Sandwich.StateDispatch(&StateCtx{
@@ -678,8 +934,39 @@ func (sh *Shard) OnDispatch(msg discord.ReceivedPayload) (err error) {
 
 	msg.AddTrace("state", time.Now().UTC())
 
+	sh.recordEventOutcome(msg.Type, eventStageState, err)
+
+	// Dispatches discord has added since this release are forwarded as they
+	// were received if events.forward_unhandled is enabled.
+	unhandled := xerrors.Is(err, NoHandler)
+	if unhandled {
+		sh.Manager.recordUnhandled(msg.Type)
+
+		sh.Manager.ConfigurationMu.RLock()
+		forward := sh.Manager.Configuration.Events.ForwardUnhandled
+		sh.Manager.ConfigurationMu.RUnlock()
+
+		if !forward {
+			return err
+		}
+
+		results = structs.StateResult{Data: msg.Data}
+		ok, err = true, nil
+	}
+
 	if err != nil {
-		return xerrors.Errorf("on dispatch failure for %s: %w", msg.Type, err)
+		var decodeError *eventDecodeError
+
+		if !xerrors.As(err, &decodeError) {
+			return xerrors.Errorf("on dispatch failure for %s: %w", msg.Type, err)
+		}
+
+		// The state could not be updated from the event but it is still
+		// published with the data discord sent.
+		sh.Logger.Debug().Err(err).Str("type", msg.Type).Msg("Publishing event which failed to decode")
+
+		results = structs.StateResult{Data: msg.Data}
+		ok = true
 	}
 
 	if !ok {
@@ -689,13 +976,19 @@ func (sh *Shard) OnDispatch(msg discord.ReceivedPayload) (err error) {
 	// Do not publish the event if it is in the produce blacklist,
 	// regardless if it has been marked ok.
 	sh.Manager.ProduceBlacklistMu.RLock()
-	contains = gotils.StringSliceInclude(sh.Manager.ProduceBlacklist, msg.Type)
+	contains = sh.Manager.ProduceBlacklist.Match(msg.Type)
 	sh.Manager.ProduceBlacklistMu.RUnlock()
 
 	if contains {
 		return
 	}
 
+	// The state is kept up to date by every ShardGroup but only the active
+	// producer publishes whilst a new ShardGroup takes over.
+	if !sh.ShardGroup.IsActiveProducer() {
+		return
+	}
+
 	packet := sh.pp.Get().(*structs.SandwichPayload)
 	defer sh.pp.Put(packet)
 
@@ -703,19 +996,26 @@ func (sh *Shard) OnDispatch(msg discord.ReceivedPayload) (err error) {
 	packet.Trace = msg.Trace
 	packet.Data = results.Data
 	packet.Extra = results.Extra
+	packet.Metadata = structs.SandwichMetadata{Unhandled: unhandled}
 
 	err = sh.PublishEvent(packet)
+	sh.recordEventOutcome(msg.Type, eventStagePublish, err)
+
+	if err == nil && !msg.ReceivedAt.IsZero() {
+		sh.Manager.recordSLO(msg.Type, time.Since(msg.ReceivedAt))
+	}
 
 	return err
 }
 
 // Listen to gateway and process accordingly.
 func (sh *Shard) Listen() (err error) {
-	wsConn := sh.wsConn
+	_, generation := sh.ws.Get()
+	ctx := sh.currentContext()
 
 	for {
 		select {
-		case <-sh.ctx.Done():
+		case <-ctx.Done():
 			return
 		default:
 		}
@@ -726,6 +1026,18 @@ func (sh *Shard) Listen() (err error) {
 				break
 			}
 
+			var readLimitError *ReadLimitError
+
+			// The websocket library closes the connection when the read limit
+			// is exceeded so the shard resumes past the payload instead.
+			if _, currentGeneration := sh.ws.Get(); errors.As(err, &readLimitError) && generation == currentGeneration {
+				if sh.handleReadLimit(readLimitError) {
+					return sh.Reidentify()
+				}
+
+				return sh.Reconnect(websocket.StatusNormalClosure)
+			}
+
 			sh.Logger.Error().Err(err).Msg("Error reading from gateway")
 
 			var closeError *websocket.CloseError
@@ -744,7 +1056,8 @@ func (sh *Shard) Listen() (err error) {
 						closeError.Code,
 					)
 
-					go sh.PublishWebhook("ShardGroup is closing due to invalid token being passed", "", 16760839, false)
+					go sh.PublishWebhook("ShardGroup is closing due to invalid token being passed",
+						sh.sessionDescription(), 16760839, false)
 
 					// We cannot continue so we will kill the ShardGroup
 					sh.ShardGroup.ErrorMu.Lock()
@@ -758,11 +1071,18 @@ func (sh *Shard) Listen() (err error) {
 
 					return err
 				default:
-					sh.Logger.Warn().Msgf("Websocket was closed with code %d", closeError.Code)
+					session := sh.Session()
+
+					sh.Logger.Warn().
+						Str("gateway_node", session.GatewayNode).
+						Str("session_id", session.SessionID).
+						Msgf("Websocket was closed with code %d", closeError.Code)
 				}
 			}
 
-			if wsConn == sh.wsConn {
+			_, currentGeneration := sh.ws.Get()
+
+			if generation == currentGeneration {
 				// We have likely closed so we should attempt to reconnect
 				sh.Logger.Warn().Msg("We have encountered an error whilst in the same connection, reconnecting...")
 				err = sh.Reconnect(websocket.StatusNormalClosure)
@@ -774,22 +1094,60 @@ func (sh *Shard) Listen() (err error) {
 				return nil
 			}
 
-			wsConn = sh.wsConn
+			// The error was from a connection which has since been
+			// replaced so there is no message to handle.
+			generation = currentGeneration
+
+			continue
 		}
 
 		sh.OnEvent(msg)
 
-		// In the event we have reconnected, the wsConn could have changed,
-		// we will use the new wsConn if this is the case
-		if sh.wsConn != wsConn {
-			sh.Logger.Debug().Msg("New wsConn was assigned to shard")
-			wsConn = sh.wsConn
+		// In the event we have reconnected, the connection could have changed,
+		// we will use the new generation if this is the case
+		if _, currentGeneration := sh.ws.Get(); currentGeneration != generation {
+			sh.Logger.Debug().Msg("New websocket connection was assigned to shard")
+			generation = currentGeneration
 		}
 	}
 
 	return err
 }
 
+// setHeartbeater replaces the heartbeat ticker with one for the interval
+// discord sent in HELLO. The previous ticker is stopped and Heartbeat is woken
+// up so it waits on the new one.
+func (sh *Shard) setHeartbeater(interval time.Duration) {
+	sh.Manager.ConfigurationMu.RLock()
+	maxFailures := sh.Manager.Configuration.Bot.MaxHeartbeatFailures
+	sh.Manager.ConfigurationMu.RUnlock()
+
+	sh.Lock()
+	if sh.Heartbeater != nil {
+		sh.Heartbeater.Stop()
+	}
+
+	sh.HeartbeatInterval = interval
+	sh.MaxHeartbeatFailures = interval * time.Duration(maxFailures)
+	sh.Heartbeater = time.NewTicker(interval)
+	sh.Unlock()
+
+	select {
+	case sh.heartbeaterChanged <- void{}:
+	default:
+	}
+}
+
+// stopHeartbeater stops the heartbeat ticker until the next HELLO.
+func (sh *Shard) stopHeartbeater() {
+	sh.Lock()
+	if sh.Heartbeater != nil {
+		sh.Heartbeater.Stop()
+		sh.Heartbeater = nil
+	}
+	sh.Unlock()
+}
+
 // Heartbeat maintains a heartbeat with discord
 // TODO: Make a shardgroup specific heartbeat function to heartbeat on behalf of all running shards.
 func (sh *Shard) Heartbeat() {
@@ -798,13 +1156,23 @@ func (sh *Shard) Heartbeat() {
 
 	for {
 		sh.RLock()
+		ctx := sh.ctx
 		heartbeater := sh.Heartbeater
 		sh.RUnlock()
 
+		// A nil channel blocks so the shard waits for the next HELLO
+		// whilst there is no ticker.
+		var tick <-chan time.Time
+		if heartbeater != nil {
+			tick = heartbeater.C
+		}
+
 		select {
-		case <-sh.ctx.Done():
+		case <-ctx.Done():
 			return
-		case <-heartbeater.C:
+		case <-sh.heartbeaterChanged:
+			continue
+		case <-tick:
 			sh.Logger.Debug().Msg("Heartbeating")
 			seq := atomic.LoadInt64(sh.seq)
 
@@ -820,7 +1188,7 @@ func (sh *Shard) Heartbeat() {
 				if err != nil {
 					sh.Logger.Error().Err(err).Msg("Failed to heartbeat. Reconnecting")
 
-					go sh.PublishWebhook("Failed to heartbeat. Reconnecting", "", 16760839, false)
+					go sh.PublishNoisyWebhook("Failed to heartbeat. Reconnecting", "", 16760839, false)
 				} else {
 					sh.Manager.Sandwich.ConfigurationMu.RLock()
 					sh.Logger.Warn().Err(err).
@@ -828,7 +1196,7 @@ func (sh *Shard) Heartbeat() {
 							"Gateway failed to ACK and has passed MaxHeartbeatFailures of %d. Reconnecting",
 							sh.Manager.Configuration.Bot.MaxHeartbeatFailures)
 
-					go sh.PublishWebhook(fmt.Sprintf(
+					go sh.PublishNoisyWebhook(fmt.Sprintf(
 						"Gateway failed to ACK and has passed MaxHeartbeatFailures of %d. Reconnecting",
 						sh.Manager.Configuration.Bot.MaxHeartbeatFailures), "", 1548214, false)
 
@@ -848,9 +1216,7 @@ func (sh *Shard) Heartbeat() {
 
 // decodeContent converts the stored msg into the passed interface.
 func (sh *Shard) decodeContent(msg discord.ReceivedPayload, out interface{}) (err error) {
-	err = json.Unmarshal(msg.Data, &out)
-
-	return
+	return decodeEvent(msg, out)
 }
 
 // readMessage fills the shard msg buffer from a websocket message.
@@ -863,11 +1229,17 @@ func (sh *Shard) readMessage() (msg discord.ReceivedPayload, err error) {
 	}
 
 	sh.RLock()
+	ctx := sh.ctx
 	errorch := sh.ErrorCh
 	messagech := sh.MessageCh
 	sh.RUnlock()
 
+	// Closing the shard stops the websocket feeding these channels without
+	// sending an error so the context is watched too, otherwise the read
+	// loop would wait on a connection that is gone.
 	select {
+	case <-ctx.Done():
+		return msg, ctx.Err()
 	case err = <-errorch:
 		return msg, err
 	case msg = <-messagech:
@@ -879,15 +1251,13 @@ func (sh *Shard) readMessage() (msg discord.ReceivedPayload, err error) {
 
 // CloseWS closes the websocket. This will always return 0 as the error is suppressed.
 func (sh *Shard) CloseWS(statusCode websocket.StatusCode) (err error) {
-	if sh.wsConn != nil {
-		sh.Logger.Debug().Str("code", statusCode.String()).Msg("Closing websocket connection")
-
-		err = sh.wsConn.Close(statusCode, "")
-		if err != nil && !xerrors.Is(err, context.Canceled) {
-			sh.Logger.Warn().Err(err).Msg("Failed to close websocket connection")
-		}
+	conn, err := sh.ws.CloseCurrent(statusCode)
+	if conn != nil {
+		sh.Logger.Debug().Str("code", statusCode.String()).Msg("Closed websocket connection")
+	}
 
-		sh.wsConn = nil
+	if err != nil && !xerrors.Is(err, context.Canceled) {
+		sh.Logger.Warn().Err(err).Msg("Failed to close websocket connection")
 	}
 
 	return nil
@@ -903,11 +1273,17 @@ func (sh *Shard) Resume() (err error) {
 	sh.Manager.ConfigurationMu.RLock()
 	defer sh.Manager.ConfigurationMu.RUnlock()
 
+	seq := atomic.LoadInt64(sh.seq)
+	sh.startResume(seq)
+
 	err = sh.SendEvent(discord.GatewayOpResume, discord.Resume{
 		Token:     sh.Manager.Configuration.Token,
 		SessionID: sh.sessionID,
-		Sequence:  atomic.LoadInt64(sh.seq),
+		Sequence:  seq,
 	})
+	if err != nil {
+		sh.cancelResume()
+	}
 
 	return
 }
@@ -921,16 +1297,9 @@ func (sh *Shard) Identify() (err error) {
 	sh.Manager.ConfigurationMu.RLock()
 	defer sh.Manager.ConfigurationMu.RUnlock()
 
-	hash, err := QuickHash(sh.Manager.Configuration.Token)
-	if err != nil {
-		sh.Logger.Error().Err(err).Msg("Failed to generate token hash")
-
-		return err
-	}
-
 	sh.Manager.GatewayMu.RLock()
 	err = sh.Manager.Sandwich.Buckets.WaitForBucket(
-		fmt.Sprintf("gw:%s:%d", hash, sh.ShardID%sh.Manager.Gateway.SessionStartLimit.MaxConcurrency),
+		fmt.Sprintf("gw:%s:%d", sh.Manager.TokenHash(), sh.ShardID%sh.Manager.Gateway.SessionStartLimit.MaxConcurrency),
 	)
 	sh.Manager.GatewayMu.RUnlock()
 
@@ -940,20 +1309,14 @@ func (sh *Shard) Identify() (err error) {
 		sh.Logger.Error().Err(err).Msg("Failed to wait for bucket")
 	}
 
-	err = sh.SendEvent(discord.GatewayOpIdentify, discord.Identify{
-		Token: sh.Manager.Configuration.Token,
-		Properties: &discord.IdentifyProperties{
-			OS:      runtime.GOOS,
-			Browser: "Sandwich " + VERSION,
-			Device:  "Sandwich " + VERSION,
-		},
-		Compress:           sh.Manager.Configuration.Bot.Compression,
-		LargeThreshold:     sh.Manager.Configuration.Bot.LargeThreshold,
-		Shard:              [2]int{sh.ShardID, sh.ShardGroup.ShardCount},
-		Presence:           sh.Manager.Configuration.Bot.DefaultPresence,
-		GuildSubscriptions: sh.Manager.Configuration.Bot.GuildSubscriptions,
-		Intents:            sh.Manager.Configuration.Bot.Intents,
-	})
+	payload, err := sh.identifyPayload()
+	if err != nil {
+		return err
+	}
+
+	sh.recordIdentify(payload)
+
+	err = sh.SendEvent(discord.GatewayOpIdentify, payload)
 
 	return err
 }
@@ -976,18 +1339,36 @@ func (sh *Shard) SendEvent(op discord.GatewayOp, data interface{}) (err error) {
 
 // WriteJSON writes json data to the websocket.
 func (sh *Shard) WriteJSON(op discord.GatewayOp, i interface{}) (err error) {
-	res, err := json.Marshal(i)
+	buf := sh.cp.Get().(*bytes.Buffer)
+
+	defer func() {
+		buf.Reset()
+		sh.cp.Put(buf)
+	}()
+
+	stream := json.BorrowStream(buf)
+	stream.WriteVal(i)
+	_ = stream.Flush()
+	err = stream.Error
+	json.ReturnStream(stream)
+
 	if err != nil {
 		return xerrors.Errorf("writeJSON marshal: %w", err)
 	}
 
+	res := buf.Bytes()
+
+	// The connection is captured before waiting on the bucket so the message
+	// is dropped rather than sent on a connection made whilst waiting.
+	conn, generation := sh.ws.Get()
+
 	// We will bypass the WS bucket when it is a heartbeat.
 	// We do this to always ensure that heartbeat is not blocked if we are fetching
 	// member chunks, for example. To still ensure we are not passing the 120 messages
 	// per minute ratelimit on the gateway, we only allow up to 115 messages a minute
 	// for non heartbeat messages. We should only really make it 118 in cases where it
 	// heartbeats twice in a minute but allowing up to 5 a minute is more safe.
-	if i != discord.GatewayOpHeartbeat {
+	if op != discord.GatewayOpHeartbeat {
 		err = sh.Manager.Buckets.WaitForBucket(
 			fmt.Sprintf("ws:%d:%d", sh.ShardID, sh.ShardGroup.ShardCount),
 		)
@@ -996,12 +1377,16 @@ func (sh *Shard) WriteJSON(op discord.GatewayOp, i interface{}) (err error) {
 		}
 	}
 
-	sh.Manager.Sandwich.ConfigurationMu.RLock()
-	sh.Logger.Trace().Msg(strings.ReplaceAll(gotils.B2S(res), sh.Manager.Configuration.Token, "..."))
-	sh.Manager.Sandwich.ConfigurationMu.RUnlock()
+	// Redacting the token copies the payload so it is only done when it
+	// will be logged.
+	if e := sh.Logger.Trace(); e.Enabled() {
+		e.Msg(sh.Manager.Redact(gotils.B2S(res)))
+	}
 
-	if sh.wsConn != nil {
-		err = sh.wsConn.Write(sh.ctx, websocket.MessageText, res)
+	if conn != nil {
+		// The bucket is waited on before the payload is queued so the writer
+		// is never held up by it and heartbeats can be sent straight away.
+		err = sh.queueSend(op, res, generation)
 		if err != nil {
 			return xerrors.Errorf("writeJSON write: %w", err)
 		}
@@ -1010,18 +1395,51 @@ func (sh *Shard) WriteJSON(op discord.GatewayOp, i interface{}) (err error) {
 	return nil
 }
 
+// markReady closes the ready channel of the shard. It is safe to call for
+// every READY and RESUMED received during the same session.
+func (sh *Shard) markReady() {
+	sh.readyMu.Lock()
+	defer sh.readyMu.Unlock()
+
+	select {
+	case <-sh.ready:
+	default:
+		close(sh.ready)
+	}
+}
+
+// resetReady replaces the ready channel if it has been closed so
+// WaitForReady waits for the next session to be ready.
+func (sh *Shard) resetReady() {
+	sh.readyMu.Lock()
+	defer sh.readyMu.Unlock()
+
+	select {
+	case <-sh.ready:
+		sh.ready = make(chan void)
+	default:
+	}
+}
+
 // WaitForReady waits until the shard is ready.
 func (sh *Shard) WaitForReady() {
 	since := time.Now().UTC()
 	t := time.NewTicker(waitForReadyTimeout)
+	defer t.Stop()
+
+	sh.readyMu.Lock()
+	ready := sh.ready
+	sh.readyMu.Unlock()
+
+	ctx := sh.currentContext()
 
 	for {
 		select {
-		case <-sh.ready:
+		case <-ready:
 			sh.Logger.Debug().Msg("Shard ready due to channel closure")
 
 			return
-		case <-sh.ctx.Done():
+		case <-ctx.Done():
 			sh.Logger.Debug().Msg("Shard ready due to context done")
 
 			return
@@ -1037,7 +1455,7 @@ func (sh *Shard) WaitForReady() {
 			}
 
 			sh.Logger.Debug().
-				Err(sh.ctx.Err()).
+				Err(ctx.Err()).
 				Dur("since", time.Now().UTC().Sub(since).Round(time.Second)).
 				Msg("Still waiting for shard to be ready")
 		}
@@ -1046,15 +1464,36 @@ func (sh *Shard) WaitForReady() {
 
 // Reconnect attempts to reconnect to the gateway.
 func (sh *Shard) Reconnect(code websocket.StatusCode) error {
-	wait := time.Second
+	wait := minReconnectWait
+	limit := sh.Manager.maxReconnectWait()
+
+	// Close sets the status to closed so the disconnect is counted here
+	// instead of when the status changes to reconnecting.
+	sh.StatusMu.RLock()
+	wasReady := sh.Status == structs.ShardReady
+	sh.StatusMu.RUnlock()
 
 	sh.Close(code)
 
+	if wasReady {
+		sh.Manager.Sandwich.recordDisconnect(time.Now().UTC())
+	}
+
 	if err := sh.SetStatus(structs.ShardReconnecting); err != nil {
 		sh.Logger.Error().Err(err).Msg("Encountered error setting shard status")
 	}
 
+	if delay := time.Duration(atomic.SwapInt64(sh.reconnectDelay, 0)); delay > 0 {
+		sh.Logger.Debug().Dur("delay", delay).Msg("Waiting before identifying after an invalid session")
+		<-time.After(delay)
+	}
+
 	for {
+		if delay := sh.Manager.Sandwich.incidentBackoff(wait); delay > 0 {
+			sh.Logger.Debug().Dur("delay", delay).Msg("Delaying reconnect whilst there is a gateway incident")
+			<-time.After(delay)
+		}
+
 		sh.Logger.Info().Msg("Trying to reconnect to gateway")
 
 		err := sh.Connect()
@@ -1062,6 +1501,12 @@ func (sh *Shard) Reconnect(code websocket.StatusCode) error {
 			atomic.StoreInt32(sh.Retries, sh.Manager.Configuration.Bot.Retries)
 			sh.Logger.Info().Msg("Successfully reconnected to gateway")
 
+			// The read loop stops when the shard is closed so it is started
+			// again when reconnecting from elsewhere, such as the watchdog.
+			// If the loop is still stopping, it carries on instead.
+			sh.reopen.Set()
+			go sh.Open()
+
 			return nil
 		}
 
@@ -1072,32 +1517,78 @@ func (sh *Shard) Reconnect(code websocket.StatusCode) error {
 
 			err = sh.Connect()
 			if err != nil {
-				go sh.PublishWebhook("Failed to reconnect to gateway", err.Error(), 14431557, false)
+				go sh.PublishNoisyWebhook("Failed to reconnect to gateway", err.Error(), 14431557, false)
+			} else {
+				sh.reopen.Set()
+				go sh.Open()
 			}
 
 			return err
 		}
 
-		sh.Logger.Warn().Err(err).Dur("retry", wait).Msg("Failed to reconnect to gateway")
-		<-time.After(wait)
+		retryIn := jitterReconnectWait(wait)
 
-		wait *= 2
-		if wait > maxReconnectWait {
-			wait = maxReconnectWait
+		sh.Logger.Warn().Err(err).Dur("retry", retryIn).Msg("Failed to reconnect to gateway")
+
+		if err = sh.setStatus(structs.ShardReconnecting, retryIn); err != nil {
+			sh.Logger.Error().Err(err).Msg("Encountered error setting shard status")
 		}
+
+		<-time.After(retryIn)
+
+		wait = nextReconnectWait(wait, limit)
 	}
 }
 
-// SetStatus changes the Shard status.
+// SetStatus changes the Shard status. All status changes go through here so
+// transitions which are not allowed by the shard state machine are rejected.
+// Setting the status the shard already has does nothing.
 func (sh *Shard) SetStatus(status structs.ShardStatus) (err error) {
+	return sh.setStatus(status, 0)
+}
+
+// setStatus changes the Shard status. retryIn is how long until the shard
+// next tries to connect whilst reconnecting. It is sent to consumers in
+// SHARD_STATUS even if the status has not changed.
+func (sh *Shard) setStatus(status structs.ShardStatus, retryIn time.Duration) (err error) {
 	sh.StatusMu.Lock()
+	previous := sh.Status
+
+	if previous == status {
+		sh.StatusMu.Unlock()
+
+		if retryIn > 0 {
+			return sh.publishStatus(status, retryIn)
+		}
+
+		return nil
+	}
+
+	if !previous.CanTransition(status) {
+		sh.StatusMu.Unlock()
+
+		return xerrors.Errorf("set status %s to %s: %w", previous.String(), status.String(), ErrInvalidTransition)
+	}
+
+	now := time.Now().UTC()
+
 	sh.Status = status
+	sh.StatusSince = now
 	sh.StatusMu.Unlock()
 
+	if previous == structs.ShardReady && (status == structs.ShardReconnecting || status == structs.ShardWaiting) {
+		sh.Manager.Sandwich.recordDisconnect(now)
+	}
+
+	if status == structs.ShardReconnecting {
+		sh.Logger.autoEscalate("shard is reconnecting")
+	}
+
 	sh.Logger.Debug().
 		Str("manager", sh.Manager.Configuration.Identifier).
 		Int32("shardgroup", sh.ShardGroup.ID).
 		Int("shard", sh.ShardID).
+		Str("previous", previous.String()).
 		Msgf("Status changed to %s (%d)", status.String(), status)
 
 	switch status {
@@ -1106,7 +1597,7 @@ func (sh *Shard) SetStatus(status structs.ShardStatus) (err error) {
 		isMinimal := sh.Manager.Sandwich.Configuration.Logging.MinimalWebhooks
 		sh.Manager.ConfigurationMu.RUnlock()
 
-		go sh.PublishWebhook(fmt.Sprintf("Shard is now **%s**", status.String()), "", status.Colour(), isMinimal)
+		go sh.PublishNoisyWebhook(fmt.Sprintf("Shard is now **%s**", status.String()), "", status.Colour(), isMinimal)
 	case structs.ShardIdle,
 		structs.ShardWaiting,
 		structs.ShardConnecting,
@@ -1114,21 +1605,58 @@ func (sh *Shard) SetStatus(status structs.ShardStatus) (err error) {
 		structs.ShardClosed:
 	}
 
+	return sh.publishStatus(status, retryIn)
+}
+
+// publishStatus sends SHARD_STATUS to consumers.
+func (sh *Shard) publishStatus(status structs.ShardStatus, retryIn time.Duration) (err error) {
 	packet := sh.pp.Get().(*structs.SandwichPayload)
 	defer sh.pp.Put(packet)
 
 	packet.ReceivedPayload = discord.ReceivedPayload{
 		Type: "SHARD_STATUS",
 	}
+	packet.Metadata = structs.SandwichMetadata{}
 
-	packet.Data = structs.MessagingStatusUpdate{
+	// The packet may have last been a dispatch whose trace is still written
+	// to once it has been published.
+	packet.Trace = nil
+	packet.Extra = nil
+
+	update := structs.MessagingStatusUpdate{
 		ShardID: sh.ShardID,
 		Status:  int32(status),
 	}
 
+	if retryIn > 0 {
+		update.RetryIn = retryIn.Milliseconds()
+		update.RetryAt = time.Now().UTC().Add(retryIn).UnixNano() / int64(time.Millisecond)
+	}
+
+	packet.Data = update
+
 	return sh.PublishEvent(packet)
 }
 
+// SinceLastDispatch returns how long it has been since the shard last
+// received a dispatch event.
+func (sh *Shard) SinceLastDispatch() time.Duration {
+	return time.Now().UTC().Sub(time.Unix(0, atomic.LoadInt64(sh.lastDispatch)))
+}
+
+// Reidentify discards the current session and reconnects the shard which
+// will make it identify instead of resume.
+func (sh *Shard) Reidentify() (err error) {
+	sh.Lock()
+	sh.sessionID = ""
+	sh.resumeGatewayURL = ""
+	sh.Unlock()
+
+	atomic.StoreInt64(sh.seq, 0)
+
+	return sh.Reconnect(websocket.StatusNormalClosure)
+}
+
 // Latency returns the heartbeat latency in milliseconds.
 func (sh *Shard) Latency() (latency int64) {
 	sh.LastHeartbeatMu.RLock()
@@ -1137,17 +1665,58 @@ func (sh *Shard) Latency() (latency int64) {
 	return sh.LastHeartbeatAck.Sub(sh.LastHeartbeatSent).Round(time.Millisecond).Milliseconds()
 }
 
+// SmoothedLatency returns the exponentially weighted moving average of the
+// heartbeat latency and the jitter around it in milliseconds. Decisions based
+// on latency should use this rather than Latency, which only reflects the
+// last heartbeat.
+func (sh *Shard) SmoothedLatency() (ewma float64, jitter float64) {
+	sh.LastHeartbeatMu.RLock()
+	defer sh.LastHeartbeatMu.RUnlock()
+
+	return sh.latencyEWMA, sh.jitter
+}
+
+// recordLatency adds the round trip of a heartbeat to the smoothed latency.
+// The first heartbeat of a connection sets it directly. LastHeartbeatMu must
+// be held.
+func (sh *Shard) recordLatency(rtt time.Duration) {
+	sample := float64(rtt) / float64(time.Millisecond)
+
+	if sh.latencyEWMA == 0 {
+		sh.latencyEWMA = sample
+
+		return
+	}
+
+	deviation := math.Abs(sample - sh.latencyEWMA)
+
+	sh.latencyEWMA += latencyEWMAWeight * (sample - sh.latencyEWMA)
+	sh.jitter += latencyEWMAWeight * (deviation - sh.jitter)
+}
+
 // Close closes the shard connection.
 func (sh *Shard) Close(code websocket.StatusCode) {
 	// Ensure that if we close during shardgroup connecting, it will not
 	// feedback loop.
 	// cancel is only defined when Connect() has been ran on a shard.
 	// If the ShardGroup was closed before this happens, it would segmentation fault.
-	if sh.ctx != nil && sh.cancel != nil {
-		sh.cancel()
+	sh.RLock()
+	cancel := sh.cancel
+	sh.RUnlock()
+
+	if cancel != nil {
+		cancel()
+	}
+
+	sh.stopHeartbeater()
+
+	sh.closeSession(code)
+
+	if dropped := sh.sends.drop(ErrSendQueueClosed); dropped > 0 {
+		sh.Logger.Debug().Int("dropped", dropped).Msg("Dropped queued payloads whilst closing")
 	}
 
-	if sh.wsConn != nil {
+	if conn, _ := sh.ws.Get(); conn != nil {
 		if err := sh.CloseWS(code); err != nil {
 			// It is highly common we are closing an already closed websocket
 			// and at this point if we error closing it, its fair game. It would
@@ -1164,6 +1733,15 @@ func (sh *Shard) Close(code websocket.StatusCode) {
 
 // ChunkGuild requests guild chunks for a guild.
 func (sh *Shard) ChunkGuild(guildID snowflake.ID, wait bool) (err error) {
+	_, err = sh.requestGuildChunks(guildID, wait)
+
+	return err
+}
+
+// requestGuildChunks requests guild chunks for a guild like ChunkGuild and
+// returns the member chunks received. Chunks are only counted when wait is
+// set and the guild was not already being chunked.
+func (sh *Shard) requestGuildChunks(guildID snowflake.ID, wait bool) (chunks int, err error) {
 	sh.ShardGroup.MemberChunksCompleteMu.RLock()
 	completed, ok := sh.ShardGroup.MemberChunksComplete[guildID]
 	sh.ShardGroup.MemberChunksCompleteMu.RUnlock()
@@ -1202,7 +1780,7 @@ func (sh *Shard) ChunkGuild(guildID snowflake.ID, wait bool) (err error) {
 		go sh.chunkGuild(guildID, true) // nolint:errcheck
 	}
 
-	return nil
+	return 0, nil
 }
 
 // cleanGuildChunks all traces of a guild from the member chunking
@@ -1212,18 +1790,13 @@ func (sh *Shard) cleanGuildChunks(guildID snowflake.ID) {
 	delete(sh.ShardGroup.MemberChunksCallback, guildID)
 	sh.ShardGroup.MemberChunksCallbackMu.Unlock()
 
-	sh.ShardGroup.MemberChunkCallbacksMu.Lock()
-	close(sh.ShardGroup.MemberChunkCallbacks[guildID])
-	delete(sh.ShardGroup.MemberChunkCallbacks, guildID)
-	sh.ShardGroup.MemberChunkCallbacksMu.Unlock()
-
 	sh.ShardGroup.MemberChunksCompleteMu.Lock()
 	delete(sh.ShardGroup.MemberChunksComplete, guildID)
 	sh.ShardGroup.MemberChunksCompleteMu.Unlock()
 }
 
 // chunkGuild handles managing all state and cleaning it up.
-func (sh *Shard) chunkGuild(guildID snowflake.ID, waitForTicket bool) (err error) {
+func (sh *Shard) chunkGuild(guildID snowflake.ID, waitForTicket bool) (chunks int, err error) {
 	var ticket int
 
 	if waitForTicket {
@@ -1235,6 +1808,7 @@ func (sh *Shard) chunkGuild(guildID snowflake.ID, waitForTicket bool) (err error
 	}
 
 	start := time.Now().UTC()
+	timeouts := sh.Manager.shardTimeouts()
 
 	sh.Logger.Debug().
 		Int("guild_id", int(guildID.Int64())).
@@ -1250,14 +1824,12 @@ func (sh *Shard) chunkGuild(guildID snowflake.ID, waitForTicket bool) (err error
 	sh.ShardGroup.MemberChunksComplete[guildID] = completed
 	sh.ShardGroup.MemberChunksCompleteMu.Unlock()
 
-	// Channel to signify when MEMBER_CHUNKs are received by the
-	// gateway as this task does not handle reading and is "stateless".
-	// We inform the channel when we receive it.
-	chunkCallbacks := make(chan bool)
+	// The chunks of this request are matched by its nonce as this task
+	// does not handle reading and is "stateless".
+	request := newMemberChunkRequest(guildID)
+	nonce := sh.ShardGroup.addMemberChunkRequest(request)
 
-	sh.ShardGroup.MemberChunkCallbacksMu.Lock()
-	sh.ShardGroup.MemberChunkCallbacks[guildID] = chunkCallbacks
-	sh.ShardGroup.MemberChunkCallbacksMu.Unlock()
+	defer sh.ShardGroup.removeMemberChunkRequest(nonce)
 
 	// Channel to signify when chunking has completed.
 	// If we find a waitgroup, we should wait for it to be done
@@ -1275,6 +1847,7 @@ func (sh *Shard) chunkGuild(guildID snowflake.ID, waitForTicket bool) (err error
 		GuildID: guildID,
 		Query:   "",
 		Limit:   0,
+		Nonce:   nonce,
 	})
 	if err != nil {
 		sh.Logger.Error().Err(err).
@@ -1282,14 +1855,16 @@ func (sh *Shard) chunkGuild(guildID snowflake.ID, waitForTicket bool) (err error
 			Msg("Failed to chunk guild")
 
 		sh.cleanGuildChunks(guildID)
+		sh.Manager.recordChunkFailure(sh, guildID, err)
 
-		return err
+		return 0, err
 	}
 
-	t := time.NewTicker(initialMemberChunkTimeout)
+	t := time.NewTicker(timeouts.initialChunk)
+	defer t.Stop()
 
 	select {
-	case <-chunkCallbacks:
+	case <-request.chunks:
 		break
 	case <-t.C:
 		sh.Logger.Warn().
@@ -1297,27 +1872,37 @@ func (sh *Shard) chunkGuild(guildID snowflake.ID, waitForTicket bool) (err error
 			Msg("Timed out on initial member chunks")
 
 		sh.cleanGuildChunks(guildID)
+		sh.Manager.recordChunkFailure(sh, guildID, ErrChunkTimeout)
 
-		return ErrChunkTimeout
+		return 0, ErrChunkTimeout
 	}
 
-	t.Reset(memberChunkTimeout)
-
-	receivedMemberChunks := 1
+	t.Reset(timeouts.chunk)
 
+	// Chunking is complete once every chunk_index has been received. The
+	// timeout is only a safety net for chunks discord never sends.
 memberChunks:
 	for {
 		select {
-		case <-chunkCallbacks:
-			receivedMemberChunks++
-			t.Reset(memberChunkTimeout)
+		case <-request.complete:
+			received, _ := request.progress()
+
 			sh.Logger.Debug().
 				Int64("guild_id", guildID.Int64()).
-				Msg("Received member chunk")
+				Int("received", received).
+				Int64("duration", time.Now().UTC().Sub(start).Round(time.Millisecond).Milliseconds()).
+				Msg("Received all member chunks")
+
+			break memberChunks
+		case <-request.chunks:
+			t.Reset(timeouts.chunk)
 		case <-t.C:
-			sh.Logger.Debug().
+			received, expected := request.progress()
+
+			sh.Logger.Warn().
 				Int64("guild_id", guildID.Int64()).
-				Int("received", receivedMemberChunks).
+				Int("received", received).
+				Int("expected", expected).
 				Int64("duration", time.Now().UTC().Sub(start).Round(time.Millisecond).Milliseconds()).
 				Msg("Timed out on member chunks")
 
@@ -1325,12 +1910,16 @@ memberChunks:
 		}
 	}
 
+	receivedMemberChunks, _ := request.progress()
+
 	// Finish marking chunking as done and handle closing.
 	wg.Done()
 	completed.Set()
 
+	sh.Manager.clearChunkFailure(guildID)
+
 	go func() {
-		time.Sleep(chunkStatePersistTimeout)
+		time.Sleep(timeouts.chunkPersist)
 
 		sh.cleanGuildChunks(guildID)
 
@@ -1339,53 +1928,31 @@ memberChunks:
 			Msg("Cleaned MemberChunk tables")
 	}()
 
-	return nil
+	return receivedMemberChunks, nil
 }
 
 // PublishWebhook is the same as sg.PublishWebhook but has extra sugar for
 // displaying information about the shard.
 func (sh *Shard) PublishWebhook(title string, description string, colour int, raw bool) {
-	var message discord.WebhookMessage
+	sh.Manager.Sandwich.Notify(context.Background(),
+		NewNotification(title, description).ForShard(sh).WithColour(colour).AsRaw(raw))
+}
 
-	if raw {
-		message = discord.WebhookMessage{
-			Content: fmt.Sprintf("[**%s - %d/%d**] %s %s",
-				sh.Manager.Configuration.DisplayName,
-				sh.ShardGroup.ID, sh.ShardID, title, description),
-		}
+// PublishNoisyWebhook is used for reconnect and heartbeat notifications. It is
+// the same as PublishWebhook however it is only logged whilst the manager is
+// in maintenance.
+func (sh *Shard) PublishNoisyWebhook(title string, description string, colour int, raw bool) {
+	if sh.Manager.InMaintenance() {
+		sh.Logger.Debug().Str("title", title).Msg("Suppressed webhook as manager is in maintenance")
 
-		sh.RLock()
-		if sh.User != nil && message.AvatarURL == "" && message.Username == "" {
-			message.AvatarURL = fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-				sh.User.ID.String(), sh.User.Avatar)
-			message.Username = sh.User.Username
-		}
-		sh.RUnlock()
-	} else {
-		message = discord.WebhookMessage{
-			Embeds: []discord.Embed{
-				{
-					Title:       title,
-					Description: description,
-					Color:       colour,
-					Timestamp:   WebhookTime(time.Now().UTC()),
-					Footer: &discord.EmbedFooter{
-						Text: fmt.Sprintf("Manager %s | ShardGroup %d | Shard %d",
-							sh.Manager.Configuration.DisplayName,
-							sh.ShardGroup.ID, sh.ShardID),
-					},
-				},
-			},
-		}
+		return
+	}
 
-		sh.RLock()
-		if sh.User != nil && message.AvatarURL == "" && message.Username == "" {
-			message.AvatarURL = fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png",
-				sh.User.ID.String(), sh.User.Avatar)
-			message.Username = sh.User.Username
-		}
-		sh.RUnlock()
+	if sh.Manager.Sandwich.InIncident() {
+		sh.Logger.Debug().Str("title", title).Msg("Suppressed webhook as there is a gateway incident")
+
+		return
 	}
 
-	sh.Manager.Sandwich.PublishWebhook(context.Background(), message)
+	sh.PublishWebhook(title, description, colour, raw)
 }
@@ -0,0 +1,742 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/rs/zerolog"
+	"nhooyr.io/websocket"
+)
+
+const testToken = "NzkyNzE1NDU0MTk2MDg4ODQy.X-hvzA.Ovy4MCQywSkoMRRclStW4xAYK7I"
+
+// testGateway is a websocket server which records the frames shards send.
+// When reply is set, it is sent HELLO on connecting and replies to each
+// frame with what reply returns, if anything.
+type testGateway struct {
+	server *httptest.Server
+	frames chan []byte
+
+	// When set, frames are passed to record along with the index of their
+	// connection, in the order connections were accepted, instead of being
+	// sent to frames.
+	record   func(index int, frame []byte)
+	accepted *int64
+
+	reply func(payload discord.SentPayload) interface{}
+}
+
+func newTestGateway(t *testing.T) *testGateway {
+	t.Helper()
+
+	return startTestGateway(t, &testGateway{})
+}
+
+// newDiscordGateway creates a gateway which says HELLO and answers IDENTIFY
+// with READY and RESUME with RESUMED.
+func newDiscordGateway(t *testing.T) *testGateway {
+	t.Helper()
+
+	return startTestGateway(t, &testGateway{reply: discordReply})
+}
+
+// discordReply answers IDENTIFY with READY and RESUME with RESUMED.
+func discordReply(payload discord.SentPayload) interface{} {
+	switch discord.GatewayOp(payload.Op) {
+	case discord.GatewayOpIdentify:
+		return map[string]interface{}{
+			"op": discord.GatewayOpDispatch, "t": "READY", "s": 1,
+			"d": map[string]interface{}{"session_id": "session", "user": map[string]string{"id": "1"}},
+		}
+	case discord.GatewayOpResume:
+		return map[string]interface{}{"op": discord.GatewayOpDispatch, "t": "RESUMED", "s": 2, "d": nil}
+	default:
+		return nil
+	}
+}
+
+func startTestGateway(t *testing.T, gw *testGateway) *testGateway {
+	t.Helper()
+
+	gw.frames = make(chan []byte, 256)
+	gw.accepted = new(int64)
+
+	gw.server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(rw, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.StatusNormalClosure, "")
+
+		index := int(atomic.AddInt64(gw.accepted, 1) - 1)
+
+		if gw.reply != nil {
+			hello := map[string]interface{}{"op": discord.GatewayOpHello, "d": map[string]int{"heartbeat_interval": 45000}}
+			if gw.write(r.Context(), conn, hello) != nil {
+				return
+			}
+		}
+
+		for {
+			_, data, err := conn.Read(r.Context())
+			if err != nil {
+				return
+			}
+
+			if gw.record != nil {
+				gw.record(index, data)
+			} else {
+				gw.frames <- data
+			}
+
+			if gw.reply == nil {
+				continue
+			}
+
+			payload := discord.SentPayload{}
+			if json.Unmarshal(data, &payload) != nil {
+				continue
+			}
+
+			if response := gw.reply(payload); response != nil && gw.write(r.Context(), conn, response) != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(gw.server.Close)
+
+	return gw
+}
+
+func (gw *testGateway) write(ctx context.Context, conn *websocket.Conn, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return conn.Write(ctx, websocket.MessageText, data)
+}
+
+// url returns the websocket url of the gateway.
+func (gw *testGateway) url() string {
+	return "ws" + strings.TrimPrefix(gw.server.URL, "http")
+}
+
+// dial connects to the gateway. The connection is closed when the test
+// finishes.
+func (gw *testGateway) dial(t *testing.T) *websocket.Conn {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, gw.url(), nil)
+	if err != nil {
+		t.Fatalf("failed to dial gateway: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close(websocket.StatusNormalClosure, "") })
+
+	return conn
+}
+
+// frame returns the next frame the gateway received.
+func (gw *testGateway) frame(t *testing.T) []byte {
+	t.Helper()
+
+	select {
+	case frame := <-gw.frames:
+		return frame
+	case <-time.After(5 * time.Second):
+		t.Fatal("gateway received no frame")
+	}
+
+	return nil
+}
+
+// newTestShard creates the only shard of a shardgroup with testToken as the
+// token of its manager. Nothing is connected.
+func newTestShard(t testing.TB) *Shard {
+	t.Helper()
+
+	sg, err := newSandwich(ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to create sandwich: %v", err)
+	}
+
+	configuration := &ManagerConfiguration{Identifier: "test", Token: testToken}
+	configuration.Messaging.ClientName = "sandwich"
+
+	mg, err := sg.NewManager(configuration)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	mg.Gateway.SessionStartLimit.MaxConcurrency = 1
+
+	group := mg.NewShardGroup(0)
+	group.ShardCount = 1
+
+	sh := group.NewShard(0)
+	t.Cleanup(sh.cancel)
+
+	return sh
+}
+
+// connectTestShard connects a shard to a new gateway.
+func connectTestShard(t *testing.T, sh *Shard) *testGateway {
+	t.Helper()
+
+	gw := newTestGateway(t)
+	sh.ws.Swap(gw.dial(t))
+
+	return gw
+}
+
+func TestWriteJSONRedactsLogs(t *testing.T) {
+	levels := []zerolog.Level{
+		zerolog.TraceLevel, zerolog.DebugLevel, zerolog.InfoLevel, zerolog.WarnLevel, zerolog.ErrorLevel,
+	}
+
+	for _, level := range levels {
+		sh := newTestShard(t)
+		gw := connectTestShard(t, sh)
+
+		logs := &bytes.Buffer{}
+		sh.Logger = newShardLogger(sh.Manager.Sandwich, zerolog.New(logs).Level(level))
+
+		if err := sh.Identify(); err != nil {
+			t.Fatalf("%s: identify failed: %v", level, err)
+		}
+
+		if err := sh.Resume(); err != nil {
+			t.Fatalf("%s: resume failed: %v", level, err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if frame := gw.frame(t); !bytes.Contains(frame, []byte(testToken)) {
+				t.Errorf("%s: sent frame was redacted: %s", level, frame)
+			}
+		}
+
+		if level == zerolog.TraceLevel && !strings.Contains(logs.String(), redactedToken) {
+			t.Errorf("trace logs did not include the redacted frames: %s", logs)
+		}
+
+		if strings.Contains(logs.String(), testToken) {
+			t.Errorf("%s: token was logged: %s", level, logs)
+		}
+	}
+}
+
+func TestWriteJSONReusedBuffers(t *testing.T) {
+	sh := newTestShard(t)
+	gw := connectTestShard(t, sh)
+
+	if err := sh.Identify(); err != nil {
+		t.Fatalf("identify failed: %v", err)
+	}
+
+	gw.frame(t)
+
+	// Frames written after the identify reuse its buffer and must not
+	// include what was left in it.
+	for i := 0; i < 10; i++ {
+		if err := sh.SendEvent(discord.GatewayOpHeartbeat, i); err != nil {
+			t.Fatalf("heartbeat failed: %v", err)
+		}
+
+		frame := gw.frame(t)
+		if bytes.Contains(frame, []byte(testToken)) {
+			t.Fatalf("heartbeat included the token: %s", frame)
+		}
+
+		payload := discord.SentPayload{}
+		if err := json.Unmarshal(frame, &payload); err != nil {
+			t.Fatalf("heartbeat was not valid json: %s", frame)
+		}
+	}
+}
+
+func TestWriteJSONHeartbeatBypassesBucket(t *testing.T) {
+	const bucketReset = 500 * time.Millisecond
+
+	sh := newTestShard(t)
+	gw := connectTestShard(t, sh)
+
+	// The bucket only allows one message and it has already been used.
+	bucket := fmt.Sprintf("ws:%d:%d", sh.ShardID, sh.ShardGroup.ShardCount)
+	sh.Manager.Buckets.CreateBucket(bucket, 1, bucketReset)
+
+	if err := sh.Manager.Buckets.WaitForBucket(bucket); err != nil {
+		t.Fatalf("failed to use bucket: %v", err)
+	}
+
+	exhausted := time.Now()
+
+	chunked := make(chan error, 1)
+
+	go func() {
+		chunked <- sh.SendEvent(discord.GatewayOpRequestGuildMembers, discord.RequestGuildMembers{GuildID: testGuildID})
+	}()
+
+	if err := sh.SendEvent(discord.GatewayOpHeartbeat, 1); err != nil {
+		t.Fatalf("heartbeat failed: %v", err)
+	}
+
+	frame := gw.frame(t)
+	if elapsed := time.Since(exhausted); elapsed >= bucketReset/2 {
+		t.Errorf("heartbeat took %s with an exhausted bucket", elapsed)
+	}
+
+	if payload := (discord.SentPayload{}); json.Unmarshal(frame, &payload) != nil ||
+		payload.Op != int(discord.GatewayOpHeartbeat) {
+		t.Errorf("first frame was not the heartbeat: %s", frame)
+	}
+
+	select {
+	case err := <-chunked:
+		t.Fatalf("chunk request was sent with an exhausted bucket: %v", err)
+	default:
+	}
+
+	if err := <-chunked; err != nil {
+		t.Fatalf("chunk request failed: %v", err)
+	}
+
+	if elapsed := time.Since(exhausted); elapsed < bucketReset*9/10 {
+		t.Errorf("chunk request was sent after %s, before the bucket reset", elapsed)
+	}
+
+	gw.frame(t)
+}
+
+// connectDiscordGateway connects a shard to gw and returns the first event
+// it receives without handling it.
+func connectDiscordGateway(t *testing.T, sh *Shard, gw *testGateway) discord.ReceivedPayload {
+	t.Helper()
+
+	sh.Manager.GatewayMu.Lock()
+	sh.Manager.Gateway.URL = gw.url()
+	sh.Manager.GatewayMu.Unlock()
+
+	if err := sh.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	sh.RLock()
+	messages := sh.MessageCh
+	sh.RUnlock()
+
+	return <-messages
+}
+
+// waitForReady returns if WaitForReady returned within timeout.
+func waitForReady(sh *Shard, timeout time.Duration) bool {
+	ready := make(chan void)
+
+	go func() {
+		sh.WaitForReady()
+		close(ready)
+	}()
+
+	select {
+	case <-ready:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestWaitForReadyReconnect(t *testing.T) {
+	sh := newTestShard(t)
+	sh.Manager.swapProducer(&mqclients.NoneMQClient{})
+
+	gw := newDiscordGateway(t)
+
+	// READY is processed once no GUILD_CREATE has been received for
+	// timeoutDuration.
+	go sh.OnEvent(connectDiscordGateway(t, sh, gw))
+
+	if op := sentOp(t, gw.frame(t)); op != discord.GatewayOpIdentify {
+		t.Fatalf("first connect sent op %d, want identify", op)
+	}
+
+	if !waitForReady(sh, timeoutDuration+3*time.Second) {
+		t.Fatal("WaitForReady did not return after READY")
+	}
+
+	// A resumed session is still ready, even before RESUMED is handled.
+	_ = sh.CloseWS(websocket.StatusNormalClosure)
+	resumed := connectDiscordGateway(t, sh, gw)
+
+	if op := sentOp(t, gw.frame(t)); op != discord.GatewayOpResume {
+		t.Fatalf("reconnect sent op %d, want resume", op)
+	}
+
+	if !waitForReady(sh, 100*time.Millisecond) {
+		t.Fatal("WaitForReady did not return whilst resuming")
+	}
+
+	sh.OnEvent(resumed)
+
+	if !waitForReady(sh, 100*time.Millisecond) {
+		t.Fatal("WaitForReady did not return after RESUMED")
+	}
+
+	// A new session waits for READY again.
+	_ = sh.CloseWS(websocket.StatusNormalClosure)
+
+	sh.Lock()
+	sh.sessionID = ""
+	sh.Unlock()
+
+	go sh.OnEvent(connectDiscordGateway(t, sh, gw))
+
+	if op := sentOp(t, gw.frame(t)); op != discord.GatewayOpIdentify {
+		t.Fatalf("new session sent op %d, want identify", op)
+	}
+
+	if waitForReady(sh, timeoutDuration/2) {
+		t.Fatal("WaitForReady returned before the new session was ready")
+	}
+
+	if !waitForReady(sh, timeoutDuration+3*time.Second) {
+		t.Fatal("WaitForReady did not return after the new READY")
+	}
+}
+
+// sentFrame identifies a frame sent by TestSendEventReconnect.
+type sentFrame struct {
+	Sender int `json:"sender"`
+	Seq    int `json:"seq"`
+}
+
+// sentFramePayload is a frame sent by TestSendEventReconnect.
+type sentFramePayload struct {
+	Op   int       `json:"op"`
+	Data sentFrame `json:"d"`
+}
+
+// messageHook calls run with the message of each log event.
+type messageHook func(msg string)
+
+func (h messageHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	h(msg)
+}
+
+func TestSendEventReconnect(t *testing.T) {
+	const (
+		senders    = 8
+		reconnects = 20
+	)
+
+	receivedMu := sync.Mutex{}
+	received := make([]receivedFrame, 0)
+
+	gw := startTestGateway(t, &testGateway{
+		reply: discordReply,
+		record: func(index int, frame []byte) {
+			receivedMu.Lock()
+			received = append(received, receivedFrame{index: index, data: string(frame)})
+			receivedMu.Unlock()
+		},
+	})
+
+	sh := newTestShard(t)
+	sh.Manager.swapProducer(&mqclients.NoneMQClient{})
+
+	// Heartbeats are written before RESUME so the frames are sent as
+	// requests whose bucket is not exhausted by the senders.
+	sh.Manager.Buckets.CreateBucket(fmt.Sprintf("ws:%d:%d", sh.ShardID, sh.ShardGroup.ShardCount), 1<<30, time.Minute)
+
+	// WriteJSON takes the connection to write to, logs the frame, then
+	// queues it to be written. Senders hold swapMu until the frame is
+	// logged so the connection taken is the one they saw, and the shard
+	// reconnects whilst holding it. Every other frame is then held back
+	// until the shard has reconnected so it is queued for a connection which
+	// has been replaced.
+	swapMu := sync.RWMutex{}
+	holding := make([]int32, senders)
+	seen := make([]int64, senders)
+
+	release := func(sender int) {
+		if atomic.CompareAndSwapInt32(&holding[sender], 1, 0) {
+			swapMu.RUnlock()
+		}
+	}
+
+	done := make(chan void)
+
+	sh.Logger = newShardLogger(sh.Manager.Sandwich, zerolog.New(ioutil.Discard).Level(zerolog.TraceLevel).
+		Hook(messageHook(func(msg string) {
+			payload := sentFramePayload{}
+			if json.Unmarshal([]byte(msg), &payload) != nil || payload.Op != int(discord.GatewayOpRequestGuildMembers) {
+				return
+			}
+
+			release(payload.Data.Sender)
+
+			if payload.Data.Seq%2 == 1 {
+				return
+			}
+
+			for {
+				if conn, generation := sh.ws.Get(); conn != nil && generation != atomic.LoadInt64(&seen[payload.Data.Sender]) {
+					return
+				}
+
+				select {
+				case <-done:
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+		})))
+
+	sh.Manager.GatewayMu.Lock()
+	sh.Manager.Gateway.URL = gw.url()
+	sh.Manager.GatewayMu.Unlock()
+
+	if err := sh.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	go sh.Open()
+	defer sh.Close(websocket.StatusNormalClosure)
+
+	// Reconnects resume once READY has been handled rather than waiting to
+	// identify again.
+	if !waitForReady(sh, timeoutDuration+3*time.Second) {
+		t.Fatal("shard did not become ready")
+	}
+
+	// The generation of each connection by the order the gateway accepted
+	// them in.
+	_, generation := sh.ws.Get()
+	generations := []int64{generation}
+
+	sentMu := sync.Mutex{}
+	sent := make(map[sentFrame]int64)
+
+	wg := sync.WaitGroup{}
+
+	stop := func() {
+		select {
+		case <-done:
+		default:
+			close(done)
+			wg.Wait()
+		}
+	}
+	defer stop()
+
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+
+		go func(sender int) {
+			defer wg.Done()
+
+			for seq := 0; ; seq++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				id := sentFrame{Sender: sender, Seq: seq}
+
+				swapMu.RLock()
+				atomic.StoreInt32(&holding[sender], 1)
+
+				_, generation := sh.ws.Get()
+				atomic.StoreInt64(&seen[sender], generation)
+
+				// Frames sent whilst the shard reconnects are dropped.
+				_ = sh.SendEvent(discord.GatewayOpRequestGuildMembers, id)
+
+				release(sender)
+
+				sentMu.Lock()
+				sent[id] = generation
+				sentMu.Unlock()
+			}
+		}(i)
+	}
+
+	for i := 0; i < reconnects; i++ {
+		time.Sleep(10 * time.Millisecond)
+
+		swapMu.Lock()
+		err := sh.Reconnect(websocket.StatusNormalClosure)
+		_, generation = sh.ws.Get()
+		swapMu.Unlock()
+
+		if err != nil {
+			t.Fatalf("failed to reconnect: %v", err)
+		}
+
+		generations = append(generations, generation)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	stop()
+
+	if accepted := atomic.LoadInt64(gw.accepted); accepted != int64(len(generations)) {
+		t.Fatalf("gateway accepted %d connections, want %d", accepted, len(generations))
+	}
+
+	receivedMu.Lock()
+	frames := append([]receivedFrame(nil), received...)
+	receivedMu.Unlock()
+
+	checked := 0
+	connections := make(map[int]bool)
+
+	for _, frame := range frames {
+		payload := sentFramePayload{}
+		if json.Unmarshal([]byte(frame.data), &payload) != nil ||
+			payload.Op != int(discord.GatewayOpRequestGuildMembers) {
+			continue
+		}
+
+		generation, ok := sent[payload.Data]
+		if !ok {
+			t.Errorf("gateway received a frame which was not sent: %s", frame.data)
+
+			continue
+		}
+
+		if generations[frame.index] != generation {
+			t.Errorf("frame %+v sent on generation %d reached connection %d of generation %d",
+				payload.Data, generation, frame.index, generations[frame.index])
+		}
+
+		checked++
+		connections[frame.index] = true
+	}
+
+	if checked == 0 || len(connections) < 2 {
+		t.Errorf("checked %d frames on %d connections", checked, len(connections))
+	}
+}
+
+// helloPayload is a HELLO with a heartbeat interval.
+func helloPayload(interval time.Duration) discord.ReceivedPayload {
+	return discord.ReceivedPayload{
+		Op:   discord.GatewayOpHello,
+		Data: []byte(fmt.Sprintf(`{"heartbeat_interval":%d}`, interval.Milliseconds())),
+	}
+}
+
+func TestHeartbeaterHelloSoak(t *testing.T) {
+	sh := newTestShard(t)
+	gw := connectTestShard(t, sh)
+
+	go sh.Heartbeat()
+
+	sh.OnEvent(helloPayload(time.Hour))
+
+	time.Sleep(50 * time.Millisecond)
+
+	goroutines := runtime.NumGoroutine()
+
+	for i := 0; i < 1000; i++ {
+		sh.OnEvent(helloPayload(time.Hour))
+	}
+
+	// The goroutine OnEvent starts for each event may still be finishing.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if after := runtime.NumGoroutine(); after > goroutines {
+		t.Errorf("%d goroutines after 1000 HELLOs, started with %d", after, goroutines)
+	}
+
+	if sh.HeartbeatActive.IsNotSet() {
+		t.Fatal("heartbeat stopped")
+	}
+
+	// The heartbeat goroutine must use the ticker of the latest HELLO. No
+	// ACKs are sent so enough failures are allowed to not reconnect.
+	sh.Manager.ConfigurationMu.Lock()
+	sh.Manager.Configuration.Bot.MaxHeartbeatFailures = 1000
+	sh.Manager.ConfigurationMu.Unlock()
+
+	sh.OnEvent(helloPayload(20 * time.Millisecond))
+
+	if op := sentOp(t, gw.frame(t)); op != discord.GatewayOpHeartbeat {
+		t.Errorf("sent op %d, want heartbeat", op)
+	}
+
+	sh.Close(websocket.StatusNormalClosure)
+
+	sh.RLock()
+	heartbeater := sh.Heartbeater
+	sh.RUnlock()
+
+	if heartbeater != nil {
+		t.Error("Close did not stop the heartbeater")
+	}
+
+	deadline = time.Now().Add(time.Second)
+	for sh.HeartbeatActive.IsSet() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if sh.HeartbeatActive.IsSet() {
+		t.Error("heartbeat did not stop after Close")
+	}
+}
+
+// sentOp returns the op of a frame a shard sent.
+func sentOp(t *testing.T, frame []byte) discord.GatewayOp {
+	t.Helper()
+
+	payload := discord.SentPayload{}
+	if err := json.Unmarshal(frame, &payload); err != nil {
+		t.Fatalf("frame was not valid json: %s", frame)
+	}
+
+	return discord.GatewayOp(payload.Op)
+}
+
+// benchmarkWriteJSON marshals and logs heartbeats with the logger at level.
+// The shard has no connection so nothing is sent.
+func benchmarkWriteJSON(b *testing.B, level zerolog.Level) {
+	sh := newTestShard(b)
+	sh.Logger = newShardLogger(sh.Manager.Sandwich, zerolog.New(ioutil.Discard).Level(level))
+
+	packet := &discord.SentPayload{Op: int(discord.GatewayOpHeartbeat), Data: 1}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := sh.WriteJSON(discord.GatewayOpHeartbeat, packet); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteJSON(b *testing.B) {
+	benchmarkWriteJSON(b, zerolog.InfoLevel)
+}
+
+func BenchmarkWriteJSONTrace(b *testing.B) {
+	benchmarkWriteJSON(b, zerolog.TraceLevel)
+}
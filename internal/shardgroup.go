@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,6 +20,9 @@ import (
 // Total number of active goroutines chunking guilds per ShardGroup shard.
 var guildChunkLimiterCount = 16
 
+// Time between checking shards in a ShardGroup for stalled events.
+const stallCheckInterval = 15 * time.Second
+
 // ShardGroup groups a selection of shards.
 type ShardGroup struct {
 	StatusMu sync.RWMutex             `json:"-"`
@@ -61,10 +65,11 @@ type ShardGroup struct {
 	MemberChunksCompleteMu sync.RWMutex                       `json:"-"`
 	MemberChunksComplete   map[snowflake.ID]*abool.AtomicBool `json:"-"`
 
-	// MemberChunkCallbacks is used to signal when any MEMBER_CHUNK
-	// events are received for the specific guild.
-	MemberChunkCallbacksMu sync.RWMutex               `json:"-"`
-	MemberChunkCallbacks   map[snowflake.ID]chan bool `json:"-"`
+	// MemberChunkRequests are the member requests made by chunkGuild by
+	// the nonce they were sent with.
+	MemberChunkRequestsMu sync.RWMutex                   `json:"-"`
+	MemberChunkRequests   map[string]*memberChunkRequest `json:"-"`
+	memberChunkNonce      *int64
 
 	// ConcurrencyLimiter for total number of guilds that can be
 	// simultaneously chunked when no Wait provided.
@@ -76,12 +81,29 @@ type ShardGroup struct {
 	// Used to close active goroutines
 	close chan void
 
+	// Set whilst the ShardGroup is the active producer of the manager. Only
+	// the active producer publishes dispatches so consumers do not receive
+	// every event twice whilst a new ShardGroup takes over.
 	floodgate *abool.AtomicBool
+
+	// Prioritises structural events until the ShardGroup is ready.
+	startup *startupQueue
+
+	// Dispatches being handled by the shards. Once dispatchClosing is set
+	// no more are started so Close can wait for them.
+	dispatchMu         sync.RWMutex
+	dispatchClosing    bool
+	dispatches         sync.WaitGroup
+	inFlightDispatches *int64
+	rejectedDispatches *int64 // Dispatches received once closing
+
+	CloseSummaryMu sync.RWMutex                    `json:"-"`
+	CloseSummary   *structs.ShardGroupCloseSummary `json:"close_summary,omitempty"`
 }
 
 // NewShardGroup creates a new shardgroup.
-func (mg *Manager) NewShardGroup(id int32) *ShardGroup {
-	return &ShardGroup{
+func (mg *Manager) NewShardGroup(id int32) (sg *ShardGroup) {
+	sg = &ShardGroup{
 		StatusMu: sync.RWMutex{},
 		Status:   structs.ShardGroupIdle,
 		ErrorMu:  sync.RWMutex{},
@@ -111,19 +133,38 @@ func (mg *Manager) NewShardGroup(id int32) *ShardGroup {
 		MemberChunksCompleteMu: sync.RWMutex{},
 		MemberChunksComplete:   make(map[snowflake.ID]*abool.AtomicBool),
 
-		MemberChunkCallbacksMu: sync.RWMutex{},
-		MemberChunkCallbacks:   make(map[snowflake.ID]chan bool),
+		MemberChunkRequestsMu: sync.RWMutex{},
+		MemberChunkRequests:   make(map[string]*memberChunkRequest),
+		memberChunkNonce:      new(int64),
 
 		floodgate: abool.New(),
+
+		dispatchMu:         sync.RWMutex{},
+		inFlightDispatches: new(int64),
+		rejectedDispatches: new(int64),
+
+		CloseSummaryMu: sync.RWMutex{},
 	}
+
+	sg.startup = newStartupQueue(sg)
+
+	return sg
 }
 
 // Open starts up the shardgroup.
 func (sg *ShardGroup) Open(shardIDs []int, shardCount int) (ready chan bool, err error) {
 	sg.Start = time.Now().UTC()
 
+	// Without another producer there is nothing to hand over from, so the
+	// ShardGroup publishes straight away.
+	producing := false
+
 	sg.Manager.ShardGroupsMu.Lock()
 	for _, _sg := range sg.Manager.ShardGroups {
+		if _sg != sg && _sg.floodgate.IsSet() {
+			producing = true
+		}
+
 		// We preferably do not want to mark an erroring shardgroup as replaced as it overwrites how it is displayed.
 		_sg.StatusMu.RLock()
 		shardNotErroring := _sg.Status != structs.ShardGroupError
@@ -137,6 +178,10 @@ func (sg *ShardGroup) Open(shardIDs []int, shardCount int) (ready chan bool, err
 	}
 	sg.Manager.ShardGroupsMu.Unlock()
 
+	if !producing {
+		sg.floodgate.Set()
+	}
+
 	if err := sg.SetStatus(structs.ShardGroupStarting); err != nil {
 		sg.Logger.Error().Err(err).Msg("Encountered error setting shard group status")
 	}
@@ -144,6 +189,14 @@ func (sg *ShardGroup) Open(shardIDs []int, shardCount int) (ready chan bool, err
 	sg.ShardCount = shardCount
 	sg.ShardIDs = shardIDs
 
+	sg.Manager.ConfigurationMu.RLock()
+	startupPriority := sg.Manager.Configuration.Events.StartupPriority
+	sg.Manager.ConfigurationMu.RUnlock()
+
+	if startupPriority {
+		sg.startup.Start()
+	}
+
 	sg.ChunkLimiter = limiter.NewConcurrencyLimiter("guild_chunks", guildChunkLimiterCount*len(shardIDs))
 
 	ready = make(chan bool, 1)
@@ -268,25 +321,41 @@ func (sg *ShardGroup) Open(shardIDs []int, shardCount int) (ready chan bool, err
 			sg.Logger.Error().Err(err).Msg("Encountered error setting shard group status")
 		}
 
+		sg.startup.Finish()
+
 		// If a shardgroup has successfully started up, we can remove any manager errors.
 		sg.Manager.ErrorMu.Lock()
 		sg.Manager.Error = ""
 		sg.Manager.ErrorMu.Unlock()
 
+		// The new ShardGroup starts publishing before the old ones are
+		// closed so no dispatches are dropped whilst they close. The old
+		// ShardGroups are receiving the same dispatches so they stop
+		// publishing at the same time.
+		oldShardGroups := make(map[int32]*ShardGroup)
+
 		sg.Manager.ShardGroupsMu.RLock()
 		for index, _sg := range sg.Manager.ShardGroups {
 			if _sg != sg {
 				_sg.floodgate.UnSet()
-				sg.Manager.Logger.Debug().Int32("index", index).Msg("Killed ShardGroup")
-				_sg.Close()
+				oldShardGroups[index] = _sg
 			}
 		}
 		sg.Manager.ShardGroupsMu.RUnlock()
 
 		sg.floodgate.Set()
+		sg.Logger.Info().Msg("ShardGroup is now the active producer")
+
+		for index, _sg := range oldShardGroups {
+			_sg.Close()
+			sg.Manager.Logger.Debug().Int32("index", index).Msg("Killed ShardGroup")
+		}
+
 		close(ready)
 	}(sg)
 
+	go sg.monitorStalls()
+
 	return ready, nil
 }
 
@@ -307,21 +376,152 @@ func (sg *ShardGroup) SetStatus(status structs.ShardGroupStatus) (err error) {
 	return sg.Manager.PublishEvent("SHARD_STATUS", structs.MessagingStatusUpdate{Status: int32(status)})
 }
 
-// Close closes the shard group and finishes any shards.
+// monitorStalls periodically checks for shards that have stopped receiving
+// dispatch events. This is independent of heartbeats as the gateway can
+// continue to ACK heartbeats whilst not sending any events.
+func (sg *ShardGroup) monitorStalls() {
+	t := time.NewTicker(stallCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-sg.close:
+			return
+		case <-t.C:
+		}
+
+		sg.Manager.ConfigurationMu.RLock()
+		threshold := time.Duration(sg.Manager.Configuration.Bot.EventStallThreshold) * time.Second
+		reidentify := sg.Manager.Configuration.Bot.ReidentifyOnStall
+		sg.Manager.ConfigurationMu.RUnlock()
+
+		if threshold > 0 {
+			sg.checkStalls(threshold, reidentify)
+		}
+	}
+}
+
+// checkStalls marks shards as stalled if they have not received a dispatch event
+// within the threshold. A shard is only considered stalled when at least one other
+// shard in the ShardGroup is receiving events, so quiet periods on small bots do
+// not cause false positives.
+func (sg *ShardGroup) checkStalls(threshold time.Duration, reidentify bool) {
+	candidates := make([]*Shard, 0)
+	healthy := 0
+
+	sg.ShardsMu.RLock()
+	for _, shard := range sg.Shards {
+		shard.StatusMu.RLock()
+		status := shard.Status
+		shard.StatusMu.RUnlock()
+
+		if status != structs.ShardReady {
+			continue
+		}
+
+		if shard.SinceLastDispatch() > threshold {
+			candidates = append(candidates, shard)
+		} else {
+			healthy++
+
+			if shard.stalled.SetToIf(true, false) {
+				shard.Logger.Info().Msg("Shard is receiving events again")
+			}
+		}
+	}
+	sg.ShardsMu.RUnlock()
+
+	if healthy == 0 {
+		return
+	}
+
+	for _, shard := range candidates {
+		if !shard.stalled.SetToIf(false, true) {
+			continue
+		}
+
+		since := shard.SinceLastDispatch().Round(time.Second)
+
+		shard.Logger.Warn().
+			Dur("since", since).
+			Int("healthy", healthy).
+			Msg("Shard has stopped receiving events whilst others in the ShardGroup have not")
+
+		go shard.PublishNoisyWebhook("Shard has stopped receiving events",
+			fmt.Sprintf("No events received for `%s`", since.String()), 16760839, false)
+
+		if reidentify {
+			go func(shard *Shard) {
+				shard.Logger.Info().Msg("Reidentifying stalled shard")
+
+				if err := shard.Reidentify(); err != nil {
+					shard.Logger.Error().Err(err).Msg("Failed to reidentify stalled shard")
+				}
+
+				shard.stalled.UnSet()
+			}(shard)
+		}
+	}
+}
+
+// closed returns if the shard group has been closed.
+func (sg *ShardGroup) closed() bool {
+	select {
+	case <-sg.close:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the shard group and finishes any shards. Dispatches already
+// being handled are given bot.dispatch_grace_period to finish before the
+// group is marked closed.
 func (sg *ShardGroup) Close() {
+	// A normal closure ends the sessions on discord. When sessions are
+	// persisted they are kept open so they can be resumed after a restart.
+	code := websocket.StatusNormalClosure
+	if sr, _ := sg.Manager.Sandwich.sessionStore(); sr != nil {
+		code = reconnectCloseCode
+	}
+
+	sg.closeWithCode(code)
+}
+
+// closeWithCode closes the shard group like Close, closing the shards with
+// the code provided.
+func (sg *ShardGroup) closeWithCode(code websocket.StatusCode) {
 	sg.Logger.Info().Msg("Closing ShardGroup")
 
+	start := time.Now().UTC()
+
+	// Stop any goroutines the ShardGroup is running.
+	select {
+	case <-sg.close:
+	default:
+		close(sg.close)
+	}
+
 	if err := sg.SetStatus(structs.ShardGroupClosing); err != nil {
 		sg.Logger.Error().Err(err).Msg("Encountered error setting shard group status")
 	}
 
 	sg.ShardsMu.RLock()
 	for _, shard := range sg.Shards {
-		shard.Close(websocket.StatusNormalClosure)
+		shard.Close(code)
 	}
 	sg.ShardsMu.RUnlock()
 
+	sg.drainDispatches(start)
+
 	if err := sg.SetStatus(structs.ShardGroupClosed); err != nil {
 		sg.Logger.Error().Err(err).Msg("Encountered error setting shard group status")
 	}
 }
+
+// IsActiveProducer returns if the ShardGroup publishes the dispatches it
+// receives. A ShardGroup becomes the active producer once all of its shards
+// are ready and the ShardGroup it replaces has closed.
+func (sg *ShardGroup) IsActiveProducer() bool {
+	return sg.floodgate.IsSet()
+}
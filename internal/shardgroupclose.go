@@ -0,0 +1,150 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// Seconds a closing ShardGroup waits for in-flight dispatches if
+// bot.dispatch_grace_period is not set.
+const defaultDispatchGracePeriod = 5
+
+// startDispatch registers a dispatch about to be handled by a shard of the
+// group. False is returned once the group is closing and the dispatch must
+// be dropped.
+func (sg *ShardGroup) startDispatch() bool {
+	sg.dispatchMu.RLock()
+	defer sg.dispatchMu.RUnlock()
+
+	if sg.dispatchClosing {
+		atomic.AddInt64(sg.rejectedDispatches, 1)
+
+		return false
+	}
+
+	sg.dispatches.Add(1)
+	atomic.AddInt64(sg.inFlightDispatches, 1)
+
+	return true
+}
+
+// finishDispatch marks a dispatch registered with startDispatch as handled.
+func (sg *ShardGroup) finishDispatch() {
+	atomic.AddInt64(sg.inFlightDispatches, -1)
+	sg.dispatches.Done()
+}
+
+// drainDispatches stops new dispatches, waits up to the grace period for
+// in-flight ones and discards the payloads still buffered by the shards. The
+// shards must already be closed so their readers have stopped. The summary
+// is stored on the group and sent as a webhook. Only the first call drains.
+func (sg *ShardGroup) drainDispatches(start time.Time) {
+	sg.dispatchMu.Lock()
+	already := sg.dispatchClosing
+	sg.dispatchClosing = true
+	sg.dispatchMu.Unlock()
+
+	if already {
+		return
+	}
+
+	sg.Manager.ConfigurationMu.RLock()
+	grace := time.Duration(sg.Manager.Configuration.Bot.DispatchGracePeriod) * time.Second
+	sg.Manager.ConfigurationMu.RUnlock()
+
+	inFlight := atomic.LoadInt64(sg.inFlightDispatches)
+
+	finished := make(chan void)
+
+	go func() {
+		sg.dispatches.Wait()
+		close(finished)
+	}()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	timedOut := false
+
+	select {
+	case <-finished:
+	case <-timer.C:
+		timedOut = true
+	}
+
+	abandoned := atomic.LoadInt64(sg.inFlightDispatches)
+
+	var buffered int64
+
+	sg.ShardsMu.RLock()
+	for _, shard := range sg.Shards {
+		buffered += shard.discardBuffered()
+	}
+	sg.ShardsMu.RUnlock()
+
+	now := time.Now().UTC()
+
+	summary := &structs.ShardGroupCloseSummary{
+		ClosedAt:  now,
+		Duration:  now.Sub(start).Milliseconds(),
+		InFlight:  inFlight,
+		Completed: inFlight - abandoned,
+		Abandoned: abandoned,
+		Buffered:  buffered,
+		Rejected:  atomic.LoadInt64(sg.rejectedDispatches),
+		TimedOut:  timedOut,
+	}
+
+	sg.CloseSummaryMu.Lock()
+	sg.CloseSummary = summary
+	sg.CloseSummaryMu.Unlock()
+
+	sg.Logger.Info().
+		Int64("in_flight", summary.InFlight).
+		Int64("completed", summary.Completed).
+		Int64("abandoned", summary.Abandoned).
+		Int64("buffered", summary.Buffered).
+		Int64("rejected", summary.Rejected).
+		Int64("duration", summary.Duration).
+		Msg("Drained ShardGroup dispatches")
+
+	severity := SeverityInfo
+	if summary.Abandoned > 0 || summary.Buffered > 0 || summary.Rejected > 0 {
+		severity = SeverityWarning
+	}
+
+	go sg.Manager.Sandwich.Notify(context.Background(),
+		NewNotification("Closed ShardGroup",
+			fmt.Sprintf("Took %dms. %d of %d in-flight dispatches completed and %d were abandoned.\n"+
+				"%d buffered and %d late dispatches were dropped.",
+				summary.Duration, summary.Completed, summary.InFlight, summary.Abandoned,
+				summary.Buffered, summary.Rejected)).
+			ForShardGroup(sg).
+			WithSeverity(severity).
+			At(now))
+}
+
+// discardBuffered empties the payloads read from the gateway which the shard
+// has not handled and returns how many there were.
+func (sh *Shard) discardBuffered() (discarded int64) {
+	sh.RLock()
+	messageCh := sh.MessageCh
+	sh.RUnlock()
+
+	if messageCh == nil {
+		return 0
+	}
+
+	for {
+		select {
+		case <-messageCh:
+			discarded++
+		default:
+			return discarded
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package gateway
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Period errors of a shard are counted over for error_threshold.
+	shardLogErrorWindow = time.Minute
+
+	// Defaults used when logging.shard_escalation is not set.
+	defaultShardLogLevel          = zerolog.TraceLevel
+	defaultShardLogDuration       = 300
+	defaultShardLogErrorThreshold = 10
+)
+
+// The level of logging.level is enforced by levelSampler rather than the
+// global level. This lets the global level be lowered whilst a shard is
+// escalated without every other logger of the daemon becoming verbose.
+var (
+	logLevel = new(int32) // Read by levelSampler on every log call
+
+	logLevelMu     sync.Mutex
+	escalatedCount int // Shard loggers which are escalated
+)
+
+// levelSampler drops events below logging.level.
+type levelSampler struct{}
+
+func (levelSampler) Sample(level zerolog.Level) bool {
+	return level >= zerolog.Level(atomic.LoadInt32(logLevel))
+}
+
+// setLogLevel changes the level the daemon logs at.
+func setLogLevel(level zerolog.Level) {
+	logLevelMu.Lock()
+	defer logLevelMu.Unlock()
+
+	atomic.StoreInt32(logLevel, int32(level))
+	applyGlobalLevel()
+}
+
+// applyGlobalLevel sets the global level to logging.level or to trace whilst
+// any shard is escalated. logLevelMu must be held.
+func applyGlobalLevel() {
+	if escalatedCount > 0 {
+		zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	} else {
+		zerolog.SetGlobalLevel(zerolog.Level(atomic.LoadInt32(logLevel)))
+	}
+}
+
+// escalationChanged counts a shard logger becoming escalated or reverting.
+func escalationChanged(delta int) {
+	logLevelMu.Lock()
+	defer logLevelMu.Unlock()
+
+	escalatedCount += delta
+	applyGlobalLevel()
+}
+
+// shardLogSettings is logging.shard_escalation with defaults applied. It is
+// stored on the daemon when the configuration changes as shard loggers read
+// it whilst logging, where taking ConfigurationMu could deadlock.
+type shardLogSettings struct {
+	enabled        bool
+	level          zerolog.Level
+	duration       time.Duration
+	errorThreshold int
+	bufferSize     int
+	output         io.Writer // Writers of the daemon logger
+}
+
+// storeShardLogSettings updates the shard log settings from the configuration.
+// ConfigurationMu must be held. If output is nil the previous writers are
+// kept.
+func (sg *Sandwich) storeShardLogSettings(output io.Writer) {
+	configuration := sg.Configuration.Logging.ShardEscalation
+
+	settings := shardLogSettings{
+		enabled:        configuration.Enabled,
+		level:          defaultShardLogLevel,
+		duration:       time.Duration(configuration.Duration) * time.Second,
+		errorThreshold: configuration.ErrorThreshold,
+		bufferSize:     configuration.BufferSize,
+		output:         output,
+	}
+
+	if level, err := zerolog.ParseLevel(configuration.Level); err == nil && configuration.Level != "" {
+		settings.level = level
+	}
+
+	if settings.duration <= 0 {
+		settings.duration = defaultShardLogDuration * time.Second
+	}
+
+	if settings.errorThreshold < 1 {
+		settings.errorThreshold = defaultShardLogErrorThreshold
+	}
+
+	if settings.output == nil {
+		settings.output = sg.shardLogSettings().output
+	}
+
+	sg.shardLog.Store(settings)
+}
+
+// shardLogSettings returns the stored shard log settings.
+func (sg *Sandwich) shardLogSettings() shardLogSettings {
+	settings, _ := sg.shardLog.Load().(shardLogSettings)
+
+	return settings
+}
+
+// shardLogEscalation is a logger used in place of the normal logger of a
+// shard until it expires.
+type shardLogEscalation struct {
+	logger zerolog.Logger
+	level  zerolog.Level
+	reason string
+	manual bool
+	until  time.Time
+}
+
+// ShardLogger is the logger of a shard. It logs like any other logger of the
+// daemon until it is escalated, after which it logs at a lower level and
+// keeps the lines in a ring buffer until it reverts. Whilst not escalated a
+// log call costs an atomic load over using the logger directly.
+type ShardLogger struct {
+	sg   *Sandwich
+	base zerolog.Logger
+
+	// *shardLogEscalation, nil whilst not escalated.
+	escalation atomic.Value
+
+	mu          sync.Mutex // Guards escalating and the fields below
+	timer       *time.Timer
+	buffer      *shardLogBuffer
+	errorsSince time.Time
+	errors      int
+}
+
+func newShardLogger(sg *Sandwich, logger zerolog.Logger) *ShardLogger {
+	l := &ShardLogger{
+		sg:   sg,
+		base: logger,
+		mu:   sync.Mutex{},
+	}
+
+	l.escalation.Store((*shardLogEscalation)(nil))
+
+	return l
+}
+
+func (l *ShardLogger) logger() *zerolog.Logger {
+	if escalation, _ := l.escalation.Load().(*shardLogEscalation); escalation != nil {
+		return &escalation.logger
+	}
+
+	return &l.base
+}
+
+// Trace starts a new message with trace level.
+func (l *ShardLogger) Trace() *zerolog.Event {
+	return l.logger().Trace()
+}
+
+// Debug starts a new message with debug level.
+func (l *ShardLogger) Debug() *zerolog.Event {
+	return l.logger().Debug()
+}
+
+// Info starts a new message with info level.
+func (l *ShardLogger) Info() *zerolog.Event {
+	return l.logger().Info()
+}
+
+// Warn starts a new message with warn level.
+func (l *ShardLogger) Warn() *zerolog.Event {
+	return l.logger().Warn()
+}
+
+// Error starts a new message with error level. Errors are counted towards
+// logging.shard_escalation.error_threshold.
+func (l *ShardLogger) Error() *zerolog.Event {
+	l.recordError()
+
+	return l.logger().Error()
+}
+
+// recordError escalates the logger once error_threshold errors have been
+// logged within shardLogErrorWindow.
+func (l *ShardLogger) recordError() {
+	settings := l.sg.shardLogSettings()
+	if !settings.enabled {
+		return
+	}
+
+	now := time.Now().UTC()
+
+	l.mu.Lock()
+
+	if now.Sub(l.errorsSince) > shardLogErrorWindow {
+		l.errorsSince = now
+		l.errors = 0
+	}
+
+	l.errors++
+	exceeded := l.errors == settings.errorThreshold
+
+	l.mu.Unlock()
+
+	if exceeded {
+		l.Escalate(settings.level, settings.duration, "error threshold exceeded", false)
+	}
+}
+
+// autoEscalate escalates the logger with the configured level and duration if
+// logging.shard_escalation is enabled.
+func (l *ShardLogger) autoEscalate(reason string) {
+	settings := l.sg.shardLogSettings()
+	if settings.enabled {
+		l.Escalate(settings.level, settings.duration, reason, false)
+	}
+}
+
+// Escalate logs the shard at level until duration has passed. Automatic
+// escalations do not replace a manual one and only extend an automatic one.
+func (l *ShardLogger) Escalate(level zerolog.Level, duration time.Duration, reason string, manual bool) time.Time {
+	settings := l.sg.shardLogSettings()
+	until := time.Now().UTC().Add(duration)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, _ := l.escalation.Load().(*shardLogEscalation)
+
+	if current != nil && !manual {
+		if current.manual || current.until.After(until) {
+			return current.until
+		}
+
+		if current.level < level {
+			level = current.level
+		}
+	}
+
+	logger := l.base.Sample(nil).Level(level)
+
+	if settings.bufferSize > 0 && settings.output != nil {
+		if l.buffer == nil || l.buffer.size() != settings.bufferSize {
+			l.buffer = newShardLogBuffer(settings.bufferSize)
+		}
+
+		logger = logger.Output(io.MultiWriter(settings.output, l.buffer))
+	}
+
+	escalation := &shardLogEscalation{
+		logger: logger,
+		level:  level,
+		reason: reason,
+		manual: manual,
+		until:  until,
+	}
+
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+
+	l.timer = time.AfterFunc(duration, func() { l.revert(escalation) })
+
+	if current == nil {
+		escalationChanged(1)
+	}
+
+	l.escalation.Store(escalation)
+
+	escalation.logger.Info().
+		Str("level", level.String()).
+		Str("reason", reason).
+		Bool("manual", manual).
+		Time("until", until).
+		Msg("Escalated shard logging")
+
+	return until
+}
+
+// revert returns the logger to its normal level if it is still escalated by
+// the escalation provided.
+func (l *ShardLogger) revert(escalation *shardLogEscalation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if current, _ := l.escalation.Load().(*shardLogEscalation); current != escalation {
+		return
+	}
+
+	l.escalation.Store((*shardLogEscalation)(nil))
+	escalationChanged(-1)
+
+	l.base.Info().
+		Str("reason", escalation.reason).
+		Msg("Reverted shard logging")
+}
+
+// Reset returns the logger to its normal level straight away.
+func (l *ShardLogger) Reset() {
+	l.mu.Lock()
+	escalation, _ := l.escalation.Load().(*shardLogEscalation)
+
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.mu.Unlock()
+
+	if escalation != nil {
+		l.revert(escalation)
+	}
+}
+
+// Status returns the escalation of the logger and the lines it has kept.
+func (l *ShardLogger) Status() (status structs.ShardLogs) {
+	if escalation, _ := l.escalation.Load().(*shardLogEscalation); escalation != nil {
+		until := escalation.until
+
+		status.Escalated = true
+		status.Level = escalation.level.String()
+		status.Reason = escalation.reason
+		status.Manual = escalation.manual
+		status.Until = &until
+	}
+
+	l.mu.Lock()
+	buffer := l.buffer
+	l.mu.Unlock()
+
+	if buffer != nil {
+		status.Lines = buffer.lines()
+	} else {
+		status.Lines = make([]jsoniter.RawMessage, 0)
+	}
+
+	return status
+}
+
+// shardLogBuffer keeps the last lines written to it.
+type shardLogBuffer struct {
+	mu    sync.Mutex
+	ring  [][]byte
+	next  int
+	count int
+}
+
+func newShardLogBuffer(size int) *shardLogBuffer {
+	return &shardLogBuffer{
+		mu:   sync.Mutex{},
+		ring: make([][]byte, size),
+	}
+}
+
+func (b *shardLogBuffer) size() int {
+	return len(b.ring)
+}
+
+// Write stores a copy of p, replacing the oldest line once the buffer is full.
+func (b *shardLogBuffer) Write(p []byte) (n int, err error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+
+	b.mu.Lock()
+	b.ring[b.next] = line
+	b.next = (b.next + 1) % len(b.ring)
+
+	if b.count < len(b.ring) {
+		b.count++
+	}
+	b.mu.Unlock()
+
+	return len(p), nil
+}
+
+// lines returns the stored lines from oldest to newest.
+func (b *shardLogBuffer) lines() (lines []jsoniter.RawMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines = make([]jsoniter.RawMessage, 0, b.count)
+	start := (b.next - b.count + len(b.ring)) % len(b.ring)
+
+	for i := 0; i < b.count; i++ {
+		lines = append(lines, b.ring[(start+i)%len(b.ring)])
+	}
+
+	return lines
+}
+
+// shard returns a shard of the manager. If no shardgroup is provided the
+// newest shardgroup is used.
+func (mg *Manager) shard(shardGroupID *int32, shardID int) (*Shard, error) {
+	var shardGroup *ShardGroup
+
+	if shardGroupID != nil {
+		mg.ShardGroupsMu.RLock()
+		shardGroup = mg.ShardGroups[*shardGroupID]
+		mg.ShardGroupsMu.RUnlock()
+	} else {
+		shardGroup = mg.latestShardGroup()
+	}
+
+	if shardGroup == nil {
+		return nil, ErrInvalidShardGroup
+	}
+
+	shardGroup.ShardsMu.RLock()
+	sh, ok := shardGroup.Shards[shardID]
+	shardGroup.ShardsMu.RUnlock()
+
+	if !ok {
+		return nil, ErrInvalidShard
+	}
+
+	return sh, nil
+}
@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"sort"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// shardMap returns an entry for each shard in the shardgroup. Guilds are
+// counted by the shard they belong to rather than which shard received them.
+func (sg *ShardGroup) shardMap() (entries []structs.ShardMapEntry) {
+	guilds := make(map[int]int64)
+
+	if sg.ShardCount > 0 {
+		sg.GuildsMu.RLock()
+		for guildID := range sg.Guilds {
+			guilds[int((guildID.Int64()>>22)%int64(sg.ShardCount))]++
+		}
+		sg.GuildsMu.RUnlock()
+	}
+
+	shards := sg.shardsSnapshot()
+	entries = make([]structs.ShardMapEntry, 0, len(shards))
+
+	for shardID, shard := range shards {
+		shard.StatusMu.RLock()
+		status := shard.Status
+		shard.StatusMu.RUnlock()
+
+		latencyEWMA, jitter := shard.SmoothedLatency()
+
+		entries = append(entries, structs.ShardMapEntry{
+			ShardID:             shardID,
+			ShardGroup:          sg.ID,
+			Status:              status,
+			Latency:             shard.Latency(),
+			LatencyEWMA:         latencyEWMA,
+			Jitter:              jitter,
+			Guilds:              guilds[shardID],
+			LastEventSecondsAgo: int64(shard.SinceLastDispatch().Seconds()),
+		})
+	}
+
+	return entries
+}
+
+// shardMap returns the shards of every shardgroup of the manager ordered by
+// shard ID. Shards which exist in multiple shardgroups whilst scaling are
+// ordered by shardgroup.
+func (mg *Manager) shardMap() (entries []structs.ShardMapEntry) {
+	entries = make([]structs.ShardMapEntry, 0)
+
+	for _, sg := range mg.shardGroupsSnapshot() {
+		entries = append(entries, sg.shardMap()...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ShardID != entries[j].ShardID {
+			return entries[i].ShardID < entries[j].ShardID
+		}
+
+		return entries[i].ShardGroup < entries[j].ShardGroup
+	})
+
+	return entries
+}
@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+const (
+	// Seconds manager:shard:restart waits for the shard to be ready when no
+	// timeout is given.
+	defaultShardRestartTimeout = 30
+
+	shardRestartPollInterval = 100 * time.Millisecond
+)
+
+// restart reconnects the shard and waits until it is ready again or the
+// timeout elapses. Without force the connection is closed with
+// reconnectCloseCode so the session is kept and resumed. With force the
+// session is discarded and the shard identifies. ready is false if the
+// timeout elapsed first, in which case the shard keeps reconnecting in the
+// background.
+func (sh *Shard) restart(force bool, timeout time.Duration) (ready bool, err error) {
+	errs := make(chan error, 1)
+
+	go func() {
+		if force {
+			errs <- sh.Reidentify()
+		} else {
+			errs <- sh.Reconnect(reconnectCloseCode)
+		}
+	}()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	select {
+	case err = <-errs:
+		if err != nil {
+			return false, err
+		}
+	case <-deadline.C:
+		return false, nil
+	}
+
+	t := time.NewTicker(shardRestartPollInterval)
+	defer t.Stop()
+
+	for {
+		sh.StatusMu.RLock()
+		status := sh.Status
+		sh.StatusMu.RUnlock()
+
+		if status == structs.ShardReady {
+			return true, nil
+		}
+
+		select {
+		case <-t.C:
+		case <-deadline.C:
+			return false, nil
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"time"
+
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/xerrors"
+)
+
+// Ops which are refused by manager:shard:send unless allow_dangerous is set.
+// Identify, resume and heartbeat are managed by the shard and sending them
+// would leave it out of step with the gateway. The rest are only ever sent
+// by the gateway which closes the connection if it receives them.
+var dangerousSendOps = map[discord.GatewayOp]bool{
+	discord.GatewayOpDispatch:       true,
+	discord.GatewayOpHeartbeat:      true,
+	discord.GatewayOpIdentify:       true,
+	discord.GatewayOpResume:         true,
+	discord.GatewayOpReconnect:      true,
+	discord.GatewayOpInvalidSession: true,
+	discord.GatewayOpHello:          true,
+	discord.GatewayOpHeartbeatACK:   true,
+}
+
+// SendRaw sends a payload with an op Sandwich does not need to understand.
+// Like any other payload it waits on the websocket bucket, except for
+// heartbeats. sentAt is when the payload was written to the websocket.
+func (sh *Shard) SendRaw(op discord.GatewayOp, data jsoniter.RawMessage, allowDangerous bool) (sentAt time.Time, err error) {
+	if dangerousSendOps[op] && !allowDangerous {
+		return sentAt, ErrDangerousOp
+	}
+
+	if len(data) == 0 {
+		data = jsoniter.RawMessage("null")
+	}
+
+	if !jsoniter.Valid(data) {
+		return sentAt, xerrors.New("send raw: d is not valid JSON")
+	}
+
+	// WriteJSON drops payloads when there is no connection.
+	if conn, _ := sh.ws.Get(); conn == nil {
+		return sentAt, ErrShardNotReady
+	}
+
+	err = sh.SendEvent(op, data)
+	if err != nil {
+		return sentAt, xerrors.Errorf("send raw: %w", err)
+	}
+
+	return time.Now().UTC(), nil
+}
@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Seconds the daemon waits for dispatches to drain when shutting down if
+	// shutdown_timeout is not set.
+	defaultShutdownTimeout = 30
+
+	// Interval between checking if the dispatch pool has drained.
+	shutdownPollInterval = 100 * time.Millisecond
+
+	// Time producers are given to flush when the deadline has already
+	// passed whilst draining.
+	minShutdownProducerTimeout = time.Second
+)
+
+// shutdownTimeout returns shutdown_timeout as a duration.
+func (sg *Sandwich) shutdownTimeout() time.Duration {
+	sg.ConfigurationMu.RLock()
+	seconds := sg.Configuration.ShutdownTimeout
+	sg.ConfigurationMu.RUnlock()
+
+	if seconds < 1 {
+		seconds = defaultShutdownTimeout
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// ShuttingDown returns if Shutdown has been called.
+func (sg *Sandwich) ShuttingDown() bool {
+	return sg.shuttingDown.IsSet()
+}
+
+// Shutdown stops the daemon gracefully. New HTTP, RPC and gRPC requests are
+// refused and every shardgroup is closed with a reconnect close code so the
+// sessions can be resumed, persisting them if session persistence is
+// enabled. It then waits up to shutdown_timeout for the dispatch pool to
+// drain before the producers are flushed and closed. ErrShutdownTimeout is
+// returned if dispatches were abandoned.
+func (sg *Sandwich) Shutdown() (err error) {
+	if !sg.shuttingDown.SetToIf(false, true) {
+		return nil
+	}
+
+	start := time.Now().UTC()
+	deadline := start.Add(sg.shutdownTimeout())
+
+	sg.Logger.Info().Time("deadline", deadline).Msg("Shutting down sandwich")
+
+	// Webhooks are sent in the background so a slow webhook does not
+	// use up the time given to drain dispatches.
+	notifyCtx, cancelNotify := context.WithDeadline(context.Background(), deadline)
+	defer cancelNotify()
+
+	go sg.Notify(notifyCtx, NewNotification("Shutting down sandwich", "Draining shards"))
+
+	sg.stopGRPC(deadline)
+
+	managers := sg.managersSnapshot()
+	timedOut := false
+
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+
+	for _, mg := range managers {
+		for _, shardGroup := range mg.shardGroupsSnapshot() {
+			wg.Add(1)
+
+			go func(shardGroup *ShardGroup) {
+				defer wg.Done()
+
+				shardGroup.closeWithCode(reconnectCloseCode)
+
+				shardGroup.CloseSummaryMu.RLock()
+				abandoned := shardGroup.CloseSummary != nil && shardGroup.CloseSummary.TimedOut
+				shardGroup.CloseSummaryMu.RUnlock()
+
+				if abandoned {
+					mu.Lock()
+					timedOut = true
+					mu.Unlock()
+				}
+			}(shardGroup)
+		}
+	}
+
+	wg.Wait()
+
+	if !sg.drainPool(deadline) {
+		timedOut = true
+	}
+
+	sg.closeProducers(managers, deadline)
+
+	sg.cancel()
+
+	if err = sg.Audit.Close(); err != nil {
+		sg.Logger.Error().Err(err).Msg("Failed to close audit log")
+	}
+
+	duration := time.Since(start).Round(time.Millisecond)
+
+	if timedOut {
+		sg.Logger.Warn().Dur("duration", duration).Msg("Shut down before all dispatches were handled")
+
+		return xerrors.Errorf("shutdown after %s: %w", duration, ErrShutdownTimeout)
+	}
+
+	sg.Logger.Info().Dur("duration", duration).Msg("Shut down sandwich")
+
+	return nil
+}
+
+// closeProducers flushes and closes the producers of every manager at once.
+// They have until the deadline, or minShutdownProducerTimeout if it has
+// already passed. Payloads still waiting in the retry buffer are dropped.
+func (sg *Sandwich) closeProducers(managers map[string]*Manager, deadline time.Time) {
+	timeout := time.Until(deadline)
+	if timeout < minShutdownProducerTimeout {
+		timeout = minShutdownProducerTimeout
+	}
+
+	if timeout > producerCloseTimeout {
+		timeout = producerCloseTimeout
+	}
+
+	wg := sync.WaitGroup{}
+
+	for _, mg := range managers {
+		wg.Add(1)
+
+		go func(mg *Manager) {
+			defer wg.Done()
+
+			if producer := mg.swapProducer(nil); producer != nil {
+				mg.closeProducerWithin(producer, timeout)
+			}
+
+			if dropped := mg.publishRetry.Discard(); dropped > 0 {
+				mg.Logger.Warn().Int("dropped", dropped).Msg("Dropped payloads waiting to be published again")
+			}
+
+			if mg.cancel != nil {
+				mg.cancel()
+			}
+		}(mg)
+	}
+
+	wg.Wait()
+}
+
+// drainPool waits until no dispatches are waiting for or holding a ticket of
+// the dispatch pool. False is returned if the deadline passed first.
+func (sg *Sandwich) drainPool(deadline time.Time) bool {
+	t := time.NewTicker(shutdownPollInterval)
+	defer t.Stop()
+
+	for {
+		waiting := atomic.LoadInt64(sg.PoolWaiting)
+		inProgress := sg.Pool.InProgress()
+
+		if waiting <= 0 && inProgress <= 0 {
+			return true
+		}
+
+		if time.Now().UTC().After(deadline) {
+			sg.Logger.Warn().
+				Int64("waiting", waiting).
+				Int32("in_progress", inProgress).
+				Msg("Timed out waiting for the dispatch pool to drain")
+
+			return false
+		}
+
+		<-t.C
+	}
+}
+
+// stopGRPC stops the gRPC server from accepting requests and waits for the
+// ones in progress until the deadline.
+func (sg *Sandwich) stopGRPC(deadline time.Time) {
+	sg.grpcServerMu.Lock()
+	server := sg.grpcServer
+	sg.grpcServerMu.Unlock()
+
+	if server == nil {
+		return
+	}
+
+	stopped := make(chan void)
+
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Until(deadline)):
+		server.Stop()
+	}
+}
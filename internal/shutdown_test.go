@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/mqclients"
+	"github.com/rs/zerolog"
+)
+
+// slowProducer takes delay to close.
+type slowProducer struct {
+	mqclients.NoneMQClient
+
+	delay  time.Duration
+	closed *int64
+}
+
+func (p *slowProducer) Close(ctx context.Context) (err error) {
+	select {
+	case <-time.After(p.delay):
+		atomic.AddInt64(p.closed, 1)
+	case <-ctx.Done():
+	}
+
+	return nil
+}
+
+func TestCloseProducersParallel(t *testing.T) {
+	const delay = 300 * time.Millisecond
+
+	sg := newAccessSandwich(false)
+	sg.Configuration.Producer.RetryBuffer.Enabled = true
+
+	closed := new(int64)
+	managers := make(map[string]*Manager)
+
+	for _, identifier := range []string{"a", "b", "c"} {
+		mg := &Manager{
+			Sandwich: sg,
+			Logger:   zerolog.Nop(),
+			ctx:      context.Background(),
+		}
+
+		mg.publishRetry = newPublishRetry(mg)
+		mg.swapProducer(&slowProducer{delay: delay, closed: closed})
+
+		managers[identifier] = mg
+	}
+
+	// Without a producer the payload stays buffered and the retry buffer
+	// stops once the manager is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	buffered := &Manager{Sandwich: sg, Logger: zerolog.Nop(), ctx: ctx}
+	buffered.publishRetry = newPublishRetry(buffered)
+	buffered.publishRetry.Enqueue("channel", mqclients.Message{}, []byte("payload"), false)
+
+	start := time.Now()
+	sg.closeProducers(managers, start.Add(time.Minute))
+
+	if elapsed := time.Since(start); elapsed >= 2*delay {
+		t.Errorf("closing took %s, producers were not closed at once", elapsed)
+	}
+
+	if got := atomic.LoadInt64(closed); got != int64(len(managers)) {
+		t.Errorf("%d producers were closed, want %d", got, len(managers))
+	}
+
+	sg.closeProducers(map[string]*Manager{"buffered": buffered}, start.Add(time.Minute))
+
+	for identifier, mg := range managers {
+		if mg.Producer() != nil {
+			t.Errorf("manager %s still has a producer", identifier)
+		}
+	}
+
+	if dropped := buffered.publishRetry.API().Dropped; dropped != 1 {
+		t.Errorf("%d buffered payloads were counted as dropped, want 1", dropped)
+	}
+}
+
+func TestCloseProducersDeadline(t *testing.T) {
+	sg := newAccessSandwich(false)
+
+	mg := &Manager{Sandwich: sg, Logger: zerolog.Nop(), ctx: context.Background()}
+	mg.publishRetry = newPublishRetry(mg)
+	mg.swapProducer(&slowProducer{delay: time.Minute, closed: new(int64)})
+
+	start := time.Now()
+	sg.closeProducers(map[string]*Manager{"a": mg}, start.Add(-time.Second))
+
+	if elapsed := time.Since(start); elapsed > minShutdownProducerTimeout+time.Second {
+		t.Errorf("closing took %s after the deadline had passed", elapsed)
+	}
+}
@@ -0,0 +1,332 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// Event type published when an event type falls below its objective.
+const sloBreachEvent = "SANDWICH_SLO_BREACH"
+
+const (
+	// Latencies are counted in slots of sloInterval which is also how often
+	// objectives are evaluated. The longest window is sloSlots slots.
+	sloInterval = 10 * time.Second
+	sloSlots    = 360
+
+	// Window of an objective in seconds if none is given.
+	defaultSLOWindow = 300
+
+	// Events needed within a window before it can breach, so a single slow
+	// event of a rare type does not.
+	sloMinEvents = 20
+)
+
+// Upper bounds of the latency histogram buckets. Latencies above the last
+// bound are counted in an overflow bucket.
+var sloBuckets = [...]time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second,
+}
+
+// EventSLO is a latency objective for an event type. Target percent of the
+// events of the type must be published within Latency milliseconds of being
+// received, measured over Window seconds.
+type EventSLO struct {
+	Event   string  `json:"event" yaml:"event"`
+	Latency int     `json:"latency" yaml:"latency"`
+	Target  float64 `json:"target" yaml:"target"`
+	Window  int     `json:"window" yaml:"window"`
+}
+
+// sloSlot counts the latencies of an event type over a single sloInterval.
+// Every field is accessed atomically.
+type sloSlot struct {
+	buckets [len(sloBuckets) + 1]int64
+	within  int64
+	total   int64
+}
+
+// sloTracker measures an event type against its objective. observe is
+// called on the dispatch path and only uses atomics. The rest is only used
+// by the runner.
+type sloTracker struct {
+	latency *int64 // Objective latency in nanoseconds
+	current *int64 // Slot being written to
+	slots   [sloSlots]sloSlot
+
+	objective EventSLO
+	breached  bool
+	status    structs.SLOStatus
+}
+
+func newSLOTracker() *sloTracker {
+	return &sloTracker{
+		latency: new(int64),
+		current: new(int64),
+	}
+}
+
+// observe counts the latency of an event.
+func (st *sloTracker) observe(latency time.Duration) {
+	slot := &st.slots[atomic.LoadInt64(st.current)]
+
+	bucket := len(sloBuckets)
+
+	for i, bound := range sloBuckets {
+		if latency <= bound {
+			bucket = i
+
+			break
+		}
+	}
+
+	atomic.AddInt64(&slot.buckets[bucket], 1)
+	atomic.AddInt64(&slot.total, 1)
+
+	if int64(latency) <= atomic.LoadInt64(st.latency) {
+		atomic.AddInt64(&slot.within, 1)
+	}
+}
+
+// rotate clears the next slot and moves writes to it. The slot rotated out
+// is complete so it can be evaluated.
+func (st *sloTracker) rotate() {
+	next := (atomic.LoadInt64(st.current) + 1) % sloSlots
+	slot := &st.slots[next]
+
+	for i := range slot.buckets {
+		atomic.StoreInt64(&slot.buckets[i], 0)
+	}
+
+	atomic.StoreInt64(&slot.within, 0)
+	atomic.StoreInt64(&slot.total, 0)
+
+	atomic.StoreInt64(st.current, next)
+}
+
+// evaluate measures the completed slots covering the window of the objective.
+func (st *sloTracker) evaluate(now time.Time) structs.SLOStatus {
+	span := int64(time.Duration(st.objective.Window)*time.Second/sloInterval) + 1
+	if span > sloSlots-1 {
+		span = sloSlots - 1
+	}
+
+	var (
+		buckets [len(sloBuckets) + 1]int64
+		within  int64
+		total   int64
+	)
+
+	current := atomic.LoadInt64(st.current)
+
+	for i := int64(1); i <= span; i++ {
+		slot := &st.slots[(current-i+sloSlots)%sloSlots]
+
+		for bucket := range buckets {
+			buckets[bucket] += atomic.LoadInt64(&slot.buckets[bucket])
+		}
+
+		within += atomic.LoadInt64(&slot.within)
+		total += atomic.LoadInt64(&slot.total)
+	}
+
+	status := structs.SLOStatus{
+		Event:       st.objective.Event,
+		Latency:     st.objective.Latency,
+		Target:      st.objective.Target,
+		Window:      st.objective.Window,
+		Events:      total,
+		Compliance:  100,
+		EvaluatedAt: now,
+	}
+
+	if total == 0 {
+		return status
+	}
+
+	status.Compliance = float64(within) * 100 / float64(total)
+
+	// The latency the target percentile falls under is the upper bound of
+	// its bucket. -1 means it is above the largest bound.
+	needed := int64(float64(total) * status.Target / 100)
+	status.TargetLatency = -1
+
+	var seen int64
+
+	for i, bound := range sloBuckets {
+		seen += buckets[i]
+		if seen >= needed {
+			status.TargetLatency = bound.Milliseconds()
+
+			break
+		}
+	}
+
+	return status
+}
+
+// recordSLO counts the latency of an event between it being received and
+// published if its type has an objective.
+func (mg *Manager) recordSLO(eventType string, latency time.Duration) {
+	mg.sloMu.RLock()
+	tracker, ok := mg.slos[eventType]
+	mg.sloMu.RUnlock()
+
+	if ok {
+		tracker.observe(latency)
+	}
+}
+
+// syncSLOs creates trackers for new objectives, removes those for objectives
+// which were removed and updates the rest so configuration changes apply
+// without restarting.
+func (mg *Manager) syncSLOs() {
+	mg.ConfigurationMu.RLock()
+	objectives := make(map[string]EventSLO, len(mg.Configuration.Events.SLOs))
+
+	for _, objective := range mg.Configuration.Events.SLOs {
+		if objective.Event == "" || objective.Latency < 1 || objective.Target <= 0 || objective.Target > 100 {
+			continue
+		}
+
+		objectives[objective.Event] = objective
+	}
+	mg.ConfigurationMu.RUnlock()
+
+	mg.sloMu.Lock()
+	defer mg.sloMu.Unlock()
+
+	for eventType := range mg.slos {
+		if _, ok := objectives[eventType]; !ok {
+			delete(mg.slos, eventType)
+		}
+	}
+
+	for eventType, objective := range objectives {
+		tracker, ok := mg.slos[eventType]
+		if !ok {
+			tracker = newSLOTracker()
+			mg.slos[eventType] = tracker
+		}
+
+		tracker.objective = objective
+		atomic.StoreInt64(tracker.latency, int64(time.Duration(objective.Latency)*time.Millisecond))
+	}
+}
+
+// sloRunner evaluates the objectives every sloInterval until the manager is
+// closed.
+func (mg *Manager) sloRunner() {
+	if !mg.sloActive.SetToIf(false, true) {
+		return
+	}
+	defer mg.sloActive.UnSet()
+
+	t := time.NewTicker(sloInterval)
+	defer t.Stop()
+
+	for {
+		mg.syncSLOs()
+
+		select {
+		case <-mg.ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		mg.evaluateSLOs(time.Now().UTC())
+	}
+}
+
+// evaluateSLOs rotates the slots of every tracker and reports event types
+// which have fallen below or recovered to their target.
+func (mg *Manager) evaluateSLOs(now time.Time) {
+	breaches := make([]structs.SLOStatus, 0)
+
+	mg.sloMu.Lock()
+
+	for _, tracker := range mg.slos {
+		tracker.rotate()
+		tracker.status = tracker.evaluate(now)
+
+		below := tracker.status.Events >= sloMinEvents && tracker.status.Compliance < tracker.status.Target
+
+		switch {
+		case below && !tracker.breached:
+			tracker.breached = true
+
+			breaches = append(breaches, tracker.status)
+		case !below && tracker.breached:
+			tracker.breached = false
+
+			mg.Logger.Info().
+				Str("type", tracker.status.Event).
+				Float64("compliance", tracker.status.Compliance).
+				Msg("Event type has recovered to its objective")
+		}
+
+		tracker.status.Breached = tracker.breached
+	}
+
+	mg.sloMu.Unlock()
+
+	for _, status := range breaches {
+		mg.publishSLOBreach(status)
+	}
+}
+
+// publishSLOBreach tells consumers and webhooks that an event type has
+// fallen below its objective.
+func (mg *Manager) publishSLOBreach(status structs.SLOStatus) {
+	mg.Logger.Warn().
+		Str("type", status.Event).
+		Float64("compliance", status.Compliance).
+		Float64("target", status.Target).
+		Int("latency", status.Latency).
+		Int64("events", status.Events).
+		Msg("Event type has fallen below its objective")
+
+	if err := mg.PublishEvent(sloBreachEvent, status); err != nil {
+		mg.Logger.Warn().Err(err).Msg("Failed to publish SLO breach")
+	}
+
+	targetLatency := "over 10s"
+	if status.TargetLatency >= 0 {
+		targetLatency = fmt.Sprintf("within %dms", status.TargetLatency)
+	}
+
+	go mg.Sandwich.Notify(context.Background(),
+		NewNotification(fmt.Sprintf("`%s` is below its objective", status.Event),
+			fmt.Sprintf("%.2f%% of %d events were published within %dms over the last %ds "+
+				"against a target of %.2f%%. %.2f%% were published %s.",
+				status.Compliance, status.Events, status.Latency, status.Window,
+				status.Target, status.Target, targetLatency)).
+			ForManager(mg).
+			WithSeverity(SeverityWarning).
+			At(status.EvaluatedAt))
+}
+
+// SLOs returns the last evaluation of every objective of the manager.
+func (mg *Manager) SLOs() (statuses []structs.SLOStatus) {
+	mg.sloMu.RLock()
+	statuses = make([]structs.SLOStatus, 0, len(mg.slos))
+
+	for _, tracker := range mg.slos {
+		if !tracker.status.EvaluatedAt.IsZero() {
+			statuses = append(statuses, tracker.status)
+		}
+	}
+	mg.sloMu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Event < statuses[j].Event })
+
+	return statuses
+}
@@ -0,0 +1,56 @@
+package gateway
+
+// The HTTP and analytics readers range over copies of the manager,
+// shardgroup and shard maps rather than the maps themselves. A copy is taken
+// whilst holding the lock of its map and is never written to afterwards, so
+// managers and shardgroups created or deleted through RPC whilst a response
+// is built cannot race it and the map locks are not held whilst every entry
+// is inspected. The values are still live and must be read with their own
+// locks.
+
+// managersSnapshot returns a copy of the managers by identifier.
+func (sg *Sandwich) managersSnapshot() map[string]*Manager {
+	sg.ManagersMu.RLock()
+	defer sg.ManagersMu.RUnlock()
+
+	managers := make(map[string]*Manager, len(sg.Managers))
+	for identifier, mg := range sg.Managers {
+		managers[identifier] = mg
+	}
+
+	return managers
+}
+
+// shardGroupsSnapshot returns a copy of the shardgroups of the manager by id.
+func (mg *Manager) shardGroupsSnapshot() map[int32]*ShardGroup {
+	mg.ShardGroupsMu.RLock()
+	defer mg.ShardGroupsMu.RUnlock()
+
+	shardGroups := make(map[int32]*ShardGroup, len(mg.ShardGroups))
+	for id, sg := range mg.ShardGroups {
+		shardGroups[id] = sg
+	}
+
+	return shardGroups
+}
+
+// shardsSnapshot returns a copy of the shards of the shardgroup by shard id.
+func (sg *ShardGroup) shardsSnapshot() map[int]*Shard {
+	sg.ShardsMu.RLock()
+	defer sg.ShardsMu.RUnlock()
+
+	shards := make(map[int]*Shard, len(sg.Shards))
+	for shardID, sh := range sg.Shards {
+		shards[shardID] = sh
+	}
+
+	return shards
+}
+
+// displayName returns the display name of the manager.
+func (mg *Manager) displayName() string {
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	return mg.Configuration.DisplayName
+}
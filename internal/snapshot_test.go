@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// TestStatusWhilstManagersChange requests /api/status whilst managers are
+// created through RPC, start a shardgroup and are deleted again. It is only
+// meaningful with -race.
+func TestStatusWhilstManagersChange(t *testing.T) {
+	mg, _ := newDiscordManager(t)
+	sg := mg.Sandwich
+
+	// Managers created through RPC are saved to ConfigurationPath in the
+	// working directory.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	const (
+		readers  = 4
+		managers = 8
+	)
+
+	status := APIStatusHandler(sg)
+	done := make(chan void)
+	wg := sync.WaitGroup{}
+
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				rw := httptest.NewRecorder()
+				status(rw, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+
+				result := structs.APIStatusResult{}
+				response := structs.BaseResponse{Data: &result}
+
+				if err := json.Unmarshal(rw.Body.Bytes(), &response); err != nil || rw.Code != http.StatusOK {
+					t.Errorf("status returned %d: %s", rw.Code, rw.Body.String())
+
+					return
+				}
+
+				if len(result.Managers) == 0 {
+					t.Error("status did not include the test manager")
+
+					return
+				}
+			}
+		}()
+	}
+
+	writers := sync.WaitGroup{}
+
+	for i := 0; i < managers; i++ {
+		writers.Add(1)
+
+		go func(i int) {
+			defer writers.Done()
+
+			// Identifies are limited by token so each manager has its own.
+			identifier := fmt.Sprintf("status%d", i)
+			token := testToken[:len(testToken)-1] + string(rune('a'+i))
+
+			if code := callRPC(t, mg, "manager:create", structs.RPCManagerCreateEvent{
+				Persist:    true,
+				Identifier: identifier,
+				Token:      token,
+				Client:     "sandwich",
+			}, nil); code != http.StatusOK {
+				t.Errorf("creating %s returned %d", identifier, code)
+
+				return
+			}
+
+			sg.ManagersMu.RLock()
+			created := sg.Managers[identifier]
+			sg.ManagersMu.RUnlock()
+
+			if _, err := created.StartShards(); err != nil {
+				t.Errorf("failed to start shards of %s: %v", identifier, err)
+			}
+
+			if code := callRPC(t, mg, "manager:delete", structs.RPCManagerDeleteEvent{
+				Manager: identifier,
+				Confirm: identifier,
+			}, nil); code != http.StatusOK {
+				t.Errorf("deleting %s returned %d", identifier, code)
+			}
+		}(i)
+	}
+
+	writers.Wait()
+
+	sg.ManagersMu.RLock()
+	remaining := len(sg.Managers)
+	sg.ManagersMu.RUnlock()
+
+	if remaining != 1 {
+		t.Errorf("%d managers remain, want 1", remaining)
+	}
+}
@@ -1,7 +1,10 @@
 package gateway
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
 	"github.com/TheRockettek/Sandwich-Daemon/structs"
@@ -44,6 +47,20 @@ func (sg *Sandwich) StateDispatch(ctx *StateCtx,
 	return result, false, xerrors.Errorf("failed to dispatch: %w", NoHandler)
 }
 
+// includeBefore returns if events.include_before asks for the cached object
+// before an update. Updates replace cached objects instead of changing them
+// so the object included is not modified by later events.
+func (ctx *StateCtx) includeBefore(eventType string) bool {
+	if ctx.Mg == nil {
+		return false
+	}
+
+	ctx.Mg.IncludeBeforeMu.RLock()
+	defer ctx.Mg.IncludeBeforeMu.RUnlock()
+
+	return ctx.Mg.IncludeBefore.Match(eventType)
+}
+
 func NewSandwichState() (st *SandwichState) {
 	st = &SandwichState{
 		GuildsMu: sync.RWMutex{},
@@ -56,13 +73,24 @@ func NewSandwichState() (st *SandwichState) {
 		Channels:   make(map[snowflake.ID]*discord.Channel),
 
 		RolesMu: sync.RWMutex{},
-		Roles:   make(map[snowflake.ID]*discord.Role),
+		Roles:   make(map[snowflake.ID]map[snowflake.ID]*discord.Role),
 
 		EmojisMu: sync.RWMutex{},
 		Emojis:   make(map[snowflake.ID]*discord.Emoji),
 
+		ThreadsMu: sync.RWMutex{},
+		Threads:   make(map[snowflake.ID]map[snowflake.ID]*discord.Channel),
+
 		UsersMu: sync.RWMutex{},
 		Users:   make(map[snowflake.ID]*discord.User),
+
+		VoiceStatesMu: sync.RWMutex{},
+		VoiceStates:   make(map[snowflake.ID]map[snowflake.ID]*discord.VoiceState),
+
+		PresencesMu: sync.RWMutex{},
+		Presences:   make(map[snowflake.ID]*discord.StatePresence),
+
+		memberEviction: newMemberEviction(),
 	}
 
 	return st
@@ -100,30 +128,45 @@ func (st *SandwichState) AddGuild(ctx *StateCtx, g *discord.Guild) (sg *discord.
 	sg = &discord.StateGuild{}
 
 	for _, r := range g.Roles {
-		st.AddRole(ctx, r)
+		st.cacheRole(g.ID, r)
 		sg.RoleIDs = append(sg.RoleIDs, r.ID)
 	}
 
 	for _, c := range g.Channels {
-		st.AddChannel(ctx, c)
+		st.cacheChannel(c)
 		sg.ChannelIDs = append(sg.ChannelIDs, c.ID)
 	}
 
 	for _, e := range g.Emojis {
-		st.AddEmoji(ctx, e)
+		st.cacheEmoji(e)
 		sg.EmojiIDs = append(sg.EmojiIDs, e.ID)
 	}
 
+	st.SyncThreads(g.ID, nil, g.Threads)
+	st.SyncVoiceStates(g.ID, g.VoiceStates)
+
 	sg.Guild = g
 	sg.Roles = make([]*discord.Role, 0, len(sg.RoleIDs))
 	sg.Channels = make([]*discord.Channel, 0, len(sg.ChannelIDs))
 	sg.Emojis = make([]*discord.Emoji, 0, len(sg.EmojiIDs))
 
+	st.cacheGuild(sg)
+
+	if st.redis != nil {
+		if err := st.redis.storeGuild(sg, g.Roles, g.Channels, g.Emojis); err != nil {
+			ctx.Sg.Logger.Warn().Err(err).Msgf("Failed to store guild ID %d in redis", g.ID)
+		}
+	}
+
+	return
+}
+
+func (st *SandwichState) cacheGuild(sg *discord.StateGuild) {
 	st.GuildsMu.Lock()
-	st.Guilds[g.ID] = sg
+	st.Guilds[sg.ID] = sg
 	st.GuildsMu.Unlock()
 
-	return
+	st.touch(stateKindGuild, 0, sg.ID)
 }
 
 func (st *SandwichState) GetGuild(ctx *StateCtx, s snowflake.ID, expand bool) (g *discord.Guild, o bool) {
@@ -131,6 +174,13 @@ func (st *SandwichState) GetGuild(ctx *StateCtx, s snowflake.ID, expand bool) (g
 	sg, o := st.Guilds[s]
 	st.GuildsMu.RUnlock()
 
+	if !o && st.redis != nil {
+		sg = &discord.StateGuild{}
+		if o = st.redis.get(ctx, st.redis.key("guild"), s, sg); o {
+			st.cacheGuild(sg)
+		}
+	}
+
 	if !o {
 		return
 	}
@@ -141,7 +191,7 @@ func (st *SandwichState) GetGuild(ctx *StateCtx, s snowflake.ID, expand bool) (g
 	// slices from the State.
 	if expand {
 		for _, ri := range sg.RoleIDs {
-			if r, ok := st.GetRole(ctx, ri); ok {
+			if r, ok := st.GetRole(ctx, sg.ID, ri); ok {
 				sg.Roles = append(sg.Roles, r)
 			} else {
 				ctx.Sh.Logger.Warn().Msgf("GetGuild referenced role ID %d that was not in state", ri)
@@ -168,28 +218,158 @@ func (st *SandwichState) GetGuild(ctx *StateCtx, s snowflake.ID, expand bool) (g
 	return g, o
 }
 
-func (st *SandwichState) RemoveGuild(ctx *StateCtx, s snowflake.ID) {
+// GuildCleanup counts the objects removed from state along with a guild.
+type GuildCleanup struct {
+	Members     int
+	Roles       int
+	Channels    int
+	Threads     int
+	Emojis      int
+	VoiceStates int
+}
+
+// RemoveGuild removes a guild along with its members, roles, channels,
+// threads, emojis and voice states.
+func (st *SandwichState) RemoveGuild(ctx *StateCtx, s snowflake.ID) (removed GuildCleanup) {
+	removed.Members = st.removeGuildMembers(ctx, s)
+
 	st.GuildsMu.Lock()
 	defer st.GuildsMu.Unlock()
 
 	sg, o := st.Guilds[s]
 	if !o {
-		return
+		return removed
 	}
 
-	for _, ri := range sg.RoleIDs {
-		st.RemoveRole(ctx, ri)
-	}
+	// Locks are always taken in the same order as GuildSync.
+	removed.Roles = st.removeGuildRoles(ctx, s, sg.RoleIDs)
 
 	for _, ci := range sg.ChannelIDs {
-		st.RemoveChannel(ctx, ci)
+		if st.RemoveChannel(ctx, ci) {
+			removed.Channels++
+		}
 	}
 
 	for _, ei := range sg.EmojiIDs {
-		st.RemoveEmoji(ctx, ei)
+		if st.RemoveEmoji(ctx, ei) {
+			removed.Emojis++
+		}
 	}
 
+	st.ThreadsMu.Lock()
+	removed.Threads = len(st.Threads[s])
+	delete(st.Threads, s)
+	st.ThreadsMu.Unlock()
+
+	st.VoiceStatesMu.Lock()
+	removed.VoiceStates = len(st.VoiceStates[s])
+	delete(st.VoiceStates, s)
+	st.VoiceStatesMu.Unlock()
+
 	delete(st.Guilds, s)
+
+	if st.redis != nil {
+		st.redis.del(ctx, st.redis.key("guild"), s)
+	}
+
+	return removed
+}
+
+// UpdateGuild applies a GUILD_UPDATE to a guild in state. Members, channels,
+// threads and voice states are not sent with updates so they are kept. The
+// guild before the update is returned without them.
+func (st *SandwichState) UpdateGuild(ctx *StateCtx, g *discord.Guild) (before *discord.Guild, o bool) {
+	roleIDs := make([]snowflake.ID, 0, len(g.Roles))
+	for _, r := range g.Roles {
+		st.cacheRole(g.ID, r)
+		roleIDs = append(roleIDs, r.ID)
+	}
+
+	emojiIDs := make([]snowflake.ID, 0, len(g.Emojis))
+	for _, e := range g.Emojis {
+		st.cacheEmoji(e)
+		emojiIDs = append(emojiIDs, e.ID)
+	}
+
+	st.GuildsMu.Lock()
+
+	sg, o := st.Guilds[g.ID]
+	if !o {
+		st.GuildsMu.Unlock()
+
+		return nil, false
+	}
+
+	previous := *sg.Guild
+
+	updated := *g
+	updated.JoinedAt = previous.JoinedAt
+	updated.Large = previous.Large
+	updated.MemberCount = previous.MemberCount
+	updated.Members = previous.Members
+	updated.Channels = previous.Channels
+	updated.Threads = previous.Threads
+	updated.Presences = previous.Presences
+	updated.VoiceStates = previous.VoiceStates
+
+	// The guild is replaced rather than changed so previous stays as it was.
+	sg.Guild = &updated
+
+	staleRoles := missingIDs(sg.RoleIDs, roleIDs)
+	staleEmojis := missingIDs(sg.EmojiIDs, emojiIDs)
+
+	sg.RoleIDs = roleIDs
+	sg.EmojiIDs = emojiIDs
+
+	st.GuildsMu.Unlock()
+
+	if len(staleRoles) > 0 {
+		st.RolesMu.Lock()
+		for _, id := range staleRoles {
+			delete(st.Roles[g.ID], id)
+		}
+		st.RolesMu.Unlock()
+	}
+
+	for _, id := range staleEmojis {
+		st.RemoveEmoji(ctx, id)
+	}
+
+	if st.redis != nil {
+		for _, id := range staleRoles {
+			st.redis.del(ctx, st.redis.key("role"), id)
+		}
+
+		if err := st.redis.storeGuild(sg, g.Roles, nil, g.Emojis); err != nil {
+			ctx.Sg.Logger.Warn().Err(err).Msgf("Failed to store guild ID %d in redis", g.ID)
+		}
+	}
+
+	previous.Roles = nil
+	previous.Emojis = nil
+	previous.Members = nil
+	previous.Channels = nil
+	previous.Threads = nil
+	previous.Presences = nil
+	previous.VoiceStates = nil
+
+	return &previous, true
+}
+
+// missingIDs returns the IDs of previous which are not in current.
+func missingIDs(previous []snowflake.ID, current []snowflake.ID) (missing []snowflake.ID) {
+	kept := make(map[snowflake.ID]bool, len(current))
+	for _, id := range current {
+		kept[id] = true
+	}
+
+	for _, id := range previous {
+		if !kept[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	return missing
 }
 
 // Guild State Shardgroup Specific
@@ -202,12 +382,12 @@ func (st *SandwichState) AddGuildShardGroup(ctx *StateCtx, g *discord.Guild) {
 	ctx.Sh.ShardGroup.GuildsMu.Unlock()
 }
 
-func (st *SandwichState) RemoveGuildShardGroup(ctx *StateCtx, s snowflake.ID) {
+func (st *SandwichState) RemoveGuildShardGroup(ctx *StateCtx, s snowflake.ID) (removed GuildCleanup) {
 	ctx.Sh.ShardGroup.GuildsMu.Lock()
 	delete(ctx.Sh.ShardGroup.Guilds, s)
 	ctx.Sh.ShardGroup.GuildsMu.Unlock()
 
-	st.RemoveGuild(ctx, s)
+	return st.RemoveGuild(ctx, s)
 }
 
 // Member State
@@ -215,26 +395,50 @@ func (st *SandwichState) RemoveGuildShardGroup(ctx *StateCtx, s snowflake.ID) {
 // AddMembers creates a StateGuildMember object if a guild does not have it,
 // It also adds the User to the cache if it does not already exist.
 func (st *SandwichState) AddMember(ctx *StateCtx, g *discord.Guild, m *discord.GuildMember) {
+	st.AddMembers(ctx, g, []*discord.GuildMember{m})
+}
+
+// AddMembers adds multiple members of a guild along with their users. When
+// using redis, these are written in a single pipeline.
+func (st *SandwichState) AddMembers(ctx *StateCtx, g *discord.Guild, ms []*discord.GuildMember) {
+	for _, m := range ms {
+		st.cacheUser(m.User)
+		st.cacheMember(ctx, g.ID, discord.FromGuildMember(m))
+	}
+
+	if st.redis != nil {
+		if err := st.redis.storeMembers(g.ID, ms); err != nil {
+			ctx.Sg.Logger.Warn().Err(err).Msgf("Failed to store members of guild ID %d in redis", g.ID)
+		}
+	}
+}
+
+func (st *SandwichState) cacheMember(ctx *StateCtx, guildID snowflake.ID, sgm *discord.StateGuildMember) {
 	st.GuildMembersMu.RLock()
-	members, ok := st.GuildMembers[g.ID]
+	members, ok := st.GuildMembers[guildID]
 	st.GuildMembersMu.RUnlock()
 
 	if !ok {
 		ctx.Sg.Logger.Trace().
-			Msgf("Created new GuildMembers entry for guild ID %d", g.ID)
-
-		members = NewStateGuildMembers(g)
+			Msgf("Created new GuildMembers entry for guild ID %d", guildID)
 
 		st.GuildMembersMu.Lock()
-		st.GuildMembers[g.ID] = members
+		if members, ok = st.GuildMembers[guildID]; !ok {
+			members = NewStateGuildMembers(&discord.Guild{ID: guildID})
+			st.GuildMembers[guildID] = members
+		}
 		st.GuildMembersMu.Unlock()
 	}
 
-	st.AddUser(ctx, m.User)
+	atomic.StoreInt64(&sgm.LastUsed, time.Now().Unix())
 
 	members.MembersMu.Lock()
-	members.Members[m.User.ID] = discord.FromGuildMember(m)
+	members.Members[sgm.User] = sgm
+	count := len(members.Members)
 	members.MembersMu.Unlock()
+
+	st.touch(stateKindMember, guildID, sgm.User)
+	st.memberStored(members, sgm, count)
 }
 
 func (st *SandwichState) GetMember(ctx *StateCtx, g *discord.Guild, s snowflake.ID) (m *discord.GuildMember, o bool) {
@@ -242,18 +446,27 @@ func (st *SandwichState) GetMember(ctx *StateCtx, g *discord.Guild, s snowflake.
 	gm, o := st.GuildMembers[g.ID]
 	st.GuildMembersMu.RUnlock()
 
-	if !o {
-		return
+	var sgm *discord.StateGuildMember
+
+	if o {
+		gm.MembersMu.RLock()
+		sgm, o = gm.Members[s]
+		gm.MembersMu.RUnlock()
 	}
 
-	gm.MembersMu.RLock()
-	sgm, o := gm.Members[s]
-	gm.MembersMu.RUnlock()
+	if !o && st.redis != nil {
+		sgm = &discord.StateGuildMember{}
+		if o = st.redis.get(ctx, st.redis.membersKey(g.ID), s, sgm); o {
+			st.cacheMember(ctx, g.ID, sgm)
+		}
+	}
 
 	if !o {
 		return
 	}
 
+	st.memberUsed(g.ID, sgm)
+
 	u, o := st.GetUser(ctx, sgm.User)
 	if !o {
 		ctx.Sh.Logger.Warn().Msgf("GetMessage referenced user ID %d that was not in state", sgm.User)
@@ -262,28 +475,148 @@ func (st *SandwichState) GetMember(ctx *StateCtx, g *discord.Guild, s snowflake.
 	return sgm.ToGuildMember(u), true
 }
 
-func (st *SandwichState) RemoveMember(ctx *StateCtx, g *discord.Guild, s snowflake.ID) {
-	st.GuildMembersMu.RUnlock()
-	gm, o := st.GuildMembers[g.ID]
+// GetMembers returns the members of a guild that could be found. Members
+// not in memory are fetched from redis in a single request.
+func (st *SandwichState) GetMembers(ctx *StateCtx, g *discord.Guild,
+	ids []snowflake.ID) (ms map[snowflake.ID]*discord.GuildMember) {
+	ms = make(map[snowflake.ID]*discord.GuildMember, len(ids))
+	missing := make([]snowflake.ID, 0)
+
+	st.GuildMembersMu.RLock()
+	gm, ok := st.GuildMembers[g.ID]
 	st.GuildMembersMu.RUnlock()
 
+	for _, id := range ids {
+		var sgm *discord.StateGuildMember
+
+		if ok {
+			gm.MembersMu.RLock()
+			sgm = gm.Members[id]
+			gm.MembersMu.RUnlock()
+		}
+
+		if sgm == nil {
+			missing = append(missing, id)
+
+			continue
+		}
+
+		st.memberUsed(g.ID, sgm)
+
+		u, _ := st.GetUser(ctx, sgm.User)
+		ms[id] = sgm.ToGuildMember(u)
+	}
+
+	if len(missing) == 0 || st.redis == nil {
+		return ms
+	}
+
+	fetched, err := st.redis.getMembers(ctx, g.ID, missing)
+	if err != nil {
+		ctx.Sg.Logger.Warn().Err(err).Msgf("Failed to fetch members of guild ID %d from redis", g.ID)
+
+		return ms
+	}
+
+	for _, sgm := range fetched {
+		st.cacheMember(ctx, g.ID, sgm)
+
+		u, _ := st.GetUser(ctx, sgm.User)
+		ms[sgm.User] = sgm.ToGuildMember(u)
+	}
+
+	return ms
+}
+
+// UpdateMember applies a GUILD_MEMBER_UPDATE to a member in state. The
+// member before the update is returned. Nothing is done if the member is not
+// in state as the update does not include every field of a member.
+func (st *SandwichState) UpdateMember(ctx *StateCtx, guildID snowflake.ID,
+	update *discord.GuildMemberUpdate) (before *discord.GuildMember, o bool) {
+	g := &discord.Guild{ID: guildID}
+
+	before, o = st.GetMembers(ctx, g, []snowflake.ID{update.User.ID})[update.User.ID]
 	if !o {
-		return
+		return nil, false
 	}
 
-	gm.MembersMu.Lock()
-	delete(gm.Members, s)
-	gm.MembersMu.Unlock()
+	// GetMembers returns a new member so before is not changed by storing
+	// the update.
+	member := *before
+	member.User = update.User
+	member.Nick = update.Nick
+	member.Roles = update.Roles
 
-	return
+	st.AddMember(ctx, g, &member)
+
+	return before, true
+}
+
+// removeGuildMembers removes every member of a guild and returns how many
+// were held in memory.
+func (st *SandwichState) removeGuildMembers(ctx *StateCtx, guildID snowflake.ID) (count int) {
+	st.GuildMembersMu.Lock()
+	gm, o := st.GuildMembers[guildID]
+	delete(st.GuildMembers, guildID)
+	st.GuildMembersMu.Unlock()
+
+	if o {
+		gm.MembersMu.RLock()
+		ids := make([]snowflake.ID, 0, len(gm.Members))
+		for id := range gm.Members {
+			ids = append(ids, id)
+		}
+		gm.MembersMu.RUnlock()
+
+		count = len(ids)
+		st.memberForgotten(guildID, ids)
+	}
+
+	if st.redis != nil {
+		key := st.redis.membersKey(guildID)
+
+		if err := st.redis.client.Del(context.Background(), key).Err(); err != nil {
+			ctx.Sg.Logger.Warn().Err(err).Str("key", key).Msgf("Failed to remove members of guild ID %d from redis", guildID)
+		}
+	}
+
+	return count
+}
+
+func (st *SandwichState) RemoveMember(ctx *StateCtx, g *discord.Guild, s snowflake.ID) {
+	st.GuildMembersMu.RLock()
+	gm, o := st.GuildMembers[g.ID]
+	st.GuildMembersMu.RUnlock()
+
+	if o {
+		gm.MembersMu.Lock()
+		delete(gm.Members, s)
+		gm.MembersMu.Unlock()
+
+		st.memberForgotten(g.ID, []snowflake.ID{s})
+	}
+
+	if st.redis != nil {
+		st.redis.del(ctx, st.redis.membersKey(g.ID), s)
+	}
 }
 
 // Channel State
 
 func (st *SandwichState) AddChannel(ctx *StateCtx, c *discord.Channel) {
+	st.cacheChannel(c)
+
+	if st.redis != nil {
+		st.redis.set(ctx, st.redis.key("channel"), c.ID, c)
+	}
+}
+
+func (st *SandwichState) cacheChannel(c *discord.Channel) {
 	st.ChannelsMu.Lock()
 	st.Channels[c.ID] = c
 	st.ChannelsMu.Unlock()
+
+	st.touch(stateKindChannel, 0, c.ID)
 }
 
 func (st *SandwichState) GetChannel(ctx *StateCtx, s snowflake.ID) (c *discord.Channel, o bool) {
@@ -291,51 +624,186 @@ func (st *SandwichState) GetChannel(ctx *StateCtx, s snowflake.ID) (c *discord.C
 	c, o = st.Channels[s]
 	st.ChannelsMu.RUnlock()
 
+	if !o && st.redis != nil {
+		c = &discord.Channel{}
+		if o = st.redis.get(ctx, st.redis.key("channel"), s, c); o {
+			st.cacheChannel(c)
+		}
+	}
+
 	if !o {
-		c.ID = s
+		c = &discord.Channel{ID: s}
 	}
 
 	return
 }
 
-func (st *SandwichState) RemoveChannel(ctx *StateCtx, s snowflake.ID) {
+// RemoveChannel removes a channel and returns if it was held in memory.
+func (st *SandwichState) RemoveChannel(ctx *StateCtx, s snowflake.ID) (o bool) {
 	st.ChannelsMu.Lock()
+	_, o = st.Channels[s]
 	delete(st.Channels, s)
 	st.ChannelsMu.Unlock()
+
+	if st.redis != nil {
+		st.redis.del(ctx, st.redis.key("channel"), s)
+	}
+
+	return o
 }
 
 // Role State
 
-func (st *SandwichState) AddRole(ctx *StateCtx, r *discord.Role) {
+// AddRole stores a role of a guild and adds it to the roles of the guild if
+// it is new.
+func (st *SandwichState) AddRole(ctx *StateCtx, guildID snowflake.ID, r *discord.Role) {
+	st.cacheRole(guildID, r)
+
+	st.GuildsMu.Lock()
+	sg, ok := st.Guilds[guildID]
+
+	added := false
+
+	if ok {
+		added = true
+
+		for _, id := range sg.RoleIDs {
+			if id == r.ID {
+				added = false
+
+				break
+			}
+		}
+
+		if added {
+			sg.RoleIDs = append(sg.RoleIDs, r.ID)
+		}
+	}
+	st.GuildsMu.Unlock()
+
+	if st.redis != nil {
+		st.redis.set(ctx, st.redis.key("role"), r.ID, r)
+
+		if added {
+			st.redis.setGuild(ctx, guildID, sg)
+		}
+	}
+}
+
+func (st *SandwichState) cacheRole(guildID snowflake.ID, r *discord.Role) {
 	st.RolesMu.Lock()
-	st.Roles[r.ID] = r
+	roles, ok := st.Roles[guildID]
+	if !ok {
+		roles = make(map[snowflake.ID]*discord.Role)
+		st.Roles[guildID] = roles
+	}
+	roles[r.ID] = r
 	st.RolesMu.Unlock()
+
+	st.touch(stateKindRole, guildID, r.ID)
 }
 
-func (st *SandwichState) GetRole(ctx *StateCtx, s snowflake.ID) (r *discord.Role, o bool) {
+func (st *SandwichState) GetRole(ctx *StateCtx, guildID snowflake.ID, s snowflake.ID) (r *discord.Role, o bool) {
 	st.RolesMu.RLock()
-	r, o = st.Roles[s]
+	r, o = st.Roles[guildID][s]
 	st.RolesMu.RUnlock()
 
+	if !o && st.redis != nil {
+		r = &discord.Role{}
+		if o = st.redis.get(ctx, st.redis.key("role"), s, r); o {
+			st.cacheRole(guildID, r)
+		}
+	}
+
 	if !o {
-		r.ID = s
+		r = &discord.Role{ID: s}
 	}
 
 	return
 }
 
-func (st *SandwichState) RemoveRole(ctx *StateCtx, s snowflake.ID) {
+// RemoveRole removes a role from the state and from the roles of its guild.
+func (st *SandwichState) RemoveRole(ctx *StateCtx, guildID snowflake.ID, s snowflake.ID) {
+	st.RolesMu.Lock()
+	if roles, ok := st.Roles[guildID]; ok {
+		delete(roles, s)
+
+		if len(roles) == 0 {
+			delete(st.Roles, guildID)
+		}
+	}
+	st.RolesMu.Unlock()
+
+	st.GuildsMu.Lock()
+	sg, ok := st.Guilds[guildID]
+
+	removed := false
+
+	if ok {
+		for i, id := range sg.RoleIDs {
+			if id == s {
+				sg.RoleIDs = append(sg.RoleIDs[:i:i], sg.RoleIDs[i+1:]...)
+				removed = true
+
+				break
+			}
+		}
+	}
+	st.GuildsMu.Unlock()
+
+	if st.redis != nil {
+		st.redis.del(ctx, st.redis.key("role"), s)
+
+		if removed {
+			st.redis.setGuild(ctx, guildID, sg)
+		}
+	}
+}
+
+// removeGuildRoles removes every role of a guild which is being removed and
+// returns how many were held in memory.
+func (st *SandwichState) removeGuildRoles(ctx *StateCtx, guildID snowflake.ID, roleIDs []snowflake.ID) (count int) {
 	st.RolesMu.Lock()
-	delete(st.Roles, s)
+	count = len(st.Roles[guildID])
+	delete(st.Roles, guildID)
 	st.RolesMu.Unlock()
+
+	if st.redis != nil {
+		for _, id := range roleIDs {
+			st.redis.del(ctx, st.redis.key("role"), id)
+		}
+	}
+
+	return count
+}
+
+// RoleCount returns the number of roles held in memory.
+func (st *SandwichState) RoleCount() (count int) {
+	st.RolesMu.RLock()
+	for _, roles := range st.Roles {
+		count += len(roles)
+	}
+	st.RolesMu.RUnlock()
+
+	return count
 }
 
 // Emoji State
 
 func (st *SandwichState) AddEmoji(ctx *StateCtx, e *discord.Emoji) {
+	st.cacheEmoji(e)
+
+	if st.redis != nil {
+		st.redis.set(ctx, st.redis.key("emoji"), e.ID, e)
+	}
+}
+
+func (st *SandwichState) cacheEmoji(e *discord.Emoji) {
 	st.EmojisMu.Lock()
 	st.Emojis[e.ID] = e
 	st.EmojisMu.Unlock()
+
+	st.touch(stateKindEmoji, 0, e.ID)
 }
 
 func (st *SandwichState) GetEmoji(ctx *StateCtx, s snowflake.ID) (e *discord.Emoji, o bool) {
@@ -343,25 +811,50 @@ func (st *SandwichState) GetEmoji(ctx *StateCtx, s snowflake.ID) (e *discord.Emo
 	e, o = st.Emojis[s]
 	st.EmojisMu.RUnlock()
 
+	if !o && st.redis != nil {
+		e = &discord.Emoji{}
+		if o = st.redis.get(ctx, st.redis.key("emoji"), s, e); o {
+			st.cacheEmoji(e)
+		}
+	}
+
 	if !o {
-		e.ID = s
+		e = &discord.Emoji{ID: s}
 	}
 
 	return
 }
 
-func (st *SandwichState) RemoveEmoji(ctx *StateCtx, s snowflake.ID) {
+// RemoveEmoji removes an emoji and returns if it was held in memory.
+func (st *SandwichState) RemoveEmoji(ctx *StateCtx, s snowflake.ID) (o bool) {
 	st.EmojisMu.Lock()
+	_, o = st.Emojis[s]
 	delete(st.Emojis, s)
 	st.EmojisMu.Unlock()
+
+	if st.redis != nil {
+		st.redis.del(ctx, st.redis.key("emoji"), s)
+	}
+
+	return o
 }
 
 // User state
 
 func (st *SandwichState) AddUser(ctx *StateCtx, u *discord.User) {
+	st.cacheUser(u)
+
+	if st.redis != nil {
+		st.redis.set(ctx, st.redis.key("user"), u.ID, u)
+	}
+}
+
+func (st *SandwichState) cacheUser(u *discord.User) {
 	st.UsersMu.Lock()
 	st.Users[u.ID] = u
 	st.UsersMu.Unlock()
+
+	st.touch(stateKindUser, 0, u.ID)
 }
 
 func (st *SandwichState) GetUser(ctx *StateCtx, s snowflake.ID) (u *discord.User, o bool) {
@@ -369,8 +862,15 @@ func (st *SandwichState) GetUser(ctx *StateCtx, s snowflake.ID) (u *discord.User
 	u, o = st.Users[s]
 	st.UsersMu.RUnlock()
 
+	if !o && st.redis != nil {
+		u = &discord.User{}
+		if o = st.redis.get(ctx, st.redis.key("user"), s, u); o {
+			st.cacheUser(u)
+		}
+	}
+
 	if !o {
-		u.ID = s
+		u = &discord.User{ID: s}
 	}
 
 	return
@@ -380,10 +880,33 @@ func (st *SandwichState) RemoveUser(ctx *StateCtx, s snowflake.ID) {
 	st.UsersMu.Lock()
 	delete(st.Users, s)
 	st.UsersMu.Unlock()
+
+	if st.redis != nil {
+		st.redis.del(ctx, st.redis.key("user"), s)
+	}
 }
 
 func init() {
 	registerState("READY", StateReady)
+	registerState("RESUMED", StateResumed)
 	registerState("GUILD_CREATE", StateGuildCreate)
+	registerState("GUILD_UPDATE", StateGuildUpdate)
+	registerState("GUILD_DELETE", StateGuildDelete)
+	registerState("GUILD_MEMBER_ADD", StateGuildMemberAdd)
+	registerState("GUILD_MEMBER_UPDATE", StateGuildMemberUpdate)
+	registerState("GUILD_MEMBER_REMOVE", StateGuildMemberRemove)
+	registerState("CHANNEL_UPDATE", StateChannelUpdate)
+	registerState("GUILD_ROLE_CREATE", StateGuildRoleCreate)
+	registerState("GUILD_ROLE_UPDATE", StateGuildRoleUpdate)
+	registerState("GUILD_ROLE_DELETE", StateGuildRoleDelete)
+	registerState("PRESENCE_UPDATE", StatePresenceUpdate)
+	registerState("VOICE_STATE_UPDATE", StateVoiceStateUpdate)
+	registerState("THREAD_CREATE", StateThreadCreate)
+	registerState("THREAD_UPDATE", StateThreadUpdate)
+	registerState("THREAD_DELETE", StateThreadDelete)
+	registerState("THREAD_LIST_SYNC", StateThreadListSync)
+	registerState("THREAD_MEMBER_UPDATE", StateThreadMemberUpdate)
+	registerState("THREAD_MEMBERS_UPDATE", StateThreadMembersUpdate)
 	registerState("GUILD_MEMBERS_CHUNK", StateGuildMembersChunk)
+	registerState("MESSAGE_CREATE", StateMessageCreate)
 }
@@ -0,0 +1,39 @@
+package gateway
+
+import (
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+// StateChannelUpdate handles the CHANNEL_UPDATE event. The channel before the
+// update is included as before if it was in state and the event is in
+// events.include_before.
+func StateChannelUpdate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.ChannelUpdate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	if packet.Channel == nil {
+		return result, false, nil
+	}
+
+	result = structs.StateResult{
+		Data:  packet.Channel,
+		Extra: make(map[string]interface{}),
+	}
+
+	if ctx.includeBefore(msg.Type) {
+		if before, o := ctx.Sg.State.GetChannel(ctx, packet.ID); o {
+			result.Extra["before"] = before
+		}
+	}
+
+	// Cached channels are replaced by AddChannel so before is not changed.
+	ctx.Sg.State.AddChannel(ctx, packet.Channel)
+
+	return result, true, nil
+}
@@ -0,0 +1,380 @@
+package gateway
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// How often members past caching.member_ttl or over
+	// caching.max_members_per_guild are removed.
+	memberSweepInterval = 30 * time.Second
+
+	// Members removed each time the members lock of a guild is held so
+	// sweeping large guilds does not block events for long.
+	memberSweepBatch = 1000
+
+	// When a guild goes over caching.max_members_per_guild, the least
+	// recently used members are removed until it is at this percentage of
+	// the limit so a trim is not needed for every new member.
+	memberTrimTarget = 90
+)
+
+// memberEviction limits how many members are held in memory. Members are
+// removed once unused for the TTL, the least recently used members of a
+// guild are removed once it has more than perGuild members and the least
+// recently used members overall are removed once more than the size of the
+// lru are held. Zero disables a limit. Presences share the TTL and size.
+type memberEviction struct {
+	ttl      *int64 // Seconds
+	perGuild *int64
+
+	lruMu       sync.RWMutex
+	lru         *lru.Cache // Keyed by stateKey, nil without caching.max_members
+	presenceLRU *lru.Cache // Keyed by user ID, nil without caching.max_members
+
+	// Guilds currently being trimmed to perGuild.
+	trimmingMu sync.Mutex
+	trimming   map[snowflake.ID]bool
+
+	evictedTTL   *int64
+	evictedGuild *int64
+	evictedLRU   *int64
+
+	evictedPresenceTTL *int64
+	evictedPresenceLRU *int64
+}
+
+func newMemberEviction() *memberEviction {
+	return &memberEviction{
+		ttl:      new(int64),
+		perGuild: new(int64),
+
+		lruMu: sync.RWMutex{},
+
+		trimmingMu: sync.Mutex{},
+		trimming:   make(map[snowflake.ID]bool),
+
+		evictedTTL:   new(int64),
+		evictedGuild: new(int64),
+		evictedLRU:   new(int64),
+
+		evictedPresenceTTL: new(int64),
+		evictedPresenceLRU: new(int64),
+	}
+}
+
+// ConfigureMemberEviction sets the limits on members held in memory. ttl is
+// in seconds. Members over a lowered limit are removed by the next sweep or,
+// for maxMembers, immediately. Zero disables a limit.
+func (st *SandwichState) ConfigureMemberEviction(ttl int, perGuild int, maxMembers int) {
+	atomic.StoreInt64(st.memberEviction.ttl, int64(ttl))
+	atomic.StoreInt64(st.memberEviction.perGuild, int64(perGuild))
+
+	me := st.memberEviction
+
+	me.lruMu.Lock()
+	defer me.lruMu.Unlock()
+
+	switch {
+	case maxMembers < 1:
+		// Members are no longer tracked so removing the lru must not evict
+		// them.
+		me.lru = nil
+		me.presenceLRU = nil
+	case me.lru == nil:
+		// lru.NewWithEvict only errors for sizes below 1.
+		me.lru, _ = lru.NewWithEvict(maxMembers, st.evictMember)
+		me.presenceLRU, _ = lru.NewWithEvict(maxMembers, st.evictPresence)
+	default:
+		me.lru.Resize(maxMembers)
+		me.presenceLRU.Resize(maxMembers)
+	}
+}
+
+// MemberEvictions returns how many members have been removed from memory.
+func (st *SandwichState) MemberEvictions() structs.MemberEvictionStats {
+	return structs.MemberEvictionStats{
+		TTL:   atomic.LoadInt64(st.memberEviction.evictedTTL),
+		Guild: atomic.LoadInt64(st.memberEviction.evictedGuild),
+		LRU:   atomic.LoadInt64(st.memberEviction.evictedLRU),
+
+		PresenceTTL: atomic.LoadInt64(st.memberEviction.evictedPresenceTTL),
+		PresenceLRU: atomic.LoadInt64(st.memberEviction.evictedPresenceLRU),
+	}
+}
+
+// memberLRU returns the lru of members or nil if caching.max_members is not
+// set.
+func (st *SandwichState) memberLRU() (cache *lru.Cache) {
+	st.memberEviction.lruMu.RLock()
+	cache = st.memberEviction.lru
+	st.memberEviction.lruMu.RUnlock()
+
+	return cache
+}
+
+// presenceLRU returns the lru of presences or nil if caching.max_members is
+// not set.
+func (st *SandwichState) presenceLRU() (cache *lru.Cache) {
+	st.memberEviction.lruMu.RLock()
+	cache = st.memberEviction.presenceLRU
+	st.memberEviction.lruMu.RUnlock()
+
+	return cache
+}
+
+// memberStored tracks a member which was just added to memory. count is the
+// number of members the guild now has. No members lock may be held.
+func (st *SandwichState) memberStored(gm *discord.StateGuildMembers, sgm *discord.StateGuildMember, count int) {
+	if cache := st.memberLRU(); cache != nil {
+		cache.Add(stateKey{kind: stateKindMember, guildID: gm.GuildID, id: sgm.User}, nil)
+	}
+
+	if perGuild := int(atomic.LoadInt64(st.memberEviction.perGuild)); perGuild > 0 && count > perGuild {
+		st.trimMembers(gm, perGuild)
+	}
+}
+
+// memberUsed marks a member in memory as recently used.
+func (st *SandwichState) memberUsed(guildID snowflake.ID, sgm *discord.StateGuildMember) {
+	atomic.StoreInt64(&sgm.LastUsed, time.Now().Unix())
+
+	if cache := st.memberLRU(); cache != nil {
+		cache.Get(stateKey{kind: stateKindMember, guildID: guildID, id: sgm.User})
+	}
+}
+
+// memberForgotten stops tracking a member which is no longer in memory.
+func (st *SandwichState) memberForgotten(guildID snowflake.ID, ids []snowflake.ID) {
+	cache := st.memberLRU()
+	if cache == nil {
+		return
+	}
+
+	// The member is already removed so evictMember does not count it.
+	for _, id := range ids {
+		cache.Remove(stateKey{kind: stateKindMember, guildID: guildID, id: id})
+	}
+}
+
+// evictMember removes a member from memory once it has fallen out of the
+// lru of caching.max_members. When using redis, it is still available there.
+func (st *SandwichState) evictMember(key interface{}, _ interface{}) {
+	k, ok := key.(stateKey)
+	if !ok {
+		return
+	}
+
+	st.GuildMembersMu.RLock()
+	gm, ok := st.GuildMembers[k.guildID]
+	st.GuildMembersMu.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	gm.MembersMu.Lock()
+	_, ok = gm.Members[k.id]
+	delete(gm.Members, k.id)
+	gm.MembersMu.Unlock()
+
+	if ok {
+		atomic.AddInt64(st.memberEviction.evictedLRU, 1)
+	}
+}
+
+// removeMembers removes members from a guild in batches. As the lock is
+// released between batches, remove is checked again for each member before
+// it is removed. The removed members are returned.
+func (st *SandwichState) removeMembers(gm *discord.StateGuildMembers, ids []snowflake.ID,
+	remove func(sgm *discord.StateGuildMember) bool) (removed []snowflake.ID) {
+	removed = make([]snowflake.ID, 0, len(ids))
+
+	for start := 0; start < len(ids); start += memberSweepBatch {
+		end := start + memberSweepBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		gm.MembersMu.Lock()
+		for _, id := range ids[start:end] {
+			if sgm, ok := gm.Members[id]; ok && remove(sgm) {
+				delete(gm.Members, id)
+				removed = append(removed, id)
+			}
+		}
+		gm.MembersMu.Unlock()
+	}
+
+	return removed
+}
+
+// trimMembers removes the least recently used members of a guild until it is
+// at memberTrimTarget percent of limit. Nothing is done if the guild is
+// already being trimmed.
+func (st *SandwichState) trimMembers(gm *discord.StateGuildMembers, limit int) {
+	me := st.memberEviction
+
+	me.trimmingMu.Lock()
+	if me.trimming[gm.GuildID] {
+		me.trimmingMu.Unlock()
+
+		return
+	}
+	me.trimming[gm.GuildID] = true
+	me.trimmingMu.Unlock()
+
+	defer func() {
+		me.trimmingMu.Lock()
+		delete(me.trimming, gm.GuildID)
+		me.trimmingMu.Unlock()
+	}()
+
+	type memberUse struct {
+		id       snowflake.ID
+		lastUsed int64
+	}
+
+	gm.MembersMu.RLock()
+	uses := make([]memberUse, 0, len(gm.Members))
+	for id, sgm := range gm.Members {
+		uses = append(uses, memberUse{id: id, lastUsed: atomic.LoadInt64(&sgm.LastUsed)})
+	}
+	gm.MembersMu.RUnlock()
+
+	excess := len(uses) - limit*memberTrimTarget/100
+	if len(uses) <= limit || excess < 1 {
+		return
+	}
+
+	sort.Slice(uses, func(i, j int) bool {
+		return uses[i].lastUsed < uses[j].lastUsed
+	})
+
+	ids := make([]snowflake.ID, excess)
+	for i := range ids {
+		ids[i] = uses[i].id
+	}
+
+	// Members stored again since are kept.
+	removed := st.removeMembers(gm, ids, func(sgm *discord.StateGuildMember) bool {
+		return atomic.LoadInt64(&sgm.LastUsed) <= uses[excess-1].lastUsed
+	})
+
+	atomic.AddInt64(me.evictedGuild, int64(len(removed)))
+	st.memberForgotten(gm.GuildID, removed)
+}
+
+// expiredMembers returns the members of a guild last used before expiry. The
+// IDs are copied first and then checked memberSweepBatch at a time so the
+// members lock is not held whilst checking every member of a large guild.
+func expiredMembers(gm *discord.StateGuildMembers, expiry int64) (expired []snowflake.ID) {
+	gm.MembersMu.RLock()
+	ids := make([]snowflake.ID, 0, len(gm.Members))
+	for id := range gm.Members {
+		ids = append(ids, id)
+	}
+	gm.MembersMu.RUnlock()
+
+	expired = make([]snowflake.ID, 0)
+
+	for start := 0; start < len(ids); start += memberSweepBatch {
+		end := start + memberSweepBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		gm.MembersMu.RLock()
+		for _, id := range ids[start:end] {
+			if sgm, ok := gm.Members[id]; ok && atomic.LoadInt64(&sgm.LastUsed) < expiry {
+				expired = append(expired, id)
+			}
+		}
+		gm.MembersMu.RUnlock()
+	}
+
+	return expired
+}
+
+// sweepMembers removes members which have not been used within the TTL and
+// trims guilds which are over the per guild limit.
+func (st *SandwichState) sweepMembers(now time.Time) {
+	ttl := atomic.LoadInt64(st.memberEviction.ttl)
+	perGuild := int(atomic.LoadInt64(st.memberEviction.perGuild))
+
+	if ttl < 1 && perGuild < 1 {
+		return
+	}
+
+	st.GuildMembersMu.RLock()
+	guilds := make([]*discord.StateGuildMembers, 0, len(st.GuildMembers))
+	for _, gm := range st.GuildMembers {
+		guilds = append(guilds, gm)
+	}
+	st.GuildMembersMu.RUnlock()
+
+	expiry := now.Unix() - ttl
+
+	for _, gm := range guilds {
+		if ttl > 0 {
+			expired := expiredMembers(gm, expiry)
+
+			if len(expired) > 0 {
+				removed := st.removeMembers(gm, expired, func(sgm *discord.StateGuildMember) bool {
+					return atomic.LoadInt64(&sgm.LastUsed) < expiry
+				})
+
+				atomic.AddInt64(st.memberEviction.evictedTTL, int64(len(removed)))
+				st.memberForgotten(gm.GuildID, removed)
+			}
+		}
+
+		if perGuild > 0 {
+			gm.MembersMu.RLock()
+			count := len(gm.Members)
+			gm.MembersMu.RUnlock()
+
+			if count > perGuild {
+				st.trimMembers(gm, perGuild)
+			}
+		}
+	}
+}
+
+// memberEvictionSettings returns caching.member_ttl,
+// caching.max_members_per_guild and caching.max_members.
+func (sg *Sandwich) memberEvictionSettings() (ttl int, perGuild int, maxMembers int) {
+	sg.ConfigurationMu.RLock()
+	defer sg.ConfigurationMu.RUnlock()
+
+	return sg.Configuration.Caching.MemberTTL,
+		sg.Configuration.Caching.MaxMembersPerGuild,
+		sg.Configuration.Caching.MaxMembers
+}
+
+// memberEvictionRunner applies configuration changes to the member limits
+// and sweeps members and presences every memberSweepInterval until the
+// daemon shuts down.
+func (sg *Sandwich) memberEvictionRunner() {
+	t := time.NewTicker(memberSweepInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-sg.ctx.Done():
+			return
+		case now := <-t.C:
+			sg.State.ConfigureMemberEviction(sg.memberEvictionSettings())
+			sg.State.sweepMembers(now)
+			sg.State.sweepPresences(now)
+		}
+	}
+}
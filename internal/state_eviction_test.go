@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+func TestSweepMembersTTL(t *testing.T) {
+	ctx := newTestStateCtx(t)
+	st := ctx.Sg.State
+
+	guild := &discord.Guild{ID: testGuildID}
+	count := memberSweepBatch*2 + memberSweepBatch/2
+
+	for i := 1; i <= count; i++ {
+		st.AddMember(ctx, guild, &discord.GuildMember{User: &discord.User{ID: snowflake.ID(i)}})
+	}
+
+	now := time.Now()
+	st.ConfigureMemberEviction(60, 0, 0)
+
+	st.GuildMembersMu.RLock()
+	gm := st.GuildMembers[testGuildID]
+	st.GuildMembersMu.RUnlock()
+
+	// Every other member was last used before the TTL.
+	gm.MembersMu.RLock()
+	for id, sgm := range gm.Members {
+		if id%2 == 0 {
+			atomic.StoreInt64(&sgm.LastUsed, now.Unix()-120)
+		}
+	}
+	gm.MembersMu.RUnlock()
+
+	st.sweepMembers(now)
+
+	gm.MembersMu.RLock()
+	defer gm.MembersMu.RUnlock()
+
+	if len(gm.Members) != count-count/2 {
+		t.Errorf("%d members were kept, want %d", len(gm.Members), count-count/2)
+	}
+
+	for id := range gm.Members {
+		if id%2 == 0 {
+			t.Errorf("expired member %d was kept", id)
+		}
+	}
+
+	if evicted := st.MemberEvictions().TTL; evicted != int64(count/2) {
+		t.Errorf("%d members were counted as expired, want %d", evicted, count/2)
+	}
+}
+
+func TestMemberEvictionRunnerStops(t *testing.T) {
+	ctx := newTestStateCtx(t)
+
+	done := make(chan void)
+
+	go func() {
+		ctx.Sg.memberEvictionRunner()
+		close(done)
+	}()
+
+	ctx.Sg.cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("runner did not stop after shutdown")
+	}
+}
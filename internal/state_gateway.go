@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
@@ -16,18 +17,24 @@ func StateReady(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.Stat
 
 	var guildPayload discord.GuildCreate
 
-	err = json.Unmarshal(msg.Data, &packet)
+	err = ctx.Sh.decodeContent(msg, &packet)
 	if err != nil {
 		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
 	}
 
 	ctx.Sh.Logger.Info().Msg("Received READY payload")
 
+	ctx.Sh.recordReady(msg.Data, false)
+
 	ctx.Sh.Lock()
 	ctx.Sh.sessionID = packet.SessionID
+	ctx.Sh.resumeGatewayURL = packet.ResumeGatewayURL
 	ctx.Sh.User = packet.User
 	ctx.Sh.Unlock()
 
+	// Persist the new session with the next dispatch.
+	atomic.StoreInt64(ctx.Sh.persistedSeq, 0)
+
 	events := make([]discord.ReceivedPayload, 0)
 	guildIDs := make([]snowflake.ID, 0, len(packet.Guilds))
 
@@ -41,6 +48,8 @@ func StateReady(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.Stat
 
 	guildCreateEvents := 0
 
+	ctx.Sh.guildRecovery.Start()
+
 	// If true will only run events once finished loading.
 	// TODO: Add to sandwich configuration.
 	preemptiveEvents := false
@@ -72,8 +81,14 @@ ready:
 			if preemptiveEvents {
 				events = append(events, msg)
 			} else {
-				if err = ctx.Sh.OnDispatch(msg); err != nil && !xerrors.Is(err, NoHandler) {
-					ctx.Sh.Logger.Error().Err(err).Msg("Failed dispatching event")
+				handle := func() {
+					if err := ctx.Sh.OnDispatch(msg); err != nil && !xerrors.Is(err, NoHandler) {
+						ctx.Sh.Logger.Error().Err(err).Msg("Failed dispatching event")
+					}
+				}
+
+				if msg.Type != "GUILD_CREATE" || !ctx.Sh.guildRecovery.Enqueue(msg, handle) {
+					handle()
 				}
 			}
 		case <-t.C:
@@ -81,6 +96,22 @@ ready:
 
 			if ctx.Sh.Manager.Configuration.Caching.RequestMembers {
 				go func() {
+					// Member requests wait for a gateway incident to end so
+					// they do not compete with shards reconnecting.
+					select {
+					case <-ctx.Sh.Manager.Sandwich.incidentCleared():
+					case <-ctx.Sh.currentContext().Done():
+						return
+					}
+
+					// Chunking is deferred until the guilds have been
+					// ingested so it does not add to the recovery burst.
+					select {
+					case <-ctx.Sh.guildRecovery.Recovered():
+					case <-ctx.Sh.currentContext().Done():
+						return
+					}
+
 					for _, guildID := range guildIDs {
 						ticket := ctx.Sh.ShardGroup.ChunkLimiter.Wait()
 
@@ -101,7 +132,8 @@ ready:
 		}
 	}
 
-	ctx.Sh.ready <- void{}
+	ctx.Sh.markReady()
+
 	if err := ctx.Sh.SetStatus(structs.ShardReady); err != nil {
 		ctx.Sh.Logger.Error().Err(err).Msg("Encountered error setting shard status")
 	}
@@ -122,3 +154,20 @@ ready:
 
 	return result, false, nil
 }
+
+// StateResumed handles the RESUMED event.
+func StateResumed(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	ctx.Sh.Logger.Info().Msg("Received RESUMED payload")
+
+	ctx.Sh.recordReady(msg.Data, true)
+
+	ctx.Sh.guildRecovery.Start()
+
+	ctx.Sh.markReady()
+
+	if err := ctx.Sh.SetStatus(structs.ShardReady); err != nil {
+		ctx.Sh.Logger.Error().Err(err).Msg("Encountered error setting shard status")
+	}
+
+	return result, false, nil
+}
@@ -1,6 +1,9 @@
 package gateway
 
 import (
+	"strings"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
 	"github.com/TheRockettek/Sandwich-Daemon/structs"
 	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
 	"golang.org/x/xerrors"
@@ -10,12 +13,13 @@ import (
 func StateGuildCreate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
 	var packet discord.GuildCreate
 
-	err = json.Unmarshal(msg.Data, &packet)
+	err = ctx.Sh.decodeContent(msg, &packet)
 	if err != nil {
 		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
 	}
 
 	ctx.Sg.State.AddGuildShardGroup(ctx, &packet.Guild)
+	ctx.storeMutuals(packet.Guild.ID, packet.Guild.Members)
 
 	lazy, _ := ctx.Vars["lazy"].(bool)
 
@@ -49,21 +53,28 @@ func StateGuildMembersChunk(ctx *StateCtx, msg discord.ReceivedPayload) (result
 
 	var packet discord.GuildMembersChunk
 
-	err = json.Unmarshal(msg.Data, &packet)
+	err = ctx.Sh.decodeContent(msg, &packet)
 	if err != nil {
 		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
 	}
 
 	ctx.Sh.Logger.Debug().Msgf("Received member chunk %d/%d for guild ID %d", packet.ChunkIndex, packet.ChunkCount, packet.GuildID)
 
-	ctx.Sh.ShardGroup.MemberChunkCallbacksMu.RLock()
-	callback, ok := ctx.Sh.ShardGroup.MemberChunkCallbacks[packet.GuildID]
-	ctx.Sh.ShardGroup.MemberChunkCallbacksMu.RUnlock()
+	// Chunks for lazy member requests are not part of a full guild chunk.
+	if strings.HasPrefix(packet.Nonce, lazyMemberNoncePrefix) {
+		if g, o := ctx.Sg.State.GetGuild(ctx, packet.GuildID, false); o {
+			ctx.Sg.State.AddMembers(ctx, g, packet.Members)
+			ctx.storeMutuals(packet.GuildID, packet.Members)
+		}
+
+		ctx.Sh.lazyMembers.Resolve(&packet)
 
-	if ok {
-		callback <- true
-	} else {
-		ctx.Sh.Logger.Warn().Msgf("Received member chunk for guild ID %d but no callback was active", packet.GuildID)
+		return result, false, nil
+	}
+
+	if !ctx.Sh.ShardGroup.receiveMemberChunk(&packet) {
+		ctx.Sh.Logger.Warn().Msgf("Received member chunk for guild ID %d with nonce %q but no request was active",
+			packet.GuildID, packet.Nonce)
 	}
 
 	g, o := ctx.Sg.State.GetGuild(ctx, packet.GuildID, false)
@@ -73,14 +84,258 @@ func StateGuildMembersChunk(ctx *StateCtx, msg discord.ReceivedPayload) (result
 		return
 	}
 
-	for _, member := range packet.Members {
-		ctx.Sg.State.AddMember(ctx, g, member)
-	}
-
-	// TODO: Handle storing mutuals
+	ctx.Sg.State.AddMembers(ctx, g, packet.Members)
+	ctx.storeMutuals(packet.GuildID, packet.Members)
 
 	// We do not want to send member chunks to
 	// consumers as they will have no use.
 
 	return result, false, nil
 }
+
+// StateGuildUpdate handles the GUILD_UPDATE event. The guild before the
+// update, without its members and channels, is included as before if the
+// event is in events.include_before.
+func StateGuildUpdate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.GuildUpdate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	guild := discord.Guild(packet)
+
+	before, o := ctx.Sg.State.UpdateGuild(ctx, &guild)
+	if o && ctx.includeBefore(msg.Type) {
+		result.Extra["before"] = before
+	}
+
+	return result, true, nil
+}
+
+// StateGuildMemberAdd handles the GUILD_MEMBER_ADD event.
+func StateGuildMemberAdd(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.GuildMemberAdd
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	if packet.GuildMember != nil && packet.User != nil {
+		ctx.Sg.State.AddMember(ctx, &discord.Guild{ID: packet.GuildID}, packet.GuildMember)
+		ctx.storeMutuals(packet.GuildID, []*discord.GuildMember{packet.GuildMember})
+	}
+
+	return structs.StateResult{
+		Data: packet,
+	}, true, nil
+}
+
+// StateGuildMemberRemove handles the GUILD_MEMBER_REMOVE event. The removed
+// member is included as member if it was in state.
+func StateGuildMemberRemove(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.GuildMemberRemove
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	if packet.User == nil {
+		return result, true, nil
+	}
+
+	g := &discord.Guild{ID: packet.GuildID}
+
+	if member, o := ctx.Sg.State.GetMembers(ctx, g, []snowflake.ID{packet.User.ID})[packet.User.ID]; o {
+		result.Extra["member"] = member
+	}
+
+	ctx.Sg.State.RemoveMember(ctx, g, packet.User.ID)
+	ctx.Mg.mutuals.remove(packet.GuildID, packet.User.ID)
+
+	return result, true, nil
+}
+
+// StateGuildMemberUpdate handles the GUILD_MEMBER_UPDATE event. Only members
+// already in state are updated. The member before the update is included as
+// before if the event is in events.include_before.
+func StateGuildMemberUpdate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.GuildMemberUpdate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	if packet.User == nil {
+		return result, true, nil
+	}
+
+	ctx.storeMutuals(packet.GuildID, []*discord.GuildMember{{User: packet.User}})
+
+	before, o := ctx.Sg.State.UpdateMember(ctx, packet.GuildID, &packet)
+	if o && ctx.includeBefore(msg.Type) {
+		result.Extra["before"] = before
+	}
+
+	return result, true, nil
+}
+
+// StateGuildDelete handles the GUILD_DELETE event. Guilds which are only
+// unavailable are kept in state as a GUILD_CREATE follows once they are
+// available again.
+func StateGuildDelete(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.GuildDelete
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	if packet.Unavailable {
+		ctx.Sh.UnavailableMu.Lock()
+		ctx.Sh.Unavailable[packet.ID] = true
+		ctx.Sh.UnavailableMu.Unlock()
+	}
+
+	cleanupGuild(ctx, packet.ID, packet.Unavailable)
+
+	return structs.StateResult{
+		Data: packet,
+	}, true, nil
+}
+
+// cleanupGuild removes what is held for a deleted guild. Unavailable guilds
+// only lose their voice states and member chunking as everything else is
+// sent again with GUILD_CREATE. Guilds the bot was removed from are removed
+// entirely.
+func cleanupGuild(ctx *StateCtx, guildID snowflake.ID, unavailable bool) {
+	ctx.Sh.cleanGuildChunks(guildID)
+
+	if unavailable {
+		voiceStates := len(ctx.Sg.State.GetVoiceStates(guildID))
+
+		// Voice states are sent again once the guild is available.
+		ctx.Sg.State.SyncVoiceStates(guildID, nil)
+
+		ctx.Sh.Logger.Debug().
+			Int64("guild_id", guildID.Int64()).
+			Int("voice_states", voiceStates).
+			Msg("Cleaned up unavailable guild")
+
+		return
+	}
+
+	ctx.Sh.UnavailableMu.Lock()
+	delete(ctx.Sh.Unavailable, guildID)
+	ctx.Sh.UnavailableMu.Unlock()
+
+	ctx.Mg.forgetChunkFailure(guildID)
+
+	ctx.Mg.guildActivityMu.Lock()
+	delete(ctx.Mg.guildActivity, guildID)
+	ctx.Mg.guildActivityMu.Unlock()
+
+	ctx.Mg.mutuals.removeGuild(guildID)
+
+	removed := ctx.Sg.State.RemoveGuildShardGroup(ctx, guildID)
+
+	ctx.Sh.Logger.Debug().
+		Int64("guild_id", guildID.Int64()).
+		Int("members", removed.Members).
+		Int("roles", removed.Roles).
+		Int("channels", removed.Channels).
+		Int("threads", removed.Threads).
+		Int("emojis", removed.Emojis).
+		Int("voice_states", removed.VoiceStates).
+		Msg("Cleaned up removed guild")
+}
+
+// StateGuildRoleCreate handles the GUILD_ROLE_CREATE event.
+func StateGuildRoleCreate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.GuildRoleCreate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	if packet.Role != nil {
+		ctx.Sg.State.AddRole(ctx, packet.GuildID, packet.Role)
+	}
+
+	return structs.StateResult{
+		Data: packet,
+	}, true, nil
+}
+
+// StateGuildRoleUpdate handles the GUILD_ROLE_UPDATE event. The role before
+// the update is included as before if it was in state and the event is in
+// events.include_before.
+func StateGuildRoleUpdate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.GuildRoleUpdate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	if packet.Role != nil {
+		if ctx.includeBefore(msg.Type) {
+			if before, o := ctx.Sg.State.GetRole(ctx, packet.GuildID, packet.Role.ID); o {
+				result.Extra["before"] = before
+			}
+		}
+
+		ctx.Sg.State.AddRole(ctx, packet.GuildID, packet.Role)
+	}
+
+	return result, true, nil
+}
+
+// StateGuildRoleDelete handles the GUILD_ROLE_DELETE event. The deleted role
+// is included as role if it was in state.
+func StateGuildRoleDelete(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.GuildRoleDelete
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	if role, o := ctx.Sg.State.GetRole(ctx, packet.GuildID, packet.RoleID); o {
+		result.Extra["role"] = role
+	}
+
+	ctx.Sg.State.RemoveRole(ctx, packet.GuildID, packet.RoleID)
+
+	return result, true, nil
+}
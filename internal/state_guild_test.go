@@ -0,0 +1,145 @@
+package gateway
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/tevino/abool"
+)
+
+const (
+	testEmojiID snowflake.ID = 105
+)
+
+// newGuildCleanupCtx returns a StateCtx of a real shard with a guild holding
+// a role, channel, emoji and member in state whilst it is being chunked.
+func newGuildCleanupCtx(t *testing.T) *StateCtx {
+	t.Helper()
+
+	sh := newTestShard(t)
+	sh.Manager.Configuration.Caching.StoreMutuals = true
+
+	ctx := &StateCtx{Sg: sh.Manager.Sandwich, Mg: sh.Manager, Sh: sh}
+
+	// Set up by READY which always comes before GUILD_DELETE.
+	sh.Unavailable = make(map[snowflake.ID]bool)
+
+	guild := &discord.Guild{
+		ID:       testGuildID,
+		Name:     "guild",
+		Roles:    []*discord.Role{{ID: testRoleID, Name: "role"}},
+		Channels: []*discord.Channel{{ID: testChannelID, GuildID: testGuildID, Name: "channel"}},
+		Emojis:   []*discord.Emoji{{ID: testEmojiID, Name: "emoji"}},
+	}
+
+	member := &discord.GuildMember{
+		User:     &discord.User{ID: testUserID, Username: "user"},
+		JoinedAt: "2021-01-01T00:00:00Z",
+	}
+
+	ctx.Sg.State.AddGuildShardGroup(ctx, guild)
+	ctx.Sg.State.AddMember(ctx, guild, member)
+	ctx.storeMutuals(testGuildID, []*discord.GuildMember{member})
+
+	sh.ShardGroup.MemberChunksCallbackMu.Lock()
+	sh.ShardGroup.MemberChunksCallback[testGuildID] = &sync.WaitGroup{}
+	sh.ShardGroup.MemberChunksCallbackMu.Unlock()
+
+	sh.ShardGroup.MemberChunksCompleteMu.Lock()
+	sh.ShardGroup.MemberChunksComplete[testGuildID] = abool.New()
+	sh.ShardGroup.MemberChunksCompleteMu.Unlock()
+
+	return ctx
+}
+
+// guildCached reports which parts of the test guild are still in state.
+func guildCached(ctx *StateCtx) map[string]bool {
+	guild := &discord.Guild{ID: testGuildID}
+
+	_, guildOk := ctx.Sg.State.GetGuild(ctx, testGuildID, false)
+	_, roleOk := ctx.Sg.State.GetRole(ctx, testGuildID, testRoleID)
+	_, channelOk := ctx.Sg.State.GetChannel(ctx, testChannelID)
+	_, emojiOk := ctx.Sg.State.GetEmoji(ctx, testEmojiID)
+	_, memberOk := ctx.Sg.State.GetMember(ctx, guild, testUserID)
+
+	ctx.Sh.ShardGroup.GuildsMu.RLock()
+	_, shardGroupOk := ctx.Sh.ShardGroup.Guilds[testGuildID]
+	ctx.Sh.ShardGroup.GuildsMu.RUnlock()
+
+	mutuals, _ := ctx.Mg.mutuals.page(testUserID, maxMutualGuilds, 0)
+
+	return map[string]bool{
+		"guild":       guildOk,
+		"role":        roleOk,
+		"channel":     channelOk,
+		"emoji":       emojiOk,
+		"member":      memberOk,
+		"shard group": shardGroupOk,
+		"mutual":      len(mutuals) > 0,
+	}
+}
+
+// chunkingCached reports if the test guild has any member chunking entries.
+func chunkingCached(ctx *StateCtx) bool {
+	ctx.Sh.ShardGroup.MemberChunksCallbackMu.RLock()
+	_, callback := ctx.Sh.ShardGroup.MemberChunksCallback[testGuildID]
+	ctx.Sh.ShardGroup.MemberChunksCallbackMu.RUnlock()
+
+	ctx.Sh.ShardGroup.MemberChunksCompleteMu.RLock()
+	_, complete := ctx.Sh.ShardGroup.MemberChunksComplete[testGuildID]
+	ctx.Sh.ShardGroup.MemberChunksCompleteMu.RUnlock()
+
+	return callback || complete
+}
+
+func TestStateGuildDeleteUnavailableKeepsState(t *testing.T) {
+	ctx := newGuildCleanupCtx(t)
+
+	dispatchState(t, ctx, "GUILD_DELETE", discord.GuildDelete{ID: testGuildID, Unavailable: true})
+
+	for name, cached := range guildCached(ctx) {
+		if !cached {
+			t.Errorf("%s was removed from an unavailable guild", name)
+		}
+	}
+
+	if chunkingCached(ctx) {
+		t.Error("member chunking of an unavailable guild was not cleaned up")
+	}
+
+	ctx.Sh.UnavailableMu.RLock()
+	unavailable := ctx.Sh.Unavailable[testGuildID]
+	ctx.Sh.UnavailableMu.RUnlock()
+
+	if !unavailable {
+		t.Error("guild was not marked unavailable")
+	}
+}
+
+func TestStateGuildDeleteRemovesState(t *testing.T) {
+	ctx := newGuildCleanupCtx(t)
+
+	// A guild that went unavailable first is still removed entirely.
+	dispatchState(t, ctx, "GUILD_DELETE", discord.GuildDelete{ID: testGuildID, Unavailable: true})
+	dispatchState(t, ctx, "GUILD_DELETE", discord.GuildDelete{ID: testGuildID})
+
+	for name, cached := range guildCached(ctx) {
+		if cached {
+			t.Errorf("%s was kept after the guild was removed", name)
+		}
+	}
+
+	if chunkingCached(ctx) {
+		t.Error("member chunking of a removed guild was not cleaned up")
+	}
+
+	ctx.Sh.UnavailableMu.RLock()
+	_, unavailable := ctx.Sh.Unavailable[testGuildID]
+	ctx.Sh.UnavailableMu.RUnlock()
+
+	if unavailable {
+		t.Error("removed guild is still marked unavailable")
+	}
+}
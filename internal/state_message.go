@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+// StateMessageCreate handles the MESSAGE_CREATE event.
+func StateMessageCreate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.Message
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	ctx.Mg.markGuildActive(packet.GuildID, time.Now().UTC())
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	// Webhook messages do not have a member to look up.
+	if packet.WebhookID == 0 {
+		if member, o := ctx.Sh.lazyMember(ctx, msg.Type, packet.GuildID, packet.Author); o {
+			result.Extra["member"] = member
+		}
+	}
+
+	return result, true, nil
+}
@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+// Presence State
+//
+// Presences are only held in memory and are limited by caching.member_ttl
+// and caching.max_members like members.
+
+// SetPresence stores the last known presence of a user.
+func (st *SandwichState) SetPresence(userID snowflake.ID, sp *discord.StatePresence) {
+	atomic.StoreInt64(&sp.LastUsed, time.Now().Unix())
+
+	st.PresencesMu.Lock()
+	st.Presences[userID] = sp
+	st.PresencesMu.Unlock()
+
+	if cache := st.presenceLRU(); cache != nil {
+		cache.Add(userID, nil)
+	}
+}
+
+func (st *SandwichState) GetPresence(s snowflake.ID) (sp *discord.StatePresence, o bool) {
+	st.PresencesMu.RLock()
+	sp, o = st.Presences[s]
+	st.PresencesMu.RUnlock()
+
+	if o {
+		atomic.StoreInt64(&sp.LastUsed, time.Now().Unix())
+
+		if cache := st.presenceLRU(); cache != nil {
+			cache.Get(s)
+		}
+	}
+
+	return
+}
+
+// PresenceCount returns the number of presences held in memory.
+func (st *SandwichState) PresenceCount() (count int) {
+	st.PresencesMu.RLock()
+	count = len(st.Presences)
+	st.PresencesMu.RUnlock()
+
+	return count
+}
+
+// evictPresence removes a presence once it has fallen out of the lru of
+// caching.max_members.
+func (st *SandwichState) evictPresence(key interface{}, _ interface{}) {
+	userID, ok := key.(snowflake.ID)
+	if !ok {
+		return
+	}
+
+	st.PresencesMu.Lock()
+	_, ok = st.Presences[userID]
+	delete(st.Presences, userID)
+	st.PresencesMu.Unlock()
+
+	if ok {
+		atomic.AddInt64(st.memberEviction.evictedPresenceLRU, 1)
+	}
+}
+
+// sweepPresences removes presences which have not been used within
+// caching.member_ttl in batches of memberSweepBatch.
+func (st *SandwichState) sweepPresences(now time.Time) {
+	ttl := atomic.LoadInt64(st.memberEviction.ttl)
+	if ttl < 1 {
+		return
+	}
+
+	expiry := now.Unix() - ttl
+	expired := make([]snowflake.ID, 0)
+
+	st.PresencesMu.RLock()
+	for id, sp := range st.Presences {
+		if atomic.LoadInt64(&sp.LastUsed) < expiry {
+			expired = append(expired, id)
+		}
+	}
+	st.PresencesMu.RUnlock()
+
+	removed := make([]snowflake.ID, 0, len(expired))
+
+	for start := 0; start < len(expired); start += memberSweepBatch {
+		end := start + memberSweepBatch
+		if end > len(expired) {
+			end = len(expired)
+		}
+
+		st.PresencesMu.Lock()
+		for _, id := range expired[start:end] {
+			// Presences updated since are kept.
+			if sp, ok := st.Presences[id]; ok && atomic.LoadInt64(&sp.LastUsed) < expiry {
+				delete(st.Presences, id)
+				removed = append(removed, id)
+			}
+		}
+		st.PresencesMu.Unlock()
+	}
+
+	atomic.AddInt64(st.memberEviction.evictedPresenceTTL, int64(len(removed)))
+
+	if cache := st.presenceLRU(); cache != nil {
+		// The presence is already removed so evictPresence does not count it.
+		for _, id := range removed {
+			cache.Remove(id)
+		}
+	}
+}
+
+// StatePresenceUpdate handles the PRESENCE_UPDATE event. The presence is
+// only stored if caching.cache_presences is enabled. The previous presence is
+// included as before if it was in state and events.include_before has the
+// event.
+func StatePresenceUpdate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.PresenceUpdate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	ctx.Mg.ConfigurationMu.RLock()
+	cachePresences := ctx.Mg.Configuration.Caching.CachePresences
+	ctx.Mg.ConfigurationMu.RUnlock()
+
+	if !cachePresences || packet.User == nil {
+		return result, true, nil
+	}
+
+	if ctx.includeBefore(msg.Type) {
+		if before, o := ctx.Sg.State.GetPresence(packet.User.ID); o {
+			result.Extra["before"] = before
+		}
+	}
+
+	ctx.Sg.State.SetPresence(packet.User.ID, discord.FromPresenceUpdate(&packet))
+
+	return result, true, nil
+}
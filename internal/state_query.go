@@ -0,0 +1,389 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/vmihailenco/msgpack"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Suffix of the messaging channel consumers send state queries on.
+	stateQueriesSuffix = ":state"
+
+	// Queries answered each second when messaging.state_query_rate is not
+	// set.
+	defaultStateQueryRate = 100
+)
+
+var (
+	// ErrStateQueryOp is returned for state queries with an unknown op.
+	ErrStateQueryOp = xerrors.New("unknown state query op")
+
+	// ErrStateQueryLimited is returned for state queries received after the
+	// rate limit of the current second was reached.
+	ErrStateQueryLimited = xerrors.New("state query rate limit reached")
+)
+
+// stateQueryLimiter counts state queries and limits how many are answered
+// each second.
+type stateQueryLimiter struct {
+	mu     sync.Mutex
+	window int64 // Unix second the count belongs to
+	count  int
+
+	handled  *int64
+	notFound *int64
+	limited  *int64
+}
+
+func newStateQueryLimiter() *stateQueryLimiter {
+	return &stateQueryLimiter{
+		mu: sync.Mutex{},
+
+		handled:  new(int64),
+		notFound: new(int64),
+		limited:  new(int64),
+	}
+}
+
+// Allow returns if another query can be answered this second.
+func (sl *stateQueryLimiter) Allow(rate int) bool {
+	now := time.Now().Unix()
+
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	if sl.window != now {
+		sl.window = now
+		sl.count = 0
+	}
+
+	if sl.count >= rate {
+		atomic.AddInt64(sl.limited, 1)
+
+		return false
+	}
+
+	sl.count++
+
+	return true
+}
+
+// API returns how many state queries have been answered.
+func (sl *stateQueryLimiter) API() structs.StateQueryStats {
+	return structs.StateQueryStats{
+		Handled:  atomic.LoadInt64(sl.handled),
+		NotFound: atomic.LoadInt64(sl.notFound),
+		Limited:  atomic.LoadInt64(sl.limited),
+	}
+}
+
+// stateQueryRate returns how many state queries are answered each second.
+func (mg *Manager) stateQueryRate() (rate int) {
+	mg.ConfigurationMu.RLock()
+	rate = mg.Configuration.Messaging.StateQueryRate
+	mg.ConfigurationMu.RUnlock()
+
+	// Configurations updated over RPC are not normalized.
+	if rate < 1 {
+		rate = defaultStateQueryRate
+	}
+
+	return rate
+}
+
+// subscribeStateQueries answers state queries on the current producer if
+// messaging.state_queries is enabled, replacing the previous subscription.
+// ConfigurationMu must be held.
+func (mg *Manager) subscribeStateQueries() {
+	mg.stateQueriesMu.Lock()
+	defer mg.stateQueriesMu.Unlock()
+
+	if mg.stateQueriesCancel != nil {
+		mg.stateQueriesCancel()
+		mg.stateQueriesCancel = nil
+	}
+
+	producer := mg.Producer()
+	if !mg.Configuration.Messaging.StateQueries || producer == nil {
+		return
+	}
+
+	responder, ok := producer.(MQResponder)
+	if !ok {
+		mg.Logger.Warn().
+			Str("driver", producer.String()).
+			Msg("Producer cannot reply to requests so state queries are disabled")
+
+		return
+	}
+
+	channel := mg.Configuration.Messaging.ChannelName + stateQueriesSuffix
+	ctx, cancel := context.WithCancel(mg.ctx)
+
+	err := responder.Respond(ctx, channel, mg.handleStateQuery)
+	if err != nil {
+		cancel()
+		mg.Logger.Error().Err(err).Str("channel", channel).Msg("Failed to subscribe to state queries")
+
+		return
+	}
+
+	mg.stateQueriesCancel = cancel
+
+	mg.Logger.Info().Str("channel", channel).Msg("Listening for state queries")
+}
+
+// handleStateQuery answers a msgpack encoded state query with a msgpack
+// encoded structs.StateQueryReply.
+func (mg *Manager) handleStateQuery(data []byte) (reply []byte) {
+	result := structs.StateQueryReply{}
+
+	if !mg.stateQueries.Allow(mg.stateQueryRate()) {
+		result.Error = ErrStateQueryLimited.Error()
+	} else {
+		atomic.AddInt64(mg.stateQueries.handled, 1)
+
+		query := structs.StateQuery{}
+
+		if err := msgpack.Unmarshal(data, &query); err != nil {
+			result.Error = xerrors.Errorf("failed to decode state query: %w", err).Error()
+		} else {
+			result = mg.Sandwich.queryState(query)
+			if !result.Found && result.Error == "" {
+				atomic.AddInt64(mg.stateQueries.notFound, 1)
+			}
+		}
+	}
+
+	reply, err := msgpack.Marshal(result)
+	if err != nil {
+		mg.Logger.Error().Err(err).Msg("Failed to encode state query reply")
+
+		return nil
+	}
+
+	return reply
+}
+
+// queryState returns the cached object a state query asks for. The ops
+// mirror the /api/state endpoints.
+func (sg *Sandwich) queryState(query structs.StateQuery) (result structs.StateQueryReply) {
+	var value interface{}
+
+	switch query.Op {
+	case structs.StateQueryGuild:
+		guild, err := sg.stateGuild(query.ID)
+		if err != nil {
+			return result
+		}
+
+		value = guild
+	case structs.StateQueryMembers:
+		limit := query.Limit
+		if limit < 1 {
+			limit = defaultGuildSyncMembers
+		} else if limit > maxGuildSyncMembers {
+			limit = maxGuildSyncMembers
+		}
+
+		members, ok := sg.stateMembers(query.GuildID, limit, query.After)
+		if !ok {
+			return result
+		}
+
+		value = members
+	case structs.StateQueryMember:
+		member, ok := sg.stateMember(query.GuildID, query.ID)
+		if !ok {
+			return result
+		}
+
+		value = member
+	case structs.StateQueryRoles:
+		roles, err := sg.stateRoles(query.GuildID)
+		if err != nil {
+			return result
+		}
+
+		value = roles
+	case structs.StateQueryRole:
+		role, ok := sg.stateRole(query.GuildID, query.ID)
+		if !ok {
+			return result
+		}
+
+		value = role
+	case structs.StateQueryVoiceStates:
+		voiceStates, ok := sg.stateVoiceStates(query.GuildID)
+		if !ok {
+			return result
+		}
+
+		value = voiceStates
+	case structs.StateQueryVoiceState:
+		voiceState, ok := sg.State.GetVoiceState(query.GuildID, query.ID)
+		if !ok {
+			return result
+		}
+
+		value = voiceState
+	case structs.StateQueryChannel:
+		channel, ok := sg.stateChannel(query.ID)
+		if !ok {
+			return result
+		}
+
+		value = channel
+	case structs.StateQueryUser:
+		user, ok := sg.stateUser(query.ID)
+		if !ok {
+			return result
+		}
+
+		value = user
+	case structs.StateQueryPresence:
+		presence, ok := sg.statePresence(query.ID)
+		if !ok {
+			return result
+		}
+
+		value = presence
+	default:
+		result.Error = ErrStateQueryOp.Error()
+
+		return result
+	}
+
+	data, err := filterStateFields(value, query.Fields)
+	if err != nil {
+		result.Error = err.Error()
+
+		return result
+	}
+
+	result.Found = true
+	result.Data = data
+
+	return result
+}
+
+// filterStateFields encodes a cached object with msgpack. If fields are
+// given, only those top level fields are kept.
+func filterStateFields(value interface{}, fields []string) (data []byte, err error) {
+	data, err = msgpack.Marshal(value)
+	if err != nil || len(fields) == 0 {
+		return data, err
+	}
+
+	all := make(map[string]interface{})
+
+	err = msgpack.Unmarshal(data, &all)
+	if err != nil {
+		return nil, xerrors.Errorf("fields cannot be filtered on this object: %w", err)
+	}
+
+	filtered := make(map[string]interface{}, len(fields))
+
+	for _, field := range fields {
+		if fieldValue, ok := all[field]; ok {
+			filtered[field] = fieldValue
+		}
+	}
+
+	return msgpack.Marshal(filtered)
+}
+
+// stateGuild returns a guild with its roles, channels, threads, emojis and
+// voice states.
+func (sg *Sandwich) stateGuild(guildID snowflake.ID) (guild *discord.Guild, err error) {
+	document, err := sg.State.GuildSync(&StateCtx{Sg: sg}, guildID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	guild = document.Guild
+	guild.Roles = document.Roles
+	guild.Channels = document.Channels
+	guild.Threads = document.Threads
+	guild.Emojis = document.Emojis
+	guild.VoiceStates = document.VoiceStates
+
+	return guild, nil
+}
+
+// stateMembers returns up to limit members of a guild in order of their ID
+// after the given member. ok is false if the guild is not in state.
+func (sg *Sandwich) stateMembers(guildID snowflake.ID, limit int,
+	after snowflake.ID) (result structs.APIStateMembers, ok bool) {
+	if _, ok = sg.State.GetGuild(&StateCtx{Sg: sg}, guildID, false); !ok {
+		return result, false
+	}
+
+	result.Members, result.Next = sg.State.guildSyncMembers(guildID, limit, after)
+
+	return result, true
+}
+
+func (sg *Sandwich) stateMember(guildID snowflake.ID, memberID snowflake.ID) (member *discord.GuildMember, ok bool) {
+	members := sg.State.GetMembers(&StateCtx{Sg: sg}, &discord.Guild{ID: guildID}, []snowflake.ID{memberID})
+
+	member, ok = members[memberID]
+
+	return member, ok
+}
+
+// stateRoles returns the roles of a guild.
+func (sg *Sandwich) stateRoles(guildID snowflake.ID) (roles []*discord.Role, err error) {
+	document, err := sg.State.GuildSync(&StateCtx{Sg: sg}, guildID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return document.Roles, nil
+}
+
+// stateRole returns a role if it belongs to the guild.
+func (sg *Sandwich) stateRole(guildID snowflake.ID, roleID snowflake.ID) (role *discord.Role, ok bool) {
+	roles, err := sg.stateRoles(guildID)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, role = range roles {
+		if role.ID == roleID {
+			return role, true
+		}
+	}
+
+	return nil, false
+}
+
+// stateVoiceStates returns the voice states of a guild. ok is false if the
+// guild is not in state.
+func (sg *Sandwich) stateVoiceStates(guildID snowflake.ID) (voiceStates []*discord.VoiceState, ok bool) {
+	if _, ok = sg.State.GetGuild(&StateCtx{Sg: sg}, guildID, false); !ok {
+		return nil, false
+	}
+
+	return sg.State.GetVoiceStates(guildID), true
+}
+
+func (sg *Sandwich) stateChannel(channelID snowflake.ID) (channel *discord.Channel, ok bool) {
+	return sg.State.GetChannel(&StateCtx{Sg: sg}, channelID)
+}
+
+func (sg *Sandwich) stateUser(userID snowflake.ID) (user *discord.User, ok bool) {
+	return sg.State.GetUser(&StateCtx{Sg: sg}, userID)
+}
+
+func (sg *Sandwich) statePresence(userID snowflake.ID) (presence *discord.StatePresence, ok bool) {
+	return sg.State.GetPresence(userID)
+}
@@ -0,0 +1,305 @@
+package gateway
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/go-redis/redis/v8"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/vmihailenco/msgpack"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// StateBackendMemory keeps all state in memory. This is the default.
+	StateBackendMemory = "memory"
+	// StateBackendRedis stores state in redis so it can be shared between
+	// daemons. Memory is used as a write-through cache.
+	StateBackendRedis = "redis"
+
+	// Default number of state objects kept in memory when using redis.
+	defaultStateCacheSize = 100000
+)
+
+type stateKind uint8
+
+const (
+	stateKindGuild stateKind = iota
+	stateKindMember
+	stateKindChannel
+	stateKindRole
+	stateKindEmoji
+	stateKindUser
+)
+
+// stateKey identifies an object in the in-memory state for the LRU.
+type stateKey struct {
+	kind    stateKind
+	guildID snowflake.ID // Only used for members and roles
+	id      snowflake.ID
+}
+
+// stateRedis stores state objects as msgpack in redis hashes.
+type stateRedis struct {
+	client *redis.Client
+	prefix string
+}
+
+// UseRedis makes the state write through to redis and only keep the most
+// recently used size objects in memory.
+func (st *SandwichState) UseRedis(ctx context.Context, opts *redis.Options, prefix string, size int) (err error) {
+	client := redis.NewClient(opts)
+
+	err = client.Ping(ctx).Err()
+	if err != nil {
+		return xerrors.Errorf("state redis ping: %w", err)
+	}
+
+	if size < 1 {
+		size = defaultStateCacheSize
+	}
+
+	st.lru, err = lru.NewWithEvict(size, st.evict)
+	if err != nil {
+		return xerrors.Errorf("state redis lru: %w", err)
+	}
+
+	st.redis = &stateRedis{
+		client: client,
+		prefix: prefix,
+	}
+
+	return nil
+}
+
+// touch marks an object as recently used. This does nothing when the
+// state is only held in memory.
+func (st *SandwichState) touch(kind stateKind, guildID snowflake.ID, id snowflake.ID) {
+	if st.lru != nil {
+		st.lru.Add(stateKey{kind: kind, guildID: guildID, id: id}, nil)
+	}
+}
+
+// evict removes an object from memory once it has fallen out of the LRU.
+// It is still available in redis.
+func (st *SandwichState) evict(key interface{}, _ interface{}) {
+	k, ok := key.(stateKey)
+	if !ok {
+		return
+	}
+
+	switch k.kind {
+	case stateKindGuild:
+		st.GuildsMu.Lock()
+		delete(st.Guilds, k.id)
+		st.GuildsMu.Unlock()
+	case stateKindMember:
+		st.GuildMembersMu.RLock()
+		gm, ok := st.GuildMembers[k.guildID]
+		st.GuildMembersMu.RUnlock()
+
+		if ok {
+			gm.MembersMu.Lock()
+			delete(gm.Members, k.id)
+			gm.MembersMu.Unlock()
+		}
+	case stateKindChannel:
+		st.ChannelsMu.Lock()
+		delete(st.Channels, k.id)
+		st.ChannelsMu.Unlock()
+	case stateKindRole:
+		st.RolesMu.Lock()
+		if roles, ok := st.Roles[k.guildID]; ok {
+			delete(roles, k.id)
+
+			if len(roles) == 0 {
+				delete(st.Roles, k.guildID)
+			}
+		}
+		st.RolesMu.Unlock()
+	case stateKindEmoji:
+		st.EmojisMu.Lock()
+		delete(st.Emojis, k.id)
+		st.EmojisMu.Unlock()
+	case stateKindUser:
+		st.UsersMu.Lock()
+		delete(st.Users, k.id)
+		st.UsersMu.Unlock()
+	}
+}
+
+func (sr *stateRedis) key(name string) string {
+	return sr.prefix + ":" + name
+}
+
+func (sr *stateRedis) membersKey(guildID snowflake.ID) string {
+	return sr.prefix + ":guild:" + guildID.String() + ":members"
+}
+
+func field(id snowflake.ID) string {
+	return strconv.FormatInt(id.Int64(), 10)
+}
+
+// hset queues a msgpack encoded value on a pipeline.
+func (sr *stateRedis) hset(pipe redis.Pipeliner, key string, id snowflake.ID, value interface{}) (err error) {
+	data, err := msgpack.Marshal(value)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal %s: %w", key, err)
+	}
+
+	pipe.HSet(context.Background(), key, field(id), data)
+
+	return nil
+}
+
+func (sr *stateRedis) set(ctx *StateCtx, key string, id snowflake.ID, value interface{}) {
+	data, err := msgpack.Marshal(value)
+	if err == nil {
+		err = sr.client.HSet(context.Background(), key, field(id), data).Err()
+	}
+
+	if err != nil {
+		ctx.Sg.Logger.Warn().Err(err).Str("key", key).Msgf("Failed to store ID %d in redis", id)
+	}
+}
+
+// get decodes an object from redis into value and returns false if it
+// does not exist.
+func (sr *stateRedis) get(ctx *StateCtx, key string, id snowflake.ID, value interface{}) bool {
+	data, err := sr.client.HGet(context.Background(), key, field(id)).Bytes()
+	if err == nil {
+		err = msgpack.Unmarshal(data, value)
+	}
+
+	if err != nil {
+		if !xerrors.Is(err, redis.Nil) {
+			ctx.Sg.Logger.Warn().Err(err).Str("key", key).Msgf("Failed to fetch ID %d from redis", id)
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func (sr *stateRedis) del(ctx *StateCtx, key string, id snowflake.ID) {
+	err := sr.client.HDel(context.Background(), key, field(id)).Err()
+	if err != nil {
+		ctx.Sg.Logger.Warn().Err(err).Str("key", key).Msgf("Failed to remove ID %d from redis", id)
+	}
+}
+
+// setGuild writes a guild without its roles, channels and emojis.
+func (sr *stateRedis) setGuild(ctx *StateCtx, guildID snowflake.ID, sg *discord.StateGuild) {
+	sr.set(ctx, sr.key("guild"), guildID, storedGuild(sg))
+}
+
+// storedGuild returns a copy of a guild to store in redis. The roles,
+// emojis and channels of the embedded Guild share their msgpack names with
+// the ID lists of the StateGuild. Both would be encoded but only the ID
+// lists can be decoded, so the objects are left out. They are stored under
+// their own keys.
+func storedGuild(sg *discord.StateGuild) *discord.StateGuild {
+	stored := *sg
+
+	if sg.Guild != nil {
+		guild := *sg.Guild
+		guild.Roles = nil
+		guild.Emojis = nil
+		guild.Channels = nil
+
+		stored.Guild = &guild
+	}
+
+	return &stored
+}
+
+// storeGuild writes a guild along with its roles, channels and emojis in
+// a single pipeline.
+func (sr *stateRedis) storeGuild(sg *discord.StateGuild, roles []*discord.Role,
+	channels []*discord.Channel, emojis []*discord.Emoji) (err error) {
+	pipe := sr.client.Pipeline()
+
+	for _, r := range roles {
+		if err = sr.hset(pipe, sr.key("role"), r.ID, r); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range channels {
+		if err = sr.hset(pipe, sr.key("channel"), c.ID, c); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range emojis {
+		if err = sr.hset(pipe, sr.key("emoji"), e.ID, e); err != nil {
+			return err
+		}
+	}
+
+	if err = sr.hset(pipe, sr.key("guild"), sg.ID, storedGuild(sg)); err != nil {
+		return err
+	}
+
+	_, err = pipe.Exec(context.Background())
+
+	return err
+}
+
+// storeMembers writes members and their users in a single pipeline.
+func (sr *stateRedis) storeMembers(guildID snowflake.ID, members []*discord.GuildMember) (err error) {
+	pipe := sr.client.Pipeline()
+
+	for _, m := range members {
+		if err = sr.hset(pipe, sr.key("user"), m.User.ID, m.User); err != nil {
+			return err
+		}
+
+		if err = sr.hset(pipe, sr.membersKey(guildID), m.User.ID, discord.FromGuildMember(m)); err != nil {
+			return err
+		}
+	}
+
+	_, err = pipe.Exec(context.Background())
+
+	return err
+}
+
+// getMembers fetches multiple members of a guild in a single request.
+// Members that do not exist are omitted.
+func (sr *stateRedis) getMembers(ctx *StateCtx, guildID snowflake.ID,
+	ids []snowflake.ID) (members []*discord.StateGuildMember, err error) {
+	fields := make([]string, 0, len(ids))
+	for _, id := range ids {
+		fields = append(fields, field(id))
+	}
+
+	values, err := sr.client.HMGet(context.Background(), sr.membersKey(guildID), fields...).Result()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to fetch members: %w", err)
+	}
+
+	members = make([]*discord.StateGuildMember, 0, len(values))
+
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		sgm := &discord.StateGuildMember{}
+
+		if err := msgpack.Unmarshal([]byte(data), sgm); err != nil {
+			ctx.Sg.Logger.Warn().Err(err).Msgf("Failed to decode member of guild ID %d from redis", guildID)
+
+			continue
+		}
+
+		members = append(members, sgm)
+	}
+
+	return members, nil
+}
@@ -0,0 +1,430 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// Members in the guild used by the dispatch benchmarks.
+	benchmarkMembers = 1000
+
+	// Objects kept in memory when using redis. This is large enough that
+	// nothing used by the benchmarks is evicted.
+	benchmarkCacheSize = 100000
+)
+
+// redisStub is a redis server which only supports the hash commands used
+// by the state. It is used when SANDWICH_TEST_REDIS_ADDRESS is not set so
+// the redis backend can be benchmarked without a server.
+type redisStub struct {
+	listener net.Listener
+
+	hashesMu sync.Mutex
+	hashes   map[string]map[string]string
+}
+
+// newRedisStub starts a redisStub and returns its address.
+func newRedisStub(tb testing.TB) string {
+	tb.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("failed to listen: %v", err)
+	}
+
+	stub := &redisStub{
+		listener: listener,
+		hashes:   make(map[string]map[string]string),
+	}
+
+	wg := sync.WaitGroup{}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				stub.serve(conn)
+			}()
+		}
+	}()
+
+	tb.Cleanup(func() {
+		_ = listener.Close()
+		wg.Wait()
+	})
+
+	return listener.Addr().String()
+}
+
+// serve answers the commands sent on a connection until it is closed.
+// Replies are flushed once every pipelined command has been read.
+func (rs *redisStub) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		args, err := readRedisCommand(r)
+		if err != nil {
+			return
+		}
+
+		rs.reply(w, args)
+
+		if r.Buffered() == 0 {
+			if err = w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readRedisCommand reads a command sent as an array of bulk strings.
+func readRedisCommand(r *bufio.Reader) (args []string, err error) {
+	count, err := readRedisLength(r, '*')
+	if err != nil {
+		return nil, err
+	}
+
+	args = make([]string, count)
+
+	for i := range args {
+		length, err := readRedisLength(r, '$')
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, length+2)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		args[i] = string(data[:length])
+	}
+
+	return args, nil
+}
+
+func readRedisLength(r *bufio.Reader, prefix byte) (length int, err error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+
+	if len(line) < 3 || line[0] != prefix {
+		return 0, fmt.Errorf("expected %c but got %q", prefix, line)
+	}
+
+	return strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+}
+
+func (rs *redisStub) reply(w *bufio.Writer, args []string) {
+	rs.hashesMu.Lock()
+	defer rs.hashesMu.Unlock()
+
+	switch strings.ToLower(args[0]) {
+	case "ping":
+		fmt.Fprint(w, "+PONG\r\n")
+	case "hset":
+		hash, ok := rs.hashes[args[1]]
+		if !ok {
+			hash = make(map[string]string)
+			rs.hashes[args[1]] = hash
+		}
+
+		added := 0
+
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, ok := hash[args[i]]; !ok {
+				added++
+			}
+
+			hash[args[i]] = args[i+1]
+		}
+
+		fmt.Fprintf(w, ":%d\r\n", added)
+	case "hget":
+		writeRedisBulk(w, rs.hashes[args[1]], args[2])
+	case "hmget":
+		fmt.Fprintf(w, "*%d\r\n", len(args)-2)
+
+		for _, field := range args[2:] {
+			writeRedisBulk(w, rs.hashes[args[1]], field)
+		}
+	case "hdel":
+		removed := 0
+
+		for _, field := range args[2:] {
+			if _, ok := rs.hashes[args[1]][field]; ok {
+				delete(rs.hashes[args[1]], field)
+				removed++
+			}
+		}
+
+		fmt.Fprintf(w, ":%d\r\n", removed)
+	case "del":
+		removed := 0
+
+		for _, key := range args[1:] {
+			if _, ok := rs.hashes[key]; ok {
+				delete(rs.hashes, key)
+				removed++
+			}
+		}
+
+		fmt.Fprintf(w, ":%d\r\n", removed)
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// writeRedisBulk writes a field of a hash or nil if it does not exist.
+func writeRedisBulk(w *bufio.Writer, hash map[string]string, field string) {
+	value, ok := hash[field]
+	if !ok {
+		fmt.Fprint(w, "$-1\r\n")
+
+		return
+	}
+
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(value), value)
+}
+
+// newBackendStateCtx creates a StateCtx using the state backend given. The
+// redis backend uses SANDWICH_TEST_REDIS_ADDRESS if it is set and a
+// redisStub otherwise.
+func newBackendStateCtx(tb testing.TB, backend string, cacheSize int) *StateCtx {
+	tb.Helper()
+
+	ctx := newTestStateCtx(tb)
+
+	if backend != StateBackendRedis {
+		return ctx
+	}
+
+	opts := &redis.Options{
+		Addr:     os.Getenv("SANDWICH_TEST_REDIS_ADDRESS"),
+		Password: os.Getenv("SANDWICH_TEST_REDIS_PASSWORD"),
+	}
+
+	if opts.Addr == "" {
+		opts.Addr = newRedisStub(tb)
+	}
+
+	// Each run has its own keys so runs against a real server do not share
+	// state.
+	prefix := fmt.Sprintf("sandwich-benchmark-%d", time.Now().UnixNano())
+
+	if err := ctx.Sg.State.UseRedis(context.Background(), opts, prefix, cacheSize); err != nil {
+		tb.Fatalf("failed to use redis: %v", err)
+	}
+
+	tb.Cleanup(func() { _ = ctx.Sg.State.redis.client.Close() })
+
+	return ctx
+}
+
+// benchmarkStateGuild returns a guild with benchmarkMembers members and a
+// similar number of roles, channels and emojis to a mid sized server.
+func benchmarkStateGuild() *discord.Guild {
+	guild := &discord.Guild{ID: testGuildID, Name: "guild"}
+
+	for i := 0; i < 50; i++ {
+		id := snowflake.ID(1000 + i)
+
+		guild.Roles = append(guild.Roles, &discord.Role{ID: id, Name: "role"})
+		guild.Channels = append(guild.Channels, &discord.Channel{ID: id + 1000, GuildID: testGuildID, Name: "channel"})
+		guild.Emojis = append(guild.Emojis, &discord.Emoji{ID: id + 2000, Name: "emoji"})
+	}
+
+	for i := 0; i < benchmarkMembers; i++ {
+		guild.Members = append(guild.Members, benchmarkStateMember(i))
+	}
+
+	return guild
+}
+
+func benchmarkStateMember(i int) *discord.GuildMember {
+	return &discord.GuildMember{
+		User:     &discord.User{ID: snowflake.ID(10000 + i), Username: "user"},
+		Roles:    []snowflake.ID{1000, 1001},
+		JoinedAt: "2021-01-01T00:00:00.000000+00:00",
+	}
+}
+
+// benchmarkDispatch dispatches payloads in turn through the state handler of
+// an event type. setup is run before the timer is started.
+func benchmarkDispatch(b *testing.B, ctx *StateCtx, eventType string, payloads []interface{},
+	setup func(ctx *StateCtx)) {
+	b.Helper()
+
+	if setup != nil {
+		setup(ctx)
+	}
+
+	messages := make([]discord.ReceivedPayload, len(payloads))
+	size := 0
+
+	for i, payload := range payloads {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			b.Fatalf("failed to marshal %s: %v", eventType, err)
+		}
+
+		messages[i] = discord.ReceivedPayload{Op: discord.GatewayOpDispatch, Type: eventType, Data: body}
+		size += len(body)
+	}
+
+	b.SetBytes(int64(size / len(messages)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, ok, err := ctx.Sg.StateDispatch(ctx, messages[i%len(messages)]); err != nil || !ok {
+			b.Fatalf("failed to dispatch %s: ok=%v err=%v", eventType, ok, err)
+		}
+	}
+}
+
+// addBenchmarkGuild adds the guild from benchmarkStateGuild to the state
+// along with its members, as if they had been chunked.
+func addBenchmarkGuild(ctx *StateCtx) {
+	guild := benchmarkStateGuild()
+
+	ctx.Sg.State.AddGuildShardGroup(ctx, guild)
+	ctx.Sg.State.AddMembers(ctx, guild, guild.Members)
+}
+
+func TestStateRedisFallback(t *testing.T) {
+	// Only one object is kept in memory so everything else has to be
+	// fetched from redis.
+	ctx := newBackendStateCtx(t, StateBackendRedis, 1)
+	addBenchmarkGuild(ctx)
+
+	guild, ok := ctx.Sg.State.GetGuild(ctx, testGuildID, false)
+	if !ok || guild.Name != "guild" {
+		t.Fatalf("guild is %+v", guild)
+	}
+
+	// The guild fetched from redis is cached with the IDs of its roles,
+	// channels and emojis.
+	ctx.Sg.State.GuildsMu.RLock()
+	stored := ctx.Sg.State.Guilds[testGuildID]
+	ctx.Sg.State.GuildsMu.RUnlock()
+
+	if stored == nil || len(stored.RoleIDs) != 50 || len(stored.ChannelIDs) != 50 || len(stored.EmojiIDs) != 50 {
+		t.Errorf("stored guild is %+v", stored)
+	}
+
+	if channel, ok := ctx.Sg.State.GetChannel(ctx, 2000); !ok || channel.Name != "channel" {
+		t.Errorf("channel is %+v", channel)
+	}
+
+	if role, ok := ctx.Sg.State.GetRole(ctx, testGuildID, 1000); !ok || role.Name != "role" {
+		t.Errorf("role is %+v", role)
+	}
+
+	member, ok := ctx.Sg.State.GetMember(ctx, guild, 10005)
+	if !ok || member.User == nil || member.User.Username != "user" || len(member.Roles) != 2 {
+		t.Fatalf("member is %+v", member)
+	}
+
+	ids := []snowflake.ID{10001, 10002, 10003, 20000}
+	if members := ctx.Sg.State.GetMembers(ctx, guild, ids); len(members) != 3 || members[20000] != nil {
+		t.Errorf("fetched %d members", len(members))
+	}
+
+	dispatchState(t, ctx, "GUILD_MEMBER_UPDATE", discord.GuildMemberUpdate{
+		GuildID: testGuildID,
+		User:    member.User,
+		Roles:   member.Roles,
+		Nick:    "nick",
+	})
+
+	// Fetching another member evicts the updated one from memory.
+	ctx.Sg.State.GetMember(ctx, guild, 10006)
+
+	if member, ok = ctx.Sg.State.GetMember(ctx, guild, 10005); !ok || member.Nick != "nick" {
+		t.Errorf("updated member is %+v", member)
+	}
+}
+
+func BenchmarkDispatchGuildCreate(b *testing.B) {
+	for _, backend := range []string{StateBackendMemory, StateBackendRedis} {
+		b.Run(backend, func(b *testing.B) {
+			ctx := newBackendStateCtx(b, backend, benchmarkCacheSize)
+
+			benchmarkDispatch(b, ctx, "GUILD_CREATE", []interface{}{benchmarkStateGuild()}, nil)
+		})
+	}
+}
+
+func BenchmarkDispatchGuildMemberAdd(b *testing.B) {
+	payloads := make([]interface{}, benchmarkMembers)
+	for i := range payloads {
+		payloads[i] = discord.GuildMemberAdd{GuildMember: benchmarkStateMember(i), GuildID: testGuildID}
+	}
+
+	for _, backend := range []string{StateBackendMemory, StateBackendRedis} {
+		b.Run(backend, func(b *testing.B) {
+			ctx := newBackendStateCtx(b, backend, benchmarkCacheSize)
+
+			benchmarkDispatch(b, ctx, "GUILD_MEMBER_ADD", payloads, nil)
+		})
+	}
+}
+
+func BenchmarkDispatchGuildMemberUpdate(b *testing.B) {
+	payloads := make([]interface{}, benchmarkMembers)
+	for i := range payloads {
+		member := benchmarkStateMember(i)
+
+		payloads[i] = discord.GuildMemberUpdate{
+			GuildID: testGuildID,
+			Roles:   member.Roles,
+			User:    member.User,
+			Nick:    "nick",
+		}
+	}
+
+	tests := []struct {
+		name      string
+		backend   string
+		cacheSize int
+	}{
+		{StateBackendMemory, StateBackendMemory, 0},
+		{StateBackendRedis, StateBackendRedis, benchmarkCacheSize},
+		// Only one object is kept in memory so members are fetched from redis.
+		{"redis_uncached", StateBackendRedis, 1},
+	}
+
+	for _, test := range tests {
+		b.Run(test.name, func(b *testing.B) {
+			ctx := newBackendStateCtx(b, test.backend, test.cacheSize)
+
+			benchmarkDispatch(b, ctx, "GUILD_MEMBER_UPDATE", payloads, addBenchmarkGuild)
+		})
+	}
+}
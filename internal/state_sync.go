@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// Default and maximum members returned by a single guild sync.
+	defaultGuildSyncMembers = 100
+	maxGuildSyncMembers     = 1000
+)
+
+// ErrGuildNotInState is returned when syncing a guild that is not cached.
+var ErrGuildNotInState = xerrors.New("guild is not in state")
+
+// GuildSync assembles a guild and everything that belongs to it into a single
+// document. The guild, roles, channels and emojis are read whilst holding all
+// of their locks so the document reflects a single point in time.
+//
+// Only members are paginated as they are the only part of a guild which is
+// not bounded. Up to memberLimit members with an ID greater than after are
+// included. When using the redis backend, only members held in memory are
+// returned.
+func (st *SandwichState) GuildSync(ctx *StateCtx, guildID snowflake.ID, memberLimit int,
+	after snowflake.ID) (document *structs.APIGuildSync, err error) {
+	// Loads the guild into memory if it is only stored in redis.
+	if _, ok := st.GetGuild(ctx, guildID, false); !ok {
+		return nil, ErrGuildNotInState
+	}
+
+	document = &structs.APIGuildSync{
+		Channels:    make([]*discord.Channel, 0),
+		Threads:     make([]*discord.Channel, 0),
+		Roles:       make([]*discord.Role, 0),
+		Emojis:      make([]*discord.Emoji, 0),
+		VoiceStates: make([]*discord.VoiceState, 0),
+	}
+
+	// Locks are always taken in the same order as RemoveGuild.
+	st.GuildsMu.RLock()
+	st.RolesMu.RLock()
+	st.ChannelsMu.RLock()
+	st.EmojisMu.RLock()
+	st.ThreadsMu.RLock()
+
+	sg, ok := st.Guilds[guildID]
+	if ok {
+		guild := *sg.Guild
+		guild.Roles, guild.Emojis, guild.Channels, guild.Threads = nil, nil, nil, nil
+		guild.Members, guild.Presences, guild.VoiceStates = nil, nil, nil
+		document.Guild = &guild
+
+		roles := st.Roles[guildID]
+
+		for _, id := range sg.RoleIDs {
+			if r, ok := roles[id]; ok {
+				document.Roles = append(document.Roles, r)
+			}
+		}
+
+		for _, id := range sg.ChannelIDs {
+			if c, ok := st.Channels[id]; ok {
+				if c.Type.IsThread() {
+					document.Threads = append(document.Threads, c)
+				} else {
+					document.Channels = append(document.Channels, c)
+				}
+			}
+		}
+
+		for _, id := range sg.EmojiIDs {
+			if e, ok := st.Emojis[id]; ok {
+				document.Emojis = append(document.Emojis, e)
+			}
+		}
+
+		for _, thread := range st.Threads[guildID] {
+			document.Threads = append(document.Threads, thread)
+		}
+	}
+
+	st.ThreadsMu.RUnlock()
+	st.EmojisMu.RUnlock()
+	st.ChannelsMu.RUnlock()
+	st.RolesMu.RUnlock()
+	st.GuildsMu.RUnlock()
+
+	if !ok {
+		return nil, ErrGuildNotInState
+	}
+
+	sort.Slice(document.Threads, func(i, j int) bool { return document.Threads[i].ID < document.Threads[j].ID })
+
+	document.VoiceStates = st.GetVoiceStates(guildID)
+
+	if memberLimit > 0 {
+		document.Members, document.NextMembers = st.guildSyncMembers(guildID, memberLimit, after)
+	}
+
+	document.GeneratedAt = time.Now().UTC()
+
+	return document, nil
+}
+
+// guildSyncMembers returns up to limit members in order of their ID and the
+// ID to continue from if there are more.
+func (st *SandwichState) guildSyncMembers(guildID snowflake.ID, limit int,
+	after snowflake.ID) (members []*discord.GuildMember, next snowflake.ID) {
+	members = make([]*discord.GuildMember, 0)
+
+	st.GuildMembersMu.RLock()
+	gm, ok := st.GuildMembers[guildID]
+	st.GuildMembersMu.RUnlock()
+
+	if !ok {
+		return members, 0
+	}
+
+	gm.MembersMu.RLock()
+	defer gm.MembersMu.RUnlock()
+
+	ids := make([]snowflake.ID, 0, len(gm.Members))
+
+	for id := range gm.Members {
+		if id > after {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+		next = ids[limit-1]
+	}
+
+	st.UsersMu.RLock()
+	defer st.UsersMu.RUnlock()
+
+	for _, id := range ids {
+		sgm := gm.Members[id]
+
+		user, ok := st.Users[sgm.User]
+		if !ok {
+			user = &discord.User{ID: sgm.User}
+		}
+
+		members = append(members, sgm.ToGuildMember(user))
+	}
+
+	return members, next
+}
+
+// GuildOwner returns the manager, shardgroup and shard that a guild belongs
+// to. If multiple shardgroups contain the guild, the newest is used.
+func (sg *Sandwich) GuildOwner(guildID snowflake.ID) (manager string, shardGroup int32, shardID int, ok bool) {
+	sg.ManagersMu.RLock()
+	defer sg.ManagersMu.RUnlock()
+
+	for identifier, mg := range sg.Managers {
+		mg.ShardGroupsMu.RLock()
+
+		for _, group := range mg.ShardGroups {
+			group.GuildsMu.RLock()
+			_, contains := group.Guilds[guildID]
+			group.GuildsMu.RUnlock()
+
+			if !contains || (ok && group.ID < shardGroup) || group.ShardCount < 1 {
+				continue
+			}
+
+			manager = identifier
+			shardGroup = group.ID
+			shardID = int((guildID.Int64() >> 22) % int64(group.ShardCount))
+			ok = true
+		}
+
+		mg.ShardGroupsMu.RUnlock()
+	}
+
+	return manager, shardGroup, shardID, ok
+}
@@ -0,0 +1,244 @@
+package gateway
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/rs/zerolog"
+)
+
+const (
+	testGuildID   snowflake.ID = 100
+	testChannelID snowflake.ID = 101
+	testRoleID    snowflake.ID = 102
+	testThreadID  snowflake.ID = 103
+	testUserID    snowflake.ID = 104
+)
+
+// newTestStateCtx creates a StateCtx with empty state. The events given are
+// included in events.include_before.
+func newTestStateCtx(t testing.TB, includeBefore ...string) *StateCtx {
+	t.Helper()
+
+	sg, err := newSandwich(ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to create sandwich: %v", err)
+	}
+
+	matcher, _ := NewEventMatcher(includeBefore)
+
+	mg := &Manager{
+		Sandwich:      sg,
+		Configuration: &ManagerConfiguration{},
+		IncludeBefore: matcher,
+	}
+
+	shardGroup := &ShardGroup{
+		Manager: mg,
+		Guilds:  make(map[snowflake.ID]*discord.StateGuild),
+	}
+
+	sh := &Shard{
+		Logger:     newShardLogger(sg, zerolog.Nop()),
+		Manager:    mg,
+		ShardGroup: shardGroup,
+	}
+
+	return &StateCtx{
+		Sg: sg,
+		Mg: mg,
+		Sh: sh,
+	}
+}
+
+// dispatchState runs the state handler of an event with data as the payload.
+func dispatchState(t *testing.T, ctx *StateCtx, eventType string, data interface{}) structs.StateResult {
+	t.Helper()
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("failed to marshal %s: %v", eventType, err)
+	}
+
+	result, ok, err := ctx.Sg.StateDispatch(ctx, discord.ReceivedPayload{
+		Op:   discord.GatewayOpDispatch,
+		Type: eventType,
+		Data: body,
+	})
+	if err != nil || !ok {
+		t.Fatalf("failed to dispatch %s: ok=%v err=%v", eventType, ok, err)
+	}
+
+	return result
+}
+
+func TestStateGuildUpdateBefore(t *testing.T) {
+	ctx := newTestStateCtx(t, "GUILD_UPDATE")
+
+	ctx.Sg.State.AddGuild(ctx, &discord.Guild{
+		ID:    testGuildID,
+		Name:  "before",
+		Roles: []*discord.Role{{ID: testRoleID, Name: "role"}},
+	})
+
+	result := dispatchState(t, ctx, "GUILD_UPDATE", discord.Guild{
+		ID:    testGuildID,
+		Name:  "after",
+		Roles: []*discord.Role{{ID: testRoleID, Name: "role"}},
+	})
+
+	before, ok := result.Extra["before"].(*discord.Guild)
+	if !ok {
+		t.Fatalf("before was %T", result.Extra["before"])
+	}
+
+	dispatchState(t, ctx, "GUILD_UPDATE", discord.Guild{ID: testGuildID, Name: "later"})
+
+	if before.Name != "before" {
+		t.Errorf("before name changed to %q", before.Name)
+	}
+
+	if guild, _ := ctx.Sg.State.GetGuild(ctx, testGuildID, false); guild == nil || guild.Name != "later" {
+		t.Errorf("guild was not updated: %+v", guild)
+	}
+}
+
+func TestStateGuildMemberUpdateBefore(t *testing.T) {
+	ctx := newTestStateCtx(t, "GUILD_MEMBER_UPDATE")
+
+	user := &discord.User{ID: testUserID, Username: "user"}
+
+	ctx.Sg.State.AddMember(ctx, &discord.Guild{ID: testGuildID}, &discord.GuildMember{
+		User:     user,
+		Nick:     "before",
+		Roles:    []snowflake.ID{testRoleID},
+		JoinedAt: "2021-01-01T00:00:00Z",
+	})
+
+	result := dispatchState(t, ctx, "GUILD_MEMBER_UPDATE", discord.GuildMemberUpdate{
+		GuildID: testGuildID,
+		User:    user,
+		Nick:    "after",
+	})
+
+	before, ok := result.Extra["before"].(*discord.GuildMember)
+	if !ok {
+		t.Fatalf("before was %T", result.Extra["before"])
+	}
+
+	dispatchState(t, ctx, "GUILD_MEMBER_UPDATE", discord.GuildMemberUpdate{
+		GuildID: testGuildID,
+		User:    user,
+		Nick:    "later",
+		Roles:   []snowflake.ID{testRoleID + 1},
+	})
+
+	if before.Nick != "before" {
+		t.Errorf("before nick changed to %q", before.Nick)
+	}
+
+	if len(before.Roles) != 1 || before.Roles[0] != testRoleID {
+		t.Errorf("before roles changed to %v", before.Roles)
+	}
+
+	members := ctx.Sg.State.GetMembers(ctx, &discord.Guild{ID: testGuildID}, []snowflake.ID{testUserID})
+	if member := members[testUserID]; member == nil || member.Nick != "later" || member.JoinedAt != "2021-01-01T00:00:00Z" {
+		t.Errorf("member was not updated: %+v", member)
+	}
+}
+
+func TestStateChannelUpdateBefore(t *testing.T) {
+	ctx := newTestStateCtx(t, "CHANNEL_UPDATE")
+
+	ctx.Sg.State.AddChannel(ctx, &discord.Channel{ID: testChannelID, GuildID: testGuildID, Name: "before"})
+
+	result := dispatchState(t, ctx, "CHANNEL_UPDATE",
+		discord.Channel{ID: testChannelID, GuildID: testGuildID, Name: "after"})
+
+	before, ok := result.Extra["before"].(*discord.Channel)
+	if !ok {
+		t.Fatalf("before was %T", result.Extra["before"])
+	}
+
+	dispatchState(t, ctx, "CHANNEL_UPDATE",
+		discord.Channel{ID: testChannelID, GuildID: testGuildID, Name: "later"})
+
+	if before.Name != "before" {
+		t.Errorf("before name changed to %q", before.Name)
+	}
+}
+
+func TestStateGuildRoleUpdateBefore(t *testing.T) {
+	ctx := newTestStateCtx(t, "GUILD_ROLE_UPDATE")
+
+	ctx.Sg.State.AddRole(ctx, testGuildID, &discord.Role{ID: testRoleID, Name: "before"})
+
+	result := dispatchState(t, ctx, "GUILD_ROLE_UPDATE", discord.GuildRoleUpdate{
+		GuildID: testGuildID,
+		Role:    &discord.Role{ID: testRoleID, Name: "after"},
+	})
+
+	before, ok := result.Extra["before"].(*discord.Role)
+	if !ok {
+		t.Fatalf("before was %T", result.Extra["before"])
+	}
+
+	dispatchState(t, ctx, "GUILD_ROLE_UPDATE", discord.GuildRoleUpdate{
+		GuildID: testGuildID,
+		Role:    &discord.Role{ID: testRoleID, Name: "later"},
+	})
+
+	if before.Name != "before" {
+		t.Errorf("before name changed to %q", before.Name)
+	}
+}
+
+func TestStateThreadUpdateBefore(t *testing.T) {
+	ctx := newTestStateCtx(t, "THREAD_UPDATE")
+
+	ctx.Sg.State.AddThread(testGuildID, &discord.Channel{
+		ID:       testThreadID,
+		GuildID:  testGuildID,
+		ParentID: testChannelID,
+		Name:     "before",
+	})
+
+	result := dispatchState(t, ctx, "THREAD_UPDATE", discord.Channel{
+		ID:       testThreadID,
+		GuildID:  testGuildID,
+		ParentID: testChannelID,
+		Name:     "after",
+	})
+
+	before, ok := result.Extra["before"].(*discord.Channel)
+	if !ok {
+		t.Fatalf("before was %T", result.Extra["before"])
+	}
+
+	dispatchState(t, ctx, "THREAD_UPDATE", discord.Channel{
+		ID:       testThreadID,
+		GuildID:  testGuildID,
+		ParentID: testChannelID,
+		Name:     "later",
+	})
+
+	if before.Name != "before" {
+		t.Errorf("before name changed to %q", before.Name)
+	}
+}
+
+func TestStateUpdateWithoutIncludeBefore(t *testing.T) {
+	ctx := newTestStateCtx(t)
+
+	ctx.Sg.State.AddChannel(ctx, &discord.Channel{ID: testChannelID, GuildID: testGuildID, Name: "before"})
+
+	result := dispatchState(t, ctx, "CHANNEL_UPDATE",
+		discord.Channel{ID: testChannelID, GuildID: testGuildID, Name: "after"})
+
+	if _, ok := result.Extra["before"]; ok {
+		t.Error("before was included without events.include_before")
+	}
+}
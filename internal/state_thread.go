@@ -0,0 +1,321 @@
+package gateway
+
+import (
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+// Thread State
+//
+// Threads are stored as channels of their guild. Cached threads are never
+// modified, updates replace them so readers can hold onto a thread.
+
+// AddThread stores an active thread. Archived threads are removed instead
+// as discord only keeps bots up to date with active threads.
+func (st *SandwichState) AddThread(guildID snowflake.ID, thread *discord.Channel) {
+	if thread.ThreadMetadata != nil && thread.ThreadMetadata.Archived {
+		st.RemoveThread(guildID, thread.ID)
+
+		return
+	}
+
+	st.ThreadsMu.Lock()
+	threads, ok := st.Threads[guildID]
+	if !ok {
+		threads = make(map[snowflake.ID]*discord.Channel)
+		st.Threads[guildID] = threads
+	}
+	threads[thread.ID] = withGuildID(thread, guildID)
+	st.ThreadsMu.Unlock()
+}
+
+func (st *SandwichState) GetThread(guildID snowflake.ID, s snowflake.ID) (thread *discord.Channel, o bool) {
+	st.ThreadsMu.RLock()
+	thread, o = st.Threads[guildID][s]
+	st.ThreadsMu.RUnlock()
+
+	return
+}
+
+func (st *SandwichState) RemoveThread(guildID snowflake.ID, s snowflake.ID) {
+	st.ThreadsMu.Lock()
+	if threads, ok := st.Threads[guildID]; ok {
+		delete(threads, s)
+
+		if len(threads) == 0 {
+			delete(st.Threads, guildID)
+		}
+	}
+	st.ThreadsMu.Unlock()
+}
+
+// SyncThreads replaces the threads of the given parent channels of a guild.
+// If no parent channels are given, every thread of the guild is replaced.
+func (st *SandwichState) SyncThreads(guildID snowflake.ID, parentIDs []snowflake.ID, threads []*discord.Channel) {
+	parents := make(map[snowflake.ID]bool, len(parentIDs))
+	for _, id := range parentIDs {
+		parents[id] = true
+	}
+
+	st.ThreadsMu.Lock()
+	defer st.ThreadsMu.Unlock()
+
+	synced := make(map[snowflake.ID]*discord.Channel, len(threads))
+
+	if len(parents) > 0 {
+		for id, thread := range st.Threads[guildID] {
+			if !parents[thread.ParentID] {
+				synced[id] = thread
+			}
+		}
+	}
+
+	for _, thread := range threads {
+		if thread.ThreadMetadata == nil || !thread.ThreadMetadata.Archived {
+			synced[thread.ID] = withGuildID(thread, guildID)
+		}
+	}
+
+	if len(synced) == 0 {
+		delete(st.Threads, guildID)
+	} else {
+		st.Threads[guildID] = synced
+	}
+}
+
+// updateThread replaces a thread with a copy changed by update. Nothing is
+// done if the thread is not in state.
+func (st *SandwichState) updateThread(guildID snowflake.ID, s snowflake.ID,
+	update func(thread *discord.Channel)) (thread *discord.Channel, o bool) {
+	st.ThreadsMu.Lock()
+	defer st.ThreadsMu.Unlock()
+
+	thread, o = st.Threads[guildID][s]
+	if !o {
+		return nil, false
+	}
+
+	updated := *thread
+	update(&updated)
+	st.Threads[guildID][s] = &updated
+
+	return &updated, true
+}
+
+// withGuildID returns the thread with its guild set. Threads in GUILD_CREATE
+// and THREAD_LIST_SYNC do not include it.
+func withGuildID(thread *discord.Channel, guildID snowflake.ID) *discord.Channel {
+	if thread.GuildID == guildID {
+		return thread
+	}
+
+	withGuild := *thread
+	withGuild.GuildID = guildID
+
+	return &withGuild
+}
+
+// StateThreadCreate handles the THREAD_CREATE event. The parent channel is
+// included as parent if it is in state.
+func StateThreadCreate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.ThreadCreate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	thread := discord.Channel(packet)
+	ctx.Sg.State.AddThread(thread.GuildID, &thread)
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	if parent, o := ctx.Sg.State.GetChannel(ctx, thread.ParentID); o {
+		result.Extra["parent"] = parent
+	}
+
+	return result, true, nil
+}
+
+// StateThreadUpdate handles the THREAD_UPDATE event. The thread before the
+// update is included as before if it was in state and the event is in
+// events.include_before.
+func StateThreadUpdate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.ThreadUpdate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	thread := discord.Channel(packet)
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	if before, o := ctx.Sg.State.GetThread(thread.GuildID, thread.ID); o {
+		if ctx.includeBefore(msg.Type) {
+			result.Extra["before"] = before
+		}
+
+		// The thread member of the current user is not sent with updates.
+		if thread.Member == nil {
+			thread.Member = before.Member
+		}
+	}
+
+	if parent, o := ctx.Sg.State.GetChannel(ctx, thread.ParentID); o {
+		result.Extra["parent"] = parent
+	}
+
+	ctx.Sg.State.AddThread(thread.GuildID, &thread)
+
+	return result, true, nil
+}
+
+// StateThreadDelete handles the THREAD_DELETE event. The deleted thread is
+// included as thread if it was in state.
+func StateThreadDelete(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.ThreadDelete
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	if thread, o := ctx.Sg.State.GetThread(packet.GuildID, packet.ID); o {
+		result.Extra["thread"] = thread
+	}
+
+	if parent, o := ctx.Sg.State.GetChannel(ctx, packet.ParentID); o {
+		result.Extra["parent"] = parent
+	}
+
+	ctx.Sg.State.RemoveThread(packet.GuildID, packet.ID)
+
+	return result, true, nil
+}
+
+// StateThreadListSync handles the THREAD_LIST_SYNC event which is sent
+// when the current user gains access to channels. The threads of the synced
+// channels are replaced.
+func StateThreadListSync(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.ThreadListSync
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	members := make(map[snowflake.ID]*discord.ThreadMember, len(packet.Members))
+	for _, member := range packet.Members {
+		members[member.ID] = member
+	}
+
+	threads := make([]*discord.Channel, 0, len(packet.Threads))
+
+	for _, thread := range packet.Threads {
+		if member, o := members[thread.ID]; o && thread.Member == nil {
+			withMember := *thread
+			withMember.Member = member
+			thread = &withMember
+		}
+
+		threads = append(threads, thread)
+	}
+
+	ctx.Sg.State.SyncThreads(packet.GuildID, packet.ChannelIDs, threads)
+
+	return structs.StateResult{
+		Data: packet,
+	}, true, nil
+}
+
+// StateThreadMemberUpdate handles the THREAD_MEMBER_UPDATE event which is
+// sent when the thread member of the current user changes.
+func StateThreadMemberUpdate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.ThreadMemberUpdate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	member := packet.ThreadMember
+
+	thread, o := ctx.Sg.State.updateThread(packet.GuildID, packet.ID, func(thread *discord.Channel) {
+		thread.Member = &member
+	})
+	if o {
+		result.Extra["thread"] = thread
+	}
+
+	return result, true, nil
+}
+
+// StateThreadMembersUpdate handles the THREAD_MEMBERS_UPDATE event. The
+// member count of the thread is updated and the removed members are
+// included as removed_members if they are in state.
+func StateThreadMembersUpdate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.ThreadMembersUpdate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	var currentUser snowflake.ID
+	if ctx.Sh.User != nil {
+		currentUser = ctx.Sh.User.ID
+	}
+
+	thread, o := ctx.Sg.State.updateThread(packet.GuildID, packet.ID, func(thread *discord.Channel) {
+		thread.MemberCount = packet.MemberCount
+
+		for _, member := range packet.AddedMembers {
+			if member.UserID == currentUser {
+				thread.Member = member
+			}
+		}
+
+		for _, id := range packet.RemovedMemberIDs {
+			if id == currentUser {
+				thread.Member = nil
+			}
+		}
+	})
+	if o {
+		result.Extra["thread"] = thread
+	}
+
+	if len(packet.RemovedMemberIDs) > 0 {
+		removed := ctx.Sg.State.GetMembers(ctx, &discord.Guild{ID: packet.GuildID}, packet.RemovedMemberIDs)
+		if len(removed) > 0 {
+			result.Extra["removed_members"] = removed
+		}
+	}
+
+	return result, true, nil
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"sort"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+// Voice State
+//
+// Voice states are only held in memory. Users without a voice state are not
+// in a voice channel of the guild.
+
+// SetVoiceState stores the voice state of a user. Voice states without a
+// channel remove the user instead.
+func (st *SandwichState) SetVoiceState(guildID snowflake.ID, vs *discord.VoiceState) {
+	if vs.ChannelID == 0 {
+		st.RemoveVoiceState(guildID, vs.UserID)
+
+		return
+	}
+
+	st.VoiceStatesMu.Lock()
+	voiceStates, ok := st.VoiceStates[guildID]
+	if !ok {
+		voiceStates = make(map[snowflake.ID]*discord.VoiceState)
+		st.VoiceStates[guildID] = voiceStates
+	}
+	voiceStates[vs.UserID] = vs
+	st.VoiceStatesMu.Unlock()
+}
+
+func (st *SandwichState) GetVoiceState(guildID snowflake.ID, userID snowflake.ID) (vs *discord.VoiceState, o bool) {
+	st.VoiceStatesMu.RLock()
+	vs, o = st.VoiceStates[guildID][userID]
+	st.VoiceStatesMu.RUnlock()
+
+	return
+}
+
+// GetVoiceStates returns the voice states of a guild in order of user ID.
+func (st *SandwichState) GetVoiceStates(guildID snowflake.ID) (voiceStates []*discord.VoiceState) {
+	st.VoiceStatesMu.RLock()
+	voiceStates = make([]*discord.VoiceState, 0, len(st.VoiceStates[guildID]))
+	for _, vs := range st.VoiceStates[guildID] {
+		voiceStates = append(voiceStates, vs)
+	}
+	st.VoiceStatesMu.RUnlock()
+
+	sort.Slice(voiceStates, func(i, j int) bool { return voiceStates[i].UserID < voiceStates[j].UserID })
+
+	return voiceStates
+}
+
+func (st *SandwichState) RemoveVoiceState(guildID snowflake.ID, userID snowflake.ID) {
+	st.VoiceStatesMu.Lock()
+	if voiceStates, ok := st.VoiceStates[guildID]; ok {
+		delete(voiceStates, userID)
+
+		if len(voiceStates) == 0 {
+			delete(st.VoiceStates, guildID)
+		}
+	}
+	st.VoiceStatesMu.Unlock()
+}
+
+// SyncVoiceStates replaces the voice states of a guild. Voice states in
+// GUILD_CREATE do not include the guild so it is set on a copy.
+func (st *SandwichState) SyncVoiceStates(guildID snowflake.ID, voiceStates []*discord.VoiceState) {
+	synced := make(map[snowflake.ID]*discord.VoiceState, len(voiceStates))
+
+	for _, vs := range voiceStates {
+		if vs.ChannelID == 0 {
+			continue
+		}
+
+		if vs.GuildID != guildID {
+			withGuild := *vs
+			withGuild.GuildID = guildID
+			vs = &withGuild
+		}
+
+		synced[vs.UserID] = vs
+	}
+
+	st.VoiceStatesMu.Lock()
+	if len(synced) == 0 {
+		delete(st.VoiceStates, guildID)
+	} else {
+		st.VoiceStates[guildID] = synced
+	}
+	st.VoiceStatesMu.Unlock()
+}
+
+// StateVoiceStateUpdate handles the VOICE_STATE_UPDATE event. The previous
+// voice state of the user is included as before so consumers can tell joins,
+// moves and leaves apart.
+func StateVoiceStateUpdate(ctx *StateCtx, msg discord.ReceivedPayload) (result structs.StateResult, ok bool, err error) {
+	var packet discord.VoiceStateUpdate
+
+	err = ctx.Sh.decodeContent(msg, &packet)
+	if err != nil {
+		return result, false, xerrors.Errorf("Failed to unmarshal message: %w", err)
+	}
+
+	result = structs.StateResult{
+		Data:  packet,
+		Extra: make(map[string]interface{}),
+	}
+
+	// Voice states of group DMs are not cached.
+	if packet.GuildID == 0 {
+		return result, true, nil
+	}
+
+	if before, o := ctx.Sg.State.GetVoiceState(packet.GuildID, packet.UserID); o {
+		result.Extra["before"] = before
+	}
+
+	vs := discord.VoiceState(packet)
+	ctx.Sg.State.SetVoiceState(packet.GuildID, &vs)
+
+	return result, true, nil
+}
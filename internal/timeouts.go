@@ -0,0 +1,50 @@
+package gateway
+
+import "time"
+
+// Defaults in seconds used when the bot timeouts are not set.
+const (
+	// Time an event can take to handle before it is logged as a possible deadlock.
+	defaultDispatchTimeout = 30
+
+	// Time necessary to abort chunking if no event received in this timeframe.
+	defaultInitialMemberChunkTimeout = 10
+
+	// Time necessary to mark chunking as completed if no more events received in this timeframe.
+	defaultMemberChunkTimeout = 1
+
+	// Time between chunks before marked as no longer chunked.
+	defaultChunkStatePersistTimeout = 10
+)
+
+// shardTimeouts are the dispatch and member chunk timeouts of a manager.
+type shardTimeouts struct {
+	dispatch     time.Duration
+	initialChunk time.Duration
+	chunk        time.Duration
+	chunkPersist time.Duration
+}
+
+// shardTimeouts returns the timeouts shards of the manager use. Defaults are
+// used for any left unset, as configurations updated over RPC are not
+// normalized.
+func (mg *Manager) shardTimeouts() shardTimeouts {
+	mg.ConfigurationMu.RLock()
+	bot := mg.Configuration.Bot
+	mg.ConfigurationMu.RUnlock()
+
+	return shardTimeouts{
+		dispatch:     timeoutSeconds(bot.DispatchTimeout, defaultDispatchTimeout),
+		initialChunk: timeoutSeconds(bot.InitialMemberChunkTimeout, defaultInitialMemberChunkTimeout),
+		chunk:        timeoutSeconds(bot.MemberChunkTimeout, defaultMemberChunkTimeout),
+		chunkPersist: timeoutSeconds(bot.ChunkStatePersistTimeout, defaultChunkStatePersistTimeout),
+	}
+}
+
+func timeoutSeconds(seconds int, fallback int) time.Duration {
+	if seconds < 1 {
+		seconds = fallback
+	}
+
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,305 @@
+package gateway
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
+
+const (
+	// Guilds kept in the heap of each bucket and listed by
+	// /api/state/top_guilds.
+	topGuildsK = 100
+
+	// Events are counted in buckets of topGuildsBucket. A bucket is reset
+	// when it is reused so the longest window is topGuildsBuckets buckets.
+	topGuildsBucket  = 5 * time.Minute
+	topGuildsBuckets = 144
+
+	// Window of /api/state/top_guilds if none is given.
+	defaultTopGuildsWindow = time.Hour
+
+	// Size of the count-min sketch of each bucket. Estimates are over by at
+	// most e/topGuildsWidth of the events in the bucket with a probability
+	// of 1-e^-topGuildsDepth.
+	topGuildsWidth = 1024
+	topGuildsDepth = 4
+)
+
+// Seeds of each row of the count-min sketch.
+var topGuildsSeeds = [topGuildsDepth]uint64{
+	0x9e3779b97f4a7c15, 0xbf58476d1ce4e5b9, 0x94d049bb133111eb, 0xd6e8feb86659fd93,
+}
+
+// topGuildEntry is a guild in the heap of a bucket. types counts the events
+// of the guild by type since it entered the heap.
+type topGuildEntry struct {
+	guildID snowflake.ID
+	count   uint32
+	index   int
+	types   map[string]int64
+}
+
+// topGuildHeap is a min heap so the guild with the fewest events is replaced
+// when a busier guild is seen.
+type topGuildHeap []*topGuildEntry
+
+func (h topGuildHeap) Len() int           { return len(h) }
+func (h topGuildHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h topGuildHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *topGuildHeap) Push(x interface{}) {
+	entry := x.(*topGuildEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *topGuildHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return entry
+}
+
+// topGuildsBucketData counts the events of guilds for a single bucket.
+type topGuildsBucketData struct {
+	start   int64 // Bucket number, 0 if the bucket is unused
+	events  int64
+	sketch  [topGuildsDepth][topGuildsWidth]uint32
+	heap    topGuildHeap
+	entries map[snowflake.ID]*topGuildEntry
+}
+
+// reset clears the bucket so it can be reused for the bucket number start.
+func (b *topGuildsBucketData) reset(start int64) {
+	b.start = start
+	b.events = 0
+	b.sketch = [topGuildsDepth][topGuildsWidth]uint32{}
+	b.heap = b.heap[:0]
+	b.entries = make(map[snowflake.ID]*topGuildEntry, topGuildsK)
+}
+
+// topGuildsHash returns the column of a guild in a row of the sketch.
+func topGuildsHash(guildID snowflake.ID, row int) int {
+	// splitmix64 finaliser
+	x := uint64(guildID) ^ topGuildsSeeds[row]
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return int(x % topGuildsWidth)
+}
+
+// add counts an event of a guild and returns its estimated count.
+func (b *topGuildsBucketData) add(guildID snowflake.ID) (estimate uint32) {
+	for row := 0; row < topGuildsDepth; row++ {
+		cell := &b.sketch[row][topGuildsHash(guildID, row)]
+		*cell++
+
+		if row == 0 || *cell < estimate {
+			estimate = *cell
+		}
+	}
+
+	return estimate
+}
+
+// estimate returns the estimated count of a guild without counting an event.
+func (b *topGuildsBucketData) estimate(guildID snowflake.ID) (estimate uint32) {
+	for row := 0; row < topGuildsDepth; row++ {
+		if cell := b.sketch[row][topGuildsHash(guildID, row)]; row == 0 || cell < estimate {
+			estimate = cell
+		}
+	}
+
+	return estimate
+}
+
+// topGuilds tracks the guilds of a manager which receive the most events.
+// Memory is bounded by the sketch and heap of each bucket regardless of how
+// many guilds the manager has.
+type topGuilds struct {
+	mu      sync.Mutex
+	buckets [topGuildsBuckets]*topGuildsBucketData
+}
+
+func newTopGuilds() *topGuilds {
+	return &topGuilds{
+		mu: sync.Mutex{},
+	}
+}
+
+// record counts an event of eventType for a guild. The bucket for now is
+// reset first if it last held an older bucket.
+func (tg *topGuilds) record(guildID snowflake.ID, eventType string, now time.Time) {
+	number := now.UnixNano() / int64(topGuildsBucket)
+
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+
+	slot := number % topGuildsBuckets
+
+	bucket := tg.buckets[slot]
+	if bucket == nil {
+		bucket = &topGuildsBucketData{}
+		tg.buckets[slot] = bucket
+		bucket.reset(number)
+	} else if bucket.start != number {
+		bucket.reset(number)
+	}
+
+	bucket.events++
+	count := bucket.add(guildID)
+
+	if entry, ok := bucket.entries[guildID]; ok {
+		entry.count = count
+		entry.types[eventType]++
+		heap.Fix(&bucket.heap, entry.index)
+
+		return
+	}
+
+	if len(bucket.heap) >= topGuildsK {
+		if bucket.heap[0].count >= count {
+			return
+		}
+
+		evicted := heap.Pop(&bucket.heap).(*topGuildEntry)
+		delete(bucket.entries, evicted.guildID)
+	}
+
+	entry := &topGuildEntry{
+		guildID: guildID,
+		count:   count,
+		types:   map[string]int64{eventType: 1},
+	}
+
+	heap.Push(&bucket.heap, entry)
+	bucket.entries[guildID] = entry
+}
+
+// topGuildsSpan returns how many buckets cover window. The current bucket
+// is always included even though it has not finished.
+func topGuildsSpan(window time.Duration) int64 {
+	span := int64((window + topGuildsBucket - 1) / topGuildsBucket)
+
+	switch {
+	case span < 1:
+		return 1
+	case span > topGuildsBuckets:
+		return topGuildsBuckets
+	}
+
+	return span
+}
+
+// top returns the guilds with the most events in the buckets covering window
+// and when the first of those buckets started. The event types of a guild
+// only include events whilst it was in the heap of a bucket so they can add
+// up to less than its count.
+func (tg *topGuilds) top(window time.Duration, now time.Time, limit int) (
+	guilds []structs.APITopGuild, events int64, since time.Time) {
+	span := topGuildsSpan(window)
+	current := now.UnixNano() / int64(topGuildsBucket)
+	first := current - span + 1
+	since = time.Unix(0, first*int64(topGuildsBucket)).UTC()
+
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+
+	buckets := make([]*topGuildsBucketData, 0, span)
+
+	for _, bucket := range tg.buckets {
+		if bucket != nil && bucket.start >= first && bucket.start <= current {
+			buckets = append(buckets, bucket)
+			events += bucket.events
+		}
+	}
+
+	byGuild := make(map[snowflake.ID]*structs.APITopGuild)
+
+	for _, bucket := range buckets {
+		for _, entry := range bucket.heap {
+			guild, ok := byGuild[entry.guildID]
+			if !ok {
+				guild = &structs.APITopGuild{
+					ID:         entry.guildID,
+					EventTypes: make(map[string]int64),
+				}
+				byGuild[entry.guildID] = guild
+			}
+
+			for eventType, count := range entry.types {
+				guild.EventTypes[eventType] += count
+			}
+		}
+	}
+
+	guilds = make([]structs.APITopGuild, 0, len(byGuild))
+
+	for _, guild := range byGuild {
+		for _, bucket := range buckets {
+			guild.Events += int64(bucket.estimate(guild.ID))
+		}
+
+		guilds = append(guilds, *guild)
+	}
+
+	sort.Slice(guilds, func(i, j int) bool {
+		if guilds[i].Events != guilds[j].Events {
+			return guilds[i].Events > guilds[j].Events
+		}
+
+		return guilds[i].ID < guilds[j].ID
+	})
+
+	if limit > 0 && len(guilds) > limit {
+		guilds = guilds[:limit]
+	}
+
+	return guilds, events, since
+}
+
+// recordGuildEvent counts a dispatch towards the top guilds of the manager.
+// Events which do not belong to a guild are ignored.
+func (mg *Manager) recordGuildEvent(msg discord.ReceivedPayload, now time.Time) {
+	if guildID := eventGuildID(msg.Type, msg.Data); guildID != 0 {
+		mg.topGuilds.record(guildID, msg.Type, now)
+	}
+}
+
+// TopGuilds returns the guilds which received the most events over window,
+// rounded up to whole buckets. Names are filled in from the state if the
+// guild is cached.
+func (mg *Manager) TopGuilds(window time.Duration) (result structs.APITopGuildsResult) {
+	guilds, events, since := mg.topGuilds.top(window, time.Now().UTC(), topGuildsK)
+
+	state := mg.Sandwich.State
+
+	state.GuildsMu.RLock()
+	for i, guild := range guilds {
+		if cached, ok := state.Guilds[guild.ID]; ok && cached.Guild != nil {
+			guilds[i].Name = cached.Name
+		}
+	}
+	state.GuildsMu.RUnlock()
+
+	return structs.APITopGuildsResult{
+		Window: (time.Duration(topGuildsSpan(window)) * topGuildsBucket).String(),
+		Since:  since,
+		Events: events,
+		Guilds: guilds,
+	}
+}
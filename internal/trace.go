@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// Most trace entries kept from a single payload.
+	maxTraceEntries = 16
+
+	// Longest trace entry kept. Newer entries include timing information
+	// which can be large.
+	maxTraceEntryLength = 2048
+)
+
+// parseTrace returns the "_trace" entries of a HELLO, READY or RESUMED
+// payload. The field is undocumented so anything other than a list or a
+// string is ignored and entries which are not strings are kept as JSON.
+func parseTrace(data []byte) (trace []string) {
+	value := json.Get(data, "_trace")
+
+	switch value.ValueType() {
+	case jsoniter.ArrayValue:
+		for i := 0; i < value.Size() && len(trace) < maxTraceEntries; i++ {
+			if entry := truncateTrace(value.Get(i).ToString()); entry != "" {
+				trace = append(trace, entry)
+			}
+		}
+	case jsoniter.StringValue:
+		if entry := truncateTrace(value.ToString()); entry != "" {
+			trace = append(trace, entry)
+		}
+	default:
+	}
+
+	return trace
+}
+
+func truncateTrace(entry string) string {
+	if len(entry) > maxTraceEntryLength {
+		return entry[:maxTraceEntryLength]
+	}
+
+	return entry
+}
+
+// traceNode returns the name of the gateway node in a trace. Entries are
+// either the name itself or a JSON list starting with the name.
+func traceNode(trace []string) (node string) {
+	for _, entry := range trace {
+		name := entry
+
+		if strings.HasPrefix(entry, "[") {
+			name = json.Get([]byte(entry), 0).ToString()
+		}
+
+		if name == "" {
+			continue
+		}
+
+		if strings.Contains(name, "gateway") {
+			return name
+		}
+
+		if node == "" {
+			node = name
+		}
+	}
+
+	return node
+}
+
+// recordHello stores the trace of a HELLO payload. This starts a new
+// connection so the trace of the previous READY is kept until it is replaced.
+func (sh *Shard) recordHello(data []byte) {
+	trace := parseTrace(data)
+
+	sh.sessionMu.Lock()
+	sh.session.HelloTrace = trace
+	sh.session.HelloAt = time.Now().UTC()
+
+	if node := traceNode(trace); node != "" {
+		sh.session.GatewayNode = node
+	}
+	sh.sessionMu.Unlock()
+}
+
+// recordReady stores the trace and session metadata of a READY or RESUMED
+// payload.
+func (sh *Shard) recordReady(data []byte, resumed bool) {
+	trace := parseTrace(data)
+
+	sh.sessionMu.Lock()
+	defer sh.sessionMu.Unlock()
+
+	sh.session.ReadyTrace = trace
+	sh.session.ReadyAt = time.Now().UTC()
+	sh.session.Resumed = resumed
+
+	if node := traceNode(trace); node != "" && sh.session.GatewayNode == "" {
+		sh.session.GatewayNode = node
+	}
+
+	if resumed {
+		return
+	}
+
+	sh.session.SessionID = json.Get(data, "session_id").ToString()
+	sh.session.SessionType = json.Get(data, "session_type").ToString()
+	sh.session.ResumeGatewayURL = json.Get(data, "resume_gateway_url").ToString()
+	sh.session.Version = json.Get(data, "v").ToInt()
+}
+
+// Session returns the trace and session metadata of the current connection.
+func (sh *Shard) Session() (session structs.ShardSession) {
+	sh.sessionMu.RLock()
+	session = sh.session
+	sh.sessionMu.RUnlock()
+
+	return session
+}
+
+// sessionDescription describes the current session for webhooks so it can
+// be passed on to discord when investigating session problems.
+func (sh *Shard) sessionDescription() string {
+	session := sh.Session()
+
+	if session.SessionID == "" && session.GatewayNode == "" {
+		return ""
+	}
+
+	description := fmt.Sprintf("Session `%s` on `%s`", session.SessionID, session.GatewayNode)
+
+	if len(session.ReadyTrace) > 0 {
+		description += fmt.Sprintf("\nTrace: `%s`", strings.Join(session.ReadyTrace, "`, `"))
+	}
+
+	if len(description) > maxTraceEntryLength {
+		description = description[:maxTraceEntryLength] + "..."
+	}
+
+	return description
+}
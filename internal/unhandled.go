@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+)
+
+// Event types without a state handler which are tracked per manager. Types
+// seen after this are counted under unhandledOverflow.
+const (
+	maxUnhandledTypes = 256
+	unhandledOverflow = "OTHER"
+)
+
+// unhandledEvent counts dispatches of a type without a state handler.
+type unhandledEvent struct {
+	count     int64
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// recordUnhandled counts a dispatch no state handler exists for.
+func (mg *Manager) recordUnhandled(eventType string) {
+	now := time.Now().UTC()
+
+	mg.unhandledMu.Lock()
+	defer mg.unhandledMu.Unlock()
+
+	event, ok := mg.unhandled[eventType]
+	if !ok {
+		if len(mg.unhandled) >= maxUnhandledTypes {
+			eventType = unhandledOverflow
+			event, ok = mg.unhandled[eventType]
+		}
+
+		if !ok {
+			event = &unhandledEvent{firstSeen: now}
+			mg.unhandled[eventType] = event
+
+			mg.Logger.Info().Str("type", eventType).Msg("Received dispatch without a state handler")
+		}
+	}
+
+	event.count++
+	event.lastSeen = now
+}
+
+// UnhandledEvents returns the dispatch types without a state handler which
+// have been received, most frequent first.
+func (mg *Manager) UnhandledEvents() (events []structs.UnhandledEvent) {
+	mg.unhandledMu.Lock()
+	events = make([]structs.UnhandledEvent, 0, len(mg.unhandled))
+
+	for eventType, event := range mg.unhandled {
+		events = append(events, structs.UnhandledEvent{
+			Type:      eventType,
+			Count:     event.count,
+			FirstSeen: event.firstSeen,
+			LastSeen:  event.lastSeen,
+		})
+	}
+	mg.unhandledMu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Count == events[j].Count {
+			return events[i].Type < events[j].Type
+		}
+
+		return events[i].Count > events[j].Count
+	})
+
+	return events
+}
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
-	"golang.org/x/xerrors"
 )
 
 const (
@@ -18,6 +17,9 @@ const (
 	hourSeconds           = 3600
 	minuteSeconds         = 60
 	discordSnowflakeEpoch = 1420070400000
+
+	// Text tokens are replaced with when redacted.
+	redactedToken = "..."
 )
 
 // We change the default Epoch of the snowflake to match discord's.
@@ -72,14 +74,43 @@ func DeepEqualExports(tagName string, a interface{}, b interface{}) bool {
 }
 
 // QuickHash simply returns hash from input.
+//
+// Deprecated: Use TokenHash or Manager.TokenHash which is cached.
 func QuickHash(hash string) (result string, err error) {
-	h := sha256.New()
+	return TokenHash(hash), nil
+}
+
+// TokenHash returns the hex encoded sha256 of a token. This is used in place
+// of the token wherever it needs to be identified, such as in bucket names.
+func TokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Redact removes the tokens of every manager from a string.
+func (sg *Sandwich) Redact(s string) string {
+	sg.ManagersMu.RLock()
+	defer sg.ManagersMu.RUnlock()
 
-	if _, err := h.Write([]byte(hash)); err != nil {
-		return "", xerrors.Errorf("Failed to write to sha256 writer: %w", err)
+	for _, mg := range sg.Managers {
+		s = mg.Redact(s)
+	}
+
+	return s
+}
+
+// RedactTokens replaces every occurrence of the tokens provided. All output
+// that could contain a token should be passed through this before it is
+// logged or stored.
+func RedactTokens(s string, tokens ...string) string {
+	for _, token := range tokens {
+		if token != "" {
+			s = strings.ReplaceAll(s, token, redactedToken)
+		}
 	}
 
-	return hex.EncodeToString(h.Sum(nil)), nil
+	return s
 }
 
 // DurationTimestamp outputs in a format similar to the timestamp String().
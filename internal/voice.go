@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"golang.org/x/xerrors"
+)
+
+// UpdateVoiceState sends a voice state update on the shard of the guild in the
+// latest shardgroup. Discord answers with VOICE_STATE_UPDATE and, when joining
+// a channel, VOICE_SERVER_UPDATE on the same shard which consumers identify
+// through the shard in the event metadata.
+func (mg *Manager) UpdateVoiceState(state discord.UpdateVoiceState) (result structs.RPCManagerVoiceStateUpdateResponse, err error) {
+	shardGroup := mg.latestShardGroup()
+	if shardGroup == nil {
+		return result, ErrNoShardGroup
+	}
+
+	shardID := int((state.GuildID.Int64() >> 22) % int64(shardGroup.ShardCount))
+
+	shardGroup.ShardsMu.RLock()
+	sh, ok := shardGroup.Shards[shardID]
+	shardGroup.ShardsMu.RUnlock()
+
+	if !ok {
+		return result, ErrInvalidShard
+	}
+
+	sh.StatusMu.RLock()
+	status := sh.Status
+	sh.StatusMu.RUnlock()
+
+	if status != structs.ShardReady {
+		return result, xerrors.Errorf("shard %d is %s: %w", shardID, status.String(), ErrShardNotReady)
+	}
+
+	if err = sh.SendEvent(discord.GatewayOpVoiceStateUpdate, state); err != nil {
+		return result, xerrors.Errorf("send voice state update: %w", err)
+	}
+
+	return structs.RPCManagerVoiceStateUpdateResponse{
+		ShardGroup: shardGroup.ID,
+		ShardID:    shardID,
+		ShardCount: shardGroup.ShardCount,
+	}, nil
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"nhooyr.io/websocket"
+)
+
+// Interval between the watchdog of a shard checking its connection.
+const watchdogInterval = 5 * time.Second
+
+// recordMessage marks a message as received on the websocket.
+func (sh *Shard) recordMessage(now time.Time) {
+	atomic.StoreInt64(sh.lastMessage, now.UnixNano())
+}
+
+// lastMessageAt returns when the last websocket message was received.
+func (sh *Shard) lastMessageAt() time.Time {
+	if last := atomic.LoadInt64(sh.lastMessage); last > 0 {
+		return time.Unix(0, last).UTC()
+	}
+
+	return time.Time{}
+}
+
+// watchdog reconnects the shard when neither a message nor a heartbeat ACK
+// has been received for MaxHeartbeatFailures. A websocket which dies without
+// a close frame otherwise leaves the read loop blocked forever, as reads have
+// no deadline. It runs until the shardgroup closes.
+func (sh *Shard) watchdog() {
+	sh.watchdogActive.Set()
+	defer sh.watchdogActive.UnSet()
+
+	t := time.NewTicker(watchdogInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-sh.ShardGroup.close:
+			return
+		case now := <-t.C:
+			if silence, stalled := sh.watchdogStalled(now.UTC()); stalled {
+				sh.restartStalled(silence)
+			}
+		}
+	}
+}
+
+// watchdogStalled returns how long the connection has been silent and if it
+// is longer than the shard allows. Shards which are not connected are
+// ignored as they are already handled by Connect and Reconnect.
+func (sh *Shard) watchdogStalled(now time.Time) (silence time.Duration, stalled bool) {
+	sh.StatusMu.RLock()
+	status := sh.Status
+	sh.StatusMu.RUnlock()
+
+	if status != structs.ShardConnected && status != structs.ShardReady {
+		return 0, false
+	}
+
+	sh.RLock()
+	limit := sh.MaxHeartbeatFailures
+	sh.RUnlock()
+
+	if limit <= 0 {
+		return 0, false
+	}
+
+	sh.LastHeartbeatMu.RLock()
+	last := sh.LastHeartbeatAck
+	sh.LastHeartbeatMu.RUnlock()
+
+	if message := sh.lastMessageAt(); message.After(last) {
+		last = message
+	}
+
+	silence = now.Sub(last)
+
+	return silence, silence > limit
+}
+
+// restartStalled closes the connection of a stalled shard and reconnects.
+// Reconnect cancels the connection context and closes the websocket, which
+// unblocks the read loop.
+func (sh *Shard) restartStalled(silence time.Duration) {
+	atomic.AddInt64(sh.watchdogStalls, 1)
+	atomic.StoreInt64(sh.lastStall, time.Now().UTC().UnixNano())
+
+	sh.Logger.Warn().
+		Dur("silence", silence).
+		Msg("Nothing has been received from the gateway. Restarting the stalled shard")
+
+	go sh.PublishNoisyWebhook("Shard connection stalled. Reconnecting",
+		fmt.Sprintf("Nothing was received for `%s`", silence.Round(time.Second)), discord.EmbedWarning, false)
+
+	if err := sh.Reconnect(websocket.StatusNormalClosure); err != nil {
+		sh.Logger.Error().Err(err).Msg("Failed to reconnect stalled shard")
+	}
+}
+
+// Watchdog returns the state of the watchdog of the shard.
+func (sh *Shard) Watchdog() (watchdog structs.ShardWatchdog) {
+	watchdog.LastMessageAt = sh.lastMessageAt()
+	watchdog.Stalls = atomic.LoadInt64(sh.watchdogStalls)
+
+	if last := atomic.LoadInt64(sh.lastStall); last > 0 {
+		lastStall := time.Unix(0, last).UTC()
+		watchdog.LastStallAt = &lastStall
+	}
+
+	return watchdog
+}
@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"context"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/xerrors"
+	"nhooyr.io/websocket"
+)
+
+// ErrStaleConnection is returned when writing to a connection which has
+// since been replaced or closed.
+var ErrStaleConnection = xerrors.New("websocket connection has been replaced")
+
+// wsConnEntry is a connection along with the generation it was stored as.
+type wsConnEntry struct {
+	conn       *websocket.Conn
+	generation int64
+}
+
+// wsConnHolder holds the current websocket connection of a shard. Every time
+// the connection is replaced or closed, the generation is increased so writes
+// made for one connection can never be sent on the one that replaced it.
+type wsConnHolder struct {
+	entry      unsafe.Pointer // *wsConnEntry
+	generation *int64
+}
+
+func newWSConnHolder() *wsConnHolder {
+	return &wsConnHolder{
+		entry:      unsafe.Pointer(&wsConnEntry{}),
+		generation: new(int64),
+	}
+}
+
+func (wh *wsConnHolder) load() *wsConnEntry {
+	return (*wsConnEntry)(atomic.LoadPointer(&wh.entry))
+}
+
+// Get returns the current connection and its generation. The connection
+// is nil if there is no open connection.
+func (wh *wsConnHolder) Get() (conn *websocket.Conn, generation int64) {
+	entry := wh.load()
+
+	return entry.conn, entry.generation
+}
+
+// Swap replaces the current connection and returns the previous one.
+func (wh *wsConnHolder) Swap(conn *websocket.Conn) (old *websocket.Conn, generation int64) {
+	entry := &wsConnEntry{
+		conn:       conn,
+		generation: atomic.AddInt64(wh.generation, 1),
+	}
+
+	previous := (*wsConnEntry)(atomic.SwapPointer(&wh.entry, unsafe.Pointer(entry)))
+
+	return previous.conn, entry.generation
+}
+
+// CloseCurrent closes and removes the current connection. The returned
+// connection is nil if there was nothing to close.
+func (wh *wsConnHolder) CloseCurrent(statusCode websocket.StatusCode) (closed *websocket.Conn, err error) {
+	for {
+		current := atomic.LoadPointer(&wh.entry)
+
+		entry := (*wsConnEntry)(current)
+		if entry.conn == nil {
+			return nil, nil
+		}
+
+		next := &wsConnEntry{
+			generation: atomic.AddInt64(wh.generation, 1),
+		}
+
+		if atomic.CompareAndSwapPointer(&wh.entry, current, unsafe.Pointer(next)) {
+			return entry.conn, entry.conn.Close(statusCode, "")
+		}
+	}
+}
+
+// Write sends a message on the connection of the generation provided. If
+// the connection has since been replaced, ErrStaleConnection is returned.
+func (wh *wsConnHolder) Write(ctx context.Context, generation int64,
+	typ websocket.MessageType, p []byte) (err error) {
+	entry := wh.load()
+
+	if entry.generation != generation || entry.conn == nil {
+		return ErrStaleConnection
+	}
+
+	return entry.conn.Write(ctx, typ, p)
+}
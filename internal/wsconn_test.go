@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+	"nhooyr.io/websocket"
+)
+
+// receivedFrame is a frame received on the connection dialed with an index.
+type receivedFrame struct {
+	index int
+	data  string
+}
+
+func TestWSConnHolderConcurrent(t *testing.T) {
+	const (
+		connections = 8
+		writers     = 8
+	)
+
+	receivedMu := sync.Mutex{}
+	received := make([]receivedFrame, 0)
+
+	handlers := sync.WaitGroup{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		handlers.Add(1)
+		defer handlers.Done()
+
+		conn, err := websocket.Accept(rw, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.StatusNormalClosure, "")
+
+		// The first frame is the index the connection was dialed with.
+		_, data, err := conn.Read(r.Context())
+		if err != nil {
+			return
+		}
+
+		index, _ := strconv.Atoi(string(data))
+
+		for {
+			_, data, err := conn.Read(r.Context())
+			if err != nil {
+				return
+			}
+
+			receivedMu.Lock()
+			received = append(received, receivedFrame{index: index, data: string(data)})
+			receivedMu.Unlock()
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conns := make([]*websocket.Conn, connections)
+
+	for i := range conns {
+		conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+
+		if err = conn.Write(ctx, websocket.MessageText, []byte(strconv.Itoa(i))); err != nil {
+			t.Fatalf("failed to write index: %v", err)
+		}
+
+		conns[i] = conn
+	}
+
+	wh := newWSConnHolder()
+
+	// Generation each connection was stored as.
+	generations := make([]int64, connections)
+
+	done := make(chan void)
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				conn, generation := wh.Get()
+				if conn == nil {
+					continue
+				}
+
+				err := wh.Write(ctx, generation, websocket.MessageText, []byte(strconv.FormatInt(generation, 10)))
+
+				// Writes racing a close may fail on the closed connection but
+				// must never reach another one.
+				var closeErr websocket.CloseError
+				if err != nil && !xerrors.Is(err, ErrStaleConnection) &&
+					!xerrors.As(err, &closeErr) && !strings.Contains(err.Error(), "closed") {
+					t.Errorf("unexpected write error: %v", err)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(done)
+
+		for i, conn := range conns {
+			previous, generation := wh.Swap(conn)
+			generations[i] = generation
+
+			if previous != nil {
+				previous.Close(websocket.StatusNormalClosure, "")
+			}
+
+			time.Sleep(time.Millisecond)
+
+			if i%3 == 2 {
+				if _, err := wh.CloseCurrent(websocket.StatusNormalClosure); err != nil {
+					t.Errorf("failed to close current: %v", err)
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+	<-done
+
+	wh.CloseCurrent(websocket.StatusNormalClosure)
+
+	for _, conn := range conns {
+		conn.Close(websocket.StatusNormalClosure, "")
+	}
+
+	handlers.Wait()
+
+	receivedMu.Lock()
+	defer receivedMu.Unlock()
+
+	if len(received) == 0 {
+		t.Fatal("no writes were received")
+	}
+
+	for _, frame := range received {
+		if want := strconv.FormatInt(generations[frame.index], 10); frame.data != want {
+			t.Errorf("connection %d of generation %s received a write for generation %s",
+				frame.index, want, frame.data)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+
+	"golang.org/x/xerrors"
+)
+
+// zlibStreamSuffix ends every message which completes a payload when
+// zlib-stream transport compression is used.
+var zlibStreamSuffix = []byte{0x00, 0x00, 0xff, 0xff}
+
+// zlibStream inflates a connection which uses zlib-stream transport
+// compression. Unlike payload compression the compression context is shared
+// by the whole connection so every message must pass through the same
+// stream in the order it was received. A stream is only used by the
+// goroutine reading its connection.
+type zlibStream struct {
+	chunks   chan []byte // Compressed messages waiting to be inflated
+	payloads chan []byte // Inflated payloads
+	done     chan void   // Closed once the inflater stops
+	err      error       // Why the inflater stopped. Read once done is closed
+
+	// Only used by the inflater goroutine.
+	current  []byte
+	complete bool // If the last message ended with zlibStreamSuffix
+	inflated *bytes.Buffer
+}
+
+func newZlibStream() *zlibStream {
+	zs := &zlibStream{
+		chunks:   make(chan []byte),
+		payloads: make(chan []byte, 1),
+		done:     make(chan void),
+		inflated: new(bytes.Buffer),
+	}
+
+	go zs.run()
+
+	return zs
+}
+
+// transportCompression returns if shards connect with zlib-stream transport
+// compression.
+func (mg *Manager) transportCompression() bool {
+	mg.ConfigurationMu.RLock()
+	defer mg.ConfigurationMu.RUnlock()
+
+	return mg.Configuration.Bot.TransportCompression
+}
+
+// run inflates messages until the stream is closed or the data is invalid.
+func (zs *zlibStream) run() {
+	defer close(zs.done)
+
+	// The zlib header is only sent at the start of the connection so this
+	// waits for the first message.
+	zr, err := zlib.NewReader(zs)
+	if err != nil {
+		zs.err = xerrors.Errorf("zlib stream header: %w", err)
+
+		return
+	}
+
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, err := zr.Read(buf)
+		zs.inflated.Write(buf[:n])
+
+		if err != nil {
+			zs.err = xerrors.Errorf("zlib stream inflate: %w", err)
+
+			return
+		}
+	}
+}
+
+// Read passes compressed messages to the zlib reader. When the reader asks
+// for more data after a message ending with zlibStreamSuffix, everything
+// before the flush has been inflated so the payload is complete.
+func (zs *zlibStream) Read(p []byte) (n int, err error) {
+	for len(zs.current) == 0 {
+		if zs.complete {
+			zs.complete = false
+			zs.payloads <- zs.inflated.Bytes()
+			zs.inflated = new(bytes.Buffer)
+		}
+
+		chunk, ok := <-zs.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+
+		zs.current = chunk
+		zs.complete = bytes.HasSuffix(chunk, zlibStreamSuffix)
+	}
+
+	n = copy(p, zs.current)
+	zs.current = zs.current[n:]
+
+	return n, nil
+}
+
+// inflate passes a message received from the gateway to the stream. Large
+// payloads can be split over several messages so ok is only true once a
+// message completes a payload.
+func (zs *zlibStream) inflate(message []byte) (payload []byte, ok bool, err error) {
+	select {
+	case zs.chunks <- message:
+	case <-zs.done:
+		return nil, false, zs.err
+	}
+
+	if !bytes.HasSuffix(message, zlibStreamSuffix) {
+		return nil, false, nil
+	}
+
+	select {
+	case payload = <-zs.payloads:
+		return payload, true, nil
+	case <-zs.done:
+		return nil, false, zs.err
+	}
+}
+
+// Close stops the inflater. The stream cannot be used afterwards.
+func (zs *zlibStream) Close() {
+	close(zs.chunks)
+	<-zs.done
+}
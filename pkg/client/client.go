@@ -0,0 +1,280 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/xerrors"
+	"nhooyr.io/websocket"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	// SessionCookie is the name of the cookie sandwich stores sessions in.
+	SessionCookie = "session"
+
+	defaultRetries    = 3
+	defaultRetryDelay = time.Millisecond * 500
+
+	// Maximum size of a single /api/ws message.
+	subscribeReadLimit = 32 << 20
+)
+
+// Client is a client for the sandwich HTTP API.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	token   string
+	session string
+
+	// Retries is the number of times a GET or HEAD request is retried when
+	// sandwich returns a 5xx status or the request could not be made.
+	Retries int
+	// RetryDelay is the time waited before the first retry. This doubles
+	// with each attempt.
+	RetryDelay time.Duration
+	// RetryUnsafe also retries other methods such as the POST made for RPC
+	// calls. These may have been applied even when an error is returned so
+	// only enable this if the calls made can safely be repeated.
+	RetryUnsafe bool
+}
+
+// Option configures a Client.
+type Option func(c *Client)
+
+// WithToken authenticates requests with a bearer token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithSession authenticates requests with the value of a session cookie
+// retrieved from logging in to the dashboard.
+func WithSession(session string) Option {
+	return func(c *Client) {
+		c.session = session
+	}
+}
+
+// WithRetryUnsafe retries requests which are not GET or HEAD, such as RPC
+// calls, in the same way. See Client.RetryUnsafe.
+func WithRetryUnsafe() Option {
+	return func(c *Client) {
+		c.RetryUnsafe = true
+	}
+}
+
+// WithHTTPClient uses a custom http.Client for requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// NewClient creates a Client for the sandwich instance at baseURL such
+// as http://127.0.0.1:5469.
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+
+		Retries:    defaultRetries,
+		RetryDelay: defaultRetryDelay,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// APIError is returned when sandwich responds with success set to false.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return http.StatusText(e.StatusCode) + ": " + e.Message
+}
+
+// response is the same as structs.BaseResponse however the data is
+// kept raw so it can be decoded into the expected structure.
+type response struct {
+	Success bool                `json:"success"`
+	Data    jsoniter.RawMessage `json:"data,omitempty"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// Do makes a request to sandwich and decodes the data of the response
+// into result. result may be nil if the response is not needed.
+func (c *Client) Do(ctx context.Context, method string, path string, body interface{}, result interface{}) (err error) {
+	var payload []byte
+
+	if body != nil {
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return xerrors.Errorf("failed to marshal body: %w", err)
+		}
+	}
+
+	retries := c.Retries
+	if !c.RetryUnsafe && !idempotent(method) {
+		retries = 0
+	}
+
+	delay := c.RetryDelay
+
+	for attempt := 0; ; attempt++ {
+		var retry bool
+
+		retry, err = c.do(ctx, method, path, payload, result)
+		if err == nil || !retry || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
+
+// idempotent returns if a request with method can be retried without
+// changing anything it may have already done.
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, payload []byte, result interface{}) (retry bool, err error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return false, xerrors.Errorf("failed to create request: %w", err)
+	}
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	c.authenticate(req.Header)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, xerrors.Errorf("failed to do request: %w", err)
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return true, xerrors.Errorf("failed to read response: %w", err)
+	}
+
+	retry = res.StatusCode >= http.StatusInternalServerError
+
+	resp := response{}
+
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		if retry {
+			return true, &APIError{StatusCode: res.StatusCode, Message: string(data)}
+		}
+
+		return false, xerrors.Errorf("failed to decode response: %w", err)
+	}
+
+	if !resp.Success {
+		return retry, &APIError{StatusCode: res.StatusCode, Message: resp.Error}
+	}
+
+	if result != nil && len(resp.Data) > 0 {
+		err = json.Unmarshal(resp.Data, result)
+		if err != nil {
+			return false, xerrors.Errorf("failed to decode response data: %w", err)
+		}
+	}
+
+	return false, nil
+}
+
+func (c *Client) authenticate(header http.Header) {
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	if c.session != "" {
+		header.Add("Cookie", (&http.Cookie{Name: SessionCookie, Value: c.session}).String())
+	}
+}
+
+// Subscribe connects to /api/ws and calls handler with every update sent
+// until the context is cancelled or handler returns an error.
+func (c *Client) Subscribe(ctx context.Context, handler func(result structs.APISubscribeResult) error) (err error) {
+	wsURL, err := url.Parse(c.baseURL + "/api/ws")
+	if err != nil {
+		return xerrors.Errorf("failed to parse url: %w", err)
+	}
+
+	switch wsURL.Scheme {
+	case "https":
+		wsURL.Scheme = "wss"
+	default:
+		wsURL.Scheme = "ws"
+	}
+
+	header := http.Header{}
+	c.authenticate(header)
+
+	conn, _, err := websocket.Dial(ctx, wsURL.String(), &websocket.DialOptions{
+		HTTPClient: c.httpClient,
+		HTTPHeader: header,
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to dial: %w", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	conn.SetReadLimit(subscribeReadLimit)
+
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return xerrors.Errorf("failed to read: %w", err)
+		}
+
+		result := structs.APISubscribeResult{}
+
+		err = json.Unmarshal(data, &result)
+		if err != nil {
+			return xerrors.Errorf("failed to decode update: %w", err)
+		}
+
+		err = handler(result)
+		if err != nil {
+			return err
+		}
+	}
+}
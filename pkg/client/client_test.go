@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"golang.org/x/xerrors"
+)
+
+// failingServer responds with status to the first failures requests and
+// succeeds afterwards. It returns the server and the number of requests
+// made.
+func failingServer(t *testing.T, failures int64, status int) (*httptest.Server, *int64) {
+	t.Helper()
+
+	requests := new(int64)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+
+		if atomic.AddInt64(requests, 1) <= failures {
+			rw.WriteHeader(status)
+			_, _ = rw.Write([]byte(`{"success":false,"error":"failed"}`))
+
+			return
+		}
+
+		_, _ = rw.Write([]byte(`{"success":true,"data":{"uptime":5}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func testClient(url string, opts ...Option) *Client {
+	c := NewClient(url, opts...)
+	c.RetryDelay = time.Millisecond
+
+	return c
+}
+
+func TestGetRetriedOnServerError(t *testing.T) {
+	server, requests := failingServer(t, 2, http.StatusServiceUnavailable)
+
+	status, err := testClient(server.URL).Status(context.Background())
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+
+	if status.Uptime != 5 {
+		t.Errorf("uptime was %d", status.Uptime)
+	}
+
+	if got := atomic.LoadInt64(requests); got != 3 {
+		t.Errorf("made %d requests, want 3", got)
+	}
+}
+
+func TestGetRetriesExhausted(t *testing.T) {
+	server, requests := failingServer(t, defaultRetries+1, http.StatusInternalServerError)
+
+	_, err := testClient(server.URL).Status(context.Background())
+
+	apiErr := &APIError{}
+	if !xerrors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("error was %v", err)
+	}
+
+	if got := atomic.LoadInt64(requests); got != defaultRetries+1 {
+		t.Errorf("made %d requests, want %d", got, defaultRetries+1)
+	}
+}
+
+func TestGetNotRetriedOnClientError(t *testing.T) {
+	server, requests := failingServer(t, 1, http.StatusForbidden)
+
+	if _, err := testClient(server.URL).Status(context.Background()); err == nil {
+		t.Fatal("status succeeded")
+	}
+
+	if got := atomic.LoadInt64(requests); got != 1 {
+		t.Errorf("made %d requests, want 1", got)
+	}
+}
+
+func TestRPCNotRetried(t *testing.T) {
+	server, requests := failingServer(t, 1, http.StatusInternalServerError)
+
+	err := testClient(server.URL).RPC(context.Background(), MethodDaemonUpdate, nil, nil)
+	if err == nil {
+		t.Fatal("rpc succeeded")
+	}
+
+	if got := atomic.LoadInt64(requests); got != 1 {
+		t.Errorf("made %d requests, want 1", got)
+	}
+}
+
+func TestRPCRetriedWhenUnsafe(t *testing.T) {
+	server, requests := failingServer(t, 1, http.StatusInternalServerError)
+
+	err := testClient(server.URL, WithRetryUnsafe()).RPC(context.Background(), MethodDaemonUpdate, nil, nil)
+	if err != nil {
+		t.Fatalf("rpc failed: %v", err)
+	}
+
+	if got := atomic.LoadInt64(requests); got != 2 {
+		t.Errorf("made %d requests, want 2", got)
+	}
+}
+
+func TestRequestAuthenticated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			_, _ = rw.Write([]byte(`{"success":false,"error":"unauthorized"}`))
+
+			return
+		}
+
+		if cookie, err := r.Cookie(SessionCookie); err != nil || cookie.Value != "session" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			_, _ = rw.Write([]byte(`{"success":false,"error":"no session"}`))
+
+			return
+		}
+
+		_, _ = rw.Write([]byte(`{"success":true,"data":{}}`))
+	}))
+	defer server.Close()
+
+	c := testClient(server.URL, WithToken("token"), WithSession("session"))
+
+	result := structs.APIStatusResult{}
+	if err := c.Do(context.Background(), http.MethodGet, "/api/status", nil, &result); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+}
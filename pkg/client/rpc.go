@@ -0,0 +1,574 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/xerrors"
+)
+
+// RPC methods registered by sandwich.
+const (
+	MethodManagerUpdate          = "manager:update"
+	MethodManagerCreate          = "manager:create"
+	MethodManagerDelete          = "manager:delete"
+	MethodManagerRestart         = "manager:restart"
+	MethodManagerRefreshGateway  = "manager:refresh_gateway"
+	MethodManagerProducerRestart = "manager:producer:restart"
+	MethodManagerClientReset     = "manager:client:reset"
+
+	MethodManagerBlacklistGet    = "manager:blacklist:get"
+	MethodManagerBlacklistAdd    = "manager:blacklist:add"
+	MethodManagerBlacklistRemove = "manager:blacklist:remove"
+
+	MethodManagerCapture      = "manager:capture"
+	MethodManagerCaptureFetch = "manager:capture:fetch"
+
+	MethodManagerChunkRetry  = "manager:chunk_failures:retry"
+	MethodManagerErrorsReset = "manager:errors:reset"
+
+	MethodManagerGuildChunk       = "manager:guild:chunk"
+	MethodManagerVoiceStateUpdate = "manager:guild:voice_state"
+	MethodManagerMutualGuilds     = "manager:user:mutual_guilds"
+
+	MethodManagerUnackedRequeue = "manager:unacked:requeue"
+	MethodManagerUnackedDiscard = "manager:unacked:discard"
+
+	MethodManagerLeavePolicyEvaluate = "manager:leave_policy:evaluate"
+	MethodManagerAffinitySet         = "manager:affinity:set"
+	MethodManagerRebalanceReport     = "manager:rebalance_report"
+
+	MethodShardRestart      = "manager:shard:restart"
+	MethodShardSend         = "manager:shard:send"
+	MethodShardStatusUpdate = "manager:shard:status_update"
+	MethodShardLogLevel     = "shard:log_level"
+
+	MethodShardGroupCreate = "manager:shardgroup:create"
+	MethodShardGroupStop   = "manager:shardgroup:stop"
+	MethodShardGroupDelete = "manager:shardgroup:delete"
+	MethodShardGroupRoll   = "manager:shardgroup:roll"
+
+	MethodJobStatus  = "job:status"
+	MethodJobDismiss = "job:dismiss"
+
+	MethodDaemonJobStatus        = "daemon:job:status" // Alias of MethodJobStatus
+	MethodDaemonMethods          = "daemon:methods"
+	MethodDaemonVerifyRestTunnel = "daemon:verify_resttunnel"
+	MethodDaemonUpdate           = "daemon:update"
+	MethodDaemonMaintenance      = "daemon:maintenance"
+	MethodDaemonValidate         = "daemon:validate"
+	MethodDaemonAddWebhook       = "daemon:add_webhook"
+	MethodDaemonTestWebhook      = "daemon:test_webhook"
+	MethodDaemonRemoveWebhook    = "daemon:remove_webhook"
+)
+
+// RPC calls an RPC method with data and decodes the response into result.
+func (c *Client) RPC(ctx context.Context, method string, data interface{}, result interface{}) (err error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal rpc data: %w", err)
+	}
+
+	return c.Do(ctx, http.MethodPost, "/api/rpc", structs.RPCRequest{
+		Method: method,
+		Data:   jsoniter.RawMessage(raw),
+	}, result)
+}
+
+// Status returns the /api/status response. This does not require authentication.
+func (c *Client) Status(ctx context.Context) (result structs.APIStatusResult, err error) {
+	err = c.Do(ctx, http.MethodGet, "/api/status", nil, &result)
+
+	return result, err
+}
+
+// Me returns the user the client is authenticated as.
+func (c *Client) Me(ctx context.Context) (result structs.APIMe, err error) {
+	err = c.Do(ctx, http.MethodGet, "/api/me", nil, &result)
+
+	return result, err
+}
+
+// Analytics returns the /api/analytics response.
+func (c *Client) Analytics(ctx context.Context) (result structs.APIAnalyticsResult, err error) {
+	err = c.Do(ctx, http.MethodGet, "/api/analytics", nil, &result)
+
+	return result, err
+}
+
+// Managers returns the /api/managers response sorted by identifier. If manager
+// is not empty, only that manager is returned. Fields limits which fields are
+// included, all are included if none are passed.
+func (c *Client) Managers(ctx context.Context, manager string,
+	fields ...string) (result []structs.APIManagersResponseManager, err error) {
+	query := url.Values{}
+
+	if manager != "" {
+		query.Set("manager", manager)
+	}
+
+	if len(fields) > 0 {
+		query.Set("fields", strings.Join(fields, ","))
+	}
+
+	err = c.Do(ctx, http.MethodGet, "/api/managers?"+query.Encode(), nil, &result)
+
+	return result, err
+}
+
+// ManagersMap returns the /api/managers response in the previous map format.
+func (c *Client) ManagersMap(ctx context.Context) (result map[string]structs.APIConfigurationResponseManager, err error) {
+	err = c.Do(ctx, http.MethodGet, "/api/managers?format=map", nil, &result)
+
+	return result, err
+}
+
+// Configuration returns the /api/configuration response.
+func (c *Client) Configuration(ctx context.Context) (result structs.APIConfigurationResponse, err error) {
+	err = c.Do(ctx, http.MethodGet, "/api/configuration", nil, &result)
+
+	return result, err
+}
+
+// Poll returns the /api/poll response which is the same as a single /api/ws update.
+func (c *Client) Poll(ctx context.Context) (result structs.APISubscribeResult, err error) {
+	err = c.Do(ctx, http.MethodGet, "/api/poll", nil, &result)
+
+	return result, err
+}
+
+// Audit returns up to limit recent audit entries, newest first, with an ID
+// lower than before. A before of 0 returns the newest entries.
+func (c *Client) Audit(ctx context.Context, limit int, before snowflake.ID) (result []structs.AuditEntry, err error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+
+	if before != 0 {
+		query.Set("before", before.String())
+	}
+
+	err = c.Do(ctx, http.MethodGet, "/api/audit?"+query.Encode(), nil, &result)
+
+	return result, err
+}
+
+// StateGuild returns a guild from state with its roles, channels, threads,
+// emojis and voice states.
+func (c *Client) StateGuild(ctx context.Context, guildID snowflake.ID) (result discord.Guild, err error) {
+	err = c.Do(ctx, http.MethodGet, "/api/state/guilds/"+guildID.String(), nil, &result)
+
+	return result, err
+}
+
+// GuildSync returns a guild and everything belonging to it. Up to members
+// members with an ID greater than after are included. Pass NextMembers from
+// the response as after to fetch the next page.
+func (c *Client) GuildSync(ctx context.Context, guildID snowflake.ID, members int,
+	after snowflake.ID) (result structs.APIGuildSync, err error) {
+	query := url.Values{}
+	query.Set("members", strconv.Itoa(members))
+
+	if after != 0 {
+		query.Set("after", after.String())
+	}
+
+	err = c.Do(ctx, http.MethodGet, "/api/state/guilds/"+guildID.String()+"/sync?"+query.Encode(), nil, &result)
+
+	return result, err
+}
+
+// UpdateManager replaces the configuration of a manager. configuration
+// should be the full manager configuration as returned by Managers.
+func (c *Client) UpdateManager(ctx context.Context, configuration interface{}) (err error) {
+	return c.RPC(ctx, MethodManagerUpdate, configuration, nil)
+}
+
+// CreateManager creates a new manager.
+func (c *Client) CreateManager(ctx context.Context, event structs.RPCManagerCreateEvent) (err error) {
+	return c.RPC(ctx, MethodManagerCreate, event, nil)
+}
+
+// DeleteManager deletes a manager. Confirm must be equal to Manager.
+func (c *Client) DeleteManager(ctx context.Context, event structs.RPCManagerDeleteEvent) (err error) {
+	return c.RPC(ctx, MethodManagerDelete, event, nil)
+}
+
+// RestartManager restarts a manager. Confirm must be equal to Manager.
+func (c *Client) RestartManager(ctx context.Context, event structs.RPCManagerRestartEvent) (err error) {
+	return c.RPC(ctx, MethodManagerRestart, event, nil)
+}
+
+// RefreshGateway refreshes the /gateway/bot response of a manager.
+func (c *Client) RefreshGateway(ctx context.Context, manager string) (err error) {
+	return c.RPC(ctx, MethodManagerRefreshGateway, structs.RPCManagerRefreshGatewayEvent{
+		Manager: manager,
+	}, nil)
+}
+
+// RestartProducer reconnects the producer of a manager without restarting
+// its shards.
+func (c *Client) RestartProducer(ctx context.Context,
+	manager string) (result structs.RPCManagerRebuildResponse, err error) {
+	err = c.RPC(ctx, MethodManagerProducerRestart, structs.RPCManagerRebuildEvent{
+		Manager: manager,
+	}, &result)
+
+	return result, err
+}
+
+// ResetClient rebuilds the REST client of a manager, resolving RestTunnel
+// again, and refreshes its /gateway/bot response without restarting its shards.
+func (c *Client) ResetClient(ctx context.Context,
+	manager string) (result structs.RPCManagerRebuildResponse, err error) {
+	err = c.RPC(ctx, MethodManagerClientReset, structs.RPCManagerRebuildEvent{
+		Manager: manager,
+	}, &result)
+
+	return result, err
+}
+
+// RetryChunkFailures requeues guilds of a manager which have exhausted their
+// chunk retries. If guildIDs is empty, every exhausted guild is requeued.
+func (c *Client) RetryChunkFailures(ctx context.Context, manager string,
+	guildIDs []snowflake.ID) (result structs.RPCManagerChunkRetryResponse, err error) {
+	err = c.RPC(ctx, MethodManagerChunkRetry, structs.RPCManagerChunkRetryEvent{
+		Manager:  manager,
+		GuildIDs: guildIDs,
+	}, &result)
+
+	return result, err
+}
+
+// ResetEventErrors resets the event error counters of a manager. If
+// eventTypes is empty, every counter is reset. Returns how many counters
+// were reset.
+func (c *Client) ResetEventErrors(ctx context.Context, manager string,
+	eventTypes []string) (reset int, err error) {
+	err = c.RPC(ctx, MethodManagerErrorsReset, structs.RPCManagerErrorsResetEvent{
+		Manager:    manager,
+		EventTypes: eventTypes,
+	}, &reset)
+
+	return reset, err
+}
+
+// ChunkGuild requests the members of a guild. If wait is set, the response is
+// sent once chunking has finished.
+func (c *Client) ChunkGuild(ctx context.Context, manager string, guildID snowflake.ID,
+	wait bool) (result structs.RPCManagerGuildChunkResponse, err error) {
+	err = c.RPC(ctx, MethodManagerGuildChunk, structs.RPCManagerGuildChunkEvent{
+		Manager: manager,
+		GuildID: guildID,
+		Wait:    wait,
+	}, &result)
+
+	return result, err
+}
+
+// UpdateVoiceState joins, moves or leaves a voice channel in a guild. The
+// VOICE_SERVER_UPDATE that follows is dispatched by the shard returned.
+func (c *Client) UpdateVoiceState(ctx context.Context,
+	event structs.RPCManagerVoiceStateUpdateEvent) (result structs.RPCManagerVoiceStateUpdateResponse, err error) {
+	err = c.RPC(ctx, MethodManagerVoiceStateUpdate, event, &result)
+
+	return result, err
+}
+
+// MutualGuilds returns a page of the guilds a user shares with a manager.
+func (c *Client) MutualGuilds(ctx context.Context,
+	event structs.RPCManagerMutualGuildsEvent) (result structs.APIMutualGuilds, err error) {
+	err = c.RPC(ctx, MethodManagerMutualGuilds, event, &result)
+
+	return result, err
+}
+
+// RequeueUnacked publishes dead lettered events of a manager again. If
+// eventIDs is empty, every dead lettered event is requeued.
+func (c *Client) RequeueUnacked(ctx context.Context, manager string,
+	eventIDs []int64) (result structs.RPCManagerUnackedResponse, err error) {
+	err = c.RPC(ctx, MethodManagerUnackedRequeue, structs.RPCManagerUnackedEvent{
+		Manager:  manager,
+		EventIDs: eventIDs,
+	}, &result)
+
+	return result, err
+}
+
+// DiscardUnacked removes dead lettered events of a manager. If eventIDs is
+// empty, every dead lettered event is removed.
+func (c *Client) DiscardUnacked(ctx context.Context, manager string,
+	eventIDs []int64) (result structs.RPCManagerUnackedResponse, err error) {
+	err = c.RPC(ctx, MethodManagerUnackedDiscard, structs.RPCManagerUnackedEvent{
+		Manager:  manager,
+		EventIDs: eventIDs,
+	}, &result)
+
+	return result, err
+}
+
+// EvaluateLeavePolicy evaluates the leave policy of a manager immediately.
+// If dryRun is set, the guilds which would be left are only reported.
+func (c *Client) EvaluateLeavePolicy(ctx context.Context, manager string,
+	dryRun bool) (report structs.LeavePolicyReport, err error) {
+	err = c.RPC(ctx, MethodManagerLeavePolicyEvaluate, structs.RPCManagerLeavePolicyEvent{
+		Manager: manager,
+		DryRun:  dryRun,
+	}, &report)
+
+	return report, err
+}
+
+// SetGuildAffinity overrides the affinity tag stamped onto events of guilds
+// for ttl. A ttl of 0 uses the default of the manager and an empty tag
+// removes the overrides.
+func (c *Client) SetGuildAffinity(ctx context.Context, manager string, guildIDs []snowflake.ID,
+	tag string, ttl time.Duration) (result structs.RPCManagerAffinityResponse, err error) {
+	err = c.RPC(ctx, MethodManagerAffinitySet, structs.RPCManagerAffinityEvent{
+		Manager:  manager,
+		GuildIDs: guildIDs,
+		Tag:      tag,
+		TTL:      int(ttl.Seconds()),
+	}, &result)
+
+	return result, err
+}
+
+// RebalanceReport returns the load of each shard in the newest shardgroup of
+// a manager.
+func (c *Client) RebalanceReport(ctx context.Context, manager string) (report structs.RebalanceReport, err error) {
+	err = c.RPC(ctx, MethodManagerRebalanceReport, structs.RPCManagerRebalanceReportEvent{
+		Manager: manager,
+	}, &report)
+
+	return report, err
+}
+
+// Blacklist returns the entries and version of a manager blacklist.
+// list is either event or produce.
+func (c *Client) Blacklist(ctx context.Context, manager string,
+	list string) (result structs.RPCManagerBlacklistResponse, err error) {
+	err = c.RPC(ctx, MethodManagerBlacklistGet, structs.RPCManagerBlacklistEvent{
+		Manager: manager,
+		List:    list,
+	}, &result)
+
+	return result, err
+}
+
+// AddToBlacklist adds events to a manager blacklist. If version is not
+// empty, the request fails if the blacklist has changed since.
+func (c *Client) AddToBlacklist(ctx context.Context, manager string, list string,
+	events []string, version string) (result structs.RPCManagerBlacklistResponse, err error) {
+	err = c.RPC(ctx, MethodManagerBlacklistAdd, structs.RPCManagerBlacklistEvent{
+		Manager: manager,
+		List:    list,
+		Events:  events,
+		Version: version,
+	}, &result)
+
+	return result, err
+}
+
+// RemoveFromBlacklist removes events from a manager blacklist. If version is
+// not empty, the request fails if the blacklist has changed since.
+func (c *Client) RemoveFromBlacklist(ctx context.Context, manager string, list string,
+	events []string, version string) (result structs.RPCManagerBlacklistResponse, err error) {
+	err = c.RPC(ctx, MethodManagerBlacklistRemove, structs.RPCManagerBlacklistEvent{
+		Manager: manager,
+		List:    list,
+		Events:  events,
+		Version: version,
+	}, &result)
+
+	return result, err
+}
+
+// RestartShard reconnects a single shard and waits up to the timeout of event
+// for it to be ready. The shard resumes its session unless Force is set.
+func (c *Client) RestartShard(ctx context.Context,
+	event structs.RPCManagerShardRestartEvent) (result structs.RPCManagerShardRestartResponse, err error) {
+	err = c.RPC(ctx, MethodShardRestart, event, &result)
+
+	return result, err
+}
+
+// SendToShard sends a raw gateway payload through a shard. Ops which change
+// the state of the shard are refused unless AllowDangerous is set.
+func (c *Client) SendToShard(ctx context.Context,
+	event structs.RPCManagerShardSendEvent) (result structs.RPCManagerShardSendResponse, err error) {
+	err = c.RPC(ctx, MethodShardSend, event, &result)
+
+	return result, err
+}
+
+// UpdateShardStatus updates the presence of shards of a manager.
+func (c *Client) UpdateShardStatus(ctx context.Context,
+	event structs.RPCManagerStatusUpdateEvent) (result structs.RPCManagerStatusUpdateResponse, err error) {
+	err = c.RPC(ctx, MethodShardStatusUpdate, event, &result)
+
+	return result, err
+}
+
+// SetShardLogLevel logs a shard at a different level for a while. An empty
+// Level reverts the shard to its normal level.
+func (c *Client) SetShardLogLevel(ctx context.Context,
+	event structs.RPCShardLogLevelEvent) (result structs.ShardLogs, err error) {
+	err = c.RPC(ctx, MethodShardLogLevel, event, &result)
+
+	return result, err
+}
+
+// CreateShardGroup creates and optionally starts a new shardgroup. This
+// is also used to scale a manager to a new shard count. The shardgroup is
+// started in the background and its progress can be followed with JobStatus.
+func (c *Client) CreateShardGroup(ctx context.Context,
+	event structs.RPCManagerShardGroupCreateEvent) (result structs.RPCManagerShardGroupCreateResponse, err error) {
+	err = c.RPC(ctx, MethodShardGroupCreate, event, &result)
+
+	return result, err
+}
+
+// RollShardGroup replaces a shardgroup with a new one as a job which can be
+// followed with JobStatus.
+func (c *Client) RollShardGroup(ctx context.Context,
+	event structs.RPCManagerShardGroupRollEvent) (result structs.RPCManagerShardGroupRollResponse, err error) {
+	err = c.RPC(ctx, MethodShardGroupRoll, event, &result)
+
+	return result, err
+}
+
+// JobStatus returns a job with the progress of each of its shards.
+func (c *Client) JobStatus(ctx context.Context, id snowflake.ID) (result structs.Job, err error) {
+	err = c.RPC(ctx, MethodJobStatus, structs.RPCJobStatusEvent{
+		ID: id,
+	}, &result)
+
+	return result, err
+}
+
+// Jobs returns every job which has not been dismissed or expired.
+func (c *Client) Jobs(ctx context.Context) (result []structs.Job, err error) {
+	err = c.RPC(ctx, MethodJobStatus, structs.RPCJobStatusEvent{}, &result)
+
+	return result, err
+}
+
+// DismissJob removes a finished job or cancels a job that has not started.
+func (c *Client) DismissJob(ctx context.Context, id snowflake.ID) (err error) {
+	return c.RPC(ctx, MethodJobDismiss, structs.RPCJobDismissEvent{
+		ID: id,
+	}, nil)
+}
+
+// StopShardGroup stops a shardgroup.
+func (c *Client) StopShardGroup(ctx context.Context, manager string, shardGroup int32) (err error) {
+	return c.RPC(ctx, MethodShardGroupStop, structs.RPCManagerShardGroupStopEvent{
+		Manager:    manager,
+		ShardGroup: shardGroup,
+	}, nil)
+}
+
+// DeleteShardGroup removes a stopped shardgroup.
+func (c *Client) DeleteShardGroup(ctx context.Context, manager string, shardGroup int32) (err error) {
+	return c.RPC(ctx, MethodShardGroupDelete, structs.RPCManagerShardGroupDeleteEvent{
+		Manager:    manager,
+		ShardGroup: shardGroup,
+	}, nil)
+}
+
+// Methods returns every RPC method the daemon has with an example of the
+// data each expects.
+func (c *Client) Methods(ctx context.Context) (methods []structs.RPCMethod, err error) {
+	err = c.RPC(ctx, MethodDaemonMethods, nil, &methods)
+
+	return methods, err
+}
+
+// VerifyRestTunnel returns if RestTunnel is reachable by the daemon.
+func (c *Client) VerifyRestTunnel(ctx context.Context) (enabled bool, err error) {
+	err = c.RPC(ctx, MethodDaemonVerifyRestTunnel, nil, &enabled)
+
+	return enabled, err
+}
+
+// UpdateDaemon replaces the daemon configuration. configuration should be
+// the full daemon configuration as returned by Configuration.
+func (c *Client) UpdateDaemon(ctx context.Context, configuration interface{}) (err error) {
+	return c.RPC(ctx, MethodDaemonUpdate, configuration, nil)
+}
+
+// Maintenance starts maintenance for a manager, or all managers if manager
+// is empty, for the duration provided. A duration of 0 ends maintenance.
+func (c *Client) Maintenance(ctx context.Context, manager string, duration time.Duration, reason string) (err error) {
+	return c.RPC(ctx, MethodDaemonMaintenance, structs.RPCDaemonMaintenanceEvent{
+		Manager:  manager,
+		Duration: int64(duration.Seconds()),
+		Reason:   reason,
+	}, nil)
+}
+
+// Validate returns warnings about every manager configuration.
+func (c *Client) Validate(ctx context.Context) (warnings []structs.ConfigurationWarning, err error) {
+	err = c.RPC(ctx, MethodDaemonValidate, nil, &warnings)
+
+	return warnings, err
+}
+
+// ValidateManager returns warnings about a manager configuration without
+// applying it. configuration should be the full manager configuration.
+func (c *Client) ValidateManager(ctx context.Context,
+	configuration interface{}) (warnings []structs.ConfigurationWarning, err error) {
+	err = c.RPC(ctx, MethodDaemonValidate, configuration, &warnings)
+
+	return warnings, err
+}
+
+// AddWebhook adds a webhook to the daemon.
+func (c *Client) AddWebhook(ctx context.Context, webhookURL string) (err error) {
+	return c.RPC(ctx, MethodDaemonAddWebhook, webhookURL, nil)
+}
+
+// TestWebhook sends a test message to a webhook.
+func (c *Client) TestWebhook(ctx context.Context, webhookURL string) (err error) {
+	return c.RPC(ctx, MethodDaemonTestWebhook, webhookURL, nil)
+}
+
+// RemoveWebhook removes a webhook from the daemon.
+func (c *Client) RemoveWebhook(ctx context.Context, webhookURL string) (err error) {
+	return c.RPC(ctx, MethodDaemonRemoveWebhook, webhookURL, nil)
+}
+
+// StartCapture starts capturing the payloads a manager produces for up to
+// duration. If guildID is not 0, only payloads for that guild are captured
+// and if events is not empty, only those events are captured.
+func (c *Client) StartCapture(ctx context.Context, manager string, guildID snowflake.ID, events []string,
+	duration time.Duration, maxEvents int) (result structs.EventCapture, err error) {
+	err = c.RPC(ctx, MethodManagerCapture, structs.RPCManagerCaptureEvent{
+		Manager:   manager,
+		GuildID:   guildID,
+		Events:    events,
+		Duration:  int64(duration.Seconds()),
+		MaxEvents: maxEvents,
+	}, &result)
+
+	return result, err
+}
+
+// FetchCapture returns and clears the payloads buffered by the capture of
+// a manager.
+func (c *Client) FetchCapture(ctx context.Context,
+	manager string) (result structs.RPCManagerCaptureFetchResponse, err error) {
+	err = c.RPC(ctx, MethodManagerCaptureFetch, structs.RPCManagerCaptureFetchEvent{
+		Manager: manager,
+	}, &result)
+
+	return result, err
+}
@@ -0,0 +1,293 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	gateway "github.com/TheRockettek/Sandwich-Daemon/internal"
+	"github.com/TheRockettek/Sandwich-Daemon/internal/discordtest"
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/xerrors"
+)
+
+const (
+	testAPIToken = "api-token"
+	testManager  = "test"
+	testToken    = "NzkyNzE1NDU0MTk2MDg4ODQy.X-hvzA.Ovy4MCQywSkoMRRclStW4xAYK7I"
+
+	testGuildID snowflake.ID = 100
+
+	// How long the shards of the test manager have to become ready.
+	readyTimeout = 15 * time.Second
+)
+
+var errStopSubscribe = xerrors.New("stop subscribing")
+
+// clientMethods is every RPC method constant of the client. It is compared to
+// the methods sandwich registers so either side missing one fails.
+var clientMethods = []string{
+	MethodManagerUpdate,
+	MethodManagerCreate,
+	MethodManagerDelete,
+	MethodManagerRestart,
+	MethodManagerRefreshGateway,
+	MethodManagerProducerRestart,
+	MethodManagerClientReset,
+	MethodManagerBlacklistGet,
+	MethodManagerBlacklistAdd,
+	MethodManagerBlacklistRemove,
+	MethodManagerCapture,
+	MethodManagerCaptureFetch,
+	MethodManagerChunkRetry,
+	MethodManagerErrorsReset,
+	MethodManagerGuildChunk,
+	MethodManagerVoiceStateUpdate,
+	MethodManagerMutualGuilds,
+	MethodManagerUnackedRequeue,
+	MethodManagerUnackedDiscard,
+	MethodManagerLeavePolicyEvaluate,
+	MethodManagerAffinitySet,
+	MethodManagerRebalanceReport,
+	MethodShardRestart,
+	MethodShardSend,
+	MethodShardStatusUpdate,
+	MethodShardLogLevel,
+	MethodShardGroupCreate,
+	MethodShardGroupStop,
+	MethodShardGroupDelete,
+	MethodShardGroupRoll,
+	MethodJobStatus,
+	MethodJobDismiss,
+	MethodDaemonJobStatus,
+	MethodDaemonMethods,
+	MethodDaemonVerifyRestTunnel,
+	MethodDaemonUpdate,
+	MethodDaemonMaintenance,
+	MethodDaemonValidate,
+	MethodDaemonAddWebhook,
+	MethodDaemonTestWebhook,
+	MethodDaemonRemoveWebhook,
+}
+
+// testSandwich is a sandwich serving its real HTTP API with a manager whose
+// only shard is connected to a fake discord.
+type testSandwich struct {
+	sg      *gateway.Sandwich
+	discord *discordtest.Server
+	url     string
+}
+
+func newTestSandwich(t *testing.T) *testSandwich {
+	t.Helper()
+
+	fake := discordtest.NewServer(t, &discord.Guild{ID: testGuildID, Name: "guild"})
+
+	configuration := &gateway.SandwichConfiguration{}
+	configuration.Logging.Level = "error"
+	configuration.Producer.Type = "none"
+	configuration.GRPC.Network = "tcp"
+	configuration.GRPC.Host = "127.0.0.1:0"
+	configuration.HTTP.Enabled = true
+	configuration.HTTP.Host = "127.0.0.1:0"
+	configuration.HTTP.APITokens = []gateway.APIToken{{Name: "test", Token: testAPIToken}}
+	configuration.RestTunnel.Enabled = true
+	configuration.RestTunnel.URL = fake.URL
+
+	manager := &gateway.ManagerConfiguration{Identifier: testManager, Token: testToken}
+	manager.Messaging.ClientName = testManager
+	manager.Sharding.ShardCount = 1
+	configuration.Managers = []*gateway.ManagerConfiguration{manager}
+
+	sg, err := gateway.NewSandwichWithConfiguration(ioutil.Discard, configuration)
+	if err != nil {
+		t.Fatalf("failed to create sandwich: %v", err)
+	}
+
+	if err = sg.Open(); err != nil {
+		t.Fatalf("failed to open sandwich: %v", err)
+	}
+
+	t.Cleanup(func() { _ = sg.Shutdown() })
+
+	sg.ManagersMu.RLock()
+	mg := sg.Managers[testManager]
+	sg.ManagersMu.RUnlock()
+
+	ready, err := mg.StartShards()
+	if err != nil {
+		t.Fatalf("failed to start shards: %v", err)
+	}
+
+	select {
+	case <-ready:
+	case <-time.After(readyTimeout):
+		t.Fatal("shards did not become ready")
+	}
+
+	// Open serves the API on a port which cannot be found out so it is
+	// served again on a listener of the test.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := &fasthttp.Server{Handler: sg.HandleRequest}
+
+	go func() { _ = server.Serve(listener) }()
+
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return &testSandwich{sg: sg, discord: fake, url: "http://" + listener.Addr().String()}
+}
+
+// apiError returns the status code of an APIError or 0 if err is not one.
+func apiError(err error) int {
+	apiErr := &APIError{}
+	if !xerrors.As(err, &apiErr) {
+		return 0
+	}
+
+	return apiErr.StatusCode
+}
+
+func TestSandwich(t *testing.T) {
+	ts := newTestSandwich(t)
+
+	c := testClient(ts.url, WithToken(testAPIToken))
+	anonymous := testClient(ts.url)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	t.Run("Status", func(t *testing.T) {
+		status, err := anonymous.Status(ctx)
+		if err != nil {
+			t.Fatalf("status failed: %v", err)
+		}
+
+		if len(status.Managers) != 1 || len(status.Managers[0].ShardGroups) != 1 {
+			t.Fatalf("status was %+v", status)
+		}
+
+		shards := status.Managers[0].ShardGroups[0].Shards
+		if len(shards) != 1 || shards[0].Status != structs.ShardReady {
+			t.Errorf("shards were %+v", shards)
+		}
+	})
+
+	t.Run("RPC", func(t *testing.T) {
+		if _, err := c.Validate(ctx); err != nil {
+			t.Errorf("validate failed: %v", err)
+		}
+
+		if _, err := anonymous.Validate(ctx); apiError(err) != http.StatusForbidden {
+			t.Errorf("unauthenticated validate returned %v", err)
+		}
+
+		if err := c.RPC(ctx, "unknown", nil, nil); apiError(err) != http.StatusBadRequest {
+			t.Errorf("unknown method returned %v", err)
+		}
+	})
+
+	t.Run("Methods", func(t *testing.T) {
+		methods, err := c.Methods(ctx)
+		if err != nil {
+			t.Fatalf("methods failed: %v", err)
+		}
+
+		known := make(map[string]bool, len(clientMethods))
+		for _, method := range clientMethods {
+			known[method] = true
+		}
+
+		registered := make(map[string]bool, len(methods))
+
+		for _, method := range methods {
+			registered[method.Method] = true
+
+			if !known[method.Method] {
+				t.Errorf("client has no constant for %s", method.Method)
+			}
+		}
+
+		for _, method := range clientMethods {
+			if !registered[method] {
+				t.Errorf("%s is not registered by sandwich", method)
+			}
+		}
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		updates := 0
+
+		err := c.Subscribe(ctx, func(result structs.APISubscribeResult) error {
+			updates++
+
+			if _, ok := result.Managers[testManager]; !ok {
+				t.Errorf("update did not include the manager: %+v", result.Managers)
+			}
+
+			return errStopSubscribe
+		})
+		if !xerrors.Is(err, errStopSubscribe) {
+			t.Fatalf("subscribe returned %v", err)
+		}
+
+		if updates != 1 {
+			t.Errorf("received %d updates", updates)
+		}
+
+		if err = anonymous.Subscribe(ctx, func(structs.APISubscribeResult) error {
+			return errStopSubscribe
+		}); err == nil || xerrors.Is(err, errStopSubscribe) {
+			t.Errorf("unauthenticated subscribe returned %v", err)
+		}
+	})
+
+	t.Run("StateGuild", func(t *testing.T) {
+		guild, err := c.StateGuild(ctx, testGuildID)
+		if err != nil {
+			t.Fatalf("state guild failed: %v", err)
+		}
+
+		if guild.ID != testGuildID || guild.Name != "guild" {
+			t.Errorf("guild was %+v", guild)
+		}
+
+		if _, err = c.StateGuild(ctx, testGuildID+1); apiError(err) != http.StatusNotFound {
+			t.Errorf("unknown guild returned %v", err)
+		}
+	})
+
+	t.Run("RestartShard", func(t *testing.T) {
+		identifies, resumes := ts.discord.Identifies(), ts.discord.Resumes()
+
+		result, err := c.RestartShard(ctx, structs.RPCManagerShardRestartEvent{
+			Manager: testManager,
+			Shard:   0,
+			Timeout: int(readyTimeout.Seconds()),
+		})
+		if err != nil {
+			t.Fatalf("restart shard failed: %v", err)
+		}
+
+		if !result.Ready || result.Status != structs.ShardReady || result.Error != "" {
+			t.Errorf("restart returned %+v", result)
+		}
+
+		if got := ts.discord.Resumes(); got != resumes+1 {
+			t.Errorf("shard resumed %d times, want 1", got-resumes)
+		}
+
+		if got := ts.discord.Identifies(); got != identifies {
+			t.Errorf("shard identified %d times, want 0", got-identifies)
+		}
+	})
+}
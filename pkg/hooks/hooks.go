@@ -0,0 +1,145 @@
+// Package hooks embeds sandwich in another Go program. Payloads are received
+// by listeners in-process instead of, or as well as, through a producer and
+// managers are controlled directly instead of through the HTTP API.
+//
+// Set the producer type to "none" to only deliver payloads to listeners.
+// The HTTP and gRPC servers still start as configured.
+package hooks
+
+import (
+	"context"
+	"io"
+
+	gateway "github.com/TheRockettek/Sandwich-Daemon/internal"
+	"github.com/TheRockettek/Sandwich-Daemon/structs"
+	"golang.org/x/xerrors"
+)
+
+// ErrUnknownManager is returned when no manager has the identifier given.
+var ErrUnknownManager = xerrors.New("no manager with this identifier")
+
+type (
+	// Sandwich is an instance of the daemon.
+	Sandwich = gateway.Sandwich
+
+	// Manager is a bot within a Sandwich.
+	Manager = gateway.Manager
+
+	// Configuration is the configuration of a Sandwich. It is normally
+	// loaded from sandwich.yaml.
+	Configuration = gateway.SandwichConfiguration
+
+	// ManagerConfiguration is the configuration of a single manager.
+	ManagerConfiguration = gateway.ManagerConfiguration
+
+	// Listener receives every payload produced by any manager. The payload
+	// is reused once the listener returns.
+	Listener = gateway.EventHook
+
+	// Payload is a payload produced by a manager.
+	Payload = structs.SandwichPayload
+)
+
+// New creates a Sandwich from configuration. Call Open to start it and the
+// managers which auto start.
+func New(logger io.Writer, configuration *Configuration) (sg *Sandwich, err error) {
+	sg, err = gateway.NewSandwichWithConfiguration(logger, configuration)
+	if err != nil {
+		return nil, xerrors.Errorf("hooks new: %w", err)
+	}
+
+	return sg, nil
+}
+
+// Listen registers a listener on sg. The returned function removes it.
+func Listen(sg *Sandwich, listener Listener) (remove func()) {
+	return sg.AddEventHook(listener)
+}
+
+// ListenFunc registers a listener which only receives payloads of the event
+// types given. If none are given, every payload is received.
+func ListenFunc(sg *Sandwich, listener func(ctx context.Context, packet *Payload) error,
+	eventTypes ...string) (remove func()) {
+	if len(eventTypes) == 0 {
+		return sg.AddEventHook(listener)
+	}
+
+	types := make(map[string]bool, len(eventTypes))
+	for _, eventType := range eventTypes {
+		types[eventType] = true
+	}
+
+	return sg.AddEventHook(func(ctx context.Context, packet *Payload) error {
+		if !types[packet.Type] {
+			return nil
+		}
+
+		return listener(ctx, packet)
+	})
+}
+
+// GetManager returns the manager with an identifier.
+func GetManager(sg *Sandwich, identifier string) (mg *Manager, err error) {
+	sg.ManagersMu.RLock()
+	mg, ok := sg.Managers[identifier]
+	sg.ManagersMu.RUnlock()
+
+	if !ok {
+		return nil, ErrUnknownManager
+	}
+
+	return mg, nil
+}
+
+// StartManager starts the shards of a manager which did not auto start.
+// ready is signalled once every shard is ready.
+func StartManager(sg *Sandwich, identifier string) (ready chan bool, err error) {
+	mg, err := GetManager(sg, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	ready, err = mg.StartShards()
+	if err != nil {
+		return nil, xerrors.Errorf("hooks start manager: %w", err)
+	}
+
+	return ready, nil
+}
+
+// StopManager closes every ShardGroup of a manager. The manager and its
+// producer are kept so it can be started again.
+func StopManager(sg *Sandwich, identifier string) (err error) {
+	mg, err := GetManager(sg, identifier)
+	if err != nil {
+		return err
+	}
+
+	mg.ShardGroupsMu.RLock()
+	for _, shardGroup := range mg.ShardGroups {
+		shardGroup.Close()
+	}
+	mg.ShardGroupsMu.RUnlock()
+
+	return nil
+}
+
+// ScaleManager starts a new ShardGroup with shardCount shards which replaces
+// the current ShardGroups of the manager.
+func ScaleManager(sg *Sandwich, identifier string, shardCount int) (ready chan bool, err error) {
+	mg, err := GetManager(sg, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if shardCount < 1 {
+		return nil, xerrors.New("hooks scale manager: shard count must be at least 1")
+	}
+
+	ready, err = mg.Scale(mg.GenerateShardIDs(shardCount), shardCount, true)
+	if err != nil {
+		return nil, xerrors.Errorf("hooks scale manager: %w", err)
+	}
+
+	return ready, nil
+}
@@ -0,0 +1,167 @@
+package hooks
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/internal/discordtest"
+	"golang.org/x/xerrors"
+)
+
+const (
+	testManager = "embedded"
+	testToken   = "NzkyNzE1NDU0MTk2MDg4ODQy.X-hvzA.Ovy4MCQywSkoMRRclStW4xAYK7I"
+
+	// How long the shards of the manager have to become ready.
+	readyTimeout = 15 * time.Second
+)
+
+// received is a payload seen by a listener. The payload itself is reused
+// once the listener returns.
+type received struct {
+	identifier string
+	eventType  string
+	data       string
+}
+
+// newEmbedded opens a sandwich configured like examples/embedded whose
+// manager is started against a fake discord.
+func newEmbedded(t *testing.T) (*Sandwich, *discordtest.Server) {
+	t.Helper()
+
+	fake := discordtest.NewServer(t)
+
+	configuration := &Configuration{}
+	configuration.Logging.Level = "error"
+	configuration.Producer.Type = "none"
+	configuration.GRPC.Network = "tcp"
+	configuration.GRPC.Host = "127.0.0.1:0"
+	configuration.HTTP.Host = "127.0.0.1:0"
+	configuration.RestTunnel.Enabled = true
+	configuration.RestTunnel.URL = fake.URL
+
+	manager := &ManagerConfiguration{
+		Identifier:  testManager,
+		DisplayName: "Embedded",
+		Token:       testToken,
+	}
+	manager.Messaging.ClientName = testManager
+	manager.Sharding.AutoSharded = true
+
+	configuration.Managers = []*ManagerConfiguration{manager}
+
+	sg, err := New(ioutil.Discard, configuration)
+	if err != nil {
+		t.Fatalf("failed to create sandwich: %v", err)
+	}
+
+	if err = sg.Open(); err != nil {
+		t.Fatalf("failed to open sandwich: %v", err)
+	}
+
+	t.Cleanup(func() { _ = sg.Close() })
+
+	return sg, fake
+}
+
+// listen returns a listener which sends what it receives to a channel.
+func listen(events chan<- received) func(ctx context.Context, packet *Payload) error {
+	return func(ctx context.Context, packet *Payload) error {
+		events <- received{
+			identifier: packet.Metadata.Identifier,
+			eventType:  packet.Type,
+			data:       string(packet.ReceivedPayload.Data),
+		}
+
+		return nil
+	}
+}
+
+// next returns the next payload of eventType, skipping any other.
+func next(t *testing.T, events <-chan received, eventType string) received {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case event := <-events:
+			if event.eventType == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no %s was received", eventType)
+		}
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	sg, fake := newEmbedded(t)
+
+	messages := make(chan received, 64)
+	removeMessages := ListenFunc(sg, listen(messages), "MESSAGE_CREATE")
+
+	all := make(chan received, 64)
+	removeAll := Listen(sg, listen(all))
+
+	defer removeAll()
+
+	if _, err := GetManager(sg, "unknown"); !xerrors.Is(err, ErrUnknownManager) {
+		t.Errorf("unknown manager returned %v", err)
+	}
+
+	ready, err := StartManager(sg, testManager)
+	if err != nil {
+		t.Fatalf("failed to start manager: %v", err)
+	}
+
+	select {
+	case <-ready:
+	case <-time.After(readyTimeout):
+		t.Fatal("manager did not become ready")
+	}
+
+	// The status of the shard starting is produced to every listener but
+	// the filtered one.
+	next(t, all, "SHARD_STATUS")
+
+	message := `{"id":"300","channel_id":"200","content":"hello"}`
+
+	if _, err = fake.Dispatch("MESSAGE_CREATE", stdjson.RawMessage(message)); err != nil {
+		t.Fatalf("failed to dispatch: %v", err)
+	}
+
+	if event := next(t, all, "MESSAGE_CREATE"); event.identifier != testManager || event.data != message {
+		t.Errorf("listener received %+v", event)
+	}
+
+	// Listeners run in the order they were added so the filtered listener
+	// has already seen every event.
+	select {
+	case event := <-messages:
+		if event.eventType != "MESSAGE_CREATE" || event.identifier != testManager || event.data != message {
+			t.Errorf("filtered listener received %+v", event)
+		}
+	default:
+		t.Fatal("filtered listener did not receive MESSAGE_CREATE")
+	}
+
+	if len(messages) != 0 {
+		t.Errorf("filtered listener received %+v", <-messages)
+	}
+
+	removeMessages()
+
+	if _, err = fake.Dispatch("MESSAGE_CREATE", stdjson.RawMessage(message)); err != nil {
+		t.Fatalf("failed to dispatch: %v", err)
+	}
+
+	next(t, all, "MESSAGE_CREATE")
+
+	if len(messages) != 0 {
+		t.Errorf("removed listener received %+v", <-messages)
+	}
+}
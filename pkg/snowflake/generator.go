@@ -0,0 +1,76 @@
+package snowflake
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// DiscordEpoch is the first second of 2015 in milliseconds which discord uses.
+const DiscordEpoch int64 = 1420070400000
+
+// MaxWorkerID is the largest worker ID a Generator can use.
+const MaxWorkerID = generatorWorkerMax
+
+const (
+	generatorWorkerBits = 10
+	generatorStepBits   = 12
+
+	generatorWorkerMax = -1 ^ (-1 << generatorWorkerBits)
+	generatorStepMask  = -1 ^ (-1 << generatorStepBits)
+	generatorTimeShift = generatorWorkerBits + generatorStepBits
+)
+
+// Generator creates unique snowflake IDs without locking. Unlike Node, the
+// epoch is set per generator so it can be used alongside other epochs.
+type Generator struct {
+	epoch  int64
+	worker int64
+
+	// Last time and step used, packed as time<<generatorStepBits | step.
+	state *int64
+}
+
+// NewGenerator creates a Generator with an epoch in milliseconds and a
+// worker ID between 0 and 1023. If epoch is 0, DiscordEpoch is used.
+func NewGenerator(epoch int64, worker int64) (*Generator, error) {
+	if worker < 0 || worker > generatorWorkerMax {
+		return nil, errors.New("Worker number must be between 0 and " + strconv.FormatInt(generatorWorkerMax, 10))
+	}
+
+	if epoch == 0 {
+		epoch = DiscordEpoch
+	}
+
+	return &Generator{
+		epoch:  epoch,
+		worker: worker,
+		state:  new(int64),
+	}, nil
+}
+
+// Generate returns a unique ID. If the step is exhausted within a millisecond,
+// the next millisecond is used instead of waiting.
+func (g *Generator) Generate() ID {
+	for {
+		old := atomic.LoadInt64(g.state)
+		now := time.Now().UnixNano()/int64(time.Millisecond) - g.epoch
+
+		last := old >> generatorStepBits
+		step := old & generatorStepMask
+
+		switch {
+		case now > last:
+			last, step = now, 0
+		case step == generatorStepMask:
+			last, step = last+1, 0
+		default:
+			step++
+		}
+
+		if atomic.CompareAndSwapInt64(g.state, old, last<<generatorStepBits|step) {
+			return ID(last<<generatorTimeShift | g.worker<<generatorStepBits | step)
+		}
+	}
+}
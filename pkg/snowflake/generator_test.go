@@ -0,0 +1,68 @@
+package snowflake
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestGeneratorParallelUnique(t *testing.T) {
+	const (
+		goroutines = 8
+		perWorker  = 20000
+	)
+
+	generators := make([]*Generator, 0, 2)
+
+	for _, worker := range []int64{1, 2} {
+		g, err := NewGenerator(0, worker)
+		if err != nil {
+			t.Fatalf("failed to create generator: %v", err)
+		}
+
+		generators = append(generators, g)
+	}
+
+	ids := make(chan ID, goroutines*perWorker*len(generators))
+	wg := sync.WaitGroup{}
+
+	for _, g := range generators {
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+
+			go func(g *Generator) {
+				defer wg.Done()
+
+				for j := 0; j < perWorker; j++ {
+					ids <- g.Generate()
+				}
+			}(g)
+		}
+	}
+
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[ID]bool, cap(ids))
+
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("generated %d more than once", id)
+		}
+
+		seen[id] = true
+	}
+}
+
+func TestNewGeneratorWorkerRange(t *testing.T) {
+	if _, err := NewGenerator(0, -1); err == nil {
+		t.Error("worker -1 was allowed")
+	}
+
+	if _, err := NewGenerator(0, MaxWorkerID+1); err == nil {
+		t.Errorf("worker %d was allowed", MaxWorkerID+1)
+	}
+
+	if _, err := NewGenerator(0, MaxWorkerID); err != nil {
+		t.Errorf("worker %d was not allowed: %v", MaxWorkerID, err)
+	}
+}
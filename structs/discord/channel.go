@@ -14,8 +14,20 @@ const (
 	ChannelTypeGuildCategory
 	ChannelTypeGuildNews
 	ChannelTypeGuildStore
+	_
+	_
+	_
+	ChannelTypeGuildNewsThread
+	ChannelTypeGuildPublicThread
+	ChannelTypeGuildPrivateThread
+	ChannelTypeGuildStageVoice
 )
 
+// IsThread returns if the channel type is a thread.
+func (ct ChannelType) IsThread() bool {
+	return ct == ChannelTypeGuildNewsThread || ct == ChannelTypeGuildPublicThread || ct == ChannelTypeGuildPrivateThread
+}
+
 // Channel represents a Discord channel.
 type Channel struct {
 	ID                   snowflake.ID       `json:"id" msgpack:"id"`
@@ -36,6 +48,30 @@ type Channel struct {
 	ApplicationID        snowflake.ID       `json:"application_id,omitempty" msgpack:"application_id,omitempty"`
 	ParentID             snowflake.ID       `json:"parent_id,omitempty" msgpack:"parent_id,omitempty"`
 	LastPinTimestamp     string             `json:"last_pin_timestamp,omitempty" msgpack:"last_pin_timestamp,omitempty"`
+
+	// Only set for threads. Member is the thread member of the current user
+	// if they have joined the thread.
+	MessageCount   int             `json:"message_count,omitempty" msgpack:"message_count,omitempty"`
+	MemberCount    int             `json:"member_count,omitempty" msgpack:"member_count,omitempty"`
+	ThreadMetadata *ThreadMetadata `json:"thread_metadata,omitempty" msgpack:"thread_metadata,omitempty"`
+	Member         *ThreadMember   `json:"member,omitempty" msgpack:"member,omitempty"`
+}
+
+// ThreadMetadata represents the thread specific fields of a thread.
+type ThreadMetadata struct {
+	Archived            bool   `json:"archived" msgpack:"archived"`
+	AutoArchiveDuration int    `json:"auto_archive_duration" msgpack:"auto_archive_duration"` // Minutes
+	ArchiveTimestamp    string `json:"archive_timestamp" msgpack:"archive_timestamp"`
+	Locked              bool   `json:"locked" msgpack:"locked"`
+	Invitable           bool   `json:"invitable,omitempty" msgpack:"invitable,omitempty"`
+}
+
+// ThreadMember represents a user who has joined a thread.
+type ThreadMember struct {
+	ID            snowflake.ID `json:"id,omitempty" msgpack:"id,omitempty"` // ID of the thread
+	UserID        snowflake.ID `json:"user_id,omitempty" msgpack:"user_id,omitempty"`
+	JoinTimestamp string       `json:"join_timestamp" msgpack:"join_timestamp"`
+	Flags         int          `json:"flags" msgpack:"flags"`
 }
 
 // ChannelOverwrite represents a permission overwrite for a channel.
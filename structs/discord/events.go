@@ -17,6 +17,9 @@ type Ready struct {
 	User      *User    `json:"user" msgpack:"user"`
 	Guilds    []*Guild `json:"guilds" msgpack:"guilds"`
 	SessionID string   `json:"session_id" msgpack:"session_id"`
+
+	// Gateway URL resumes of this session must connect to.
+	ResumeGatewayURL string `json:"resume_gateway_url" msgpack:"resume_gateway_url"`
 }
 
 // Resume represents a resume packet.
@@ -57,10 +60,10 @@ type RequestGuildMembers struct {
 
 // UpdateVoiceState represents an update voice state packet.
 type UpdateVoiceState struct {
-	GuildID   snowflake.ID `json:"guild_id" msgpack:"guild_id"`
-	ChannelID snowflake.ID `json:"channel_id" msgpack:"channel_id"`
-	SelfMute  bool         `json:"self_mute" msgpack:"self_mute"`
-	SelfDeaf  bool         `json:"self_deaf" msgpack:"self_deaf"`
+	GuildID   snowflake.ID  `json:"guild_id" msgpack:"guild_id"`
+	ChannelID *snowflake.ID `json:"channel_id" msgpack:"channel_id"` // nil to leave the voice channel
+	SelfMute  bool          `json:"self_mute" msgpack:"self_mute"`
+	SelfDeaf  bool          `json:"self_deaf" msgpack:"self_deaf"`
 }
 
 // UpdateStatus represents an update status packet.
@@ -207,16 +210,56 @@ type GuildMembersChunk struct {
 	Nonce      string           `json:"nonce" msgpack:"nonce"`
 }
 
+// ThreadCreate represents a thread create packet.
+type ThreadCreate Channel
+
+// ThreadUpdate represents a thread update packet.
+type ThreadUpdate Channel
+
+// ThreadDelete represents a thread delete packet.
+type ThreadDelete struct {
+	ID       snowflake.ID `json:"id" msgpack:"id"`
+	GuildID  snowflake.ID `json:"guild_id" msgpack:"guild_id"`
+	ParentID snowflake.ID `json:"parent_id" msgpack:"parent_id"`
+	Type     ChannelType  `json:"type" msgpack:"type"`
+}
+
+// ThreadListSync represents a thread list sync packet. If ChannelIDs is
+// empty, Threads are every active thread of the guild, otherwise only those
+// of the listed parent channels.
+type ThreadListSync struct {
+	GuildID    snowflake.ID    `json:"guild_id" msgpack:"guild_id"`
+	ChannelIDs []snowflake.ID  `json:"channel_ids,omitempty" msgpack:"channel_ids,omitempty"`
+	Threads    []*Channel      `json:"threads" msgpack:"threads"`
+	Members    []*ThreadMember `json:"members" msgpack:"members"` // Thread members of the current user
+}
+
+// ThreadMemberUpdate represents a thread member update packet.
+type ThreadMemberUpdate struct {
+	ThreadMember
+
+	GuildID snowflake.ID `json:"guild_id" msgpack:"guild_id"`
+}
+
+// ThreadMembersUpdate represents a thread members update packet.
+type ThreadMembersUpdate struct {
+	ID               snowflake.ID    `json:"id" msgpack:"id"`
+	GuildID          snowflake.ID    `json:"guild_id" msgpack:"guild_id"`
+	MemberCount      int             `json:"member_count" msgpack:"member_count"`
+	AddedMembers     []*ThreadMember `json:"added_members,omitempty" msgpack:"added_members,omitempty"`
+	RemovedMemberIDs []snowflake.ID  `json:"removed_member_ids,omitempty" msgpack:"removed_member_ids,omitempty"`
+}
+
 // GuildRoleCreate represents a guild role create packet.
 type GuildRoleCreate struct {
 	GuildID snowflake.ID `json:"guild_id" msgpack:"guild_id"`
-	Role    interface{}  `json:"role" msgpack:"role"` // TODO: type
+	Role    *Role        `json:"role" msgpack:"role"`
 }
 
 // GuildRoleUpdate represents a guild role update packet.
 type GuildRoleUpdate struct {
 	GuildID snowflake.ID `json:"guild_id" msgpack:"guild_id"`
-	Role    *Role        `json:"role" msgpack:"role"` // TODO: type
+	Role    *Role        `json:"role" msgpack:"role"`
 }
 
 // GuildRoleDelete represents a guild role delete packet.
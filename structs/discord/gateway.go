@@ -98,6 +98,9 @@ type ReceivedPayload struct {
 	// Used for trace tracking
 	TraceTime time.Time      `json:"-" msgpack:"-"`
 	Trace     map[string]int `json:"-" msgpack:"-"`
+
+	// When the payload was read from the gateway
+	ReceivedAt time.Time `json:"-" msgpack:"-"`
 }
 
 // ReceivedPayload adds a trace entry and overwrites the current trace time.
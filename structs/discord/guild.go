@@ -107,6 +107,7 @@ type Guild struct {
 	VoiceStates []*VoiceState  `json:"voice_states,omitempty" msgpack:"voice_states,omitempty"`
 	Members     []*GuildMember `json:"members,omitempty" msgpack:"members,omitempty"`
 	Channels    []*Channel     `json:"channels,omitempty" msgpack:"channels,omitempty"`
+	Threads     []*Channel     `json:"threads,omitempty" msgpack:"threads,omitempty"`
 	Presences   []*Activity    `json:"presences,omitempty" msgpack:"presences,omitempty"`
 
 	MaxPresences  int         `json:"max_presences,omitempty" msgpack:"max_presences,omitempty"`
@@ -0,0 +1,100 @@
+package structs
+
+// EventIntents maps dispatch event types to the intents which cause them to
+// be sent. An event is sent if any of its intents are enabled. Event types
+// that are not listed are sent regardless of the intents used.
+var EventIntents = map[string]uint{
+	"GUILD_CREATE":          IntentGuilds,
+	"GUILD_UPDATE":          IntentGuilds,
+	"GUILD_DELETE":          IntentGuilds,
+	"GUILD_ROLE_CREATE":     IntentGuilds,
+	"GUILD_ROLE_UPDATE":     IntentGuilds,
+	"GUILD_ROLE_DELETE":     IntentGuilds,
+	"CHANNEL_CREATE":        IntentGuilds,
+	"CHANNEL_UPDATE":        IntentGuilds,
+	"CHANNEL_DELETE":        IntentGuilds,
+	"CHANNEL_PINS_UPDATE":   IntentGuilds | IntentDirectMessages,
+	"THREAD_CREATE":         IntentGuilds,
+	"THREAD_UPDATE":         IntentGuilds,
+	"THREAD_DELETE":         IntentGuilds,
+	"THREAD_LIST_SYNC":      IntentGuilds,
+	"THREAD_MEMBER_UPDATE":  IntentGuilds,
+	"STAGE_INSTANCE_CREATE": IntentGuilds,
+	"STAGE_INSTANCE_UPDATE": IntentGuilds,
+	"STAGE_INSTANCE_DELETE": IntentGuilds,
+
+	"GUILD_MEMBER_ADD":      IntentGuildMembers,
+	"GUILD_MEMBER_UPDATE":   IntentGuildMembers,
+	"GUILD_MEMBER_REMOVE":   IntentGuildMembers,
+	"THREAD_MEMBERS_UPDATE": IntentGuildMembers,
+
+	"GUILD_BAN_ADD":    IntentGuildBans,
+	"GUILD_BAN_REMOVE": IntentGuildBans,
+
+	"GUILD_EMOJIS_UPDATE":   IntentGuildEmojis,
+	"GUILD_STICKERS_UPDATE": IntentGuildEmojis,
+
+	"GUILD_INTEGRATIONS_UPDATE": IntentGuildIntegrations,
+	"INTEGRATION_CREATE":        IntentGuildIntegrations,
+	"INTEGRATION_UPDATE":        IntentGuildIntegrations,
+	"INTEGRATION_DELETE":        IntentGuildIntegrations,
+
+	"WEBHOOKS_UPDATE": IntentGuildWebhooks,
+
+	"INVITE_CREATE": IntentGuildInvites,
+	"INVITE_DELETE": IntentGuildInvites,
+
+	"VOICE_STATE_UPDATE": IntentGuildVoiceStates,
+
+	"PRESENCE_UPDATE": IntentGuildPresences,
+
+	"MESSAGE_CREATE":      IntentGuildMessages | IntentDirectMessages,
+	"MESSAGE_UPDATE":      IntentGuildMessages | IntentDirectMessages,
+	"MESSAGE_DELETE":      IntentGuildMessages | IntentDirectMessages,
+	"MESSAGE_DELETE_BULK": IntentGuildMessages,
+
+	"MESSAGE_REACTION_ADD":          IntentGuildMessageReactions | IntentDirectMessageReactions,
+	"MESSAGE_REACTION_REMOVE":       IntentGuildMessageReactions | IntentDirectMessageReactions,
+	"MESSAGE_REACTION_REMOVE_ALL":   IntentGuildMessageReactions | IntentDirectMessageReactions,
+	"MESSAGE_REACTION_REMOVE_EMOJI": IntentGuildMessageReactions | IntentDirectMessageReactions,
+
+	"TYPING_START": IntentGuildMessageTyping | IntentDirectMessageTyping,
+}
+
+// IntentNames is the name of each intent as used by discord.
+var IntentNames = map[uint]string{
+	IntentGuilds:                 "GUILDS",
+	IntentGuildMembers:           "GUILD_MEMBERS",
+	IntentGuildBans:              "GUILD_BANS",
+	IntentGuildEmojis:            "GUILD_EMOJIS",
+	IntentGuildIntegrations:      "GUILD_INTEGRATIONS",
+	IntentGuildWebhooks:          "GUILD_WEBHOOKS",
+	IntentGuildInvites:           "GUILD_INVITES",
+	IntentGuildVoiceStates:       "GUILD_VOICE_STATES",
+	IntentGuildPresences:         "GUILD_PRESENCES",
+	IntentGuildMessages:          "GUILD_MESSAGES",
+	IntentGuildMessageReactions:  "GUILD_MESSAGE_REACTIONS",
+	IntentGuildMessageTyping:     "GUILD_MESSAGE_TYPING",
+	IntentDirectMessages:         "DIRECT_MESSAGES",
+	IntentDirectMessageReactions: "DIRECT_MESSAGE_REACTIONS",
+	IntentDirectMessageTyping:    "DIRECT_MESSAGE_TYPING",
+}
+
+// EventReceived returns if an event type is sent when identifying with the
+// intents provided.
+func EventReceived(eventType string, intents uint) bool {
+	required, ok := EventIntents[eventType]
+
+	return !ok || intents&required != 0
+}
+
+// IntentsToNames returns the names of every intent in a bitfield.
+func IntentsToNames(intents uint) (names []string) {
+	for intent := IntentGuilds; intent <= IntentDirectMessageTyping; intent <<= 1 {
+		if intents&intent != 0 {
+			names = append(names, IntentNames[intent])
+		}
+	}
+
+	return names
+}
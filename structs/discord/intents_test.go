@@ -0,0 +1,142 @@
+package structs
+
+import (
+	"reflect"
+	"testing"
+)
+
+// allIntents is every intent EventReceived knows of.
+const allIntents = IntentDirectMessageTyping<<1 - 1
+
+func TestEventReceived(t *testing.T) {
+	guildMessages := []string{"GUILD_MESSAGES", "DIRECT_MESSAGES"}
+	reactions := []string{"GUILD_MESSAGE_REACTIONS", "DIRECT_MESSAGE_REACTIONS"}
+
+	tests := []struct {
+		eventType string
+		intents   []string // Any of these cause the event to be sent
+	}{
+		{"GUILD_CREATE", []string{"GUILDS"}},
+		{"GUILD_UPDATE", []string{"GUILDS"}},
+		{"GUILD_DELETE", []string{"GUILDS"}},
+		{"GUILD_ROLE_CREATE", []string{"GUILDS"}},
+		{"GUILD_ROLE_UPDATE", []string{"GUILDS"}},
+		{"GUILD_ROLE_DELETE", []string{"GUILDS"}},
+		{"CHANNEL_CREATE", []string{"GUILDS"}},
+		{"CHANNEL_UPDATE", []string{"GUILDS"}},
+		{"CHANNEL_DELETE", []string{"GUILDS"}},
+		{"CHANNEL_PINS_UPDATE", []string{"GUILDS", "DIRECT_MESSAGES"}},
+		{"THREAD_CREATE", []string{"GUILDS"}},
+		{"THREAD_UPDATE", []string{"GUILDS"}},
+		{"THREAD_DELETE", []string{"GUILDS"}},
+		{"THREAD_LIST_SYNC", []string{"GUILDS"}},
+		{"THREAD_MEMBER_UPDATE", []string{"GUILDS"}},
+		{"STAGE_INSTANCE_CREATE", []string{"GUILDS"}},
+		{"STAGE_INSTANCE_UPDATE", []string{"GUILDS"}},
+		{"STAGE_INSTANCE_DELETE", []string{"GUILDS"}},
+		{"GUILD_MEMBER_ADD", []string{"GUILD_MEMBERS"}},
+		{"GUILD_MEMBER_UPDATE", []string{"GUILD_MEMBERS"}},
+		{"GUILD_MEMBER_REMOVE", []string{"GUILD_MEMBERS"}},
+		{"THREAD_MEMBERS_UPDATE", []string{"GUILD_MEMBERS"}},
+		{"GUILD_BAN_ADD", []string{"GUILD_BANS"}},
+		{"GUILD_BAN_REMOVE", []string{"GUILD_BANS"}},
+		{"GUILD_EMOJIS_UPDATE", []string{"GUILD_EMOJIS"}},
+		{"GUILD_STICKERS_UPDATE", []string{"GUILD_EMOJIS"}},
+		{"GUILD_INTEGRATIONS_UPDATE", []string{"GUILD_INTEGRATIONS"}},
+		{"INTEGRATION_CREATE", []string{"GUILD_INTEGRATIONS"}},
+		{"INTEGRATION_UPDATE", []string{"GUILD_INTEGRATIONS"}},
+		{"INTEGRATION_DELETE", []string{"GUILD_INTEGRATIONS"}},
+		{"WEBHOOKS_UPDATE", []string{"GUILD_WEBHOOKS"}},
+		{"INVITE_CREATE", []string{"GUILD_INVITES"}},
+		{"INVITE_DELETE", []string{"GUILD_INVITES"}},
+		{"VOICE_STATE_UPDATE", []string{"GUILD_VOICE_STATES"}},
+		{"PRESENCE_UPDATE", []string{"GUILD_PRESENCES"}},
+		{"MESSAGE_CREATE", guildMessages},
+		{"MESSAGE_UPDATE", guildMessages},
+		{"MESSAGE_DELETE", guildMessages},
+		{"MESSAGE_DELETE_BULK", []string{"GUILD_MESSAGES"}},
+		{"MESSAGE_REACTION_ADD", reactions},
+		{"MESSAGE_REACTION_REMOVE", reactions},
+		{"MESSAGE_REACTION_REMOVE_ALL", reactions},
+		{"MESSAGE_REACTION_REMOVE_EMOJI", reactions},
+		{"TYPING_START", []string{"GUILD_MESSAGE_TYPING", "DIRECT_MESSAGE_TYPING"}},
+
+		// Events which are not listed are sent regardless of intents.
+		{"READY", nil},
+		{"RESUMED", nil},
+		{"USER_UPDATE", nil},
+		{"INTERACTION_CREATE", nil},
+		{"GUILD_MEMBERS_CHUNK", nil},
+		{"", nil},
+	}
+
+	intentsByName := make(map[string]uint, len(IntentNames))
+	for intent, name := range IntentNames {
+		intentsByName[name] = intent
+	}
+
+	tested := make(map[string]bool, len(tests))
+
+	for _, test := range tests {
+		tested[test.eventType] = true
+
+		var required uint
+
+		for _, name := range test.intents {
+			intent, ok := intentsByName[name]
+			if !ok {
+				t.Fatalf("%s: unknown intent %s", test.eventType, name)
+			}
+
+			required |= intent
+
+			if !EventReceived(test.eventType, intent) {
+				t.Errorf("%s is not received with %s", test.eventType, name)
+			}
+		}
+
+		if required == 0 {
+			if !EventReceived(test.eventType, 0) {
+				t.Errorf("%s is not received without intents", test.eventType)
+			}
+
+			continue
+		}
+
+		if EventReceived(test.eventType, allIntents&^required) {
+			t.Errorf("%s is received without %v", test.eventType, test.intents)
+		}
+
+		if names := IntentsToNames(EventIntents[test.eventType]); !reflect.DeepEqual(names, IntentsToNames(required)) {
+			t.Errorf("%s requires %v, want %v", test.eventType, names, test.intents)
+		}
+	}
+
+	for eventType := range EventIntents {
+		if !tested[eventType] {
+			t.Errorf("%s is mapped but not tested", eventType)
+		}
+	}
+}
+
+func TestIntentsToNames(t *testing.T) {
+	if names := IntentsToNames(0); len(names) != 0 {
+		t.Errorf("no intents returned %v", names)
+	}
+
+	names := IntentsToNames(allIntents)
+	if len(names) != len(IntentNames) {
+		t.Fatalf("every intent returned %v", names)
+	}
+
+	for i, name := range names {
+		if IntentNames[IntentGuilds<<i] != name {
+			t.Errorf("intent %d is named %s, want %s", i, name, IntentNames[IntentGuilds<<i])
+		}
+	}
+
+	// Bits which are not intents are ignored.
+	if names := IntentsToNames(IntentGuildMessages | 1<<20); !reflect.DeepEqual(names, []string{"GUILD_MESSAGES"}) {
+		t.Errorf("GUILD_MESSAGES returned %v", names)
+	}
+}
@@ -34,6 +34,10 @@ type StateGuildMember struct {
 	GuildMember
 
 	User snowflake.ID `json:"user" msgpack:"user"`
+
+	// Unix time the member was last stored or read. Used to evict members
+	// from memory and accessed atomically.
+	LastUsed int64 `json:"-" msgpack:"-"`
 }
 
 // FromDiscord converts a guild member to a state guild member.
@@ -51,6 +55,8 @@ func FromGuildMember(member *GuildMember) (sgm *StateGuildMember) {
 }
 
 func (sgm *StateGuildMember) ToGuildMember(u *User) (member *GuildMember) {
+	member = &GuildMember{}
+
 	member.User = u
 	member.Nick = sgm.Nick
 	member.Roles = sgm.Roles
@@ -60,3 +66,37 @@ func (sgm *StateGuildMember) ToGuildMember(u *User) (member *GuildMember) {
 
 	return member
 }
+
+// StatePresence represents the last known presence of a user in the state.
+// Activities only keep their name and type to keep it small.
+type StatePresence struct {
+	Status     PresenceStatus  `json:"status" msgpack:"status"`
+	Activities []StateActivity `json:"activities" msgpack:"activities"`
+
+	// Unix time the presence was last stored or read. Used to evict
+	// presences from memory and accessed atomically.
+	LastUsed int64 `json:"-" msgpack:"-"`
+}
+
+// StateActivity represents an activity of a presence in the state.
+type StateActivity struct {
+	Name string       `json:"name" msgpack:"name"`
+	Type ActivityType `json:"type" msgpack:"type"`
+}
+
+// FromPresenceUpdate converts a presence update to a state presence.
+func FromPresenceUpdate(presence *PresenceUpdate) (sp *StatePresence) {
+	sp = &StatePresence{
+		Status:     presence.Status,
+		Activities: make([]StateActivity, 0, len(presence.Activities)),
+	}
+
+	for _, activity := range presence.Activities {
+		sp.Activities = append(sp.Activities, StateActivity{
+			Name: activity.Name,
+			Type: activity.Type,
+		})
+	}
+
+	return sp
+}
@@ -23,9 +23,9 @@ var LineChartColours = [][]string{
 
 // BaseResponse is the response when returning REST requests and RPC calls.
 type BaseResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	Success bool        `json:"success" msgpack:"success"`
+	Data    interface{} `json:"data,omitempty" msgpack:"data,omitempty"`
+	Error   string      `json:"error,omitempty" msgpack:"error,omitempty"`
 }
 
 // RPCRequest is the structure the client sends when an RPC call is made.
@@ -34,6 +34,14 @@ type RPCRequest struct {
 	Data   jsoniter.RawMessage `json:"data"`
 }
 
+// RPCMethod describes a RPC method. Example is the data the method expects
+// with every field at its zero value.
+type RPCMethod struct {
+	Method      string      `json:"method"`
+	Description string      `json:"description"`
+	Example     interface{} `json:"example"`
+}
+
 // DataStamp stores time and its corresponding value.
 type DataStamp struct {
 	Time  interface{} `json:"x"`
@@ -89,13 +97,65 @@ type APIMe struct {
 type APIStatusResult struct {
 	Managers []APIStatusManager `json:"managers"`
 	Uptime   int64              `json:"uptime"`
+	Incident *Incident          `json:"incident,omitempty"` // Gateway incident in progress
+}
+
+// APIHealthResult is the structure of the /healthz and /readyz endpoints.
+type APIHealthResult struct {
+	Status   string          `json:"status"`
+	Managers map[string]bool `json:"managers,omitempty"` // If each manager checked by /readyz is ready
+}
+
+// APIIncidentsResult is the structure of the /api/incidents endpoint.
+type APIIncidentsResult struct {
+	Current *Incident  `json:"current"`
+	History []Incident `json:"history"` // Incidents which have ended, oldest first
+}
+
+// Incident is a period where many shards disconnected together, which is
+// usually caused by discord having a gateway incident.
+type Incident struct {
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Duration  int64      `json:"duration"` // Milliseconds
+
+	Shards      int     `json:"shards"`      // Shards which were open when last checked
+	Disconnects int64   `json:"disconnects"` // Ready shards which disconnected
+	PeakDown    int     `json:"peak_down"`   // Most shards which were not ready at once
+	Recovery    float64 `json:"recovery"`    // Percent of shards which were ready when last checked
 }
 
 // APIStatusManager is the structure of a manager.
 type APIStatusManager struct {
-	DisplayName string                `json:"name"`
-	Guilds      int64                 `json:"guilds"`
-	ShardGroups []APIStatusShardGroup `json:"shard_groups"`
+	DisplayName      string                `json:"name"`
+	Guilds           int64                 `json:"guilds"`
+	ProducedMessages int64                 `json:"produced_messages"` // Messages produced in the last minute
+	ProducedBytes    int64                 `json:"produced_bytes"`
+	LastPublish      time.Time             `json:"last_publish"`
+	ProducerStatus   ProducerStatus        `json:"producer_status"`
+	Maintenance      *APIStatusMaintenance `json:"maintenance,omitempty"`
+	LazyMembers      *APIStatusLazyMembers `json:"lazy_members,omitempty"`
+	ShardGroups      []APIStatusShardGroup `json:"shard_groups"`
+
+	ReadLimitExceeded int64        `json:"read_limit_exceeded"` // Gateway payloads larger than the read limit
+	REST              APIRESTStats `json:"rest"`
+	Resumes           int64        `json:"resumes"`
+	ReplayedEvents    int64        `json:"replayed_events"`  // Dispatches replayed by discord whilst resuming
+	InvalidSessions   int          `json:"invalid_sessions"` // Within bot.invalid_session_window
+}
+
+// APIStatusMaintenance is the structure of an active maintenance window.
+type APIStatusMaintenance struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason"`
+}
+
+// APIStatusLazyMembers is the structure of the lazy member fetch counters.
+type APIStatusLazyMembers struct {
+	Hits     int64 `json:"hits"`     // Members found in cache
+	Misses   int64 `json:"misses"`   // Members that had to be requested
+	Timeouts int64 `json:"timeouts"` // Requested members not received within the budget
 }
 
 // APIStatusShardGroup is the structure of a shardgroup.
@@ -107,22 +167,68 @@ type APIStatusShardGroup struct {
 
 // APIStatusShard is the structure of a shard.
 type APIStatusShard struct {
-	Status  ShardStatus `json:"status"`
-	Latency int64       `json:"latency"`
-	Uptime  int64       `json:"uptime"`
+	Status         ShardStatus `json:"status"`
+	StatusSince    time.Time   `json:"state_since"`
+	Latency        int64       `json:"latency"`
+	LatencyEWMA    float64     `json:"latency_ewma_ms"`
+	Jitter         float64     `json:"jitter_ms"`
+	Uptime         int64       `json:"uptime"`
+	SinceLastEvent int64       `json:"since_last_event"`
+
+	Recovery *APIStatusGuildRecovery `json:"recovery,omitempty"` // Set whilst recovering guilds
+}
+
+// APIStatusGuildRecovery is the progress of a shard ingesting guilds after
+// it reconnected.
+type APIStatusGuildRecovery struct {
+	Queued      int `json:"queued"`      // GUILD_CREATE waiting to be or being ingested
+	Unavailable int `json:"unavailable"` // Guilds discord has not sent yet
+	Ingested    int `json:"ingested"`
 }
 
 // APIAnalyticsResult is the structure of the /api/analytics request.
 type APIAnalyticsResult struct {
-	Graph    LineChart            `json:"chart"`
-	Guilds   int64                `json:"guilds"`
-	Channels int64                `json:"channels"`
-	Users    int64                `json:"users"`
-	Members  int64                `json:"members"`
-	Emojis   int64                `json:"emojis"`
-	Uptime   string               `json:"uptime"`
-	Events   int64                `json:"events"`
-	Managers []ManagerInformation `json:"managers"`
+	Graph     LineChart            `json:"chart"`
+	RESTGraph LineChart            `json:"rest_chart"` // REST requests made by each manager
+	Guilds    int64                `json:"guilds"`
+	Channels  int64                `json:"channels"`
+	Users     int64                `json:"users"`
+	Members   int64                `json:"members"`
+	Emojis    int64                `json:"emojis"`
+	Roles     int64                `json:"roles"`
+	Presences int64                `json:"presences"`
+	Uptime    string               `json:"uptime"`
+	Events    int64                `json:"events"`
+	Managers  []ManagerInformation `json:"managers"`
+
+	MemberEvictions MemberEvictionStats `json:"member_evictions"`
+
+	// Shards of each manager by identifier for /api/shardmap.
+	ShardMaps map[string][]ShardMapEntry `json:"-"`
+
+	GeneratedAt time.Time `json:"generated_at"`
+	Age         int64     `json:"age"` // Milliseconds since the result was generated
+}
+
+// APIShardMapResult is the structure of the /api/shardmap endpoint.
+type APIShardMapResult struct {
+	Manager     string              `json:"manager"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Age         int64               `json:"age"`    // Milliseconds since the shards were gathered
+	Totals      map[ShardStatus]int `json:"totals"` // Number of shards with each status
+	Shards      []ShardMapEntry     `json:"shards"`
+}
+
+// ShardMapEntry is a shard in the /api/shardmap endpoint.
+type ShardMapEntry struct {
+	ShardID             int         `json:"shard_id"`
+	ShardGroup          int32       `json:"shard_group"`
+	Status              ShardStatus `json:"status"`
+	Latency             int64       `json:"latency_ms"`
+	LatencyEWMA         float64     `json:"latency_ewma_ms"`
+	Jitter              float64     `json:"jitter_ms"`
+	Guilds              int64       `json:"guilds"`
+	LastEventSecondsAgo int64       `json:"last_event_seconds_ago"`
 }
 
 // ManagerInformation is the structure of the manager in the /api/analytics request.
@@ -131,6 +237,141 @@ type ManagerInformation struct {
 	Guilds    int64                      `json:"guilds"`
 	Status    map[int32]ShardGroupStatus `json:"status"`
 	AutoStart bool                       `json:"autostart"`
+	REST      APIRESTStats               `json:"rest"`
+	SLOs      []SLOStatus                `json:"slos,omitempty"`
+	Dispatch  DispatchQueueStats         `json:"dispatch_queue"`
+	Retry     PublishRetryStats          `json:"publish_retry"`
+	State     StateQueryStats            `json:"state_queries"`
+	Mutuals   MutualIndexStats           `json:"mutuals"`
+}
+
+// MutualIndexStats describes the mutual guilds a manager stores when
+// caching.store_mutuals is enabled.
+type MutualIndexStats struct {
+	Users   int   `json:"users"`   // Users with at least one guild
+	Guilds  int   `json:"guilds"`  // Guilds with at least one user
+	Entries int64 `json:"entries"` // Guilds stored over every user
+}
+
+// StateQueryStats counts the state queries a manager received.
+type StateQueryStats struct {
+	Handled  int64 `json:"handled"`   // Queries answered, including not found ones
+	NotFound int64 `json:"not_found"` // Queries for objects which are not in state
+	Limited  int64 `json:"limited"`   // Queries refused as the rate limit was reached
+}
+
+// MemberEvictionStats counts the members removed from memory by each of the
+// caching limits.
+type MemberEvictionStats struct {
+	TTL   int64 `json:"ttl"`   // Unused for caching.member_ttl
+	Guild int64 `json:"guild"` // Over caching.max_members_per_guild
+	LRU   int64 `json:"lru"`   // Over caching.max_members
+
+	PresenceTTL int64 `json:"presence_ttl"` // Presences unused for caching.member_ttl
+	PresenceLRU int64 `json:"presence_lru"` // Presences over caching.max_members
+}
+
+// PublishRetryStats describes the publish retry buffer of a manager.
+type PublishRetryStats struct {
+	Queued  int   `json:"queued"`  // Payloads waiting to be published again
+	Dropped int64 `json:"dropped"` // Payloads dropped as the buffer was full or they were too old
+	Retried int64 `json:"retried"` // Payloads published from the buffer
+}
+
+// DispatchQueueStats describes the ordered dispatch queues of a manager.
+type DispatchQueueStats struct {
+	Queued    int64 `json:"queued"`    // Dispatches waiting for a worker
+	Saturated int64 `json:"saturated"` // Times a dispatch waited for a full queue
+}
+
+// RebalanceReport is the structure of the /api/managers/{id}/rebalance_report
+// endpoint. Variation is the coefficient of variation of each measure over
+// the shards and Measure is the one which led to re-sharding being
+// recommended.
+type RebalanceReport struct {
+	Manager     string    `json:"manager"`
+	GeneratedAt time.Time `json:"generated_at"`
+	ShardGroup  int32     `json:"shard_group"`
+	ShardCount  int       `json:"shard_count"`
+
+	Threshold float64            `json:"threshold"`
+	Variation map[string]float64 `json:"variation"`
+	Measure   string             `json:"measure,omitempty"`
+
+	Recommended         bool `json:"recommended"`
+	SuggestedShardCount int  `json:"suggested_shard_count"`
+
+	Shards []RebalanceShard `json:"shards"`
+}
+
+// RebalanceShard is the load of a shard in a rebalance report.
+type RebalanceShard struct {
+	ShardID   int     `json:"shard_id"`
+	Guilds    int64   `json:"guilds"`
+	Members   int64   `json:"members"`
+	EventRate float64 `json:"event_rate"` // Dispatches per second
+}
+
+// SLOStatus is the last evaluation of the latency objective of an event type.
+// It is also the data of SANDWICH_SLO_BREACH events.
+type SLOStatus struct {
+	Event   string  `json:"event"`
+	Latency int     `json:"latency"` // Objective in milliseconds
+	Target  float64 `json:"target"`  // Percent of events which must meet the objective
+	Window  int     `json:"window"`  // Seconds
+
+	Events     int64   `json:"events"`
+	Compliance float64 `json:"compliance"` // Percent of events which met the objective
+
+	// Upper bound in milliseconds of the histogram bucket the target
+	// percentile fell in. -1 if it was above every bucket.
+	TargetLatency int64 `json:"target_latency"`
+
+	Breached    bool      `json:"breached"`
+	EvaluatedAt time.Time `json:"evaluated_at"`
+}
+
+// APIRESTStats counts the REST requests made by a manager by response class.
+// Failed requests did not receive a response.
+type APIRESTStats struct {
+	Requests       int64 `json:"requests"`
+	Success        int64 `json:"success"`
+	ClientErrors   int64 `json:"client_errors"`
+	RateLimited    int64 `json:"rate_limited"`
+	ServerErrors   int64 `json:"server_errors"`
+	Failed         int64 `json:"failed"`
+	AverageLatency int64 `json:"average_latency"` // Milliseconds
+}
+
+// APIRESTRoute is a route in the /api/rest/routes endpoint. Snowflakes and
+// tokens in the route are templated.
+type APIRESTRoute struct {
+	Route          string `json:"route"`
+	Requests       int64  `json:"requests"`
+	Errors         int64  `json:"errors"`
+	RateLimited    int64  `json:"rate_limited"`
+	AverageLatency int64  `json:"average_latency"` // Milliseconds
+}
+
+// APITopGuildsResult is the structure of the /api/state/top_guilds endpoint.
+// Window is rounded up to whole buckets and Since is when the first of them
+// started. Events counts every event which belonged to a guild.
+type APITopGuildsResult struct {
+	Manager string        `json:"manager"`
+	Window  string        `json:"window"`
+	Since   time.Time     `json:"since"`
+	Events  int64         `json:"events"`
+	Guilds  []APITopGuild `json:"guilds"`
+}
+
+// APITopGuild is a guild in the /api/state/top_guilds endpoint. Events is
+// estimated and can be over by a small amount. EventTypes only counts events
+// received whilst the guild was among the busiest.
+type APITopGuild struct {
+	ID         snowflake.ID     `json:"id"`
+	Name       string           `json:"name,omitempty"`
+	Events     int64            `json:"events"`
+	EventTypes map[string]int64 `json:"event_types"`
 }
 
 // APIConfigurationResponse is the structure of the thread safe /api/configuration endpoint.
@@ -139,7 +380,124 @@ type APIConfigurationResponse struct {
 	Configuration     interface{} `json:"configuration"`
 	RestTunnelEnabled bool        `json:"rest_tunnel_enabled"`
 	MQDrivers         []string    `json:"mq_drivers"`
+	MQCapabilities    interface{} `json:"mq_capabilities"` // Capabilities of each driver by name
 	Version           string      `json:"version"`
+
+	Warnings []ConfigurationWarning `json:"warnings"`
+
+	// Captures started through RPC which are running or have not been fetched.
+	Captures []EventCapture `json:"captures"`
+}
+
+// EventCapture describes a capture of the payloads produced by a manager.
+type EventCapture struct {
+	Manager   string       `json:"manager"`
+	GuildID   snowflake.ID `json:"guild_id"`
+	Events    []string     `json:"events"`
+	Start     time.Time    `json:"start"`
+	End       time.Time    `json:"end"`
+	MaxEvents int          `json:"max_events"`
+	Buffered  int          `json:"buffered"` // Payloads waiting to be fetched
+	Captured  int          `json:"captured"`
+	Dropped   int          `json:"dropped"` // Payloads not captured as the capture was full
+	User      *DiscordUser `json:"user"`
+}
+
+// Job describes a long running action started through RPC.
+type Job struct {
+	ID                  snowflake.ID `json:"id"`
+	Type                string       `json:"type"`
+	Manager             string       `json:"manager"`
+	Status              JobStatus    `json:"status"`
+	Error               string       `json:"error,omitempty"`
+	User                *DiscordUser `json:"user"`
+	Created             time.Time    `json:"created"`
+	StartAt             time.Time    `json:"start_at"`
+	Started             time.Time    `json:"started,omitempty"`
+	Finished            time.Time    `json:"finished,omitempty"`
+	EstimatedCompletion time.Time    `json:"estimated_completion"`
+
+	// ShardGroup the job started. Nil until the job has started it.
+	ShardGroup *int32 `json:"shardgroup,omitempty"`
+
+	// Progress of each shard the job is starting, by shard id.
+	ShardIDs []int            `json:"shard_ids"`
+	Shards   map[int]JobShard `json:"shards"`
+	Ready    int              `json:"ready"` // Shards which are ready
+	Total    int              `json:"total"` // Shards the job is starting
+}
+
+// JobShard is the progress of a single shard in a Job.
+type JobShard struct {
+	Status      ShardStatus `json:"status"`
+	StatusSince time.Time   `json:"state_since"`
+}
+
+// AuditEntry is a single mutating action recorded in the audit log.
+type AuditEntry struct {
+	ID         snowflake.ID `json:"id"`
+	Time       time.Time    `json:"time"`
+	Action     string       `json:"action"`
+	User       *DiscordUser `json:"user,omitempty"` // Empty for automatic actions
+	Manager    string       `json:"manager,omitempty"`
+	Parameters interface{}  `json:"parameters,omitempty"` // Secrets are redacted
+	Success    bool         `json:"success"`
+	Status     int          `json:"status,omitempty"` // HTTP status of the response to RPC calls
+	Summary    string       `json:"summary,omitempty"`
+}
+
+// APIGuildSync is the structure of the /api/state/guilds/{id}/sync endpoint.
+// Members are only included when requested and are paginated using
+// NextMembers. Everything else is returned whole.
+type APIGuildSync struct {
+	GeneratedAt time.Time `json:"generated_at" msgpack:"generated_at"`
+
+	Manager    string `json:"manager" msgpack:"manager"`
+	ShardGroup int32  `json:"shard_group" msgpack:"shard_group"`
+	ShardID    int    `json:"shard_id" msgpack:"shard_id"`
+
+	Guild       *discord.Guild        `json:"guild" msgpack:"guild"`
+	Channels    []*discord.Channel    `json:"channels" msgpack:"channels"`
+	Threads     []*discord.Channel    `json:"threads" msgpack:"threads"`
+	Roles       []*discord.Role       `json:"roles" msgpack:"roles"`
+	Emojis      []*discord.Emoji      `json:"emojis" msgpack:"emojis"`
+	VoiceStates []*discord.VoiceState `json:"voice_states" msgpack:"voice_states"`
+
+	Members     []*discord.GuildMember `json:"members,omitempty" msgpack:"members,omitempty"`
+	NextMembers snowflake.ID           `json:"next_members,omitempty" msgpack:"next_members,omitempty"` // Pass as after to continue
+}
+
+// APIStateMembers is the structure of the /api/state/guilds/{id}/members
+// endpoint.
+type APIStateMembers struct {
+	Members []*discord.GuildMember `json:"members" msgpack:"members"`
+	Next    snowflake.ID           `json:"next,omitempty" msgpack:"next,omitempty"` // Pass as after to continue
+}
+
+// APIMutualGuilds is the structure of the /api/state/users/{id}/mutual_guilds
+// endpoint and the manager:user:mutual_guilds RPC.
+type APIMutualGuilds struct {
+	GuildIDs []snowflake.ID `json:"guild_ids" msgpack:"guild_ids"`
+	Guilds   []*MutualGuild `json:"guilds,omitempty" msgpack:"guilds,omitempty"` // Only set when expanded
+	Next     snowflake.ID   `json:"next,omitempty" msgpack:"next,omitempty"`     // Pass as after to continue
+}
+
+// MutualGuild is an expanded guild of APIMutualGuilds. Name and icon are
+// empty if the guild is not in state.
+type MutualGuild struct {
+	ID   snowflake.ID `json:"id" msgpack:"id"`
+	Name string       `json:"name" msgpack:"name"`
+	Icon string       `json:"icon" msgpack:"icon"`
+}
+
+// ConfigurationWarning is a problem found in a manager configuration that
+// does not stop it from running.
+type ConfigurationWarning struct {
+	Manager string   `json:"manager"`
+	Setting string   `json:"setting"`
+	Event   string   `json:"event"`
+	Intents []string `json:"intents"` // Intents of which at least one is needed
+	Message string   `json:"message"`
 }
 
 // APIConfigurationResponseManager is the structure of the manager in the /api/configuration endpoint.
@@ -148,6 +506,32 @@ type APIConfigurationResponseManager struct {
 	Configuration interface{}                                  `json:"configuration"`
 	Gateway       interface{}                                  `json:"gateway"`
 	Error         string                                       `json:"error"`
+
+	GuildAffinityOverrides int `json:"guild_affinity_overrides"` // Unexpired affinity overrides set through RPC
+}
+
+// APIManagersResponseManager is the structure of a manager in the /api/managers
+// endpoint. Fields which were not selected are omitted.
+type APIManagersResponseManager struct {
+	Identifier    string                          `json:"identifier"`
+	ShardGroups   []APIManagersResponseShardGroup `json:"shard_groups,omitempty"`
+	Configuration interface{}                     `json:"configuration,omitempty"`
+	Gateway       interface{}                     `json:"gateway,omitempty"`
+	Error         string                          `json:"error,omitempty"`
+}
+
+// APIManagersResponseShardGroup is the structure of a shardgroup in the /api/managers endpoint.
+type APIManagersResponseShardGroup struct {
+	Status     ShardGroupStatus                `json:"status"`
+	Error      string                          `json:"error"`
+	Start      time.Time                       `json:"uptime"`
+	WaitingFor int32                           `json:"waiting_for"`
+	ID         int32                           `json:"id"`
+	ShardCount int                             `json:"shard_count"`
+	ShardIDs   []int                           `json:"shard_ids"`
+	Shards     []APIConfigurationResponseShard `json:"shards"`
+
+	CloseSummary *ShardGroupCloseSummary `json:"close_summary,omitempty"`
 }
 
 // APIConfigurationResponseShardGroup is the structure of a shardgroup in the /api/configuration endpoint.
@@ -160,17 +544,207 @@ type APIConfigurationResponseShardGroup struct {
 	ShardCount int                 `json:"shard_count"`
 	ShardIDs   []int               `json:"shard_ids"`
 	Shards     map[int]interface{} `json:"shards"`
+
+	StartupQueue   *APIStartupQueue `json:"startup_queue"`
+	ActiveProducer bool             `json:"active_producer"` // Dispatches are only produced by the active producer
+
+	CloseSummary *ShardGroupCloseSummary `json:"close_summary,omitempty"`
+}
+
+// ShardGroupCloseSummary describes how the dispatches of a ShardGroup were
+// handled when it closed. InFlight dispatches were being handled when
+// closing began and either completed or were abandoned after the grace
+// period. Buffered payloads had been read but were not handled and Rejected
+// dispatches arrived once closing began. Duration is in milliseconds.
+type ShardGroupCloseSummary struct {
+	ClosedAt  time.Time `json:"closed_at"`
+	Duration  int64     `json:"duration"`
+	InFlight  int64     `json:"in_flight"`
+	Completed int64     `json:"completed"`
+	Abandoned int64     `json:"abandoned"`
+	Buffered  int64     `json:"buffered"`
+	Rejected  int64     `json:"rejected"`
+	TimedOut  bool      `json:"timed_out"`
+}
+
+// APIStartupQueue is the state of the startup prioritisation of a shardgroup.
+type APIStartupQueue struct {
+	Active     bool  `json:"active"`
+	Structural int   `json:"structural"` // Queued guild, channel and role events
+	Other      int   `json:"other"`
+	MaxDelay   int64 `json:"max_delay"` // Milliseconds the longest non structural event waited
 }
 
 // APIConfigurationResponseShard is the structure of a shard in the /api/configuration endpoint.
 type APIConfigurationResponseShard struct {
-	ShardID              int           `json:"shard_id"`
-	Retries              int32         `json:"retries"`
-	Status               ShardStatus   `json:"status"`
-	HeartbeatInterval    time.Duration `json:"heartbeat_interval"`
-	MaxHeartbeatFailures time.Duration `json:"max_heartbeat_failures"`
-	LastHeartbeatAck     time.Time     `json:"last_heartbeat_ack"`
-	LastHeartbeatSent    time.Time     `json:"last_heartbeat_sent"`
-	Start                time.Time     `json:"start"`
-	User                 *discord.User `json:"user"`
+	ShardID              int              `json:"shard_id"`
+	Retries              int32            `json:"retries"`
+	Status               ShardStatus      `json:"status"`
+	StatusSince          time.Time        `json:"state_since"`
+	HeartbeatInterval    time.Duration    `json:"heartbeat_interval"`
+	MaxHeartbeatFailures time.Duration    `json:"max_heartbeat_failures"`
+	LastHeartbeatAck     time.Time        `json:"last_heartbeat_ack"`
+	LastHeartbeatSent    time.Time        `json:"last_heartbeat_sent"`
+	LatencyEWMA          float64          `json:"latency_ewma_ms"`
+	Jitter               float64          `json:"jitter_ms"`
+	Start                time.Time        `json:"start"`
+	SinceLastEvent       int64            `json:"since_last_event"`
+	Opcodes              *APIShardOpcodes `json:"opcodes"`
+	Session              ShardSession     `json:"session"`
+	ReadLimitExceeded    int64            `json:"read_limit_exceeded"`
+	Watchdog             ShardWatchdog    `json:"watchdog"`
+	User                 *discord.User    `json:"user"`
+}
+
+// ShardWatchdog is the state of the watchdog which restarts shards whose
+// connection has silently died.
+type ShardWatchdog struct {
+	LastMessageAt time.Time  `json:"last_message_at"` // Last websocket message of any opcode
+	Stalls        int64      `json:"stalls"`          // Times the shard was restarted
+	LastStallAt   *time.Time `json:"last_stall_at,omitempty"`
+}
+
+// UnhandledEvent is a dispatch type received without a state handler in
+// the /api/unhandled endpoint.
+type UnhandledEvent struct {
+	Type      string    `json:"type"`
+	Count     int64     `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// APIUnacked is the structure of the /api/unacked endpoint.
+type APIUnacked struct {
+	Manager         string         `json:"manager"`
+	PendingLimit    int            `json:"pending_limit"`
+	DeadLetterLimit int            `json:"dead_letter_limit"`
+	Pending         []UnackedEvent `json:"pending"`      // Waiting for an acknowledgement
+	DeadLetters     []UnackedEvent `json:"dead_letters"` // Never acknowledged, oldest first
+}
+
+// UnackedEvent is a produced event which has not been acknowledged.
+type UnackedEvent struct {
+	EventID        int64     `json:"event_id"`
+	Type           string    `json:"type"`
+	Size           int       `json:"size"` // Bytes of the produced payload
+	Attempts       int       `json:"attempts"`
+	FirstPublished time.Time `json:"first_published"`
+	LastPublished  time.Time `json:"last_published"`
+	DeadLettered   time.Time `json:"dead_lettered"` // Zero whilst pending
+	Reason         string    `json:"reason,omitempty"`
+}
+
+// ShardLogs is the logging escalation of a shard and the lines kept whilst it
+// was escalated.
+type ShardLogs struct {
+	Escalated bool                  `json:"escalated"`
+	Level     string                `json:"level,omitempty"`
+	Reason    string                `json:"reason,omitempty"`
+	Manual    bool                  `json:"manual"`
+	Until     *time.Time            `json:"until,omitempty"`
+	Lines     []jsoniter.RawMessage `json:"lines"` // Oldest first
+}
+
+// ShardSession is the trace and session metadata discord sent for the
+// current connection of a shard. Discord support asks for these when
+// investigating session problems.
+type ShardSession struct {
+	GatewayNode      string    `json:"gateway_node"`
+	SessionID        string    `json:"session_id"`
+	SessionType      string    `json:"session_type,omitempty"`
+	ResumeGatewayURL string    `json:"resume_gateway_url,omitempty"`
+	Version          int       `json:"version,omitempty"`
+	Resumed          bool      `json:"resumed"` // If the last READY was a RESUMED
+	HelloTrace       []string  `json:"hello_trace"`
+	HelloAt          time.Time `json:"hello_at"`
+	ReadyTrace       []string  `json:"ready_trace"`
+	ReadyAt          time.Time `json:"ready_at"`
+
+	// Last identify payload sent with the token redacted.
+	Identify   jsoniter.RawMessage `json:"identify,omitempty"`
+	IdentifyAt time.Time           `json:"identify_at"`
+
+	// Resumes of the shard and the dispatches replayed by them.
+	Resumes        int64        `json:"resumes"`
+	ReplayedEvents int64        `json:"replayed_events"`
+	LastResume     *ShardResume `json:"last_resume,omitempty"`
+}
+
+// ShardResume describes the last time a shard resumed its session. Gap is
+// how many sequences were missed whilst disconnected and Replayed is how
+// many dispatches discord sent before RESUMED.
+type ShardResume struct {
+	At            time.Time     `json:"at"`
+	Duration      time.Duration `json:"duration"`
+	Sequence      int64         `json:"sequence"`       // Sequence resumed from
+	FirstSequence int64         `json:"first_sequence"` // Sequence of the first replayed dispatch
+	LastSequence  int64         `json:"last_sequence"`
+	Gap           int64         `json:"gap"`
+	Replayed      int64         `json:"replayed"`
+}
+
+// APIShardOpcodes is the number of packets a shard has received by opcode.
+type APIShardOpcodes struct {
+	Dispatch       int64 `json:"dispatch"`
+	Heartbeat      int64 `json:"heartbeat"`
+	HeartbeatACK   int64 `json:"heartbeat_ack"`
+	Reconnect      int64 `json:"reconnect"`
+	InvalidSession int64 `json:"invalid_session"`
+	Hello          int64 `json:"hello"`
+	Other          int64 `json:"other"`
+
+	// Reconnect requests and invalid sessions received in the last hour.
+	ReconnectsLastHour      int64 `json:"reconnects_last_hour"`
+	InvalidSessionsLastHour int64 `json:"invalid_sessions_last_hour"`
+}
+
+// EventErrorCount is the number of events of a type which failed at a stage
+// in the /api/errors endpoint. Stage is either state or publish. DecodeErrors
+// counts the state errors caused by data which could not be decoded, by
+// whether it was invalid JSON (syntax) or had a field of another type (type).
+type EventErrorCount struct {
+	Type         string    `json:"type"`
+	Stage        string    `json:"stage"`
+	Events       int64     `json:"events"`
+	Errors       int64     `json:"errors"`
+	LastError    string    `json:"last_error"`
+	LastErrorAt  time.Time `json:"last_error_at"`
+	WindowStart  time.Time `json:"window_start"`
+	WindowEvents int64     `json:"window_events"`
+	WindowErrors int64     `json:"window_errors"`
+
+	DecodeErrors map[string]int64 `json:"decode_errors,omitempty"`
+}
+
+// LeavePolicyReport is the result of evaluating the leave policy of a manager.
+type LeavePolicyReport struct {
+	Manager     string           `json:"manager"`
+	DryRun      bool             `json:"dry_run"`
+	EvaluatedAt time.Time        `json:"evaluated_at"`
+	Candidates  []LeaveCandidate `json:"candidates"` // Guilds the policy matched
+	Left        []snowflake.ID   `json:"left"`
+	Errors      []string         `json:"errors"`
+	Capped      bool             `json:"capped"` // If the daily cap stopped guilds being left
+}
+
+// LeaveCandidate is a guild matched by the leave policy.
+type LeaveCandidate struct {
+	GuildID      snowflake.ID `json:"guild_id"`
+	Name         string       `json:"name"`
+	Members      int          `json:"members"`
+	LastActivity time.Time    `json:"last_activity"`
+	Reason       string       `json:"reason"` // listed or inactive
+}
+
+// ChunkFailure is a guild which has failed to chunk.
+type ChunkFailure struct {
+	Manager     string       `json:"manager"`
+	ShardGroup  int32        `json:"shard_group"`
+	ShardID     int          `json:"shard_id"`
+	GuildID     snowflake.ID `json:"guild_id"`
+	Attempts    int          `json:"attempts"`
+	LastError   string       `json:"last_error"`
+	LastAttempt time.Time    `json:"last_attempt"`
+	NextAttempt time.Time    `json:"next_attempt"` // Zero if retries are exhausted
+	Exhausted   bool         `json:"exhausted"`
 }
@@ -1,5 +1,13 @@
 package structs
 
+import (
+	"time"
+
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+	jsoniter "github.com/json-iterator/go"
+)
+
 // RPCManagerShardGroupCreateEvent is the data structure of a RPCManagerShardGroupCreate request.
 type RPCManagerShardGroupCreateEvent struct {
 	Manager          string `json:"manager"`
@@ -9,6 +17,50 @@ type RPCManagerShardGroupCreateEvent struct {
 	AutoIDs          bool   `json:"autoIDs"`
 	AutoShard        bool   `json:"autoShard"`
 	StartImmediately bool   `json:"startImmediately"`
+
+	// If set, the shardgroup is not started until this time.
+	StartAt time.Time `json:"start_at"`
+}
+
+// RPCManagerShardGroupCreateResponse is the response of a RPCManagerShardGroupCreate request.
+type RPCManagerShardGroupCreateResponse struct {
+	JobID               snowflake.ID `json:"job_id"`
+	ShardIDs            []int        `json:"shard_ids"`
+	ShardCount          int          `json:"shard_count"`
+	StartAt             time.Time    `json:"start_at"`
+	EstimatedCompletion time.Time    `json:"estimated_completion"`
+}
+
+// RPCManagerShardGroupRollEvent is the data structure of a
+// RPCManagerShardGroupRoll request.
+type RPCManagerShardGroupRollEvent struct {
+	Manager    string `json:"manager"`
+	ShardGroup *int32 `json:"shardgroup,omitempty"` // If nil, the newest shardgroup is rolled
+	AutoShard  bool   `json:"autoShard"`            // Use the recommended shard count instead of the current one
+
+	// If set, the new shardgroup is not started until this time.
+	StartAt time.Time `json:"start_at"`
+}
+
+// RPCManagerShardGroupRollResponse is the response of a
+// RPCManagerShardGroupRoll request.
+type RPCManagerShardGroupRollResponse struct {
+	JobID               snowflake.ID `json:"job_id"`
+	Replacing           int32        `json:"replacing"`
+	ShardIDs            []int        `json:"shard_ids"`
+	ShardCount          int          `json:"shard_count"`
+	StartAt             time.Time    `json:"start_at"`
+	EstimatedCompletion time.Time    `json:"estimated_completion"`
+}
+
+// RPCJobStatusEvent is the data structure of a RPCJobStatus request.
+type RPCJobStatusEvent struct {
+	ID snowflake.ID `json:"id"` // If 0, all jobs are returned
+}
+
+// RPCJobDismissEvent is the data structure of a RPCJobDismiss request.
+type RPCJobDismissEvent struct {
+	ID snowflake.ID `json:"id"`
 }
 
 // RPCManagerShardGroupStopEvent is the data structure of a RPCManagerShardGroupStop request.
@@ -50,3 +102,228 @@ type RPCManagerRestartEvent struct {
 type RPCManagerRefreshGatewayEvent struct {
 	Manager string `json:"manager"`
 }
+
+// RPCManagerRebuildEvent is the data structure of the RPCManagerProducerRestart
+// and RPCManagerClientReset requests.
+type RPCManagerRebuildEvent struct {
+	Manager string `json:"manager"`
+}
+
+// RPCManagerChunkRetryEvent is the data structure of a RPCManagerChunkRetry request.
+type RPCManagerChunkRetryEvent struct {
+	Manager  string         `json:"manager"`
+	GuildIDs []snowflake.ID `json:"guild_ids"` // If empty, all exhausted guilds are requeued
+}
+
+// RPCManagerChunkRetryResponse is the response of a RPCManagerChunkRetry request.
+type RPCManagerChunkRetryResponse struct {
+	Requeued []snowflake.ID `json:"requeued"`
+}
+
+// RPCManagerGuildChunkEvent is the data structure of a RPCManagerGuildChunk request.
+type RPCManagerGuildChunkEvent struct {
+	Manager string       `json:"manager"`
+	GuildID snowflake.ID `json:"guild_id"`
+	Wait    bool         `json:"wait"` // If set, the response is sent once chunking has finished
+}
+
+// RPCManagerMutualGuildsEvent is the data structure of a RPCManagerMutualGuilds request.
+type RPCManagerMutualGuildsEvent struct {
+	Manager string       `json:"manager"`
+	UserID  snowflake.ID `json:"user_id"`
+	Limit   int          `json:"limit"`  // Defaults to 100, at most 1000
+	After   snowflake.ID `json:"after"`  // next of the previous page
+	Expand  bool         `json:"expand"` // Include the name and icon of each guild
+}
+
+// RPCManagerUnackedEvent is the data structure of RPCManagerUnackedRequeue
+// and RPCManagerUnackedDiscard requests.
+type RPCManagerUnackedEvent struct {
+	Manager  string  `json:"manager"`
+	EventIDs []int64 `json:"event_ids"` // If empty, all dead lettered events are used
+}
+
+// RPCManagerUnackedResponse is the response of RPCManagerUnackedRequeue and
+// RPCManagerUnackedDiscard requests.
+type RPCManagerUnackedResponse struct {
+	EventIDs []int64 `json:"event_ids"`
+}
+
+// RPCManagerGuildChunkResponse is the response of a RPCManagerGuildChunk request.
+type RPCManagerGuildChunkResponse struct {
+	ShardGroup int32 `json:"shardgroup"`
+	ShardID    int   `json:"shard_id"`
+	Waited     bool  `json:"waited"`
+	Chunks     int   `json:"chunks"`   // 0 if the guild was already being chunked
+	Duration   int64 `json:"duration"` // Milliseconds
+}
+
+// RPCManagerVoiceStateUpdateEvent is the data structure of a RPCManagerVoiceStateUpdate request.
+type RPCManagerVoiceStateUpdateEvent struct {
+	Manager   string        `json:"manager"`
+	GuildID   snowflake.ID  `json:"guild_id"`
+	ChannelID *snowflake.ID `json:"channel_id"` // null to leave the voice channel
+	SelfMute  bool          `json:"self_mute"`
+	SelfDeaf  bool          `json:"self_deaf"`
+}
+
+// RPCManagerVoiceStateUpdateResponse is the response of a RPCManagerVoiceStateUpdate request.
+// Consumers should match the VOICE_SERVER_UPDATE dispatch on the same shard.
+type RPCManagerVoiceStateUpdateResponse struct {
+	ShardGroup int32 `json:"shardgroup"`
+	ShardID    int   `json:"shard_id"`
+	ShardCount int   `json:"shard_count"`
+}
+
+// RPCManagerErrorsResetEvent is the data structure of a RPCManagerErrorsReset request.
+type RPCManagerErrorsResetEvent struct {
+	Manager    string   `json:"manager"`
+	EventTypes []string `json:"event_types"` // If empty, all counters are reset
+}
+
+// RPCManagerLeavePolicyEvent is the data structure of a RPCManagerLeavePolicyEvaluate request.
+type RPCManagerLeavePolicyEvent struct {
+	Manager string `json:"manager"`
+	DryRun  bool   `json:"dry_run"` // Only report the guilds which would be left
+}
+
+// RPCManagerRebalanceReportEvent is the data structure of a
+// RPCManagerRebalanceReport request.
+type RPCManagerRebalanceReportEvent struct {
+	Manager string `json:"manager"`
+}
+
+// RPCManagerStatusUpdateEvent is the data structure of a
+// RPCManagerShardStatusUpdate request.
+type RPCManagerStatusUpdateEvent struct {
+	Manager    string               `json:"manager"`
+	ShardGroup *int32               `json:"shardgroup,omitempty"` // If nil, the newest shardgroup is used
+	Shards     []int                `json:"shards,omitempty"`     // If empty, every shard is updated
+	Presence   discord.UpdateStatus `json:"presence"`
+}
+
+// RPCShardLogLevelEvent is the data structure of a RPCShardLogLevel request.
+type RPCShardLogLevelEvent struct {
+	Manager    string `json:"manager"`
+	ShardGroup *int32 `json:"shardgroup,omitempty"` // If nil, the newest shardgroup is used
+	Shard      int    `json:"shard"`
+	Level      string `json:"level"`    // If empty, the shard reverts to its normal level
+	Duration   int    `json:"duration"` // Seconds. If 0, logging.shard_escalation.duration is used
+}
+
+// RPCManagerShardRestartEvent is the data structure of a
+// RPCManagerShardRestart request.
+type RPCManagerShardRestartEvent struct {
+	Manager    string `json:"manager"`
+	ShardGroup *int32 `json:"shardgroup,omitempty"` // If nil, the newest shardgroup is used
+	Shard      int    `json:"shard"`
+	Force      bool   `json:"force"`   // Discard the session so the shard identifies instead of resuming
+	Timeout    int    `json:"timeout"` // Seconds to wait for the shard to be ready. Defaults to 30
+}
+
+// RPCManagerShardRestartResponse is the response of a
+// RPCManagerShardRestart request.
+type RPCManagerShardRestartResponse struct {
+	ShardGroup int32       `json:"shardgroup"`
+	ShardID    int         `json:"shard_id"`
+	Status     ShardStatus `json:"status"`
+	Ready      bool        `json:"ready"` // False if the timeout elapsed first
+	Error      string      `json:"error,omitempty"`
+}
+
+// RPCManagerShardSendEvent is the data structure of a RPCManagerShardSend
+// request.
+type RPCManagerShardSendEvent struct {
+	Manager        string              `json:"manager"`
+	ShardGroup     *int32              `json:"shardgroup,omitempty"` // If nil, the newest shardgroup is used
+	Shard          int                 `json:"shard"`
+	Op             discord.GatewayOp   `json:"op"`
+	Data           jsoniter.RawMessage `json:"d"`
+	AllowDangerous bool                `json:"allow_dangerous"` // Allow ops which change the state of the shard
+}
+
+// RPCManagerShardSendResponse is the response of a RPCManagerShardSend
+// request.
+type RPCManagerShardSendResponse struct {
+	ShardGroup int32     `json:"shardgroup"`
+	ShardID    int       `json:"shard_id"`
+	SentAt     time.Time `json:"sent_at"`
+}
+
+// RPCShardResult is the outcome of a request for a single shard.
+type RPCShardResult struct {
+	ShardID int    `json:"shard_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RPCManagerStatusUpdateResponse is the response of a
+// RPCManagerShardStatusUpdate request.
+type RPCManagerStatusUpdateResponse struct {
+	ShardGroup int32            `json:"shardgroup"`
+	Updated    int              `json:"updated"`
+	Shards     []RPCShardResult `json:"shards"`
+}
+
+// RPCManagerAffinityEvent is the data structure of a RPCManagerAffinitySet request.
+type RPCManagerAffinityEvent struct {
+	Manager  string         `json:"manager"`
+	GuildIDs []snowflake.ID `json:"guild_ids"`
+	Tag      string         `json:"tag"` // If empty, the overrides are removed
+	TTL      int            `json:"ttl"` // Seconds. If 0, events.guild_affinity_ttl is used
+}
+
+// RPCManagerAffinityResponse is the response of a RPCManagerAffinitySet request.
+type RPCManagerAffinityResponse struct {
+	Updated int `json:"updated"`
+	Active  int `json:"active"` // Unexpired overrides of the manager
+}
+
+// RPCManagerRebuildResponse is the response of the RPCManagerProducerRestart
+// and RPCManagerClientReset requests.
+type RPCManagerRebuildResponse struct {
+	Rebuilt []string `json:"rebuilt"` // Parts of the manager which were rebuilt
+	Errors  []string `json:"errors"`
+}
+
+// RPCManagerBlacklistEvent is the data structure of the RPCManagerBlacklist requests.
+type RPCManagerBlacklistEvent struct {
+	Manager string   `json:"manager"`
+	List    string   `json:"list"`    // event or produce
+	Events  []string `json:"events"`  // Not used when getting a blacklist
+	Version string   `json:"version"` // If set, the blacklist is only modified if it has not changed since
+}
+
+// RPCManagerBlacklistResponse is the response of the RPCManagerBlacklist requests.
+type RPCManagerBlacklistResponse struct {
+	List    string   `json:"list"`
+	Events  []string `json:"events"`
+	Version string   `json:"version"`
+}
+
+// RPCManagerCaptureEvent is the data structure of a RPCManagerCapture request.
+type RPCManagerCaptureEvent struct {
+	Manager   string       `json:"manager"`
+	GuildID   snowflake.ID `json:"guild_id"`   // If 0, payloads of all guilds are captured
+	Events    []string     `json:"events"`     // If empty, all events are captured
+	Duration  int64        `json:"duration"`   // Seconds
+	MaxEvents int          `json:"max_events"` // Most payloads kept by the capture
+}
+
+// RPCManagerCaptureFetchEvent is the data structure of a RPCManagerCaptureFetch request.
+type RPCManagerCaptureFetchEvent struct {
+	Manager string `json:"manager"`
+}
+
+// RPCManagerCaptureFetchResponse is the response of a RPCManagerCaptureFetch request.
+type RPCManagerCaptureFetchResponse struct {
+	Capture  EventCapture          `json:"capture"`
+	Payloads []jsoniter.RawMessage `json:"payloads"`
+}
+
+// RPCDaemonMaintenanceEvent is the data structure of a RPCDaemonMaintenance request.
+type RPCDaemonMaintenanceEvent struct {
+	Manager  string `json:"manager"`  // If empty, all managers are affected
+	Duration int64  `json:"duration"` // Seconds. 0 will end maintenance
+	Reason   string `json:"reason"`
+}
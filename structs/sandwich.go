@@ -1,6 +1,9 @@
 package structs
 
-import discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+import (
+	"github.com/TheRockettek/Sandwich-Daemon/pkg/snowflake"
+	discord "github.com/TheRockettek/Sandwich-Daemon/structs/discord"
+)
 
 // StateResult represents the data a state handler would return which would be converted to
 // a sandwich payload.
@@ -24,11 +27,61 @@ type SandwichPayload struct {
 type SandwichMetadata struct {
 	Version    string `json:"v" msgpack:"v"`
 	Identifier string `json:"i" msgpack:"i"`
-	Shard      [3]int `json:"s,omitempty" msgpack:"s,omitempty"` // ShardGroup ID, Shard ID, Shard Count
+	GuildID    int64  `json:"guild_id,omitempty" msgpack:"guild_id,omitempty"`   // Guild the event belongs to, 0 if it does not belong to one
+	Shard      [3]int `json:"s,omitempty" msgpack:"s,omitempty"`                 // ShardGroup ID, Shard ID, Shard Count
+	EventID    int64  `json:"event_id" msgpack:"event_id"`                       // Unique ID consumers can use to dedupe events
+	Affinity   string `json:"affinity,omitempty" msgpack:"affinity,omitempty"`   // Affinity tag of the guild the event belongs to
+	Ack        bool   `json:"ack,omitempty" msgpack:"ack,omitempty"`             // Consumers must acknowledge EventID on <channel>:ack
+	Unhandled  bool   `json:"unhandled,omitempty" msgpack:"unhandled,omitempty"` // Forwarded as received as the daemon has no handler for it
 }
 
 // MessagingStatusUpdate represents a shard status update.
 type MessagingStatusUpdate struct {
 	ShardID int   `msgpack:"shard,omitempty"`
 	Status  int32 `msgpack:"status"`
-}
\ No newline at end of file
+	RetryIn int64 `msgpack:"retry_in,omitempty"` // Milliseconds until a reconnecting shard tries again
+	RetryAt int64 `msgpack:"retry_at,omitempty"` // Unix milliseconds of the next reconnect attempt
+}
+
+// MessagingKeepalive is sent periodically to consumers when keepalives are
+// enabled so they can tell a dead subscription from a quiet bot.
+type MessagingKeepalive struct {
+	Identifier string `json:"identifier" msgpack:"identifier"`
+	Sequence   int64  `json:"sequence" msgpack:"sequence"` // Payloads published by the manager so far
+	Time       int64  `json:"time" msgpack:"time"`         // Daemon time in unix milliseconds
+}
+
+// Ops of a state query. They mirror the /api/state endpoints.
+const (
+	StateQueryGuild       = "guild"        // ID is the guild
+	StateQueryMembers     = "members"      // GuildID is the guild, paginated with Limit and After
+	StateQueryMember      = "member"       // GuildID is the guild and ID the member
+	StateQueryRoles       = "roles"        // GuildID is the guild
+	StateQueryRole        = "role"         // GuildID is the guild and ID the role
+	StateQueryVoiceStates = "voice_states" // GuildID is the guild
+	StateQueryVoiceState  = "voice_state"  // GuildID is the guild and ID the user
+	StateQueryChannel     = "channel"      // ID is the channel
+	StateQueryUser        = "user"         // ID is the user
+	StateQueryPresence    = "presence"     // ID is the user
+)
+
+// StateQuery is a msgpack request consumers send on <channel_name>:state to
+// read an object from the cache. Fields limits the top level fields which are
+// returned.
+type StateQuery struct {
+	Op      string       `json:"op" msgpack:"op"`
+	ID      snowflake.ID `json:"id,omitempty" msgpack:"id,omitempty"`
+	GuildID snowflake.ID `json:"guild_id,omitempty" msgpack:"guild_id,omitempty"`
+	Limit   int          `json:"limit,omitempty" msgpack:"limit,omitempty"`
+	After   snowflake.ID `json:"after,omitempty" msgpack:"after,omitempty"`
+	Fields  []string     `json:"fields,omitempty" msgpack:"fields,omitempty"`
+}
+
+// StateQueryReply is the reply to a StateQuery. Data is the msgpack encoded
+// object and is empty when Found is false. Error is set if the query could
+// not be answered.
+type StateQueryReply struct {
+	Found bool   `json:"found" msgpack:"found"`
+	Data  []byte `json:"data,omitempty" msgpack:"data,omitempty"`
+	Error string `json:"error,omitempty" msgpack:"error,omitempty"`
+}
@@ -8,12 +8,37 @@ const (
 	ShardIdle         ShardStatus = iota // Represents a Shard that has been created but not opened yet
 	ShardWaiting                         // Represents a Shard waiting for the identify ratelimit
 	ShardConnecting                      // Represents a Shard connecting to the gateway
-	ShardConnected                       // Represents a Shard that has connected to discords gateway
-	ShardReady                           // Represents a Shard that has finished lazy loading
+	ShardConnected                       // Represents a Shard that has received HELLO and sent IDENTIFY or RESUME
+	ShardReady                           // Represents a Shard that has processed READY or RESUMED
 	ShardReconnecting                    // Represents a Shard that is reconnecting
 	ShardClosed                          // Represents a Shard that has been closed
 )
 
+// shardTransitions is the shard state machine. A shard normally moves from
+// Idle to Waiting, Connecting, Connected and Ready. Every connection attempt
+// starts again from Waiting and a shard can be closed or start reconnecting
+// at any point once it has been opened.
+var shardTransitions = map[ShardStatus][]ShardStatus{
+	ShardIdle:         {ShardWaiting, ShardClosed},
+	ShardWaiting:      {ShardConnecting, ShardReconnecting, ShardClosed},
+	ShardConnecting:   {ShardConnected, ShardWaiting, ShardReconnecting, ShardClosed},
+	ShardConnected:    {ShardReady, ShardWaiting, ShardReconnecting, ShardClosed},
+	ShardReady:        {ShardWaiting, ShardReconnecting, ShardClosed},
+	ShardReconnecting: {ShardWaiting, ShardClosed},
+	ShardClosed:       {ShardIdle, ShardWaiting, ShardReconnecting},
+}
+
+// CanTransition returns if a shard can change from this status to next.
+func (ss *ShardStatus) CanTransition(next ShardStatus) bool {
+	for _, status := range shardTransitions[*ss] {
+		if status == next {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (ss *ShardStatus) String() string {
 	switch *ss {
 	case ShardIdle:
@@ -42,9 +67,9 @@ func (ss *ShardStatus) Colour() int {
 	case ShardWaiting:
 		return 1548214
 	case ShardConnecting:
-		return 1548214
+		return 3447003
 	case ShardConnected:
-		return 1548214
+		return 1752220
 	case ShardReady:
 		return 2664005
 	case ShardReconnecting:
@@ -70,3 +95,25 @@ const (
 	ShardGroupClosed                             // Represent a closed ShardGroup
 	ShardGroupError                              // Represents a closed ShardGroup that closed unexpectedly due to an error
 )
+
+// ProducerStatus represents the status of a managers producer.
+type ProducerStatus int32
+
+// Status Codes for Producers.
+const (
+	ProducerIdle      ProducerStatus = iota // Represents a producer that has not been connected or has not published yet
+	ProducerConnected                       // Represents a producer whose last publish was successful
+	ProducerError                           // Represents a producer whose last publish failed
+)
+
+// JobStatus represents the status of a long running job.
+type JobStatus int32
+
+// Status Codes for Jobs.
+const (
+	JobScheduled JobStatus = iota // Represents a Job waiting for its start time
+	JobRunning                    // Represents a Job that has started
+	JobCompleted                  // Represents a Job that finished successfully
+	JobFailed                     // Represents a Job that finished with an error
+	JobCancelled                  // Represents a scheduled Job that was dismissed before it started
+)
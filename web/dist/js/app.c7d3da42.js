@@ -1 +1 @@
-(function(t){function e(e){for(var s,r,o=e[0],l=e[1],c=e[2],d=0,g=[];d<o.length;d++)r=o[d],Object.prototype.hasOwnProperty.call(i,r)&&i[r]&&g.push(i[r][0]),i[r]=0;for(s in l)Object.prototype.hasOwnProperty.call(l,s)&&(t[s]=l[s]);u&&u(e);while(g.length)g.shift()();return n.push.apply(n,c||[]),a()}function a(){for(var t,e=0;e<n.length;e++){for(var a=n[e],s=!0,o=1;o<a.length;o++){var l=a[o];0!==i[l]&&(s=!1)}s&&(n.splice(e--,1),t=r(r.s=a[0]))}return t}var s={},i={app:0},n=[];function r(e){if(s[e])return s[e].exports;var a=s[e]={i:e,l:!1,exports:{}};return t[e].call(a.exports,a,a.exports,r),a.l=!0,a.exports}r.m=t,r.c=s,r.d=function(t,e,a){r.o(t,e)||Object.defineProperty(t,e,{enumerable:!0,get:a})},r.r=function(t){"undefined"!==typeof Symbol&&Symbol.toStringTag&&Object.defineProperty(t,Symbol.toStringTag,{value:"Module"}),Object.defineProperty(t,"__esModule",{value:!0})},r.t=function(t,e){if(1&e&&(t=r(t)),8&e)return t;if(4&e&&"object"===typeof t&&t&&t.__esModule)return t;var a=Object.create(null);if(r.r(a),Object.defineProperty(a,"default",{enumerable:!0,value:t}),2&e&&"string"!=typeof t)for(var s in t)r.d(a,s,function(e){return t[e]}.bind(null,s));return a},r.n=function(t){var e=t&&t.__esModule?function(){return t["default"]}:function(){return t};return r.d(e,"a",e),e},r.o=function(t,e){return Object.prototype.hasOwnProperty.call(t,e)},r.p="/";var o=window["webpackJsonp"]=window["webpackJsonp"]||[],l=o.push.bind(o);o.push=e,o=o.slice();for(var c=0;c<o.length;c++)e(o[c]);var u=l;n.push([0,"chunk-vendors"]),a()})({0:function(t,e,a){t.exports=a("56d7")},"034f":function(t,e,a){"use strict";a("4ff2")},"4fd5":function(t,e,a){var s={"./en-au":"64fc","./en-au.js":"64fc","./en-ca":"d02e","./en-ca.js":"d02e","./en-gb":"e4ab","./en-gb.js":"e4ab","./en-ie":"2078","./en-ie.js":"2078","./en-il":"30ea","./en-il.js":"30ea","./en-in":"0929","./en-in.js":"0929","./en-nz":"9e37","./en-nz.js":"9e37","./en-sg":"5930","./en-sg.js":"5930"};function i(t){var e=n(t);return a(e)}function n(t){if(!a.o(s,t)){var e=new Error("Cannot find module '"+t+"'");throw e.code="MODULE_NOT_FOUND",e}return s[t]}i.keys=function(){return Object.keys(s)},i.resolve=n,t.exports=i,i.id="4fd5"},"4ff2":function(t,e,a){},"56d7":function(t,e,a){"use strict";a.r(e);a("d3b7"),a("25f0"),a("e623"),a("e379"),a("5dc8"),a("37e1");var s,i,n=a("2b0e"),r=a("2f62"),o=a("4eb5"),l=a.n(o),c=a("bc3a"),u=a.n(c),d=a("d604"),g=a.n(d),m=a("0c37"),p=a("7b17"),f=(a("ab8b"),a("2dd8"),a("c1df")),b=a.n(f),h=(a("4a68"),function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{attrs:{id:"app"}},[a("div",{},[a("nav",{staticClass:"navbar navbar-expand-sm navbar-dark bg-dark"},[a("div",{staticClass:"container-fluid"},[a("router-link",{staticClass:"navbar-brand",attrs:{to:"/"}},[t._v(" Sandwich Daemon "),a("span",{staticClass:"text-secondary h6"},[t._v("Version "+t._s(t.$root.version))])]),t._m(0),a("div",{staticClass:"collapse navbar-collapse justify-content-end",attrs:{id:"navbarSupportedContent"}},[a("ul",{staticClass:"navbar-nav"},[t._m(1),t.$store.state.user&&t.$store.state.user.id?a("li",{staticClass:"nav-item"},[a("router-link",{staticClass:"nav-link",attrs:{"aria-current":"page",to:"/dashboard"}},[t._v("Dashboard")])],1):t._e(),t.$store.state.user&&t.$store.state.user.id?a("li",{staticClass:"nav-item"},[a("span",{staticClass:"nav-link dropdown-toggle",attrs:{id:"userDropdown",role:"button","data-toggle":"dropdown","aria-expanded":"false"}},[a("img",{attrs:{width:"24",height:"24",src:"https://cdn.discordapp.com/avatars/"+t.$store.state.user.id+"/"+t.$store.state.user.avatar+".png?size=32",alt:"User profile picture"}})]),a("ul",{staticClass:"dropdown-menu dropdown-menu-right",attrs:{"aria-label":"Profile"}},[a("li",[a("span",{staticClass:"dropdown-item"},[t._v(" Signed in as "),a("b",[t._v(t._s(t.$store.state.user.username))])])]),t._m(2),t._m(3)])]):a("li",{staticClass:"nav-item"},[a("a",{staticClass:"nav-link",attrs:{"aria-current":"page",href:"/login"}},[t._v("Login")])])])])],1)]),t.$store.state.error?a("div",{staticClass:"text-center py-5"},[a("error",{attrs:{message:t.$store.state.error}})],1):a("div",[a("router-view")],1)])])}),v=[function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"navbar-toggler",attrs:{type:"button","data-toggle":"collapse","data-target":"#navbarSupportedContent","aria-controls":"navbarSupportedContent","aria-expanded":"false","aria-label":"Toggle navigation"}},[a("span",{staticClass:"navbar-toggler-icon"})])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("li",{staticClass:"nav-item"},[a("a",{staticClass:"nav-link",attrs:{"aria-current":"page",href:"https://github.com/TheRockettek/Sandwich-Daemon"}},[t._v("GitHub")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("li",[a("hr",{staticClass:"dropdown-divider"})])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("li",[a("a",{staticClass:"dropdown-item",attrs:{href:"/logout"}},[t._v("Sign Out")])])}],_=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"text-center my-5"},[a("svg-icon",{staticClass:"mb-2 text-muted",attrs:{type:"mdi",width:64,height:64,path:t.mdiAlertCircle}}),a("h3",[t._v("Oops, something happened.")]),a("p",{staticClass:"m-auto my-2 col-10 text-muted"},[t._v(" Something unexpected happened whilst handling your request, sorry. ")]),a("kbd",[t._v(t._s(this.message))])],1)},C=[],y=a("1fee"),k=a("94ed"),x={components:{SvgIcon:y["a"]},props:["message"],data:function(){return{mdiAlertCircle:k["a"]}}},D=x,w=a("2877"),S=Object(w["a"])(D,_,C,!1,null,null,null),M=S.exports,G={components:{Error:M}},$=G,I=(a("034f"),Object(w["a"])($,h,v,!1,null,null,null)),E=I.exports,A=a("8c4f"),O=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"text-center my-5"},[a("svg-icon",{staticClass:"mb-2 text-muted",attrs:{type:"mdi",width:64,height:64,path:t.mdiHelpRhombus}}),a("h3",[t._v("404")]),a("p",{staticClass:"m-auto my-2 col-10 text-muted"},[t._v(" It seems you are not where you want to be... ")]),a("kbd",[t._v(t._s(this.$route.path))])],1)},R=[],j={components:{SvgIcon:y["a"]},name:"NotFoundPage",data:function(){return{mdiHelpRhombus:k["g"]}}},P=j,T=Object(w["a"])(P,O,R,!1,null,null,null),N=T.exports,L=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"container-sm d-flex"},[a("div",{staticClass:"align-items-center text-center m-auto d-flex flex-wrap mt-5"},[a("img",{staticClass:"justify-content-start m-auto flex-grow-1 p-2",attrs:{width:"128",height:"128",src:t.items[t.item%t.items.length]},on:{click:function(e){t.item++}}}),a("div",{staticClass:"justify-content-end text-center flex-grow-2 mx-auto p-2"},[a("h1",[t._v("Sandwich Daemon")]),t._m(0),a("router-link",{staticClass:"ml-1",attrs:{to:"/dashboard"}},[a("button",{staticClass:"btn btn-dark",attrs:{type:"button"}},[t._v(" Dashboard ")])])],1)])])},V=[function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("a",{staticClass:"mr-1",attrs:{href:"https://github.com/TheRockettek/Sandwich-Daemon"}},[a("button",{staticClass:"btn btn-dark",attrs:{type:"button"}},[t._v("Github")])])}],U={name:"Index",data:function(){return{item:0,items:["/img/brand.svg","/img/comfy.svg"]}}},q=U,W=Object(w["a"])(q,L,V,!1,null,null,null),B=W.exports,J=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"container-xl p-3"},[t.$store.state.userLoading?a("div",[t._m(0)]):t.$store.state.userAuthenticated?this.loading?t._e():a("div",[a("div",{staticClass:"modal fade",attrs:{id:"createShardGroupDialogue",tabindex:"-1","aria-labelledby":"createShardGroupDialogueLabel","aria-hidden":"true"}},[a("div",{staticClass:"modal-dialog"},[a("div",{staticClass:"modal-content"},[a("div",{staticClass:"modal-header"},[a("h5",{staticClass:"modal-title",attrs:{id:"createShardGroupDialogueLabel"}},[t._v(" New ShardGroup for "+t._s(t.createShardGroupDialogueData.manager)+" ")]),t._m(1)]),a("div",{staticClass:"modal-body"},[a("div",{staticClass:"mb-3"},[a("label",{staticClass:"col-sm-12 form-label"},[t._v("Shard Count")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createShardGroupDialogueData.autoShard,expression:"createShardGroupDialogueData.autoShard"}],staticClass:"form-check-input",attrs:{type:"checkbox"},domProps:{checked:Array.isArray(t.createShardGroupDialogueData.autoShard)?t._i(t.createShardGroupDialogueData.autoShard,null)>-1:t.createShardGroupDialogueData.autoShard},on:{change:function(e){var a=t.createShardGroupDialogueData.autoShard,s=e.target,i=!!s.checked;if(Array.isArray(a)){var n=null,r=t._i(a,n);s.checked?r<0&&t.$set(t.createShardGroupDialogueData,"autoShard",a.concat([n])):r>-1&&t.$set(t.createShardGroupDialogueData,"autoShard",a.slice(0,r).concat(a.slice(r+1)))}else t.$set(t.createShardGroupDialogueData,"autoShard",i)}}}),a("label",{staticClass:"form-check-label ml-2 mb-2"},[t._v("Auto Determine")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createShardGroupDialogueData.shardCount,expression:"createShardGroupDialogueData.shardCount"}],staticClass:"form-control",attrs:{type:"number",disabled:t.createShardGroupDialogueData.autoShard},domProps:{value:t.createShardGroupDialogueData.shardCount},on:{input:function(e){e.target.composing||t.$set(t.createShardGroupDialogueData,"shardCount",e.target.value)}}})]),a("div",{staticClass:"mb-3"},[a("label",{staticClass:"col-sm-12 form-label"},[t._v("Shard IDs")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createShardGroupDialogueData.autoIDs,expression:"createShardGroupDialogueData.autoIDs"}],staticClass:"form-check-input",attrs:{type:"checkbox"},domProps:{checked:Array.isArray(t.createShardGroupDialogueData.autoIDs)?t._i(t.createShardGroupDialogueData.autoIDs,null)>-1:t.createShardGroupDialogueData.autoIDs},on:{change:function(e){var a=t.createShardGroupDialogueData.autoIDs,s=e.target,i=!!s.checked;if(Array.isArray(a)){var n=null,r=t._i(a,n);s.checked?r<0&&t.$set(t.createShardGroupDialogueData,"autoIDs",a.concat([n])):r>-1&&t.$set(t.createShardGroupDialogueData,"autoIDs",a.slice(0,r).concat(a.slice(r+1)))}else t.$set(t.createShardGroupDialogueData,"autoIDs",i)}}}),a("label",{staticClass:"form-check-label ml-2 mb-2"},[t._v("Auto Determine")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createShardGroupDialogueData.shardIDs,expression:"createShardGroupDialogueData.shardIDs"}],staticClass:"form-control",attrs:{type:"text",disabled:t.createShardGroupDialogueData.autoIDs,placeholder:"from-to,from-to,from-to"},domProps:{value:t.createShardGroupDialogueData.shardIDs},on:{input:function(e){e.target.composing||t.$set(t.createShardGroupDialogueData,"shardIDs",e.target.value)}}})])]),a("div",{staticClass:"modal-footer"},[a("button",{staticClass:"btn btn-secondary",attrs:{type:"button","data-dismiss":"modal"}},[t._v(" Close ")]),a("button",{staticClass:"btn btn-success",attrs:{type:"button"},on:{click:function(e){return t.createShardGroup()}}},[t._v(" Create ShardGroup ")])])])])]),a("div",{staticClass:"modal fade",attrs:{id:"stopShardGroupDialogue",tabindex:"-1","aria-labelledby":"stopShardGroupDialogueLabel","aria-hidden":"true"}},[a("div",{staticClass:"modal-dialog"},[a("div",{staticClass:"modal-content"},[a("div",{staticClass:"modal-header"},[a("h5",{staticClass:"modal-title",attrs:{id:"stopShardGroupDialogueLabel"}},[t._v(" Stop ShardGroup "+t._s(t.stopShardGroupDialogueData.shardgroup)+" for "+t._s(t.stopShardGroupDialogueData.manager)+" ")]),t._m(2)]),t._m(3),a("div",{staticClass:"modal-footer"},[a("button",{staticClass:"btn btn-secondary",attrs:{type:"button","data-dismiss":"modal"}},[t._v(" Close ")]),a("button",{staticClass:"btn btn-danger",attrs:{type:"button"},on:{click:function(e){return t.stopShardGroup()}}},[t._v(" Stop ShardGroup ")])])])])]),a("div",{staticClass:"modal fade",attrs:{id:"createManagerDialogue",tabindex:"-1","aria-labelledby":"createManagerDialogueLabel","aria-hidden":"true"}},[a("div",{staticClass:"modal-dialog"},[a("div",{staticClass:"modal-content"},[t._m(4),a("div",{staticClass:"modal-body"},[a("form",[a("div",{staticClass:"mb-3"},[t._m(5),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createManagerDialogueData.identifier,expression:"createManagerDialogueData.identifier"}],staticClass:"form-control",attrs:{type:"text",placeholder:"Enter unique identifiable name for manager"},domProps:{value:t.createManagerDialogueData.identifier},on:{input:function(e){e.target.composing||t.$set(t.createManagerDialogueData,"identifier",e.target.value)}}})]),a("div",{staticClass:"mb-3"},[t._m(6),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createManagerDialogueData.token,expression:"createManagerDialogueData.token"}],staticClass:"form-control",attrs:{type:"password",placeholder:"Enter bot token",autocomplete:"false"},domProps:{value:t.createManagerDialogueData.token},on:{input:function(e){e.target.composing||t.$set(t.createManagerDialogueData,"token",e.target.value)}}})]),a("div",{staticClass:"mb-3"},[t._m(7),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createManagerDialogueData.client,expression:"createManagerDialogueData.client"}],staticClass:"form-control",attrs:{type:"text",placeholder:"eg: "+t.createManagerDialogueData.identifier},domProps:{value:t.createManagerDialogueData.client},on:{input:function(e){e.target.composing||t.$set(t.createManagerDialogueData,"client",e.target.value)}}}),a("p",{staticClass:"text-muted"},[t._v(" Duplicate client names may not allow the manager to start however the exact name the client uses does not impact anything ")])]),a("div",{staticClass:"mb-3"},[a("label",{staticClass:"col-sm-12 form-label"},[t._v("Channel Name")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createManagerDialogueData.channel,expression:"createManagerDialogueData.channel"}],staticClass:"form-control",attrs:{type:"text",placeholder:"Defaults to "+t.configuration.producer.configuration.channel},domProps:{value:t.createManagerDialogueData.channel},on:{input:function(e){e.target.composing||t.$set(t.createManagerDialogueData,"channel",e.target.value)}}}),a("p",{staticClass:"text-muted"},[t._v(" Keeping all your managers on a single channel name is useful as it allows multiple consumers to manage multiple managers as they all read from the same source ")])]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createManagerDialogueData.persist,expression:"createManagerDialogueData.persist"}],staticClass:"form-check-input",attrs:{type:"checkbox"},domProps:{checked:Array.isArray(t.createManagerDialogueData.persist)?t._i(t.createManagerDialogueData.persist,null)>-1:t.createManagerDialogueData.persist},on:{change:function(e){var a=t.createManagerDialogueData.persist,s=e.target,i=!!s.checked;if(Array.isArray(a)){var n=null,r=t._i(a,n);s.checked?r<0&&t.$set(t.createManagerDialogueData,"persist",a.concat([n])):r>-1&&t.$set(t.createManagerDialogueData,"persist",a.slice(0,r).concat(a.slice(r+1)))}else t.$set(t.createManagerDialogueData,"persist",i)}}}),a("label",{staticClass:"form-check-label ml-2 mb-2"},[t._v("Persist")]),a("p",{staticClass:"text-muted"},[t._v(" When enabled, changes to Manager will be saved to the configuration. Un-check if you do not want to save the manager. ")])]),a("p",{staticClass:"text-danger"},[t._v("* Required")])]),a("div",{staticClass:"modal-footer"},[a("button",{staticClass:"btn btn-secondary",attrs:{type:"button","data-dismiss":"modal"}},[t._v(" Close ")]),a("button",{staticClass:"btn btn-success",attrs:{type:"button",disabled:""===t.createManagerDialogueData.client||""===t.createManagerDialogueData.token||""===t.createManagerDialogueData.identifier},on:{click:function(e){return t.createManager()}}},[t._v(" Create Manager ")])])])])]),a("div",{staticClass:"modal fade",attrs:{id:"deleteManagerDialogue",tabindex:"-1","aria-labelledby":"deleteManagerDialogueLabel","aria-hidden":"true"}},[a("div",{staticClass:"modal-dialog"},[a("div",{staticClass:"modal-content"},[a("div",{staticClass:"modal-header"},[a("h5",{staticClass:"modal-title",attrs:{id:"deleteManagerDialogueLabel"}},[t._v(" Delete Manager "+t._s(t.deleteManagerDialogueData.manager)+"? ")]),t._m(8)]),a("div",{staticClass:"modal-body"},[a("label",{staticClass:"form-check-label mb-2"},[t._v("Confirm deleting manager by typing "),a("b",[t._v(t._s(t.deleteManagerDialogueData.manager))])]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.deleteManagerDialogueData.confirm,expression:"deleteManagerDialogueData.confirm"}],staticClass:"form-control",attrs:{type:"text",placeholder:"Type: '"+t.deleteManagerDialogueData.manager+"'"},domProps:{value:t.deleteManagerDialogueData.confirm},on:{input:function(e){e.target.composing||t.$set(t.deleteManagerDialogueData,"confirm",e.target.value)}}})]),a("div",{staticClass:"modal-footer"},[a("button",{staticClass:"btn btn-secondary",attrs:{type:"button","data-dismiss":"modal"}},[t._v(" Close ")]),a("button",{staticClass:"btn btn-danger",attrs:{type:"button"},on:{click:function(e){return t.deleteManager()}}},[t._v(" Delete Manager ")])])])])]),a("div",{staticClass:"modal fade",attrs:{id:"restartManagerDialogue",tabindex:"-1","aria-labelledby":"restartManagerDialogueLabel","aria-hidden":"true"}},[a("div",{staticClass:"modal-dialog"},[a("div",{staticClass:"modal-content"},[a("div",{staticClass:"modal-header"},[a("h5",{staticClass:"modal-title",attrs:{id:"restartManagerDialogueLabel"}},[t._v(" Restart Manager "+t._s(t.restartManagerDialogueData.manager)+"? ")]),t._m(9)]),a("div",{staticClass:"modal-body"},[a("label",{staticClass:"form-check-label mb-2"},[t._v("Confirm restarting manager by typing its name. This will stop any currently running shardgroups")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.restartManagerDialogueData.confirm,expression:"restartManagerDialogueData.confirm"}],staticClass:"form-control",attrs:{type:"text",placeholder:"Type: '"+t.restartManagerDialogueData.manager+"'"},domProps:{value:t.restartManagerDialogueData.confirm},on:{input:function(e){e.target.composing||t.$set(t.restartManagerDialogueData,"confirm",e.target.value)}}})]),a("div",{staticClass:"modal-footer"},[a("button",{staticClass:"btn btn-secondary",attrs:{type:"button","data-dismiss":"modal"}},[t._v(" Close ")]),a("button",{staticClass:"btn btn-info",attrs:{type:"button"},on:{click:function(e){return t.restartManager()}}},[t._v(" Restart Manager ")])])])])]),a("ul",{staticClass:"list-group mb-4"},[t._l(t.erroredManagers(),(function(e,s){return a("li",{key:s,staticClass:"list-group-item list-group-item-action border-danger text-danger"},[a("svg-icon",{attrs:{type:"mdi",path:t.mdiAlertCircle}}),t._v(" Manager "+t._s(e.configuration.display_name)+" encountered an error ")],1)})),t._l(t.erroredShardGroups(),(function(e,s){return a("li",{key:s,staticClass:"list-group-item list-group-item-action border-danger text-danger"},[t._v(" "+t._s(e.manager)+" ShardGroup "+t._s(e.id)+" encountered an error ")])})),t._l(t.loadingShardGroups(),(function(e,s){return a("li",{key:s,staticClass:"list-group-item d-flex justify-content-between align-items-center border-info text-info"},[a("div",[a("svg-icon",{attrs:{type:"mdi",path:t.mdiConnection}}),a("span",[t._v(" Starting "+t._s(e.manager)+" ShardGroup "+t._s(e.id)+" ")])],1),a("status-graph",{staticStyle:{width:"50%"},attrs:{value:e,colours:t.colourShard}})],1)}))],2),t._m(10),a("div",{staticClass:"tab-content",attrs:{id:"pills-tabContent"}},[a("div",{staticClass:"tab-pane fade show active",attrs:{id:"pills-home",role:"tabpanel","aria-labelledby":"pills-home-tab"}},[a("div",{staticClass:"m-5"},[a("h3",{staticClass:"text-center text-dark"},[t._v("Sandwich Daemon")]),a("div",{staticClass:"row row-cols-1 row-cols-sm-2 row-cols-md-3 row-cols-lg-4 g-4 justify-content-center"},[a("card-display",{attrs:{title:"Guilds",value:t.analytics.guilds,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Members",value:t.analytics.members,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Users",value:t.analytics.users,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Channels",value:t.analytics.channels,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Emojis",value:t.analytics.emojis,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Online",value:t.analytics.online,bg:t.analytics.colour}}),a("card-display",{attrs:{title:"Uptime",value:t.analytics.uptime,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Events Processed",value:t.analytics.events,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Events queued",value:t.waiting,bg:t.waiting>0?"bg-danger":"bg-dark"}})],1),a("div",[t.analytics.chart?a("line-chart",{attrs:{"chart-data":t.analytics.chart,height:150,options:t.chartOptions.events}}):t._e()],1)]),t.rest_tunnel_enabled?a("div",{staticClass:"m-5"},[a("div",[a("h3",{staticClass:"text-center text-dark"},[t._v("RestTunnel")]),t.resttunnel?a("div",{staticClass:"row row-cols-1 row-cols-sm-2 row-cols-md-3 row-cols-lg-4 g-4 justify-content-center"},[a("card-display",{attrs:{title:"Requests Processed",value:t.resttunnel.numbers.requests,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Hits",value:t.resttunnel.numbers.hits,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Misses",value:t.resttunnel.numbers.misses,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Uptime",value:t.resttunnel.uptime,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Waiting",value:t.resttunnel.numbers.waiting,bg:"bg-dark"}})],1):t._e(),a("div",{staticClass:"row row-cols-2 row-cols-sm-1 row-cols-md-2 row-cols-xl-3 g-4 justify-content-center"},[a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.hits?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.hits,height:300,options:t.chartOptions.ratelimitHits}}):t._e()],1),a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.misses?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.misses,height:300,options:t.chartOptions.ratelimitMisses}}):t._e()],1),a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.waiting?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.waiting,height:300,options:t.chartOptions.waitingRequests}}):t._e()],1),a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.requests?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.requests,height:300,options:t.chartOptions.totalRequests}}):t._e()],1),a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.callbacks?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.callbacks,height:300,options:t.chartOptions.callbackBuffer}}):t._e()],1),a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.average_response?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.average_response,height:300,options:t.chartOptions.averageResponse}}):t._e()],1)])])]):t._e()]),a("div",{staticClass:"tab-pane fade",attrs:{id:"pills-managers",role:"tabpanel","aria-labelledby":"pills-managers-tab"}},[a("button",{staticClass:"btn btn-dark",attrs:{type:"button"},on:{click:function(e){return t.createManagerDialogue()}}},[t._v(" Create Manager ")]),t._l(t.managers,(function(e,s){return a("div",{key:s},[a("div",{staticClass:"accordion my-4",attrs:{id:"manager-"+e.configuration.identifier}},[a("div",{staticClass:"card"},[a("div",{staticClass:"card-header",attrs:{id:"header-manager-"+e.configuration.identifier}},[a("h2",{staticClass:"mb-0"},[a("button",{staticClass:"btn btn-link btn-block text-left text-decoration-none text-dark",attrs:{type:"button","data-toggle":"collapse","data-target":"#collapse-manager-"+e.configuration.identifier,"aria-expanded":"true","aria-controls":"collapse-manager-"+e.configuration.identifier}},[a("span",{class:"badge mr-2 bg-"+t.colourCluster[e.status]||!1},[t._v(t._s(t.statusGroup[e.status]))]),a("span",[t._v(t._s(e.configuration.display_name))])])])]),a("div",{staticClass:"collapse",attrs:{id:"collapse-manager-"+e.configuration.identifier,"aria-labelledby":"header-manager-"+e.configuration.identifier,"data-parent":"#manager-"+e.configuration.identifier}},[a("div",{staticClass:"card-body"},[a("ul",{staticClass:"nav nav-tabs",attrs:{id:"pills-tab",role:"tablist"}},[a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link active",attrs:{id:"manager-"+e.configuration.identifier+"-pills-status-tab","data-toggle":"pill",href:"#manager-"+e.configuration.identifier+"-pills-status",role:"tab","aria-controls":"manager-"+e.configuration.identifier+"-pills-status","aria-selected":"true"}},[t._v("Status")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"manager-"+e.configuration.identifier+"-pills-settings-tab","data-toggle":"pill",href:"#manager-"+e.configuration.identifier+"-pills-settings",role:"tab","aria-controls":"manager-"+e.configuration.identifier+"-pills-settings","aria-selected":"false"}},[t._v("Settings")])])]),a("div",{staticClass:"tab-content"},[a("div",{staticClass:"tab-pane fade p-4 active show",attrs:{id:"manager-"+e.configuration.identifier+"-pills-status",role:"tabpanel","aria-labelledby":"manager-"+e.configuration.identifier+"-pills-status-tab"}},[a("ul",{staticClass:"list-group mb-4"},[a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Shard Groups "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(Object.keys(e.shard_groups).length))])]),a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Average Latency "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(t.calculateAverage(e))+" ms")])]),a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Sessions "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(e.gateway.session_start_limit.remaining)+"/"+t._s(e.gateway.session_start_limit.total)+" ("+t._s(e.gateway.session_start_limit.max_concurrency)+")")])])]),a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"button"},on:{click:function(a){return t.createShardGroupDialogue(e.configuration.identifier)}}},[t._v(" Scale Manager ")]),a("button",{staticClass:"btn btn-info mr-1",attrs:{type:"button"},on:{click:function(a){return t.refreshGateway(e.configuration.identifier)}}},[t._v(" Refresh Gateway ")]),a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"button"},on:{click:function(a){return t.deleteManagerDialogue(e.configuration.identifier)}}},[t._v(" Delete Manager ")]),a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"button"},on:{click:function(a){return t.restartManagerDialogue(e.configuration.identifier)}}},[t._v(" Restart Manager ")]),""!=e.error?a("div",[a("div",{staticClass:"p-3 mb-2 bg-danger text-white my-4"},[t._v(" "+t._s(e.error)+" ")])]):t._e(),t._l(e.shard_groups,(function(s,i){return a("div",{key:i,staticClass:"border border-bottom bg-light rounded-lg p-3 my-4"},[a("div",{staticClass:"mb-3"},[a("h5",[a("span",{class:"badge rounded-pill bg-"+t.colourGroup[s.status]||!1},[t._v(t._s(t.statusGroup[s.status]))]),t._v(" ShardGroup "+t._s(s.id)+" ")]),6!=s.status?a("div",[a("button",{staticClass:"btn btn-dark",attrs:{type:"button"},on:{click:function(a){return t.stopShardGroupDialogue(e.configuration.identifier,s.id)}}},[t._v(" Stop ShardGroup ")])]):t._e(),s.status>=6?a("div",[a("button",{staticClass:"btn btn-dark",attrs:{type:"button"},on:{click:function(a){return t.deleteShardGroup(e.configuration.identifier,s.id)}}},[t._v(" Delete ShardGroup ")])]):t._e()]),6!=s.status?a("div",[a("ul",{staticClass:"list-group mb-4"},[a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Status "),a("status-graph",{staticStyle:{width:"50%"},attrs:{value:s,colours:t.colourShard}})],1),a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Uptime "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(t.since(s.uptime)))])]),a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Shards "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(Object.keys(s.shard_ids).length)+"/"+t._s(s.shard_count))])]),a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Average Latency "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(t.calculateAverageShardGroup(s))+" ms")])])]),s.error?a("div",[a("div",{staticClass:"p-3 mb-2 bg-danger text-white"},[t._v(" "+t._s(s.error)+" ")])]):t._e(),s.shard_count>4*e.gateway.shards?a("div",[a("div",{staticClass:"p-3 mb-2 bg-info text-white"},[t._v(" You are launching this shardgroup with an unnecessarily large number of shards. This may cause the shardgroup to take longer to become ready. It is recommend you use a smaller number closer to the recommended shard count of 1 shard per 1000 guilds which is "),a("b",[t._v(t._s(e.gateway.shards))]),t._v(". ")])]):t._e(),a("div",{staticClass:"status-container my-3"},t._l(s.shards,(function(e,s){return a("div",{directives:[{name:"b-tooltip",rawName:"v-b-tooltip.hover",modifiers:{hover:!0}}],key:s,staticClass:"status-box-container",attrs:{title:"Shard "+e.shard_id+" - "+(new Date(e.last_heartbeat_ack)-new Date(e.last_heartbeat_sent))+"ms"}},[a("div",{class:"status-box bg-"+t.colourShard[e.status]})])})),0),a("table",{staticClass:"table table-borderless"},[t._m(11,!0),a("tbody",t._l(s.shards,(function(e,s){return a("tr",{key:s,staticClass:"table-default"},[a("th",{attrs:{scope:"row"}},[t._v(t._s(e.shard_id))]),a("td",[a("span",{class:"badge bg-"+t.colourShard[e.status]},[t._v(t._s(t.statusShard[e.status]))])]),a("td",[t._v(" "+t._s(new Date(e.last_heartbeat_ack)-new Date(e.last_heartbeat_sent))+"ms ")])])})),0)])]):t._e()])}))],2),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-pills-settings",role:"tabpanel","aria-labelledby":"manager-"+e.configuration.identifier+"-pills-settings-tab"}},[a("ul",{staticClass:"nav nav-tabs",attrs:{id:"tabpanel",role:"tablist"}},[a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link active",attrs:{id:"general-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-general",role:"tab","aria-selected":"true"}},[t._v("General")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"bot-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-bot",role:"tab","aria-selected":"false"}},[t._v("Bot")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"caching-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-caching",role:"tab","aria-selected":"false"}},[t._v("Caching")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"events-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-events",role:"tab","aria-selected":"false"}},[t._v("Events")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"messaging-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-messaging",role:"tab","aria-selected":"false"}},[t._v("Messaging")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"sharding-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-sharding",role:"tab","aria-selected":"false"}},[t._v("Sharding")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"raw-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-raw",role:"tab","aria-selected":"false"}},[t._v("RAW (Read Only)")])])]),a("div",{staticClass:"tab-content p-5",attrs:{id:"pills-managerSettings"}},[a("div",{staticClass:"tab-pane fade show active",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-general",role:"tabpanel","aria-labelledby":"general-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-autostart",label:"Auto Start"},model:{value:e.configuration.auto_start,callback:function(a){t.$set(e.configuration,"auto_start",a)},expression:"manager.configuration.auto_start"}}),a("p",{staticClass:"text-muted"},[t._v(" When enabled, the Manager will start up a shardgroup automatically. ")]),a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-persist",label:"Persist"},model:{value:e.configuration.persist,callback:function(a){t.$set(e.configuration,"persist",a)},expression:"manager.configuration.persist"}}),a("p",{staticClass:"text-muted"},[t._v(" When enabled, changes to Manager will be saved else the configuration will be discarded when the daemon is next started. ")])],1),a("form-input",{attrs:{type:"text",id:"managerConfig-"+e.configuration.identifier+"-identifier",label:"Identifier",disabled:!0},model:{value:e.configuration.identifier,callback:function(a){t.$set(e.configuration,"identifier",a)},expression:"manager.configuration.identifier"}}),t._m(12,!0),a("form-input",{attrs:{type:"text",id:"managerConfig-"+e.configuration.identifier+"-display_name",label:"Display Name"},model:{value:e.configuration.display_name,callback:function(a){t.$set(e.configuration,"display_name",a)},expression:"manager.configuration.display_name"}}),a("form-input",{attrs:{type:"password",id:"managerConfig-"+e.configuration.identifier+"-token",label:"Token"},model:{value:e.configuration.token,callback:function(a){t.$set(e.configuration,"token",a)},expression:"manager.configuration.token"}}),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-bot",role:"tabpanel","aria-labelledby":"bot-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-bot.compression",label:"Compression"},model:{value:e.configuration.bot.compression,callback:function(a){t.$set(e.configuration.bot,"compression",a)},expression:"manager.configuration.bot.compression"}}),a("p",{staticClass:"text-muted"},[t._v(" Enables zstd compression on the gateway websocket. Recommended. ")]),a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-bot.guild_subscriptions",label:"Guild Subscriptions"},model:{value:e.configuration.bot.guild_subscriptions,callback:function(a){t.$set(e.configuration.bot,"guild_subscriptions",a)},expression:"\n                                manager.configuration.bot.guild_subscriptions\n                              "}}),t._m(13,!0)],1),a("form-input",{attrs:{type:"presence",id:"managerConfig-"+e.configuration.identifier+"-bot.presence",label:"Presence"},model:{value:e.configuration.bot.presence,callback:function(a){t.$set(e.configuration.bot,"presence",a)},expression:"manager.configuration.bot.presence"}}),a("form-input",{attrs:{type:"intent",id:"managerConfig-"+e.configuration.identifier+"-bot.intents",label:"Intents"},model:{value:e.configuration.bot.intents,callback:function(a){t.$set(e.configuration.bot,"intents",a)},expression:"manager.configuration.bot.intents"}}),a("form-input",{attrs:{type:"number",id:"managerConfig-"+e.configuration.identifier+"-bot.large_threshold",label:"Large Threshold"},model:{value:e.configuration.bot.large_threshold,callback:function(a){t.$set(e.configuration.bot,"large_threshold",a)},expression:"\n                              manager.configuration.bot.large_threshold\n                            "}}),a("form-input",{attrs:{type:"number",id:"managerConfig-"+e.configuration.identifier+"-bot.max_heartbeat_failures",label:"Max Heartbeat Failures"},model:{value:e.configuration.bot.max_heartbeat_failures,callback:function(a){t.$set(e.configuration.bot,"max_heartbeat_failures",a)},expression:"\n                              manager.configuration.bot.max_heartbeat_failures\n                            "}}),a("p",{staticClass:"text-muted"},[t._v(" Amount of heartbeat durations until the bot forcibly reconnects. 5 is recommended. ")]),a("form-input",{attrs:{type:"number",id:"managerConfig-"+e.configuration.identifier+"-bot.retries",label:"Retries"},model:{value:e.configuration.bot.retries,callback:function(a){t.$set(e.configuration.bot,"retries",a)},expression:"manager.configuration.bot.retries"}}),a("p",{staticClass:"text-muted"},[t._v(" Amount of reconnects to attempt before giving up. Recommended to be more than 1 in the event it fails when starting up a ShardGroup causing all other shards to die. ")]),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-caching",role:"tabpanel","aria-labelledby":"caching-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-caching.cache_users",label:"Cache Users"},model:{value:e.configuration.caching.cache_users,callback:function(a){t.$set(e.configuration.caching,"cache_users",a)},expression:"\n                                manager.configuration.caching.cache_users\n                              "}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, users will be cached. This does not affect GUILD_MEMBERS_CHUNK. ")]),a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-caching.cache_members",label:"Cache Members"},model:{value:e.configuration.caching.cache_members,callback:function(a){t.$set(e.configuration.caching,"cache_members",a)},expression:"\n                                manager.configuration.caching.cache_members\n                              "}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, members will be cached. This does not affect GUILD_MEMBERS_CHUNK. ")]),a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-caching.request_members",label:"Request Members"},model:{value:e.configuration.caching.request_members,callback:function(a){t.$set(e.configuration.caching,"request_members",a)},expression:"\n                                manager.configuration.caching.request_members\n                              "}}),t._m(14,!0),a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-caching.store_mutuals",label:"Store Mutuals"},model:{value:e.configuration.caching.store_mutuals,callback:function(a){t.$set(e.configuration.caching,"store_mutuals",a)},expression:"\n                                manager.configuration.caching.store_mutuals\n                              "}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, guild IDs a member is directly available. ")])],1),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-events",role:"tabpanel","aria-labelledby":"events-tab"}},[a("p",{staticClass:"text-muted"},[t._v(" Changes to events will be reflected when a new shardgroup is made ")]),a("form-input",{attrs:{type:"list",id:"managerConfig-"+e.configuration.identifier+"-events.event_blacklist",label:"Event Blacklist"},model:{value:e.configuration.events.event_blacklist,callback:function(a){t.$set(e.configuration.events,"event_blacklist",a)},expression:"\n                              manager.configuration.events.event_blacklist\n                            "}}),a("p",{staticClass:"text-muted"},[t._v(" Events in event blacklist are completely ignored by Sandwich Daemon. It is recommended you instead use Intents to stop events as it still has to process the events. ")]),a("form-input",{attrs:{type:"list",id:"managerConfig-"+e.configuration.identifier+"-events.produce_blacklist",label:"Produce Blacklist"},model:{value:e.configuration.events.produce_blacklist,callback:function(a){t.$set(e.configuration.events,"produce_blacklist",a)},expression:"\n                              manager.configuration.events.produce_blacklist\n                            "}}),a("p",{staticClass:"text-muted"},[t._v(" Events in produce blacklist are processed (cached etc.) however are not relayed to consumers. Useful for reducing events that the consumers unnecessarily need but you still need for cache purposes. ")]),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-messaging",role:"tabpanel","aria-labelledby":"messaging-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-messaging.use_random_suffix",label:"Use Random Suffix"},model:{value:e.configuration.messaging.use_random_suffix,callback:function(a){t.$set(e.configuration.messaging,"use_random_suffix",a)},expression:"\n                                manager.configuration.messaging\n                                  .use_random_suffix\n                              "}}),a("p",{staticClass:"text-muted"},[t._v(" When enabled, the client name will have a random suffix added. Useful if you have multiple managers using the same client name. ")])],1),a("form-input",{attrs:{type:"text",id:"managerConfig-"+e.configuration.identifier+"-messaging.client_name",label:"Client Name"},model:{value:e.configuration.messaging.client_name,callback:function(a){t.$set(e.configuration.messaging,"client_name",a)},expression:"\n                              manager.configuration.messaging.client_name\n                            "}}),t._m(15,!0),a("form-input",{attrs:{type:"text",id:"managerConfig-"+e.configuration.identifier+"-messaging.channel_name",label:"Channel Name"},model:{value:e.configuration.messaging.channel_name,callback:function(a){t.$set(e.configuration.messaging,"channel_name",a)},expression:"\n                              manager.configuration.messaging.channel_name\n                            "}}),a("p",{staticClass:"text-muted"},[t._v(" Custom definition of the channel the manager will use. If empty, it will use the configuration in daemon.producer.configuration.channel which managers should use by default. ")]),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-sharding",role:"tabpanel","aria-labelledby":"sharding-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-sharding.auto_sharded",label:"AutoSharded"},model:{value:e.configuration.sharding.auto_sharded,callback:function(a){t.$set(e.configuration.sharding,"auto_sharded",a)},expression:"\n                                manager.configuration.sharding.auto_sharded\n                              "}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, will set the shard count to what is recommended on the gateway ")])],1),a("form-input",{attrs:{type:"number",id:"managerConfig-"+e.configuration.identifier+"-sharding.shard_count",label:"Shard Count"},model:{value:e.configuration.sharding.shard_count,callback:function(a){t.$set(e.configuration.sharding,"shard_count",a)},expression:"\n                              manager.configuration.sharding.shard_count\n                            "}}),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-raw",role:"tabpanel","aria-labelledby":"raw-tab"}},[a("pre",[t._v(t._s(e))])])])])])])])])])])}))],2),a("div",{staticClass:"tab-pane fade",attrs:{id:"pills-settings",role:"tabpanel","aria-labelledby":"pills-settings-tab"}},[a("form",{on:{submit:function(t){t.preventDefault()}}},[a("ul",{staticClass:"nav nav-tabs",attrs:{id:"tabpanel",role:"tablist"}},[a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link active",attrs:{id:"logging-tab","data-toggle":"tab",href:"#daemonSettings-logging",role:"tab","aria-selected":"true"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiTextBoxMultiple}}),t._v(" Logging ")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"webhooks-tab","data-toggle":"tab",href:"#daemonSettings-webhooks",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiWebhook}}),t._v(" Webhooks ")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"access-tab","data-toggle":"tab",href:"#daemonSettings-access",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiDoorOpen}}),t._v(" Access ")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"producer-tab","data-toggle":"tab",href:"#daemonSettings-producer",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiMessageProcessing}}),t._v(" Outbound MQ")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"grpc-tab","data-toggle":"tab",href:"#daemonSettings-grpc",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiForum}}),t._v(" GRPC")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"http-tab","data-toggle":"tab",href:"#daemonSettings-http",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiWeb}}),t._v(" HTTP")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"resttunnel-tab","data-toggle":"tab",href:"#daemonSettings-resttunnel",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiFilter}}),t._v(" RestTunnel")],1)]),t._m(16)]),a("div",{staticClass:"tab-content p-5",attrs:{id:"pills-daemonSettings"}},[a("div",{staticClass:"tab-pane fade show active",attrs:{id:"daemonSettings-logging",role:"tabpanel","aria-labelledby":"logging-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"loggingConsoleLogging",label:"Console Logging"},model:{value:t.configuration.logging.console_logging,callback:function(e){t.$set(t.configuration.logging,"console_logging",e)},expression:"configuration.logging.console_logging"}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, logs will be shown in console. ")]),a("form-input",{attrs:{type:"checkbox",id:"loggingFileLogging",label:"File Logging"},model:{value:t.configuration.logging.file_logging,callback:function(e){t.$set(t.configuration.logging,"file_logging",e)},expression:"configuration.logging.file_logging"}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, logs will be saved to a file. ")]),a("form-input",{attrs:{type:"checkbox",id:"loggingEncodeAsJson",label:"Encode as JSON"},model:{value:t.configuration.logging.encode_as_json,callback:function(e){t.$set(t.configuration.logging,"encode_as_json",e)},expression:"configuration.logging.encode_as_json"}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, text will be encoded as json instead of human readable. ")]),a("form-input",{staticClass:"mt-5",attrs:{type:"checkbox",id:"loggingMinimalWebhooks",label:"Minimal Webhooks"},model:{value:t.configuration.logging.minimal_webhooks,callback:function(e){t.$set(t.configuration.logging,"minimal_webhooks",e)},expression:"configuration.logging.minimal_webhooks"}}),a("p",{staticClass:"text-muted"},[t._v(" When enabled, webhooks will prefer a more minimalistic one line message rather than fancier embeds. Useful when you have lots of shards. ")])],1),a("form-input",{attrs:{type:"select",id:"loggingLevel",label:"Logging Level",values:["trace","debug","info","warn","error","fatal","panic"]},model:{value:t.configuration.logging.level,callback:function(e){t.$set(t.configuration.logging,"level",e)},expression:"configuration.logging.level"}}),a("form-input",{attrs:{type:"text",id:"loggingDirectory",label:"Directory"},model:{value:t.configuration.logging.directory,callback:function(e){t.$set(t.configuration.logging,"directory",e)},expression:"configuration.logging.directory"}}),a("form-input",{attrs:{type:"text",id:"loggingFilename",label:"Filename"},model:{value:t.configuration.logging.filename,callback:function(e){t.$set(t.configuration.logging,"filename",e)},expression:"configuration.logging.filename"}}),a("form-input",{attrs:{type:"number",id:"loggingMaxSize",label:"Max Size"},model:{value:t.configuration.logging.max_size,callback:function(e){t.$set(t.configuration.logging,"max_size",e)},expression:"configuration.logging.max_size"}}),t._m(17),a("form-input",{attrs:{type:"number",id:"loggingMaxBackups",label:"Max Backups"},model:{value:t.configuration.logging.max_backups,callback:function(e){t.$set(t.configuration.logging,"max_backups",e)},expression:"configuration.logging.max_backups"}}),a("p",{staticClass:"text-muted"},[t._v(" Max number of log files before old ones are deleted. ")]),a("form-input",{attrs:{type:"number",id:"loggingMaxAge",label:"Max Age"},model:{value:t.configuration.logging.max_age,callback:function(e){t.$set(t.configuration.logging,"max_age",e)},expression:"configuration.logging.max_age"}}),a("p",{staticClass:"text-muted"},[t._v(" Number of days that a log file can exist before it is removed. ")]),a("form-submit",{on:{click:function(e){return t.saveDaemonSettings()}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-webhooks",role:"tabpanel","aria-labelledby":"webhooks-tab"}},[a("div",{staticClass:"d-flex pb-3 border-bottom border-muted"},[a("input",{directives:[{name:"model",rawName:"v-model",value:t.webhook_url,expression:"webhook_url"}],staticClass:"form-control flex-grow-1",staticStyle:{width:"fit-content"},attrs:{type:"text",id:"webhookName",placeholder:"Webhook URL"},domProps:{value:t.webhook_url},on:{input:function(e){e.target.composing||(t.webhook_url=e.target.value)}}}),a("button",{staticClass:"btn btn-dark ml-3",attrs:{type:"button",disabled:!t.validURL(t.webhook_url)},on:{click:function(e){return t.addWebhook(t.webhook_url)}}},[t._v(" Add Webhook ")])]),t._l(t.configuration.webhooks,(function(e,s){return a("div",{key:s,staticClass:"card text-left py-3 border-bottom border-muted border-top-0 border-left-0 border-right-0 flex-column flex-sm-row"},[a("div",{staticClass:"my-auto",staticStyle:{width:"fit-content"}},[a("small",{staticClass:"text-dark",staticStyle:{"overflow-wrap":"anywhere"}},[t._v(t._s(e))])]),a("div",{staticClass:"text-center ml-3"},[a("button",{staticClass:"m-1 btn btn-info",attrs:{type:"button"},on:{click:function(a){return t.testWebhook(e)}}},[t._v(" Test Webhook ")]),a("button",{staticClass:"m-1 btn btn-danger",attrs:{type:"button"},on:{click:function(a){return t.removeWebhook(e)}}},[t._v(" Remove Webhook ")])])])}))],2),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-resttunnel",role:"tabpanel","aria-labelledby":"resttunnel-tab"}},[t._m(18),a("p",{staticClass:"text-muted"},[t._v(" RestTunnel enabled: "),a("b",[t._v(t._s(t.rest_tunnel_enabled))])]),a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"restTunnelEnabled",label:"Enabled"},model:{value:t.configuration.resttunnel.enabled,callback:function(e){t.$set(t.configuration.resttunnel,"enabled",e)},expression:"configuration.resttunnel.enabled"}})],1),a("form-input",{attrs:{type:"text",id:"restTunnelURL",placeholder:"http://127.0.0.1:8000",label:"URL"},model:{value:t.configuration.resttunnel.url,callback:function(e){t.$set(t.configuration.resttunnel,"url",e)},expression:"configuration.resttunnel.url"}}),a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"button"},on:{click:function(e){return t.saveDaemonSettings()}}},[t._v(" Save Changes ")]),a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"button"},on:{click:function(e){return t.verifyRestTunnel()}}},[t._v(" Verify RestTunnel ")])],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-access",role:"tabpanel","aria-labelledby":"access-tab"}},[a("p",{staticClass:"text-muted"},[t._v(" Access allows you to manage who has access to the dashboard ")]),a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"httpPublic",label:"Public Access"},model:{value:t.configuration.http.public,callback:function(e){t.$set(t.configuration.http,"public",e)},expression:"configuration.http.public"}}),t._m(19)],1),a("ul",{staticClass:"list-group mt-3 mb-2"},[a("li",{staticClass:"list-group-item list-group-item-dark"},[t._v(" Users ("+t._s(t.configuration.elevated_users.length)+") ")]),t._l(t.configuration.elevated_users,(function(e,s){return a("li",{key:s,staticClass:"list-group-item"},[t._v(" "+t._s(e)+" ")])}))],2),a("form-input",{attrs:{type:"text",id:"elevatedUsers",label:"",placeholder:"Discord user ID"},model:{value:t.userid,callback:function(e){t.userid=e},expression:"userid"}}),a("form-submit",{attrs:{disabled:t.$store.state.user&&t.userid===t.$store.state.user.id,label:"Remove User"},on:{click:function(e){t.$store.state.user&&t.userid===t.$store.state.user.id||(t.configuration.elevated_users=t.configuration.elevated_users.filter((function(e){return e!==t.userid})),t.userid=void 0)}}}),a("form-submit",{attrs:{label:"Add User"},on:{click:function(e){Number(t.userid)==t.userid&&(t.configuration.elevated_users.includes(t.userid)||(t.configuration.elevated_users.push(t.userid),t.userid=void 0))}}}),a("p",{staticClass:"text-muted mb-4"},[t._v(" List of discord user IDs who are able to manage the settings on the dashboard. Only give this to users you trust. ")]),a("form-submit",{on:{click:function(e){return t.saveDaemonSettings()}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-producer",role:"tabpanel","aria-labelledby":"producer-tab"}},[a("p",{staticClass:"text-muted"},[t._v(" OutboundMQ is the driver that allows for consumers to process messages that are sent by the Daemon. This ensures that consumers receive their messages and tailer to your setup. ")]),a("form-input",{attrs:{type:"select",id:"outboundMQtype",label:"Driver",values:t.mq_drivers},model:{value:t.configuration.producer.type,callback:function(e){t.$set(t.configuration.producer,"type",e)},expression:"configuration.producer.type"}}),a("p",{staticClass:"text-muted"},[t._v(" Select the type of driver you would like to use to handle Outbound messaging. When changing, ensure you pass the proper arguments to the OutboundMQ Configuration. You may be required to restart sandwich-daemon when changing this or restarting managers. ")]),a("form-input",{attrs:{type:"kvpair",id:"outboundMQconfiguration",label:"OutboundMQ Configuration"},model:{value:t.configuration.producer.configuration,callback:function(e){t.$set(t.configuration.producer,"configuration",e)},expression:"configuration.producer.configuration"}}),a("form-submit",{on:{click:function(e){return t.saveDaemonSettings()}}}),a("b",[t._v("Changing these values require a restart before changes are seen")])],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-grpc",role:"tabpanel","aria-labelledby":"grpc-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"select",id:"grpcNetwork",label:"Network",values:["tcp","tcp4","tcp6","unix","unixpacket"]},model:{value:t.configuration.grpc.network,callback:function(e){t.$set(t.configuration.grpc,"network",e)},expression:"configuration.grpc.network"}}),a("p",{staticClass:"text-muted"},[t._v(" Specify the type of network transport you want to use ")])],1),a("form-input",{attrs:{type:"text",id:"grpcHost",label:"Host"},model:{value:t.configuration.grpc.host,callback:function(e){t.$set(t.configuration.grpc,"host",e)},expression:"configuration.grpc.host"}}),t._m(20),a("form-submit",{on:{click:function(e){return t.saveDaemonSettings()}}}),a("b",[t._v("Changing these values require a restart before changes are seen")])],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-http",role:"tabpanel","aria-labelledby":"http-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"httpEnabled",label:"Enabled"},model:{value:t.configuration.http.enabled,callback:function(e){t.$set(t.configuration.http,"enabled",e)},expression:"configuration.http.enabled"}}),a("p",{staticClass:"text-muted"},[t._v(" Disabling HTTP will still show the web interface however will shown an error. ")])],1),a("form-input",{attrs:{type:"text",id:"httpHost",label:"Host"},model:{value:t.configuration.http.host,callback:function(e){t.$set(t.configuration.http,"host",e)},expression:"configuration.http.host"}}),t._m(21),a("form-input",{attrs:{type:"password",id:"httpSecret",label:"Session Secret"},model:{value:t.configuration.http.secret,callback:function(e){t.$set(t.configuration.http,"secret",e)},expression:"configuration.http.secret"}}),t._m(22),a("form-submit",{on:{click:function(e){return t.saveDaemonSettings()}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-raw",role:"tabpanel","aria-labelledby":"raw-tab"}},[a("pre",[t._v(t._s(t.configuration))])])])])]),a("div",{staticClass:"tab-pane fade",attrs:{id:"pills-console",role:"tabpanel","aria-labelledby":"pills-console-tab"}},[a("console",{attrs:{wsurl:"/api/console",limit:0}})],1)])]):a("div",[a("div",{staticClass:"text-center my-5"},[a("svg-icon",{staticClass:"mb-2 text-muted",attrs:{type:"mdi",width:64,height:64,path:t.mdiAlertCircle}}),a("h3",[t._v("You are not authenticated")]),a("p",{staticClass:"m-auto my-2 col-10 text-muted"},[t._v(" It seems you are not where you want to be... ")])],1)]),a("div",{staticStyle:{position:"relative","min-height":"200px"},attrs:{"aria-live":"polite","aria-atomic":"true"}},[a("div",{staticStyle:{position:"fixed",top:"20px",right:"20px"}},[a("div",{staticClass:"toast",attrs:{id:"toast",role:"alert","aria-live":"assertive","aria-atomic":"true"}},[a("div",{staticClass:"toast-header"},[a("strong",{staticClass:"mr-auto"},[t._v(t._s(t.toast.title)+" ")]),t._m(23)]),a("div",{staticClass:"toast-body"},[t._v(" "+t._s(t.toast.body)+" ")])])])])])},F=[function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"spinner-border mx-auto mt-5 d-flex",attrs:{role:"status"}},[a("span",{staticClass:"visually-hidden"},[t._v("Loading...")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"close",attrs:{type:"button","data-dismiss":"modal","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"close",attrs:{type:"button","data-dismiss":"modal","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"modal-body"},[a("span",[t._v("Are you sure you want to kill this shard group?")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"modal-header"},[a("h5",{staticClass:"modal-title",attrs:{id:"createManagerDialogueLabel"}},[t._v(" New Manager ")]),a("button",{staticClass:"close",attrs:{type:"button","data-dismiss":"modal","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("label",{staticClass:"col-sm-12 form-label"},[t._v("Identifier "),a("span",{staticClass:"text-danger"},[t._v("*")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("label",{staticClass:"col-sm-12 form-label"},[t._v("Token "),a("span",{staticClass:"text-danger"},[t._v("*")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("label",{staticClass:"col-sm-12 form-label"},[t._v("Client Name "),a("span",{staticClass:"text-danger"},[t._v("*")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"close",attrs:{type:"button","data-dismiss":"modal","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"close",attrs:{type:"button","data-dismiss":"modal","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("ul",{staticClass:"nav nav-pills mb-3",attrs:{id:"pills-tab",role:"tablist"}},[a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link active",attrs:{id:"pills-home-tab","data-toggle":"pill",href:"#pills-home",role:"tab","aria-controls":"pills-home","aria-selected":"true"}},[t._v("Analytics")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"pills-managers-tab","data-toggle":"pill",href:"#pills-managers",role:"tab","aria-controls":"pills-managers","aria-selected":"false"}},[t._v("Managers")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"pills-settings-tab","data-toggle":"pill",href:"#pills-settings",role:"tab","aria-controls":"pills-settings","aria-selected":"false"}},[t._v("Daemon Settings")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"pills-console-tab","data-toggle":"pill",href:"#pills-console",role:"tab","aria-controls":"pills-console","aria-selected":"false"}},[t._v("Console")])])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("thead",[a("tr",{staticClass:"table-dark"},[a("th",{attrs:{scope:"col"}},[t._v("Shard")]),a("th",{attrs:{scope:"col"}},[t._v("Status")]),a("th",{attrs:{scope:"col"}},[t._v("RTT Latency")])])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("You cannot modify the identifier as that can possibly break many things.")]),t._v(" The name the manager is internally referenced by. Produced messages will also include this identifier in its messages. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("Not recommended, use intents.")]),t._v(" Events such as PRESENCE_UPDATE, TYPING, GUILD_MEMBER_JOIN etc. are not sent to the bot. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("Due to the new intent changes, enabling this on larger bots is not recommended.")]),t._v(" If enabled, guild members will be requested when lazy loading. "),a("b",[t._v("This bypasses Cache Users and Cache Members options.")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[t._v(" Name of the client the manager uses. "),a("b",[t._v("Client names must be unique.")]),t._v(" If you are using multiple managers with the same client name, it is recommended you use separate client names or enable random suffix. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"raw-tab","data-toggle":"tab",href:"#daemonSettings-raw",role:"tab","aria-selected":"false"}},[t._v("RAW (Read Only)")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("Stored in Megabytes.")]),t._v(" Max size each log can be before a new file is made. 0 is unlimited. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[t._v(" RestTunnel is a second-party application that is used in conjunction with Sandwich-Daemon to allow for centralized rate limit management for multiple systems. The Github repository can be found "),a("a",{attrs:{href:"https://github.com/TheRockettek/RestTunnel"}},[t._v("here")]),t._v(". ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[t._v(" If enabled, users will not need elevation to access the internal API. "),a("b",[t._v("This should never need to be enabled.")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("It is recommended you keep this on a local IP such as localhost or 127.0.0.1.")]),t._v(" Entering 0.0.0.0 or the devices IP will make it accessable to users outside of the local network. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("It is recommended you keep this on a local IP such as localhost or 127.0.0.1 or disable public access in the Access tab.")]),t._v(" Entering 0.0.0.0 or the devices IP will make it accessable to users outside of the local network. Ensure you disable public access in the Access tab. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[t._v(" Secret to use for encrypting user sessions. "),a("b",[t._v("Changing this will log you out")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"ml-2 mb-1 close",attrs:{type:"button","data-dismiss":"toast","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])}],H=(a("fb6a"),a("b65f"),a("a9e3"),a("b64b"),a("07ac"),a("3ca3"),a("ddb0"),a("2b3d"),function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"col justify-content-center d-flex"},[a("div",{class:t.bg+" card text-white m-1",staticStyle:{width:"18rem"}},[a("div",{staticClass:"card-header"},[t._v(t._s(t.title))]),a("div",{staticClass:"card-body"},[a("h5",{staticClass:"card-title"},[t._v(t._s(t.value))])])])])}),z=[],K={name:"CardDisplay",props:["title","value","bg"]},Y=K,Q=Object(w["a"])(Y,H,z,!1,null,null,null),Z=Q.exports,X=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",[a("ul",{staticClass:"console mt-4 p-3 rounded-lg"},t._l(t.entries,(function(e,s){return a("li",{key:s,staticClass:"d-flex font-monospace text-white"},[a("span",{staticClass:"text-white-50"},[t._v(t._s(e.time))]),t.levels[e.level]?a("span",{class:"text-"+(t.levels[e.level][1]||"white")},[t._v(t._s(t.levels[e.level][0]||"???"))]):a("span",{staticClass:"text-white"},[t._v("???")]),a("span",[t._v(t._s(e.message))]),t._l(e.args,(function(e,s){return a("div",{key:s},["error"==s?a("span",{staticClass:"text-danger"},[a("span",[t._v(t._s(s)+"=")]),a("span",[t._v(t._s(e))])]):a("span",[a("span",{staticClass:"text-info"},[t._v(t._s(s)+"=")]),a("span",[t._v(t._s(e))])])])}))],2)})),0),a("div",{staticClass:"d-flex"},[a("button",{staticClass:"btn btn-dark mr-2",on:{click:function(e){return t.toggle_connection()}}},[t._v(" "+t._s(this.connected?"Disconnect":"Connect")+" ")]),a("button",{staticClass:"btn btn-dark mr-2",on:{click:function(e){return t.clear()}}},[t._v("Clear")]),a("div",{staticClass:"my-auto mr-2"},[a("input",{directives:[{name:"model",rawName:"v-model",value:t.autoscroll,expression:"autoscroll"}],staticClass:"mr-1",attrs:{type:"checkbox",id:"autoscroll__checkbox"},domProps:{checked:Array.isArray(t.autoscroll)?t._i(t.autoscroll,null)>-1:t.autoscroll},on:{change:function(e){var a=t.autoscroll,s=e.target,i=!!s.checked;if(Array.isArray(a)){var n=null,r=t._i(a,n);s.checked?r<0&&(t.autoscroll=a.concat([n])):r>-1&&(t.autoscroll=a.slice(0,r).concat(a.slice(r+1)))}else t.autoscroll=i}}}),a("label",{attrs:{for:"autoscroll__checkbox"}},[t._v("Autoscroll")])]),a("div",{staticClass:"my-auto mr-2"},[a("input",{directives:[{name:"model",rawName:"v-model",value:t.line_limit,expression:"line_limit"}],staticClass:"mr-1",staticStyle:{width:"60px"},attrs:{type:"number",id:"max__lines"},domProps:{value:t.line_limit},on:{input:function(e){e.target.composing||(t.line_limit=e.target.value)}}}),a("label",{attrs:{for:"autoscroll__checkbox"}},[t._v("Line Limit")])])])])},tt=[],et=(a("caad"),a("2532"),{name:"Console",props:["wsurl","limit","auto"],data:function(){return{ws:void 0,ping_interval:void 0,connected:!1,autoscroll:!0,line_limit:this.limit,entries:[],white:["level","time","message"],levels:{trace:["TRC","primary"],debug:["DBG","warning"],info:["INF","success"],warn:["WRN","danger"],error:["ERR","danger"],fatal:["FTL","danger"],panic:["PNC","danger"],"":["???","white"]}}},mounted:function(){this.auto&&this.connect()},methods:{toggle_connection:function(){this.connected?(this.ws.close(),this.addentry({message:"Disconnecting"})):this.connect()},clear:function(){this.entries=[],this.addentry({message:"Cleared logs"})},connect:function(){this.ws=new WebSocket(("http:"==window.location.protocol?"ws://":"wss://")+window.location.host+this.wsurl),this.ws.onopen=this.onopen,this.ws.onmessage=this.onmessage,this.ws.onclose=this.onclose,this.ws.onerror=this.onerror},addentry:function(t){var e=this,a={time:b()(t.time).format("MMM D HH:mm:ss"),level:t.level,message:t.message,args:{}};for(var s in t)this.white.includes(s)||(a.args[s]=t[s]);this.entries.push(a),this.line_limit>0&&(this.entries=this.entries.length>=this.line_limit?this.entries.slice(Math.max(this.entries.length-this.line_limit,1)):this.entries),this.autoscroll&&this.$nextTick((function(){var t=e.$el.querySelector(".console");t.scrollTop=t.scrollHeight}))},onopen:function(){var t=this;this.connected=!0,this.addentry({message:"Connected to console websocket"}),this.ping_interval=setInterval((function(){t.ws.send(JSON.stringify({op:1}))}),15e3)},onmessage:function(t){this.addentry(JSON.parse(t.data))},onclose:function(){this.addentry({message:"Connection was closed"}),this.connected=!1,clearInterval(this.ping_interval)},onerror:function(){this.addentry({message:"Encountered error"})}}}),at=et,st=(a("e8a4"),Object(w["a"])(at,X,tt,!1,null,"7fd6cd58",null)),it=st.exports,nt=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",["checkbox"==t.type?a("div",{staticClass:"form-check"},[a("input",{staticClass:"form-check-input",attrs:{type:"checkbox",id:t.id,disabled:t.disabled},domProps:{checked:t.value},on:{change:function(e){return t.updateValue(e.target.checked)}}}),a("label",{staticClass:"form-check-label",attrs:{for:t.id}},[t._v(t._s(t.label))])]):"text"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("input",{staticClass:"form-control",attrs:{type:"text",id:t.id,disabled:t.disabled,placeholder:t.placeholder},domProps:{value:t.value},on:{change:function(e){return t.updateValue(e.target.value)}}})]):"list"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("input",{staticClass:"form-control",attrs:{type:"text",id:t.id,placeholder:"A,B,C",disabled:t.disabled},domProps:{value:t.value},on:{change:function(e){t.updateValue(Array.from(new Set(e.target.value.split(","))))}}})]):"number"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("input",{staticClass:"form-control",attrs:{type:"number",id:t.id,disabled:t.disabled,placeholder:t.placeholder},domProps:{value:t.value},on:{change:function(e){t.updateValue(Number(e.target.value))}}})]):"password"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("div",{staticClass:"input-group"},[a("input",{staticClass:"form-control",attrs:{type:"password",id:t.id,autocomplete:"",disabled:t.disabled,placeholder:t.placeholder},domProps:{value:t.value},on:{change:function(e){return t.updateValue(e.target.value)}}}),a("button",{directives:[{name:"clipboard",rawName:"v-clipboard:copy",value:t.value,expression:"value",arg:"copy"}],staticClass:"btn btn-outline-dark",attrs:{type:"button"}},[t._v(" Copy ")])])]):"select"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("select",{staticClass:"form-select",attrs:{id:t.id,disabled:t.disabled},on:{change:function(e){return t.updateValue(e.target.value)}}},t._l(t.values,(function(e,s){return a("option",{key:s,domProps:{selected:e==t.value}},[t._v(t._s(e))])})),0)]):"intent"==t.type?a("div",{staticClass:"mb-3 row pb-4"},[a("label",{staticClass:"col-sm-3 form-label",attrs:{for:"managerBotIntents"}},[t._v(t._s(t.label))]),a("div",{staticClass:"col-sm-9"},[a("input",{staticClass:"form-control",attrs:{type:"number",min:"0",max:"32767",disabled:t.disabled},domProps:{value:t.value},on:{change:function(e){e.target.value=32767&e.target.value,t.updateValue(Number(e.target.value)),t.fromIntents(e.target.value)},input:function(e){t.updateValue(Number(e.target.value)),t.fromIntents(e.target.value)}}}),a("div",{staticClass:"form-row py-2"},t._l(this.intents,(function(e,s){return a("div",{key:s,staticClass:"form-check form-check-inline col-sm-8 col-md-5"},[a("input",{directives:[{name:"model",rawName:"v-model",value:t.selectedIntent,expression:"selectedIntent"}],staticClass:"form-check-input",attrs:{type:"checkbox",id:t.id+s},domProps:{value:s,checked:Array.isArray(t.selectedIntent)?t._i(t.selectedIntent,s)>-1:t.selectedIntent},on:{change:[function(e){var a=t.selectedIntent,i=e.target,n=!!i.checked;if(Array.isArray(a)){var r=s,o=t._i(a,r);i.checked?o<0&&(t.selectedIntent=a.concat([r])):o>-1&&(t.selectedIntent=a.slice(0,o).concat(a.slice(o+1)))}else t.selectedIntent=n},function(e){return t.calculateIntent()}]}}),a("label",{staticClass:"form-check-label",attrs:{for:"managerBotIntentBox"+s}},[t._v(t._s(e))])])})),0)])]):"presence"==t.type?a("div",{staticClass:"mb-3 row pb-4"},[a("label",{staticClass:"col-sm-3 col-form-label"},[t._v(t._s(t.label))]),a("div",{staticClass:"col-sm-9"},[a("div",{staticClass:"mb-3"},[a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id+"status"}},[t._v("Status")]),a("select",{staticClass:"form-select",attrs:{id:t.id+"status"},domProps:{value:t.value.status},on:{input:function(e){t.value.status=e.target.value}}},t._l(["","online","dnd","idle","invisible","offline"],(function(e){return a("option",{key:e,attrs:{disabled:!e},domProps:{selected:e==t.value}},[t._v(t._s(e))])})),0)]),a("div",{staticClass:"mb-3"},[a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id+"name"}},[t._v("Name")]),a("input",{staticClass:"form-control",attrs:{type:"text",id:t.id+"name"},domProps:{value:t.value.name},on:{input:function(e){t.value.name=e.target.value}}})]),a("div",{staticClass:"form-check"},[a("input",{staticClass:"form-check-input",attrs:{type:"checkbox",id:t.id+"afk"},domProps:{checked:t.value.afk},on:{input:function(e){t.value.afk=e.target.checked}}}),a("label",{staticClass:"form-check-label",attrs:{for:t.id+"afk"}},[t._v("AFK")])])])]):"kvpair"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("div",{staticClass:"mx-2"},[a("div",{staticClass:"row mb-2 pb-2 border-bottom"},[a("input",{directives:[{name:"model",rawName:"v-model",value:t.keyValue,expression:"keyValue"}],staticClass:"form-control col mx-1",attrs:{type:"text",disabled:t.disabled,placeholder:"Key"},domProps:{value:t.keyValue},on:{input:function(e){e.target.composing||(t.keyValue=e.target.value)}}}),a("input",{directives:[{name:"model",rawName:"v-model",value:t.valueValue,expression:"valueValue"}],staticClass:"form-control col mx-1",attrs:{type:"text",disabled:t.disabled,placeholder:"Value"},domProps:{value:t.valueValue},on:{input:function(e){e.target.composing||(t.valueValue=e.target.value)}}}),a("button",{staticClass:"btn btn-dark col-1 mx-1",on:{click:function(e){return t.addKV(t.keyValue,t.valueValue)}}},[t._v(" + ")])]),t._l(t.kvStore,(function(e,s){return a("div",{key:s},[a("div",{staticClass:"row mb-1"},[a("input",{directives:[{name:"model",rawName:"v-model",value:e.key,expression:"kv.key"}],staticClass:"form-control col mx-1",domProps:{value:e.key},on:{change:function(e){t.updateValue(t.toKVdict(t.kvStore))},input:function(a){a.target.composing||t.$set(e,"key",a.target.value)}}}),a("input",{directives:[{name:"model",rawName:"v-model",value:e.value,expression:"kv.value"}],staticClass:"form-control col mx-1",domProps:{value:e.value},on:{change:function(e){t.updateValue(t.toKVdict(t.kvStore))},input:function(a){a.target.composing||t.$set(e,"value",a.target.value)}}}),a("button",{staticClass:"btn btn-dark col-1 mx-1",on:{click:function(a){return t.removeKV(e.key)}}},[t._v(" - ")])])])}))],2)]):a("span",{staticClass:"badge bg-warning text-dark"},[t._v('Invalid type "'+t._s(t.type)+'" for "'+t._s(t.id)+'"')])])},rt=[],ot=(a("4de4"),a("4160"),a("c975"),a("4fad"),a("ac1f"),a("1276"),a("159b"),{props:["type","id","label","values","value","disabled","placeholder"],data:function(){return{intents:["GUILDS","GUILD_MEMBERS","GUILD_BANS","GUILD_INTEGRATIONS","GUILD_EMOJIS","GUILD_WEBHOOKS","GUILD_INVITES","GUILD_VOICE_STATES","GUILD_PRESENCES","GUILD_MESSAGES","GUILD_MESSAGE_REACTIONS","GUILD_MESSAGE_TYPING","DIRECT_MESSAGES","DIRECT_MESSAGE_REACTIONS","DIRECT_MESSAGE_TYPING"],selectedIntent:[],kvStore:[],keyValue:"",valueValue:""}},mounted:function(){"intent"==this.type&&this.fromIntents(this.value),"kvpair"==this.type&&(this.kvStore=this.fromKVdict(this.value))},methods:{removeKV:function(t){this.kvStore=this.kvStore.filter((function(e){return e.key!=t})),this.updateValue(this.toKVdict(this.kvStore))},addKV:function(t,e){""!=t&&""!=e&&(this.kvStore.push({key:t,value:e}),this.updateValue(this.toKVdict(this.kvStore)))},fromKVdict:function(t){var e=[];return Object.entries(t).forEach((function(t){e.push({key:t[0],value:t[1]})})),console.log(t,e),e},toKVdict:function(t){var e={};return this.kvStore.forEach((function(t){e[t.key]=t.value})),console.log(t,e),e},calculateIntent:function(){var t=this;this.intentValue=0,this.selectedIntent.forEach((function(e){t.intentValue+=1<<e})),this.updateValue(Number(this.intentValue))},fromIntents:function(t){var e=this,a=Number(t).toString(2).split("").reverse(),s=[];a.forEach((function(t,a){"1"===t&&-1===e.selectedIntent.indexOf(t)&&s.push(a)})),this.selectedIntent=s},updateValue:function(t){this.$emit("input",t)}}}),lt=ot,ct=Object(w["a"])(lt,nt,rt,!1,null,null,null),ut=ct.exports,dt=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"submit"},on:{click:t.onClick}},[t._v(" "+t._s(t.label)+" ")])},gt=[],mt={name:"FormSubmit",props:{label:{default:"Save Changes"}},methods:{onClick:function(){this.$emit("click")}}},pt=mt,ft=Object(w["a"])(pt,dt,gt,!1,null,null,null),bt=ft.exports,ht=a("1fca"),vt={name:"LineChart",extends:ht["a"],mixins:[ht["b"].reactiveProp],props:["chartData","options"],mounted:function(){this.renderChart(this.chartData,this.options)}},_t=vt,Ct=Object(w["a"])(_t,s,i,!1,null,null,null),yt=Ct.exports,kt=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"progress"},t._l(this.keys,(function(e,s){return a("div",{key:s,class:"progress-bar bg-"+t.colours[s],style:"width: "+e/t.total*100+"%",attrs:{role:"progressbar","aria-valuenow":e/t.total*100,"aria-valuemin":"0","aria-valuemax":"100"}})})),0)},xt=[],Dt={name:"StatusGraph",props:["value","colours"],data:function(){return{keys:{},total:0}},watch:{value:function(){this.loadValues()}},mounted:function(){this.loadValues()},methods:{loadValues:function(){this.keys={},this.total=0;var t=Object.values(this.value.shards);for(var e in t){var a=t[e];a.status in this.keys?this.keys[a.status]++:this.keys[a.status]=1,this.total++}this.$forceUpdate()}}},wt=Dt,St=Object(w["a"])(wt,kt,xt,!1,null,null,null),Mt=St.exports,Gt={components:{CardDisplay:Z,Console:it,FormInput:ut,FormSubmit:bt,LineChart:yt,StatusGraph:Mt,SvgIcon:y["a"]},name:"Dashboard",data:function(){return{mdiAlertCircle:k["a"],mdiConnection:k["b"],mdiTextBoxMultiple:k["i"],mdiWebhook:k["k"],mdiDoorOpen:k["d"],mdiDatabase:k["c"],mdiForum:k["f"],mdiMessageProcessing:k["h"],mdiWeb:k["j"],mdiFilter:k["e"],webhook_url:"",fetch_task:void 0,version:"...",loading:!0,error:!1,userid:void 0,loadingRestTunnel:!0,loadingAnalytics:!0,rest_tunnel_enabled:!0,waiting:"...",managers:{},configuration:{},mq_drivers:[],toast:{title:"",body:""},analytics:{chart:{},uptime:"...",visible:"...",events:"...",online:"...",colour:"bg-success"},resttunnel:{charts:{hits:{},misses:{},waiting:{},requests:{},callbacks:{},average_response:{}},numbers:{hits:0,misses:0,requests:0,waiting:0},uptime:"..."},createShardGroupDialogueData:{manager:"",autoShard:!0,shardCount:1,autoIDs:!0,shardIDs:"",startImmediately:!0},stopShardGroupDialogueData:{manager:"",shardgroup:0},createManagerDialogueData:{identifier:"",persist:!0,token:"",prefix:"",client:"",channel:""},deleteManagerDialogueData:{confirm:"",manager:""},restartManagerDialogueData:{confirm:"",manager:""},statusShard:["Idle","Waiting","Connecting","Connected","Ready","Reconnecting","Closed"],colourShard:["dark","info","info","info","success","warn","secondary"],statusGroup:["Idle","Starting","Connecting","Ready","Replaced","Closing","Closed","Error"],colourGroup:["dark","info","info","success","info","warn","dark","danger"],colourCluster:["dark","info","info","success","warn","warn","dark","danger"],chartOptions:{ratelimitHits:{title:{display:!0,text:"Ratelimit Hits"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{fixedStepSize:1}}],xAxes:[{type:"time"}]},animation:{duration:0}},ratelimitMisses:{title:{display:!0,text:"Ratelimit Misses"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{fixedStepSize:1}}],xAxes:[{type:"time"}]},animation:{duration:0}},waitingRequests:{title:{display:!0,text:"Waiting requests"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{fixedStepSize:1}}],xAxes:[{type:"time"}]},animation:{duration:0}},totalRequests:{title:{display:!0,text:"Total requests"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{fixedStepSize:1}}],xAxes:[{type:"time"}]},animation:{duration:0}},callbackBuffer:{title:{display:!0,text:"Callback buffer"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{fixedStepSize:1}}],xAxes:[{type:"time"}]},animation:{duration:0}},averageResponse:{title:{display:!0,text:"Average response"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{beginAtZero:!0}}],xAxes:[{type:"time"}]},animation:{duration:0},tooltips:{callbacks:{label:function(t,e){return e.datasets[t.datasetIndex].label+": "+t.yLabel+"ms"}}}},events:{title:{display:!0,text:"Events"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events"}],xAxes:[{type:"time"}]},animation:{duration:0}}}}},filters:{pretty:function(t){return JSON.stringify(t,null,2)}},mounted:function(){var t=this;this.toastModal=new p["b"](document.getElementById("toast"),{delay:2e3}),this.fetchConfiguration(),this.fetch_task=window.setInterval((function(){t.pollData()}),5e3),this.pollData()},methods:{validURL:function(t){try{return new URL(t),!0}catch(e){return!1}},sendRPC:function(t,e,a){var s=this;u.a.post("/api/rpc",{method:t,data:e,id:a}).then((function(e){var a=e.data.error;return e.data.success?s.showToast(t,"Executed successfuly"):s.showToast("Error executing "+t,a),e})).catch((function(t){s.showToast("Exception sending RPC",t)}))},erroredManagers:function(){var t=[];for(var e in this.managers){var a=JSON.parse(JSON.stringify(this.managers[e]));if(a.status=0,a.shard_groups.length>0&&(a.status=a.shard_groups.slice(-1)[0].status),""!=a.error&&(a.status=7),7===a.status){t.push(a);break}}return t},erroredShardGroups:function(){var t=[];for(var e in this.managers){var a=this.managers[e];for(var s in a.shard_groups){var i=JSON.parse(JSON.stringify(a.shard_groups[s]));for(var n in i.manager=a.configuration.display_name,i.shards){var r=i.shards[n];if(7===r.status){t.push(i);break}}}}return t},loadingShardGroups:function(){var t=[];for(var e in this.managers){var a=this.managers[e];for(var s in a.shard_groups){var i=JSON.parse(JSON.stringify(a.shard_groups[s]));for(var n in i.manager=a.configuration.display_name,i.shards){var r=i.shards[n];if(0===r.status){t.push(i);break}}}}return t},showToast:function(t,e){this.toast.title=t,this.toast.body=e,this.toastModal.show()},addWebhook:function(t){var e=this;this.sendRPC("daemon:add_webhook",t),setTimeout((function(){return e.fetchConfiguration()}),1e3)},testWebhook:function(t){this.sendRPC("daemon:test_webhook",t)},removeWebhook:function(t){var e=this;this.sendRPC("daemon:remove_webhook",t),setTimeout((function(){return e.fetchConfiguration()}),1e3)},verifyRestTunnel:function(){this.sendRPC("daemon:verify_resttunnel")},saveClusterSettings:function(t){this.sendRPC("manager:update",t.configuration)},saveDaemonSettings:function(){this.sendRPC("daemon:update",this.configuration)},stopShardGroupDialogue:function(t,e){this.stopShardGroupDialogueModal=new p["a"](document.getElementById("stopShardGroupDialogue"),{}),this.stopShardGroupDialogueData.manager=t,this.stopShardGroupDialogueData.shardgroup=e,this.stopShardGroupDialogueModal.show()},stopShardGroup:function(){var t=this,e=Object.assign({},this.stopShardGroupDialogueData);this.sendRPC("manager:shardgroup:stop",e),setTimeout((function(){return t.pollData()}),1e3),this.stopShardGroupDialogueModal.hide()},deleteShardGroup:function(t,e){var a=this,s={manager:t,shardgroup:e};this.sendRPC("manager:shardgroup:delete",s),setTimeout((function(){return a.pollData()}),1e3)},refreshGateway:function(t){var e=this,a={manager:t};this.sendRPC("manager:refresh_gateway",a),setTimeout((function(){return e.pollData()}),1e3)},createManagerDialogue:function(){this.createManagerDialogueModal=new p["a"](document.getElementById("createManagerDialogue"),{}),this.createManagerDialogueData.identifier="",this.createManagerDialogueData.persist=!0,this.createManagerDialogueData.token="",this.createManagerDialogueData.prefix="",this.createManagerDialogueData.client="",this.createManagerDialogueData.channel="",this.createManagerDialogueModal.show()},createManager:function(){var t=this;this.sendRPC("manager:create",this.createManagerDialogueData),setTimeout((function(){return t.fetchConfiguration()}),1e3),this.createManagerDialogueModal.hide()},deleteManagerDialogue:function(t){this.deleteManagerDialogueModal=new p["a"](document.getElementById("deleteManagerDialogue"),{}),this.deleteManagerDialogueData={confirm:"",manager:t},this.deleteManagerDialogueModal.show()},deleteManager:function(){var t=this;this.sendRPC("manager:delete",this.deleteManagerDialogueData),setTimeout((function(){return t.fetchConfiguration()}),1e3),this.deleteManagerDialogueModal.hide()},restartManagerDialogue:function(t){this.restartManagerDialogueModal=new p["a"](document.getElementById("restartManagerDialogue"),{}),this.restartManagerDialogueData={confirm:"",manager:t},this.restartManagerDialogueModal.show()},restartManager:function(){var t=this;this.sendRPC("manager:restart",this.restartManagerDialogueData),setTimeout((function(){return t.fetchConfiguration()}),1e3),this.restartManagerDialogueModal.hide()},createShardGroupDialogue:function(t){this.createShardGroupDialogueModal=new p["a"](document.getElementById("createShardGroupDialogue"),{}),this.createShardGroupDialogueData.manager=t,this.createShardGroupDialogueData.autoShard=!0,this.createShardGroupDialogueData.shardCount=1,this.createShardGroupDialogueData.autoIDs=!0,this.createShardGroupDialogueData.shardIDs="",this.createShardGroupDialogueData.startImmediately=!0,this.createShardGroupDialogueModal.show()},createShardGroup:function(){var t=this,e=Object.assign({},this.createShardGroupDialogueData);e.shardCount=Number(e.shardCount),this.sendRPC("manager:shardgroup:create",e),setTimeout((function(){return t.pollData()}),1e3),this.createShardGroupDialogueModal.hide()},pollData:function(){var t=this;u.a.get("/api/poll").then((function(e){if(403===e.status&&clearInterval(t.fetch_task),!1!==e.data.success){t.error&&document.location.reload(),t.error=!e.data.success,t.rest_tunnel_enabled=e.data.data.rest_tunnel_enabled,t.uptime=e.data.data.uptime,t.waiting=e.data.data.waiting;var a=Object.keys(e.data.data.managers);for(var s in a){var i,n=a[s],r=e.data.data.managers[n];i=n in t.managers?t.managers[n]:r,i.error=r.error,i.gateway=r.gateway,i.shard_groups=r.shard_groups;var o=Object.values(r.shard_groups);i.status=0,o.length>0&&(i.status=o.slice(-1)[0].status),""!=r.error&&(i.status=7),t.$set(t.managers,n,i)}t.analytics=e.data.data.analytics;var l=0,c=0,u=0;for(s in t.analytics.colour="bg-success",a=Object.values(t.analytics.managers),a)for(var d in r=a[s],u+=r.guilds,o=Object.values(r.status),o){var g=o[d];2<g&&g<4&&l++,c++}t.analytics.visible=u,t.analytics.online=l+"/"+c,e.data.data.rest_tunnel_enabled&&(t.resttunnel.charts=e.data.data.resttunnel.data.charts,t.resttunnel.uptime=e.data.data.resttunnel.data.uptime,t.resttunnel.numbers=e.data.data.resttunnel.data.numbers)}})).catch((function(e){var a;403===(null===(a=e.response)||void 0===a?void 0:a.status)&&clearInterval(t.fetch_task),t.showToast("Exception fetching manager data",e)})).finally((function(){t.loading=!1,t.loadingAnalytics=!1,t.loadingRestTunnel=!1}))},fetchConfiguration:function(){var t=this;u.a.get("/api/configuration").then((function(e){403===e.status&&clearInterval(t.fetch_task),t.$root.version=e.data.data.version,t.configuration=e.data.data.configuration,t.rest_tunnel_enabled=e.data.data.rest_tunnel_enabled,t.mq_drivers=e.data.data.mq_drivers,t.error=!e.data.success})).catch((function(e){var a;403===(null===(a=e.response)||void 0===a?void 0:a.status)&&clearInterval(t.fetch_task),t.showToast("Exception fetching configuration",e)})).finally((function(){t.loading=!1}))},calculateAverage:function(t){var e=0,a=0,s=Object.values(t.shard_groups);for(var i in s){var n=s[i];if(n.status<6){var r=Object.values(n.shards);for(var o in r){var l=r[o],c=new Date(l.last_heartbeat_ack)-new Date(l.last_heartbeat_sent);c>0&&(a+=c,e+=1)}}}return Math.round(a/e)||"-"},calculateAverageShardGroup:function(t){var e=0,a=0,s=Object.values(t.shards);for(var i in s){var n=s[i],r=new Date(n.last_heartbeat_ack)-new Date(n.last_heartbeat_sent);r>0&&(a+=r,e+=1)}return Math.round(a/e)||"-"},since:function(t){var e=new Date(t),a=((new Date).getTime()-e)/1e3,s="";if(a>86400){var i=Math.trunc(a/86400);i>0&&(s+=i+"d"),a%=86400}if(a>3600){var n=Math.trunc(a/3600);n>0&&(s+=n+"h"),a%=3600}if(a>60){var r=Math.trunc(a/60);r>0&&(s+=r+"m");var o=Math.ceil(a%60);o>0&&(s+=o+"s")}return s}}},$t=Gt,It=(a("73c0"),a("c044"),Object(w["a"])($t,J,F,!1,null,"22ba42eb",null)),Et=It.exports;n["a"].use(A["a"]);var At=[{path:"/",name:"Index",component:B},{path:"/dashboard",name:"Dashboard",component:Et},{path:"*",component:N}],Ot=new A["a"]({mode:"history",base:"/",routes:At}),Rt=Ot,jt=a("9483");Object(jt["a"])("".concat("/","service-worker.js"),{ready:function(){console.log("App is being served from cache by a service worker.\nFor more details, visit https://goo.gl/AFskqB")},registered:function(){console.log("Service worker has been registered.")},cached:function(){console.log("Content has been cached for offline use.")},updatefound:function(){console.log("New content is downloading.")},updated:function(){console.log("New content is available; please refresh.")},offline:function(){console.log("No internet connection found. App is running in offline mode.")},error:function(t){console.error("Error during service worker registration:",t)}});var Pt=g()({storeAsString:!0});n["a"].config.productionTip=!1,n["a"].use(m["a"]),n["a"].use(l.a),n["a"].use(r["a"]);var Tt=new r["a"].Store({state:{error:"",userLoading:!0,userAuthenticated:!1,user:{}}});new n["a"]({store:Tt,router:Rt,render:function(t){return t(E)},mounted:function(){this.fetchMe()},data:function(){return{version:"🥪"}},methods:{fetchMe:function(){u.a.get("/api/me",{transformResponse:[function(t){return Pt.parse(t)}]}).then((function(t){var e=t.data;e.success?(Tt.state.userAuthenticated=e.data.authenticated,Tt.state.user=e.data.user):Tt.state.error=e.error})).catch((function(t){var e;(null===(e=t.response)||void 0===e?void 0:e.data)?Tt.state.error=t.response.data.error||t.response.data:Tt.state.error=t.text||t.toString()})).finally((function(){Tt.state.userLoading=!1}))}}}).$mount("#app")},"73c0":function(t,e,a){"use strict";a("9766")},"94a4":function(t,e,a){},9766:function(t,e,a){},c044:function(t,e,a){"use strict";a("cd0e")},cd0e:function(t,e,a){},e8a4:function(t,e,a){"use strict";a("94a4")},fd21:function(t,e,a){var s={"./en-au":"0e6b","./en-au.js":"0e6b","./en-ca":"3886","./en-ca.js":"3886","./en-gb":"39a6","./en-gb.js":"39a6","./en-ie":"e1d3","./en-ie.js":"e1d3","./en-il":"7333","./en-il.js":"7333","./en-in":"ec2e","./en-in.js":"ec2e","./en-nz":"6f50","./en-nz.js":"6f50","./en-sg":"b7e9","./en-sg.js":"b7e9"};function i(t){var e=n(t);return a(e)}function n(t){if(!a.o(s,t)){var e=new Error("Cannot find module '"+t+"'");throw e.code="MODULE_NOT_FOUND",e}return s[t]}i.keys=function(){return Object.keys(s)},i.resolve=n,t.exports=i,i.id="fd21"}});
\ No newline at end of file
+(function(t){function e(e){for(var s,r,o=e[0],l=e[1],c=e[2],d=0,g=[];d<o.length;d++)r=o[d],Object.prototype.hasOwnProperty.call(i,r)&&i[r]&&g.push(i[r][0]),i[r]=0;for(s in l)Object.prototype.hasOwnProperty.call(l,s)&&(t[s]=l[s]);u&&u(e);while(g.length)g.shift()();return n.push.apply(n,c||[]),a()}function a(){for(var t,e=0;e<n.length;e++){for(var a=n[e],s=!0,o=1;o<a.length;o++){var l=a[o];0!==i[l]&&(s=!1)}s&&(n.splice(e--,1),t=r(r.s=a[0]))}return t}var s={},i={app:0},n=[];function r(e){if(s[e])return s[e].exports;var a=s[e]={i:e,l:!1,exports:{}};return t[e].call(a.exports,a,a.exports,r),a.l=!0,a.exports}r.m=t,r.c=s,r.d=function(t,e,a){r.o(t,e)||Object.defineProperty(t,e,{enumerable:!0,get:a})},r.r=function(t){"undefined"!==typeof Symbol&&Symbol.toStringTag&&Object.defineProperty(t,Symbol.toStringTag,{value:"Module"}),Object.defineProperty(t,"__esModule",{value:!0})},r.t=function(t,e){if(1&e&&(t=r(t)),8&e)return t;if(4&e&&"object"===typeof t&&t&&t.__esModule)return t;var a=Object.create(null);if(r.r(a),Object.defineProperty(a,"default",{enumerable:!0,value:t}),2&e&&"string"!=typeof t)for(var s in t)r.d(a,s,function(e){return t[e]}.bind(null,s));return a},r.n=function(t){var e=t&&t.__esModule?function(){return t["default"]}:function(){return t};return r.d(e,"a",e),e},r.o=function(t,e){return Object.prototype.hasOwnProperty.call(t,e)},r.p="/";var o=window["webpackJsonp"]=window["webpackJsonp"]||[],l=o.push.bind(o);o.push=e,o=o.slice();for(var c=0;c<o.length;c++)e(o[c]);var u=l;n.push([0,"chunk-vendors"]),a()})({0:function(t,e,a){t.exports=a("56d7")},"034f":function(t,e,a){"use strict";a("4ff2")},"4fd5":function(t,e,a){var s={"./en-au":"64fc","./en-au.js":"64fc","./en-ca":"d02e","./en-ca.js":"d02e","./en-gb":"e4ab","./en-gb.js":"e4ab","./en-ie":"2078","./en-ie.js":"2078","./en-il":"30ea","./en-il.js":"30ea","./en-in":"0929","./en-in.js":"0929","./en-nz":"9e37","./en-nz.js":"9e37","./en-sg":"5930","./en-sg.js":"5930"};function i(t){var e=n(t);return a(e)}function n(t){if(!a.o(s,t)){var e=new Error("Cannot find module '"+t+"'");throw e.code="MODULE_NOT_FOUND",e}return s[t]}i.keys=function(){return Object.keys(s)},i.resolve=n,t.exports=i,i.id="4fd5"},"4ff2":function(t,e,a){},"56d7":function(t,e,a){"use strict";a.r(e);a("d3b7"),a("25f0"),a("e623"),a("e379"),a("5dc8"),a("37e1");var s,i,n=a("2b0e"),r=a("2f62"),o=a("4eb5"),l=a.n(o),c=a("bc3a"),u=a.n(c),d=a("d604"),g=a.n(d),m=a("0c37"),p=a("7b17"),f=(a("ab8b"),a("2dd8"),a("c1df")),b=a.n(f),h=(a("4a68"),function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{attrs:{id:"app"}},[a("div",{},[a("nav",{staticClass:"navbar navbar-expand-sm navbar-dark bg-dark"},[a("div",{staticClass:"container-fluid"},[a("router-link",{staticClass:"navbar-brand",attrs:{to:"/"}},[t._v(" Sandwich Daemon "),a("span",{staticClass:"text-secondary h6"},[t._v("Version "+t._s(t.$root.version))])]),t._m(0),a("div",{staticClass:"collapse navbar-collapse justify-content-end",attrs:{id:"navbarSupportedContent"}},[a("ul",{staticClass:"navbar-nav"},[t._m(1),t.$store.state.user&&t.$store.state.user.id?a("li",{staticClass:"nav-item"},[a("router-link",{staticClass:"nav-link",attrs:{"aria-current":"page",to:"/dashboard"}},[t._v("Dashboard")])],1):t._e(),t.$store.state.user&&t.$store.state.user.id?a("li",{staticClass:"nav-item"},[a("span",{staticClass:"nav-link dropdown-toggle",attrs:{id:"userDropdown",role:"button","data-toggle":"dropdown","aria-expanded":"false"}},[a("img",{attrs:{width:"24",height:"24",src:"https://cdn.discordapp.com/avatars/"+t.$store.state.user.id+"/"+t.$store.state.user.avatar+".png?size=32",alt:"User profile picture"}})]),a("ul",{staticClass:"dropdown-menu dropdown-menu-right",attrs:{"aria-label":"Profile"}},[a("li",[a("span",{staticClass:"dropdown-item"},[t._v(" Signed in as "),a("b",[t._v(t._s(t.$store.state.user.username))])])]),t._m(2),t._m(3)])]):a("li",{staticClass:"nav-item"},[a("a",{staticClass:"nav-link",attrs:{"aria-current":"page",href:"login"}},[t._v("Login")])])])])],1)]),t.$store.state.error?a("div",{staticClass:"text-center py-5"},[a("error",{attrs:{message:t.$store.state.error}})],1):a("div",[a("router-view")],1)])])}),v=[function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"navbar-toggler",attrs:{type:"button","data-toggle":"collapse","data-target":"#navbarSupportedContent","aria-controls":"navbarSupportedContent","aria-expanded":"false","aria-label":"Toggle navigation"}},[a("span",{staticClass:"navbar-toggler-icon"})])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("li",{staticClass:"nav-item"},[a("a",{staticClass:"nav-link",attrs:{"aria-current":"page",href:"https://github.com/TheRockettek/Sandwich-Daemon"}},[t._v("GitHub")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("li",[a("hr",{staticClass:"dropdown-divider"})])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("li",[a("a",{staticClass:"dropdown-item",attrs:{href:"logout"}},[t._v("Sign Out")])])}],_=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"text-center my-5"},[a("svg-icon",{staticClass:"mb-2 text-muted",attrs:{type:"mdi",width:64,height:64,path:t.mdiAlertCircle}}),a("h3",[t._v("Oops, something happened.")]),a("p",{staticClass:"m-auto my-2 col-10 text-muted"},[t._v(" Something unexpected happened whilst handling your request, sorry. ")]),a("kbd",[t._v(t._s(this.message))])],1)},C=[],y=a("1fee"),k=a("94ed"),x={components:{SvgIcon:y["a"]},props:["message"],data:function(){return{mdiAlertCircle:k["a"]}}},D=x,w=a("2877"),S=Object(w["a"])(D,_,C,!1,null,null,null),M=S.exports,G={components:{Error:M}},$=G,I=(a("034f"),Object(w["a"])($,h,v,!1,null,null,null)),E=I.exports,A=a("8c4f"),O=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"text-center my-5"},[a("svg-icon",{staticClass:"mb-2 text-muted",attrs:{type:"mdi",width:64,height:64,path:t.mdiHelpRhombus}}),a("h3",[t._v("404")]),a("p",{staticClass:"m-auto my-2 col-10 text-muted"},[t._v(" It seems you are not where you want to be... ")]),a("kbd",[t._v(t._s(this.$route.path))])],1)},R=[],j={components:{SvgIcon:y["a"]},name:"NotFoundPage",data:function(){return{mdiHelpRhombus:k["g"]}}},P=j,T=Object(w["a"])(P,O,R,!1,null,null,null),N=T.exports,L=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"container-sm d-flex"},[a("div",{staticClass:"align-items-center text-center m-auto d-flex flex-wrap mt-5"},[a("img",{staticClass:"justify-content-start m-auto flex-grow-1 p-2",attrs:{width:"128",height:"128",src:t.items[t.item%t.items.length]},on:{click:function(e){t.item++}}}),a("div",{staticClass:"justify-content-end text-center flex-grow-2 mx-auto p-2"},[a("h1",[t._v("Sandwich Daemon")]),t._m(0),a("router-link",{staticClass:"ml-1",attrs:{to:"/dashboard"}},[a("button",{staticClass:"btn btn-dark",attrs:{type:"button"}},[t._v(" Dashboard ")])])],1)])])},V=[function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("a",{staticClass:"mr-1",attrs:{href:"https://github.com/TheRockettek/Sandwich-Daemon"}},[a("button",{staticClass:"btn btn-dark",attrs:{type:"button"}},[t._v("Github")])])}],U={name:"Index",data:function(){return{item:0,items:["/img/brand.svg","/img/comfy.svg"]}}},q=U,W=Object(w["a"])(q,L,V,!1,null,null,null),B=W.exports,J=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"container-xl p-3"},[t.$store.state.userLoading?a("div",[t._m(0)]):t.$store.state.userAuthenticated?this.loading?t._e():a("div",[a("div",{staticClass:"modal fade",attrs:{id:"createShardGroupDialogue",tabindex:"-1","aria-labelledby":"createShardGroupDialogueLabel","aria-hidden":"true"}},[a("div",{staticClass:"modal-dialog"},[a("div",{staticClass:"modal-content"},[a("div",{staticClass:"modal-header"},[a("h5",{staticClass:"modal-title",attrs:{id:"createShardGroupDialogueLabel"}},[t._v(" New ShardGroup for "+t._s(t.createShardGroupDialogueData.manager)+" ")]),t._m(1)]),a("div",{staticClass:"modal-body"},[a("div",{staticClass:"mb-3"},[a("label",{staticClass:"col-sm-12 form-label"},[t._v("Shard Count")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createShardGroupDialogueData.autoShard,expression:"createShardGroupDialogueData.autoShard"}],staticClass:"form-check-input",attrs:{type:"checkbox"},domProps:{checked:Array.isArray(t.createShardGroupDialogueData.autoShard)?t._i(t.createShardGroupDialogueData.autoShard,null)>-1:t.createShardGroupDialogueData.autoShard},on:{change:function(e){var a=t.createShardGroupDialogueData.autoShard,s=e.target,i=!!s.checked;if(Array.isArray(a)){var n=null,r=t._i(a,n);s.checked?r<0&&t.$set(t.createShardGroupDialogueData,"autoShard",a.concat([n])):r>-1&&t.$set(t.createShardGroupDialogueData,"autoShard",a.slice(0,r).concat(a.slice(r+1)))}else t.$set(t.createShardGroupDialogueData,"autoShard",i)}}}),a("label",{staticClass:"form-check-label ml-2 mb-2"},[t._v("Auto Determine")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createShardGroupDialogueData.shardCount,expression:"createShardGroupDialogueData.shardCount"}],staticClass:"form-control",attrs:{type:"number",disabled:t.createShardGroupDialogueData.autoShard},domProps:{value:t.createShardGroupDialogueData.shardCount},on:{input:function(e){e.target.composing||t.$set(t.createShardGroupDialogueData,"shardCount",e.target.value)}}})]),a("div",{staticClass:"mb-3"},[a("label",{staticClass:"col-sm-12 form-label"},[t._v("Shard IDs")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createShardGroupDialogueData.autoIDs,expression:"createShardGroupDialogueData.autoIDs"}],staticClass:"form-check-input",attrs:{type:"checkbox"},domProps:{checked:Array.isArray(t.createShardGroupDialogueData.autoIDs)?t._i(t.createShardGroupDialogueData.autoIDs,null)>-1:t.createShardGroupDialogueData.autoIDs},on:{change:function(e){var a=t.createShardGroupDialogueData.autoIDs,s=e.target,i=!!s.checked;if(Array.isArray(a)){var n=null,r=t._i(a,n);s.checked?r<0&&t.$set(t.createShardGroupDialogueData,"autoIDs",a.concat([n])):r>-1&&t.$set(t.createShardGroupDialogueData,"autoIDs",a.slice(0,r).concat(a.slice(r+1)))}else t.$set(t.createShardGroupDialogueData,"autoIDs",i)}}}),a("label",{staticClass:"form-check-label ml-2 mb-2"},[t._v("Auto Determine")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createShardGroupDialogueData.shardIDs,expression:"createShardGroupDialogueData.shardIDs"}],staticClass:"form-control",attrs:{type:"text",disabled:t.createShardGroupDialogueData.autoIDs,placeholder:"from-to,from-to,from-to"},domProps:{value:t.createShardGroupDialogueData.shardIDs},on:{input:function(e){e.target.composing||t.$set(t.createShardGroupDialogueData,"shardIDs",e.target.value)}}})])]),a("div",{staticClass:"modal-footer"},[a("button",{staticClass:"btn btn-secondary",attrs:{type:"button","data-dismiss":"modal"}},[t._v(" Close ")]),a("button",{staticClass:"btn btn-success",attrs:{type:"button"},on:{click:function(e){return t.createShardGroup()}}},[t._v(" Create ShardGroup ")])])])])]),a("div",{staticClass:"modal fade",attrs:{id:"stopShardGroupDialogue",tabindex:"-1","aria-labelledby":"stopShardGroupDialogueLabel","aria-hidden":"true"}},[a("div",{staticClass:"modal-dialog"},[a("div",{staticClass:"modal-content"},[a("div",{staticClass:"modal-header"},[a("h5",{staticClass:"modal-title",attrs:{id:"stopShardGroupDialogueLabel"}},[t._v(" Stop ShardGroup "+t._s(t.stopShardGroupDialogueData.shardgroup)+" for "+t._s(t.stopShardGroupDialogueData.manager)+" ")]),t._m(2)]),t._m(3),a("div",{staticClass:"modal-footer"},[a("button",{staticClass:"btn btn-secondary",attrs:{type:"button","data-dismiss":"modal"}},[t._v(" Close ")]),a("button",{staticClass:"btn btn-danger",attrs:{type:"button"},on:{click:function(e){return t.stopShardGroup()}}},[t._v(" Stop ShardGroup ")])])])])]),a("div",{staticClass:"modal fade",attrs:{id:"createManagerDialogue",tabindex:"-1","aria-labelledby":"createManagerDialogueLabel","aria-hidden":"true"}},[a("div",{staticClass:"modal-dialog"},[a("div",{staticClass:"modal-content"},[t._m(4),a("div",{staticClass:"modal-body"},[a("form",[a("div",{staticClass:"mb-3"},[t._m(5),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createManagerDialogueData.identifier,expression:"createManagerDialogueData.identifier"}],staticClass:"form-control",attrs:{type:"text",placeholder:"Enter unique identifiable name for manager"},domProps:{value:t.createManagerDialogueData.identifier},on:{input:function(e){e.target.composing||t.$set(t.createManagerDialogueData,"identifier",e.target.value)}}})]),a("div",{staticClass:"mb-3"},[t._m(6),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createManagerDialogueData.token,expression:"createManagerDialogueData.token"}],staticClass:"form-control",attrs:{type:"password",placeholder:"Enter bot token",autocomplete:"false"},domProps:{value:t.createManagerDialogueData.token},on:{input:function(e){e.target.composing||t.$set(t.createManagerDialogueData,"token",e.target.value)}}})]),a("div",{staticClass:"mb-3"},[t._m(7),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createManagerDialogueData.client,expression:"createManagerDialogueData.client"}],staticClass:"form-control",attrs:{type:"text",placeholder:"eg: "+t.createManagerDialogueData.identifier},domProps:{value:t.createManagerDialogueData.client},on:{input:function(e){e.target.composing||t.$set(t.createManagerDialogueData,"client",e.target.value)}}}),a("p",{staticClass:"text-muted"},[t._v(" Duplicate client names may not allow the manager to start however the exact name the client uses does not impact anything ")])]),a("div",{staticClass:"mb-3"},[a("label",{staticClass:"col-sm-12 form-label"},[t._v("Channel Name")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createManagerDialogueData.channel,expression:"createManagerDialogueData.channel"}],staticClass:"form-control",attrs:{type:"text",placeholder:"Defaults to "+t.configuration.producer.configuration.channel},domProps:{value:t.createManagerDialogueData.channel},on:{input:function(e){e.target.composing||t.$set(t.createManagerDialogueData,"channel",e.target.value)}}}),a("p",{staticClass:"text-muted"},[t._v(" Keeping all your managers on a single channel name is useful as it allows multiple consumers to manage multiple managers as they all read from the same source ")])]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.createManagerDialogueData.persist,expression:"createManagerDialogueData.persist"}],staticClass:"form-check-input",attrs:{type:"checkbox"},domProps:{checked:Array.isArray(t.createManagerDialogueData.persist)?t._i(t.createManagerDialogueData.persist,null)>-1:t.createManagerDialogueData.persist},on:{change:function(e){var a=t.createManagerDialogueData.persist,s=e.target,i=!!s.checked;if(Array.isArray(a)){var n=null,r=t._i(a,n);s.checked?r<0&&t.$set(t.createManagerDialogueData,"persist",a.concat([n])):r>-1&&t.$set(t.createManagerDialogueData,"persist",a.slice(0,r).concat(a.slice(r+1)))}else t.$set(t.createManagerDialogueData,"persist",i)}}}),a("label",{staticClass:"form-check-label ml-2 mb-2"},[t._v("Persist")]),a("p",{staticClass:"text-muted"},[t._v(" When enabled, changes to Manager will be saved to the configuration. Un-check if you do not want to save the manager. ")])]),a("p",{staticClass:"text-danger"},[t._v("* Required")])]),a("div",{staticClass:"modal-footer"},[a("button",{staticClass:"btn btn-secondary",attrs:{type:"button","data-dismiss":"modal"}},[t._v(" Close ")]),a("button",{staticClass:"btn btn-success",attrs:{type:"button",disabled:""===t.createManagerDialogueData.client||""===t.createManagerDialogueData.token||""===t.createManagerDialogueData.identifier},on:{click:function(e){return t.createManager()}}},[t._v(" Create Manager ")])])])])]),a("div",{staticClass:"modal fade",attrs:{id:"deleteManagerDialogue",tabindex:"-1","aria-labelledby":"deleteManagerDialogueLabel","aria-hidden":"true"}},[a("div",{staticClass:"modal-dialog"},[a("div",{staticClass:"modal-content"},[a("div",{staticClass:"modal-header"},[a("h5",{staticClass:"modal-title",attrs:{id:"deleteManagerDialogueLabel"}},[t._v(" Delete Manager "+t._s(t.deleteManagerDialogueData.manager)+"? ")]),t._m(8)]),a("div",{staticClass:"modal-body"},[a("label",{staticClass:"form-check-label mb-2"},[t._v("Confirm deleting manager by typing "),a("b",[t._v(t._s(t.deleteManagerDialogueData.manager))])]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.deleteManagerDialogueData.confirm,expression:"deleteManagerDialogueData.confirm"}],staticClass:"form-control",attrs:{type:"text",placeholder:"Type: '"+t.deleteManagerDialogueData.manager+"'"},domProps:{value:t.deleteManagerDialogueData.confirm},on:{input:function(e){e.target.composing||t.$set(t.deleteManagerDialogueData,"confirm",e.target.value)}}})]),a("div",{staticClass:"modal-footer"},[a("button",{staticClass:"btn btn-secondary",attrs:{type:"button","data-dismiss":"modal"}},[t._v(" Close ")]),a("button",{staticClass:"btn btn-danger",attrs:{type:"button"},on:{click:function(e){return t.deleteManager()}}},[t._v(" Delete Manager ")])])])])]),a("div",{staticClass:"modal fade",attrs:{id:"restartManagerDialogue",tabindex:"-1","aria-labelledby":"restartManagerDialogueLabel","aria-hidden":"true"}},[a("div",{staticClass:"modal-dialog"},[a("div",{staticClass:"modal-content"},[a("div",{staticClass:"modal-header"},[a("h5",{staticClass:"modal-title",attrs:{id:"restartManagerDialogueLabel"}},[t._v(" Restart Manager "+t._s(t.restartManagerDialogueData.manager)+"? ")]),t._m(9)]),a("div",{staticClass:"modal-body"},[a("label",{staticClass:"form-check-label mb-2"},[t._v("Confirm restarting manager by typing its name. This will stop any currently running shardgroups")]),a("input",{directives:[{name:"model",rawName:"v-model",value:t.restartManagerDialogueData.confirm,expression:"restartManagerDialogueData.confirm"}],staticClass:"form-control",attrs:{type:"text",placeholder:"Type: '"+t.restartManagerDialogueData.manager+"'"},domProps:{value:t.restartManagerDialogueData.confirm},on:{input:function(e){e.target.composing||t.$set(t.restartManagerDialogueData,"confirm",e.target.value)}}})]),a("div",{staticClass:"modal-footer"},[a("button",{staticClass:"btn btn-secondary",attrs:{type:"button","data-dismiss":"modal"}},[t._v(" Close ")]),a("button",{staticClass:"btn btn-info",attrs:{type:"button"},on:{click:function(e){return t.restartManager()}}},[t._v(" Restart Manager ")])])])])]),a("ul",{staticClass:"list-group mb-4"},[t._l(t.erroredManagers(),(function(e,s){return a("li",{key:s,staticClass:"list-group-item list-group-item-action border-danger text-danger"},[a("svg-icon",{attrs:{type:"mdi",path:t.mdiAlertCircle}}),t._v(" Manager "+t._s(e.configuration.display_name)+" encountered an error ")],1)})),t._l(t.erroredShardGroups(),(function(e,s){return a("li",{key:s,staticClass:"list-group-item list-group-item-action border-danger text-danger"},[t._v(" "+t._s(e.manager)+" ShardGroup "+t._s(e.id)+" encountered an error ")])})),t._l(t.loadingShardGroups(),(function(e,s){return a("li",{key:s,staticClass:"list-group-item d-flex justify-content-between align-items-center border-info text-info"},[a("div",[a("svg-icon",{attrs:{type:"mdi",path:t.mdiConnection}}),a("span",[t._v(" Starting "+t._s(e.manager)+" ShardGroup "+t._s(e.id)+" ")])],1),a("status-graph",{staticStyle:{width:"50%"},attrs:{value:e,colours:t.colourShard}})],1)}))],2),t._m(10),a("div",{staticClass:"tab-content",attrs:{id:"pills-tabContent"}},[a("div",{staticClass:"tab-pane fade show active",attrs:{id:"pills-home",role:"tabpanel","aria-labelledby":"pills-home-tab"}},[a("div",{staticClass:"m-5"},[a("h3",{staticClass:"text-center text-dark"},[t._v("Sandwich Daemon")]),a("div",{staticClass:"row row-cols-1 row-cols-sm-2 row-cols-md-3 row-cols-lg-4 g-4 justify-content-center"},[a("card-display",{attrs:{title:"Guilds",value:t.analytics.guilds,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Members",value:t.analytics.members,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Users",value:t.analytics.users,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Channels",value:t.analytics.channels,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Emojis",value:t.analytics.emojis,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Online",value:t.analytics.online,bg:t.analytics.colour}}),a("card-display",{attrs:{title:"Uptime",value:t.analytics.uptime,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Events Processed",value:t.analytics.events,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Events queued",value:t.waiting,bg:t.waiting>0?"bg-danger":"bg-dark"}})],1),a("div",[t.analytics.chart?a("line-chart",{attrs:{"chart-data":t.analytics.chart,height:150,options:t.chartOptions.events}}):t._e()],1)]),t.rest_tunnel_enabled?a("div",{staticClass:"m-5"},[a("div",[a("h3",{staticClass:"text-center text-dark"},[t._v("RestTunnel")]),t.resttunnel?a("div",{staticClass:"row row-cols-1 row-cols-sm-2 row-cols-md-3 row-cols-lg-4 g-4 justify-content-center"},[a("card-display",{attrs:{title:"Requests Processed",value:t.resttunnel.numbers.requests,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Hits",value:t.resttunnel.numbers.hits,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Misses",value:t.resttunnel.numbers.misses,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Uptime",value:t.resttunnel.uptime,bg:"bg-dark"}}),a("card-display",{attrs:{title:"Waiting",value:t.resttunnel.numbers.waiting,bg:"bg-dark"}})],1):t._e(),a("div",{staticClass:"row row-cols-2 row-cols-sm-1 row-cols-md-2 row-cols-xl-3 g-4 justify-content-center"},[a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.hits?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.hits,height:300,options:t.chartOptions.ratelimitHits}}):t._e()],1),a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.misses?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.misses,height:300,options:t.chartOptions.ratelimitMisses}}):t._e()],1),a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.waiting?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.waiting,height:300,options:t.chartOptions.waitingRequests}}):t._e()],1),a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.requests?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.requests,height:300,options:t.chartOptions.totalRequests}}):t._e()],1),a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.callbacks?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.callbacks,height:300,options:t.chartOptions.callbackBuffer}}):t._e()],1),a("div",{staticClass:"col justify-content-center d-flex"},[t.resttunnel.charts.average_response?a("line-chart",{attrs:{"chart-data":t.resttunnel.charts.average_response,height:300,options:t.chartOptions.averageResponse}}):t._e()],1)])])]):t._e()]),a("div",{staticClass:"tab-pane fade",attrs:{id:"pills-managers",role:"tabpanel","aria-labelledby":"pills-managers-tab"}},[a("button",{staticClass:"btn btn-dark",attrs:{type:"button"},on:{click:function(e){return t.createManagerDialogue()}}},[t._v(" Create Manager ")]),t._l(t.managers,(function(e,s){return a("div",{key:s},[a("div",{staticClass:"accordion my-4",attrs:{id:"manager-"+e.configuration.identifier}},[a("div",{staticClass:"card"},[a("div",{staticClass:"card-header",attrs:{id:"header-manager-"+e.configuration.identifier}},[a("h2",{staticClass:"mb-0"},[a("button",{staticClass:"btn btn-link btn-block text-left text-decoration-none text-dark",attrs:{type:"button","data-toggle":"collapse","data-target":"#collapse-manager-"+e.configuration.identifier,"aria-expanded":"true","aria-controls":"collapse-manager-"+e.configuration.identifier}},[a("span",{class:"badge mr-2 bg-"+t.colourCluster[e.status]||!1},[t._v(t._s(t.statusGroup[e.status]))]),a("span",[t._v(t._s(e.configuration.display_name))])])])]),a("div",{staticClass:"collapse",attrs:{id:"collapse-manager-"+e.configuration.identifier,"aria-labelledby":"header-manager-"+e.configuration.identifier,"data-parent":"#manager-"+e.configuration.identifier}},[a("div",{staticClass:"card-body"},[a("ul",{staticClass:"nav nav-tabs",attrs:{id:"pills-tab",role:"tablist"}},[a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link active",attrs:{id:"manager-"+e.configuration.identifier+"-pills-status-tab","data-toggle":"pill",href:"#manager-"+e.configuration.identifier+"-pills-status",role:"tab","aria-controls":"manager-"+e.configuration.identifier+"-pills-status","aria-selected":"true"}},[t._v("Status")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"manager-"+e.configuration.identifier+"-pills-settings-tab","data-toggle":"pill",href:"#manager-"+e.configuration.identifier+"-pills-settings",role:"tab","aria-controls":"manager-"+e.configuration.identifier+"-pills-settings","aria-selected":"false"}},[t._v("Settings")])])]),a("div",{staticClass:"tab-content"},[a("div",{staticClass:"tab-pane fade p-4 active show",attrs:{id:"manager-"+e.configuration.identifier+"-pills-status",role:"tabpanel","aria-labelledby":"manager-"+e.configuration.identifier+"-pills-status-tab"}},[a("ul",{staticClass:"list-group mb-4"},[a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Shard Groups "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(Object.keys(e.shard_groups).length))])]),a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Average Latency "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(t.calculateAverage(e))+" ms")])]),a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Sessions "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(e.gateway.session_start_limit.remaining)+"/"+t._s(e.gateway.session_start_limit.total)+" ("+t._s(e.gateway.session_start_limit.max_concurrency)+")")])])]),a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"button"},on:{click:function(a){return t.createShardGroupDialogue(e.configuration.identifier)}}},[t._v(" Scale Manager ")]),a("button",{staticClass:"btn btn-info mr-1",attrs:{type:"button"},on:{click:function(a){return t.refreshGateway(e.configuration.identifier)}}},[t._v(" Refresh Gateway ")]),a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"button"},on:{click:function(a){return t.deleteManagerDialogue(e.configuration.identifier)}}},[t._v(" Delete Manager ")]),a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"button"},on:{click:function(a){return t.restartManagerDialogue(e.configuration.identifier)}}},[t._v(" Restart Manager ")]),""!=e.error?a("div",[a("div",{staticClass:"p-3 mb-2 bg-danger text-white my-4"},[t._v(" "+t._s(e.error)+" ")])]):t._e(),t._l(e.shard_groups,(function(s,i){return a("div",{key:i,staticClass:"border border-bottom bg-light rounded-lg p-3 my-4"},[a("div",{staticClass:"mb-3"},[a("h5",[a("span",{class:"badge rounded-pill bg-"+t.colourGroup[s.status]||!1},[t._v(t._s(t.statusGroup[s.status]))]),t._v(" ShardGroup "+t._s(s.id)+" ")]),6!=s.status?a("div",[a("button",{staticClass:"btn btn-dark",attrs:{type:"button"},on:{click:function(a){return t.stopShardGroupDialogue(e.configuration.identifier,s.id)}}},[t._v(" Stop ShardGroup ")])]):t._e(),s.status>=6?a("div",[a("button",{staticClass:"btn btn-dark",attrs:{type:"button"},on:{click:function(a){return t.deleteShardGroup(e.configuration.identifier,s.id)}}},[t._v(" Delete ShardGroup ")])]):t._e()]),6!=s.status?a("div",[a("ul",{staticClass:"list-group mb-4"},[a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Status "),a("status-graph",{staticStyle:{width:"50%"},attrs:{value:s,colours:t.colourShard}})],1),a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Uptime "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(t.since(s.uptime)))])]),a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Shards "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(Object.keys(s.shard_ids).length)+"/"+t._s(s.shard_count))])]),a("li",{staticClass:"list-group-item d-flex justify-content-between align-items-center"},[t._v(" Average Latency "),a("span",{staticClass:"badge bg-dark rounded-pill"},[t._v(t._s(t.calculateAverageShardGroup(s))+" ms")])])]),s.error?a("div",[a("div",{staticClass:"p-3 mb-2 bg-danger text-white"},[t._v(" "+t._s(s.error)+" ")])]):t._e(),s.shard_count>4*e.gateway.shards?a("div",[a("div",{staticClass:"p-3 mb-2 bg-info text-white"},[t._v(" You are launching this shardgroup with an unnecessarily large number of shards. This may cause the shardgroup to take longer to become ready. It is recommend you use a smaller number closer to the recommended shard count of 1 shard per 1000 guilds which is "),a("b",[t._v(t._s(e.gateway.shards))]),t._v(". ")])]):t._e(),a("div",{staticClass:"status-container my-3"},t._l(s.shards,(function(e,s){return a("div",{directives:[{name:"b-tooltip",rawName:"v-b-tooltip.hover",modifiers:{hover:!0}}],key:s,staticClass:"status-box-container",attrs:{title:"Shard "+e.shard_id+" - "+(new Date(e.last_heartbeat_ack)-new Date(e.last_heartbeat_sent))+"ms"}},[a("div",{class:"status-box bg-"+t.colourShard[e.status]})])})),0),a("table",{staticClass:"table table-borderless"},[t._m(11,!0),a("tbody",t._l(s.shards,(function(e,s){return a("tr",{key:s,staticClass:"table-default"},[a("th",{attrs:{scope:"row"}},[t._v(t._s(e.shard_id))]),a("td",[a("span",{class:"badge bg-"+t.colourShard[e.status]},[t._v(t._s(t.statusShard[e.status]))])]),a("td",[t._v(" "+t._s(new Date(e.last_heartbeat_ack)-new Date(e.last_heartbeat_sent))+"ms ")])])})),0)])]):t._e()])}))],2),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-pills-settings",role:"tabpanel","aria-labelledby":"manager-"+e.configuration.identifier+"-pills-settings-tab"}},[a("ul",{staticClass:"nav nav-tabs",attrs:{id:"tabpanel",role:"tablist"}},[a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link active",attrs:{id:"general-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-general",role:"tab","aria-selected":"true"}},[t._v("General")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"bot-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-bot",role:"tab","aria-selected":"false"}},[t._v("Bot")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"caching-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-caching",role:"tab","aria-selected":"false"}},[t._v("Caching")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"events-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-events",role:"tab","aria-selected":"false"}},[t._v("Events")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"messaging-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-messaging",role:"tab","aria-selected":"false"}},[t._v("Messaging")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"sharding-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-sharding",role:"tab","aria-selected":"false"}},[t._v("Sharding")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"raw-tab","data-toggle":"tab",href:"#manager-"+e.configuration.identifier+"-Settings-raw",role:"tab","aria-selected":"false"}},[t._v("RAW (Read Only)")])])]),a("div",{staticClass:"tab-content p-5",attrs:{id:"pills-managerSettings"}},[a("div",{staticClass:"tab-pane fade show active",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-general",role:"tabpanel","aria-labelledby":"general-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-autostart",label:"Auto Start"},model:{value:e.configuration.auto_start,callback:function(a){t.$set(e.configuration,"auto_start",a)},expression:"manager.configuration.auto_start"}}),a("p",{staticClass:"text-muted"},[t._v(" When enabled, the Manager will start up a shardgroup automatically. ")]),a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-persist",label:"Persist"},model:{value:e.configuration.persist,callback:function(a){t.$set(e.configuration,"persist",a)},expression:"manager.configuration.persist"}}),a("p",{staticClass:"text-muted"},[t._v(" When enabled, changes to Manager will be saved else the configuration will be discarded when the daemon is next started. ")])],1),a("form-input",{attrs:{type:"text",id:"managerConfig-"+e.configuration.identifier+"-identifier",label:"Identifier",disabled:!0},model:{value:e.configuration.identifier,callback:function(a){t.$set(e.configuration,"identifier",a)},expression:"manager.configuration.identifier"}}),t._m(12,!0),a("form-input",{attrs:{type:"text",id:"managerConfig-"+e.configuration.identifier+"-display_name",label:"Display Name"},model:{value:e.configuration.display_name,callback:function(a){t.$set(e.configuration,"display_name",a)},expression:"manager.configuration.display_name"}}),a("form-input",{attrs:{type:"password",id:"managerConfig-"+e.configuration.identifier+"-token",label:"Token"},model:{value:e.configuration.token,callback:function(a){t.$set(e.configuration,"token",a)},expression:"manager.configuration.token"}}),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-bot",role:"tabpanel","aria-labelledby":"bot-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-bot.compression",label:"Compression"},model:{value:e.configuration.bot.compression,callback:function(a){t.$set(e.configuration.bot,"compression",a)},expression:"manager.configuration.bot.compression"}}),a("p",{staticClass:"text-muted"},[t._v(" Enables zstd compression on the gateway websocket. Recommended. ")]),a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-bot.guild_subscriptions",label:"Guild Subscriptions"},model:{value:e.configuration.bot.guild_subscriptions,callback:function(a){t.$set(e.configuration.bot,"guild_subscriptions",a)},expression:"\n                                manager.configuration.bot.guild_subscriptions\n                              "}}),t._m(13,!0)],1),a("form-input",{attrs:{type:"presence",id:"managerConfig-"+e.configuration.identifier+"-bot.presence",label:"Presence"},model:{value:e.configuration.bot.presence,callback:function(a){t.$set(e.configuration.bot,"presence",a)},expression:"manager.configuration.bot.presence"}}),a("form-input",{attrs:{type:"intent",id:"managerConfig-"+e.configuration.identifier+"-bot.intents",label:"Intents"},model:{value:e.configuration.bot.intents,callback:function(a){t.$set(e.configuration.bot,"intents",a)},expression:"manager.configuration.bot.intents"}}),a("form-input",{attrs:{type:"number",id:"managerConfig-"+e.configuration.identifier+"-bot.large_threshold",label:"Large Threshold"},model:{value:e.configuration.bot.large_threshold,callback:function(a){t.$set(e.configuration.bot,"large_threshold",a)},expression:"\n                              manager.configuration.bot.large_threshold\n                            "}}),a("form-input",{attrs:{type:"number",id:"managerConfig-"+e.configuration.identifier+"-bot.max_heartbeat_failures",label:"Max Heartbeat Failures"},model:{value:e.configuration.bot.max_heartbeat_failures,callback:function(a){t.$set(e.configuration.bot,"max_heartbeat_failures",a)},expression:"\n                              manager.configuration.bot.max_heartbeat_failures\n                            "}}),a("p",{staticClass:"text-muted"},[t._v(" Amount of heartbeat durations until the bot forcibly reconnects. 5 is recommended. ")]),a("form-input",{attrs:{type:"number",id:"managerConfig-"+e.configuration.identifier+"-bot.retries",label:"Retries"},model:{value:e.configuration.bot.retries,callback:function(a){t.$set(e.configuration.bot,"retries",a)},expression:"manager.configuration.bot.retries"}}),a("p",{staticClass:"text-muted"},[t._v(" Amount of reconnects to attempt before giving up. Recommended to be more than 1 in the event it fails when starting up a ShardGroup causing all other shards to die. ")]),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-caching",role:"tabpanel","aria-labelledby":"caching-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-caching.cache_users",label:"Cache Users"},model:{value:e.configuration.caching.cache_users,callback:function(a){t.$set(e.configuration.caching,"cache_users",a)},expression:"\n                                manager.configuration.caching.cache_users\n                              "}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, users will be cached. This does not affect GUILD_MEMBERS_CHUNK. ")]),a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-caching.cache_members",label:"Cache Members"},model:{value:e.configuration.caching.cache_members,callback:function(a){t.$set(e.configuration.caching,"cache_members",a)},expression:"\n                                manager.configuration.caching.cache_members\n                              "}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, members will be cached. This does not affect GUILD_MEMBERS_CHUNK. ")]),a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-caching.request_members",label:"Request Members"},model:{value:e.configuration.caching.request_members,callback:function(a){t.$set(e.configuration.caching,"request_members",a)},expression:"\n                                manager.configuration.caching.request_members\n                              "}}),t._m(14,!0),a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-caching.store_mutuals",label:"Store Mutuals"},model:{value:e.configuration.caching.store_mutuals,callback:function(a){t.$set(e.configuration.caching,"store_mutuals",a)},expression:"\n                                manager.configuration.caching.store_mutuals\n                              "}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, guild IDs a member is directly available. ")])],1),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-events",role:"tabpanel","aria-labelledby":"events-tab"}},[a("p",{staticClass:"text-muted"},[t._v(" Changes to events will be reflected when a new shardgroup is made ")]),a("form-input",{attrs:{type:"list",id:"managerConfig-"+e.configuration.identifier+"-events.event_blacklist",label:"Event Blacklist"},model:{value:e.configuration.events.event_blacklist,callback:function(a){t.$set(e.configuration.events,"event_blacklist",a)},expression:"\n                              manager.configuration.events.event_blacklist\n                            "}}),a("p",{staticClass:"text-muted"},[t._v(" Events in event blacklist are completely ignored by Sandwich Daemon. It is recommended you instead use Intents to stop events as it still has to process the events. ")]),a("form-input",{attrs:{type:"list",id:"managerConfig-"+e.configuration.identifier+"-events.produce_blacklist",label:"Produce Blacklist"},model:{value:e.configuration.events.produce_blacklist,callback:function(a){t.$set(e.configuration.events,"produce_blacklist",a)},expression:"\n                              manager.configuration.events.produce_blacklist\n                            "}}),a("p",{staticClass:"text-muted"},[t._v(" Events in produce blacklist are processed (cached etc.) however are not relayed to consumers. Useful for reducing events that the consumers unnecessarily need but you still need for cache purposes. ")]),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-messaging",role:"tabpanel","aria-labelledby":"messaging-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-messaging.use_random_suffix",label:"Use Random Suffix"},model:{value:e.configuration.messaging.use_random_suffix,callback:function(a){t.$set(e.configuration.messaging,"use_random_suffix",a)},expression:"\n                                manager.configuration.messaging\n                                  .use_random_suffix\n                              "}}),a("p",{staticClass:"text-muted"},[t._v(" When enabled, the client name will have a random suffix added. Useful if you have multiple managers using the same client name. ")])],1),a("form-input",{attrs:{type:"text",id:"managerConfig-"+e.configuration.identifier+"-messaging.client_name",label:"Client Name"},model:{value:e.configuration.messaging.client_name,callback:function(a){t.$set(e.configuration.messaging,"client_name",a)},expression:"\n                              manager.configuration.messaging.client_name\n                            "}}),t._m(15,!0),a("form-input",{attrs:{type:"text",id:"managerConfig-"+e.configuration.identifier+"-messaging.channel_name",label:"Channel Name"},model:{value:e.configuration.messaging.channel_name,callback:function(a){t.$set(e.configuration.messaging,"channel_name",a)},expression:"\n                              manager.configuration.messaging.channel_name\n                            "}}),a("p",{staticClass:"text-muted"},[t._v(" Custom definition of the channel the manager will use. If empty, it will use the configuration in daemon.producer.configuration.channel which managers should use by default. ")]),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-sharding",role:"tabpanel","aria-labelledby":"sharding-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"managerConfig-"+e.configuration.identifier+"-sharding.auto_sharded",label:"AutoSharded"},model:{value:e.configuration.sharding.auto_sharded,callback:function(a){t.$set(e.configuration.sharding,"auto_sharded",a)},expression:"\n                                manager.configuration.sharding.auto_sharded\n                              "}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, will set the shard count to what is recommended on the gateway ")])],1),a("form-input",{attrs:{type:"number",id:"managerConfig-"+e.configuration.identifier+"-sharding.shard_count",label:"Shard Count"},model:{value:e.configuration.sharding.shard_count,callback:function(a){t.$set(e.configuration.sharding,"shard_count",a)},expression:"\n                              manager.configuration.sharding.shard_count\n                            "}}),a("form-submit",{on:{click:function(a){return t.saveClusterSettings(e)}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"manager-"+e.configuration.identifier+"-Settings-raw",role:"tabpanel","aria-labelledby":"raw-tab"}},[a("pre",[t._v(t._s(e))])])])])])])])])])])}))],2),a("div",{staticClass:"tab-pane fade",attrs:{id:"pills-settings",role:"tabpanel","aria-labelledby":"pills-settings-tab"}},[a("form",{on:{submit:function(t){t.preventDefault()}}},[a("ul",{staticClass:"nav nav-tabs",attrs:{id:"tabpanel",role:"tablist"}},[a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link active",attrs:{id:"logging-tab","data-toggle":"tab",href:"#daemonSettings-logging",role:"tab","aria-selected":"true"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiTextBoxMultiple}}),t._v(" Logging ")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"webhooks-tab","data-toggle":"tab",href:"#daemonSettings-webhooks",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiWebhook}}),t._v(" Webhooks ")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"access-tab","data-toggle":"tab",href:"#daemonSettings-access",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiDoorOpen}}),t._v(" Access ")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"producer-tab","data-toggle":"tab",href:"#daemonSettings-producer",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiMessageProcessing}}),t._v(" Outbound MQ")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"grpc-tab","data-toggle":"tab",href:"#daemonSettings-grpc",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiForum}}),t._v(" GRPC")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"http-tab","data-toggle":"tab",href:"#daemonSettings-http",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiWeb}}),t._v(" HTTP")],1)]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"resttunnel-tab","data-toggle":"tab",href:"#daemonSettings-resttunnel",role:"tab","aria-selected":"false"}},[a("svg-icon",{attrs:{type:"mdi",width:"20",height:"20",path:t.mdiFilter}}),t._v(" RestTunnel")],1)]),t._m(16)]),a("div",{staticClass:"tab-content p-5",attrs:{id:"pills-daemonSettings"}},[a("div",{staticClass:"tab-pane fade show active",attrs:{id:"daemonSettings-logging",role:"tabpanel","aria-labelledby":"logging-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"loggingConsoleLogging",label:"Console Logging"},model:{value:t.configuration.logging.console_logging,callback:function(e){t.$set(t.configuration.logging,"console_logging",e)},expression:"configuration.logging.console_logging"}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, logs will be shown in console. ")]),a("form-input",{attrs:{type:"checkbox",id:"loggingFileLogging",label:"File Logging"},model:{value:t.configuration.logging.file_logging,callback:function(e){t.$set(t.configuration.logging,"file_logging",e)},expression:"configuration.logging.file_logging"}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, logs will be saved to a file. ")]),a("form-input",{attrs:{type:"checkbox",id:"loggingEncodeAsJson",label:"Encode as JSON"},model:{value:t.configuration.logging.encode_as_json,callback:function(e){t.$set(t.configuration.logging,"encode_as_json",e)},expression:"configuration.logging.encode_as_json"}}),a("p",{staticClass:"text-muted"},[t._v(" If enabled, text will be encoded as json instead of human readable. ")]),a("form-input",{staticClass:"mt-5",attrs:{type:"checkbox",id:"loggingMinimalWebhooks",label:"Minimal Webhooks"},model:{value:t.configuration.logging.minimal_webhooks,callback:function(e){t.$set(t.configuration.logging,"minimal_webhooks",e)},expression:"configuration.logging.minimal_webhooks"}}),a("p",{staticClass:"text-muted"},[t._v(" When enabled, webhooks will prefer a more minimalistic one line message rather than fancier embeds. Useful when you have lots of shards. ")])],1),a("form-input",{attrs:{type:"select",id:"loggingLevel",label:"Logging Level",values:["trace","debug","info","warn","error","fatal","panic"]},model:{value:t.configuration.logging.level,callback:function(e){t.$set(t.configuration.logging,"level",e)},expression:"configuration.logging.level"}}),a("form-input",{attrs:{type:"text",id:"loggingDirectory",label:"Directory"},model:{value:t.configuration.logging.directory,callback:function(e){t.$set(t.configuration.logging,"directory",e)},expression:"configuration.logging.directory"}}),a("form-input",{attrs:{type:"text",id:"loggingFilename",label:"Filename"},model:{value:t.configuration.logging.filename,callback:function(e){t.$set(t.configuration.logging,"filename",e)},expression:"configuration.logging.filename"}}),a("form-input",{attrs:{type:"number",id:"loggingMaxSize",label:"Max Size"},model:{value:t.configuration.logging.max_size,callback:function(e){t.$set(t.configuration.logging,"max_size",e)},expression:"configuration.logging.max_size"}}),t._m(17),a("form-input",{attrs:{type:"number",id:"loggingMaxBackups",label:"Max Backups"},model:{value:t.configuration.logging.max_backups,callback:function(e){t.$set(t.configuration.logging,"max_backups",e)},expression:"configuration.logging.max_backups"}}),a("p",{staticClass:"text-muted"},[t._v(" Max number of log files before old ones are deleted. ")]),a("form-input",{attrs:{type:"number",id:"loggingMaxAge",label:"Max Age"},model:{value:t.configuration.logging.max_age,callback:function(e){t.$set(t.configuration.logging,"max_age",e)},expression:"configuration.logging.max_age"}}),a("p",{staticClass:"text-muted"},[t._v(" Number of days that a log file can exist before it is removed. ")]),a("form-submit",{on:{click:function(e){return t.saveDaemonSettings()}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-webhooks",role:"tabpanel","aria-labelledby":"webhooks-tab"}},[a("div",{staticClass:"d-flex pb-3 border-bottom border-muted"},[a("input",{directives:[{name:"model",rawName:"v-model",value:t.webhook_url,expression:"webhook_url"}],staticClass:"form-control flex-grow-1",staticStyle:{width:"fit-content"},attrs:{type:"text",id:"webhookName",placeholder:"Webhook URL"},domProps:{value:t.webhook_url},on:{input:function(e){e.target.composing||(t.webhook_url=e.target.value)}}}),a("button",{staticClass:"btn btn-dark ml-3",attrs:{type:"button",disabled:!t.validURL(t.webhook_url)},on:{click:function(e){return t.addWebhook(t.webhook_url)}}},[t._v(" Add Webhook ")])]),t._l(t.configuration.webhooks,(function(e,s){return a("div",{key:s,staticClass:"card text-left py-3 border-bottom border-muted border-top-0 border-left-0 border-right-0 flex-column flex-sm-row"},[a("div",{staticClass:"my-auto",staticStyle:{width:"fit-content"}},[a("small",{staticClass:"text-dark",staticStyle:{"overflow-wrap":"anywhere"}},[t._v(t._s(e))])]),a("div",{staticClass:"text-center ml-3"},[a("button",{staticClass:"m-1 btn btn-info",attrs:{type:"button"},on:{click:function(a){return t.testWebhook(e)}}},[t._v(" Test Webhook ")]),a("button",{staticClass:"m-1 btn btn-danger",attrs:{type:"button"},on:{click:function(a){return t.removeWebhook(e)}}},[t._v(" Remove Webhook ")])])])}))],2),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-resttunnel",role:"tabpanel","aria-labelledby":"resttunnel-tab"}},[t._m(18),a("p",{staticClass:"text-muted"},[t._v(" RestTunnel enabled: "),a("b",[t._v(t._s(t.rest_tunnel_enabled))])]),a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"restTunnelEnabled",label:"Enabled"},model:{value:t.configuration.resttunnel.enabled,callback:function(e){t.$set(t.configuration.resttunnel,"enabled",e)},expression:"configuration.resttunnel.enabled"}})],1),a("form-input",{attrs:{type:"text",id:"restTunnelURL",placeholder:"http://127.0.0.1:8000",label:"URL"},model:{value:t.configuration.resttunnel.url,callback:function(e){t.$set(t.configuration.resttunnel,"url",e)},expression:"configuration.resttunnel.url"}}),a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"button"},on:{click:function(e){return t.saveDaemonSettings()}}},[t._v(" Save Changes ")]),a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"button"},on:{click:function(e){return t.verifyRestTunnel()}}},[t._v(" Verify RestTunnel ")])],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-access",role:"tabpanel","aria-labelledby":"access-tab"}},[a("p",{staticClass:"text-muted"},[t._v(" Access allows you to manage who has access to the dashboard ")]),a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"httpPublic",label:"Public Access"},model:{value:t.configuration.http.public,callback:function(e){t.$set(t.configuration.http,"public",e)},expression:"configuration.http.public"}}),t._m(19)],1),a("ul",{staticClass:"list-group mt-3 mb-2"},[a("li",{staticClass:"list-group-item list-group-item-dark"},[t._v(" Users ("+t._s(t.configuration.elevated_users.length)+") ")]),t._l(t.configuration.elevated_users,(function(e,s){return a("li",{key:s,staticClass:"list-group-item"},[t._v(" "+t._s(e)+" ")])}))],2),a("form-input",{attrs:{type:"text",id:"elevatedUsers",label:"",placeholder:"Discord user ID"},model:{value:t.userid,callback:function(e){t.userid=e},expression:"userid"}}),a("form-submit",{attrs:{disabled:t.$store.state.user&&t.userid===t.$store.state.user.id,label:"Remove User"},on:{click:function(e){t.$store.state.user&&t.userid===t.$store.state.user.id||(t.configuration.elevated_users=t.configuration.elevated_users.filter((function(e){return e!==t.userid})),t.userid=void 0)}}}),a("form-submit",{attrs:{label:"Add User"},on:{click:function(e){Number(t.userid)==t.userid&&(t.configuration.elevated_users.includes(t.userid)||(t.configuration.elevated_users.push(t.userid),t.userid=void 0))}}}),a("p",{staticClass:"text-muted mb-4"},[t._v(" List of discord user IDs who are able to manage the settings on the dashboard. Only give this to users you trust. ")]),a("form-submit",{on:{click:function(e){return t.saveDaemonSettings()}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-producer",role:"tabpanel","aria-labelledby":"producer-tab"}},[a("p",{staticClass:"text-muted"},[t._v(" OutboundMQ is the driver that allows for consumers to process messages that are sent by the Daemon. This ensures that consumers receive their messages and tailer to your setup. ")]),a("form-input",{attrs:{type:"select",id:"outboundMQtype",label:"Driver",values:t.mq_drivers},model:{value:t.configuration.producer.type,callback:function(e){t.$set(t.configuration.producer,"type",e)},expression:"configuration.producer.type"}}),a("p",{staticClass:"text-muted"},[t._v(" Select the type of driver you would like to use to handle Outbound messaging. When changing, ensure you pass the proper arguments to the OutboundMQ Configuration. You may be required to restart sandwich-daemon when changing this or restarting managers. ")]),a("form-input",{attrs:{type:"kvpair",id:"outboundMQconfiguration",label:"OutboundMQ Configuration"},model:{value:t.configuration.producer.configuration,callback:function(e){t.$set(t.configuration.producer,"configuration",e)},expression:"configuration.producer.configuration"}}),a("form-submit",{on:{click:function(e){return t.saveDaemonSettings()}}}),a("b",[t._v("Changing these values require a restart before changes are seen")])],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-grpc",role:"tabpanel","aria-labelledby":"grpc-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"select",id:"grpcNetwork",label:"Network",values:["tcp","tcp4","tcp6","unix","unixpacket"]},model:{value:t.configuration.grpc.network,callback:function(e){t.$set(t.configuration.grpc,"network",e)},expression:"configuration.grpc.network"}}),a("p",{staticClass:"text-muted"},[t._v(" Specify the type of network transport you want to use ")])],1),a("form-input",{attrs:{type:"text",id:"grpcHost",label:"Host"},model:{value:t.configuration.grpc.host,callback:function(e){t.$set(t.configuration.grpc,"host",e)},expression:"configuration.grpc.host"}}),t._m(20),a("form-submit",{on:{click:function(e){return t.saveDaemonSettings()}}}),a("b",[t._v("Changing these values require a restart before changes are seen")])],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-http",role:"tabpanel","aria-labelledby":"http-tab"}},[a("div",{staticClass:"pb-4"},[a("form-input",{attrs:{type:"checkbox",id:"httpEnabled",label:"Enabled"},model:{value:t.configuration.http.enabled,callback:function(e){t.$set(t.configuration.http,"enabled",e)},expression:"configuration.http.enabled"}}),a("p",{staticClass:"text-muted"},[t._v(" Disabling HTTP will still show the web interface however will shown an error. ")])],1),a("form-input",{attrs:{type:"text",id:"httpHost",label:"Host"},model:{value:t.configuration.http.host,callback:function(e){t.$set(t.configuration.http,"host",e)},expression:"configuration.http.host"}}),t._m(21),a("form-input",{attrs:{type:"password",id:"httpSecret",label:"Session Secret"},model:{value:t.configuration.http.secret,callback:function(e){t.$set(t.configuration.http,"secret",e)},expression:"configuration.http.secret"}}),t._m(22),a("form-submit",{on:{click:function(e){return t.saveDaemonSettings()}}})],1),a("div",{staticClass:"tab-pane fade",attrs:{id:"daemonSettings-raw",role:"tabpanel","aria-labelledby":"raw-tab"}},[a("pre",[t._v(t._s(t.configuration))])])])])]),a("div",{staticClass:"tab-pane fade",attrs:{id:"pills-console",role:"tabpanel","aria-labelledby":"pills-console-tab"}},[a("console",{attrs:{wsurl:"api/console",limit:0}})],1)])]):a("div",[a("div",{staticClass:"text-center my-5"},[a("svg-icon",{staticClass:"mb-2 text-muted",attrs:{type:"mdi",width:64,height:64,path:t.mdiAlertCircle}}),a("h3",[t._v("You are not authenticated")]),a("p",{staticClass:"m-auto my-2 col-10 text-muted"},[t._v(" It seems you are not where you want to be... ")])],1)]),a("div",{staticStyle:{position:"relative","min-height":"200px"},attrs:{"aria-live":"polite","aria-atomic":"true"}},[a("div",{staticStyle:{position:"fixed",top:"20px",right:"20px"}},[a("div",{staticClass:"toast",attrs:{id:"toast",role:"alert","aria-live":"assertive","aria-atomic":"true"}},[a("div",{staticClass:"toast-header"},[a("strong",{staticClass:"mr-auto"},[t._v(t._s(t.toast.title)+" ")]),t._m(23)]),a("div",{staticClass:"toast-body"},[t._v(" "+t._s(t.toast.body)+" ")])])])])])},F=[function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"spinner-border mx-auto mt-5 d-flex",attrs:{role:"status"}},[a("span",{staticClass:"visually-hidden"},[t._v("Loading...")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"close",attrs:{type:"button","data-dismiss":"modal","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"close",attrs:{type:"button","data-dismiss":"modal","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"modal-body"},[a("span",[t._v("Are you sure you want to kill this shard group?")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"modal-header"},[a("h5",{staticClass:"modal-title",attrs:{id:"createManagerDialogueLabel"}},[t._v(" New Manager ")]),a("button",{staticClass:"close",attrs:{type:"button","data-dismiss":"modal","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("label",{staticClass:"col-sm-12 form-label"},[t._v("Identifier "),a("span",{staticClass:"text-danger"},[t._v("*")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("label",{staticClass:"col-sm-12 form-label"},[t._v("Token "),a("span",{staticClass:"text-danger"},[t._v("*")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("label",{staticClass:"col-sm-12 form-label"},[t._v("Client Name "),a("span",{staticClass:"text-danger"},[t._v("*")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"close",attrs:{type:"button","data-dismiss":"modal","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"close",attrs:{type:"button","data-dismiss":"modal","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("ul",{staticClass:"nav nav-pills mb-3",attrs:{id:"pills-tab",role:"tablist"}},[a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link active",attrs:{id:"pills-home-tab","data-toggle":"pill",href:"#pills-home",role:"tab","aria-controls":"pills-home","aria-selected":"true"}},[t._v("Analytics")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"pills-managers-tab","data-toggle":"pill",href:"#pills-managers",role:"tab","aria-controls":"pills-managers","aria-selected":"false"}},[t._v("Managers")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"pills-settings-tab","data-toggle":"pill",href:"#pills-settings",role:"tab","aria-controls":"pills-settings","aria-selected":"false"}},[t._v("Daemon Settings")])]),a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"pills-console-tab","data-toggle":"pill",href:"#pills-console",role:"tab","aria-controls":"pills-console","aria-selected":"false"}},[t._v("Console")])])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("thead",[a("tr",{staticClass:"table-dark"},[a("th",{attrs:{scope:"col"}},[t._v("Shard")]),a("th",{attrs:{scope:"col"}},[t._v("Status")]),a("th",{attrs:{scope:"col"}},[t._v("RTT Latency")])])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("You cannot modify the identifier as that can possibly break many things.")]),t._v(" The name the manager is internally referenced by. Produced messages will also include this identifier in its messages. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("Not recommended, use intents.")]),t._v(" Events such as PRESENCE_UPDATE, TYPING, GUILD_MEMBER_JOIN etc. are not sent to the bot. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("Due to the new intent changes, enabling this on larger bots is not recommended.")]),t._v(" If enabled, guild members will be requested when lazy loading. "),a("b",[t._v("This bypasses Cache Users and Cache Members options.")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[t._v(" Name of the client the manager uses. "),a("b",[t._v("Client names must be unique.")]),t._v(" If you are using multiple managers with the same client name, it is recommended you use separate client names or enable random suffix. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("li",{staticClass:"nav-item",attrs:{role:"presentation"}},[a("a",{staticClass:"nav-link",attrs:{id:"raw-tab","data-toggle":"tab",href:"#daemonSettings-raw",role:"tab","aria-selected":"false"}},[t._v("RAW (Read Only)")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("Stored in Megabytes.")]),t._v(" Max size each log can be before a new file is made. 0 is unlimited. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[t._v(" RestTunnel is a second-party application that is used in conjunction with Sandwich-Daemon to allow for centralized rate limit management for multiple systems. The Github repository can be found "),a("a",{attrs:{href:"https://github.com/TheRockettek/RestTunnel"}},[t._v("here")]),t._v(". ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[t._v(" If enabled, users will not need elevation to access the internal API. "),a("b",[t._v("This should never need to be enabled.")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("It is recommended you keep this on a local IP such as localhost or 127.0.0.1.")]),t._v(" Entering 0.0.0.0 or the devices IP will make it accessable to users outside of the local network. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[a("b",[t._v("It is recommended you keep this on a local IP such as localhost or 127.0.0.1 or disable public access in the Access tab.")]),t._v(" Entering 0.0.0.0 or the devices IP will make it accessable to users outside of the local network. Ensure you disable public access in the Access tab. ")])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("p",{staticClass:"text-muted"},[t._v(" Secret to use for encrypting user sessions. "),a("b",[t._v("Changing this will log you out")])])},function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"ml-2 mb-1 close",attrs:{type:"button","data-dismiss":"toast","aria-label":"Close"}},[a("span",{attrs:{"aria-hidden":"true"}},[t._v("×")])])}],H=(a("fb6a"),a("b65f"),a("a9e3"),a("b64b"),a("07ac"),a("3ca3"),a("ddb0"),a("2b3d"),function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"col justify-content-center d-flex"},[a("div",{class:t.bg+" card text-white m-1",staticStyle:{width:"18rem"}},[a("div",{staticClass:"card-header"},[t._v(t._s(t.title))]),a("div",{staticClass:"card-body"},[a("h5",{staticClass:"card-title"},[t._v(t._s(t.value))])])])])}),z=[],K={name:"CardDisplay",props:["title","value","bg"]},Y=K,Q=Object(w["a"])(Y,H,z,!1,null,null,null),Z=Q.exports,X=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",[a("ul",{staticClass:"console mt-4 p-3 rounded-lg"},t._l(t.entries,(function(e,s){return a("li",{key:s,staticClass:"d-flex font-monospace text-white"},[a("span",{staticClass:"text-white-50"},[t._v(t._s(e.time))]),t.levels[e.level]?a("span",{class:"text-"+(t.levels[e.level][1]||"white")},[t._v(t._s(t.levels[e.level][0]||"???"))]):a("span",{staticClass:"text-white"},[t._v("???")]),a("span",[t._v(t._s(e.message))]),t._l(e.args,(function(e,s){return a("div",{key:s},["error"==s?a("span",{staticClass:"text-danger"},[a("span",[t._v(t._s(s)+"=")]),a("span",[t._v(t._s(e))])]):a("span",[a("span",{staticClass:"text-info"},[t._v(t._s(s)+"=")]),a("span",[t._v(t._s(e))])])])}))],2)})),0),a("div",{staticClass:"d-flex"},[a("button",{staticClass:"btn btn-dark mr-2",on:{click:function(e){return t.toggle_connection()}}},[t._v(" "+t._s(this.connected?"Disconnect":"Connect")+" ")]),a("button",{staticClass:"btn btn-dark mr-2",on:{click:function(e){return t.clear()}}},[t._v("Clear")]),a("div",{staticClass:"my-auto mr-2"},[a("input",{directives:[{name:"model",rawName:"v-model",value:t.autoscroll,expression:"autoscroll"}],staticClass:"mr-1",attrs:{type:"checkbox",id:"autoscroll__checkbox"},domProps:{checked:Array.isArray(t.autoscroll)?t._i(t.autoscroll,null)>-1:t.autoscroll},on:{change:function(e){var a=t.autoscroll,s=e.target,i=!!s.checked;if(Array.isArray(a)){var n=null,r=t._i(a,n);s.checked?r<0&&(t.autoscroll=a.concat([n])):r>-1&&(t.autoscroll=a.slice(0,r).concat(a.slice(r+1)))}else t.autoscroll=i}}}),a("label",{attrs:{for:"autoscroll__checkbox"}},[t._v("Autoscroll")])]),a("div",{staticClass:"my-auto mr-2"},[a("input",{directives:[{name:"model",rawName:"v-model",value:t.line_limit,expression:"line_limit"}],staticClass:"mr-1",staticStyle:{width:"60px"},attrs:{type:"number",id:"max__lines"},domProps:{value:t.line_limit},on:{input:function(e){e.target.composing||(t.line_limit=e.target.value)}}}),a("label",{attrs:{for:"autoscroll__checkbox"}},[t._v("Line Limit")])])])])},tt=[],et=(a("caad"),a("2532"),{name:"Console",props:["wsurl","limit","auto"],data:function(){return{ws:void 0,ping_interval:void 0,connected:!1,autoscroll:!0,line_limit:this.limit,entries:[],white:["level","time","message"],levels:{trace:["TRC","primary"],debug:["DBG","warning"],info:["INF","success"],warn:["WRN","danger"],error:["ERR","danger"],fatal:["FTL","danger"],panic:["PNC","danger"],"":["???","white"]}}},mounted:function(){this.auto&&this.connect()},methods:{toggle_connection:function(){this.connected?(this.ws.close(),this.addentry({message:"Disconnecting"})):this.connect()},clear:function(){this.entries=[],this.addentry({message:"Cleared logs"})},connect:function(){this.ws=function(t){var e=new URL(t,document.baseURI);return e.protocol="http:"==e.protocol?"ws:":"wss:",new WebSocket(e.href)}(this.wsurl),this.ws.onopen=this.onopen,this.ws.onmessage=this.onmessage,this.ws.onclose=this.onclose,this.ws.onerror=this.onerror},addentry:function(t){var e=this,a={time:b()(t.time).format("MMM D HH:mm:ss"),level:t.level,message:t.message,args:{}};for(var s in t)this.white.includes(s)||(a.args[s]=t[s]);this.entries.push(a),this.line_limit>0&&(this.entries=this.entries.length>=this.line_limit?this.entries.slice(Math.max(this.entries.length-this.line_limit,1)):this.entries),this.autoscroll&&this.$nextTick((function(){var t=e.$el.querySelector(".console");t.scrollTop=t.scrollHeight}))},onopen:function(){var t=this;this.connected=!0,this.addentry({message:"Connected to console websocket"}),this.ping_interval=setInterval((function(){t.ws.send(JSON.stringify({op:1}))}),15e3)},onmessage:function(t){this.addentry(JSON.parse(t.data))},onclose:function(){this.addentry({message:"Connection was closed"}),this.connected=!1,clearInterval(this.ping_interval)},onerror:function(){this.addentry({message:"Encountered error"})}}}),at=et,st=(a("e8a4"),Object(w["a"])(at,X,tt,!1,null,"7fd6cd58",null)),it=st.exports,nt=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",["checkbox"==t.type?a("div",{staticClass:"form-check"},[a("input",{staticClass:"form-check-input",attrs:{type:"checkbox",id:t.id,disabled:t.disabled},domProps:{checked:t.value},on:{change:function(e){return t.updateValue(e.target.checked)}}}),a("label",{staticClass:"form-check-label",attrs:{for:t.id}},[t._v(t._s(t.label))])]):"text"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("input",{staticClass:"form-control",attrs:{type:"text",id:t.id,disabled:t.disabled,placeholder:t.placeholder},domProps:{value:t.value},on:{change:function(e){return t.updateValue(e.target.value)}}})]):"list"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("input",{staticClass:"form-control",attrs:{type:"text",id:t.id,placeholder:"A,B,C",disabled:t.disabled},domProps:{value:t.value},on:{change:function(e){t.updateValue(Array.from(new Set(e.target.value.split(","))))}}})]):"number"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("input",{staticClass:"form-control",attrs:{type:"number",id:t.id,disabled:t.disabled,placeholder:t.placeholder},domProps:{value:t.value},on:{change:function(e){t.updateValue(Number(e.target.value))}}})]):"password"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("div",{staticClass:"input-group"},[a("input",{staticClass:"form-control",attrs:{type:"password",id:t.id,autocomplete:"",disabled:t.disabled,placeholder:t.placeholder},domProps:{value:t.value},on:{change:function(e){return t.updateValue(e.target.value)}}}),a("button",{directives:[{name:"clipboard",rawName:"v-clipboard:copy",value:t.value,expression:"value",arg:"copy"}],staticClass:"btn btn-outline-dark",attrs:{type:"button"}},[t._v(" Copy ")])])]):"select"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("select",{staticClass:"form-select",attrs:{id:t.id,disabled:t.disabled},on:{change:function(e){return t.updateValue(e.target.value)}}},t._l(t.values,(function(e,s){return a("option",{key:s,domProps:{selected:e==t.value}},[t._v(t._s(e))])})),0)]):"intent"==t.type?a("div",{staticClass:"mb-3 row pb-4"},[a("label",{staticClass:"col-sm-3 form-label",attrs:{for:"managerBotIntents"}},[t._v(t._s(t.label))]),a("div",{staticClass:"col-sm-9"},[a("input",{staticClass:"form-control",attrs:{type:"number",min:"0",max:"32767",disabled:t.disabled},domProps:{value:t.value},on:{change:function(e){e.target.value=32767&e.target.value,t.updateValue(Number(e.target.value)),t.fromIntents(e.target.value)},input:function(e){t.updateValue(Number(e.target.value)),t.fromIntents(e.target.value)}}}),a("div",{staticClass:"form-row py-2"},t._l(this.intents,(function(e,s){return a("div",{key:s,staticClass:"form-check form-check-inline col-sm-8 col-md-5"},[a("input",{directives:[{name:"model",rawName:"v-model",value:t.selectedIntent,expression:"selectedIntent"}],staticClass:"form-check-input",attrs:{type:"checkbox",id:t.id+s},domProps:{value:s,checked:Array.isArray(t.selectedIntent)?t._i(t.selectedIntent,s)>-1:t.selectedIntent},on:{change:[function(e){var a=t.selectedIntent,i=e.target,n=!!i.checked;if(Array.isArray(a)){var r=s,o=t._i(a,r);i.checked?o<0&&(t.selectedIntent=a.concat([r])):o>-1&&(t.selectedIntent=a.slice(0,o).concat(a.slice(o+1)))}else t.selectedIntent=n},function(e){return t.calculateIntent()}]}}),a("label",{staticClass:"form-check-label",attrs:{for:"managerBotIntentBox"+s}},[t._v(t._s(e))])])})),0)])]):"presence"==t.type?a("div",{staticClass:"mb-3 row pb-4"},[a("label",{staticClass:"col-sm-3 col-form-label"},[t._v(t._s(t.label))]),a("div",{staticClass:"col-sm-9"},[a("div",{staticClass:"mb-3"},[a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id+"status"}},[t._v("Status")]),a("select",{staticClass:"form-select",attrs:{id:t.id+"status"},domProps:{value:t.value.status},on:{input:function(e){t.value.status=e.target.value}}},t._l(["","online","dnd","idle","invisible","offline"],(function(e){return a("option",{key:e,attrs:{disabled:!e},domProps:{selected:e==t.value}},[t._v(t._s(e))])})),0)]),a("div",{staticClass:"mb-3"},[a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id+"name"}},[t._v("Name")]),a("input",{staticClass:"form-control",attrs:{type:"text",id:t.id+"name"},domProps:{value:t.value.name},on:{input:function(e){t.value.name=e.target.value}}})]),a("div",{staticClass:"form-check"},[a("input",{staticClass:"form-check-input",attrs:{type:"checkbox",id:t.id+"afk"},domProps:{checked:t.value.afk},on:{input:function(e){t.value.afk=e.target.checked}}}),a("label",{staticClass:"form-check-label",attrs:{for:t.id+"afk"}},[t._v("AFK")])])])]):"kvpair"==t.type?a("div",{staticClass:"mb-3"},[t.label?a("label",{staticClass:"col-sm-12 form-label",attrs:{for:t.id}},[t._v(t._s(t.label))]):t._e(),a("div",{staticClass:"mx-2"},[a("div",{staticClass:"row mb-2 pb-2 border-bottom"},[a("input",{directives:[{name:"model",rawName:"v-model",value:t.keyValue,expression:"keyValue"}],staticClass:"form-control col mx-1",attrs:{type:"text",disabled:t.disabled,placeholder:"Key"},domProps:{value:t.keyValue},on:{input:function(e){e.target.composing||(t.keyValue=e.target.value)}}}),a("input",{directives:[{name:"model",rawName:"v-model",value:t.valueValue,expression:"valueValue"}],staticClass:"form-control col mx-1",attrs:{type:"text",disabled:t.disabled,placeholder:"Value"},domProps:{value:t.valueValue},on:{input:function(e){e.target.composing||(t.valueValue=e.target.value)}}}),a("button",{staticClass:"btn btn-dark col-1 mx-1",on:{click:function(e){return t.addKV(t.keyValue,t.valueValue)}}},[t._v(" + ")])]),t._l(t.kvStore,(function(e,s){return a("div",{key:s},[a("div",{staticClass:"row mb-1"},[a("input",{directives:[{name:"model",rawName:"v-model",value:e.key,expression:"kv.key"}],staticClass:"form-control col mx-1",domProps:{value:e.key},on:{change:function(e){t.updateValue(t.toKVdict(t.kvStore))},input:function(a){a.target.composing||t.$set(e,"key",a.target.value)}}}),a("input",{directives:[{name:"model",rawName:"v-model",value:e.value,expression:"kv.value"}],staticClass:"form-control col mx-1",domProps:{value:e.value},on:{change:function(e){t.updateValue(t.toKVdict(t.kvStore))},input:function(a){a.target.composing||t.$set(e,"value",a.target.value)}}}),a("button",{staticClass:"btn btn-dark col-1 mx-1",on:{click:function(a){return t.removeKV(e.key)}}},[t._v(" - ")])])])}))],2)]):a("span",{staticClass:"badge bg-warning text-dark"},[t._v('Invalid type "'+t._s(t.type)+'" for "'+t._s(t.id)+'"')])])},rt=[],ot=(a("4de4"),a("4160"),a("c975"),a("4fad"),a("ac1f"),a("1276"),a("159b"),{props:["type","id","label","values","value","disabled","placeholder"],data:function(){return{intents:["GUILDS","GUILD_MEMBERS","GUILD_BANS","GUILD_INTEGRATIONS","GUILD_EMOJIS","GUILD_WEBHOOKS","GUILD_INVITES","GUILD_VOICE_STATES","GUILD_PRESENCES","GUILD_MESSAGES","GUILD_MESSAGE_REACTIONS","GUILD_MESSAGE_TYPING","DIRECT_MESSAGES","DIRECT_MESSAGE_REACTIONS","DIRECT_MESSAGE_TYPING"],selectedIntent:[],kvStore:[],keyValue:"",valueValue:""}},mounted:function(){"intent"==this.type&&this.fromIntents(this.value),"kvpair"==this.type&&(this.kvStore=this.fromKVdict(this.value))},methods:{removeKV:function(t){this.kvStore=this.kvStore.filter((function(e){return e.key!=t})),this.updateValue(this.toKVdict(this.kvStore))},addKV:function(t,e){""!=t&&""!=e&&(this.kvStore.push({key:t,value:e}),this.updateValue(this.toKVdict(this.kvStore)))},fromKVdict:function(t){var e=[];return Object.entries(t).forEach((function(t){e.push({key:t[0],value:t[1]})})),console.log(t,e),e},toKVdict:function(t){var e={};return this.kvStore.forEach((function(t){e[t.key]=t.value})),console.log(t,e),e},calculateIntent:function(){var t=this;this.intentValue=0,this.selectedIntent.forEach((function(e){t.intentValue+=1<<e})),this.updateValue(Number(this.intentValue))},fromIntents:function(t){var e=this,a=Number(t).toString(2).split("").reverse(),s=[];a.forEach((function(t,a){"1"===t&&-1===e.selectedIntent.indexOf(t)&&s.push(a)})),this.selectedIntent=s},updateValue:function(t){this.$emit("input",t)}}}),lt=ot,ct=Object(w["a"])(lt,nt,rt,!1,null,null,null),ut=ct.exports,dt=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("button",{staticClass:"btn btn-dark mr-1",attrs:{type:"submit"},on:{click:t.onClick}},[t._v(" "+t._s(t.label)+" ")])},gt=[],mt={name:"FormSubmit",props:{label:{default:"Save Changes"}},methods:{onClick:function(){this.$emit("click")}}},pt=mt,ft=Object(w["a"])(pt,dt,gt,!1,null,null,null),bt=ft.exports,ht=a("1fca"),vt={name:"LineChart",extends:ht["a"],mixins:[ht["b"].reactiveProp],props:["chartData","options"],mounted:function(){this.renderChart(this.chartData,this.options)}},_t=vt,Ct=Object(w["a"])(_t,s,i,!1,null,null,null),yt=Ct.exports,kt=function(){var t=this,e=t.$createElement,a=t._self._c||e;return a("div",{staticClass:"progress"},t._l(this.keys,(function(e,s){return a("div",{key:s,class:"progress-bar bg-"+t.colours[s],style:"width: "+e/t.total*100+"%",attrs:{role:"progressbar","aria-valuenow":e/t.total*100,"aria-valuemin":"0","aria-valuemax":"100"}})})),0)},xt=[],Dt={name:"StatusGraph",props:["value","colours"],data:function(){return{keys:{},total:0}},watch:{value:function(){this.loadValues()}},mounted:function(){this.loadValues()},methods:{loadValues:function(){this.keys={},this.total=0;var t=Object.values(this.value.shards);for(var e in t){var a=t[e];a.status in this.keys?this.keys[a.status]++:this.keys[a.status]=1,this.total++}this.$forceUpdate()}}},wt=Dt,St=Object(w["a"])(wt,kt,xt,!1,null,null,null),Mt=St.exports,Gt={components:{CardDisplay:Z,Console:it,FormInput:ut,FormSubmit:bt,LineChart:yt,StatusGraph:Mt,SvgIcon:y["a"]},name:"Dashboard",data:function(){return{mdiAlertCircle:k["a"],mdiConnection:k["b"],mdiTextBoxMultiple:k["i"],mdiWebhook:k["k"],mdiDoorOpen:k["d"],mdiDatabase:k["c"],mdiForum:k["f"],mdiMessageProcessing:k["h"],mdiWeb:k["j"],mdiFilter:k["e"],webhook_url:"",fetch_task:void 0,version:"...",loading:!0,error:!1,userid:void 0,loadingRestTunnel:!0,loadingAnalytics:!0,rest_tunnel_enabled:!0,waiting:"...",managers:{},configuration:{},mq_drivers:[],toast:{title:"",body:""},analytics:{chart:{},uptime:"...",visible:"...",events:"...",online:"...",colour:"bg-success"},resttunnel:{charts:{hits:{},misses:{},waiting:{},requests:{},callbacks:{},average_response:{}},numbers:{hits:0,misses:0,requests:0,waiting:0},uptime:"..."},createShardGroupDialogueData:{manager:"",autoShard:!0,shardCount:1,autoIDs:!0,shardIDs:"",startImmediately:!0},stopShardGroupDialogueData:{manager:"",shardgroup:0},createManagerDialogueData:{identifier:"",persist:!0,token:"",prefix:"",client:"",channel:""},deleteManagerDialogueData:{confirm:"",manager:""},restartManagerDialogueData:{confirm:"",manager:""},statusShard:["Idle","Waiting","Connecting","Connected","Ready","Reconnecting","Closed"],colourShard:["dark","info","info","info","success","warn","secondary"],statusGroup:["Idle","Starting","Connecting","Ready","Replaced","Closing","Closed","Error"],colourGroup:["dark","info","info","success","info","warn","dark","danger"],colourCluster:["dark","info","info","success","warn","warn","dark","danger"],chartOptions:{ratelimitHits:{title:{display:!0,text:"Ratelimit Hits"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{fixedStepSize:1}}],xAxes:[{type:"time"}]},animation:{duration:0}},ratelimitMisses:{title:{display:!0,text:"Ratelimit Misses"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{fixedStepSize:1}}],xAxes:[{type:"time"}]},animation:{duration:0}},waitingRequests:{title:{display:!0,text:"Waiting requests"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{fixedStepSize:1}}],xAxes:[{type:"time"}]},animation:{duration:0}},totalRequests:{title:{display:!0,text:"Total requests"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{fixedStepSize:1}}],xAxes:[{type:"time"}]},animation:{duration:0}},callbackBuffer:{title:{display:!0,text:"Callback buffer"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{fixedStepSize:1}}],xAxes:[{type:"time"}]},animation:{duration:0}},averageResponse:{title:{display:!0,text:"Average response"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events",ticks:{beginAtZero:!0}}],xAxes:[{type:"time"}]},animation:{duration:0},tooltips:{callbacks:{label:function(t,e){return e.datasets[t.datasetIndex].label+": "+t.yLabel+"ms"}}}},events:{title:{display:!0,text:"Events"},elements:{point:{radius:0},line:{tension:.2,borderJoinStyle:"round"}},scales:{yAxes:[{display:!0,labelString:"events"}],xAxes:[{type:"time"}]},animation:{duration:0}}}}},filters:{pretty:function(t){return JSON.stringify(t,null,2)}},mounted:function(){var t=this;this.toastModal=new p["b"](document.getElementById("toast"),{delay:2e3}),this.fetchConfiguration(),this.fetch_task=window.setInterval((function(){t.pollData()}),5e3),this.pollData()},methods:{validURL:function(t){try{return new URL(t),!0}catch(e){return!1}},sendRPC:function(t,e,a){var s=this;u.a.post("api/rpc",{method:t,data:e,id:a}).then((function(e){var a=e.data.error;return e.data.success?s.showToast(t,"Executed successfuly"):s.showToast("Error executing "+t,a),e})).catch((function(t){s.showToast("Exception sending RPC",t)}))},erroredManagers:function(){var t=[];for(var e in this.managers){var a=JSON.parse(JSON.stringify(this.managers[e]));if(a.status=0,a.shard_groups.length>0&&(a.status=a.shard_groups.slice(-1)[0].status),""!=a.error&&(a.status=7),7===a.status){t.push(a);break}}return t},erroredShardGroups:function(){var t=[];for(var e in this.managers){var a=this.managers[e];for(var s in a.shard_groups){var i=JSON.parse(JSON.stringify(a.shard_groups[s]));for(var n in i.manager=a.configuration.display_name,i.shards){var r=i.shards[n];if(7===r.status){t.push(i);break}}}}return t},loadingShardGroups:function(){var t=[];for(var e in this.managers){var a=this.managers[e];for(var s in a.shard_groups){var i=JSON.parse(JSON.stringify(a.shard_groups[s]));for(var n in i.manager=a.configuration.display_name,i.shards){var r=i.shards[n];if(0===r.status){t.push(i);break}}}}return t},showToast:function(t,e){this.toast.title=t,this.toast.body=e,this.toastModal.show()},addWebhook:function(t){var e=this;this.sendRPC("daemon:add_webhook",t),setTimeout((function(){return e.fetchConfiguration()}),1e3)},testWebhook:function(t){this.sendRPC("daemon:test_webhook",t)},removeWebhook:function(t){var e=this;this.sendRPC("daemon:remove_webhook",t),setTimeout((function(){return e.fetchConfiguration()}),1e3)},verifyRestTunnel:function(){this.sendRPC("daemon:verify_resttunnel")},saveClusterSettings:function(t){this.sendRPC("manager:update",t.configuration)},saveDaemonSettings:function(){this.sendRPC("daemon:update",this.configuration)},stopShardGroupDialogue:function(t,e){this.stopShardGroupDialogueModal=new p["a"](document.getElementById("stopShardGroupDialogue"),{}),this.stopShardGroupDialogueData.manager=t,this.stopShardGroupDialogueData.shardgroup=e,this.stopShardGroupDialogueModal.show()},stopShardGroup:function(){var t=this,e=Object.assign({},this.stopShardGroupDialogueData);this.sendRPC("manager:shardgroup:stop",e),setTimeout((function(){return t.pollData()}),1e3),this.stopShardGroupDialogueModal.hide()},deleteShardGroup:function(t,e){var a=this,s={manager:t,shardgroup:e};this.sendRPC("manager:shardgroup:delete",s),setTimeout((function(){return a.pollData()}),1e3)},refreshGateway:function(t){var e=this,a={manager:t};this.sendRPC("manager:refresh_gateway",a),setTimeout((function(){return e.pollData()}),1e3)},createManagerDialogue:function(){this.createManagerDialogueModal=new p["a"](document.getElementById("createManagerDialogue"),{}),this.createManagerDialogueData.identifier="",this.createManagerDialogueData.persist=!0,this.createManagerDialogueData.token="",this.createManagerDialogueData.prefix="",this.createManagerDialogueData.client="",this.createManagerDialogueData.channel="",this.createManagerDialogueModal.show()},createManager:function(){var t=this;this.sendRPC("manager:create",this.createManagerDialogueData),setTimeout((function(){return t.fetchConfiguration()}),1e3),this.createManagerDialogueModal.hide()},deleteManagerDialogue:function(t){this.deleteManagerDialogueModal=new p["a"](document.getElementById("deleteManagerDialogue"),{}),this.deleteManagerDialogueData={confirm:"",manager:t},this.deleteManagerDialogueModal.show()},deleteManager:function(){var t=this;this.sendRPC("manager:delete",this.deleteManagerDialogueData),setTimeout((function(){return t.fetchConfiguration()}),1e3),this.deleteManagerDialogueModal.hide()},restartManagerDialogue:function(t){this.restartManagerDialogueModal=new p["a"](document.getElementById("restartManagerDialogue"),{}),this.restartManagerDialogueData={confirm:"",manager:t},this.restartManagerDialogueModal.show()},restartManager:function(){var t=this;this.sendRPC("manager:restart",this.restartManagerDialogueData),setTimeout((function(){return t.fetchConfiguration()}),1e3),this.restartManagerDialogueModal.hide()},createShardGroupDialogue:function(t){this.createShardGroupDialogueModal=new p["a"](document.getElementById("createShardGroupDialogue"),{}),this.createShardGroupDialogueData.manager=t,this.createShardGroupDialogueData.autoShard=!0,this.createShardGroupDialogueData.shardCount=1,this.createShardGroupDialogueData.autoIDs=!0,this.createShardGroupDialogueData.shardIDs="",this.createShardGroupDialogueData.startImmediately=!0,this.createShardGroupDialogueModal.show()},createShardGroup:function(){var t=this,e=Object.assign({},this.createShardGroupDialogueData);e.shardCount=Number(e.shardCount),this.sendRPC("manager:shardgroup:create",e),setTimeout((function(){return t.pollData()}),1e3),this.createShardGroupDialogueModal.hide()},pollData:function(){var t=this;u.a.get("api/poll").then((function(e){if(403===e.status&&clearInterval(t.fetch_task),!1!==e.data.success){t.error&&document.location.reload(),t.error=!e.data.success,t.rest_tunnel_enabled=e.data.data.rest_tunnel_enabled,t.uptime=e.data.data.uptime,t.waiting=e.data.data.waiting;var a=Object.keys(e.data.data.managers);for(var s in a){var i,n=a[s],r=e.data.data.managers[n];i=n in t.managers?t.managers[n]:r,i.error=r.error,i.gateway=r.gateway,i.shard_groups=r.shard_groups;var o=Object.values(r.shard_groups);i.status=0,o.length>0&&(i.status=o.slice(-1)[0].status),""!=r.error&&(i.status=7),t.$set(t.managers,n,i)}t.analytics=e.data.data.analytics;var l=0,c=0,u=0;for(s in t.analytics.colour="bg-success",a=Object.values(t.analytics.managers),a)for(var d in r=a[s],u+=r.guilds,o=Object.values(r.status),o){var g=o[d];2<g&&g<4&&l++,c++}t.analytics.visible=u,t.analytics.online=l+"/"+c,e.data.data.rest_tunnel_enabled&&(t.resttunnel.charts=e.data.data.resttunnel.data.charts,t.resttunnel.uptime=e.data.data.resttunnel.data.uptime,t.resttunnel.numbers=e.data.data.resttunnel.data.numbers)}})).catch((function(e){var a;403===(null===(a=e.response)||void 0===a?void 0:a.status)&&clearInterval(t.fetch_task),t.showToast("Exception fetching manager data",e)})).finally((function(){t.loading=!1,t.loadingAnalytics=!1,t.loadingRestTunnel=!1}))},fetchConfiguration:function(){var t=this;u.a.get("api/configuration").then((function(e){403===e.status&&clearInterval(t.fetch_task),t.$root.version=e.data.data.version,t.configuration=e.data.data.configuration,t.rest_tunnel_enabled=e.data.data.rest_tunnel_enabled,t.mq_drivers=e.data.data.mq_drivers,t.error=!e.data.success})).catch((function(e){var a;403===(null===(a=e.response)||void 0===a?void 0:a.status)&&clearInterval(t.fetch_task),t.showToast("Exception fetching configuration",e)})).finally((function(){t.loading=!1}))},calculateAverage:function(t){var e=0,a=0,s=Object.values(t.shard_groups);for(var i in s){var n=s[i];if(n.status<6){var r=Object.values(n.shards);for(var o in r){var l=r[o],c=new Date(l.last_heartbeat_ack)-new Date(l.last_heartbeat_sent);c>0&&(a+=c,e+=1)}}}return Math.round(a/e)||"-"},calculateAverageShardGroup:function(t){var e=0,a=0,s=Object.values(t.shards);for(var i in s){var n=s[i],r=new Date(n.last_heartbeat_ack)-new Date(n.last_heartbeat_sent);r>0&&(a+=r,e+=1)}return Math.round(a/e)||"-"},since:function(t){var e=new Date(t),a=((new Date).getTime()-e)/1e3,s="";if(a>86400){var i=Math.trunc(a/86400);i>0&&(s+=i+"d"),a%=86400}if(a>3600){var n=Math.trunc(a/3600);n>0&&(s+=n+"h"),a%=3600}if(a>60){var r=Math.trunc(a/60);r>0&&(s+=r+"m");var o=Math.ceil(a%60);o>0&&(s+=o+"s")}return s}}},$t=Gt,It=(a("73c0"),a("c044"),Object(w["a"])($t,J,F,!1,null,"22ba42eb",null)),Et=It.exports;n["a"].use(A["a"]);var At=[{path:"/",name:"Index",component:B},{path:"/dashboard",name:"Dashboard",component:Et},{path:"*",component:N}],Ot=new A["a"]({mode:"history",base:new URL(document.baseURI).pathname,routes:At}),Rt=Ot,jt=a("9483");Object(jt["a"])("".concat("","service-worker.js"),{ready:function(){console.log("App is being served from cache by a service worker.\nFor more details, visit https://goo.gl/AFskqB")},registered:function(){console.log("Service worker has been registered.")},cached:function(){console.log("Content has been cached for offline use.")},updatefound:function(){console.log("New content is downloading.")},updated:function(){console.log("New content is available; please refresh.")},offline:function(){console.log("No internet connection found. App is running in offline mode.")},error:function(t){console.error("Error during service worker registration:",t)}});var Pt=g()({storeAsString:!0});n["a"].config.productionTip=!1,n["a"].use(m["a"]),n["a"].use(l.a),n["a"].use(r["a"]);var Tt=new r["a"].Store({state:{error:"",userLoading:!0,userAuthenticated:!1,user:{}}});new n["a"]({store:Tt,router:Rt,render:function(t){return t(E)},mounted:function(){this.fetchMe()},data:function(){return{version:"🥪"}},methods:{fetchMe:function(){u.a.get("api/me",{transformResponse:[function(t){return Pt.parse(t)}]}).then((function(t){var e=t.data;e.success?(Tt.state.userAuthenticated=e.data.authenticated,Tt.state.user=e.data.user):Tt.state.error=e.error})).catch((function(t){var e;(null===(e=t.response)||void 0===e?void 0:e.data)?Tt.state.error=t.response.data.error||t.response.data:Tt.state.error=t.text||t.toString()})).finally((function(){Tt.state.userLoading=!1}))}}}).$mount("#app")},"73c0":function(t,e,a){"use strict";a("9766")},"94a4":function(t,e,a){},9766:function(t,e,a){},c044:function(t,e,a){"use strict";a("cd0e")},cd0e:function(t,e,a){},e8a4:function(t,e,a){"use strict";a("94a4")},fd21:function(t,e,a){var s={"./en-au":"0e6b","./en-au.js":"0e6b","./en-ca":"3886","./en-ca.js":"3886","./en-gb":"39a6","./en-gb.js":"39a6","./en-ie":"e1d3","./en-ie.js":"e1d3","./en-il":"7333","./en-il.js":"7333","./en-in":"ec2e","./en-in.js":"ec2e","./en-nz":"6f50","./en-nz.js":"6f50","./en-sg":"b7e9","./en-sg.js":"b7e9"};function i(t){var e=n(t);return a(e)}function n(t){if(!a.o(s,t)){var e=new Error("Cannot find module '"+t+"'");throw e.code="MODULE_NOT_FOUND",e}return s[t]}i.keys=function(){return Object.keys(s)},i.resolve=n,t.exports=i,i.id="fd21"}});
\ No newline at end of file
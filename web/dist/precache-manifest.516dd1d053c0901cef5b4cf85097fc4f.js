@@ -1,38 +1,38 @@
 self.__precacheManifest = (self.__precacheManifest || []).concat([
   {
     "revision": "738cac5f1baf35e23cdb",
-    "url": "/css/app.7e2eb317.css"
+    "url": "css/app.7e2eb317.css"
   },
   {
     "revision": "8c43a47dec5012258c21",
-    "url": "/css/chunk-vendors.a3d78994.css"
+    "url": "css/chunk-vendors.a3d78994.css"
   },
   {
     "revision": "a3ccb4d8af8a3d7bee91b16445264f7d",
-    "url": "/img/brand.svg"
+    "url": "img/brand.svg"
   },
   {
     "revision": "7a23cf90309bc35a4af761aa9d06bc45",
-    "url": "/img/comfy.svg"
+    "url": "img/comfy.svg"
   },
   {
     "revision": "ecd6ab6272a0396a9e6b1fad479238a3",
-    "url": "/index.html"
+    "url": "index.html"
   },
   {
     "revision": "738cac5f1baf35e23cdb",
-    "url": "/js/app.c7d3da42.js"
+    "url": "js/app.c7d3da42.js"
   },
   {
     "revision": "8c43a47dec5012258c21",
-    "url": "/js/chunk-vendors.144a48c0.js"
+    "url": "js/chunk-vendors.144a48c0.js"
   },
   {
     "revision": "ff90a3854e44b8054213bbf552fb038b",
-    "url": "/manifest.json"
+    "url": "manifest.json"
   },
   {
     "revision": "b6216d61c03e6ce0c9aea6ca7808f7ca",
-    "url": "/robots.txt"
+    "url": "robots.txt"
   }
 ]);
\ No newline at end of file
@@ -50,7 +50,7 @@ new Vue({
   methods: {
     fetchMe() {
       axios
-        .get("/api/me", { transformResponse: [data => jsonBig.parse(data)] })
+        .get("api/me", { transformResponse: [data => jsonBig.parse(data)] })
         .then(result => {
           var data = result.data;
           if (data.success) {
@@ -26,7 +26,8 @@ const routes = [
 
 const router = new VueRouter({
   mode: "history",
-  base: process.env.BASE_URL,
+  // The daemon sets the base element to the path it is served under.
+  base: new URL(document.baseURI).pathname,
   routes
 });
 
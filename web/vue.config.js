@@ -3,6 +3,8 @@ const webpack = require("webpack");
 
 module.exports = {
   integrity: false,
+  // Assets are relative so the dashboard can be served under a sub-path.
+  publicPath: "",
   productionSourceMap: false,
   pwa: {
     name: "Sandwich Daemon",